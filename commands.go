@@ -0,0 +1,697 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bilbo290/automagic/pkg/config"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/orchestrator"
+	"github.com/bilbo290/automagic/pkg/queue"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// command is an automagic subcommand, e.g. "automagic mr review 45". A
+// command either declares its flags in setup, which returns the function
+// running it on the remaining arguments, or parses its own in run. Declared
+// flags are listed by "automagic <command> -h" and the completion scripts.
+type command struct {
+	name        string
+	usage       string // Flags and arguments after the name
+	summary     string
+	setup       func(flags *flag.FlagSet) func(args []string) error
+	run         func(args []string) error
+	subcommands []*command
+}
+
+// commands returns the subcommands of automagic, in the order help lists them
+func commands() []*command {
+	return []*command{
+		{
+			name:    "daemon",
+			usage:   "[-memory] [-webhook] [-triage] [-dry-run|-semi-dry-run]",
+			summary: "Watch the project for labelled issues and work on them",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				options := daemonFlags(flags)
+				logLevel := logLevelFlag(flags)
+				return func(args []string) error {
+					return startDaemon(*logLevel, *options)
+				}
+			},
+		},
+		{
+			name:    "worker",
+			usage:   "[-project group/app] [-memory] [-dry-run|-semi-dry-run]",
+			summary: "Run the issues a coordinator queues on QUEUE_URL",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				options := daemonFlags(flags)
+				project := flags.String("project", "", "Project path or alias whose queued issues to claim (default DEFAULT_PROJECT_PATH)")
+				logLevel := logLevelFlag(flags)
+				return func(args []string) error {
+					options.worker = true
+					options.project = *project
+					return startDaemon(*logLevel, *options)
+				}
+			},
+		},
+		{
+			name:    "issue",
+			usage:   "[-dry-run|-semi-dry-run [-preview]] <issue>",
+			summary: "Work on one issue, given by number, project#number or URL",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				dryRun := flags.Bool("dry-run", false, "Show the prompt that would be sent to Claude without executing")
+				semiDryRun := flags.Bool("semi-dry-run", false, "Clone the repository and show the prompt without running Claude")
+				preview := flags.Bool("preview", false, "With -semi-dry-run, run Claude in a throwaway clone and print the diff it would make")
+				logLevel := logLevelFlag(flags)
+				return func(args []string) error {
+					if len(args) != 1 {
+						return fmt.Errorf("usage: automagic issue [-dry-run|-semi-dry-run [-preview]] <issue>")
+					}
+					if *dryRun && *semiDryRun {
+						return fmt.Errorf("-dry-run and -semi-dry-run cannot be used together")
+					}
+					if *preview && !*semiDryRun {
+						return fmt.Errorf("-preview requires -semi-dry-run")
+					}
+					cfg, logFile, err := loadRuntime(*logLevel)
+					if err != nil {
+						return err
+					}
+					defer logFile.Close()
+					issueIID, err := selectIssueProject(cfg, args[0])
+					if err != nil {
+						return err
+					}
+					if _, err := connectGitLab(cfg); err != nil {
+						return err
+					}
+					return processIssueWithOptions(issueIID, cfg, *dryRun, *semiDryRun, *preview)
+				}
+			},
+			subcommands: []*command{
+				{
+					name:    "list",
+					usage:   "[-label claude]",
+					summary: "List the open issues of the project",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						label := flags.String("label", "", "Only list issues with this label")
+						return func(args []string) error {
+							cfg, gitlabClient, closeLog, err := connectProject()
+							if err != nil {
+								return err
+							}
+							defer closeLog.Close()
+
+							var labels []string
+							if *label != "" {
+								labels = append(labels, *label)
+							}
+							issues, err := gitlabClient.GetProjectIssues(cfg.Projects.DefaultPath, labels, "opened")
+							if err != nil {
+								return fmt.Errorf("failed to fetch issues: %v", err)
+							}
+							printIssues(issues, cfg.Projects.DefaultPath)
+							return nil
+						}
+					},
+				},
+				{
+					name:    "state",
+					usage:   "[-mermaid] <issue>",
+					summary: "Print the lifecycle timeline of an issue",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						mermaid := flags.Bool("mermaid", false, "Print the timeline as a Mermaid diagram")
+						return func(args []string) error {
+							if len(args) != 1 {
+								return fmt.Errorf("usage: automagic issue state [-mermaid] <issue>")
+							}
+							cfg, logFile, err := loadRuntime("")
+							if err != nil {
+								return err
+							}
+							defer logFile.Close()
+							issueIID, err := selectIssueProject(cfg, args[0])
+							if err != nil {
+								return err
+							}
+							gitlabClient, err := connectGitLab(cfg)
+							if err != nil {
+								return err
+							}
+							return showIssueState(gitlabClient, cfg, issueIID, *mermaid)
+						}
+					},
+				},
+			},
+		},
+		{
+			name:    "mr",
+			usage:   "<list|review>",
+			summary: "List or review merge requests",
+			subcommands: []*command{
+				{
+					name:    "list",
+					summary: "List the merge requests assigned to GITLAB_USERNAME or awaiting their review",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						return func(args []string) error {
+							cfg, logFile, err := loadRuntime("")
+							if err != nil {
+								return err
+							}
+							defer logFile.Close()
+							gitlabClient, err := connectGitLab(cfg)
+							if err != nil {
+								return err
+							}
+							return listMergeRequests(gitlabClient, cfg)
+						}
+					},
+				},
+				{
+					name:    "review",
+					usage:   "<merge request>",
+					summary: "Review a merge request of the project with Claude",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						logLevel := logLevelFlag(flags)
+						return func(args []string) error {
+							if len(args) != 1 {
+								return fmt.Errorf("usage: automagic mr review <merge request>")
+							}
+							mrIID, err := parseIID(args[0], "!")
+							if err != nil {
+								return err
+							}
+							cfg, logFile, err := loadRuntime(*logLevel)
+							if err != nil {
+								return err
+							}
+							defer logFile.Close()
+							gitlabClient, err := connectGitLab(cfg)
+							if err != nil {
+								return err
+							}
+							return reviewMergeRequest(gitlabClient, cfg, mrIID)
+						}
+					},
+				},
+			},
+		},
+		{
+			name:    "sessions",
+			usage:   "list",
+			summary: "Inspect the sessions stored in DATA_DIR",
+			subcommands: []*command{
+				{
+					name:    "list",
+					usage:   "[-project group/app] [-since 7d]",
+					summary: "List stored sessions, newest first",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						project := flags.String("project", "", "Only list sessions of this project path or alias (default all)")
+						since := flags.String("since", "", "Only list sessions completed this recently, e.g. 7d or 12h (default all)")
+						return func(args []string) error {
+							cfg, err := config.Load()
+							if err != nil {
+								return fmt.Errorf("failed to load configuration: %v", err)
+							}
+							if cfg.Database.Encrypt {
+								if err := setupEncryption(cfg.Database.EncryptionKey, cfg.Data.Dir); err != nil {
+									return fmt.Errorf("failed to set up session encryption: %v", err)
+								}
+							}
+							return listSessions(cfg, cfg.ResolveProject(*project), *since)
+						}
+					},
+				},
+			},
+		},
+		{
+			name:    "config",
+			usage:   "<init|show>",
+			summary: "Write or print the configuration",
+			subcommands: []*command{
+				{
+					name:    "init",
+					summary: "Write a template .env to fill in",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						return func(args []string) error {
+							if err := generateConfigTemplate(); err != nil {
+								return fmt.Errorf("failed to generate config template: %v", err)
+							}
+							fmt.Println("Generated .env template file. Please edit it with your GitLab credentials.")
+							return nil
+						}
+					},
+				},
+				{
+					name:    "show",
+					summary: "Print the effective configuration, including telemetry status",
+					setup: func(flags *flag.FlagSet) func(args []string) error {
+						return func(args []string) error {
+							cfg, err := config.Load()
+							if err != nil {
+								return fmt.Errorf("failed to load configuration: %v", err)
+							}
+							config.PrintConfig(cfg)
+							return nil
+						}
+					},
+				},
+			},
+		},
+		{name: "open", usage: "[-target mr|branch|issue] [-print] <issue>", summary: "Open an issue's merge request, branch or page in the browser", run: runOpenCommand},
+		{name: "review", usage: "-range main..feature | -diff file.patch [-output review.md]", summary: "Review local changes without GitLab", run: runReviewCommand},
+		{name: "bulk", usage: "<label|comment|close|reopen> -from '#12,#15' [-add claude] [-body text]", summary: "Change many issues at once", run: runBulkCommand},
+		{name: "report", usage: "export [-format csv|json] [-since 2024-01-01]", summary: "Export the session history", run: runReportCommand},
+		{name: "metrics", usage: "dashboard [-output dashboard.json]", summary: "Write a Grafana dashboard of the daemon's metrics", run: runMetricsCommand},
+		{name: "hooks", usage: "install [-model haiku] [-threshold warn] [-force]", summary: "Install a pre-push hook reviewing pushed commits", run: runHooksCommand},
+		{name: "version", usage: "[-check]", summary: "Show the version and check for a newer release", run: runVersionCommand},
+		{
+			name:    "completion",
+			usage:   "<bash|zsh|fish>",
+			summary: "Print a shell completion script",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					if len(args) != 1 {
+						return fmt.Errorf("usage: automagic completion <bash|zsh|fish>")
+					}
+					return writeCompletion(os.Stdout, args[0], commands())
+				}
+			},
+		},
+		{
+			name:    "help",
+			summary: "Show this help",
+			setup: func(flags *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					printUsage()
+					return nil
+				}
+			},
+		},
+	}
+}
+
+// lookupCommand returns the command called name, or nil
+func lookupCommand(cmds []*command, name string) *command {
+	for _, cmd := range cmds {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runCommand runs cmd, or the subcommand named by the first argument. path
+// is how the command was called, e.g. "automagic mr review".
+func runCommand(cmd *command, path string, args []string) error {
+	if len(args) > 0 {
+		if sub := lookupCommand(cmd.subcommands, args[0]); sub != nil {
+			return runCommand(sub, path+" "+sub.name, args[1:])
+		}
+	}
+	if cmd.run != nil {
+		return cmd.run(args)
+	}
+
+	flags := flag.NewFlagSet(path, flag.ExitOnError)
+	flags.Usage = func() { printCommandUsage(flags.Output(), cmd, path, flags) }
+	if cmd.setup == nil {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+			flags.Usage()
+			return nil
+		}
+		return fmt.Errorf("unknown command %q, see %s -h", path+" "+args[0], path)
+	}
+	run := cmd.setup(flags)
+
+	// Flags may follow the arguments, as in "automagic issue 123 -dry-run"
+	var positional []string
+	for {
+		flags.Parse(args)
+		rest := flags.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return run(positional)
+}
+
+// printCommandUsage prints the usage, flags and subcommands of a command
+func printCommandUsage(w io.Writer, cmd *command, path string, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: %s %s\n\n%s\n", path, cmd.usage, cmd.summary)
+	if len(cmd.subcommands) > 0 {
+		fmt.Fprintf(w, "\nCommands:\n")
+		printCommandList(w, cmd.subcommands)
+	}
+	if cmd.setup != nil {
+		fmt.Fprintf(w, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+}
+
+// printCommandList prints one line per command, with its summary
+func printCommandList(w io.Writer, cmds []*command) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range cmds {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+}
+
+// printUsage is the help of automagic itself: its commands, then the flags
+// that predate them
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: automagic <command> [flags] [arguments]\n\nCommands:\n")
+	printCommandList(w, commands())
+	fmt.Fprintf(w, "\nRun \"automagic <command> -h\" for the flags of a command.\n")
+
+	legacy := false
+	flag.VisitAll(func(*flag.Flag) { legacy = true })
+	if legacy {
+		fmt.Fprintf(w, "\nThe flags of earlier versions keep working, e.g. automagic -daemon -memory:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// logLevelFlag declares the -log-level flag of commands that log
+func logLevelFlag(flags *flag.FlagSet) *string {
+	return flags.String("log-level", "", "Log level: debug, info, warn or error (default LOG_LEVEL)")
+}
+
+// daemonOptions are how a daemon or worker was started
+type daemonOptions struct {
+	memory     bool
+	webhook    bool
+	triage     bool
+	dryRun     bool
+	semiDryRun bool
+	worker     bool
+	project    string // Project a worker claims issues of
+}
+
+// daemonFlags declares the flags shared by the daemon and worker commands
+func daemonFlags(flags *flag.FlagSet) *daemonOptions {
+	options := &daemonOptions{}
+	flags.BoolVar(&options.memory, "memory", false, "Enable SQLite session storage and resume functionality")
+	flags.BoolVar(&options.webhook, "webhook", false, "Run on GitLab webhooks the daemon registers itself, polling only as a fallback")
+	flags.BoolVar(&options.triage, "triage", false, "Label new unlabeled issues with their type and complexity, as with TRIAGE=true")
+	flags.BoolVar(&options.dryRun, "dry-run", false, "Show the prompts that would be sent to Claude without executing")
+	flags.BoolVar(&options.semiDryRun, "semi-dry-run", false, "Clone repositories and show prompts without running Claude")
+	return options
+}
+
+// startDaemon sets automagic up and runs a daemon or worker until it stops
+func startDaemon(logLevel string, options daemonOptions) error {
+	if options.dryRun && options.semiDryRun {
+		return fmt.Errorf("-dry-run and -semi-dry-run cannot be used together")
+	}
+	printVersionInfo()
+	cfg, logFile, err := loadRuntime(logLevel)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	gitlabClient, err := connectGitLab(cfg)
+	if err != nil {
+		return err
+	}
+	return runDaemon(cfg, gitlabClient, options)
+}
+
+// runDaemon runs the daemon, or a worker when options say so, until it stops
+func runDaemon(cfg *config.Config, gitlabClient *gitlab.Client, options daemonOptions) error {
+	if options.webhook {
+		cfg.Webhook.Register = true
+	}
+	if options.triage {
+		cfg.Triage.Enabled = true
+	}
+
+	// A worker is a daemon that only runs what the coordinator queues.
+	// Daemons select their project at startup; workers are given theirs.
+	project := ""
+	if options.worker {
+		if cfg.Queue.URL == "" {
+			return fmt.Errorf("worker mode needs QUEUE_URL, the queue shared with the coordinator")
+		}
+		project = cfg.ResolveProject(options.project)
+		if project == "" {
+			project = cfg.Projects.DefaultPath
+		}
+		if project == "" {
+			return fmt.Errorf("worker mode needs a project, set DEFAULT_PROJECT_PATH or pass -project")
+		}
+		cfg.Queue.Role = queue.RoleWorker
+	}
+
+	o, err := orchestrator.New(cfg, orchestrator.Options{
+		Project:       project,
+		Memory:        options.memory,
+		Worker:        options.worker,
+		DryRun:        options.dryRun,
+		SemiDryRun:    options.semiDryRun,
+		HandleSignals: true,
+		Version:       version,
+		Client:        gitlabClient,
+	})
+	if err != nil {
+		return err
+	}
+	if err := o.Start(context.Background()); err != nil {
+		return err
+	}
+	return o.Wait()
+}
+
+// loadRuntime loads the configuration and sets up what every command
+// touching GitLab or the session store needs: logging, the data directory
+// and session encryption. A non-empty logLevel overrides LOG_LEVEL.
+func loadRuntime(logLevel string) (*config.Config, io.Closer, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if logLevel != "" {
+		cfg.Logging.Level = logLevel
+	}
+	logFile, err := logging.Setup(logging.Options{Level: cfg.Logging.Level, Format: cfg.Logging.Format, File: cfg.Logging.File})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set up logging: %v", err)
+	}
+
+	// Move the stores earlier versions kept in ~/.automagic under DATA_DIR
+	if moved, err := session.MigrateLegacyData(cfg.Data.Dir); err != nil {
+		logging.Warnf("Failed to migrate legacy data: %v", err)
+	} else if len(moved) > 0 {
+		logging.Infof("Migrated %d legacy data files to %s", len(moved), cfg.Data.Dir)
+	}
+
+	if cfg.Database.Encrypt {
+		if err := setupEncryption(cfg.Database.EncryptionKey, cfg.Data.Dir); err != nil {
+			logFile.Close()
+			return nil, nil, fmt.Errorf("failed to set up session encryption: %v", err)
+		}
+	}
+	return cfg, logFile, nil
+}
+
+// connectGitLab prepares the engine for the configuration and returns a
+// GitLab client once the connection works
+func connectGitLab(cfg *config.Config) (*gitlab.Client, error) {
+	if err := orchestrator.Prepare(cfg); err != nil {
+		return nil, err
+	}
+
+	gitlabClient := orchestrator.NewClient(cfg)
+	fmt.Printf("Testing GitLab connection...\n")
+	if err := gitlabClient.TestConnection(); err != nil {
+		return nil, fmt.Errorf("GitLab connection test failed: %v\nPlease check your GitLab URL and token configuration", err)
+	}
+	fmt.Printf("GitLab connection successful!\n")
+	return gitlabClient, nil
+}
+
+// connectProject is loadRuntime and connectGitLab for commands working on
+// DEFAULT_PROJECT_PATH
+func connectProject() (*config.Config, *gitlab.Client, io.Closer, error) {
+	cfg, logFile, err := loadRuntime("")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cfg.Projects.DefaultPath == "" {
+		logFile.Close()
+		return nil, nil, nil, fmt.Errorf("no project selected, run: automagic -interactive")
+	}
+	gitlabClient, err := connectGitLab(cfg)
+	if err != nil {
+		logFile.Close()
+		return nil, nil, nil, err
+	}
+	return cfg, gitlabClient, logFile, nil
+}
+
+// selectIssueProject points the configuration at the project of an issue
+// reference, as "automagic open" does, and returns the issue number
+func selectIssueProject(cfg *config.Config, ref string) (int, error) {
+	projectPath, issueIID, err := cfg.ParseIssueRef(ref)
+	if err != nil {
+		return 0, err
+	}
+	cfg.Projects.DefaultPath = projectPath
+	if cfg.Projects.DefaultPath == "" {
+		return 0, fmt.Errorf("no project selected, run: automagic -interactive")
+	}
+	return issueIID, nil
+}
+
+// parseIID reads an issue or merge request number, with or without its
+// prefix, e.g. 45 or !45
+func parseIID(value, prefix string) (int, error) {
+	var iid int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(value, prefix), "%d", &iid); err != nil || iid <= 0 {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return iid, nil
+}
+
+// printIssues prints the issues of a project, one block each
+func printIssues(issues []gitlab.Issue, projectPath string) {
+	fmt.Printf("Found %d issues in project %s:\n\n", len(issues), projectPath)
+	for _, issue := range issues {
+		fmt.Printf("Issue #%d: %s\n", issue.IID, issue.Title)
+		fmt.Printf("  State: %s\n", issue.State)
+		if len(issue.Labels) > 0 {
+			fmt.Printf("  Labels: %s\n", strings.Join(issue.Labels, ", "))
+		}
+		fmt.Printf("  Author: %s\n", issue.Author.Name)
+		if assignees := issue.AssigneeUsernames(); len(assignees) > 0 {
+			fmt.Printf("  Assignees: @%s\n", strings.Join(assignees, ", @"))
+		}
+		if issue.Milestone != nil {
+			fmt.Printf("  Milestone: %s\n", issue.Milestone.Title)
+		}
+		if issue.Weight > 0 {
+			fmt.Printf("  Weight: %d\n", issue.Weight)
+		}
+		if issue.DueDate != "" {
+			fmt.Printf("  Due: %s\n", issue.DueDate)
+		}
+		if issue.Confidential {
+			fmt.Printf("  Confidential: yes\n")
+		}
+		if issue.TimeStats.HumanTimeEstimate != "" {
+			fmt.Printf("  Time: %s spent of %s estimated\n", issue.TimeStats.HumanTotalTimeSpent, issue.TimeStats.HumanTimeEstimate)
+		}
+		fmt.Printf("  Created: %s\n", issue.CreatedAt)
+		fmt.Printf("  URL: %s\n\n", issue.WebURL)
+	}
+}
+
+// listMergeRequests prints the open merge requests assigned to
+// GITLAB_USERNAME and those awaiting their review
+func listMergeRequests(gitlabClient *gitlab.Client, cfg *config.Config) error {
+	fmt.Printf("Listing assigned merge requests for user: %s\n\n", cfg.GitLab.Username)
+
+	assignedMRs, err := gitlabClient.GetAssignedMergeRequests(cfg.GitLab.Username, "opened")
+	if err != nil {
+		return fmt.Errorf("failed to fetch assigned merge requests: %v", err)
+	}
+	reviewMRs, err := gitlabClient.GetMergeRequestsForReview(cfg.GitLab.Username, "opened")
+	if err != nil {
+		return fmt.Errorf("failed to fetch review merge requests: %v", err)
+	}
+
+	printMergeRequests := func(title string, mrs []gitlab.MergeRequest) {
+		fmt.Printf("=== %s (%d) ===\n", title, len(mrs))
+		for _, mr := range mrs {
+			fmt.Printf("!%d: %s\n", mr.IID, mr.Title)
+			fmt.Printf("  Author: %s\n", mr.Author.Name)
+			fmt.Printf("  Source: %s → %s\n", mr.SourceBranch, mr.TargetBranch)
+			fmt.Printf("  State: %s\n", mr.State)
+			fmt.Printf("  URL: %s\n\n", mr.WebURL)
+		}
+	}
+	if len(assignedMRs) > 0 {
+		printMergeRequests("Assigned Merge Requests", assignedMRs)
+	} else {
+		fmt.Printf("No assigned merge requests found.\n\n")
+	}
+	if len(reviewMRs) > 0 {
+		printMergeRequests("Merge Requests for Review", reviewMRs)
+	} else {
+		fmt.Printf("No merge requests for review found.\n\n")
+	}
+	return nil
+}
+
+// reviewMergeRequest reviews a merge request of DEFAULT_PROJECT_PATH with Claude
+func reviewMergeRequest(gitlabClient *gitlab.Client, cfg *config.Config, mrIID int) error {
+	if cfg.Projects.DefaultPath == "" {
+		return fmt.Errorf("no project selected, run: automagic -interactive")
+	}
+
+	fmt.Printf("Reviewing merge request !%d with Claude...\n", mrIID)
+	mr, err := gitlabClient.GetMergeRequest(cfg.Projects.DefaultPath, mrIID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch merge request: %v", err)
+	}
+	if err := processMergeRequest(mr, cfg); err != nil {
+		return fmt.Errorf("failed to process merge request: %v", err)
+	}
+	return nil
+}
+
+// listSessions prints the stored sessions of a project, or of every project
+// when projectPath is empty, completed within since, or ever when it is empty
+func listSessions(cfg *config.Config, projectPath, since string) error {
+	store, err := session.NewSQLiteSessionStore(cfg.Data.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to open session store: %v", err)
+	}
+	defer store.Close()
+	store.SetProject(projectPath)
+
+	var sessions []*session.CompletedSession
+	if since == "" {
+		sessions = store.GetCompletedSessions()
+	} else {
+		window, err := parseSince(since)
+		if err != nil {
+			return fmt.Errorf("invalid -since value: %v", err)
+		}
+		sessions = store.GetRecentlyCompletedSessions(window)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions stored")
+		return nil
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CompletionTime.After(sessions[j].CompletionTime) })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Issue\tCompleted\tBranch\tSession\t\n")
+	for _, s := range sessions {
+		branch := s.Branch
+		if branch == "" {
+			branch = fmt.Sprintf("issue-%d", s.IssueIID)
+		}
+		if s.ForkPath != "" {
+			branch = s.ForkPath + ":" + branch
+		}
+		fmt.Fprintf(w, "%s#%d\t%s\t%s\t%s\t\n", s.ProjectPath, s.IssueIID, s.CompletionTime.Format("2006-01-02 15:04"), branch, s.SessionID)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completionPath is a command reachable from the command line, with the
+// words completing after it
+type completionPath struct {
+	path        string // Subcommand names after "automagic", "" for automagic itself
+	subcommands []*command
+	flags       []*flag.Flag
+}
+
+// completionPaths walks the command tree, parents before their subcommands
+func completionPaths(cmds []*command) []completionPath {
+	paths := []completionPath{{subcommands: cmds}}
+	var walk func(prefix string, cmd *command)
+	walk = func(prefix string, cmd *command) {
+		entry := completionPath{path: strings.TrimSpace(prefix + " " + cmd.name), subcommands: cmd.subcommands}
+		if cmd.setup != nil {
+			flags := flag.NewFlagSet(entry.path, flag.ContinueOnError)
+			cmd.setup(flags)
+			flags.VisitAll(func(f *flag.Flag) { entry.flags = append(entry.flags, f) })
+		}
+		paths = append(paths, entry)
+		for _, sub := range cmd.subcommands {
+			walk(entry.path, sub)
+		}
+	}
+	for _, cmd := range cmds {
+		walk("", cmd)
+	}
+	return paths
+}
+
+// writeCompletion writes the completion script of a shell. The scripts
+// complete subcommands and the flags they declare; arguments are left to the
+// shell's default completion.
+func writeCompletion(w io.Writer, shell string, cmds []*command) error {
+	paths := completionPaths(cmds)
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, paths)
+	case "zsh":
+		// zsh runs the bash script through its compatibility layer
+		fmt.Fprintf(w, "#compdef automagic\n\nautoload -U +X bashcompinit && bashcompinit\n\n")
+		writeBashCompletion(w, paths)
+	case "fish":
+		writeFishCompletion(w, paths)
+	default:
+		return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", shell)
+	}
+	return nil
+}
+
+func writeBashCompletion(w io.Writer, paths []completionPath) {
+	fmt.Fprintf(w, "# automagic completion, from \"automagic completion bash\"\n\n")
+	fmt.Fprintf(w, "_automagic_subcommands() {\n\tcase \"$1\" in\n")
+	for _, p := range paths {
+		if len(p.subcommands) > 0 {
+			fmt.Fprintf(w, "\t%q) echo %q ;;\n", p.path, strings.Join(commandNames(p.subcommands), " "))
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n}\n\n")
+
+	fmt.Fprintf(w, "_automagic_flags() {\n\tcase \"$1\" in\n")
+	for _, p := range paths {
+		if len(p.flags) > 0 {
+			names := make([]string, len(p.flags))
+			for i, f := range p.flags {
+				names[i] = "-" + f.Name
+			}
+			fmt.Fprintf(w, "\t%q) echo %q ;;\n", p.path, strings.Join(names, " "))
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n}\n\n")
+
+	fmt.Fprint(w, `_automagic() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" path="" word i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		word="${COMP_WORDS[i]}"
+		case " $(_automagic_subcommands "$path") " in
+		*" $word "*) path="${path:+$path }$word" ;;
+		esac
+	done
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "$(_automagic_flags "$path")" -- "$cur"))
+	else
+		COMPREPLY=($(compgen -W "$(_automagic_subcommands "$path")" -- "$cur"))
+	fi
+}
+
+complete -o default -F _automagic automagic
+`)
+}
+
+func writeFishCompletion(w io.Writer, paths []completionPath) {
+	fmt.Fprintf(w, "# automagic completion, from \"automagic completion fish\"\n\n")
+	fmt.Fprintf(w, "function __automagic_subcommands\n    switch \"$argv[1]\"\n")
+	for _, p := range paths {
+		if len(p.subcommands) > 0 {
+			fmt.Fprintf(w, "        case %s\n            printf '%%s\\n' %s\n", fishQuote(p.path), strings.Join(commandNames(p.subcommands), " "))
+		}
+	}
+	fmt.Fprint(w, `    end
+end
+
+# __automagic_at succeeds when the command line is at the subcommand path in $argv[1]
+function __automagic_at
+    set -l path
+    for word in (commandline -opc)[2..-1]
+        if contains -- $word (__automagic_subcommands "$path")
+            set path (string trim -- "$path $word")
+        end
+    end
+    test "$path" = "$argv[1]"
+end
+
+`)
+	for _, p := range paths {
+		condition := fishQuote("__automagic_at " + fishQuote(p.path))
+		for _, sub := range p.subcommands {
+			fmt.Fprintf(w, "complete -c automagic -f -n %s -a %s -d %s\n", condition, sub.name, fishQuote(sub.summary))
+		}
+		for _, f := range p.flags {
+			fmt.Fprintf(w, "complete -c automagic -n %s -o %s -d %s\n", condition, f.Name, fishQuote(f.Usage))
+		}
+	}
+}
+
+// commandNames returns the names of commands, sorted
+func commandNames(cmds []*command) []string {
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fishQuote quotes a string for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,219 @@
+// Package benchmark runs benchmarks in a checkout and compares two runs in
+// the manner of benchstat
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoCommand runs the benchmarks of a Go module, without its tests, enough
+// times to tell a change from noise
+const GoCommand = "go test -run='^$' -bench=. -benchmem -count=6 ./..."
+
+// procsSuffix is the GOMAXPROCS suffix go test adds to benchmark names
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Runner runs a benchmark command in a checkout
+type Runner struct {
+	Command string        // Shell command printing Go benchmark format
+	Timeout time.Duration // Limit for one run, 0 for none
+}
+
+// Run runs the benchmark command in dir and returns its samples
+func (r *Runner) Run(dir string) (Samples, error) {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.Command)
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("benchmark run timed out after %s", r.Timeout)
+		}
+		return nil, fmt.Errorf("benchmark run failed: %v: %s", err, lastLine(output.String()))
+	}
+
+	samples := Parse(output.String())
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no benchmark results in the output: %s", lastLine(output.String()))
+	}
+	return samples, nil
+}
+
+// Key identifies one measurement of a benchmark
+type Key struct {
+	Package string // Package from the pkg: line before the benchmark, if any
+	Name    string // Benchmark name without the GOMAXPROCS suffix
+	Unit    string // e.g. ns/op, B/op or allocs/op
+}
+
+// Samples holds the values measured per benchmark and unit, one per run
+type Samples map[Key][]float64
+
+// Parse reads benchmark results in the Go benchmark format, e.g.
+//
+//	BenchmarkParse-8   20000   61234 ns/op   2048 B/op   12 allocs/op
+func Parse(output string) Samples {
+	samples := make(Samples)
+	pkg := ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "pkg:" {
+			pkg = fields[1]
+			continue
+		}
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := procsSuffix.ReplaceAllString(fields[0], "")
+		// Value and unit pairs follow the iteration count
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			key := Key{Package: pkg, Name: name, Unit: fields[i+1]}
+			samples[key] = append(samples[key], value)
+		}
+	}
+	return samples
+}
+
+// Summary is the center and spread of the samples of one measurement
+type Summary struct {
+	Median float64
+	Spread float64 // Largest deviation from the median, as a fraction of it
+}
+
+// Summarize returns the median of values and their spread around it
+func Summarize(values []float64) Summary {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	spread := 0.0
+	if median != 0 {
+		spread = math.Max(median-sorted[0], sorted[len(sorted)-1]-median) / math.Abs(median)
+	}
+	return Summary{Median: median, Spread: spread}
+}
+
+// Change is one measurement before and after
+type Change struct {
+	Key    Key
+	Before Summary
+	After  Summary
+	// Delta is the change of the median as a fraction of the old one
+	Delta float64
+	// Significant is false when the ranges of the two runs overlap, so the
+	// delta may be noise
+	Significant bool
+}
+
+// Compare pairs the measurements both runs have, ordered by package, name
+// and unit
+func Compare(before, after Samples) []Change {
+	var changes []Change
+	for key, old := range before {
+		current, ok := after[key]
+		if !ok {
+			continue
+		}
+		change := Change{Key: key, Before: Summarize(old), After: Summarize(current)}
+		if change.Before.Median != 0 {
+			change.Delta = (change.After.Median - change.Before.Median) / math.Abs(change.Before.Median)
+		}
+		change.Significant = slices.Max(old) < slices.Min(current) || slices.Max(current) < slices.Min(old)
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i].Key, changes[j].Key
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return unitOrder(a.Unit) < unitOrder(b.Unit)
+	})
+	return changes
+}
+
+// Table renders changes as a markdown table in the manner of benchstat:
+// median ± spread per side, and the delta or ~ when it is within the noise
+func Table(changes []Change) string {
+	var b strings.Builder
+	b.WriteString("| Benchmark | Before | After | Delta |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, change := range changes {
+		delta := "~"
+		if change.Significant {
+			delta = fmt.Sprintf("%+.1f%%", change.Delta*100)
+		}
+		fmt.Fprintf(&b, "| `%s` %s | %s | %s | %s |\n", displayName(change.Key), change.Key.Unit,
+			formatSummary(change.Before), formatSummary(change.After), delta)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// displayName is the benchmark name, prefixed with the last element of its
+// package path when there is one
+func displayName(key Key) string {
+	if key.Package == "" {
+		return key.Name
+	}
+	return key.Package[strings.LastIndex(key.Package, "/")+1:] + "." + key.Name
+}
+
+// formatSummary prints a median with four significant digits, or whole
+// when it is larger, and its spread
+func formatSummary(summary Summary) string {
+	median := strconv.FormatFloat(summary.Median, 'g', 4, 64)
+	if math.Abs(summary.Median) >= 1000 {
+		median = strconv.FormatFloat(summary.Median, 'f', 0, 64)
+	}
+	return fmt.Sprintf("%s ±%.0f%%", median, summary.Spread*100)
+}
+
+// unitOrder lists time first, then memory, then any custom unit
+func unitOrder(unit string) string {
+	switch unit {
+	case "ns/op":
+		return "0"
+	case "B/op":
+		return "1"
+	case "allocs/op":
+		return "2"
+	}
+	return "3" + unit
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
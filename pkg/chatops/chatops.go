@@ -0,0 +1,286 @@
+// Package chatops bridges Slack and Mattermost to the daemon: slash commands
+// such as "/automagic status" call its control operations, and summaries of
+// finished sessions are posted to a channel through an incoming webhook.
+package chatops
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/control"
+)
+
+// maxRequestBytes bounds the size of a slash command request
+const maxRequestBytes = 1 << 16
+
+// maxSignatureAge is how old a signed Slack request may be before it is
+// rejected as a replay
+const maxSignatureAge = 5 * time.Minute
+
+// Chat platforms, which differ in how messages link
+const (
+	Slack      = "slack"
+	Mattermost = "mattermost"
+)
+
+// Options configures a bridge
+type Options struct {
+	Platform      string // Slack or Mattermost
+	Token         string // Verification token sent with Mattermost (and legacy Slack) slash commands
+	SigningSecret string // Slack signing secret, verifying the X-Slack-Signature header
+	WebhookURL    string // Incoming webhook session summaries are posted to, "" for none
+}
+
+// Bridge answers slash commands with the daemon's control operations and
+// posts to the channel's incoming webhook
+type Bridge struct {
+	controller control.Controller
+	options    Options
+	client     *http.Client
+}
+
+// NewBridge returns a bridge for the controller
+func NewBridge(controller control.Controller, options Options) *Bridge {
+	return &Bridge{
+		controller: controller,
+		options:    options,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Reply is the JSON answer to a slash command, understood by Slack and Mattermost
+type Reply struct {
+	ResponseType string `json:"response_type"` // in_channel or ephemeral
+	Text         string `json:"text"`
+}
+
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+	if !b.verify(r.Header, body, form.Get("token")) {
+		http.Error(w, "invalid signature or token", http.StatusUnauthorized)
+		return
+	}
+
+	answer := b.Command(form.Get("text"), form.Get("user_name"))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answer)
+}
+
+// verify accepts a request signed with the Slack signing secret or carrying
+// the verification token, whichever is configured
+func (b *Bridge) verify(header http.Header, body []byte, token string) bool {
+	if b.options.SigningSecret != "" {
+		if signature := header.Get("X-Slack-Signature"); signature != "" {
+			return verifySlackSignature(b.options.SigningSecret, header.Get("X-Slack-Request-Timestamp"), signature, body)
+		}
+	}
+	return b.options.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(b.options.Token)) == 1
+}
+
+// verifySlackSignature checks a request against Slack's v0 signature scheme
+func verifySlackSignature(secret, timestamp, signature string, body []byte) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Command runs the text of a slash command for a user and returns the answer
+func (b *Bridge) Command(text, user string) Reply {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ephemeral(usage)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "status":
+		return inChannel(formatStatus(b.controller.Status()))
+
+	case "run":
+		if len(fields) != 2 {
+			return ephemeral("Usage: run group/project#123 or run 123")
+		}
+		project, issueIID, err := parseIssueRef(fields[1])
+		if err != nil {
+			return ephemeral(err.Error())
+		}
+		if err := b.controller.Enqueue(project, issueIID); err != nil {
+			return ephemeral(fmt.Sprintf("Could not run %s: %v", fields[1], err))
+		}
+		return inChannel(fmt.Sprintf("%s queued %s, starting now", mention(user), fields[1]))
+
+	case "cancel":
+		if len(fields) != 2 {
+			return ephemeral("Usage: cancel 123")
+		}
+		issueIID, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+		if err != nil || issueIID <= 0 {
+			return ephemeral(fmt.Sprintf("%q is not an issue number", fields[1]))
+		}
+		if err := b.controller.Cancel(issueIID); err != nil {
+			return ephemeral(fmt.Sprintf("Could not cancel #%d: %v", issueIID, err))
+		}
+		return inChannel(fmt.Sprintf("%s cancelled the session on #%d", mention(user), issueIID))
+	}
+	return ephemeral(usage)
+}
+
+const usage = "Commands:\n" +
+	"• `status`: what the daemon is working on\n" +
+	"• `run group/project#123` (or `run 123`): work on an issue now\n" +
+	"• `cancel 123`: stop the session on an issue"
+
+// parseIssueRef parses "group/project#123", "#123" or "123"
+func parseIssueRef(ref string) (string, int, error) {
+	project := ""
+	number := ref
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		project, number = ref[:i], ref[i+1:]
+	}
+	issueIID, err := strconv.Atoi(number)
+	if err != nil || issueIID <= 0 {
+		return "", 0, fmt.Errorf("%q is not an issue, use group/project#123 or 123", ref)
+	}
+	return project, issueIID, nil
+}
+
+// formatStatus renders the daemon status as a chat message
+func formatStatus(status control.Status) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Monitoring *%s*", status.Project)
+	if status.Role != "" {
+		fmt.Fprintf(&b, " as %s", status.Role)
+	}
+	if status.DryRun {
+		b.WriteString(" (dry run)")
+	}
+
+	if len(status.Running) == 0 {
+		b.WriteString(": no sessions running")
+	} else {
+		fmt.Fprintf(&b, ": %d sessions running", len(status.Running))
+		for _, running := range status.Running {
+			fmt.Fprintf(&b, "\n• #%d %s", running.IssueIID, running.Kind)
+			if !running.StartedAt.IsZero() {
+				fmt.Fprintf(&b, " for %s", time.Since(running.StartedAt).Round(time.Minute))
+			}
+		}
+	}
+	if len(status.Enqueued) > 0 {
+		queued := make([]string, 0, len(status.Enqueued))
+		for _, issueIID := range status.Enqueued {
+			queued = append(queued, fmt.Sprintf("#%d", issueIID))
+		}
+		fmt.Fprintf(&b, "\nQueued: %s", strings.Join(queued, ", "))
+	}
+	return b.String()
+}
+
+// Summary renders a finished session for the channel, linking the issue when
+// issueURL is set
+func (b *Bridge) Summary(session control.Session, issueURL string) string {
+	issue := fmt.Sprintf("%s#%d", session.ProjectPath, session.IssueIID)
+	if issueURL != "" {
+		issue = b.link(issueURL, issue)
+	}
+
+	verb := "finished"
+	if session.Outcome != "completed" {
+		verb = "failed"
+		if session.Failure != "" {
+			verb += " (" + session.Failure + ")"
+		}
+	}
+	summary := fmt.Sprintf("%s %s session on %s %s after %s", outcomeIcon(session), session.Kind, issue, verb,
+		time.Duration(session.Duration*float64(time.Second)).Round(time.Second))
+	if session.Turns > 0 {
+		summary += fmt.Sprintf(", %d turns", session.Turns)
+	}
+	if session.CostUSD > 0 {
+		summary += fmt.Sprintf(", $%.2f", session.CostUSD)
+	}
+	return summary
+}
+
+// link renders a link in the platform's message syntax
+func (b *Bridge) link(target, text string) string {
+	if b.options.Platform == Mattermost {
+		return fmt.Sprintf("[%s](%s)", text, target)
+	}
+	return fmt.Sprintf("<%s|%s>", target, text)
+}
+
+func outcomeIcon(session control.Session) string {
+	if session.Outcome == "completed" {
+		return ":white_check_mark:"
+	}
+	return ":x:"
+}
+
+// Post sends a message to the incoming webhook, if one is configured
+func (b *Bridge) Post(text string) error {
+	if b.options.WebhookURL == "" {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+	resp, err := b.client.Post(b.options.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post to chat: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("chat webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func inChannel(text string) Reply {
+	return Reply{ResponseType: "in_channel", Text: text}
+}
+
+func ephemeral(text string) Reply {
+	return Reply{ResponseType: "ephemeral", Text: text}
+}
+
+func mention(user string) string {
+	if user == "" {
+		return "Someone"
+	}
+	return "@" + user
+}
@@ -0,0 +1,178 @@
+// Package cigen generates a .gitlab-ci.yml from per-language job templates:
+// build, test and lint jobs for the project's language, a Docker image build
+// and a Cloud Run deployment. The generated part is kept between markers, so
+// it can be regenerated without touching jobs added by hand.
+package cigen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Markers around the generated part of a configuration
+const (
+	BeginMarker = "# automagic:begin"
+	EndMarker   = "# automagic:end"
+)
+
+// Deployment targets
+const (
+	DeployNone           = ""
+	DeployCloudRun       = "cloudrun"        // Image from the docker job, copied to Artifact Registry
+	DeployCloudRunSource = "cloudrun-source" // Built by Cloud Build from the repository
+)
+
+// stageOrder is the order stages run in
+var stageOrder = []string{"build", "test", "lint", "package", "deploy"}
+
+// languages maps GitLab's language names to the templates that build them
+var languages = map[string]string{
+	"Go":         "go",
+	"JavaScript": "node",
+	"TypeScript": "node",
+	"Python":     "python",
+}
+
+// Options selects the jobs to generate and fills them in
+type Options struct {
+	Language string // Template of the project's language: go, node or python
+	Docker   bool   // Build and push an image from the Dockerfile
+	Deploy   string // One of the Deploy constants
+
+	GoVersion     string // e.g. 1.24, from go.mod
+	NodeVersion   string
+	PythonVersion string
+
+	Service string // Cloud Run service, also the Artifact Registry repository
+	Region  string // Cloud Run region
+}
+
+// LanguageTemplate returns the template building a GitLab language, "" when
+// there is none
+func LanguageTemplate(language string) string {
+	return languages[language]
+}
+
+type template struct {
+	Stages []string
+	Text   string
+}
+
+// Templates are the job templates configurations are generated from
+type Templates struct {
+	templates map[string]template
+}
+
+// Load returns the built-in templates, with <name>.yml files in dir
+// replacing them. An empty dir uses the built-in ones only.
+func Load(dir string) (*Templates, error) {
+	t := &Templates{templates: make(map[string]template, len(defaultTemplates))}
+	for name, tmpl := range defaultTemplates {
+		t.templates[name] = tmpl
+	}
+	if dir == "" {
+		return t, nil
+	}
+
+	for name, tmpl := range t.templates {
+		content, err := os.ReadFile(filepath.Join(dir, name+".yml"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CI template %s: %v", name, err)
+		}
+		tmpl.Text = string(content)
+		t.templates[name] = tmpl
+	}
+	return t, nil
+}
+
+// Generate renders the configuration selected by options, between the markers
+func (t *Templates) Generate(options Options) (string, error) {
+	if options.Deploy == DeployCloudRun && !options.Docker {
+		return "", fmt.Errorf("the %s deployment needs a Dockerfile, use %s instead", DeployCloudRun, DeployCloudRunSource)
+	}
+
+	var names []string
+	if options.Language != "" {
+		names = append(names, options.Language)
+	}
+	if options.Docker {
+		names = append(names, "docker")
+	}
+	if options.Deploy != DeployNone {
+		names = append(names, options.Deploy)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("nothing to generate: no supported language, Dockerfile or deployment")
+	}
+
+	used := make(map[string]bool)
+	var jobs bytes.Buffer
+	for _, name := range names {
+		tmpl, ok := t.templates[name]
+		if !ok {
+			return "", fmt.Errorf("unknown CI template %q", name)
+		}
+		parsed, err := texttemplate.New(name).Option("missingkey=error").Parse(tmpl.Text)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse CI template %s: %v", name, err)
+		}
+		jobs.WriteString("\n")
+		if err := parsed.Execute(&jobs, options); err != nil {
+			return "", fmt.Errorf("failed to render CI template %s: %v", name, err)
+		}
+		for _, stage := range tmpl.Stages {
+			used[stage] = true
+		}
+	}
+
+	var config strings.Builder
+	config.WriteString(BeginMarker + "\n")
+	config.WriteString("# Generated by automagic; edits between these markers are replaced when it is regenerated\n")
+	config.WriteString("stages:\n")
+	for _, stage := range stageOrder {
+		if used[stage] {
+			config.WriteString("  - " + stage + "\n")
+		}
+	}
+	config.WriteString(strings.TrimRight(jobs.String(), "\n") + "\n")
+	config.WriteString(EndMarker + "\n")
+	return config.String(), nil
+}
+
+// Merge puts a generated configuration into an existing one, replacing its
+// generated part. A configuration without markers is replaced as a whole.
+func Merge(existing, generated string) string {
+	begin := strings.Index(existing, BeginMarker)
+	end := strings.Index(existing, EndMarker)
+	if begin < 0 || end < begin {
+		return generated
+	}
+	end += len(EndMarker)
+	if end < len(existing) && existing[end] == '\n' {
+		end++
+	}
+	return existing[:begin] + generated + existing[end:]
+}
+
+// GoVersion reads the go directive of a go.mod, e.g. 1.24 for "go 1.24.2",
+// returning fallback when there is none
+func GoVersion(goMod, fallback string) string {
+	for _, line := range strings.Split(goMod, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "go" {
+			parts := strings.SplitN(fields[1], ".", 3)
+			if len(parts) >= 2 {
+				return parts[0] + "." + parts[1]
+			}
+			return fields[1]
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,138 @@
+package cigen
+
+// defaultTemplates are the built-in job templates, rendered against Options.
+// Each declares the stage its jobs run in, so only used stages are listed.
+var defaultTemplates = map[string]template{
+	"go": {Stages: []string{"build", "test", "lint"}, Text: `.go:
+  image: golang:{{.GoVersion}}
+  variables:
+    GOPATH: $CI_PROJECT_DIR/.go
+  cache:
+    key: go-$CI_COMMIT_REF_SLUG
+    paths:
+      - .go/pkg/mod/
+
+build:
+  extends: .go
+  stage: build
+  script:
+    - go build ./...
+
+test:
+  extends: .go
+  stage: test
+  script:
+    - go test -race -coverprofile=coverage.out ./...
+    - go tool cover -func=coverage.out
+  coverage: '/total:\s+\(statements\)\s+(\d+.\d+)%/'
+
+lint:
+  extends: .go
+  stage: lint
+  script:
+    - go vet ./...
+    - test -z "$(gofmt -l .)" || (gofmt -l . && exit 1)
+`},
+
+	"node": {Stages: []string{"build", "test", "lint"}, Text: `.node:
+  image: node:{{.NodeVersion}}
+  cache:
+    key: node-$CI_COMMIT_REF_SLUG
+    paths:
+      - node_modules/
+  before_script:
+    - npm ci
+
+build:
+  extends: .node
+  stage: build
+  script:
+    - npm run build --if-present
+
+test:
+  extends: .node
+  stage: test
+  script:
+    - npm test
+
+lint:
+  extends: .node
+  stage: lint
+  script:
+    - npm run lint --if-present
+`},
+
+	"python": {Stages: []string{"test", "lint"}, Text: `.python:
+  image: python:{{.PythonVersion}}
+  cache:
+    key: pip-$CI_COMMIT_REF_SLUG
+    paths:
+      - .cache/pip
+  variables:
+    PIP_CACHE_DIR: $CI_PROJECT_DIR/.cache/pip
+  before_script:
+    - pip install -r requirements.txt pytest ruff
+
+test:
+  extends: .python
+  stage: test
+  script:
+    - pytest
+
+lint:
+  extends: .python
+  stage: lint
+  script:
+    - ruff check .
+`},
+
+	"docker": {Stages: []string{"package"}, Text: `docker:
+  stage: package
+  image: docker:27
+  services:
+    - docker:27-dind
+  script:
+    - docker login -u "$CI_REGISTRY_USER" -p "$CI_REGISTRY_PASSWORD" "$CI_REGISTRY"
+    - docker build -t "$CI_REGISTRY_IMAGE:$CI_COMMIT_SHORT_SHA" .
+    - docker push "$CI_REGISTRY_IMAGE:$CI_COMMIT_SHORT_SHA"
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+`},
+
+	// cloudrun deploys the image built by the docker job. Cloud Run cannot pull
+	// from the GitLab registry, so the image is copied to Artifact Registry.
+	"cloudrun": {Stages: []string{"deploy"}, Text: `deploy:
+  stage: deploy
+  image: google/cloud-sdk:latest
+  services:
+    - docker:27-dind
+  variables:
+    DOCKER_HOST: tcp://docker:2375
+    DEPLOY_IMAGE: {{.Region}}-docker.pkg.dev/$GCP_PROJECT_ID/{{.Service}}/{{.Service}}:$CI_COMMIT_SHORT_SHA
+  script:
+    - gcloud auth activate-service-account --key-file="$GCP_SERVICE_ACCOUNT_KEY"
+    - gcloud auth configure-docker {{.Region}}-docker.pkg.dev --quiet
+    - docker login -u "$CI_REGISTRY_USER" -p "$CI_REGISTRY_PASSWORD" "$CI_REGISTRY"
+    - docker pull "$CI_REGISTRY_IMAGE:$CI_COMMIT_SHORT_SHA"
+    - docker tag "$CI_REGISTRY_IMAGE:$CI_COMMIT_SHORT_SHA" "$DEPLOY_IMAGE"
+    - docker push "$DEPLOY_IMAGE"
+    - gcloud run deploy {{.Service}} --image "$DEPLOY_IMAGE" --region {{.Region}} --project "$GCP_PROJECT_ID" --quiet
+  environment:
+    name: production
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+`},
+
+	// cloudrun-source lets Cloud Build build the image from the repository
+	"cloudrun-source": {Stages: []string{"deploy"}, Text: `deploy:
+  stage: deploy
+  image: google/cloud-sdk:slim
+  script:
+    - gcloud auth activate-service-account --key-file="$GCP_SERVICE_ACCOUNT_KEY"
+    - gcloud run deploy {{.Service}} --source . --region {{.Region}} --project "$GCP_PROJECT_ID" --quiet
+  environment:
+    name: production
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+`},
+}
@@ -0,0 +1,143 @@
+package cilog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options controls how much of a job log survives distillation
+type Options struct {
+	TailLines  int // Lines kept from the end of the log
+	BlockLines int // Lines kept after each failure marker
+	MaxChars   int // Hard cap on the distilled output, 0 means unlimited
+}
+
+// DefaultOptions returns limits suited to including several failed jobs in one prompt
+func DefaultOptions() Options {
+	return Options{
+		TailLines:  60,
+		BlockLines: 25,
+		MaxChars:   12000,
+	}
+}
+
+var (
+	// ansiPattern matches terminal color and cursor escape sequences
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+	// sectionPattern matches GitLab's collapsible section markers
+	sectionPattern = regexp.MustCompile(`section_(start|end):\d+:[A-Za-z0-9_.-]+(\[[^\]]*\])?`)
+
+	// failurePattern matches lines that usually start a failing test or error block
+	failurePattern = regexp.MustCompile(`(?i)(--- FAIL|^FAIL\b|\bFAILED\b|panic:|\berror(\[|:)|Traceback \(most recent call last\)|AssertionError|✕|✗|\bERR!)`)
+)
+
+// Strip removes ANSI escapes, GitLab section markers and carriage-return
+// overwrites (progress bars), returning plain log lines
+func Strip(log string) []string {
+	log = ansiPattern.ReplaceAllString(log, "")
+	log = sectionPattern.ReplaceAllString(log, "")
+
+	rawLines := strings.Split(log, "\n")
+	lines := make([]string, 0, len(rawLines))
+	for _, line := range rawLines {
+		// Only the text after the last carriage return is visible in a terminal
+		if idx := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		lines = append(lines, strings.TrimRight(line, "\r \t"))
+	}
+
+	return lines
+}
+
+// StripString is Strip joined back into a single plain-text log
+func StripString(log string) string {
+	return strings.Join(Strip(log), "\n")
+}
+
+// Distill reduces a CI job log to what Claude needs to fix the failure: the
+// blocks around failure markers plus the tail of the log, with ANSI codes
+// stripped and repeated lines collapsed
+func Distill(log string, opts Options) string {
+	lines := Strip(log)
+
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !failurePattern.MatchString(line) {
+			continue
+		}
+		for j := i; j < len(lines) && j <= i+opts.BlockLines; j++ {
+			keep[j] = true
+		}
+	}
+
+	tailStart := len(lines) - opts.TailLines
+	if tailStart < 0 {
+		tailStart = 0
+	}
+	for i := tailStart; i < len(lines); i++ {
+		keep[i] = true
+	}
+
+	var kept []string
+	skipped := 0
+	for i, line := range lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			kept = append(kept, fmt.Sprintf("... (%d lines omitted) ...", skipped))
+			skipped = 0
+		}
+		kept = append(kept, line)
+	}
+
+	out := strings.Join(collapseRepeats(kept), "\n")
+	out = strings.TrimSpace(out)
+
+	// Prefer the end of the log when the cap is hit, that is where the failure summary lives
+	if opts.MaxChars > 0 && len(out) > opts.MaxChars {
+		out = "... (truncated) ...\n" + tail(out, opts.MaxChars)
+	}
+
+	return out
+}
+
+// tail returns at most maxBytes from the end of text, starting at a line
+// boundary, or at a rune boundary when the cut falls in the last line
+func tail(text string, maxBytes int) string {
+	cut := text[len(text)-maxBytes:]
+	if text[len(text)-maxBytes-1] == '\n' {
+		return cut
+	}
+	if i := strings.IndexByte(cut, '\n'); i >= 0 {
+		return cut[i+1:]
+	}
+	for len(cut) > 0 && !utf8.RuneStart(cut[0]) {
+		cut = cut[1:]
+	}
+	return cut
+}
+
+// collapseRepeats folds runs of identical lines into one annotated line
+func collapseRepeats(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && lines[j] == lines[i] {
+			j++
+		}
+
+		if count := j - i; count > 2 {
+			out = append(out, fmt.Sprintf("%s (repeated %d times)", lines[i], count))
+		} else {
+			out = append(out, lines[i:j]...)
+		}
+		i = j
+	}
+	return out
+}
@@ -0,0 +1,245 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables passing the approval policy to the hook
+const (
+	approvalPolicyEnv     = "AUTOMAGIC_APPROVAL_POLICY"
+	approvalCheckpointEnv = "AUTOMAGIC_CHECKPOINT"
+)
+
+// checkpointFile is written in the checkout's .git directory when the hook
+// holds an action for approval
+const checkpointFile = "automagic-checkpoint"
+
+// ApprovalPolicy lists the actions a session may only take once a human has
+// approved them on the issue. Sessions run with a PreToolUse hook that blocks
+// these tool calls and records a checkpoint.
+type ApprovalPolicy struct {
+	MigrationPaths []string // Database migration files, see matchesPath
+	CIPaths        []string // CI configuration files, see matchesPath
+	DeleteLines    int      // Deleting a file longer than this needs approval, 0 never does
+	Approved       []string // Actions already approved on the issue
+}
+
+// Check returns the action a tool call takes that needs approval, or "".
+// The action describes the call for the approval request and identifies it
+// when it is approved.
+func (p *ApprovalPolicy) Check(tool string, input map[string]interface{}, workingDir string) string {
+	action := p.action(tool, input, workingDir)
+	for _, approved := range p.Approved {
+		if approved == action {
+			return ""
+		}
+	}
+	return action
+}
+
+// action returns the action of a tool call that falls under the policy
+func (p *ApprovalPolicy) action(tool string, input map[string]interface{}, workingDir string) string {
+	switch tool {
+	case "Write", "Edit", "MultiEdit":
+		path, _ := input["file_path"].(string)
+		rel := relativePath(path, workingDir)
+		switch {
+		case rel == "":
+		case matchesAnyPath(p.MigrationPaths, rel):
+			return fmt.Sprintf("write the database migration `%s`", rel)
+		case matchesAnyPath(p.CIPaths, rel):
+			return fmt.Sprintf("change the CI configuration `%s`", rel)
+		}
+
+	case "Bash":
+		command, _ := input["command"].(string)
+		if p.DeleteLines <= 0 {
+			return ""
+		}
+		for _, path := range deletedPaths(command) {
+			rel := relativePath(path, workingDir)
+			if rel != "" && countLines(filepath.Join(workingDir, rel)) > p.DeleteLines {
+				return fmt.Sprintf("delete `%s`", rel)
+			}
+		}
+	}
+	return ""
+}
+
+// relativePath returns path relative to workingDir, "" when it is outside it
+func relativePath(path, workingDir string) string {
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	rel, err := filepath.Rel(workingDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchesAnyPath reports whether a relative path matches one of the
+// patterns. A pattern without a slash matches any file or directory name in
+// the path (migrations, *.sql); one with a slash matches the path or one of
+// its parent directories (db/migrate, .github/workflows/*.yml).
+func matchesAnyPath(patterns []string, rel string) bool {
+	segments := strings.Split(rel, "/")
+	for _, pattern := range patterns {
+		for i, segment := range segments {
+			candidate := segment
+			if strings.Contains(pattern, "/") {
+				candidate = strings.Join(segments[:i+1], "/")
+			}
+			if ok, _ := filepath.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deletedPaths returns the files a shell command removes with rm, git rm or
+// unlink. Only simple commands are understood; anything else is let through.
+func deletedPaths(command string) []string {
+	var paths []string
+	for _, part := range strings.FieldsFunc(command, func(r rune) bool { return r == ';' || r == '&' || r == '|' || r == '\n' }) {
+		fields := strings.Fields(part)
+		if len(fields) > 1 && fields[0] == "git" && fields[1] == "rm" {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || (fields[0] != "rm" && fields[0] != "unlink") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				paths = append(paths, strings.Trim(field, `"'`))
+			}
+		}
+	}
+	return paths
+}
+
+// countLines returns the number of lines in a file, 0 if it cannot be read
+func countLines(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+// checkpointPath returns where the hook records a held action for a checkout
+func checkpointPath(workingDir string) string {
+	return filepath.Join(workingDir, ".git", checkpointFile)
+}
+
+// ApprovalCommand returns the extra arguments and environment that run a
+// session in workingDir with the approval hook. executable is the automagic
+// binary, which runs the hook as "automagic approval-hook".
+func ApprovalCommand(policy *ApprovalPolicy, executable, workingDir string) ([]string, []string, error) {
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode approval policy: %v", err)
+	}
+	settings, err := json.Marshal(map[string]interface{}{
+		"hooks": map[string]interface{}{
+			"PreToolUse": []interface{}{map[string]interface{}{
+				"matcher": "Write|Edit|MultiEdit|Bash",
+				"hooks": []interface{}{map[string]interface{}{
+					"type":    "command",
+					"command": "'" + strings.ReplaceAll(executable, "'", `'\''`) + "' approval-hook",
+				}},
+			}},
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode hook settings: %v", err)
+	}
+
+	args := []string{"--settings", string(settings)}
+	env := []string{
+		approvalPolicyEnv + "=" + string(encoded),
+		approvalCheckpointEnv + "=" + checkpointPath(workingDir),
+	}
+	return args, env, nil
+}
+
+// RequireApproval makes the session stop at actions the policy holds for approval
+func (process *Process) RequireApproval(policy *ApprovalPolicy, executable string) error {
+	args, env, err := ApprovalCommand(policy, executable, process.WorkingDir)
+	if err != nil {
+		return err
+	}
+	process.Cmd.Args = append(process.Cmd.Args, args...)
+	process.Cmd.Env = append(process.Cmd.Env, env...)
+	process.Approval = policy
+	// A checkpoint left by a session that never got approved is stale
+	os.Remove(checkpointPath(process.WorkingDir))
+	return nil
+}
+
+// TakeCheckpoint returns and clears the action the hook held in a checkout
+func TakeCheckpoint(workingDir string) (string, bool) {
+	path := checkpointPath(workingDir)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	os.Remove(path)
+	return strings.TrimSpace(string(content)), true
+}
+
+// RunApprovalHook runs the PreToolUse hook: it reads the tool call Claude is
+// about to make from stdin and, when the policy in the environment holds it
+// for approval, records a checkpoint and blocks the call with exit code 2.
+// The message on stderr goes back to the model.
+func RunApprovalHook(stdin io.Reader, stderr io.Writer) int {
+	var call struct {
+		ToolName  string                 `json:"tool_name"`
+		ToolInput map[string]interface{} `json:"tool_input"`
+		Cwd       string                 `json:"cwd"`
+	}
+	if err := json.NewDecoder(stdin).Decode(&call); err != nil {
+		fmt.Fprintf(stderr, "automagic approval hook: failed to read the tool call: %v\n", err)
+		return 1
+	}
+	var policy ApprovalPolicy
+	if err := json.Unmarshal([]byte(os.Getenv(approvalPolicyEnv)), &policy); err != nil {
+		return 0
+	}
+
+	// Paths are judged relative to the checkout, wherever the session cd'ed to
+	workingDir := call.Cwd
+	if checkpoint := os.Getenv(approvalCheckpointEnv); checkpoint != "" {
+		workingDir = filepath.Dir(filepath.Dir(checkpoint))
+	}
+	action := policy.Check(call.ToolName, call.ToolInput, workingDir)
+	if action == "" {
+		return 0
+	}
+
+	if err := os.WriteFile(checkpointPath(workingDir), []byte(action), 0644); err != nil {
+		fmt.Fprintf(stderr, "automagic approval hook: failed to record the checkpoint: %v\n", err)
+	}
+	fmt.Fprintf(stderr, "This action needs a human's approval first: %s. Approval has been requested on the issue. "+
+		"Do not take this action another way or work around it. Stop now and end your turn; "+
+		"the session will be resumed once the action is approved.\n", action)
+	return 2
+}
@@ -0,0 +1,63 @@
+package claude
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PrepareIssueBranch records in the project's checkout the branch an issue
+// session should push to and the branch of the earlier attempt it replaces, so
+// the issue prompt can name them. An empty branch clears a choice left by an
+// earlier run. Dry runs change nothing.
+func PrepareIssueBranch(projectPath, gitlabURL string, issueIID int, branch, previousBranch string, dryRun bool) error {
+	repoDir, _, err := ensureRepositoryExists(projectPath, gitlabURL, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to ensure repository exists: %v", err)
+	}
+	if dryRun {
+		return nil
+	}
+
+	section := issueBranchSection(issueIID)
+	if branch == "" {
+		gitIn(repoDir, "config", "--remove-section", section)
+		return nil
+	}
+	if err := gitIn(repoDir, "config", section+".branch", branch); err != nil {
+		return fmt.Errorf("failed to record issue branch: %v", err)
+	}
+	if previousBranch == "" {
+		gitIn(repoDir, "config", "--unset", section+".previous")
+	} else if err := gitIn(repoDir, "config", section+".previous", previousBranch); err != nil {
+		return fmt.Errorf("failed to record previous issue branch: %v", err)
+	}
+	return nil
+}
+
+// IssueBranch returns the branch recorded for an issue in the repository in
+// repoDir, falling back to issue-N, and the branch of the attempt it replaces
+func IssueBranch(repoDir string, issueIID int) (branch, previousBranch string) {
+	section := issueBranchSection(issueIID)
+	branch = gitConfig(repoDir, section+".branch")
+	if branch == "" {
+		branch = fmt.Sprintf("issue-%d", issueIID)
+	}
+	return branch, gitConfig(repoDir, section+".previous")
+}
+
+// issueBranchSection is the git config section holding an issue's branches
+func issueBranchSection(issueIID int) string {
+	return fmt.Sprintf("automagic.issue-%d", issueIID)
+}
+
+// gitConfig reads a git config value in dir, returning "" when it is unset
+func gitConfig(dir, key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,86 @@
+package claude
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// BundleLines is how many lines of session output a failure bundle keeps
+const BundleLines = 200
+
+// FailureBundle is the state a failed session left behind: the end of its
+// output and the changes in its worktree, for debugging the failure remotely
+type FailureBundle struct {
+	Failure    FailureKind
+	Error      string
+	Output     []string // Last BundleLines lines of output, oldest first
+	Status     string   // git status --short of the worktree
+	DiffStat   string   // git diff --stat of the worktree
+	CapturedAt time.Time
+}
+
+// CaptureFailureBundle collects the failure bundle of a session that ran in
+// workingDir. Git errors are recorded in place of the output they prevented.
+func CaptureFailureBundle(workingDir string, failure FailureKind, errorText string, output []string) *FailureBundle {
+	bundle := &FailureBundle{
+		Failure:    failure,
+		Error:      errorText,
+		Output:     output,
+		CapturedAt: time.Now(),
+	}
+	if len(bundle.Output) > BundleLines {
+		bundle.Output = bundle.Output[len(bundle.Output)-BundleLines:]
+	}
+
+	var err error
+	if bundle.Status, err = gitReport(workingDir, "status", "--short"); err != nil {
+		bundle.Status = fmt.Sprintf("git status failed: %v", err)
+	}
+	if bundle.DiffStat, err = gitReport(workingDir, "diff", "--stat", "HEAD"); err != nil {
+		bundle.DiffStat = fmt.Sprintf("git diff --stat failed: %v", err)
+	}
+	return bundle
+}
+
+// gitReport runs a git command in dir and returns its output without the
+// final newline, keeping the leading spaces git aligns columns with
+func gitReport(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return strings.TrimRight(string(output), "\n"), err
+}
+
+// Output returns the last lines the session printed, across its attempts
+func (process *Process) Output() []string {
+	if process.transcript == nil {
+		return nil
+	}
+	return process.transcript.Lines()
+}
+
+// String renders the bundle as plain text
+func (b *FailureBundle) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Failure: %s\n", orNone(string(b.Failure)))
+	fmt.Fprintf(&sb, "Error: %s\n", orNone(b.Error))
+	fmt.Fprintf(&sb, "Captured: %s\n", b.CapturedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "\n== git status --short ==\n%s\n", orNone(b.Status))
+	fmt.Fprintf(&sb, "\n== git diff --stat ==\n%s\n", orNone(b.DiffStat))
+	fmt.Fprintf(&sb, "\n== last %d lines of output ==\n", len(b.Output))
+	for _, line := range b.Output {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// orNone stands in for empty bundle fields
+func orNone(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,187 @@
+package claude
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// Kinds of environment a session can run in besides the host
+const (
+	EnvironmentDevcontainer = "devcontainer"
+	EnvironmentNix          = "nix"
+)
+
+// forwardedEnv lists the variables passed into a devcontainer, which does not
+// inherit the daemon's environment
+var forwardedEnv = []string{"GITLAB_URL", "GITLAB_TOKEN", "ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN"}
+
+// Environment runs session commands inside a project's development
+// environment rather than directly on the host
+type Environment struct {
+	Kind   string
+	prefix []string // Command that runs the rest of its arguments inside the environment
+}
+
+// DetectEnvironment returns the kind of environment the checkout in repoDir
+// declares, or "" if none. mode is auto, devcontainer or nix; anything else
+// turns environments off.
+func DetectEnvironment(repoDir, mode string) string {
+	hasDevcontainer := fileExists(filepath.Join(repoDir, ".devcontainer", "devcontainer.json")) ||
+		fileExists(filepath.Join(repoDir, ".devcontainer.json"))
+	hasFlake := fileExists(filepath.Join(repoDir, "flake.nix"))
+
+	switch {
+	case (mode == "auto" || mode == EnvironmentDevcontainer) && hasDevcontainer:
+		return EnvironmentDevcontainer
+	case (mode == "auto" || mode == EnvironmentNix) && hasFlake:
+		return EnvironmentNix
+	}
+	return ""
+}
+
+// PrepareEnvironment builds the environment of the given kind for the
+// checkout in repoDir and returns it ready to run commands. Built
+// environments are cached per project under cacheDir and rebuilt only when
+// their definition changes.
+func PrepareEnvironment(kind, repoDir, projectPath, cacheDir string) (*Environment, error) {
+	dir := filepath.Join(cacheDir, kind, strings.ReplaceAll(projectPath, "/", "_"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create environment cache: %v", err)
+	}
+
+	switch kind {
+	case EnvironmentNix:
+		return prepareNix(repoDir, dir)
+	case EnvironmentDevcontainer:
+		return prepareDevcontainer(repoDir, projectPath, dir)
+	}
+	return nil, fmt.Errorf("unknown environment %q", kind)
+}
+
+// prepareNix records the flake's dev shell in a profile, which keeps it from
+// being garbage collected and lets later sessions enter it without evaluating
+// the flake again
+func prepareNix(repoDir, cacheDir string) (*Environment, error) {
+	if _, err := exec.LookPath("nix"); err != nil {
+		return nil, fmt.Errorf("flake.nix found but nix is not installed")
+	}
+	profile := filepath.Join(cacheDir, "profile")
+	hash := definitionHash(repoDir, "flake.nix", "flake.lock")
+
+	if !cacheCurrent(cacheDir, hash) || !fileExists(profile) {
+		logging.Infof("Building nix environment of %s...", repoDir)
+		cmd := exec.Command("nix", "develop", repoDir, "--profile", profile, "--command", "true")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("nix develop failed: %v", err)
+		}
+		recordCache(cacheDir, hash)
+	}
+	return &Environment{Kind: EnvironmentNix, prefix: []string{"nix", "develop", profile, "--command"}}, nil
+}
+
+// prepareDevcontainer starts the project's dev container, reusing the one
+// from earlier sessions unless its configuration changed
+func prepareDevcontainer(repoDir, projectPath, cacheDir string) (*Environment, error) {
+	if _, err := exec.LookPath("devcontainer"); err != nil {
+		return nil, fmt.Errorf("a devcontainer is configured but the devcontainer CLI is not installed")
+	}
+	label := "automagic.project=" + projectPath
+	hash := definitionHash(repoDir, ".devcontainer.json", ".devcontainer/devcontainer.json", ".devcontainer/Dockerfile")
+
+	args := []string{"up", "--workspace-folder", repoDir, "--id-label", label}
+	rebuild := !cacheCurrent(cacheDir, hash)
+	if rebuild {
+		args = append(args, "--remove-existing-container")
+		logging.Infof("Building dev container of %s...", repoDir)
+	}
+	cmd := exec.Command("devcontainer", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("devcontainer up failed: %v", err)
+	}
+	if rebuild {
+		recordCache(cacheDir, hash)
+	}
+	return &Environment{
+		Kind:   EnvironmentDevcontainer,
+		prefix: []string{"devcontainer", "exec", "--workspace-folder", repoDir, "--id-label", label},
+	}, nil
+}
+
+// Wrap rebuilds cmd so it runs inside the environment, bound to ctx
+func (e *Environment) Wrap(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
+	args := append([]string{}, e.prefix[1:]...)
+	if e.Kind == EnvironmentDevcontainer {
+		for _, name := range forwardedEnv {
+			if value := lookupEnv(cmd.Env, name); value != "" {
+				args = append(args, "--remote-env", name+"="+value)
+			}
+		}
+	}
+	args = append(args, cmd.Args...)
+
+	next := exec.CommandContext(ctx, e.prefix[0], args...)
+	next.Dir = cmd.Dir
+	next.Env = cmd.Env
+	next.Stdout = cmd.Stdout
+	next.Stderr = cmd.Stderr
+	return next
+}
+
+// lookupEnv returns a variable of env, or of the daemon's environment when
+// env is nil as exec.Cmd treats it
+func lookupEnv(env []string, name string) string {
+	if env == nil {
+		return os.Getenv(name)
+	}
+	value := ""
+	for _, entry := range env {
+		if v, ok := strings.CutPrefix(entry, name+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
+
+// definitionHash fingerprints the files defining an environment
+func definitionHash(repoDir string, files ...string) string {
+	h := sha256.New()
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(repoDir, file))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", file, len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheCurrent reports whether the environment cached in cacheDir was built
+// from definitions with the given hash
+func cacheCurrent(cacheDir, hash string) bool {
+	recorded, err := os.ReadFile(filepath.Join(cacheDir, "hash"))
+	return err == nil && strings.TrimSpace(string(recorded)) == hash
+}
+
+func recordCache(cacheDir, hash string) {
+	if err := os.WriteFile(filepath.Join(cacheDir, "hash"), []byte(hash+"\n"), 0644); err != nil {
+		logging.Warnf("Failed to record environment cache: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,169 @@
+package claude
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"regexp"
+	"sync"
+)
+
+// FailureKind classifies why a Claude CLI run failed, so callers can pick a
+// recovery path instead of treating every failure alike
+type FailureKind string
+
+const (
+	FailureNone            FailureKind = ""
+	FailureAuth            FailureKind = "auth_expired"
+	FailureRateLimit       FailureKind = "rate_limit"
+	FailureCapacity        FailureKind = "capacity"
+	FailureContextOverflow FailureKind = "context_overflow"
+	FailureMCPConfig       FailureKind = "mcp_config"
+	FailureInterrupted     FailureKind = "interrupted"
+	FailureStalled         FailureKind = "stalled"   // Stopped by the watchdog, no output
+	FailureMaxTurns        FailureKind = "max_turns" // Stopped by the watchdog, too many turns
+	FailureApproval        FailureKind = "approval"  // Stopped at an action awaiting approval
+	FailurePaused          FailureKind = "paused"    // Stopped at a tool boundary on request
+	FailurePanic           FailureKind = "panic"     // automagic panicked while running the session
+	FailureCancelled       FailureKind = "cancelled" // Stopped on request through the control API
+	FailureTimeout         FailureKind = "timeout"   // Stopped for running past the session timeout
+	FailureUnknown         FailureKind = "unknown"
+)
+
+// failurePatterns are checked in order; the first match wins
+var failurePatterns = []struct {
+	kind    FailureKind
+	pattern *regexp.Regexp
+}{
+	{FailureAuth, regexp.MustCompile(`(?i)authentication_error|invalid api key|invalid bearer token|oauth token has expired|token expired|please run /login|not logged in|\b401\b`)},
+	{FailureRateLimit, regexp.MustCompile(`(?i)rate_limit_error|rate limit|usage limit|too many requests|\b429\b`)},
+	{FailureCapacity, capacityPattern},
+	{FailureContextOverflow, regexp.MustCompile(`(?i)prompt is too long|context (length|window)|maximum context|too many tokens|exceed(s|ed)? .*context`)},
+	{FailureMCPConfig, regexp.MustCompile(`(?i)mcp[^\n]*(config|invalid|parse|schema)|invalid mcp|\.mcp\.json`)},
+}
+
+// ClassifyFailure maps a CLI exit code and the error text seen in its output
+// (error events and stderr) to a FailureKind
+func ClassifyFailure(exitCode int, errorText string) FailureKind {
+	if exitCode == 0 {
+		return FailureNone
+	}
+
+	// Killed by a signal (SIGINT/SIGTERM), usually daemon shutdown or cancellation
+	if exitCode == -1 || exitCode == 130 || exitCode == 143 {
+		return FailureInterrupted
+	}
+
+	for _, candidate := range failurePatterns {
+		if candidate.pattern.MatchString(errorText) {
+			return candidate.kind
+		}
+	}
+	return FailureUnknown
+}
+
+// ExitCode returns the exit code carried by an error from exec.Cmd.Wait
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// OutputTail keeps the last bytes written to it, for classifying failures
+// from CLI output without holding the whole stream in memory
+type OutputTail struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func NewOutputTail(limit int) *OutputTail {
+	return &OutputTail{limit: limit}
+}
+
+func (t *OutputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+	return len(p), nil
+}
+
+func (t *OutputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// LineTail keeps the last lines written to it, each cut to maxTailLineLength,
+// for the failure bundle of a session
+type LineTail struct {
+	mu      sync.Mutex
+	limit   int
+	lines   []string
+	partial []byte
+}
+
+// maxTailLineLength bounds a kept line; stream-json lines can hold whole files
+const maxTailLineLength = 2000
+
+func NewLineTail(limit int) *LineTail {
+	return &LineTail{limit: limit}
+}
+
+// Add keeps a complete line
+func (t *LineTail) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.add(line)
+}
+
+func (t *LineTail) add(line string) {
+	if len(line) > maxTailLineLength {
+		line = line[:maxTailLineLength] + " [...]"
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.limit {
+		t.lines = t.lines[len(t.lines)-t.limit:]
+	}
+}
+
+// Write keeps the lines of p, holding back an unfinished last line
+func (t *LineTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.add(string(data[:i]))
+		data = data[i+1:]
+	}
+	if len(data) > maxTailLineLength {
+		data = data[:maxTailLineLength]
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// Lines returns the kept lines, oldest first, with an unfinished last line
+func (t *LineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string(nil), t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+	}
+	return lines
+}
@@ -0,0 +1,71 @@
+package claude
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// ForkRemote is the git remote issue branches are pushed to when the bot
+// works through a fork of the project
+const ForkRemote = "fork"
+
+// forkConfigKey records the fork's project path in the repository's git config
+// so prompts can name it without another API call
+const forkConfigKey = "automagic.fork"
+
+// PrepareForkRemote makes sure the project is checked out as an issue session
+// would find it and points its fork remote at forkPath. An empty forkPath
+// removes a fork remote left by an earlier run. Dry runs change nothing.
+func PrepareForkRemote(projectPath, gitlabURL, forkPath string, dryRun bool) error {
+	repoDir, _, err := ensureRepositoryExists(projectPath, gitlabURL, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to ensure repository exists: %v", err)
+	}
+	if dryRun {
+		if forkPath != "" {
+			logging.Infof("[DRY RUN] Would push issue branches to fork %s", forkPath)
+		}
+		return nil
+	}
+	return SetForkRemote(repoDir, gitlabURL, forkPath)
+}
+
+// SetForkRemote points the fork remote of the repository in repoDir at
+// forkPath, or removes it when forkPath is empty
+func SetForkRemote(repoDir, gitlabURL, forkPath string) error {
+	if forkPath == "" {
+		if ForkPath(repoDir) == "" {
+			return nil
+		}
+		gitIn(repoDir, "remote", "remove", ForkRemote)
+		gitIn(repoDir, "config", "--unset", forkConfigKey)
+		return nil
+	}
+
+	forkURL := fmt.Sprintf("%s/%s.git", strings.TrimSuffix(gitlabURL, "/"), forkPath)
+	if err := gitIn(repoDir, "remote", "set-url", ForkRemote, forkURL); err != nil {
+		if err := gitIn(repoDir, "remote", "add", ForkRemote, forkURL); err != nil {
+			return fmt.Errorf("failed to add fork remote: %v", err)
+		}
+	}
+	if err := gitIn(repoDir, "config", forkConfigKey, forkPath); err != nil {
+		return fmt.Errorf("failed to record fork: %v", err)
+	}
+	return nil
+}
+
+// ForkPath returns the fork the repository in repoDir pushes issue branches
+// to, or "" when branches go to origin
+func ForkPath(repoDir string) string {
+	return gitConfig(repoDir, forkConfigKey)
+}
+
+// gitIn runs a git command in dir, discarding its output
+func gitIn(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd.Run()
+}
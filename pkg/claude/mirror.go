@@ -0,0 +1,123 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// mirrorStamp is touched in a mirror each time it is fetched
+const mirrorStamp = "automagic-fetched"
+
+// mirrorProjectKey records a mirror's project path in its git config
+const mirrorProjectKey = "automagic.project"
+
+// Mirrors keeps a bare mirror of each project that checkouts are cloned
+// against with --reference. Clones borrow the mirror's objects through git
+// alternates, so they are nearly instant and add little to disk usage.
+type Mirrors struct {
+	Dir      string                   // Holds <group>_<project>.git mirrors
+	Refresh  time.Duration            // How often mirrors are fetched
+	Projects map[string]time.Duration // Refresh overrides per project path
+
+	mu sync.Mutex
+}
+
+// mirrors is the set used when cloning, nil to clone directly. UseMirrors is
+// meant to be called once at startup, before any session is started.
+var mirrors *Mirrors
+
+// UseMirrors makes clones reference the mirrors in m
+func UseMirrors(m *Mirrors) {
+	mirrors = m
+}
+
+// ActiveMirrors returns the mirrors used when cloning, or nil
+func ActiveMirrors() *Mirrors {
+	return mirrors
+}
+
+// path returns the directory of a project's mirror
+func (m *Mirrors) path(projectPath string) string {
+	return filepath.Join(m.Dir, strings.ReplaceAll(projectPath, "/", "_")+".git")
+}
+
+// refreshInterval returns how often a project's mirror is fetched
+func (m *Mirrors) refreshInterval(projectPath string) time.Duration {
+	if interval, ok := m.Projects[projectPath]; ok {
+		return interval
+	}
+	return m.Refresh
+}
+
+// Sync creates a project's mirror, or fetches into it when it is older than
+// its refresh interval, and returns its path
+func (m *Mirrors) Sync(projectPath, cloneURL string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := m.path(projectPath)
+	stamp := filepath.Join(path, mirrorStamp)
+	info, err := os.Stat(stamp)
+	switch {
+	case os.IsNotExist(err) && !fileExists(path):
+		if err := os.MkdirAll(m.Dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create mirror directory: %v", err)
+		}
+		logging.Infof("Creating mirror of %s in %s...", projectPath, path)
+		if err := runGit("", "clone", "--mirror", cloneURL, path); err != nil {
+			os.RemoveAll(path)
+			return "", fmt.Errorf("failed to create mirror: %v", err)
+		}
+		// Checkouts borrow objects from the mirror, so it must never drop any
+		if err := gitIn(path, "config", "gc.auto", "0"); err != nil {
+			return "", fmt.Errorf("failed to configure mirror: %v", err)
+		}
+		gitIn(path, "config", mirrorProjectKey, projectPath)
+	case err == nil && time.Since(info.ModTime()) < m.refreshInterval(projectPath):
+		return path, nil
+	default:
+		if err := runGit(path, "fetch", "--prune", "origin"); err != nil {
+			return "", fmt.Errorf("failed to refresh mirror: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(stamp, nil, 0644); err != nil {
+		return "", fmt.Errorf("failed to record mirror refresh: %v", err)
+	}
+	return path, nil
+}
+
+// RefreshStale fetches every mirror older than its refresh interval
+func (m *Mirrors) RefreshStale() {
+	entries, err := os.ReadDir(m.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(m.Dir, entry.Name())
+		projectPath := gitConfig(path, mirrorProjectKey)
+		cloneURL := gitConfig(path, "remote.origin.url")
+		if !entry.IsDir() || projectPath == "" || cloneURL == "" {
+			continue
+		}
+		if _, err := m.Sync(projectPath, cloneURL); err != nil {
+			logging.Warnf("Mirror of %s: %v", projectPath, err)
+		}
+	}
+}
+
+// runGit runs a git command in dir with its output on the console
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
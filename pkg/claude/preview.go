@@ -0,0 +1,105 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// previewDisallowedTools are withheld from preview sessions so they cannot
+// post to GitLab or push what they change
+var previewDisallowedTools = []string{"mcp__MCP_GitLab", "Bash(glab:*)", "Bash(git push:*)"}
+
+// previewNote is appended to the prompt of a preview session
+const previewNote = `
+
+# Preview Run
+
+This is a preview run: the changes you make stay in this working directory and are shown to a human instead of being published. Commit your work locally, but do not push, comment on the issue or open a merge request.`
+
+// Preview is a throwaway clone of a repository a session runs in to show
+// what it would change
+type Preview struct {
+	Dir    string // Directory of the clone
+	Branch string // Branch the session starts on
+	base   string // Commit the branch starts from
+}
+
+// StartPreview moves a process into a preview: a local clone of its
+// repository under parent on a fresh branch, whose pushes are refused. The
+// GitLab tools are withheld from the session. Remove the preview when done.
+func StartPreview(process *Process, parent string) (*Preview, error) {
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create preview directory: %v", err)
+	}
+	dir, err := os.MkdirTemp(parent, fmt.Sprintf("preview-%d-", process.IssueNum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create preview directory: %v", err)
+	}
+	preview := &Preview{Dir: dir, Branch: fmt.Sprintf("automagic/preview-%d", process.IssueNum)}
+
+	steps := [][]string{
+		{"clone", "--quiet", "--shared", process.WorkingDir, dir},
+		{"-C", dir, "remote", "set-url", "--push", "origin", "preview-push-disabled"},
+		{"-C", dir, "checkout", "--quiet", "-b", preview.Branch},
+	}
+	for _, args := range steps {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			preview.Remove()
+			return nil, fmt.Errorf("failed to set up preview: git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	base, err := preview.git("rev-parse", "HEAD")
+	if err != nil {
+		preview.Remove()
+		return nil, err
+	}
+	preview.base = strings.TrimSpace(base)
+
+	args := process.Cmd.Args[1:]
+	for i, arg := range args {
+		if arg == "-p" && i+1 < len(args) {
+			args[i+1] = strings.ReplaceAll(args[i+1], process.WorkingDir, dir) + previewNote
+			break
+		}
+	}
+	args = append([]string{"--disallowedTools", strings.Join(previewDisallowedTools, " ")}, args...)
+	cmd := exec.Command(process.Cmd.Path, args...)
+	cmd.Args[0] = process.Cmd.Args[0]
+	cmd.Stderr = process.Cmd.Stderr
+	cmd.Env = process.Cmd.Env
+	cmd.Dir = dir
+	process.Cmd = cmd
+	process.WorkingDir = dir
+	return preview, nil
+}
+
+// Diff returns a unified diff of everything the session changed since the
+// preview started, committed or not
+func (p *Preview) Diff() (string, error) {
+	if _, err := p.git("add", "--all"); err != nil {
+		return "", err
+	}
+	return p.git("diff", "--cached", p.base)
+}
+
+// Commits returns the one-line log of the commits the session made
+func (p *Preview) Commits() (string, error) {
+	return p.git("log", "--oneline", p.base+"..HEAD")
+}
+
+// Remove deletes the preview's clone
+func (p *Preview) Remove() error {
+	return os.RemoveAll(p.Dir)
+}
+
+func (p *Preview) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.Dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed in preview: %v", args[0], err)
+	}
+	return string(output), nil
+}
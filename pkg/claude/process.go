@@ -2,8 +2,10 @@ package claude
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,6 +13,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/prompts"
 )
 
 type Process struct {
@@ -25,6 +30,43 @@ type Process struct {
 	WorkingDir       string
 	ClonedRepo       bool
 	OnCompletion     func(process *Process, success bool) error
+	// OnPanic is called instead of crashing when running the process or its
+	// completion callback panics; it runs on the panicking goroutine
+	OnPanic func(process *Process, recovered interface{})
+
+	Model         string // Model serving the session, from --model or the init event
+	FallbackModel string // Model to retry with when Model is over capacity
+	UsedFallback  bool
+	LastError     string      // Message of the last error event in the stream
+	Failure       FailureKind // Why the last attempt failed, if it did
+
+	StallTimeout time.Duration // Stop the session after this long without output, 0 disables
+	MaxTurns     int           // Stop the session after this many turns, 0 disables
+	Timeout      time.Duration // Stop the session after this long in all, 0 disables
+	Nudged       bool          // The session was resumed once with a continuation prompt
+	Attempts     int           // Runs of the command, more than one after a fallback or nudge
+	// OnTimeout is called when the session runs past Timeout, before it is
+	// stopped, so the issue can be told why
+	OnTimeout func(process *Process)
+
+	Environment *Environment  // Development environment the session runs in, nil for the host
+	WarmUp      time.Duration // Download dependencies for up to this long before the first attempt, 0 disables
+	Transcript  string        // File the stream-json output is appended to, "" for none
+
+	Approval   *ApprovalPolicy // Actions held for a human's approval, nil when none are
+	Checkpoint string          // Action the session was stopped at to await approval
+	Paused     string          // Why the session was paused at a tool boundary
+
+	statsMu       sync.Mutex
+	stats         SessionStats
+	awaitingModel time.Time   // When tool results were last sent to the model
+	turnLimit     int         // Turn count at which the current attempt is stopped
+	intervention  FailureKind // Why the watchdog stopped the current attempt
+	pauseReason   string      // Why a pause was requested, stops the session at the next tool boundary
+	transcript    *LineTail   // Last lines of output, for the failure bundle
+	usage         Usage       // Cost and turns reported by the attempts so far
+	plan          []PlanItem  // Latest todo list of the session
+	step          string      // Latest text the model wrote
 }
 
 type ProcessManager struct {
@@ -141,7 +183,7 @@ func ensureRepositoryExists(projectPath string, gitlabURL string, dryRun bool) (
 		// Verify it's a git repository
 		gitDir := filepath.Join(cwd, ".git")
 		if _, err := os.Stat(gitDir); err == nil {
-			fmt.Printf("Already in project directory: %s\n", cwd)
+			logging.Infof("Already in project directory: %s", cwd)
 			return cwd, false, nil // Not cloned, already existed
 		}
 	}
@@ -150,25 +192,33 @@ func ensureRepositoryExists(projectPath string, gitlabURL string, dryRun bool) (
 	projectDir := filepath.Join(cwd, projectName)
 	gitDir := filepath.Join(projectDir, ".git")
 	if _, err := os.Stat(gitDir); err == nil {
-		fmt.Printf("Found existing repository at: %s\n", projectDir)
+		logging.Infof("Found existing repository at: %s", projectDir)
 		return projectDir, false, nil // Not cloned, already existed
 	}
 
 	// Repository doesn't exist, need to clone
 	if dryRun {
-		fmt.Printf("[DRY RUN] Repository not found locally. Would clone %s\n", projectPath)
+		logging.Infof("[DRY RUN] Repository not found locally. Would clone %s", projectPath)
 		cloneURL := fmt.Sprintf("%s/%s.git", strings.TrimSuffix(gitlabURL, "/"), projectPath)
-		fmt.Printf("[DRY RUN] Clone command: git clone %s %s\n", cloneURL, projectName)
-		fmt.Printf("[DRY RUN] Would clone to: %s\n", projectDir)
+		logging.Infof("[DRY RUN] Clone command: git clone %s %s", cloneURL, projectName)
+		logging.Infof("[DRY RUN] Would clone to: %s", projectDir)
 		return projectDir, true, nil // Would be cloned in real mode
 	} else {
-		fmt.Printf("Repository not found locally. Cloning %s...\n", projectPath)
+		logging.Infof("Repository not found locally. Cloning %s...", projectPath)
 
 		// Construct clone URL
 		cloneURL := fmt.Sprintf("%s/%s.git", strings.TrimSuffix(gitlabURL, "/"), projectPath)
 
-		// Clone the repository
-		cmd := exec.Command("git", "clone", cloneURL, projectName)
+		// Clone the repository, borrowing objects from the project's mirror
+		args := []string{"clone", cloneURL, projectName}
+		if mirrors != nil {
+			if mirror, err := mirrors.Sync(projectPath, cloneURL); err != nil {
+				logging.Warnf("Cloning without a mirror: %v", err)
+			} else {
+				args = append(args, "--reference", mirror)
+			}
+		}
+		cmd := exec.Command("git", args...)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		cmd.Dir = cwd
@@ -177,7 +227,7 @@ func ensureRepositoryExists(projectPath string, gitlabURL string, dryRun bool) (
 			return "", false, fmt.Errorf("failed to clone repository: %v", err)
 		}
 
-		fmt.Printf("Successfully cloned repository to: %s\n", projectDir)
+		logging.Infof("Successfully cloned repository to: %s", projectDir)
 		return projectDir, true, nil // Was actually cloned
 	}
 }
@@ -253,6 +303,37 @@ func DetectProjectDirectory(projectPath string) (string, string, error) {
 	return cwd, "", nil
 }
 
+// IssuePromptData collects the issue template's data for a repository checked out in workingDir
+func IssuePromptData(issueNumber int, projectPath, username, workingDir string) prompts.IssueData {
+	moduleName := ""
+
+	// Check for go.mod in the repository
+	goModPath := filepath.Join(workingDir, "go.mod")
+	if content, err := os.ReadFile(goModPath); err == nil {
+		lines := strings.Split(string(content), "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "module ") {
+				moduleName = strings.TrimPrefix(line, "module ")
+				moduleName = strings.TrimSpace(moduleName)
+				break
+			}
+		}
+	}
+
+	branch, previousBranch := IssueBranch(workingDir, issueNumber)
+	return prompts.IssueData{
+		IssueIID:       issueNumber,
+		ProjectPath:    projectPath,
+		Username:       username,
+		WorkingDir:     workingDir,
+		ModuleName:     moduleName,
+		ForkPath:       ForkPath(workingDir),
+		Branch:         branch,
+		PreviousBranch: previousBranch,
+	}
+}
+
 func CreateProcess(issueNumber int, processID string, claudeCommand, claudeFlags, projectPath, username string, customPrompt ...string) (*Process, error) {
 	return CreateProcessWithCallback(issueNumber, processID, claudeCommand, claudeFlags, projectPath, username, nil, nil, customPrompt...)
 }
@@ -279,99 +360,15 @@ func CreateProcessWithCallbackAndGitlabDryRun(issueNumber int, processID string,
 
 	// Now detect project information from the repository directory
 	workingDir := repoDir
-	moduleName := ""
-
-	// Check for go.mod in the repository
-	goModPath := filepath.Join(repoDir, "go.mod")
-	if content, err := os.ReadFile(goModPath); err == nil {
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "module ") {
-				moduleName = strings.TrimPrefix(line, "module ")
-				moduleName = strings.TrimSpace(moduleName)
-				break
-			}
-		}
-	}
 
 	var prompt string
 	if len(customPrompt) > 0 && customPrompt[0] != "" {
 		prompt = customPrompt[0]
 	} else {
-		// Build project context information
-		projectInfo := fmt.Sprintf("- **GitLab Project Path**: `%s`\n- **Your Username**: @%s\n- **Current Working Directory**: `%s`", projectPath, username, workingDir)
-
-		if moduleName != "" {
-			projectInfo += fmt.Sprintf("\n- **Go Module**: `%s`", moduleName)
+		prompt, err = prompts.Render(prompts.WorkflowIssue, IssuePromptData(issueNumber, projectPath, username, workingDir))
+		if err != nil {
+			return nil, err
 		}
-
-		prompt = fmt.Sprintf(`# Look at issue %d and fix it
-## Project Information
-%s
-
-Always use Gitlab MCP for Gitlab related tasks.
-Use git for commit and push.
-
-**Important**: The repository has been verified/cloned and you are now in the project directory.
-
-Complexity Index:
-T4: 1-3 minutes
-T3: 4-6 minutes  
-T2: 7-15 minutes
-T1: 15+ minutes
-
-## MANDATORY Workflow - Follow these steps in order:
-
-### 1. **Retrieve & Analyze Issue** 
-   - Get issue details using GitLab MCP
-   - Read the issue description thoroughly
-   - Read ALL existing comments on the issue to understand context and any previous attempts
-   - Analyze the requirements and acceptance criteria
-
-### 2. **Create and Post Implementation Plan** (REQUIRED)
-   - Search the codebase to understand the current implementation
-   - Identify files that need to be modified
-   - Create a detailed plan with:
-     - Summary of the issue
-     - List of files to be modified
-     - Step-by-step implementation approach
-     - Testing strategy
-   - **POST THIS PLAN AS A COMMENT ON THE GITLAB ISSUE using GitLab MCP**
-   - Format the plan clearly with markdown
-
-### 3. **Verify Current State**
-   - Run 'git status' to check current branch and changes
-   - Run 'git pull' to ensure you have the latest changes
-
-### 4. **Create Branch**
-   - Create a new branch for the issue: `+"`git checkout -b issue-{issue_number}`"+`
-
-### 5. **Implement Changes**
-   - Follow your posted plan
-   - Make the necessary code changes
-   - Test changes locally
-   - Commit changes with clear commit messages
-
-### 6. **Push & Create MR**
-   - Push branch: `+"`git push -u origin issue-{issue_number}`"+`
-   - Create merge request using GitLab MCP
-   - Reference the issue in the MR description
-
-### 7. **Final Update & Human Review**
-   - Comment on the issue with the MR link and completion status
-   - The issue will be automagically marked as "waiting_human_review"
-   - Humans can now review your work and provide feedback
-   - If they add comments with feedback, I will automagically resume this session to iterate
-
-**IMPORTANT**: You MUST post your implementation plan to the GitLab issue before making any code changes. This ensures transparency and allows for feedback before implementation begins.
-
-**Human Review Process**: After completion, the issue enters a review phase where:
-- The issue label changes from "picked_up_by_claude" to "waiting_human_review"
-- Humans can review the code, test the changes, and provide feedback
-- Any new comments will automagically trigger a session resume with the feedback context
-- Only when humans are satisfied should they manually change the label to "solved"
-`, issueNumber, projectInfo)
 	}
 
 	// Set up environment first - this is crucial for MCP server initialization
@@ -414,6 +411,7 @@ T1: 15+ minutes
 		WorkingDir:       workingDir,
 		ClonedRepo:       wasCloned,
 		OnCompletion:     onCompletion,
+		Model:            modelFromFlags(args),
 	}
 
 	return process, nil
@@ -422,8 +420,8 @@ T1: 15+ minutes
 // cleanupRepositoryState cleans up the repository to prepare it for the next session
 // DISABLED: Repository cleanup is now disabled to allow reuse of worktree issues
 func cleanupRepositoryState(process *Process) {
-	fmt.Printf("Repository cleanup disabled - leaving repository state unchanged at: %s\n", process.WorkingDir)
-	fmt.Printf("Note: This allows reuse of existing worktree issues and preserves work in progress\n")
+	logging.Infof("Repository cleanup disabled - leaving repository state unchanged at: %s", process.WorkingDir)
+	logging.Infof("Note: This allows reuse of existing worktree issues and preserves work in progress")
 
 	// All cleanup operations are now disabled:
 	// - No git reset --hard HEAD
@@ -483,9 +481,9 @@ func cleanupIssueBranches() error {
 		// Delete branches that start with "issue-"
 		if strings.HasPrefix(branch, "issue-") {
 			if err := runGitCommand("branch", "-D", branch); err != nil {
-				fmt.Printf("Warning: failed to delete branch %s: %v\n", branch, err)
+				logging.Warnf("Failed to delete branch %s: %v", branch, err)
 			} else {
-				fmt.Printf("Deleted issue branch: %s\n", branch)
+				logging.Infof("Deleted issue branch: %s", branch)
 			}
 		}
 	}
@@ -501,28 +499,156 @@ func RunProcess(process *Process) error {
 		}
 	}()
 
-	stdout, err := process.Cmd.StdoutPipe()
+	// Every attempt, retries and nudges included, shares the one timeout
+	ctx, cancel := process.sessionContext()
+	defer cancel()
+
+	if process.WarmUp > 0 {
+		warmUp(process)
+	}
+
+	process.turnLimit = process.MaxTurns
+	success, err := runAttempt(ctx, process)
 	if err != nil {
 		process.Status = "failed"
 		if process.OnCompletion != nil {
 			process.OnCompletion(process, false)
 		}
-		return fmt.Errorf("error creating stdout pipe: %v", err)
+		return err
 	}
 
-	if err := process.Cmd.Start(); err != nil {
+	// Retry once on the fallback model when the configured one is overloaded
+	if !success && process.Failure == FailureCapacity && process.FallbackModel != "" && process.Model != process.FallbackModel {
+		logging.Issue(process.IssueNum).Infof("Model %s is over capacity for issue #%d, retrying with fallback model %s",
+			process.ServedBy(), process.IssueNum, process.FallbackModel)
+
+		process.Cmd = commandWithModel(process.Cmd, process.FallbackModel)
+		process.Model = process.FallbackModel
+		process.UsedFallback = true
+		process.ClaudeSessionID = ""
+		process.LastError = ""
+
+		success, err = runAttempt(ctx, process)
+		if err != nil {
+			process.Status = "failed"
+			if process.OnCompletion != nil {
+				process.OnCompletion(process, false)
+			}
+			return err
+		}
+	}
+
+	// Nudge a stalled or runaway session once before giving up on it
+	if !success && (process.Failure == FailureStalled || process.Failure == FailureMaxTurns) && process.ClaudeSessionID != "" {
+		stats := process.Stats()
+		logging.Issue(process.IssueNum).Infof("Nudging session %s for issue #%d (%s) with a continuation prompt",
+			process.ClaudeSessionID, process.IssueNum, process.Failure)
+
+		process.Cmd = commandWithResume(process.Cmd, process.ClaudeSessionID, nudgePrompt(process.Failure, stats, process.MaxTurns))
+		process.Nudged = true
+		if process.MaxTurns > 0 {
+			process.turnLimit = stats.Turns + wrapUpTurns(process.MaxTurns)
+		}
+
+		success, err = runAttempt(ctx, process)
+		if err != nil {
+			process.Status = "failed"
+			if process.OnCompletion != nil {
+				process.OnCompletion(process, false)
+			}
+			return err
+		}
+	}
+
+	if success {
+		process.Status = "completed"
+	} else {
 		process.Status = "failed"
-		if process.OnCompletion != nil {
-			process.OnCompletion(process, false)
+	}
+
+	// Call completion callback if provided
+	if process.OnCompletion != nil {
+		if callbackErr := process.OnCompletion(process, success); callbackErr != nil {
+			logging.Warnf("Completion callback failed: %v", callbackErr)
+		}
+	}
+
+	// Cleanup repository state after process completion (asynchronously to avoid blocking)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Issue(process.IssueNum).Warnf("Repository cleanup panicked for issue #%d: %v", process.IssueNum, r)
+			}
+		}()
+		cleanupRepositoryState(process)
+	}()
+
+	if !success {
+		return fmt.Errorf("error executing claude command")
+	}
+
+	return nil
+}
+
+// runAttempt runs the process command once, streaming its output, and reports
+// whether it exited successfully. An error means it could not be started.
+// The attempt is stopped when ctx reaches its deadline.
+func runAttempt(ctx context.Context, process *Process) (bool, error) {
+	if ctx.Err() != nil {
+		process.Failure = FailureTimeout
+		return false, nil
+	}
+	process.Attempts++
+	if process.Environment != nil {
+		// Retries rebuild the command from the unwrapped one
+		unwrapped := process.Cmd
+		process.Cmd = process.Environment.Wrap(context.Background(), unwrapped)
+		defer func() { process.Cmd = unwrapped }()
+	}
+
+	stdout, err := process.Cmd.StdoutPipe()
+	if err != nil {
+		return false, fmt.Errorf("error creating stdout pipe: %v", err)
+	}
+
+	// Keep the end of stderr to classify failures the stream doesn't report
+	stderrTail := NewOutputTail(4096)
+	if process.transcript == nil {
+		process.transcript = NewLineTail(BundleLines)
+	}
+	process.Cmd.Stderr = io.MultiWriter(os.Stderr, stderrTail, process.transcript)
+
+	transcriptFile := io.Discard
+	if process.Transcript != "" {
+		if file, err := OpenTranscript(process.Transcript); err != nil {
+			logging.Issue(process.IssueNum).Warnf("Not recording the transcript of issue #%d: %v", process.IssueNum, err)
+		} else {
+			defer file.Close()
+			transcriptFile = file
 		}
-		return fmt.Errorf("error starting claude command: %v", err)
+	}
+
+	if process.Timeout > 0 {
+		// Children of a killed session can hold its stderr open
+		process.Cmd.WaitDelay = killGrace
+	}
+	if err := process.Cmd.Start(); err != nil {
+		return false, fmt.Errorf("error starting claude command: %v", err)
 	}
 
 	process.Status = "running"
+	process.touch()
+
+	done := make(chan struct{})
+	defer close(done)
+	go process.watch(ctx, done, stdout)
 
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
 		line := scanner.Text()
+		process.touch()
+		process.transcript.Add(line)
+		fmt.Fprintln(transcriptFile, line)
 
 		var jsonData map[string]interface{}
 		if err := json.Unmarshal([]byte(line), &jsonData); err != nil {
@@ -530,18 +656,39 @@ func RunProcess(process *Process) error {
 			if process.ClaudeSessionID == "" {
 				if sessionID := extractSessionIDFromText(line); sessionID != "" {
 					process.ClaudeSessionID = sessionID
-					fmt.Printf("DEBUG: Captured Claude session ID: %s\n", sessionID)
+					logging.Debugf("Captured Claude session ID: %s", sessionID)
 				}
 			}
 			fmt.Println(line)
 			continue
 		}
 
+		process.observeEvent(jsonData, line)
+		process.recordActivity(jsonData)
+
+		// The approval hook blocked a tool call; stop before the model tries another way
+		if process.Approval != nil && jsonData["type"] == "user" {
+			if action, held := TakeCheckpoint(process.WorkingDir); held {
+				logging.Issue(process.IssueNum).Infof("Session for issue #%d needs approval to %s, stopping it", process.IssueNum, action)
+				process.Checkpoint = action
+				process.intervene(FailureApproval)
+			}
+		}
+
+		// Tool results going back to the model are the safe point to pause at
+		if jsonData["type"] == "user" && process.Paused == "" {
+			if reason := process.pauseRequested(); reason != "" {
+				logging.Issue(process.IssueNum).Infof("Pausing session for issue #%d: %s", process.IssueNum, reason)
+				process.Paused = reason
+				process.intervene(FailurePaused)
+			}
+		}
+
 		// Check for session ID in JSON
 		if process.ClaudeSessionID == "" {
 			if sessionID, ok := jsonData["session_id"].(string); ok && sessionID != "" {
 				process.ClaudeSessionID = sessionID
-				fmt.Printf("DEBUG: Captured Claude session ID from JSON: %s\n", sessionID)
+				logging.Debugf("Captured Claude session ID from JSON: %s", sessionID)
 			}
 		}
 
@@ -550,7 +697,7 @@ func RunProcess(process *Process) error {
 			if process.ClaudeSessionID == "" {
 				if sessionID := extractSessionIDFromText(content); sessionID != "" {
 					process.ClaudeSessionID = sessionID
-					fmt.Printf("DEBUG: Captured Claude session ID from content: %s\n", sessionID)
+					logging.Debugf("Captured Claude session ID from content: %s", sessionID)
 				}
 			}
 			fmt.Print(content)
@@ -559,7 +706,7 @@ func RunProcess(process *Process) error {
 			if process.ClaudeSessionID == "" {
 				if sessionID := extractSessionIDFromText(delta); sessionID != "" {
 					process.ClaudeSessionID = sessionID
-					fmt.Printf("DEBUG: Captured Claude session ID from delta: %s\n", sessionID)
+					logging.Debugf("Captured Claude session ID from delta: %s", sessionID)
 				}
 			}
 			fmt.Print(delta)
@@ -568,7 +715,7 @@ func RunProcess(process *Process) error {
 			if process.ClaudeSessionID == "" {
 				if sessionID := extractSessionIDFromText(result); sessionID != "" {
 					process.ClaudeSessionID = sessionID
-					fmt.Printf("DEBUG: Captured Claude session ID from result: %s\n", sessionID)
+					logging.Debugf("Captured Claude session ID from result: %s", sessionID)
 				}
 			}
 			fmt.Print(result)
@@ -577,43 +724,45 @@ func RunProcess(process *Process) error {
 		}
 	}
 
-	success := true
-	if err := process.Cmd.Wait(); err != nil {
-		process.Status = "failed"
-		success = false
-	} else {
-		process.Status = "completed"
+	err = process.Cmd.Wait()
+	process.Failure = ClassifyFailure(ExitCode(err), process.LastError+"\n"+stderrTail.String())
+	if intervention := process.takeIntervention(); intervention != FailureNone && err != nil {
+		process.Failure = intervention
 	}
-
-	// Call completion callback if provided
-	if process.OnCompletion != nil {
-		if callbackErr := process.OnCompletion(process, success); callbackErr != nil {
-			fmt.Printf("Warning: completion callback failed: %v\n", callbackErr)
-		}
+	// A session held at a checkpoint has not finished, however it exited
+	if process.Checkpoint != "" {
+		process.Failure = FailureApproval
+		err = fmt.Errorf("awaiting approval to %s", process.Checkpoint)
 	}
-
-	// Cleanup repository state after process completion (asynchronously to avoid blocking)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("Warning: repository cleanup panicked for issue #%d: %v\n", process.IssueNum, r)
-			}
-		}()
-		cleanupRepositoryState(process)
-	}()
-
-	if !success {
-		return fmt.Errorf("error executing claude command")
+	// A paused session resumes later from where it stopped
+	if process.Paused != "" && process.Checkpoint == "" {
+		process.Failure = FailurePaused
+		err = fmt.Errorf("paused: %s", process.Paused)
 	}
-
-	return nil
+	if process.Failure != FailureNone {
+		logging.Issue(process.IssueNum).Infof("Claude run for issue #%d failed (%s)", process.IssueNum, process.Failure)
+	}
+	return err == nil, nil
 }
 
 func RunProcessAsync(process *Process, processManager *ProcessManager) {
 	go func() {
 		defer processManager.RemoveProcess(process.ID)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			process.Status = "failed"
+			process.Failure = FailurePanic
+			process.LastError = fmt.Sprint(recovered)
+			if process.OnPanic == nil {
+				panic(recovered)
+			}
+			process.OnPanic(process, recovered)
+		}()
 		if err := RunProcess(process); err != nil {
-			fmt.Printf("Process %s failed: %v\n", process.ID, err)
+			logging.Infof("Process %s failed: %v", process.ID, err)
 		}
 	}()
 }
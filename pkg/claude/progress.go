@@ -0,0 +1,57 @@
+package claude
+
+import "strings"
+
+// PlanItem is one entry of the todo list a session keeps with TodoWrite
+type PlanItem struct {
+	Content    string // What the step does, e.g. "Add the config option"
+	ActiveForm string // How the step reads while in progress, e.g. "Adding the config option"
+	Status     string // pending, in_progress or completed
+}
+
+// Progress is what a running session last planned and said
+type Progress struct {
+	Plan  []PlanItem // Latest todo list, empty when the session kept none
+	Step  string     // Latest text the model wrote between tool calls
+	Stats SessionStats
+}
+
+// Progress returns a snapshot of the session's plan and latest step
+func (process *Process) Progress() Progress {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	return Progress{
+		Plan:  append([]PlanItem(nil), process.plan...),
+		Step:  process.step,
+		Stats: process.stats,
+	}
+}
+
+// observeProgress keeps the plan and latest step from a content block of an
+// assistant event. It is called with statsMu held.
+func (process *Process) observeProgress(block map[string]interface{}) {
+	switch block["type"] {
+	case "text":
+		if text, ok := block["text"].(string); ok && strings.TrimSpace(text) != "" {
+			process.step = strings.TrimSpace(text)
+		}
+	case "tool_use":
+		if block["name"] != "TodoWrite" {
+			return
+		}
+		input, _ := block["input"].(map[string]interface{})
+		todos, ok := input["todos"].([]interface{})
+		if !ok {
+			return
+		}
+		plan := make([]PlanItem, 0, len(todos))
+		for _, todo := range todos {
+			todo, _ := todo.(map[string]interface{})
+			content, _ := todo["content"].(string)
+			activeForm, _ := todo["activeForm"].(string)
+			status, _ := todo["status"].(string)
+			plan = append(plan, PlanItem{Content: content, ActiveForm: activeForm, Status: status})
+		}
+		process.plan = plan
+	}
+}
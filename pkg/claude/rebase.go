@@ -0,0 +1,107 @@
+package claude
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxUpstreamCommits bounds the upstream commits listed in a prompt
+const maxUpstreamCommits = 15
+
+// UpstreamChanges describes how far the default branch has moved past an
+// issue branch
+type UpstreamChanges struct {
+	Base     string   // Remote default branch, e.g. origin/main
+	Behind   int      // Commits on Base the branch does not have
+	Commits  []string // "<sha> <subject>" of the newest of those commits
+	Rebased  bool     // The branch was rebased onto Base
+	Conflict bool     // A rebase was tried and aborted on conflicts
+}
+
+// RefreshBranch fetches origin and, when the default branch is at least
+// minBehind commits ahead of branch, rebases branch onto it. The checkout is
+// shared by the sessions of a project, so the rebase only happens when branch
+// is checked out in workingDir with no uncommitted changes; otherwise the
+// changes are only reported. It returns nil when the branch is close enough
+// to the default branch.
+func RefreshBranch(workingDir, branch string, minBehind int) (*UpstreamChanges, error) {
+	if err := gitIn(workingDir, "fetch", "--quiet", "origin"); err != nil {
+		return nil, fmt.Errorf("failed to fetch origin: %v", err)
+	}
+	base, err := gitOutput(workingDir, "rev-parse", "--abbrev-ref", "origin/HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the default branch: %v", err)
+	}
+	if _, err := gitOutput(workingDir, "rev-parse", "--verify", "--quiet", branch); err != nil {
+		// The session never created its branch, so it starts from the default branch anyway
+		return nil, nil
+	}
+
+	count, err := gitOutput(workingDir, "rev-list", "--count", branch+".."+base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %v", branch, base, err)
+	}
+	behind, _ := strconv.Atoi(count)
+	if behind == 0 || behind < minBehind {
+		return nil, nil
+	}
+
+	changes := &UpstreamChanges{Base: base, Behind: behind}
+	if log, err := gitOutput(workingDir, "log", "--format=%h %s", "-n", strconv.Itoa(maxUpstreamCommits), branch+".."+base); err == nil && log != "" {
+		changes.Commits = strings.Split(log, "\n")
+	}
+
+	current, _ := gitOutput(workingDir, "rev-parse", "--abbrev-ref", "HEAD")
+	status, err := gitOutput(workingDir, "status", "--porcelain")
+	if current != branch || err != nil || status != "" {
+		return changes, nil
+	}
+	if err := gitIn(workingDir, "rebase", "--quiet", base); err != nil {
+		gitIn(workingDir, "rebase", "--abort")
+		changes.Conflict = true
+		return changes, nil
+	}
+	changes.Rebased = true
+	return changes, nil
+}
+
+// Describe renders the changes as a prompt section about branch
+func (c *UpstreamChanges) Describe(branch string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Upstream Changes\n\n`%s` has %d commits that `%s` does not have", c.Base, c.Behind, branch)
+	if len(c.Commits) > 0 {
+		b.WriteString(", the newest being:\n\n")
+		for _, commit := range c.Commits {
+			fmt.Fprintf(&b, "- %s\n", commit)
+		}
+		if more := c.Behind - len(c.Commits); more > 0 {
+			fmt.Fprintf(&b, "- ... and %d more\n", more)
+		}
+	} else {
+		b.WriteString(".\n")
+	}
+	b.WriteString("\n")
+
+	switch {
+	case c.Rebased:
+		fmt.Fprintf(&b, "`%s` has been rebased onto `%s`. Check that your changes still fit the new code, "+
+			"and push with `git push --force-with-lease` since the history changed.\n\n", branch, c.Base)
+	case c.Conflict:
+		fmt.Fprintf(&b, "Rebasing `%s` onto `%s` conflicts. Rebase it yourself and resolve the conflicts before continuing, "+
+			"then push with `git push --force-with-lease`.\n\n", branch, c.Base)
+	default:
+		fmt.Fprintf(&b, "Before continuing, commit or stash your work, check out `%s` and rebase it onto `%s`, "+
+			"then push with `git push --force-with-lease`.\n\n", branch, c.Base)
+	}
+	return b.String()
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
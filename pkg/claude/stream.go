@@ -0,0 +1,108 @@
+package claude
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// capacityPattern matches API errors that mean the model is overloaded rather
+// than that the request itself was wrong
+var capacityPattern = regexp.MustCompile(`(?i)overloaded|\b529\b|capacity`)
+
+// observeEvent inspects a stream-json event for the model serving the session
+// and for error events
+func (process *Process) observeEvent(event map[string]interface{}, line string) {
+	if event["type"] == "system" && event["subtype"] == "init" {
+		if model, ok := event["model"].(string); ok && model != "" {
+			process.Model = model
+		}
+	}
+
+	if !isErrorEvent(event) {
+		return
+	}
+
+	process.LastError = errorText(event, line)
+}
+
+// isErrorEvent reports whether a stream-json event describes a failure
+func isErrorEvent(event map[string]interface{}) bool {
+	if isError, ok := event["is_error"].(bool); ok && isError {
+		return true
+	}
+	if event["type"] == "error" {
+		return true
+	}
+	subtype, _ := event["subtype"].(string)
+	return strings.HasPrefix(subtype, "error")
+}
+
+// errorText returns the most descriptive message carried by an error event
+func errorText(event map[string]interface{}, line string) string {
+	if result, ok := event["result"].(string); ok && result != "" {
+		return result
+	}
+	switch errValue := event["error"].(type) {
+	case string:
+		return errValue
+	case map[string]interface{}:
+		if message, ok := errValue["message"].(string); ok {
+			return message
+		}
+	}
+	return line
+}
+
+// commandWithModel rebuilds a command so it runs against a different model;
+// an exec.Cmd cannot be started twice
+func commandWithModel(cmd *exec.Cmd, model string) *exec.Cmd {
+	args := withModelFlag(cmd.Args[1:], model)
+
+	next := exec.Command(cmd.Path, args...)
+	next.Dir = cmd.Dir
+	next.Env = cmd.Env
+	next.Stderr = cmd.Stderr
+	return next
+}
+
+// withModelFlag replaces any --model flag in args, or adds one
+func withModelFlag(args []string, model string) []string {
+	result := make([]string, 0, len(args)+2)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--model" && i+1 < len(args):
+			i++
+		case strings.HasPrefix(args[i], "--model="):
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return append([]string{"--model", model}, result...)
+}
+
+// modelFromFlags returns the model selected by a --model flag, if any
+func modelFromFlags(args []string) string {
+	for i, arg := range args {
+		if arg == "--model" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--model="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// ServedBy describes which model ultimately served the session
+func (process *Process) ServedBy() string {
+	model := process.Model
+	if model == "" {
+		model = "default model"
+	}
+	if process.UsedFallback {
+		return fmt.Sprintf("%s (fallback after capacity error)", model)
+	}
+	return model
+}
@@ -0,0 +1,46 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// languageMarkers maps files found at the root of a checkout to the language
+// they imply, named as GitLab's language detection names it
+var languageMarkers = map[string]string{
+	"go.mod":           "Go",
+	"package.json":     "JavaScript",
+	"tsconfig.json":    "TypeScript",
+	"pyproject.toml":   "Python",
+	"requirements.txt": "Python",
+	"setup.py":         "Python",
+	"Cargo.toml":       "Rust",
+	"Gemfile":          "Ruby",
+	"pom.xml":          "Java",
+	"build.gradle":     "Java",
+	"build.gradle.kts": "Kotlin",
+	"composer.json":    "PHP",
+}
+
+// RepositoryDir returns the local checkout of a project, cloning it if needed
+func RepositoryDir(projectPath, gitlabURL string, dryRun bool) (string, error) {
+	repoDir, _, err := ensureRepositoryExists(projectPath, gitlabURL, dryRun)
+	return repoDir, err
+}
+
+// DetectLanguages guesses the languages of the checkout in repoDir from the
+// build files at its root, for when GitLab's language detection is not
+// available
+func DetectLanguages(repoDir string) []string {
+	seen := make(map[string]bool)
+	var languages []string
+	for marker, language := range languageMarkers {
+		if _, err := os.Stat(filepath.Join(repoDir, marker)); err == nil && !seen[language] {
+			seen[language] = true
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
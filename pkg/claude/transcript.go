@@ -0,0 +1,153 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// maxTranscriptField is how much of a tool input or result a rendered
+// transcript shows; the raw transcript keeps all of it
+const maxTranscriptField = 500
+
+// OpenTranscript opens a transcript file for appending, creating it and its
+// directory readable only by the user, since sessions print whatever they read
+func OpenTranscript(path string) (*TranscriptFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %v", err)
+	}
+	return &TranscriptFile{file: file}, nil
+}
+
+// TranscriptFile appends output to a transcript line by line, sealing each
+// line with the session store's key when SESSION_ENCRYPTION is on. Read the
+// lines back with session.UnsealLine.
+type TranscriptFile struct {
+	file    *os.File
+	partial []byte // Output after the last newline
+}
+
+// Write appends the complete lines of p, keeping the rest for the next write
+func (t *TranscriptFile) Write(p []byte) (int, error) {
+	t.partial = append(t.partial, p...)
+	for {
+		end := bytes.IndexByte(t.partial, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		line := string(t.partial[:end])
+		t.partial = t.partial[end+1:]
+		if _, err := t.file.WriteString(session.SealLine(line) + "\n"); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close writes any unterminated last line and closes the file
+func (t *TranscriptFile) Close() error {
+	if len(t.partial) > 0 {
+		t.file.WriteString(session.SealLine(string(t.partial)) + "\n")
+		t.partial = nil
+	}
+	return t.file.Close()
+}
+
+// RenderTranscriptLine turns a stream-json line of a transcript into what a
+// person reading the session wants to see: the model's text, the tools it
+// called and what they returned, and the result. Lines that are not events
+// are returned as they are; events without anything to show return "".
+func RenderTranscriptLine(line string) string {
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		return line
+	}
+
+	switch event["type"] {
+	case "system":
+		if event["subtype"] != "init" {
+			return ""
+		}
+		return fmt.Sprintf("== session %v, model %v, in %v ==", event["session_id"], event["model"], event["cwd"])
+
+	case "assistant", "user":
+		message, _ := event["message"].(map[string]interface{})
+		content, _ := message["content"].([]interface{})
+		var parts []string
+		for _, item := range content {
+			block, _ := item.(map[string]interface{})
+			switch block["type"] {
+			case "text":
+				if text, _ := block["text"].(string); strings.TrimSpace(text) != "" {
+					parts = append(parts, text)
+				}
+			case "tool_use":
+				input, _ := json.Marshal(block["input"])
+				parts = append(parts, fmt.Sprintf("-> %v %s", block["name"], truncateField(string(input))))
+			case "tool_result":
+				prefix := "<-"
+				if isError, _ := block["is_error"].(bool); isError {
+					prefix = "<- error:"
+				}
+				parts = append(parts, fmt.Sprintf("%s %s", prefix, truncateField(toolResultText(block["content"]))))
+			}
+		}
+		return strings.Join(parts, "\n")
+
+	case "result":
+		summary := fmt.Sprintf("== result: %v", event["subtype"])
+		if turns, ok := event["num_turns"].(float64); ok {
+			summary += fmt.Sprintf(", %d turns", int(turns))
+		}
+		if cost, ok := event["total_cost_usd"].(float64); ok {
+			summary += fmt.Sprintf(", $%.2f", cost)
+		}
+		summary += " =="
+		if result, _ := event["result"].(string); result != "" {
+			summary += "\n" + result
+		}
+		return summary
+	}
+
+	if isErrorEvent(event) {
+		return "error: " + errorText(event, line)
+	}
+	return ""
+}
+
+// toolResultText returns the text of a tool result, which is either a string
+// or a list of content blocks
+func toolResultText(content interface{}) string {
+	switch content := content.(type) {
+	case string:
+		return content
+	case []interface{}:
+		var texts []string
+		for _, item := range content {
+			if block, ok := item.(map[string]interface{}); ok {
+				if text, ok := block["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// truncateField shortens text to maxTranscriptField characters on one line
+func truncateField(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxTranscriptField {
+		return text[:maxTranscriptField] + " [...]"
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+package claude
+
+import "encoding/json"
+
+// Usage is what Claude reports about a session in the result event that
+// ends each run
+type Usage struct {
+	Model   string
+	CostUSD float64
+	Turns   int
+
+	InputTokens         int
+	OutputTokens        int
+	CacheReadTokens     int // Input tokens read from the prompt cache
+	CacheCreationTokens int // Input tokens written to the prompt cache
+}
+
+// observe records the model of an init event and adds the cost, turns and
+// tokens of a result event
+func (usage *Usage) observe(event map[string]interface{}) {
+	switch event["type"] {
+	case "system":
+		if model, ok := event["model"].(string); ok && model != "" && event["subtype"] == "init" {
+			usage.Model = model
+		}
+	case "result":
+		// Older CLI versions called it cost_usd
+		if cost, ok := event["total_cost_usd"].(float64); ok {
+			usage.CostUSD += cost
+		} else if cost, ok := event["cost_usd"].(float64); ok {
+			usage.CostUSD += cost
+		}
+		if turns, ok := event["num_turns"].(float64); ok {
+			usage.Turns += int(turns)
+		}
+		if tokens, ok := event["usage"].(map[string]interface{}); ok {
+			usage.InputTokens += tokenCount(tokens, "input_tokens")
+			usage.OutputTokens += tokenCount(tokens, "output_tokens")
+			usage.CacheReadTokens += tokenCount(tokens, "cache_read_input_tokens")
+			usage.CacheCreationTokens += tokenCount(tokens, "cache_creation_input_tokens")
+		}
+		// The init event may have scrolled out of a transcript
+		if models, ok := event["modelUsage"].(map[string]interface{}); ok && usage.Model == "" {
+			for model := range models {
+				usage.Model = model
+				break
+			}
+		}
+	}
+}
+
+// tokenCount returns a token count of a result event's usage, 0 when missing
+func tokenCount(tokens map[string]interface{}, key string) int {
+	count, _ := tokens[key].(float64)
+	return int(count)
+}
+
+// TranscriptUsage reads the usage from the stream-json lines of a session's
+// output, ignoring lines that are not events
+func TranscriptUsage(lines []string) Usage {
+	var usage Usage
+	for _, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		usage.observe(event)
+	}
+	return usage
+}
+
+// Usage returns the usage of every attempt of the session so far
+func (process *Process) Usage() Usage {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	usage := process.usage
+	if process.Model != "" {
+		usage.Model = process.Model
+	}
+	return usage
+}
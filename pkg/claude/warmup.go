@@ -0,0 +1,62 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// dependencyWarmups lists the commands that download a checkout's
+// dependencies, by the file that calls for them
+var dependencyWarmups = []struct {
+	marker string
+	args   []string
+}{
+	{"go.mod", []string{"go", "mod", "download"}},
+	{"package-lock.json", []string{"npm", "ci", "--ignore-scripts", "--no-audit", "--no-fund"}},
+}
+
+// SharedCacheEnv returns the variables pointing Go and npm at download caches
+// under cacheDir, shared by every checkout the daemon works in
+func SharedCacheEnv(cacheDir string) []string {
+	return []string{
+		"GOMODCACHE=" + filepath.Join(cacheDir, "go-mod"),
+		"npm_config_cache=" + filepath.Join(cacheDir, "npm"),
+	}
+}
+
+// warmUp downloads the checkout's Go modules and npm packages before the
+// first attempt, so the session's first build does not wait on the network.
+// It runs with the session's environment and gives up after WarmUp; failures
+// are logged and the session starts anyway.
+func warmUp(process *Process) {
+	ctx, cancel := context.WithTimeout(context.Background(), process.WarmUp)
+	defer cancel()
+
+	for _, warmup := range dependencyWarmups {
+		if !fileExists(filepath.Join(process.WorkingDir, warmup.marker)) {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, warmup.args[0], warmup.args[1:]...)
+		cmd.Dir = process.WorkingDir
+		cmd.Env = process.Cmd.Env
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if process.Environment != nil {
+			cmd = process.Environment.Wrap(ctx, cmd)
+		}
+
+		start := time.Now()
+		logging.Issue(process.IssueNum).Infof("Warming up dependencies for issue #%d: %v", process.IssueNum, warmup.args)
+		if err := cmd.Run(); err != nil {
+			logging.Issue(process.IssueNum).Warnf("Dependency warm-up for issue #%d failed after %s: %v",
+				process.IssueNum, time.Since(start).Round(time.Second), err)
+			continue
+		}
+		logging.Issue(process.IssueNum).Infof("Warmed up dependencies for issue #%d in %s", process.IssueNum, time.Since(start).Round(time.Second))
+	}
+}
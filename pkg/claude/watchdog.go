@@ -0,0 +1,250 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// watchInterval is how often a running session is checked for stalls
+const watchInterval = 15 * time.Second
+
+// killGrace is how long a timed-out session has to exit after SIGTERM before
+// it is killed
+const killGrace = 30 * time.Second
+
+// SessionStats summarizes what a session has done, gathered from its stream
+type SessionStats struct {
+	Turns     int           // Model round trips, i.e. tool results sent back
+	ToolCalls int           // tool_use blocks requested by the model
+	LastTool  string        // Name of the most recent tool call
+	ThinkTime time.Duration // Time spent waiting on the model after tool results
+	LastEvent time.Time     // When the stream last produced output
+}
+
+// Stats returns a snapshot of the session's activity
+func (process *Process) Stats() SessionStats {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	return process.stats
+}
+
+// Describe renders the stats for a diagnostic comment
+func (stats SessionStats) Describe() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- **Turns:** %d\n", stats.Turns)
+	fmt.Fprintf(&b, "- **Tool calls:** %d", stats.ToolCalls)
+	if stats.LastTool != "" {
+		fmt.Fprintf(&b, " (last: `%s`)", stats.LastTool)
+	}
+	fmt.Fprintf(&b, "\n- **Think time:** %s\n", stats.ThinkTime.Round(time.Second))
+	if !stats.LastEvent.IsZero() {
+		fmt.Fprintf(&b, "- **Last output:** %s ago\n", time.Since(stats.LastEvent).Round(time.Second))
+	}
+	return b.String()
+}
+
+// touch records that the stream produced output
+func (process *Process) touch() {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	process.stats.LastEvent = time.Now()
+}
+
+// recordActivity updates turn, tool call and think time counters from a stream-json event
+func (process *Process) recordActivity(event map[string]interface{}) {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+
+	now := time.Now()
+	process.stats.LastEvent = now
+	process.usage.observe(event)
+
+	switch event["type"] {
+	case "assistant":
+		if !process.awaitingModel.IsZero() {
+			process.stats.ThinkTime += now.Sub(process.awaitingModel)
+			process.awaitingModel = time.Time{}
+		}
+
+		message, _ := event["message"].(map[string]interface{})
+		content, _ := message["content"].([]interface{})
+		for _, block := range content {
+			block, _ := block.(map[string]interface{})
+			process.observeProgress(block)
+			if block["type"] == "tool_use" {
+				process.stats.ToolCalls++
+				if name, ok := block["name"].(string); ok {
+					process.stats.LastTool = name
+				}
+			}
+		}
+
+	case "user":
+		// Tool results going back to the model start a new turn
+		process.stats.Turns++
+		process.awaitingModel = now
+	}
+}
+
+// sessionContext returns the context bounding a session by its Timeout
+func (process *Process) sessionContext() (context.Context, context.CancelFunc) {
+	if process.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), process.Timeout)
+}
+
+// watch cancels the running attempt when it stalls, runs past its turn limit
+// or ctx reaches its deadline, recording why in process.intervention. It
+// returns when done closes. stdout is closed when a timed-out session has to be
+// killed, so reading its stream stops.
+func (process *Process) watch(ctx context.Context, done <-chan struct{}, stdout io.Closer) {
+	if process.StallTimeout <= 0 && process.turnLimit <= 0 && process.Timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			// Cancelled rather than expired when the session is already over
+			if ctx.Err() != context.DeadlineExceeded {
+				return
+			}
+			logging.Issue(process.IssueNum).Infof("Session for issue #%d ran past its timeout of %s, stopping it",
+				process.IssueNum, process.Timeout)
+			if process.OnTimeout != nil {
+				process.OnTimeout(process)
+			}
+			process.intervene(FailureTimeout)
+			process.killAfter(done, killGrace, stdout)
+			return
+		case <-ticker.C:
+			stats := process.Stats()
+			switch {
+			case process.StallTimeout > 0 && time.Since(stats.LastEvent) > process.StallTimeout:
+				logging.Issue(process.IssueNum).Infof("Session for issue #%d produced no output for %s, stopping it",
+					process.IssueNum, process.StallTimeout)
+				process.intervene(FailureStalled)
+				return
+			case process.turnLimit > 0 && stats.Turns > process.turnLimit:
+				logging.Issue(process.IssueNum).Infof("Session for issue #%d exceeded %d turns, stopping it", process.IssueNum, process.turnLimit)
+				process.intervene(FailureMaxTurns)
+				return
+			}
+		}
+	}
+}
+
+// intervene stops the running command for the given reason
+func (process *Process) intervene(reason FailureKind) {
+	process.statsMu.Lock()
+	process.intervention = reason
+	process.statsMu.Unlock()
+
+	if process.Cmd != nil && process.Cmd.Process != nil {
+		process.Cmd.Process.Signal(syscall.SIGTERM)
+	}
+}
+
+// killAfter kills the running command if it has not exited within grace of
+// being asked to stop. Its children may still hold stdout open, so that is
+// closed too.
+func (process *Process) killAfter(done <-chan struct{}, grace time.Duration, stdout io.Closer) {
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		if process.Cmd != nil && process.Cmd.Process != nil {
+			logging.Issue(process.IssueNum).Warnf("Session for issue #%d did not stop within %s, killing it", process.IssueNum, grace)
+			process.Cmd.Process.Kill()
+		}
+		stdout.Close()
+	}
+}
+
+// Cancel stops the session now, failing it as cancelled
+func (process *Process) Cancel() {
+	process.intervene(FailureCancelled)
+}
+
+// RequestPause stops the session once the tool call in progress has
+// finished, before the model acts on its result. The session can then be
+// resumed from its last completed tool call. Requesting a pause again keeps
+// the first reason.
+func (process *Process) RequestPause(reason string) {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	if process.pauseReason == "" {
+		process.pauseReason = reason
+	}
+}
+
+// pauseRequested returns why a pause was requested, "" if none was
+func (process *Process) pauseRequested() string {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	return process.pauseReason
+}
+
+// takeIntervention returns and clears the reason the watchdog stopped the attempt
+func (process *Process) takeIntervention() FailureKind {
+	process.statsMu.Lock()
+	defer process.statsMu.Unlock()
+	reason := process.intervention
+	process.intervention = FailureNone
+	return reason
+}
+
+// wrapUpTurns is the turn budget given to a session nudged for exceeding MaxTurns
+func wrapUpTurns(maxTurns int) int {
+	if turns := maxTurns / 4; turns > 5 {
+		return turns
+	}
+	return 5
+}
+
+// nudgePrompt asks a stalled or runaway session to get back on track
+func nudgePrompt(reason FailureKind, stats SessionStats, maxTurns int) string {
+	if reason == FailureMaxTurns {
+		return fmt.Sprintf("You have used %d turns, more than the limit of %d. Wrap up now: "+
+			"commit and push the work that is done, update the merge request, and post a comment on the issue "+
+			"summarizing what is finished and what remains. You have %d turns left.",
+			stats.Turns, maxTurns, wrapUpTurns(maxTurns))
+	}
+
+	return "Your session stopped producing output and was interrupted. If a command was hanging " +
+		"(a watcher, a server or an interactive prompt), do not run it again. Continue with the next step " +
+		"of your plan. If you are blocked, post a comment on the issue explaining what is blocking you and stop."
+}
+
+// commandWithResume rebuilds a command so it resumes a session with a new prompt
+func commandWithResume(cmd *exec.Cmd, sessionID, prompt string) *exec.Cmd {
+	args := cmd.Args[1:]
+	kept := make([]string, 0, len(args)+4)
+	for i := 0; i < len(args); i++ {
+		if (args[i] == "-p" || args[i] == "-r") && i+1 < len(args) {
+			i++
+			continue
+		}
+		kept = append(kept, args[i])
+	}
+	kept = append(kept, "-r", sessionID, "-p", prompt)
+
+	next := exec.Command(cmd.Path, kept...)
+	next.Dir = cmd.Dir
+	next.Env = cmd.Env
+	return next
+}
@@ -3,25 +3,86 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 type Config struct {
+	// Source describes where settings beyond the environment came from
+	Source struct {
+		// File is the YAML configuration file read, "" for none
+		File string
+		// Environment selects an environments: section of File (AUTOMAGIC_ENV)
+		Environment string
+		// Project is the project File was applied for
+		Project string
+		// ProjectOverrides is set when File has a projects: section for Project
+		ProjectOverrides bool
+	}
+
 	GitLab struct {
 		URL      string
 		Token    string
 		Username string
+		// GraphQL fetches review issues with their newest comment in one
+		// query instead of a discussions request per issue
+		GraphQL bool
+		// Retries is how often a request failing with a transient error is
+		// retried, waiting RetryDelay seconds at first and doubling up to
+		// RetryMaxDelay seconds
+		Retries       int
+		RetryDelay    int
+		RetryMaxDelay int
+		// ForkMode decides when issue branches are pushed to a fork of the
+		// project: auto (without push access), always or never
+		ForkMode string
+		// ForkNamespace is the group new forks are created in, empty for the
+		// bot's personal namespace
+		ForkNamespace string
 	}
 
 	Claude struct {
 		Command string
 		Flags   string
+		// FallbackModel is retried when the configured model is over capacity
+		FallbackModel string
+		// OverflowFlags are added when retrying after a context overflow
+		OverflowFlags string
 	}
 
 	Projects struct {
 		DefaultPath string
+		// Aliases maps short names to project paths (backend=group/backend/api),
+		// accepted wherever a project path is
+		Aliases map[string]string
+	}
+
+	Prompts struct {
+		// Dir holds <workflow>.tmpl files overriding the built-in prompts
+		Dir string
+		// Labels maps issue labels to the workflow whose template starts
+		// their sessions instead of the issue template
+		Labels map[string]string
+	}
+
+	// Open configures "automagic open"
+	Open struct {
+		// Command opens a URL, empty for the platform's default browser
+		Command string
+		// Target is what opens by default: mr, branch or issue
+		Target string
+	}
+
+	Locale struct {
+		// Default is the language of bot comments, e.g. en or th
+		Default string
+		// Projects overrides the language per project path
+		Projects map[string]string
+		// Dir holds <language>/<message>.tmpl files overriding the built-in comments
+		Dir string
 	}
 
 	Daemon struct {
@@ -29,6 +90,468 @@ type Config struct {
 		ClaudeLabel  string
 		ProcessLabel string
 		ReviewLabel  string
+		// ExcludeLabels lists labels that block pickup (e.g. blocked, wontfix)
+		ExcludeLabels []string
+		// TierLimits caps concurrent sessions per complexity tier (T1-T4)
+		TierLimits map[string]int
+		// UntieredLimit caps sessions for issues without a tier, 0 means unlimited
+		UntieredLimit int
+		// PriorityAging is how many hours an issue waits before it is raised
+		// one priority level, 0 disables aging
+		PriorityAging int
+		// MaxConcurrentReviews caps MR reviews running at once, 0 means unlimited
+		MaxConcurrentReviews int
+		// WaitForPipeline holds the review transition until the MR's pipeline finishes
+		WaitForPipeline bool
+		// PipelineWaitTimeout is how many minutes to wait for the pipeline
+		PipelineWaitTimeout int
+		// PipelineFixAttempts is how often a failed pipeline is resumed before review
+		PipelineFixAttempts int
+		// CILintFixAttempts is how often a session whose .gitlab-ci.yml fails
+		// GitLab's CI lint is resumed before review
+		CILintFixAttempts int
+		// BackfillRate is how many backfilled issues are released per hour
+		BackfillRate int
+		// StallTimeout is how many minutes a session may go without output, 0 disables
+		StallTimeout int
+		// MaxTurns caps the turns of an issue session, 0 means unlimited
+		MaxTurns int
+		// SessionTimeout is how many minutes an issue session may run in all, 0 disables
+		SessionTimeout int
+		// TimeoutLabel marks issues whose session ran past SessionTimeout
+		TimeoutLabel string
+		// ActivityCheck skips polling ticks when the project events feed shows
+		// nothing new since the last check
+		ActivityCheck bool
+		// FullPollInterval is how many minutes may pass between full polls when
+		// the activity check keeps skipping, since label changes are not events
+		FullPollInterval int
+	}
+
+	// Environment checks the host can build and test a project before a
+	// session starts on it
+	Environment struct {
+		// ToolchainCheck looks up the commands of the project's main languages
+		// and holds the issue with Label when any is missing
+		ToolchainCheck bool
+		// MinLanguageShare is the percentage of the code a language must make
+		// up for its toolchain to be required
+		MinLanguageShare int
+		// Toolchains overrides the commands required per language, joined
+		// by + (e.g. python=python3+pip)
+		Toolchains map[string]string
+		// Label marks issues held for a missing toolchain
+		Label string
+		// Session runs sessions inside the project's development environment:
+		// auto (.devcontainer, else flake.nix), devcontainer, nix or off
+		Session string
+		// Warmup downloads Go modules and npm packages into caches shared by
+		// all checkouts before a session starts
+		Warmup bool
+		// WarmupTimeout is how many minutes the download may take
+		WarmupTimeout int
+	}
+
+	// Mirror keeps a bare mirror of each project that checkouts are cloned
+	// against, so new clones borrow its objects instead of downloading them
+	Mirror struct {
+		Enabled bool
+		// Refresh is how many minutes pass between fetches into a mirror
+		Refresh int
+		// Projects overrides Refresh per project path
+		Projects map[string]int
+	}
+
+	// Dedup holds issues that look like duplicates of open ones instead of
+	// starting a session on them
+	Dedup struct {
+		Enabled bool
+		// Threshold is the word overlap, in percent, that marks a duplicate
+		Threshold int
+		// Label marks issues held as possible duplicates
+		Label string
+	}
+
+	// Approval holds risky actions in a session until a human approves them
+	// with a /automagic approve comment
+	Approval struct {
+		Enabled bool
+		// MigrationPaths and CIPaths match the files whose changes need approval
+		MigrationPaths []string
+		CIPaths        []string
+		// DeleteLines is the length above which deleting a file needs approval
+		DeleteLines int
+		// Label marks issues waiting for an approval
+		Label string
+	}
+
+	// Pause stops issue sessions after their current tool call so they can
+	// be resumed later
+	Pause struct {
+		// Label pauses the running session of an issue; removing it resumes the session
+		Label string
+		// OnShutdown pauses running sessions when the daemon stops, waiting up
+		// to ShutdownTimeout minutes for them to reach a tool boundary
+		OnShutdown      bool
+		ShutdownTimeout int
+	}
+
+	// AutoRebase refreshes an issue branch onto the default branch before a
+	// session on it is resumed, once the default branch is MinCommits ahead
+	AutoRebase struct {
+		Enabled    bool
+		MinCommits int
+	}
+
+	// WorkingHours limits when new issues are started; issues labeled
+	// outside them wait, with a comment saying when work resumes
+	WorkingHours struct {
+		// Hours is the time of day new issues start in, e.g. 08:00-18:00;
+		// "" starts them at any time
+		Hours string
+		// Days are the weekdays Hours apply on, e.g. Mon-Fri
+		Days []string
+		// Timezone is the IANA zone of Hours, "" for the local one
+		Timezone string
+	}
+
+	// Mentions decides whom completion and approval comments @-mention
+	Mentions struct {
+		// Participants mentions the participants of the issue
+		Participants bool
+		// OptOut are usernames that are never mentioned
+		OptOut []string
+		// OptOutMarker in a user's profile bio opts them out, e.g. no-bot-pings
+		OptOutMarker string
+	}
+
+	// MergeSync follows the merge requests opened for issues and marks an
+	// issue solved once its merge request is merged
+	MergeSync struct {
+		// Interval is how often linked merge requests are checked, in
+		// seconds; 0 disables tracking
+		Interval int
+		// SolvedLabel replaces the workflow labels of an issue whose merge
+		// request was merged
+		SolvedLabel string
+		// CloseIssue closes the issue as well
+		CloseIssue bool
+	}
+
+	// DescriptionSync resumes an issue's session when the issue description
+	// is edited after its merge request was opened
+	DescriptionSync struct {
+		Enabled bool
+	}
+
+	// FailureBundle keeps the output and worktree state of failed sessions
+	FailureBundle struct {
+		Enabled bool
+		// Snippet uploads each bundle as a private snippet linked on the issue
+		Snippet bool
+	}
+
+	// Transcript keeps the raw output of every session run in a file per run
+	Transcript struct {
+		Enabled bool
+		// RetainDays is how long transcript files are kept, 0 keeps them forever
+		RetainDays int
+	}
+
+	// LabelSuggest labels new issues from the labels of similar closed issues
+	LabelSuggest struct {
+		Enabled bool
+		// Prefixes select the labels that are suggested, e.g. area::
+		Prefixes []string
+		// Apply is the confidence, in percent, at which a label is added
+		Apply int
+		// Min is the confidence, in percent, at which a label is proposed
+		Min int
+	}
+
+	// Triage classifies new issues without labels with a short Claude call,
+	// labeling their type and complexity tier and commenting an estimate
+	Triage struct {
+		Enabled bool
+		// Model classifies issues, empty for the CLI's default
+		Model string
+		// Limit is how many issues are classified per polling cycle
+		Limit int
+		// Labels maps a type (bug, feature, docs) or tier (T1-T4) to the label
+		// applied for it, e.g. bug=type::bug
+		Labels map[string]string
+	}
+
+	// KnowledgeBase points new issue sessions at the files and merge
+	// requests of completed sessions on similar issues
+	KnowledgeBase struct {
+		Enabled bool
+		// Limit is how many related sessions a prompt lists
+		Limit int
+	}
+
+	// DocIndex adds the passages of a project's docs and key source files
+	// closest to the issue, by embedding, to issue prompts
+	DocIndex struct {
+		Enabled bool
+		// TopK is how many passages a prompt includes
+		TopK int
+		// Projects overrides TopK per project path, 0 turns the index off
+		Projects map[string]int
+		// Files are globs of source files indexed next to the markdown docs
+		Files []string
+		// EmbeddingURL is an OpenAI-compatible API base URL
+		EmbeddingURL    string
+		EmbeddingModel  string
+		EmbeddingAPIKey string
+	}
+
+	// Issues configures the issues automagic files itself (follow-ups,
+	// triage, flaky test reports)
+	Issues struct {
+		// Template is the description template used for every kind of issue
+		Template string
+		// Templates overrides Template per kind (follow_up, triage, flaky_test, scaffold)
+		Templates map[string]string
+		// Labels are added to every issue automagic creates
+		Labels []string
+	}
+
+	// Scaffold creates projects from a template repository for issues
+	// carrying Label, then files their checklist as issues of the new project
+	Scaffold struct {
+		// Label marks issues asking for a new project
+		Label string
+		// Template is the path of the template project, empty disables scaffolding
+		Template string
+		// Namespace is the group new projects go in when the issue names none
+		Namespace string
+		// Visibility of new projects, empty keeps the template's
+		Visibility string
+		// IssueLabels are added to the issues filed in the new project
+		IssueLabels []string
+		// CI proposes a generated .gitlab-ci.yml for new projects without one
+		CI bool
+	}
+
+	// CI generates .gitlab-ci.yml merge requests for issues carrying
+	// SetupLabel and for scaffolded projects
+	CI struct {
+		// SetupLabel marks issues asking for the project's CI configuration
+		SetupLabel string
+		// Deploy adds a deployment: cloudrun or cloudrun-source, empty for none
+		Deploy string
+		// Region is the Cloud Run region deployed to
+		Region string
+		// TemplatesDir holds <name>.yml files overriding the built-in job templates
+		TemplatesDir string
+	}
+
+	// Terraform plans the root modules a session changed before its issue
+	// goes to review
+	Terraform struct {
+		// Plan enables the plan; a failed plan keeps the issue out of review
+		Plan bool
+		// Command is the terraform executable
+		Command string
+		// PlanTimeout is the limit in minutes for init and plan of one module
+		PlanTimeout int
+	}
+
+	// MigrationCheck runs the migrations a session adds against a disposable
+	// PostgreSQL container and reports the result on the issue
+	MigrationCheck struct {
+		Enabled bool
+		// Image is the PostgreSQL image the database runs on
+		Image string
+		// Timeout is the limit in minutes for checking one migrations directory
+		Timeout int
+	}
+
+	// Coverage posts the test coverage delta of a session's branch on its
+	// merge request
+	Coverage struct {
+		Enabled bool
+		// Command prints the total coverage as its last percentage, empty
+		// measures Go modules with go test
+		Command string
+		// Threshold is the drop in percentage points flagged as a regression
+		Threshold float64
+		// Timeout is the limit in minutes for one coverage run
+		Timeout int
+	}
+
+	// Benchmark compares the benchmarks of a session's branch with those of
+	// the default branch for issues carrying Label
+	Benchmark struct {
+		// Label marks issues whose sessions are benchmarked, empty disables
+		Label string
+		// Command prints results in Go benchmark format, empty runs the
+		// benchmarks of Go modules with go test
+		Command string
+		// Timeout is the limit in minutes for one benchmark run
+		Timeout int
+	}
+
+	// Progress keeps a comment on the issue up to date with the plan and
+	// latest step of its running session
+	Progress struct {
+		Enabled bool
+		// Interval is how many seconds pass between checks for new progress
+		Interval int
+	}
+
+	Data struct {
+		// Dir is the root for the session store, backups and other local state
+		Dir string
+	}
+
+	Database struct {
+		// BackupRetain is how many daily backups of sessions.db to keep, 0 disables them
+		BackupRetain int
+		// RetainSucceededDays and RetainFailedDays are how long session records
+		// are kept after their last activity, by the outcome of their latest
+		// run; 0 keeps them forever. Sessions still in review are always kept.
+		RetainSucceededDays int
+		RetainFailedDays    int
+		// Encrypt protects working directories, commands, environment snapshots,
+		// event details and webhook payloads in the session store with AES-GCM
+		Encrypt bool
+		// EncryptionKey is the base64 key; when empty it is kept in the OS keyring
+		EncryptionKey string
+	}
+
+	// Logging configures the daemon's log
+	Logging struct {
+		// Level is debug, info, warn or error (-log-level)
+		Level string
+		// Format is "text" or "json", one object per line
+		Format string
+		// File is appended to instead of writing the log to stdout
+		File string
+	}
+
+	// Heartbeat lets supervisors detect a hung polling loop
+	Heartbeat struct {
+		// File is rewritten with the time of every polling cycle, empty disables it
+		File string
+	}
+
+	// Metrics exposes Prometheus metrics for scraping and pushes them where
+	// the daemon cannot be scraped
+	Metrics struct {
+		// Addr is the listen address serving /metrics, empty disables it
+		Addr string
+		// PushURL is a Pushgateway base URL or InfluxDB write URL, empty disables pushing
+		PushURL string
+		// PushFormat is "pushgateway" or "influx"
+		PushFormat string
+		// PushInterval is how many seconds pass between pushes
+		PushInterval int
+		// PushToken is sent as the Authorization header, e.g. "Token <influx token>"
+		PushToken string
+		// Instance names this daemon in pushed metrics, the hostname by default
+		Instance string
+	}
+
+	// ErrorReport sends panics, repeated API failures and session crashes to
+	// Sentry or an error webhook
+	ErrorReport struct {
+		// SentryDSN is the Sentry project DSN, empty disables Sentry
+		SentryDSN string
+		// WebhookURL receives each error as a JSON POST, empty disables it
+		WebhookURL string
+		// Environment is the Sentry environment, AUTOMAGIC_ENV by default
+		Environment string
+		// APIFailures is how many consecutive failures of a GitLab operation are reported
+		APIFailures int
+	}
+
+	// Trackers mirror issue status transitions to external project trackers
+	Trackers struct {
+		// Statuses maps picked_up, in_review and done to tracker status names
+		Statuses map[string]string
+
+		JiraURL   string
+		JiraEmail string
+		JiraToken string
+
+		LinearAPIKey string
+
+		NotionToken          string
+		NotionDatabase       string
+		NotionURLProperty    string
+		NotionStatusProperty string
+	}
+
+	Telemetry struct {
+		// Enabled opts in to anonymous usage counts, off by default
+		Enabled bool
+		// Endpoint receives the reports as JSON POSTs
+		Endpoint string
+	}
+
+	Control struct {
+		// Addr is the listen address of the control API, empty disables it
+		Addr string
+		// Token must be sent as a bearer token with each control request
+		Token string
+		// FeedToken reads the activity feeds only, as ?token= for feed readers
+		FeedToken string
+	}
+
+	ChatOps struct {
+		// Addr is the listen address for slash commands, empty disables them
+		Addr string
+		// Platform is slack or mattermost, deciding the message syntax
+		Platform string
+		// Token is the verification token of the slash command
+		Token string
+		// SigningSecret verifies signed Slack requests
+		SigningSecret string
+		// WebhookURL is an incoming webhook that gets session summaries
+		WebhookURL string
+	}
+
+	Webhook struct {
+		// Addr is the listen address for the webhook endpoint, empty disables it
+		Addr string
+		// Secret must match the X-Gitlab-Token header of each delivery
+		Secret string
+		// MaxAge is how many seconds old a delivery may be before it is dropped
+		MaxAge int
+		// Per-event toggles for routing deliveries to daemon workflows
+		IssueEvents        bool // Issue events → issue pickup
+		NoteEvents         bool // Comment events → session resume
+		MergeRequestEvents bool // MR events → MR review
+		PipelineEvents     bool // Failed pipelines → CI fix
+		// Register makes the daemon register the endpoint on the monitored
+		// project and poll only every PollInterval seconds (-webhook)
+		Register bool
+		// URL is where GitLab reaches the endpoint, e.g. https://host/webhook
+		URL          string
+		PollInterval int
+	}
+
+	Queue struct {
+		// URL of a shared job queue (redis://host:6379/0), empty runs standalone
+		URL string
+		// Role is coordinator (polls GitLab and enqueues issues) or worker
+		// (claims queued issues and runs their sessions)
+		Role string
+		// Lease is how many minutes a claimed job stays with a worker that
+		// stops renewing it before it is handed to another
+		Lease int
+		// WorkerID names this worker in leases, default <hostname>-<pid>
+		WorkerID string
+		// Capabilities this worker offers, e.g. language=go, size=large
+		Capabilities map[string]string
+		// Routes maps issue labels to the capabilities a worker needs to take
+		// the issue, written as key:value pairs joined by + (size:large+gpu:yes)
+		Routes map[string]string
+		// RouteLanguage also requires the project's main language, as detected
+		// by GitLab, to be among the worker's capabilities
+		RouteLanguage bool
 	}
 }
 
@@ -66,9 +589,19 @@ func loadEnvFile(filename string) error {
 	return scanner.Err()
 }
 
+// Load reads the configuration from the environment, .env and automagic.yaml
 func Load() (*Config, error) {
+	return LoadForProject("")
+}
+
+// LoadForProject reads the configuration with the overrides automagic.yaml
+// has for projectPath, or for DEFAULT_PROJECT_PATH when it is ""
+func LoadForProject(projectPath string) (*Config, error) {
 	var config Config
 
+	// Settings of an earlier load may belong to another project
+	unsetFromFile()
+
 	// Try to load .env file first
 	envFiles := []string{".env", ".env.local"}
 	for _, envFile := range envFiles {
@@ -80,15 +613,53 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// automagic.yaml fills in what the environment and .env leave unset,
+	// so secrets can stay in .env
+	var fileAliases map[string]string
+	if path := configFilePath(); path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		config.Source.File = path
+		config.Source.Environment = os.Getenv("AUTOMAGIC_ENV")
+		config.Source.Project, config.Source.ProjectOverrides = file.apply(config.Source.Environment, projectPath)
+		fileAliases = file.aliases
+	}
+
 	// Load configuration from environment variables
 	config.GitLab.URL = getEnvWithDefault("GITLAB_URL", "https://gitlab.com")
 	config.GitLab.Token = os.Getenv("GITLAB_TOKEN")
 	config.GitLab.Username = os.Getenv("GITLAB_USERNAME")
+	config.GitLab.GraphQL = getEnvBool("GITLAB_GRAPHQL", true)
+	config.GitLab.Retries = getEnvInt("GITLAB_RETRIES", 3)
+	config.GitLab.RetryDelay = getEnvInt("GITLAB_RETRY_DELAY", 1)
+	config.GitLab.RetryMaxDelay = getEnvInt("GITLAB_RETRY_MAX_DELAY", 30)
+	config.GitLab.ForkMode = getEnvWithDefault("FORK_MODE", "auto")
+	config.GitLab.ForkNamespace = os.Getenv("FORK_NAMESPACE")
 
 	config.Claude.Command = getEnvWithDefault("CLAUDE_COMMAND", "claude")
 	config.Claude.Flags = getEnvWithDefault("CLAUDE_FLAGS", "--dangerously-skip-permissions --output-format stream-json --verbose")
+	config.Claude.FallbackModel = os.Getenv("CLAUDE_FALLBACK_MODEL")
+	config.Claude.OverflowFlags = getEnvWithDefault("CLAUDE_OVERFLOW_FLAGS", "--max-turns 40")
 
-	config.Projects.DefaultPath = os.Getenv("DEFAULT_PROJECT_PATH")
+	config.Projects.Aliases = getEnvStringMap("PROJECT_ALIASES")
+	for alias, path := range fileAliases {
+		if _, exists := config.Projects.Aliases[alias]; !exists {
+			config.Projects.Aliases[alias] = path
+		}
+	}
+	config.Projects.DefaultPath = config.ResolveProject(os.Getenv("DEFAULT_PROJECT_PATH"))
+
+	config.Open.Command = os.Getenv("OPEN_COMMAND")
+	config.Open.Target = getEnvWithDefault("OPEN_TARGET", "mr")
+
+	config.Locale.Default = getEnvWithDefault("LOCALE", "en")
+	config.Locale.Projects = make(map[string]string)
+	for project, language := range getEnvStringMap("PROJECT_LOCALES") {
+		config.Locale.Projects[config.ResolveProject(project)] = language
+	}
+	config.Locale.Dir = os.Getenv("LOCALES_DIR")
 
 	intervalStr := getEnvWithDefault("DAEMON_INTERVAL", "10")
 	interval, err := strconv.Atoi(intervalStr)
@@ -101,10 +672,277 @@ func Load() (*Config, error) {
 	config.Daemon.ClaudeLabel = getEnvWithDefault("CLAUDE_LABEL", "claude")
 	config.Daemon.ProcessLabel = getEnvWithDefault("PROCESS_LABEL", "picked_up_by_claude")
 	config.Daemon.ReviewLabel = getEnvWithDefault("REVIEW_LABEL", "waiting_human_review")
+	config.Daemon.ExcludeLabels = getEnvList("EXCLUDE_LABELS")
+	config.Daemon.TierLimits = getEnvIntMap("TIER_CONCURRENCY", "T1=1,T2=2,T3=4,T4=8")
+	config.Daemon.UntieredLimit = getEnvInt("UNTIERED_CONCURRENCY", 0)
+	config.Daemon.PriorityAging = getEnvInt("PRIORITY_AGING_HOURS", 24)
+	config.Daemon.MaxConcurrentReviews = getEnvInt("MAX_CONCURRENT_REVIEWS", 2)
+	config.Daemon.WaitForPipeline = getEnvBool("WAIT_FOR_PIPELINE", false)
+	config.Daemon.PipelineWaitTimeout = getEnvInt("PIPELINE_WAIT_TIMEOUT", 30)
+	config.Daemon.PipelineFixAttempts = getEnvInt("PIPELINE_FIX_ATTEMPTS", 1)
+	config.Daemon.CILintFixAttempts = getEnvInt("CI_LINT_FIX_ATTEMPTS", 2)
+	config.Daemon.BackfillRate = getEnvInt("BACKFILL_RATE", 6)
+	config.Daemon.StallTimeout = getEnvInt("STALL_TIMEOUT", 15)
+	config.Daemon.MaxTurns = getEnvInt("MAX_TURNS", 0)
+	config.Daemon.SessionTimeout = getEnvInt("SESSION_TIMEOUT", 0)
+	config.Daemon.TimeoutLabel = getEnvWithDefault("SESSION_TIMEOUT_LABEL", "claude_timeout")
+	config.Daemon.ActivityCheck = getEnvBool("ACTIVITY_CHECK", true)
+	config.Daemon.FullPollInterval = getEnvInt("FULL_POLL_INTERVAL", 5)
+
+	config.Environment.ToolchainCheck = getEnvBool("TOOLCHAIN_CHECK", true)
+	config.Environment.MinLanguageShare = getEnvInt("TOOLCHAIN_MIN_SHARE", 10)
+	config.Environment.Toolchains = getEnvStringMap("TOOLCHAINS")
+	config.Environment.Label = getEnvWithDefault("ENVIRONMENT_LABEL", "needs-environment")
+	config.Environment.Session = getEnvWithDefault("SESSION_ENVIRONMENT", "off")
+	config.Environment.Warmup = getEnvBool("DEPENDENCY_WARMUP", false)
+	config.Environment.WarmupTimeout = getEnvInt("WARMUP_TIMEOUT", 10)
+
+	config.Mirror.Enabled = getEnvBool("GIT_MIRRORS", false)
+	config.Mirror.Refresh = getEnvInt("MIRROR_REFRESH", 60)
+	config.Mirror.Projects = make(map[string]int)
+	for project, minutes := range getEnvStringMap("MIRROR_REFRESH_PROJECTS") {
+		n, err := strconv.Atoi(minutes)
+		if err != nil {
+			fmt.Printf("Warning: invalid MIRROR_REFRESH_PROJECTS entry '%s=%s', ignoring\n", project, minutes)
+			continue
+		}
+		config.Mirror.Projects[config.ResolveProject(project)] = n
+	}
+
+	config.Dedup.Enabled = getEnvBool("DEDUP_CHECK", false)
+	config.Dedup.Threshold = getEnvInt("DEDUP_THRESHOLD", 60)
+	config.Dedup.Label = getEnvWithDefault("DEDUP_LABEL", "possible-duplicate")
+
+	config.Approval.Enabled = getEnvBool("APPROVAL_CHECKPOINTS", false)
+	config.Approval.MigrationPaths = getEnvList("APPROVAL_MIGRATION_PATHS")
+	if len(config.Approval.MigrationPaths) == 0 {
+		config.Approval.MigrationPaths = []string{"migrations", "db/migrate"}
+	}
+	config.Approval.CIPaths = getEnvList("APPROVAL_CI_PATHS")
+	if len(config.Approval.CIPaths) == 0 {
+		config.Approval.CIPaths = []string{".gitlab-ci.yml", ".gitlab-ci", ".github/workflows"}
+	}
+	config.Approval.DeleteLines = getEnvInt("APPROVAL_DELETE_LINES", 200)
+	config.Approval.Label = getEnvWithDefault("APPROVAL_LABEL", "awaiting-approval")
+	config.Pause.Label = getEnvWithDefault("PAUSE_LABEL", "pause")
+	config.Pause.OnShutdown = getEnvBool("PAUSE_ON_SHUTDOWN", false)
+	config.Pause.ShutdownTimeout = getEnvInt("PAUSE_SHUTDOWN_TIMEOUT", 5)
+	config.AutoRebase.Enabled = getEnvBool("AUTO_REBASE", false)
+	config.AutoRebase.MinCommits = getEnvInt("AUTO_REBASE_MIN_COMMITS", 10)
+	config.WorkingHours.Hours = os.Getenv("WORKING_HOURS")
+	config.WorkingHours.Days = getEnvList("WORKING_DAYS")
+	config.WorkingHours.Timezone = os.Getenv("WORKING_TIMEZONE")
+
+	config.Mentions.Participants = getEnvBool("MENTION_PARTICIPANTS", false)
+	config.Mentions.OptOut = getEnvList("MENTION_OPT_OUT")
+	config.Mentions.OptOutMarker = getEnvWithDefault("MENTION_OPT_OUT_MARKER", "no-bot-pings")
+
+	config.MergeSync.Interval = getEnvInt("MERGE_SYNC_INTERVAL", 300)
+	config.MergeSync.SolvedLabel = getEnvWithDefault("SOLVED_LABEL", "solved")
+	config.MergeSync.CloseIssue = getEnvBool("CLOSE_ON_MERGE", false)
+	config.DescriptionSync.Enabled = getEnvBool("DESCRIPTION_SYNC", false)
+	config.FailureBundle.Enabled = getEnvBool("FAILURE_BUNDLES", true)
+	config.FailureBundle.Snippet = getEnvBool("FAILURE_BUNDLE_SNIPPET", false)
+	config.Transcript.Enabled = getEnvBool("TRANSCRIPTS", false)
+	config.Transcript.RetainDays = getEnvInt("TRANSCRIPT_RETAIN_DAYS", 30)
+
+	config.LabelSuggest.Enabled = getEnvBool("LABEL_SUGGESTIONS", false)
+	config.LabelSuggest.Prefixes = getEnvList("LABEL_SUGGEST_PREFIXES")
+	if len(config.LabelSuggest.Prefixes) == 0 {
+		config.LabelSuggest.Prefixes = []string{"area::", "component::"}
+	}
+	config.LabelSuggest.Apply = getEnvInt("LABEL_SUGGEST_APPLY", 80)
+	config.LabelSuggest.Min = getEnvInt("LABEL_SUGGEST_MIN", 40)
+	config.Triage.Enabled = getEnvBool("TRIAGE", false)
+	config.Triage.Model = getEnvWithDefault("TRIAGE_MODEL", "haiku")
+	config.Triage.Limit = getEnvInt("TRIAGE_LIMIT", 5)
+	config.Triage.Labels = getEnvStringMap("TRIAGE_LABELS")
+
+	config.KnowledgeBase.Enabled = getEnvBool("KNOWLEDGE_BASE", false)
+	config.KnowledgeBase.Limit = getEnvInt("KNOWLEDGE_BASE_LIMIT", 3)
+
+	config.DocIndex.Enabled = getEnvBool("DOC_INDEX", false)
+	config.DocIndex.TopK = getEnvInt("DOC_INDEX_TOP_K", 4)
+	config.DocIndex.Projects = make(map[string]int)
+	for project, k := range getEnvStringMap("DOC_INDEX_PROJECTS") {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			fmt.Printf("Warning: invalid DOC_INDEX_PROJECTS entry '%s=%s', ignoring\n", project, k)
+			continue
+		}
+		config.DocIndex.Projects[config.ResolveProject(project)] = n
+	}
+	config.DocIndex.Files = getEnvList("DOC_INDEX_FILES")
+	config.DocIndex.EmbeddingURL = getEnvWithDefault("EMBEDDING_URL", "http://localhost:11434/v1")
+	config.DocIndex.EmbeddingModel = getEnvWithDefault("EMBEDDING_MODEL", "nomic-embed-text")
+	config.DocIndex.EmbeddingAPIKey = os.Getenv("EMBEDDING_API_KEY")
+
+	config.Issues.Template = getEnvWithDefault("ISSUE_TEMPLATE", "Default")
+	config.Issues.Templates = getEnvStringMap("ISSUE_TEMPLATES")
+	config.Issues.Labels = getEnvList("ISSUE_LABELS")
+
+	config.Scaffold.Label = getEnvWithDefault("SCAFFOLD_LABEL", "scaffold")
+	config.Scaffold.Template = os.Getenv("SCAFFOLD_TEMPLATE")
+	config.Scaffold.Namespace = os.Getenv("SCAFFOLD_NAMESPACE")
+	config.Scaffold.Visibility = os.Getenv("SCAFFOLD_VISIBILITY")
+	config.Scaffold.IssueLabels = getEnvList("SCAFFOLD_ISSUE_LABELS")
+	config.Scaffold.CI = getEnvBool("SCAFFOLD_CI", true)
+
+	config.CI.SetupLabel = getEnvWithDefault("CI_SETUP_LABEL", "ci-setup")
+	config.CI.Deploy = os.Getenv("CI_DEPLOY")
+	config.CI.Region = getEnvWithDefault("CI_CLOUDRUN_REGION", "us-central1")
+	config.CI.TemplatesDir = os.Getenv("CI_TEMPLATES_DIR")
+
+	config.Terraform.Plan = getEnvBool("TERRAFORM_PLAN", false)
+	config.Terraform.Command = getEnvWithDefault("TERRAFORM_COMMAND", "terraform")
+	config.Terraform.PlanTimeout = getEnvInt("TERRAFORM_PLAN_TIMEOUT", 10)
+
+	config.MigrationCheck.Enabled = getEnvBool("MIGRATION_CHECK", false)
+	config.MigrationCheck.Image = getEnvWithDefault("MIGRATION_CHECK_IMAGE", "postgres:16")
+	config.MigrationCheck.Timeout = getEnvInt("MIGRATION_CHECK_TIMEOUT", 10)
+
+	config.Coverage.Enabled = getEnvBool("COVERAGE_DELTA", false)
+	config.Coverage.Command = os.Getenv("COVERAGE_COMMAND")
+	config.Coverage.Threshold = getEnvFloat("COVERAGE_THRESHOLD", 1)
+	config.Coverage.Timeout = getEnvInt("COVERAGE_TIMEOUT", 15)
+
+	config.Benchmark.Label = getEnvWithDefault("BENCHMARK_LABEL", "performance")
+	config.Benchmark.Command = os.Getenv("BENCHMARK_COMMAND")
+	config.Benchmark.Timeout = getEnvInt("BENCHMARK_TIMEOUT", 20)
+
+	config.Progress.Enabled = getEnvBool("PROGRESS_COMMENT", false)
+	config.Progress.Interval = getEnvInt("PROGRESS_INTERVAL", 60)
+	if config.Progress.Interval <= 0 {
+		config.Progress.Interval = 60
+	}
+
+	config.Data.Dir = expandHome(getEnvWithDefault("DATA_DIR", filepath.Join(os.Getenv("HOME"), ".automagic")))
+	config.Prompts.Dir = expandHome(getEnvWithDefault("PROMPTS_DIR", filepath.Join(config.Data.Dir, "prompts")))
+	config.Prompts.Labels = getEnvStringMap("PROMPT_LABELS")
+	config.Database.BackupRetain = getEnvInt("DB_BACKUP_RETAIN", 7)
+	config.Database.RetainSucceededDays = getEnvInt("SESSION_RETAIN_SUCCEEDED_DAYS", 14)
+	config.Database.RetainFailedDays = getEnvInt("SESSION_RETAIN_FAILED_DAYS", 90)
+	config.Database.Encrypt = getEnvBool("SESSION_ENCRYPTION", false)
+	config.Database.EncryptionKey = os.Getenv("SESSION_ENCRYPTION_KEY")
+
+	config.Logging.Level = getEnvWithDefault("LOG_LEVEL", "info")
+	config.Logging.Format = getEnvWithDefault("LOG_FORMAT", "text")
+	// LOG_FILE=- logs to stdout
+	config.Logging.File = expandHome(getEnvWithDefault("LOG_FILE", filepath.Join(config.Data.Dir, "automagic.log")))
+	if config.Logging.File == "-" {
+		config.Logging.File = ""
+	}
+
+	config.Heartbeat.File = os.Getenv("HEARTBEAT_FILE")
+
+	config.Metrics.Addr = os.Getenv("METRICS_ADDR")
+	config.Metrics.PushURL = os.Getenv("METRICS_PUSH_URL")
+	config.Metrics.PushFormat = getEnvWithDefault("METRICS_PUSH_FORMAT", "pushgateway")
+	config.Metrics.PushInterval = getEnvInt("METRICS_PUSH_INTERVAL", 60)
+	if config.Metrics.PushInterval <= 0 {
+		config.Metrics.PushInterval = 60
+	}
+	config.Metrics.PushToken = os.Getenv("METRICS_PUSH_TOKEN")
+	hostname, _ := os.Hostname()
+	config.Metrics.Instance = getEnvWithDefault("METRICS_INSTANCE", hostname)
+
+	config.ErrorReport.SentryDSN = os.Getenv("SENTRY_DSN")
+	config.ErrorReport.WebhookURL = os.Getenv("ERROR_WEBHOOK_URL")
+	config.ErrorReport.Environment = getEnvWithDefault("SENTRY_ENVIRONMENT", getEnvWithDefault("AUTOMAGIC_ENV", "production"))
+	config.ErrorReport.APIFailures = getEnvInt("ERROR_REPORT_API_FAILURES", 3)
+
+	config.Trackers.Statuses = getEnvStringMap("TRACKER_STATUSES")
+	config.Trackers.JiraURL = os.Getenv("JIRA_URL")
+	config.Trackers.JiraEmail = os.Getenv("JIRA_EMAIL")
+	config.Trackers.JiraToken = os.Getenv("JIRA_TOKEN")
+	config.Trackers.LinearAPIKey = os.Getenv("LINEAR_API_KEY")
+	config.Trackers.NotionToken = os.Getenv("NOTION_TOKEN")
+	config.Trackers.NotionDatabase = os.Getenv("NOTION_DATABASE_ID")
+	config.Trackers.NotionURLProperty = getEnvWithDefault("NOTION_URL_PROPERTY", "GitLab Issue")
+	config.Trackers.NotionStatusProperty = getEnvWithDefault("NOTION_STATUS_PROPERTY", "Status")
+
+	config.Telemetry.Enabled = getEnvBool("TELEMETRY", false)
+	config.Telemetry.Endpoint = os.Getenv("TELEMETRY_ENDPOINT")
+
+	config.Control.Addr = os.Getenv("CONTROL_ADDR")
+	config.Control.Token = os.Getenv("CONTROL_TOKEN")
+	config.Control.FeedToken = os.Getenv("CONTROL_FEED_TOKEN")
+
+	config.ChatOps.Addr = os.Getenv("CHATOPS_ADDR")
+	config.ChatOps.Platform = getEnvWithDefault("CHATOPS_PLATFORM", "slack")
+	config.ChatOps.Token = os.Getenv("CHATOPS_TOKEN")
+	config.ChatOps.SigningSecret = os.Getenv("CHATOPS_SIGNING_SECRET")
+	config.ChatOps.WebhookURL = os.Getenv("CHATOPS_WEBHOOK_URL")
+
+	config.Webhook.Addr = os.Getenv("WEBHOOK_ADDR")
+	config.Webhook.Secret = os.Getenv("WEBHOOK_SECRET")
+	config.Webhook.MaxAge = getEnvInt("WEBHOOK_MAX_AGE", 300)
+	config.Webhook.IssueEvents = getEnvBool("WEBHOOK_ISSUE_EVENTS", true)
+	config.Webhook.NoteEvents = getEnvBool("WEBHOOK_NOTE_EVENTS", true)
+	config.Webhook.MergeRequestEvents = getEnvBool("WEBHOOK_MR_EVENTS", true)
+	config.Webhook.PipelineEvents = getEnvBool("WEBHOOK_PIPELINE_EVENTS", true)
+	config.Webhook.URL = os.Getenv("WEBHOOK_URL")
+	config.Webhook.PollInterval = getEnvInt("WEBHOOK_POLL_INTERVAL", 300)
+
+	config.Queue.URL = os.Getenv("QUEUE_URL")
+	config.Queue.Role = getEnvWithDefault("QUEUE_ROLE", "coordinator")
+	config.Queue.Lease = getEnvInt("QUEUE_LEASE", 10)
+	config.Queue.WorkerID = os.Getenv("WORKER_ID")
+	if config.Queue.WorkerID == "" {
+		hostname, _ := os.Hostname()
+		config.Queue.WorkerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	config.Queue.Capabilities = getEnvStringMap("WORKER_CAPABILITIES")
+	config.Queue.Routes = getEnvStringMap("QUEUE_ROUTES")
+	config.Queue.RouteLanguage = getEnvBool("QUEUE_ROUTE_LANGUAGE", false)
 
 	return &config, nil
 }
 
+// ResolveProject returns the project path an alias stands for, or name itself
+// when it is not an alias
+func (c *Config) ResolveProject(name string) string {
+	if path, ok := c.Projects.Aliases[name]; ok {
+		return path
+	}
+	return name
+}
+
+// ParseIssueRef reads an issue given as a number (12 or #12), a project path
+// or alias and number (backend#12, group/app#12), or an issue URL, and returns
+// its project path and IID. Bare numbers belong to DEFAULT_PROJECT_PATH.
+func (c *Config) ParseIssueRef(ref string) (string, int, error) {
+	ref = strings.TrimSpace(ref)
+	project := c.Projects.DefaultPath
+	number := strings.TrimPrefix(ref, "#")
+
+	if strings.Contains(ref, "://") {
+		parsed, err := url.Parse(ref)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid issue URL %q: %v", ref, err)
+		}
+		path, iid, found := strings.Cut(strings.Trim(parsed.Path, "/"), "/-/issues/")
+		if !found {
+			return "", 0, fmt.Errorf("%q is not an issue URL", ref)
+		}
+		// Installations under a relative URL root carry it before the project
+		if base, err := url.Parse(c.GitLab.URL); err == nil {
+			if root := strings.Trim(base.Path, "/"); root != "" {
+				path = strings.TrimPrefix(path, root+"/")
+			}
+		}
+		project, number = path, strings.Trim(iid, "/")
+	} else if name, iid, found := strings.Cut(ref, "#"); found && name != "" {
+		project, number = c.ResolveProject(name), iid
+	}
+
+	iid, err := strconv.Atoi(number)
+	if err != nil || iid <= 0 {
+		return "", 0, fmt.Errorf("invalid issue %q, use 12, alias#12, group/app#12 or an issue URL", ref)
+	}
+	return project, iid, nil
+}
+
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -112,6 +950,103 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(os.Getenv("HOME"), rest)
+	}
+	return path
+}
+
+// getEnvList reads a comma-separated environment variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvInt reads an integer environment variable, warning and falling back on bad input
+func getEnvInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		fmt.Printf("Warning: invalid %s value '%s', using default %d\n", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat reads a decimal environment variable, warning and falling back on bad input
+func getEnvFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		fmt.Printf("Warning: invalid %s value '%s', using default %g\n", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvBool reads a boolean environment variable (true/false, 1/0, yes/no)
+func getEnvBool(key string, defaultValue bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "":
+		return defaultValue
+	case "true", "1", "yes", "on":
+		return true
+	case "false", "0", "no", "off":
+		return false
+	}
+
+	fmt.Printf("Warning: invalid %s value '%s', using default %t\n", key, os.Getenv(key), defaultValue)
+	return defaultValue
+}
+
+// getEnvIntMap reads a comma-separated list of KEY=number pairs (e.g. "T1=1,T4=8")
+func getEnvIntMap(key, defaultValue string) map[string]int {
+	values := make(map[string]int)
+	for _, pair := range strings.Split(getEnvWithDefault(key, defaultValue), ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			fmt.Printf("Warning: invalid %s entry '%s', ignoring\n", key, pair)
+			continue
+		}
+		values[strings.ToUpper(strings.TrimSpace(parts[0]))] = number
+	}
+	return values
+}
+
+// getEnvStringMap reads a comma-separated list of key=value pairs (e.g. "group/app=th")
+func getEnvStringMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getEnvList(key) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			fmt.Printf("Warning: invalid %s entry '%s', ignoring\n", key, pair)
+			continue
+		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return values
+}
+
 func Validate(config *Config) error {
 	if config.GitLab.Token == "" {
 		return fmt.Errorf("GitLab token is required. Set GITLAB_TOKEN environment variable")
@@ -165,19 +1100,197 @@ func SaveProjectSelection(projectPath string) error {
 	fmt.Fprintln(file, "")
 
 	// Write all variables in a logical order
+	writeEnvVar(file, "AUTOMAGIC_CONFIG", existingVars)
+	writeEnvVar(file, "AUTOMAGIC_ENV", existingVars)
 	writeEnvVar(file, "GITLAB_URL", existingVars)
 	writeEnvVar(file, "GITLAB_TOKEN", existingVars)
 	writeEnvVar(file, "GITLAB_USERNAME", existingVars)
+	writeEnvVar(file, "GITLAB_GRAPHQL", existingVars)
+	writeEnvVar(file, "GITLAB_RETRIES", existingVars)
+	writeEnvVar(file, "GITLAB_RETRY_DELAY", existingVars)
+	writeEnvVar(file, "GITLAB_RETRY_MAX_DELAY", existingVars)
+	writeEnvVar(file, "FORK_MODE", existingVars)
+	writeEnvVar(file, "FORK_NAMESPACE", existingVars)
 	fmt.Fprintln(file, "")
 	writeEnvVar(file, "CLAUDE_COMMAND", existingVars)
 	writeEnvVar(file, "CLAUDE_FLAGS", existingVars)
+	writeEnvVar(file, "CLAUDE_FALLBACK_MODEL", existingVars)
+	writeEnvVar(file, "CLAUDE_OVERFLOW_FLAGS", existingVars)
 	fmt.Fprintln(file, "")
 	writeEnvVar(file, "DEFAULT_PROJECT_PATH", existingVars)
+	writeEnvVar(file, "PROJECT_ALIASES", existingVars)
 	fmt.Fprintln(file, "")
 	writeEnvVar(file, "DAEMON_INTERVAL", existingVars)
 	writeEnvVar(file, "CLAUDE_LABEL", existingVars)
 	writeEnvVar(file, "PROCESS_LABEL", existingVars)
 	writeEnvVar(file, "REVIEW_LABEL", existingVars)
+	writeEnvVar(file, "EXCLUDE_LABELS", existingVars)
+	writeEnvVar(file, "TIER_CONCURRENCY", existingVars)
+	writeEnvVar(file, "UNTIERED_CONCURRENCY", existingVars)
+	writeEnvVar(file, "PRIORITY_AGING_HOURS", existingVars)
+	writeEnvVar(file, "MAX_CONCURRENT_REVIEWS", existingVars)
+	writeEnvVar(file, "WAIT_FOR_PIPELINE", existingVars)
+	writeEnvVar(file, "PIPELINE_WAIT_TIMEOUT", existingVars)
+	writeEnvVar(file, "PIPELINE_FIX_ATTEMPTS", existingVars)
+	writeEnvVar(file, "CI_LINT_FIX_ATTEMPTS", existingVars)
+	writeEnvVar(file, "BACKFILL_RATE", existingVars)
+	writeEnvVar(file, "STALL_TIMEOUT", existingVars)
+	writeEnvVar(file, "MAX_TURNS", existingVars)
+	writeEnvVar(file, "SESSION_TIMEOUT", existingVars)
+	writeEnvVar(file, "SESSION_TIMEOUT_LABEL", existingVars)
+	writeEnvVar(file, "ACTIVITY_CHECK", existingVars)
+	writeEnvVar(file, "FULL_POLL_INTERVAL", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "TOOLCHAIN_CHECK", existingVars)
+	writeEnvVar(file, "TOOLCHAIN_MIN_SHARE", existingVars)
+	writeEnvVar(file, "TOOLCHAINS", existingVars)
+	writeEnvVar(file, "ENVIRONMENT_LABEL", existingVars)
+	writeEnvVar(file, "SESSION_ENVIRONMENT", existingVars)
+	writeEnvVar(file, "DEPENDENCY_WARMUP", existingVars)
+	writeEnvVar(file, "WARMUP_TIMEOUT", existingVars)
+	writeEnvVar(file, "GIT_MIRRORS", existingVars)
+	writeEnvVar(file, "MIRROR_REFRESH", existingVars)
+	writeEnvVar(file, "MIRROR_REFRESH_PROJECTS", existingVars)
+	writeEnvVar(file, "DEDUP_CHECK", existingVars)
+	writeEnvVar(file, "DEDUP_THRESHOLD", existingVars)
+	writeEnvVar(file, "DEDUP_LABEL", existingVars)
+	writeEnvVar(file, "APPROVAL_CHECKPOINTS", existingVars)
+	writeEnvVar(file, "APPROVAL_MIGRATION_PATHS", existingVars)
+	writeEnvVar(file, "APPROVAL_CI_PATHS", existingVars)
+	writeEnvVar(file, "APPROVAL_DELETE_LINES", existingVars)
+	writeEnvVar(file, "APPROVAL_LABEL", existingVars)
+	writeEnvVar(file, "PAUSE_LABEL", existingVars)
+	writeEnvVar(file, "PAUSE_ON_SHUTDOWN", existingVars)
+	writeEnvVar(file, "PAUSE_SHUTDOWN_TIMEOUT", existingVars)
+	writeEnvVar(file, "AUTO_REBASE", existingVars)
+	writeEnvVar(file, "AUTO_REBASE_MIN_COMMITS", existingVars)
+	writeEnvVar(file, "WORKING_HOURS", existingVars)
+	writeEnvVar(file, "WORKING_DAYS", existingVars)
+	writeEnvVar(file, "WORKING_TIMEZONE", existingVars)
+	writeEnvVar(file, "MENTION_PARTICIPANTS", existingVars)
+	writeEnvVar(file, "MENTION_OPT_OUT", existingVars)
+	writeEnvVar(file, "MENTION_OPT_OUT_MARKER", existingVars)
+	writeEnvVar(file, "MERGE_SYNC_INTERVAL", existingVars)
+	writeEnvVar(file, "SOLVED_LABEL", existingVars)
+	writeEnvVar(file, "CLOSE_ON_MERGE", existingVars)
+	writeEnvVar(file, "DESCRIPTION_SYNC", existingVars)
+	writeEnvVar(file, "FAILURE_BUNDLES", existingVars)
+	writeEnvVar(file, "FAILURE_BUNDLE_SNIPPET", existingVars)
+	writeEnvVar(file, "TRANSCRIPTS", existingVars)
+	writeEnvVar(file, "TRANSCRIPT_RETAIN_DAYS", existingVars)
+	writeEnvVar(file, "LABEL_SUGGESTIONS", existingVars)
+	writeEnvVar(file, "LABEL_SUGGEST_PREFIXES", existingVars)
+	writeEnvVar(file, "LABEL_SUGGEST_APPLY", existingVars)
+	writeEnvVar(file, "LABEL_SUGGEST_MIN", existingVars)
+	writeEnvVar(file, "TRIAGE", existingVars)
+	writeEnvVar(file, "TRIAGE_MODEL", existingVars)
+	writeEnvVar(file, "TRIAGE_LIMIT", existingVars)
+	writeEnvVar(file, "TRIAGE_LABELS", existingVars)
+	writeEnvVar(file, "KNOWLEDGE_BASE", existingVars)
+	writeEnvVar(file, "KNOWLEDGE_BASE_LIMIT", existingVars)
+	writeEnvVar(file, "DOC_INDEX", existingVars)
+	writeEnvVar(file, "DOC_INDEX_TOP_K", existingVars)
+	writeEnvVar(file, "DOC_INDEX_PROJECTS", existingVars)
+	writeEnvVar(file, "DOC_INDEX_FILES", existingVars)
+	writeEnvVar(file, "EMBEDDING_URL", existingVars)
+	writeEnvVar(file, "EMBEDDING_MODEL", existingVars)
+	writeEnvVar(file, "EMBEDDING_API_KEY", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "ISSUE_TEMPLATE", existingVars)
+	writeEnvVar(file, "ISSUE_TEMPLATES", existingVars)
+	writeEnvVar(file, "ISSUE_LABELS", existingVars)
+	writeEnvVar(file, "SCAFFOLD_LABEL", existingVars)
+	writeEnvVar(file, "SCAFFOLD_TEMPLATE", existingVars)
+	writeEnvVar(file, "SCAFFOLD_NAMESPACE", existingVars)
+	writeEnvVar(file, "SCAFFOLD_VISIBILITY", existingVars)
+	writeEnvVar(file, "SCAFFOLD_ISSUE_LABELS", existingVars)
+	writeEnvVar(file, "SCAFFOLD_CI", existingVars)
+	writeEnvVar(file, "CI_SETUP_LABEL", existingVars)
+	writeEnvVar(file, "CI_DEPLOY", existingVars)
+	writeEnvVar(file, "CI_CLOUDRUN_REGION", existingVars)
+	writeEnvVar(file, "CI_TEMPLATES_DIR", existingVars)
+	writeEnvVar(file, "TERRAFORM_PLAN", existingVars)
+	writeEnvVar(file, "TERRAFORM_COMMAND", existingVars)
+	writeEnvVar(file, "TERRAFORM_PLAN_TIMEOUT", existingVars)
+	writeEnvVar(file, "MIGRATION_CHECK", existingVars)
+	writeEnvVar(file, "MIGRATION_CHECK_IMAGE", existingVars)
+	writeEnvVar(file, "MIGRATION_CHECK_TIMEOUT", existingVars)
+	writeEnvVar(file, "COVERAGE_DELTA", existingVars)
+	writeEnvVar(file, "COVERAGE_COMMAND", existingVars)
+	writeEnvVar(file, "COVERAGE_THRESHOLD", existingVars)
+	writeEnvVar(file, "COVERAGE_TIMEOUT", existingVars)
+	writeEnvVar(file, "BENCHMARK_LABEL", existingVars)
+	writeEnvVar(file, "BENCHMARK_COMMAND", existingVars)
+	writeEnvVar(file, "BENCHMARK_TIMEOUT", existingVars)
+	writeEnvVar(file, "PROGRESS_COMMENT", existingVars)
+	writeEnvVar(file, "PROGRESS_INTERVAL", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "PROMPTS_DIR", existingVars)
+	writeEnvVar(file, "PROMPT_LABELS", existingVars)
+	writeEnvVar(file, "LOCALE", existingVars)
+	writeEnvVar(file, "PROJECT_LOCALES", existingVars)
+	writeEnvVar(file, "LOCALES_DIR", existingVars)
+	writeEnvVar(file, "OPEN_COMMAND", existingVars)
+	writeEnvVar(file, "OPEN_TARGET", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "WEBHOOK_ADDR", existingVars)
+	writeEnvVar(file, "WEBHOOK_SECRET", existingVars)
+	writeEnvVar(file, "WEBHOOK_MAX_AGE", existingVars)
+	writeEnvVar(file, "WEBHOOK_ISSUE_EVENTS", existingVars)
+	writeEnvVar(file, "WEBHOOK_NOTE_EVENTS", existingVars)
+	writeEnvVar(file, "WEBHOOK_MR_EVENTS", existingVars)
+	writeEnvVar(file, "WEBHOOK_PIPELINE_EVENTS", existingVars)
+	writeEnvVar(file, "WEBHOOK_URL", existingVars)
+	writeEnvVar(file, "WEBHOOK_POLL_INTERVAL", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "QUEUE_URL", existingVars)
+	writeEnvVar(file, "QUEUE_ROLE", existingVars)
+	writeEnvVar(file, "QUEUE_LEASE", existingVars)
+	writeEnvVar(file, "WORKER_ID", existingVars)
+	writeEnvVar(file, "WORKER_CAPABILITIES", existingVars)
+	writeEnvVar(file, "QUEUE_ROUTES", existingVars)
+	writeEnvVar(file, "QUEUE_ROUTE_LANGUAGE", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "DATA_DIR", existingVars)
+	writeEnvVar(file, "DB_BACKUP_RETAIN", existingVars)
+	writeEnvVar(file, "SESSION_RETAIN_SUCCEEDED_DAYS", existingVars)
+	writeEnvVar(file, "SESSION_RETAIN_FAILED_DAYS", existingVars)
+	writeEnvVar(file, "SESSION_ENCRYPTION", existingVars)
+	writeEnvVar(file, "SESSION_ENCRYPTION_KEY", existingVars)
+	fmt.Fprintln(file, "")
+	writeEnvVar(file, "LOG_LEVEL", existingVars)
+	writeEnvVar(file, "LOG_FORMAT", existingVars)
+	writeEnvVar(file, "LOG_FILE", existingVars)
+	writeEnvVar(file, "HEARTBEAT_FILE", existingVars)
+	writeEnvVar(file, "METRICS_ADDR", existingVars)
+	writeEnvVar(file, "METRICS_PUSH_URL", existingVars)
+	writeEnvVar(file, "METRICS_PUSH_FORMAT", existingVars)
+	writeEnvVar(file, "METRICS_PUSH_INTERVAL", existingVars)
+	writeEnvVar(file, "METRICS_PUSH_TOKEN", existingVars)
+	writeEnvVar(file, "METRICS_INSTANCE", existingVars)
+	writeEnvVar(file, "SENTRY_DSN", existingVars)
+	writeEnvVar(file, "SENTRY_ENVIRONMENT", existingVars)
+	writeEnvVar(file, "ERROR_WEBHOOK_URL", existingVars)
+	writeEnvVar(file, "ERROR_REPORT_API_FAILURES", existingVars)
+	writeEnvVar(file, "TRACKER_STATUSES", existingVars)
+	writeEnvVar(file, "JIRA_URL", existingVars)
+	writeEnvVar(file, "JIRA_EMAIL", existingVars)
+	writeEnvVar(file, "JIRA_TOKEN", existingVars)
+	writeEnvVar(file, "LINEAR_API_KEY", existingVars)
+	writeEnvVar(file, "NOTION_TOKEN", existingVars)
+	writeEnvVar(file, "NOTION_DATABASE_ID", existingVars)
+	writeEnvVar(file, "NOTION_URL_PROPERTY", existingVars)
+	writeEnvVar(file, "NOTION_STATUS_PROPERTY", existingVars)
+	writeEnvVar(file, "TELEMETRY", existingVars)
+	writeEnvVar(file, "TELEMETRY_ENDPOINT", existingVars)
+	writeEnvVar(file, "CONTROL_ADDR", existingVars)
+	writeEnvVar(file, "CONTROL_TOKEN", existingVars)
+	writeEnvVar(file, "CONTROL_FEED_TOKEN", existingVars)
+	writeEnvVar(file, "CHATOPS_ADDR", existingVars)
+	writeEnvVar(file, "CHATOPS_PLATFORM", existingVars)
+	writeEnvVar(file, "CHATOPS_TOKEN", existingVars)
+	writeEnvVar(file, "CHATOPS_SIGNING_SECRET", existingVars)
+	writeEnvVar(file, "CHATOPS_WEBHOOK_URL", existingVars)
 
 	return nil
 }
@@ -199,14 +1312,291 @@ func PrintConfig(config *Config) {
 	fmt.Printf("  GitLab URL: %s\n", config.GitLab.URL)
 	fmt.Printf("  GitLab Username: %s\n", config.GitLab.Username)
 	fmt.Printf("  GitLab Token: %s\n", maskToken(config.GitLab.Token))
+	fmt.Printf("  GitLab GraphQL: %v\n", config.GitLab.GraphQL)
+	fmt.Printf("  GitLab Retries: %d (backoff %ds up to %ds)\n", config.GitLab.Retries, config.GitLab.RetryDelay, config.GitLab.RetryMaxDelay)
+	fmt.Printf("  Fork Mode: %s\n", config.GitLab.ForkMode)
+	if config.GitLab.ForkNamespace != "" {
+		fmt.Printf("  Fork Namespace: %s\n", config.GitLab.ForkNamespace)
+	}
 	fmt.Printf("  Claude Command: %s\n", config.Claude.Command)
 	fmt.Printf("  Claude Flags: %s\n", config.Claude.Flags)
+	if config.Claude.FallbackModel != "" {
+		fmt.Printf("  Claude Fallback Model: %s\n", config.Claude.FallbackModel)
+	}
+	if config.Source.File != "" {
+		fmt.Printf("  Config File: %s\n", config.Source.File)
+		if config.Source.Environment != "" {
+			fmt.Printf("    Environment: %s\n", config.Source.Environment)
+		}
+		if config.Source.ProjectOverrides {
+			fmt.Printf("    Project Overrides: %s\n", config.Source.Project)
+		}
+	}
 	fmt.Printf("  Default Project: %s\n", config.Projects.DefaultPath)
+	for alias, path := range config.Projects.Aliases {
+		fmt.Printf("    Alias %s: %s\n", alias, path)
+	}
 	fmt.Printf("  Daemon Interval: %d seconds\n", config.Daemon.Interval)
+	if config.Daemon.ActivityCheck {
+		fmt.Printf("  Activity Check: enabled (full poll at least every %d minutes)\n", config.Daemon.FullPollInterval)
+	}
 	fmt.Printf("  Labels: %s → %s → %s\n",
 		config.Daemon.ClaudeLabel,
 		config.Daemon.ProcessLabel,
 		config.Daemon.ReviewLabel)
+	if len(config.Daemon.ExcludeLabels) > 0 {
+		fmt.Printf("  Excluded Labels: %s\n", strings.Join(config.Daemon.ExcludeLabels, ", "))
+	}
+	fmt.Printf("  Tier Concurrency: T1=%d T2=%d T3=%d T4=%d (untiered: %d)\n",
+		config.Daemon.TierLimits["T1"],
+		config.Daemon.TierLimits["T2"],
+		config.Daemon.TierLimits["T3"],
+		config.Daemon.TierLimits["T4"],
+		config.Daemon.UntieredLimit)
+	if config.Daemon.PriorityAging > 0 {
+		fmt.Printf("  Priority Aging: one level per %d hours open\n", config.Daemon.PriorityAging)
+	}
+	if config.Daemon.WaitForPipeline {
+		fmt.Printf("  Wait For Pipeline: up to %d minutes\n", config.Daemon.PipelineWaitTimeout)
+		fmt.Printf("  Pipeline Fix Attempts: %d\n", config.Daemon.PipelineFixAttempts)
+	}
+	fmt.Printf("  CI Lint Fix Attempts: %d\n", config.Daemon.CILintFixAttempts)
+	fmt.Printf("  Session Watchdog: stall after %d minutes, max turns %d\n",
+		config.Daemon.StallTimeout,
+		config.Daemon.MaxTurns)
+	if config.Daemon.SessionTimeout > 0 {
+		fmt.Printf("  Session Timeout: %d minutes → %s\n", config.Daemon.SessionTimeout, config.Daemon.TimeoutLabel)
+	}
+	if config.Environment.ToolchainCheck {
+		fmt.Printf("  Toolchain Check: languages over %d%%, missing → %s\n",
+			config.Environment.MinLanguageShare, config.Environment.Label)
+		for language, commands := range config.Environment.Toolchains {
+			fmt.Printf("    %s: %s\n", language, commands)
+		}
+	}
+	if config.Environment.Session != "off" {
+		fmt.Printf("  Session Environment: %s\n", config.Environment.Session)
+	}
+	if config.Environment.Warmup {
+		fmt.Printf("  Dependency Warm-up: up to %d minutes\n", config.Environment.WarmupTimeout)
+	}
+	if config.Dedup.Enabled {
+		fmt.Printf("  Duplicate Check: %d%% similar → %s\n", config.Dedup.Threshold, config.Dedup.Label)
+	}
+	if config.Approval.Enabled {
+		fmt.Printf("  Approval Checkpoints: migrations, CI config, deleting files over %d lines → %s\n",
+			config.Approval.DeleteLines, config.Approval.Label)
+	}
+	if config.Pause.OnShutdown {
+		fmt.Printf("  Pause on Shutdown: waits up to %d minutes, label %s\n", config.Pause.ShutdownTimeout, config.Pause.Label)
+	}
+	if config.AutoRebase.Enabled {
+		fmt.Printf("  Auto Rebase: when the default branch is %d commits ahead\n", config.AutoRebase.MinCommits)
+	}
+	if config.WorkingHours.Hours != "" {
+		days := "Mon-Fri"
+		if len(config.WorkingHours.Days) > 0 {
+			days = strings.Join(config.WorkingHours.Days, ",")
+		}
+		timezone := config.WorkingHours.Timezone
+		if timezone == "" {
+			timezone = "local time"
+		}
+		fmt.Printf("  Working Hours: %s %s (%s)\n", days, config.WorkingHours.Hours, timezone)
+	}
+	if config.Mentions.Participants {
+		fmt.Printf("  Mentions: issue participants, except %d opted out and bios with %q\n",
+			len(config.Mentions.OptOut), config.Mentions.OptOutMarker)
+	}
+	if config.MergeSync.Interval > 0 {
+		fmt.Printf("  Merge Sync: every %d seconds, merged → %s (close issue: %v)\n",
+			config.MergeSync.Interval, config.MergeSync.SolvedLabel, config.MergeSync.CloseIssue)
+	}
+	if config.DescriptionSync.Enabled {
+		fmt.Printf("  Description Sync: enabled\n")
+	}
+	if config.FailureBundle.Enabled {
+		fmt.Printf("  Failure Bundles: enabled (snippets: %v)\n", config.FailureBundle.Snippet)
+	}
+	if config.Transcript.Enabled {
+		fmt.Printf("  Transcripts: kept %s\n", retentionDays(config.Transcript.RetainDays))
+	}
+	if config.LabelSuggest.Enabled {
+		fmt.Printf("  Label Suggestions: %s, added at %d%%, proposed at %d%%\n",
+			strings.Join(config.LabelSuggest.Prefixes, ", "), config.LabelSuggest.Apply, config.LabelSuggest.Min)
+	}
+	if config.Triage.Enabled {
+		fmt.Printf("  Triage: unlabeled issues with %s, up to %d per cycle\n", config.Triage.Model, config.Triage.Limit)
+	}
+	if config.KnowledgeBase.Enabled {
+		fmt.Printf("  Knowledge Base: up to %d related sessions per prompt\n", config.KnowledgeBase.Limit)
+	}
+	if config.DocIndex.Enabled {
+		fmt.Printf("  Doc Index: top %d passages, %s via %s\n", config.DocIndex.TopK, config.DocIndex.EmbeddingModel, config.DocIndex.EmbeddingURL)
+	}
+	if config.Mirror.Enabled {
+		fmt.Printf("  Git Mirrors: refreshed every %d minutes\n", config.Mirror.Refresh)
+		for project, minutes := range config.Mirror.Projects {
+			fmt.Printf("    %s: every %d minutes\n", project, minutes)
+		}
+	}
+	fmt.Printf("  Issue Template: %s\n", config.Issues.Template)
+	for kind, template := range config.Issues.Templates {
+		fmt.Printf("    %s: %s\n", kind, template)
+	}
+	if len(config.Issues.Labels) > 0 {
+		fmt.Printf("  Issue Labels: %s\n", strings.Join(config.Issues.Labels, ", "))
+	}
+	if config.Scaffold.Template != "" {
+		fmt.Printf("  Scaffold: %s issues → projects from %s\n", config.Scaffold.Label, config.Scaffold.Template)
+		if config.Scaffold.Namespace != "" {
+			fmt.Printf("  Scaffold Namespace: %s\n", config.Scaffold.Namespace)
+		}
+	}
+	fmt.Printf("  CI Setup: %s issues", config.CI.SetupLabel)
+	if config.CI.Deploy != "" {
+		fmt.Printf(", deploying to %s in %s", config.CI.Deploy, config.CI.Region)
+	}
+	fmt.Println()
+	if config.CI.TemplatesDir != "" {
+		fmt.Printf("  CI Templates Directory: %s\n", config.CI.TemplatesDir)
+	}
+	if config.Terraform.Plan {
+		fmt.Printf("  Terraform Plan: %s, %d minute timeout per module\n", config.Terraform.Command, config.Terraform.PlanTimeout)
+	}
+	if config.MigrationCheck.Enabled {
+		fmt.Printf("  Migration Check: %s, %d minute timeout per directory\n", config.MigrationCheck.Image, config.MigrationCheck.Timeout)
+	}
+	if config.Coverage.Enabled {
+		command := config.Coverage.Command
+		if command == "" {
+			command = "go test (Go modules only)"
+		}
+		fmt.Printf("  Coverage Delta: %s, flagging drops over %g points\n", command, config.Coverage.Threshold)
+	}
+	if config.Benchmark.Label != "" {
+		command := config.Benchmark.Command
+		if command == "" {
+			command = "go test -bench (Go modules only)"
+		}
+		fmt.Printf("  Benchmarks: %s for issues labeled %s\n", command, config.Benchmark.Label)
+	}
+	if config.Progress.Enabled {
+		fmt.Printf("  Progress Comment: updated every %d seconds\n", config.Progress.Interval)
+	}
+	fmt.Printf("  Prompts Directory: %s\n", config.Prompts.Dir)
+	for label, workflow := range config.Prompts.Labels {
+		fmt.Printf("    Label %s: %s prompt\n", label, workflow)
+	}
+	fmt.Printf("  Comment Language: %s\n", config.Locale.Default)
+	for project, language := range config.Locale.Projects {
+		fmt.Printf("    %s: %s\n", project, language)
+	}
+	if config.Open.Command != "" {
+		fmt.Printf("  Open Command: %s\n", config.Open.Command)
+	}
+	fmt.Printf("  Open Target: %s\n", config.Open.Target)
+	if config.Webhook.Addr != "" {
+		fmt.Printf("  Webhook: %s (secret: %s)\n", config.Webhook.Addr, maskToken(config.Webhook.Secret))
+		if config.Webhook.URL != "" {
+			fmt.Printf("  Webhook URL: %s, polling every %d seconds in webhook mode\n", config.Webhook.URL, config.Webhook.PollInterval)
+		}
+	}
+	if config.Control.Addr != "" {
+		fmt.Printf("  Control API: %s (token: %s)\n", config.Control.Addr, maskToken(config.Control.Token))
+		if config.Control.FeedToken != "" {
+			fmt.Printf("  Activity Feeds: %s/feed.atom (token: %s)\n", config.Control.Addr, maskToken(config.Control.FeedToken))
+		}
+	}
+	if config.ChatOps.Addr != "" {
+		fmt.Printf("  ChatOps: %s slash commands on %s/chatops\n", config.ChatOps.Platform, config.ChatOps.Addr)
+	}
+	if config.ChatOps.WebhookURL != "" {
+		fmt.Printf("  ChatOps Summaries: %s\n", maskURL(config.ChatOps.WebhookURL))
+	}
+	if config.Queue.URL != "" {
+		fmt.Printf("  Queue: %s as %s %s (lease %d minutes)\n", maskURL(config.Queue.URL), config.Queue.Role, config.Queue.WorkerID, config.Queue.Lease)
+		for key, value := range config.Queue.Capabilities {
+			fmt.Printf("    Capability %s: %s\n", key, value)
+		}
+		for label, requires := range config.Queue.Routes {
+			fmt.Printf("    Route %s: %s\n", label, requires)
+		}
+		if config.Queue.RouteLanguage {
+			fmt.Printf("    Route by project language: enabled\n")
+		}
+	}
+	fmt.Printf("  Data Directory: %s\n", config.Data.Dir)
+	fmt.Printf("  Database Backups: %d daily\n", config.Database.BackupRetain)
+	fmt.Printf("  Session Retention: %s succeeded, %s failed (issues in review always kept)\n",
+		retentionDays(config.Database.RetainSucceededDays), retentionDays(config.Database.RetainFailedDays))
+	fmt.Printf("  Session Encryption: %s\n", encryptionStatus(config))
+	fmt.Printf("  Log: %s as %s", config.Logging.Level, config.Logging.Format)
+	if config.Logging.File != "" {
+		fmt.Printf(" to %s", config.Logging.File)
+	}
+	fmt.Println()
+	if config.Heartbeat.File != "" {
+		fmt.Printf("  Heartbeat File: %s\n", config.Heartbeat.File)
+	}
+	if config.Metrics.Addr != "" {
+		fmt.Printf("  Metrics: http://%s/metrics\n", config.Metrics.Addr)
+	}
+	if config.Metrics.PushURL != "" {
+		fmt.Printf("  Metrics Push: %s to %s every %d seconds as %s (token: %s)\n", config.Metrics.PushFormat,
+			maskURL(config.Metrics.PushURL), config.Metrics.PushInterval, config.Metrics.Instance, maskToken(config.Metrics.PushToken))
+	}
+	if config.ErrorReport.SentryDSN != "" {
+		fmt.Printf("  Sentry: %s (environment: %s)\n", maskURL(config.ErrorReport.SentryDSN), config.ErrorReport.Environment)
+	}
+	if config.ErrorReport.WebhookURL != "" {
+		fmt.Printf("  Error Webhook: %s\n", maskURL(config.ErrorReport.WebhookURL))
+	}
+	if config.Trackers.JiraURL != "" && config.Trackers.JiraToken != "" {
+		fmt.Printf("  Jira Sync: %s\n", config.Trackers.JiraURL)
+	}
+	if config.Trackers.LinearAPIKey != "" {
+		fmt.Printf("  Linear Sync: enabled\n")
+	}
+	if config.Trackers.NotionToken != "" && config.Trackers.NotionDatabase != "" {
+		fmt.Printf("  Notion Sync: database %s, %s → %s\n", config.Trackers.NotionDatabase,
+			config.Trackers.NotionURLProperty, config.Trackers.NotionStatusProperty)
+	}
+	if config.ErrorReport.SentryDSN != "" || config.ErrorReport.WebhookURL != "" {
+		fmt.Printf("  Error Reports: panics, session crashes, GitLab operations failing %d times in a row\n", config.ErrorReport.APIFailures)
+	}
+	fmt.Printf("  Telemetry: %s\n", telemetryStatus(config))
+}
+
+// telemetryStatus describes whether usage reporting is on and what it sends
+func telemetryStatus(config *Config) string {
+	switch {
+	case !config.Telemetry.Enabled:
+		return "disabled (opt in with TELEMETRY=true)"
+	case config.Telemetry.Endpoint == "":
+		return "enabled but inactive, TELEMETRY_ENDPOINT is not set"
+	}
+	return fmt.Sprintf("enabled, daily to %s (workflow counts, error categories, versions; "+
+		"no project names, issue content or credentials)", config.Telemetry.Endpoint)
+}
+
+func retentionDays(days int) string {
+	if days <= 0 {
+		return "forever"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// encryptionStatus describes whether the session store is encrypted and where
+// its key comes from, never the key itself
+func encryptionStatus(config *Config) string {
+	switch {
+	case !config.Database.Encrypt:
+		return "disabled"
+	case config.Database.EncryptionKey != "":
+		return "enabled (key from SESSION_ENCRYPTION_KEY)"
+	}
+	return "enabled (key in OS keyring)"
 }
 
 func maskToken(token string) string {
@@ -215,3 +1605,17 @@ func maskToken(token string) string {
 	}
 	return token[:4] + "***" + token[len(token)-4:]
 }
+
+// maskURL hides the password of a URL with credentials
+func maskURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "***")
+	} else {
+		u.User = url.User("***")
+	}
+	return u.String()
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultConfigFile is read from the working directory unless AUTOMAGIC_CONFIG
+// names another file
+const DefaultConfigFile = "automagic.yaml"
+
+// configFile is a parsed automagic.yaml. Its sections flatten to the names of
+// the environment variables they set: claude: {flags: x} sets CLAUDE_FLAGS.
+type configFile struct {
+	path         string
+	base         map[string]string            // Settings outside projects and environments
+	environments map[string]map[string]string // Overrides by AUTOMAGIC_ENV
+	projects     map[string]map[string]string // Overrides by project path
+	aliases      map[string]string            // alias: entries of projects
+}
+
+// fromFile lists the variables the configuration file set, so loading again
+// for another project can take them back
+var fromFile []string
+
+// configFilePath returns the configuration file to read, "" when there is none
+func configFilePath() string {
+	if path := os.Getenv("AUTOMAGIC_CONFIG"); path != "" {
+		return expandHome(path)
+	}
+	if _, err := os.Stat(DefaultConfigFile); err == nil {
+		return DefaultConfigFile
+	}
+	return ""
+}
+
+// readConfigFile parses the configuration file at path
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	document, err := parseYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	file := &configFile{
+		path:         path,
+		base:         make(map[string]string),
+		environments: make(map[string]map[string]string),
+		projects:     make(map[string]map[string]string),
+		aliases:      make(map[string]string),
+	}
+	for key, value := range document {
+		switch key {
+		case "environments", "projects":
+			sections, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must map names to settings", path, key)
+			}
+			for name, section := range sections {
+				settings, ok := section.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("%s: %s.%s must be a mapping", path, key, name)
+				}
+				if key == "environments" {
+					file.environments[name] = flattenSettings(settings)
+					continue
+				}
+				if alias, ok := settings["alias"].(string); ok && alias != "" {
+					file.aliases[alias] = name
+					delete(settings, "alias")
+				}
+				file.projects[name] = flattenSettings(settings)
+			}
+		default:
+			flattenInto(file.base, key, value)
+		}
+	}
+	return file, nil
+}
+
+// flattenSettings turns nested settings into environment variable values
+func flattenSettings(settings map[string]interface{}) map[string]string {
+	values := make(map[string]string)
+	for key, value := range settings {
+		flattenInto(values, key, value)
+	}
+	return values
+}
+
+// flattenInto adds a setting under the variable its path names; lists
+// become the comma-separated values the variables take
+func flattenInto(values map[string]string, name string, value interface{}) {
+	name = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			flattenInto(values, name+"_"+key, child)
+		}
+	case []string:
+		values[name] = strings.Join(value, ",")
+	case string:
+		values[name] = value
+	}
+}
+
+// apply sets the variables of the file that the environment and .env leave
+// unset: the base settings, overridden by those of the environment selected
+// with AUTOMAGIC_ENV and then by those of the project. projectPath may be an
+// alias; when it is "", DEFAULT_PROJECT_PATH picks the project. It returns
+// the project path and whether the file has overrides for it.
+func (file *configFile) apply(environment, projectPath string) (string, bool) {
+	settings := make(map[string]string, len(file.base))
+	for key, value := range file.base {
+		settings[key] = value
+	}
+	if environment != "" {
+		overrides, ok := file.environments[environment]
+		if !ok {
+			fmt.Printf("Warning: AUTOMAGIC_ENV %q is not among the environments of %s\n", environment, file.path)
+		}
+		for key, value := range overrides {
+			settings[key] = value
+		}
+	}
+
+	if projectPath == "" {
+		projectPath = os.Getenv("DEFAULT_PROJECT_PATH")
+	}
+	if projectPath == "" {
+		projectPath = settings["DEFAULT_PROJECT_PATH"]
+	}
+	aliases := os.Getenv("PROJECT_ALIASES")
+	if aliases == "" {
+		aliases = settings["PROJECT_ALIASES"]
+	}
+	for _, pair := range strings.Split(aliases, ",") {
+		if alias, path, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(alias) == projectPath {
+			projectPath = strings.TrimSpace(path)
+		}
+	}
+	if path, ok := file.aliases[projectPath]; ok {
+		projectPath = path
+	}
+	overrides, hasProfile := file.projects[projectPath]
+
+	keys := make([]string, 0, len(settings)+len(overrides))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	for key := range overrides {
+		if _, ok := settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, isOverride := overrides[key]
+		if !isOverride {
+			value = settings[key]
+		}
+		if current, set := os.LookupEnv(key); set {
+			if isOverride && current != value {
+				fmt.Printf("Warning: %s from the environment or .env replaces the setting of %s in %s\n", key, projectPath, file.path)
+			}
+			continue
+		}
+		os.Setenv(key, value)
+		fromFile = append(fromFile, key)
+	}
+
+	return projectPath, hasProfile
+}
+
+// unsetFromFile removes the variables set by the last configuration file load
+func unsetFromFile() {
+	for _, key := range fromFile {
+		os.Unsetenv(key)
+	}
+	fromFile = nil
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-blank line of a YAML document without its comment
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML reads the subset of YAML that configuration files need: nested
+// mappings, scalars and lists of scalars, either as "- item" blocks or inline
+// [a, b]. Mappings come back as map[string]interface{}, lists as []string and
+// every scalar as a string.
+func parseYAML(data string) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(raw) == "---" {
+			continue
+		}
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: strings.TrimRight(trimmed, " \t")})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	mapping, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the document must be a mapping")
+	}
+	return mapping, nil
+}
+
+// parseYAMLBlock parses the mapping or list starting at lines[start], whose
+// entries are indented by indent, and returns the index of the line after it
+func parseYAMLBlock(lines []yamlLine, start, indent int) (interface{}, int, error) {
+	if isYAMLListItem(lines[start].text) {
+		var items []string
+		i := start
+		for ; i < len(lines) && lines[i].indent == indent && isYAMLListItem(lines[i].text); i++ {
+			line := lines[i]
+			item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+			if _, _, isMapping := splitYAMLKey(item); isMapping && !isQuoted(item) {
+				return nil, i, fmt.Errorf("line %d: lists may only hold plain values", line.number)
+			}
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %v", line.number, err)
+			}
+			items = append(items, value)
+		}
+		return items, i, nil
+	}
+
+	mapping := make(map[string]interface{})
+	i := start
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, i, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, i, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		i++
+
+		switch {
+		case rest != "":
+			if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") {
+				return nil, i, fmt.Errorf("line %d: block scalars are not supported, quote the value instead", line.number)
+			}
+			if strings.HasPrefix(rest, "[") {
+				items, err := parseYAMLFlowList(rest)
+				if err != nil {
+					return nil, i, fmt.Errorf("line %d: %v", line.number, err)
+				}
+				mapping[key] = items
+				continue
+			}
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %v", line.number, err)
+			}
+			mapping[key] = value
+		case i < len(lines) && lines[i].indent > indent:
+			child, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			mapping[key] = child
+			i = next
+		case i < len(lines) && lines[i].indent == indent && isYAMLListItem(lines[i].text):
+			// A list may sit at the same indentation as its key
+			child, next, err := parseYAMLBlock(lines, i, indent)
+			if err != nil {
+				return nil, next, err
+			}
+			mapping[key] = child
+			i = next
+		default:
+			mapping[key] = ""
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("line %d: unexpected indentation", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+// splitYAMLKey splits "key: value" into its key and value
+func splitYAMLKey(text string) (string, string, bool) {
+	var key, rest string
+	if isQuoted(text) {
+		quote := text[0]
+		end := strings.IndexByte(text[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest = text[1:end+1], text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+
+	if strings.HasSuffix(text, ":") {
+		key, rest = text[:len(text)-1], ""
+	} else if index := strings.Index(text, ": "); index >= 0 {
+		key, rest = text[:index], text[index+2:]
+	} else {
+		return "", "", false
+	}
+	key = strings.TrimSpace(key)
+	return key, strings.TrimSpace(rest), key != ""
+}
+
+// parseYAMLScalar unquotes a scalar; null and ~ read as empty
+func parseYAMLScalar(text string) (string, error) {
+	switch {
+	case text == "~" || text == "null":
+		return "", nil
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", fmt.Errorf("invalid quoted value %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	return text, nil
+}
+
+// parseYAMLFlowList reads an inline list such as [a, "b c"]
+func parseYAMLFlowList(text string) ([]string, error) {
+	if !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("unterminated list %s", text)
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return []string{}, nil
+	}
+
+	var items []string
+	var current strings.Builder
+	var quote byte
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			value, err := parseYAMLScalar(strings.TrimSpace(current.String()))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	value, err := parseYAMLScalar(strings.TrimSpace(current.String()))
+	if err != nil {
+		return nil, err
+	}
+	return append(items, value), nil
+}
+
+// stripYAMLComment drops a # comment that is outside quotes and starts the
+// line or follows a space
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only open a string at the start of a value
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isQuoted(text string) bool {
+	return strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'")
+}
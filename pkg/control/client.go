@@ -0,0 +1,149 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	controlv1 "github.com/bilbo290/automagic/api/control/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// callTimeout bounds each call to the control API
+const callTimeout = 10 * time.Second
+
+// Client calls the control operations of a daemon over gRPC
+type Client struct {
+	conn   *grpc.ClientConn
+	client controlv1.ControlClient
+}
+
+// NewClient returns a client for the control API at addr, e.g.
+// automagic.internal:9092. It connects on the first call.
+func NewClient(addr, token string) (*Client, error) {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(bearerToken(token)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control API client: %v", err)
+	}
+	return &Client{conn: conn, client: controlv1.NewControlClient(conn)}, nil
+}
+
+// Close closes the connection to the daemon
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Status describes what the daemon is doing
+func (c *Client) Status() (*Status, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	response, err := c.client.Status(ctx, &controlv1.StatusRequest{})
+	if err != nil {
+		return nil, callError(err)
+	}
+
+	current := &Status{
+		Project:     response.GetProject(),
+		Role:        response.GetRole(),
+		DryRun:      response.GetDryRun(),
+		Running:     []RunningSession{},
+		TierUsage:   make(map[string]int, len(response.GetTierUsage())),
+		GeneratedAt: response.GetGeneratedAt().AsTime(),
+	}
+	for _, running := range response.GetRunning() {
+		session := RunningSession{
+			IssueIID:  int(running.GetIssueIid()),
+			Kind:      running.GetKind(),
+			SessionID: running.GetSessionId(),
+			PID:       int(running.GetPid()),
+		}
+		if running.GetStartedAt() != nil {
+			session.StartedAt = running.GetStartedAt().AsTime()
+		}
+		current.Running = append(current.Running, session)
+	}
+	for _, issueIID := range response.GetEnqueued() {
+		current.Enqueued = append(current.Enqueued, int(issueIID))
+	}
+	for tier, count := range response.GetTierUsage() {
+		current.TierUsage[tier] = int(count)
+	}
+	return current, nil
+}
+
+// Sessions lists the session runs started at or after since, oldest first
+func (c *Client) Sessions(since time.Time) ([]Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	response, err := c.client.Sessions(ctx, &controlv1.SessionsRequest{Since: timestamppb.New(since)})
+	if err != nil {
+		return nil, callError(err)
+	}
+
+	sessions := make([]Session, 0, len(response.GetSessions()))
+	for _, run := range response.GetSessions() {
+		sessions = append(sessions, Session{
+			ProjectPath:  run.GetProject(),
+			IssueIID:     int(run.GetIssueIid()),
+			SessionID:    run.GetSessionId(),
+			Kind:         run.GetKind(),
+			Outcome:      run.GetOutcome(),
+			Failure:      run.GetFailure(),
+			Model:        run.GetModel(),
+			StartedAt:    run.GetStartedAt().AsTime(),
+			Duration:     run.GetDurationSeconds(),
+			CostUSD:      run.GetCostUsd(),
+			Turns:        int(run.GetTurns()),
+			Retries:      int(run.GetRetries()),
+			InputTokens:  int(run.GetInputTokens()),
+			OutputTokens: int(run.GetOutputTokens()),
+			CacheTokens:  int(run.GetCacheTokens()),
+		})
+	}
+	return sessions, nil
+}
+
+// Enqueue asks the daemon to work on an issue; projectPath may be "" for the
+// project the daemon monitors
+func (c *Client) Enqueue(projectPath string, issueIID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, err := c.client.Enqueue(ctx, &controlv1.EnqueueRequest{Project: projectPath, IssueIid: int64(issueIID)})
+	return callError(err)
+}
+
+// Cancel stops the session running on an issue
+func (c *Client) Cancel(issueIID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, err := c.client.Cancel(ctx, &controlv1.CancelRequest{IssueIid: int64(issueIID)})
+	return callError(err)
+}
+
+// callError turns a gRPC status into an error naming the control API
+func callError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if failure, ok := status.FromError(err); ok {
+		return fmt.Errorf("control API: %s", failure.Message())
+	}
+	return fmt.Errorf("failed to reach the control API: %v", err)
+}
+
+// bearerToken sends the control token with every call. The control API
+// serves plaintext HTTP/2, so it does not require transport security.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,70 @@
+// Package control exposes the daemon's control operations (enqueue an issue,
+// report status, cancel a session and list session runs) to other programs.
+// The operations are defined in api/control/v1/control.proto and served over
+// gRPC by NewServer; Client calls them from Go.
+package control
+
+import (
+	"errors"
+	"time"
+)
+
+// Controller is implemented by the daemon
+type Controller interface {
+	// Enqueue asks the daemon to work on an issue of the project it monitors
+	Enqueue(projectPath string, issueIID int) error
+	// Status describes what the daemon is doing
+	Status() Status
+	// Cancel stops the session running on an issue
+	Cancel(issueIID int) error
+	// Sessions lists the session runs started at or after since, oldest first
+	Sessions(since time.Time) ([]Session, error)
+}
+
+// Errors a Controller returns for requests it cannot serve; the server maps
+// them to gRPC status codes
+var (
+	ErrOtherProject = errors.New("issue belongs to another project than the daemon monitors")
+	ErrNotRunning   = errors.New("no session is running on the issue")
+	ErrUnsupported  = errors.New("not supported by this daemon")
+)
+
+// Status describes what the daemon is doing
+type Status struct {
+	Project     string           `json:"project"`
+	Role        string           `json:"role,omitempty"` // Distributed queue role, "" when standalone
+	DryRun      bool             `json:"dry_run,omitempty"`
+	Running     []RunningSession `json:"running"`
+	Enqueued    []int            `json:"enqueued,omitempty"` // Issues passed to Enqueue that have not started yet
+	TierUsage   map[string]int   `json:"tier_usage,omitempty"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// RunningSession is a Claude session running now
+type RunningSession struct {
+	IssueIID  int       `json:"issue_iid"`
+	Kind      string    `json:"kind"` // issue or resume
+	SessionID string    `json:"session_id,omitempty"`
+	PID       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// Session is a finished session run, as in "automagic report export"
+type Session struct {
+	ProjectPath string    `json:"project"`
+	IssueIID    int       `json:"issue_iid"`
+	SessionID   string    `json:"session_id"`
+	Kind        string    `json:"kind"`
+	Outcome     string    `json:"outcome"`
+	Failure     string    `json:"failure,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	Duration    float64   `json:"duration_seconds"` // Seconds
+	CostUSD     float64   `json:"cost_usd"`
+	Turns       int       `json:"turns"`
+	Retries     int       `json:"retries"`
+
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	CacheTokens  int `json:"cache_tokens"` // Read from and written to the prompt cache
+}
@@ -0,0 +1,160 @@
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	controlv1 "github.com/bilbo290/automagic/api/control/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewServer returns a gRPC server with the Control service of controller
+// registered, accepting calls that carry token as "authorization: Bearer
+// <token>" metadata. An empty token rejects every call. Server reflection is
+// registered too, so tools such as grpcurl can list the service.
+func NewServer(controller Controller, token string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(authorize(token)))
+	controlv1.RegisterControlServer(server, &service{controller: controller})
+	reflection.Register(server)
+	return server
+}
+
+// IsGRPC reports whether an HTTP request is a gRPC call, for serving the
+// control API and plain HTTP handlers on one listener
+func IsGRPC(protoMajor int, contentType string) bool {
+	return protoMajor == 2 && strings.HasPrefix(contentType, "application/grpc")
+}
+
+// authorize rejects calls that do not carry the token
+func authorize(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// Reflection only describes the service, so it needs no token
+		if strings.HasPrefix(info.FullMethod, "/grpc.reflection.") {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		given := ""
+		if values := md.Get("authorization"); len(values) > 0 {
+			given = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// service implements the Control service on a Controller
+type service struct {
+	controlv1.UnimplementedControlServer
+	controller Controller
+}
+
+func (s *service) Status(ctx context.Context, req *controlv1.StatusRequest) (*controlv1.StatusResponse, error) {
+	current := s.controller.Status()
+	response := &controlv1.StatusResponse{
+		Project:     current.Project,
+		Role:        current.Role,
+		DryRun:      current.DryRun,
+		TierUsage:   make(map[string]int32, len(current.TierUsage)),
+		GeneratedAt: timestamppb.New(current.GeneratedAt),
+	}
+	for _, running := range current.Running {
+		response.Running = append(response.Running, &controlv1.RunningSession{
+			IssueIid:  int64(running.IssueIID),
+			Kind:      running.Kind,
+			SessionId: running.SessionID,
+			Pid:       int32(running.PID),
+			StartedAt: optionalTimestamp(running.StartedAt),
+		})
+	}
+	for _, issueIID := range current.Enqueued {
+		response.Enqueued = append(response.Enqueued, int64(issueIID))
+	}
+	for tier, count := range current.TierUsage {
+		response.TierUsage[tier] = int32(count)
+	}
+	return response, nil
+}
+
+func (s *service) Sessions(ctx context.Context, req *controlv1.SessionsRequest) (*controlv1.SessionsResponse, error) {
+	since := time.Now().Add(-24 * time.Hour)
+	if req.GetSince() != nil {
+		since = req.GetSince().AsTime()
+	}
+
+	sessions, err := s.controller.Sessions(since)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	response := &controlv1.SessionsResponse{}
+	for _, run := range sessions {
+		response.Sessions = append(response.Sessions, &controlv1.Session{
+			Project:         run.ProjectPath,
+			IssueIid:        int64(run.IssueIID),
+			SessionId:       run.SessionID,
+			Kind:            run.Kind,
+			Outcome:         run.Outcome,
+			Failure:         run.Failure,
+			Model:           run.Model,
+			StartedAt:       timestamppb.New(run.StartedAt),
+			DurationSeconds: run.Duration,
+			CostUsd:         run.CostUSD,
+			Turns:           int32(run.Turns),
+			Retries:         int32(run.Retries),
+			InputTokens:     int64(run.InputTokens),
+			OutputTokens:    int64(run.OutputTokens),
+			CacheTokens:     int64(run.CacheTokens),
+		})
+	}
+	return response, nil
+}
+
+func (s *service) Enqueue(ctx context.Context, req *controlv1.EnqueueRequest) (*controlv1.EnqueueResponse, error) {
+	if req.GetIssueIid() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "issue_iid is required")
+	}
+	if err := s.controller.Enqueue(req.GetProject(), int(req.GetIssueIid())); err != nil {
+		return nil, statusFor(err)
+	}
+	return &controlv1.EnqueueResponse{}, nil
+}
+
+func (s *service) Cancel(ctx context.Context, req *controlv1.CancelRequest) (*controlv1.CancelResponse, error) {
+	if req.GetIssueIid() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "issue_iid is required")
+	}
+	if err := s.controller.Cancel(int(req.GetIssueIid())); err != nil {
+		return nil, statusFor(err)
+	}
+	return &controlv1.CancelResponse{}, nil
+}
+
+// statusFor maps a Controller error to a gRPC status
+func statusFor(err error) error {
+	switch {
+	case errors.Is(err, ErrOtherProject):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotRunning):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrUnsupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// optionalTimestamp leaves unknown times unset
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,96 @@
+// Package coverage measures the test coverage of a checkout and caches the
+// coverage of default branch commits
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoCommand measures the statement coverage of a Go module
+const GoCommand = "go test -coverprofile=.automagic-coverage.out ./... >/dev/null && go tool cover -func=.automagic-coverage.out"
+
+// percentPattern matches a percentage such as 73.4%
+var percentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+
+// Runner runs a coverage command in a checkout
+type Runner struct {
+	Command string        // Shell command printing the total coverage as its last percentage
+	Timeout time.Duration // Limit for one run, 0 for none
+}
+
+// Measure runs the coverage command in dir and returns the total coverage
+// in percent
+func (r *Runner) Measure(dir string) (float64, error) {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.Command)
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, fmt.Errorf("coverage run timed out after %s", r.Timeout)
+		}
+		return 0, fmt.Errorf("coverage run failed: %v: %s", err, lastLine(output.String()))
+	}
+	return Parse(output.String())
+}
+
+// Parse returns the last percentage in the output of a coverage command,
+// the total in the formats of go tool cover, Jest, coverage.py and most others
+func Parse(output string) (float64, error) {
+	matches := percentPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("no coverage percentage in the output: %s", lastLine(output))
+	}
+	return strconv.ParseFloat(matches[len(matches)-1][1], 64)
+}
+
+// Cache keeps the coverage of commits, one file per project and commit
+type Cache struct {
+	Dir string
+}
+
+// Get returns the cached coverage of a commit and whether there was one
+func (c *Cache) Get(project, sha string) (float64, bool) {
+	data, err := os.ReadFile(c.path(project, sha))
+	if err != nil {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return percent, err == nil
+}
+
+// Put caches the coverage of a commit
+func (c *Cache) Put(project, sha string, percent float64) error {
+	path := c.path(project, sha)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create coverage cache: %v", err)
+	}
+	return os.WriteFile(path, []byte(strconv.FormatFloat(percent, 'f', -1, 64)+"\n"), 0644)
+}
+
+func (c *Cache) path(project, sha string) string {
+	return filepath.Join(c.Dir, strings.ReplaceAll(project, "/", "_"), sha)
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// activityGate tracks the project activity feed so polling ticks can be
+// skipped while nothing has happened. It is only used by the wake-up loop.
+type activityGate struct {
+	lastEventID  int       // Newest project event seen, 0 before the first check
+	lastFullPoll time.Time // When a tick last ran the full poll
+}
+
+// projectActive reports whether a polling tick should run the full poll: when
+// the activity check is off, when the project has events newer than the last
+// check, or when FULL_POLL_INTERVAL has passed. Adding a label does not create
+// a project event, and issues deferred by the tier scheduler or held by the
+// backfill queue wait for the next poll, so a full poll still runs now and then.
+func (d *Daemon) projectActive() bool {
+	if !d.config.Daemon.ActivityCheck {
+		return true
+	}
+
+	gate := &d.activity
+	now := time.Now()
+	timestamp := now.Format("2006-01-02 15:04:05")
+
+	fullPoll := time.Duration(d.config.Daemon.FullPollInterval) * time.Minute
+	if gate.lastFullPoll.IsZero() || now.Sub(gate.lastFullPoll) >= fullPoll {
+		d.recordActivity(timestamp)
+		gate.lastFullPoll = now
+		return true
+	}
+
+	if !d.recordActivity(timestamp) {
+		logging.Debugf("No project activity since the last poll, skipping")
+		return false
+	}
+	gate.lastFullPoll = now
+	return true
+}
+
+// recordActivity fetches the project events newer than the last one seen and
+// reports whether there were any. Errors count as activity so a failing
+// events API never stops polling.
+func (d *Daemon) recordActivity(timestamp string) bool {
+	gate := &d.activity
+
+	events, err := d.gitlabClient.GetProjectEvents(d.selectedProject, gate.lastFullPoll)
+	if err != nil {
+		logging.Warnf("Failed to check project activity: %v", err)
+		return true
+	}
+
+	newest := gate.lastEventID
+	for _, event := range events {
+		if event.ID > newest {
+			newest = event.ID
+		}
+	}
+	if newest == gate.lastEventID {
+		return false
+	}
+
+	if gate.lastEventID != 0 {
+		logging.Debugf("Project has new activity (event %d → %d)", gate.lastEventID, newest)
+	}
+	gate.lastEventID = newest
+	return true
+}
@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// approveCommand is the comment that approves the action a session is held at
+const approveCommand = "/automagic approve"
+
+// approvalPolicy builds the approval policy of an issue's sessions, letting
+// through the actions already approved on it
+func (d *Daemon) approvalPolicy(issueIID int) *claude.ApprovalPolicy {
+	policy := &claude.ApprovalPolicy{
+		MigrationPaths: d.config.Approval.MigrationPaths,
+		CIPaths:        d.config.Approval.CIPaths,
+		DeleteLines:    d.config.Approval.DeleteLines,
+	}
+	if eventLog, ok := d.sessionStore.(session.EventLog); ok {
+		if events, err := eventLog.GetEvents(issueIID); err == nil {
+			for _, event := range events {
+				if event.Kind == session.EventApproved {
+					policy.Approved = append(policy.Approved, event.Detail)
+				}
+			}
+		}
+	}
+	return policy
+}
+
+// approvalCommand returns the arguments and environment that run a session
+// of an issue in workingDir with the approval hook
+func (d *Daemon) approvalCommand(issueIID int, workingDir string) ([]string, []string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to locate the automagic binary for the approval hook: %v", err)
+	}
+	return claude.ApprovalCommand(d.approvalPolicy(issueIID), executable, workingDir)
+}
+
+// requireApproval runs an issue session with the approval hook. The hook runs
+// the automagic binary, which a dev container does not have, so those
+// sessions run without it.
+func (d *Daemon) requireApproval(process *claude.Process) error {
+	if process.Environment != nil && process.Environment.Kind == claude.EnvironmentDevcontainer {
+		logging.Issue(process.IssueNum).Warnf("Approval checkpoints are not supported in dev containers, issue #%d runs without them", process.IssueNum)
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the automagic binary for the approval hook: %v", err)
+	}
+	return process.RequireApproval(d.approvalPolicy(process.IssueNum), executable)
+}
+
+// requestApproval asks on the issue for approval of the action a session
+// stopped at and labels the issue so it waits for it
+func (d *Daemon) requestApproval(issueIID int, sessionID, action string) {
+	logging.Issue(issueIID).Infof("Session for issue #%d is waiting for approval to %s", issueIID, action)
+	d.recordEvent(issueIID, session.EventAwaitingApproval, sessionID, action)
+
+	comment := d.message(locale.MsgApprovalRequired, map[string]interface{}{
+		"Action":  action,
+		"Command": approveCommand,
+		"Label":   d.config.Approval.Label,
+	})
+	if mentions := d.mentionLine(issueIID); mentions != "" {
+		comment += "\n\n" + mentions
+	}
+	note, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issueIID, comment)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to request approval on issue #%d: %v", issueIID, err)
+	} else if err := d.sessionStore.UpdateLastNote(issueIID, note.ID, time.Time{}); err != nil {
+		// Only the comments after the request can approve it
+		logging.Issue(issueIID).Warnf("Failed to record approval request for issue #%d: %v", issueIID, err)
+	}
+
+	// The review label would let any comment resume the session
+	d.swapLabels(issueIID, []string{d.config.Daemon.ProcessLabel, d.config.Daemon.ClaudeLabel, d.config.Daemon.ReviewLabel}, d.config.Approval.Label)
+}
+
+// pendingApproval returns the action an issue's session is held at, "" if none
+func (d *Daemon) pendingApproval(issueIID int) string {
+	eventLog, ok := d.sessionStore.(session.EventLog)
+	if !ok {
+		return ""
+	}
+	events, err := eventLog.GetEvents(issueIID)
+	if err != nil {
+		return ""
+	}
+	action := ""
+	for _, event := range events {
+		switch event.Kind {
+		case session.EventAwaitingApproval:
+			action = event.Detail
+		case session.EventApproved:
+			action = ""
+		}
+	}
+	return action
+}
+
+// checkApprovals resumes the sessions whose held action has been approved
+// with an approveCommand comment since the request. It returns how many
+// sessions it resumed.
+func (d *Daemon) checkApprovals(ctx context.Context, timestamp string) int {
+	if !d.config.Approval.Enabled {
+		return 0
+	}
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{
+		Labels: []string{d.config.Approval.Label},
+		State:  "opened",
+	})
+	if err != nil {
+		logging.Errorf("Error checking issues awaiting approval: %v", err)
+		return 0
+	}
+
+	resumed := 0
+	for _, issue := range issues {
+		if ctx.Err() != nil {
+			return resumed
+		}
+		stored, exists := d.sessionStore.GetCompletedSession(issue.IID)
+		if !exists {
+			continue
+		}
+		action := d.pendingApproval(issue.IID)
+		if action == "" {
+			continue
+		}
+
+		cutoff := sessionCutoff(stored)
+		comments, err := d.gitlabClient.GetIssueCommentsAfterWithContext(ctx, stored.ProjectPath, stored.IssueIID, cutoff.since())
+		if err != nil {
+			logging.Issue(issue.IID).Errorf("Error checking comments for issue #%d: %v", issue.IID, err)
+			continue
+		}
+		comments = cutoff.filter(comments)
+		if !approved(comments, d.config.GitLab.Username) {
+			continue
+		}
+
+		logging.Issue(issue.IID).Infof("Approved on issue #%d: %s", issue.IID, action)
+		d.recordEvent(issue.IID, session.EventApproved, stored.SessionID, action)
+		d.swapLabels(issue.IID, []string{d.config.Approval.Label}, d.config.Daemon.ReviewLabel)
+
+		if err := d.resumeSession(ctx, stored, comments, nil, false); err != nil {
+			logging.Issue(issue.IID).Errorf("Error resuming approved session for issue #%d: %v", issue.IID, err)
+			continue
+		}
+		resumed++
+		if noteID, createdAt, ok := latestFeedback(comments, nil); ok {
+			d.sessionStore.UpdateLastNote(issue.IID, noteID, createdAt)
+		}
+	}
+	return resumed
+}
+
+// approved reports whether someone other than the bot commented approveCommand
+func approved(comments []gitlab.Note, botUsername string) bool {
+	for _, note := range comments {
+		if note.System || note.Author.Username == botUsername {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(note.Body), approveCommand) {
+			return true
+		}
+	}
+	return false
+}
+
+// swapLabels removes labels from an issue and adds label
+func (d *Daemon) swapLabels(issueIID int, remove []string, label string) {
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to get issue #%d for label update: %v", issueIID, err)
+		return
+	}
+	removed := map[string]bool{label: true}
+	for _, r := range remove {
+		removed[r] = true
+	}
+	labels := []string{label}
+	for _, existing := range issue.Labels {
+		if !removed[existing] {
+			labels = append(labels, existing)
+		}
+	}
+	if err := d.setIssueLabels(issueIID, labels); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to update %s labels for issue #%d: %v", label, issueIID, err)
+	}
+}
@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// releaseBackfill hands the next due backfilled issue to the daemon, labelling
+// it for pickup, and returns the issues still held back. At most one issue is
+// released per BACKFILL_RATE slot, so a backlog that came due while the daemon
+// was down still trickles in.
+func (d *Daemon) releaseBackfill(timestamp string) map[int]bool {
+	queue, ok := d.sessionStore.(session.BackfillQueue)
+	if !ok {
+		return nil
+	}
+
+	entries, err := queue.GetBackfill(d.selectedProject)
+	if err != nil {
+		logging.Warnf("Failed to load backfill queue: %v", err)
+		return nil
+	}
+
+	held := make(map[int]bool)
+	for _, entry := range entries {
+		held[entry.IssueIID] = true
+	}
+	if len(entries) == 0 {
+		return held
+	}
+
+	now := time.Now()
+	next := entries[0]
+	if next.ReleaseAt.After(now) || now.Sub(d.lastBackfillRelease) < backfillSlot(d.config.Daemon.BackfillRate) {
+		return held
+	}
+
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(next.IssueIID).Infof("[DRY RUN] Would release backfilled issue #%d (%d still queued)", next.IssueIID, len(entries)-1)
+		return held
+	}
+
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, next.IssueIID)
+	if err != nil {
+		logging.Issue(next.IssueIID).Warnf("Failed to fetch backfilled issue #%d: %v", next.IssueIID, err)
+		return held
+	}
+
+	if issue.State == "opened" && !issue.HasAnyLabel([]string{d.config.Daemon.ClaudeLabel}) {
+		labels := append(issue.Labels, d.config.Daemon.ClaudeLabel)
+		if err := d.setIssueLabels(issue.IID, labels); err != nil {
+			logging.Issue(issue.IID).Warnf("Failed to label backfilled issue #%d: %v", issue.IID, err)
+			return held
+		}
+	}
+
+	if err := queue.RemoveBackfill(d.selectedProject, next.IssueIID); err != nil {
+		logging.Issue(next.IssueIID).Warnf("Failed to dequeue backfilled issue #%d: %v", next.IssueIID, err)
+		return held
+	}
+
+	delete(held, next.IssueIID)
+	d.lastBackfillRelease = now
+	if issue.State == "opened" {
+		logging.Issue(issue.IID).Infof("Backfill: released issue #%d (%d still queued)", issue.IID, len(held))
+	} else {
+		logging.Issue(issue.IID).Infof("Backfill: dropped issue #%d, it is %s (%d still queued)", issue.IID, issue.State, len(held))
+	}
+
+	return held
+}
+
+// withoutBackfillHeld removes issues the backfill queue is still holding back
+func withoutBackfillHeld(issues []gitlab.Issue, held map[int]bool) []gitlab.Issue {
+	if len(held) == 0 {
+		return issues
+	}
+
+	var released []gitlab.Issue
+	for _, issue := range issues {
+		if !held[issue.IID] {
+			released = append(released, issue)
+		}
+	}
+	return released
+}
+
+// backfillSlot is the minimum gap between two released issues
+func backfillSlot(ratePerHour int) time.Duration {
+	if ratePerHour <= 0 {
+		return 0
+	}
+	return time.Hour / time.Duration(ratePerHour)
+}
+
+// ScheduleBackfill queues historical issues for release at ratePerHour,
+// continuing after anything already queued. It returns how many issues were
+// newly queued and when the last one will be released.
+func ScheduleBackfill(store session.BackfillQueue, projectPath string, issues []gitlab.Issue, ratePerHour int) (int, time.Time, error) {
+	slot := backfillSlot(ratePerHour)
+
+	start := time.Now()
+	if last, ok := store.LastBackfillRelease(projectPath); ok && last.After(start) {
+		start = last.Add(slot)
+	}
+
+	entries := make([]session.BackfillEntry, 0, len(issues))
+	releaseAt := start
+	for i, issue := range issues {
+		releaseAt = start.Add(time.Duration(i) * slot)
+		entries = append(entries, session.BackfillEntry{
+			IssueIID:    issue.IID,
+			ProjectPath: projectPath,
+			ReleaseAt:   releaseAt,
+		})
+	}
+
+	added, err := store.EnqueueBackfill(entries)
+	if err != nil {
+		return added, releaseAt, fmt.Errorf("failed to queue backfill: %v", err)
+	}
+	return added, releaseAt, nil
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/benchmark"
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// benchmarkIssue runs the benchmarks of a completed issue session labeled
+// BENCHMARK_LABEL on its branch and on the default branch commit it started
+// from, one after the other on the same host. It returns the comparison for
+// the completion comment, "" when the issue is not labeled or nothing could
+// be compared.
+func (d *Daemon) benchmarkIssue(process *claude.Process, branch string) string {
+	if d.config.Benchmark.Label == "" || d.dryRun || d.semiDryRun {
+		return ""
+	}
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, process.IssueNum)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to fetch issue #%d for benchmarks: %v", process.IssueNum, err)
+		return ""
+	}
+	if !issue.HasAnyLabel([]string{d.config.Benchmark.Label}) {
+		return ""
+	}
+
+	command := d.config.Benchmark.Command
+	if command == "" {
+		if _, err := os.Stat(filepath.Join(process.WorkingDir, "go.mod")); err != nil {
+			logging.Issue(process.IssueNum).Infof("Skipping benchmarks of issue #%d: no BENCHMARK_COMMAND and not a Go module", process.IssueNum)
+			return ""
+		}
+		command = benchmark.GoCommand
+	}
+	runner := &benchmark.Runner{Command: command, Timeout: time.Duration(d.config.Benchmark.Timeout) * time.Minute}
+
+	cmd := exec.Command("git", "merge-base", "origin/HEAD", branch)
+	cmd.Dir = process.WorkingDir
+	output, err := cmd.Output()
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to find where %s left the default branch: %v", branch, err)
+		return ""
+	}
+	base := strings.TrimSpace(string(output))
+
+	logging.Issue(process.IssueNum).Infof("Benchmarking %s against %s for issue #%d", branch, base, process.IssueNum)
+	before, err := d.runBenchmarks(runner, process.WorkingDir, base)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to benchmark %s for issue #%d: %v", base, process.IssueNum, err)
+		return ""
+	}
+	after, err := d.runBenchmarks(runner, process.WorkingDir, branch)
+	if err != nil {
+		// Benchmarks broken by the change are worth telling the reviewer
+		return d.message(locale.MsgBenchmarkFailed, map[string]interface{}{
+			"Command": command,
+			"Error":   err.Error(),
+		})
+	}
+
+	changes := benchmark.Compare(before, after)
+	if len(changes) == 0 {
+		logging.Issue(process.IssueNum).Infof("No benchmarks of issue #%d ran both before and after the change", process.IssueNum)
+		return ""
+	}
+	significant := 0
+	for _, change := range changes {
+		if change.Significant {
+			significant++
+		}
+	}
+	d.recordEvent(process.IssueNum, session.EventBenchmark, process.ClaudeSessionID, fmt.Sprintf("%d measurements compared, %d changed", len(changes), significant))
+
+	return d.message(locale.MsgBenchmark, map[string]interface{}{
+		"Base":    base[:min(len(base), 8)],
+		"Command": command,
+		"Table":   benchmark.Table(changes),
+	})
+}
+
+// runBenchmarks runs the benchmark command in a worktree of ref
+func (d *Daemon) runBenchmarks(runner *benchmark.Runner, repoDir, ref string) (benchmark.Samples, error) {
+	checkout, remove, err := d.branchWorktree(repoDir, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+	return runner.Run(checkout)
+}
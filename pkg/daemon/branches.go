@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// maxBranchAttempts bounds the suffixes tried for an issue branch, issue-N
+// being the first attempt and issue-N-rK the K-th
+const maxBranchAttempts = 20
+
+// attemptBranch returns the branch of the given attempt at an issue
+func attemptBranch(issueIID, attempt int) string {
+	if attempt <= 1 {
+		return issueBranch(issueIID)
+	}
+	return fmt.Sprintf("%s-r%d", issueBranch(issueIID), attempt)
+}
+
+// newIssueBranch picks the branch a new session for the issue pushes to:
+// issue-N, or issue-N-r2, -r3 and so on when earlier attempts left their
+// branches in the project (or fork) the bot pushes to, so they are never
+// force-pushed over. It also returns the newest existing branch, which the
+// new attempt replaces. A failed lookup is returned rather than guessed at,
+// so the issue waits for the next poll instead of risking a force-push over
+// an earlier attempt.
+func (d *Daemon) newIssueBranch(issueIID int, forkPath string) (branch, previousBranch string, err error) {
+	target := d.selectedProject
+	if forkPath != "" {
+		target = forkPath
+	}
+
+	for attempt := 1; attempt <= maxBranchAttempts; attempt++ {
+		candidate := attemptBranch(issueIID, attempt)
+		exists, lookupErr := d.gitlabClient.BranchExists(target, candidate)
+		if lookupErr != nil {
+			return "", "", fmt.Errorf("failed to check branch %s in %s: %v", candidate, target, lookupErr)
+		}
+		if !exists {
+			return candidate, previousBranch, nil
+		}
+		previousBranch = candidate
+	}
+	return "", "", fmt.Errorf("%s already has branches up to %s, delete the old attempts", target, previousBranch)
+}
+
+// sessionBranch returns the branch an issue session pushed to
+func sessionBranch(s *session.CompletedSession) string {
+	if s.Branch != "" {
+		return s.Branch
+	}
+	return issueBranch(s.IssueIID)
+}
+
+// currentIssueBranch returns the branch of the issue's stored session, or
+// issue-N when there is none
+func (d *Daemon) currentIssueBranch(issueIID int) string {
+	if s, exists := d.sessionStore.GetCompletedSession(issueIID); exists {
+		return sessionBranch(s)
+	}
+	return issueBranch(issueIID)
+}
+
+// refreshBranch rebases the branch of a stored session onto the default
+// branch once it has moved AUTO_REBASE_MIN_COMMITS ahead, and returns the
+// prompt section describing the upstream changes, "" when there are none
+func (d *Daemon) refreshBranch(s *session.CompletedSession) string {
+	if s.WorkingDir == "" {
+		return ""
+	}
+	branch := sessionBranch(s)
+	changes, err := claude.RefreshBranch(s.WorkingDir, branch, d.config.AutoRebase.MinCommits)
+	if err != nil {
+		logging.Issue(s.IssueIID).Warnf("Failed to refresh %s for issue #%d: %v", branch, s.IssueIID, err)
+		return ""
+	}
+	if changes == nil {
+		return ""
+	}
+
+	outcome := "left for the session to rebase"
+	switch {
+	case changes.Rebased:
+		outcome = "rebased"
+	case changes.Conflict:
+		outcome = "rebase conflicts"
+	}
+	logging.Issue(s.IssueIID).Infof("%s is %d commits behind %s for issue #%d: %s", branch, changes.Behind, changes.Base, s.IssueIID, outcome)
+	d.recordEvent(s.IssueIID, session.EventRebased, s.SessionID, fmt.Sprintf("%d commits behind %s, %s", changes.Behind, changes.Base, outcome))
+	return changes.Describe(branch)
+}
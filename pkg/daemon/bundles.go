@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// saveFailureBundle keeps the last output and the worktree state of a failed
+// session with its issue, for "automagic -failure". With FAILURE_BUNDLE_SNIPPET
+// the bundle is also uploaded as a private snippet and linked on the issue.
+func (d *Daemon) saveFailureBundle(issueIID int, sessionID, workingDir string, failure claude.FailureKind, errorText string, output []string) {
+	store, ok := d.sessionStore.(session.FailureBundles)
+	if !ok || !d.config.FailureBundle.Enabled || workingDir == "" {
+		return
+	}
+
+	bundle := claude.CaptureFailureBundle(workingDir, failure, errorText, output)
+	record := session.FailureBundle{
+		IssueIID:    issueIID,
+		ProjectPath: d.selectedProject,
+		SessionID:   sessionID,
+		Content:     bundle.String(),
+		CreatedAt:   bundle.CapturedAt,
+	}
+	if d.config.FailureBundle.Snippet {
+		record.SnippetURL = d.uploadFailureBundle(issueIID, failure, record.Content)
+	}
+
+	if err := store.SaveFailureBundle(record); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to save failure bundle of issue #%d: %v", issueIID, err)
+		return
+	}
+	logging.Issue(issueIID).Infof("Saved failure bundle of issue #%d (%d output lines)", issueIID, len(bundle.Output))
+}
+
+// uploadFailureBundle uploads a failure bundle as a private project snippet
+// and links it on the issue, returning its URL or "" when the upload failed
+func (d *Daemon) uploadFailureBundle(issueIID int, failure claude.FailureKind, content string) string {
+	project, err := d.gitlabClient.GetProject(strings.ReplaceAll(d.selectedProject, "/", "%2F"))
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to get project for failure bundle of issue #%d: %v", issueIID, err)
+		return ""
+	}
+	title := fmt.Sprintf("Issue #%d session failure", issueIID)
+	snippet, err := d.gitlabClient.CreateProjectSnippet(project.ID, title, fmt.Sprintf("issue-%d-failure.txt", issueIID), content)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to upload failure bundle of issue #%d: %v", issueIID, err)
+		return ""
+	}
+
+	kind := string(failure)
+	if failure == claude.FailureNone {
+		kind = "unclassified"
+	}
+	d.postFailureNote(issueIID, d.message(locale.MsgFailureBundle, map[string]interface{}{
+		"Failure": kind,
+		"Link":    snippet.WebURL,
+	}))
+	return snippet.WebURL
+}
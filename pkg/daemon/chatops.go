@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/chatops"
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/events"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// startChatOps answers slash commands on CHATOPS_ADDR and posts summaries of
+// finished sessions to CHATOPS_WEBHOOK_URL until ctx is done
+func (d *Daemon) startChatOps(ctx context.Context) {
+	cfg := d.config.ChatOps
+	if cfg.Addr == "" && cfg.WebhookURL == "" {
+		return
+	}
+	bridge := chatops.NewBridge(d.Controller(), chatops.Options{
+		Platform:      cfg.Platform,
+		Token:         cfg.Token,
+		SigningSecret: cfg.SigningSecret,
+		WebhookURL:    cfg.WebhookURL,
+	})
+
+	if cfg.WebhookURL != "" && !d.dryRun && !d.semiDryRun {
+		unsubscribe := events.Subscribe(d.events, func(event events.SessionCompleted) {
+			// Held and paused sessions carry on later, so there is nothing to summarize yet
+			if failure := claude.FailureKind(event.Failure); failure == claude.FailureApproval || failure == claude.FailurePaused {
+				return
+			}
+			summary := bridge.Summary(controlSession(event.SessionRun), d.issueURL(event.ProjectPath, event.IssueIID))
+			go func() {
+				if err := bridge.Post(summary); err != nil {
+					logging.Issue(event.IssueIID).Warnf("Failed to post session summary to chat: %v", err)
+				}
+			}()
+		})
+		go func() {
+			<-ctx.Done()
+			unsubscribe()
+		}()
+	}
+
+	if cfg.Addr == "" {
+		return
+	}
+	if cfg.Token == "" && cfg.SigningSecret == "" {
+		logging.Warnf("CHATOPS_ADDR is set but neither CHATOPS_TOKEN nor CHATOPS_SIGNING_SECRET is, slash commands disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/chatops", bridge)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		logging.Warnf("ChatOps server failed to listen on %s: %v", cfg.Addr, err)
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		logging.Infof("Slash commands listening on %s/chatops", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logging.Warnf("ChatOps server stopped: %v", err)
+		}
+	}()
+}
+
+// issueURL returns the web address of an issue
+func (d *Daemon) issueURL(projectPath string, issueIID int) string {
+	if projectPath == "" {
+		projectPath = d.selectedProject
+	}
+	return fmt.Sprintf("%s/%s/-/issues/%d", strings.TrimRight(d.config.GitLab.URL, "/"), projectPath, issueIID)
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// lintIssueCI checks the .gitlab-ci.yml of a completed issue session with
+// GitLab's CI lint when the session changed it. An invalid configuration is
+// handed back to Claude up to CI_LINT_FIX_ATTEMPTS times. It returns the
+// errors left after the last attempt, nil once the configuration is valid,
+// and how many resumes were made.
+func (d *Daemon) lintIssueCI(process *claude.Process, forkPath, branch, previousSessionID, timestamp string) ([]string, int) {
+	if d.dryRun || d.semiDryRun {
+		return nil, 0
+	}
+
+	lintErrors := d.ciLintErrors(process.IssueNum, d.selectedProject, process.WorkingDir, branch)
+	attempts := 0
+	for len(lintErrors) > 0 {
+		d.recordEvent(process.IssueNum, session.EventCILint, process.ClaudeSessionID, strings.Join(lintErrors, "; "))
+		if attempts == d.config.Daemon.CILintFixAttempts {
+			break
+		}
+		attempts++
+		logging.Issue(process.IssueNum).Infof("CI configuration of issue #%d is invalid, resuming to fix it (attempt %d/%d)", process.IssueNum, attempts, d.config.Daemon.CILintFixAttempts)
+		if !d.resumeToFix(process, forkPath, branch, previousSessionID, timestamp) {
+			break
+		}
+		lintErrors = d.ciLintErrors(process.IssueNum, d.selectedProject, process.WorkingDir, branch)
+	}
+	return lintErrors, attempts
+}
+
+// ciLintContext describes the CI lint errors of a session's branch for a
+// resume prompt, "" when its configuration is valid or unchanged
+func (d *Daemon) ciLintContext(s *session.CompletedSession) string {
+	lintErrors := d.ciLintErrors(s.IssueIID, s.ProjectPath, s.WorkingDir, sessionBranch(s))
+	if len(lintErrors) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("# Invalid CI Configuration\n\n")
+	fmt.Fprintf(&b, "GitLab's CI lint rejects the `%s` on your branch:\n\n%s\n\n", ciConfigPath, formatLintErrors(lintErrors))
+	b.WriteString("Please fix the configuration and push the changes to the same branch.\n\n")
+	return b.String()
+}
+
+// ciLintErrors lints the .gitlab-ci.yml of a branch against its project when
+// the branch changed it, returning GitLab's errors. It returns nil when the
+// configuration is valid, untouched or cannot be checked.
+func (d *Daemon) ciLintErrors(issueIID int, projectPath, repoDir, branch string) []string {
+	content, changed, err := changedCIConfig(repoDir, branch)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to check the CI configuration of issue #%d: %v", issueIID, err)
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+
+	project, err := d.gitlabClient.GetProject(strings.ReplaceAll(projectPath, "/", "%2F"))
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to get project %s to lint its CI configuration: %v", projectPath, err)
+		return nil
+	}
+	lint, err := d.gitlabClient.LintCIConfig(project.ID, content)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to lint the CI configuration of issue #%d: %v", issueIID, err)
+		return nil
+	}
+	if lint.Valid {
+		return nil
+	}
+	if len(lint.Errors) == 0 {
+		return []string{"the configuration is invalid, GitLab gave no details"}
+	}
+	return lint.Errors
+}
+
+// changedCIConfig returns the .gitlab-ci.yml of a branch and whether the
+// branch added or changed it since it left the default branch
+func changedCIConfig(repoDir, branch string) (string, bool, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=d", "origin/HEAD..."+branch, "--", ciConfigPath)
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", false, err
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return "", false, nil
+	}
+
+	cmd = exec.Command("git", "show", branch+":"+ciConfigPath)
+	cmd.Dir = repoDir
+	content, err := cmd.Output()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %v", ciConfigPath, err)
+	}
+	return string(content), true, nil
+}
+
+// formatLintErrors renders lint errors as a markdown list
+func formatLintErrors(lintErrors []string) string {
+	return "- " + strings.Join(lintErrors, "\n- ")
+}
@@ -0,0 +1,190 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/cigen"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+const ciConfigPath = ".gitlab-ci.yml"
+
+// Versions used when the repository does not pin one
+const (
+	defaultGoVersion     = "1.24"
+	defaultNodeVersion   = "22"
+	defaultPythonVersion = "3.12"
+)
+
+// ciManifests maps a file at the root of a repository to the template
+// building it, checked before falling back to GitLab's language statistics
+var ciManifests = []struct{ file, language string }{
+	{"go.mod", "go"},
+	{"package.json", "node"},
+	{"requirements.txt", "python"},
+	{"pyproject.toml", "python"},
+}
+
+// ciProposal is a merge request adding or regenerating a CI configuration
+type ciProposal struct {
+	mergeRequest *gitlab.MergeRequest
+	replaced     bool   // The project had a configuration before
+	jobs         string // The templates used, e.g. "go, docker"
+}
+
+// setupCI generates a .gitlab-ci.yml for a project from the job templates and
+// opens a merge request with it once GitLab's CI lint accepts it. An existing
+// configuration has its generated part replaced; one written by hand is only
+// replaced when replace is set. It returns nil when nothing needs to change.
+func (d *Daemon) setupCI(project *gitlab.Project, reference string, replace bool) (*ciProposal, error) {
+	branch := project.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	options := cigen.Options{
+		Deploy:        d.config.CI.Deploy,
+		GoVersion:     defaultGoVersion,
+		NodeVersion:   defaultNodeVersion,
+		PythonVersion: defaultPythonVersion,
+		Service:       path.Base(project.PathWithNamespace),
+		Region:        d.config.CI.Region,
+	}
+	for _, manifest := range ciManifests {
+		content, err := d.gitlabClient.GetRawFile(project.ID, branch, manifest.file)
+		if err != nil {
+			return nil, err
+		}
+		if content == "" {
+			continue
+		}
+		options.Language = manifest.language
+		if manifest.file == "go.mod" {
+			options.GoVersion = cigen.GoVersion(content, defaultGoVersion)
+		}
+		break
+	}
+	if options.Language == "" {
+		languages, err := d.gitlabClient.GetProjectLanguages(project.PathWithNamespace)
+		if err != nil {
+			logging.Warnf("Failed to get languages of %s: %v", project.PathWithNamespace, err)
+		}
+		options.Language = cigen.LanguageTemplate(gitlab.MainLanguage(languages))
+	}
+
+	dockerfile, err := d.gitlabClient.GetRawFile(project.ID, branch, "Dockerfile")
+	if err != nil {
+		return nil, err
+	}
+	options.Docker = dockerfile != ""
+	if options.Deploy == cigen.DeployCloudRun && !options.Docker {
+		logging.Infof("%s has no Dockerfile, deploying it with %s", project.PathWithNamespace, cigen.DeployCloudRunSource)
+		options.Deploy = cigen.DeployCloudRunSource
+	}
+
+	templates, err := cigen.Load(d.config.CI.TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := templates.Generate(options)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := d.gitlabClient.GetRawFile(project.ID, branch, ciConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if existing != "" && !replace && !strings.Contains(existing, cigen.BeginMarker) {
+		logging.Infof("%s already has a CI configuration, leaving it alone", project.PathWithNamespace)
+		return nil, nil
+	}
+	config := cigen.Merge(existing, generated)
+	if config == existing {
+		return nil, nil
+	}
+
+	lint, err := d.gitlabClient.LintCIConfig(project.ID, config)
+	if err != nil {
+		return nil, err
+	}
+	if !lint.Valid {
+		return nil, fmt.Errorf("GitLab's CI lint rejected the generated configuration:\n- %s", strings.Join(lint.Errors, "\n- "))
+	}
+	for _, warning := range lint.Warnings {
+		logging.Warnf("CI lint warning for %s: %s", project.PathWithNamespace, warning)
+	}
+
+	var jobs []string
+	if options.Language != "" {
+		jobs = append(jobs, options.Language)
+	}
+	if options.Docker {
+		jobs = append(jobs, "docker")
+	}
+	if options.Deploy != cigen.DeployNone {
+		jobs = append(jobs, options.Deploy)
+	}
+
+	source := fmt.Sprintf("automagic/ci-setup-%d", time.Now().Unix())
+	if err := d.gitlabClient.CreateBranch(project.ID, source, branch); err != nil {
+		return nil, err
+	}
+	if err := d.gitlabClient.CommitFiles(project.ID, source, "Generate "+ciConfigPath+" for "+reference,
+		map[string]string{ciConfigPath: config}); err != nil {
+		return nil, err
+	}
+	mr, err := d.gitlabClient.CreateMergeRequest(project.ID, gitlab.NewMergeRequest{
+		SourceBranch: source,
+		TargetBranch: branch,
+		Title:        "Set up GitLab CI",
+		Description: fmt.Sprintf("Generated `%s` with %s jobs for %s. GitLab's CI lint accepted it.\n\n"+
+			"Edits between `%s` and `%s` are replaced when the configuration is regenerated.",
+			ciConfigPath, strings.Join(jobs, ", "), reference, cigen.BeginMarker, cigen.EndMarker),
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.Infof("Proposed a CI configuration for %s in !%d", project.PathWithNamespace, mr.IID)
+	return &ciProposal{mergeRequest: mr, replaced: existing != "", jobs: strings.Join(jobs, ", ")}, nil
+}
+
+// setupIssueCI works on an issue carrying CI_SETUP_LABEL: it proposes a
+// regenerated .gitlab-ci.yml for the selected project, replacing one written
+// by hand, and comments the merge request on the issue
+func (d *Daemon) setupIssueCI(issue *gitlab.Issue) error {
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would propose a CI configuration for issue #%d", issue.IID)
+		return nil
+	}
+
+	return d.runIssueTask(issue, "CI setup", "set up CI", locale.MsgCISetupFailed, func() error {
+		project, err := d.gitlabClient.GetProject(strings.ReplaceAll(d.selectedProject, "/", "%2F"))
+		if err != nil {
+			return fmt.Errorf("failed to get project %s: %v", d.selectedProject, err)
+		}
+		proposal, err := d.setupCI(project, fmt.Sprintf("#%d", issue.IID), true)
+		if err != nil {
+			return err
+		}
+
+		comment := d.message(locale.MsgCIUpToDate, nil)
+		if proposal != nil {
+			comment = d.message(locale.MsgCIProposed, map[string]interface{}{
+				"MergeRequest": proposal.mergeRequest.IID,
+				"Link":         proposal.mergeRequest.WebURL,
+				"Replaced":     proposal.replaced,
+				"Jobs":         proposal.jobs,
+			})
+		}
+		if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, comment); err != nil {
+			logging.Issue(issue.IID).Warnf("Failed to post CI setup comment on issue #%d: %v", issue.IID, err)
+		}
+		return nil
+	})
+}
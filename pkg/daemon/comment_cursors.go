@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// commentCursors tracks the ID of the last note processed per issue. With a
+// tracker the IDs survive restarts; otherwise, as in dry runs, they only live
+// in memory.
+type commentCursors struct {
+	mu      sync.Mutex
+	notes   map[int]int
+	tracker session.CommentTracker
+}
+
+// newCommentCursors persists cursors through store when it supports it and
+// persist is set
+func newCommentCursors(store session.Store, persist bool) *commentCursors {
+	cursors := &commentCursors{notes: make(map[int]int)}
+	if tracker, ok := store.(session.CommentTracker); ok && persist {
+		cursors.tracker = tracker
+	}
+	return cursors
+}
+
+// get returns the ID of the last processed note of an issue
+func (c *commentCursors) get(issueIID int) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if noteID, ok := c.notes[issueIID]; ok {
+		return noteID, true
+	}
+	if c.tracker == nil {
+		return 0, false
+	}
+	noteID, ok := c.tracker.GetProcessedNote(issueIID)
+	if ok {
+		c.notes[issueIID] = noteID
+	}
+	return noteID, ok
+}
+
+// set records the ID of the last processed note of an issue
+func (c *commentCursors) set(issueIID, noteID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.notes[issueIID] = noteID
+	if c.tracker != nil {
+		if err := c.tracker.SetProcessedNote(issueIID, noteID); err != nil {
+			logging.Issue(issueIID).Warnf("Failed to persist last processed note for issue #%d: %v", issueIID, err)
+		}
+	}
+}
+
+// forget drops the last processed note of an issue
+func (c *commentCursors) forget(issueIID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.notes, issueIID)
+	if c.tracker != nil {
+		if err := c.tracker.ClearProcessedNote(issueIID); err != nil {
+			logging.Issue(issueIID).Warnf("Failed to clear last processed note for issue #%d: %v", issueIID, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/control"
+	"github.com/bilbo290/automagic/pkg/feed"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/queue"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Controller returns the daemon's control operations, as served on
+// CONTROL_ADDR, for programs and bridges running alongside it
+func (d *Daemon) Controller() control.Controller {
+	return daemonController{d}
+}
+
+// daemonController implements control.Controller for a daemon
+type daemonController struct {
+	d *Daemon
+}
+
+func (c daemonController) Enqueue(projectPath string, issueIID int) error {
+	d := c.d
+	if d.queueRole() == queue.RoleWorker {
+		return control.ErrUnsupported
+	}
+	if projectPath != "" && d.config.ResolveProject(projectPath) != d.selectedProject {
+		return control.ErrOtherProject
+	}
+	logging.Issue(issueIID).Infof("Issue #%d enqueued through the control API", issueIID)
+	d.EnqueueIssue(issueIID)
+	return nil
+}
+
+func (c daemonController) Status() control.Status {
+	d := c.d
+	status := control.Status{
+		Project:     d.selectedProject,
+		Role:        d.queueRole(),
+		DryRun:      d.dryRun || d.semiDryRun,
+		Running:     []control.RunningSession{},
+		TierUsage:   d.scheduler.usage(),
+		GeneratedAt: time.Now(),
+	}
+
+	for _, process := range d.processManager.GetRunningProcesses() {
+		running := control.RunningSession{
+			IssueIID:  process.IssueNum,
+			Kind:      session.RunIssue,
+			SessionID: process.ClaudeSessionID,
+			StartedAt: process.StartTime,
+		}
+		if process.Cmd != nil && process.Cmd.Process != nil {
+			running.PID = process.Cmd.Process.Pid
+		}
+		status.Running = append(status.Running, running)
+	}
+	for issueIID, cmd := range d.resumeProcesses {
+		running := control.RunningSession{IssueIID: issueIID, Kind: session.RunResume}
+		if cmd != nil && cmd.Process != nil {
+			running.PID = cmd.Process.Pid
+		}
+		status.Running = append(status.Running, running)
+	}
+	sort.Slice(status.Running, func(i, j int) bool { return status.Running[i].IssueIID < status.Running[j].IssueIID })
+
+	d.wakeMu.Lock()
+	for issueIID := range d.enqueuedIssues {
+		status.Enqueued = append(status.Enqueued, issueIID)
+	}
+	d.wakeMu.Unlock()
+	sort.Ints(status.Enqueued)
+	return status
+}
+
+func (c daemonController) Cancel(issueIID int) error {
+	d := c.d
+	for _, process := range d.processManager.GetRunningProcesses() {
+		if process.IssueNum == issueIID {
+			logging.Issue(issueIID).Infof("Cancelling session for issue #%d on request", issueIID)
+			process.Cancel()
+			return nil
+		}
+	}
+	if cmd := d.resumeProcesses[issueIID]; cmd != nil && cmd.Process != nil {
+		logging.Issue(issueIID).Infof("Cancelling resume session for issue #%d on request", issueIID)
+		d.cancelled.add(issueIID)
+		cmd.Process.Signal(syscall.SIGTERM)
+		return nil
+	}
+	return control.ErrNotRunning
+}
+
+func (c daemonController) Sessions(since time.Time) ([]control.Session, error) {
+	history, ok := c.d.sessionStore.(session.RunHistory)
+	if !ok {
+		return nil, control.ErrUnsupported
+	}
+	runs, err := history.GetRuns(since)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]control.Session, 0, len(runs))
+	for _, run := range runs {
+		sessions = append(sessions, controlSession(run))
+	}
+	return sessions, nil
+}
+
+// controlSession converts a session run for the control API
+func controlSession(run session.SessionRun) control.Session {
+	return control.Session{
+		ProjectPath: run.ProjectPath,
+		IssueIID:    run.IssueIID,
+		SessionID:   run.SessionID,
+		Kind:        run.Kind,
+		Outcome:     run.Outcome,
+		Failure:     run.Failure,
+		Model:       run.Model,
+		StartedAt:   run.StartedAt,
+		Duration:    run.Duration.Seconds(),
+		CostUSD:     run.CostUSD,
+		Turns:       run.Turns,
+		Retries:     run.Retries,
+
+		InputTokens:  run.InputTokens,
+		OutputTokens: run.OutputTokens,
+		CacheTokens:  run.CacheReadTokens + run.CacheCreationTokens,
+	}
+}
+
+// cancellations remembers resume sessions stopped through the control API,
+// so their exit is not mistaken for a failure
+type cancellations struct {
+	mu     sync.Mutex
+	issues map[int]bool
+}
+
+func (c *cancellations) add(issueIID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.issues == nil {
+		c.issues = make(map[int]bool)
+	}
+	c.issues[issueIID] = true
+}
+
+// take reports whether the issue's session was cancelled, forgetting it
+func (c *cancellations) take(issueIID int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cancelled := c.issues[issueIID]
+	delete(c.issues, issueIID)
+	return cancelled
+}
+
+// startControlServer serves the control API, and the activity feeds next to
+// it, on CONTROL_ADDR until ctx is done
+func (d *Daemon) startControlServer(ctx context.Context) {
+	cfg := d.config.Control
+	if cfg.Addr == "" {
+		return
+	}
+	if cfg.Token == "" {
+		logging.Warnf("CONTROL_ADDR is set but CONTROL_TOKEN is empty, control API disabled")
+		return
+	}
+
+	// Feed readers get a read-only token of their own; the control token works too
+	mux := http.NewServeMux()
+	feeds := feed.NewHandler(d.activityFeed, cfg.Token, cfg.FeedToken)
+	for _, path := range feed.Paths {
+		mux.Handle(path, feeds)
+	}
+
+	// gRPC calls and the feeds share the listener, gRPC over plaintext HTTP/2
+	grpcServer := control.NewServer(d.Controller(), cfg.Token)
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if control.IsGRPC(r.ProtoMajor, r.Header.Get("Content-Type")) {
+				grpcServer.ServeHTTP(w, r)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}),
+		Protocols:         protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		logging.Warnf("Control API failed to listen on %s: %v", cfg.Addr, err)
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		logging.Infof("Control API listening on %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logging.Warnf("Control API stopped: %v", err)
+		}
+	}()
+}
+
+// cancelledFailure returns FailureCancelled for a resume stopped through the
+// control API, and failure otherwise
+func (d *Daemon) cancelledFailure(issueIID int, failure claude.FailureKind) claude.FailureKind {
+	if d.cancelled.take(issueIID) {
+		return claude.FailureCancelled
+	}
+	return failure
+}
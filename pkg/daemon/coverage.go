@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/coverage"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// reportIssueCoverage measures the test coverage of a completed issue
+// session's branch and of the default branch commit it started from, and
+// posts the delta on the merge request. The default branch side is cached per
+// commit. It returns what to add to the completion comment: the delta when it
+// is a regression beyond COVERAGE_THRESHOLD or could not be posted, else "".
+func (d *Daemon) reportIssueCoverage(process *claude.Process, branch string) string {
+	if !d.config.Coverage.Enabled || d.dryRun || d.semiDryRun {
+		return ""
+	}
+
+	command := d.config.Coverage.Command
+	if command == "" {
+		if _, err := os.Stat(filepath.Join(process.WorkingDir, "go.mod")); err != nil {
+			logging.Issue(process.IssueNum).Debugf("Skipping coverage of issue #%d: no COVERAGE_COMMAND and not a Go module", process.IssueNum)
+			return ""
+		}
+		command = coverage.GoCommand
+	}
+	runner := &coverage.Runner{Command: command, Timeout: time.Duration(d.config.Coverage.Timeout) * time.Minute}
+
+	cmd := exec.Command("git", "merge-base", "origin/HEAD", branch)
+	cmd.Dir = process.WorkingDir
+	output, err := cmd.Output()
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to find where %s left the default branch: %v", branch, err)
+		return ""
+	}
+	base := strings.TrimSpace(string(output))
+
+	cache := &coverage.Cache{Dir: filepath.Join(d.config.Data.Dir, "coverage")}
+	before, cached := cache.Get(d.selectedProject, base)
+	if !cached {
+		if before, err = d.measureCoverage(runner, process.WorkingDir, base); err != nil {
+			logging.Issue(process.IssueNum).Warnf("Failed to measure the coverage of %s for issue #%d: %v", base, process.IssueNum, err)
+			return ""
+		}
+		if err := cache.Put(d.selectedProject, base, before); err != nil {
+			logging.Warnf("Failed to cache the coverage of %s: %v", base, err)
+		}
+	}
+	after, err := d.measureCoverage(runner, process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to measure the coverage of issue #%d: %v", process.IssueNum, err)
+		return ""
+	}
+
+	delta := after - before
+	regression := delta < -d.config.Coverage.Threshold
+	d.recordEvent(process.IssueNum, session.EventCoverage, process.ClaudeSessionID, fmt.Sprintf("%.1f%% → %.1f%%", before, after))
+	report := d.message(locale.MsgCoverageDelta, map[string]interface{}{
+		"Before":     fmt.Sprintf("%.1f%%", before),
+		"After":      fmt.Sprintf("%.1f%%", after),
+		"Delta":      fmt.Sprintf("%+.1f", delta),
+		"Regression": regression,
+		"Threshold":  fmt.Sprintf("%g", d.config.Coverage.Threshold),
+	})
+
+	posted := false
+	mr, err := d.issueMergeRequest(d.selectedProject, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to look up MR for issue #%d: %v", process.IssueNum, err)
+	} else if mr != nil {
+		if _, err := d.gitlabClient.CreateMergeRequestNote(d.selectedProject, mr.IID, report); err != nil {
+			logging.Issue(process.IssueNum).Warnf("Failed to post the coverage delta on !%d: %v", mr.IID, err)
+		} else {
+			posted = true
+		}
+	}
+	if regression || !posted {
+		return report
+	}
+	return ""
+}
+
+// measureCoverage runs the coverage command in a worktree of ref
+func (d *Daemon) measureCoverage(runner *coverage.Runner, repoDir, ref string) (float64, error) {
+	checkout, remove, err := d.branchWorktree(repoDir, ref)
+	if err != nil {
+		return 0, err
+	}
+	defer remove()
+	return runner.Measure(checkout)
+}
@@ -2,20 +2,33 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"os/signal"
+	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/bilbo290/automagic/pkg/claude"
 	"github.com/bilbo290/automagic/pkg/config"
+	"github.com/bilbo290/automagic/pkg/errreport"
+	"github.com/bilbo290/automagic/pkg/events"
 	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/heartbeat"
+	"github.com/bilbo290/automagic/pkg/interactive"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/metrics"
+	"github.com/bilbo290/automagic/pkg/prompts"
+	"github.com/bilbo290/automagic/pkg/queue"
 	"github.com/bilbo290/automagic/pkg/session"
+	"github.com/bilbo290/automagic/pkg/telemetry"
+	"github.com/bilbo290/automagic/pkg/tracker"
 )
 
 type Daemon struct {
@@ -27,96 +40,145 @@ type Daemon struct {
 	resumeProcesses map[int]*exec.Cmd // Track resume processes by issue ID
 	dryRun          bool
 	semiDryRun      bool
-	lastCommentTime map[int]string // Track last processed comment timestamp by issue ID
+	lastCommentTime *commentCursors // Last processed note ID by issue ID, persisted across restarts
+	scheduler       *tierScheduler // Per-complexity-tier concurrency limits
+	hours           *workingHours  // When new issues start, nil for any time
+	reviews         *reviewQueue   // Coalesced MR reviews keyed by head SHA
+	trigger         chan struct{}  // Wakes the polling loop early (webhook deliveries)
+
+	wakeMu           sync.Mutex
+	environmentMu    sync.Mutex       // Serializes building development environments
+	docIndexMu       sync.Mutex       // Serializes updating documentation indexes
+	pendingWorkflows workflow         // Workflows requested by webhooks since the last wake-up
+	enqueuedIssues   map[int]bool     // Issues passed to EnqueueIssue since the last cycle
+	failedPipelines  []failedPipeline // Pipeline failures awaiting a CI fix
+
+	lastBackfillRelease time.Time      // When the backfill queue last released an issue
+	activity            activityGate   // Project activity feed state for skipping idle ticks
+	retries             failureRetries // Recovery attempts per issue and failure kind
+
+	telemetry *telemetry.Reporter  // Opt-in usage counts, nil when disabled
+	heartbeat *heartbeat.Heartbeat // Liveness signal for supervisors, nil when disabled
+	metrics   *metrics.Metrics     // Prometheus metrics, nil when disabled
+
+	errorReports *errreport.Reporter // Sentry or error webhook reporting, nil when disabled
+	trackers     *tracker.Syncer     // Jira, Linear or Notion status sync, nil when disabled
+
+	events      *events.Bus // Issue, session, label and API events for subscribers
+	apiFailures apiFailures // GitLab operations that failed last time, for APIError events
+
+	stopCh        chan struct{}   // Closed by Stop
+	runCtx        context.Context // Context of the running loop, set by runContext
+	stopOnce      sync.Once
+	ignoreSignals bool // Leave SIGINT and SIGTERM to the embedding program
+
+	cancelled cancellations // Resume sessions stopped through the control API
+
+	queue     queue.Queue    // Job queue shared with other daemons, nil when standalone
+	leases    *leaseSet      // Jobs this worker claimed from the queue
+	languages *languageCache // Main language per project, for routing jobs
+
+	labelModels labelModelCache // Label associations learned per project
 }
 
 func New(gitlabClient *gitlab.Client, config *config.Config) *Daemon {
 	var sessionStore session.Store
 
-	sqliteStore, err := session.NewSQLiteSessionStore("")
+	sqliteStore, err := session.NewSQLiteSessionStore(config.Data.Dir)
 	if err != nil {
-		fmt.Printf("Warning: Failed to create SQLite session store, falling back to JSON store: %v\n", err)
+		logging.Warnf("Failed to create SQLite session store, falling back to JSON store: %v", err)
 		// Fallback to JSON store
-		jsonStore := session.NewSessionStore("")
+		jsonStore := session.NewSessionStore(config.Data.Dir)
 		jsonStore.Load() // Load existing sessions, ignore errors
 		sessionStore = jsonStore
 	} else {
 		sessionStore = sqliteStore
 		// Try to migrate from old JSON store if it exists
-		jsonStore := session.NewSessionStore("")
+		jsonStore := session.NewSessionStore(config.Data.Dir)
 		if jsonStore.Load() == nil {
-			fmt.Printf("Migrating sessions from JSON to SQLite...\n")
+			logging.Infof("Migrating sessions from JSON to SQLite...")
 			if err := sqliteStore.MigrateFromJSONStore(jsonStore); err != nil {
-				fmt.Printf("Warning: Failed to migrate sessions: %v\n", err)
+				logging.Warnf("Failed to migrate sessions: %v", err)
 			} else {
-				fmt.Printf("Successfully migrated sessions to SQLite\n")
+				logging.Infof("Successfully migrated sessions to SQLite")
 			}
 		}
 
-		// Clean up invalid and old sessions
-		fmt.Printf("Cleaning up invalid session IDs...\n")
+		// Clean up invalid sessions; old ones are pruned by the maintenance
+		// loop once the project is known, per SESSION_RETAIN_*_DAYS
+		logging.Infof("Cleaning up invalid session IDs...")
 		if err := sqliteStore.CleanupInvalidSessions(); err != nil {
-			fmt.Printf("Warning: Failed to cleanup invalid sessions: %v\n", err)
-		}
-
-		// Clean up sessions older than 7 days (Claude sessions likely expire)
-		fmt.Printf("Cleaning up old sessions...\n")
-		if err := sqliteStore.CleanupOldSessions(7 * 24 * time.Hour); err != nil {
-			fmt.Printf("Warning: Failed to cleanup old sessions: %v\n", err)
+			logging.Warnf("Failed to cleanup invalid sessions: %v", err)
 		}
 	}
 
-	return &Daemon{
+	d := &Daemon{
 		gitlabClient:    gitlabClient,
 		config:          config,
 		processManager:  claude.NewProcessManager(),
 		sessionStore:    sessionStore,
 		resumeProcesses: make(map[int]*exec.Cmd),
 		dryRun:          false,
-		lastCommentTime: make(map[int]string),
-	}
+		lastCommentTime: newCommentCursors(sessionStore, true),
+		scheduler:       newTierScheduler(config.Daemon.TierLimits, config.Daemon.UntieredLimit),
+		hours:           newWorkingHours(config.WorkingHours.Hours, config.WorkingHours.Days, config.WorkingHours.Timezone),
+		reviews:         newReviewQueue(config.Daemon.MaxConcurrentReviews, sessionStore),
+		trigger:         make(chan struct{}, 1),
+		events:          events.NewBus(),
+		stopCh:          make(chan struct{}),
+	}
+	d.subscribe()
+	return d
 }
 
 func NewWithDryRun(gitlabClient *gitlab.Client, config *config.Config, dryRun bool) *Daemon {
 	var sessionStore session.Store
 
-	sqliteStore, err := session.NewSQLiteSessionStore("")
+	sqliteStore, err := session.NewSQLiteSessionStore(config.Data.Dir)
 	if err != nil {
-		fmt.Printf("Warning: Failed to create SQLite session store, falling back to JSON store: %v\n", err)
+		logging.Warnf("Failed to create SQLite session store, falling back to JSON store: %v", err)
 		// Fallback to JSON store
-		jsonStore := session.NewSessionStore("")
+		jsonStore := session.NewSessionStore(config.Data.Dir)
 		jsonStore.Load() // Load existing sessions, ignore errors
 		sessionStore = jsonStore
 	} else {
 		sessionStore = sqliteStore
 	}
 
-	return &Daemon{
+	d := &Daemon{
 		gitlabClient:    gitlabClient,
 		config:          config,
 		processManager:  claude.NewProcessManager(),
 		sessionStore:    sessionStore,
 		resumeProcesses: make(map[int]*exec.Cmd),
 		dryRun:          dryRun,
-		lastCommentTime: make(map[int]string),
-	}
+		lastCommentTime: newCommentCursors(sessionStore, !dryRun),
+		scheduler:       newTierScheduler(config.Daemon.TierLimits, config.Daemon.UntieredLimit),
+		hours:           newWorkingHours(config.WorkingHours.Hours, config.WorkingHours.Days, config.WorkingHours.Timezone),
+		reviews:         newReviewQueue(config.Daemon.MaxConcurrentReviews, sessionStore),
+		trigger:         make(chan struct{}, 1),
+		events:          events.NewBus(),
+		stopCh:          make(chan struct{}),
+	}
+	d.subscribe()
+	return d
 }
 
 func NewWithSemiDryRun(gitlabClient *gitlab.Client, config *config.Config) *Daemon {
 	var sessionStore session.Store
 
-	sqliteStore, err := session.NewSQLiteSessionStore("")
+	sqliteStore, err := session.NewSQLiteSessionStore(config.Data.Dir)
 	if err != nil {
-		fmt.Printf("Warning: Failed to create SQLite session store, falling back to JSON store: %v\n", err)
+		logging.Warnf("Failed to create SQLite session store, falling back to JSON store: %v", err)
 		// Fallback to JSON store
-		jsonStore := session.NewSessionStore("")
+		jsonStore := session.NewSessionStore(config.Data.Dir)
 		jsonStore.Load() // Load existing sessions, ignore errors
 		sessionStore = jsonStore
 	} else {
 		sessionStore = sqliteStore
 	}
 
-	return &Daemon{
+	d := &Daemon{
 		gitlabClient:    gitlabClient,
 		config:          config,
 		processManager:  claude.NewProcessManager(),
@@ -124,10 +186,97 @@ func NewWithSemiDryRun(gitlabClient *gitlab.Client, config *config.Config) *Daem
 		resumeProcesses: make(map[int]*exec.Cmd),
 		dryRun:          false, // For semi-dry-run, we clone repos but don't execute
 		semiDryRun:      true,
-		lastCommentTime: make(map[int]string),
+		lastCommentTime: newCommentCursors(sessionStore, false),
+		scheduler:       newTierScheduler(config.Daemon.TierLimits, config.Daemon.UntieredLimit),
+		hours:           newWorkingHours(config.WorkingHours.Hours, config.WorkingHours.Days, config.WorkingHours.Timezone),
+		reviews:         newReviewQueue(config.Daemon.MaxConcurrentReviews, sessionStore),
+		trigger:         make(chan struct{}, 1),
+		events:          events.NewBus(),
+		stopCh:          make(chan struct{}),
+	}
+	d.subscribe()
+	return d
+}
+
+// SetTelemetry enables usage reporting through reporter
+func (d *Daemon) SetTelemetry(reporter *telemetry.Reporter) {
+	d.telemetry = reporter
+}
+
+// SetHeartbeat signals each completed polling cycle to external supervisors
+func (d *Daemon) SetHeartbeat(beat *heartbeat.Heartbeat) {
+	d.heartbeat = beat
+}
+
+// SetMetrics enables Prometheus metrics; nil disables them
+func (d *Daemon) SetMetrics(m *metrics.Metrics) {
+	d.metrics = m
+}
+
+// SetErrorReporter enables error reporting; nil disables it
+func (d *Daemon) SetErrorReporter(reporter *errreport.Reporter) {
+	d.errorReports = reporter
+}
+
+// SetTrackers mirrors issue status transitions to external trackers; nil
+// disables it
+func (d *Daemon) SetTrackers(trackers *tracker.Syncer) {
+	d.trackers = trackers
+}
+
+// endCycle signals a completed polling cycle to supervisors and metrics
+func (d *Daemon) endCycle() {
+	d.heartbeat.Beat()
+	d.metrics.Cycle(len(d.processManager.GetRunningProcesses()))
+}
+
+// useProject selects the monitored project and scopes the session store to it
+func (d *Daemon) useProject(projectPath string) {
+	d.selectedProject = projectPath
+	logging.SetProject(projectPath)
+	d.applyProjectConfig(projectPath)
+	if scoped, ok := d.sessionStore.(session.ProjectScoped); ok {
+		scoped.SetProject(projectPath)
 	}
 }
 
+// applyProjectConfig reloads the configuration when the configuration file
+// was applied for another project than the one selected, so the selected
+// project's overrides take effect. Settings used only at startup, such as
+// concurrency limits, keep their loaded values. The reloaded configuration
+// replaces the daemon's own rather than being copied over the one it was
+// given, and only before the daemon starts, since its goroutines read the
+// configuration without locking.
+func (d *Daemon) applyProjectConfig(projectPath string) {
+	if d.config.Source.File == "" || d.config.Source.Project == projectPath {
+		return
+	}
+	if d.runCtx != nil {
+		logging.Warnf("Not applying the settings of %s, the daemon is already running", projectPath)
+		return
+	}
+	cfg, err := config.LoadForProject(projectPath)
+	if err != nil {
+		logging.Warnf("Failed to load the settings of %s: %v", projectPath, err)
+		return
+	}
+	if cfg.Source.ProjectOverrides {
+		logging.Infof("Applying the %s overrides of %s", projectPath, cfg.Source.File)
+	}
+	// Command line flags set these after loading
+	cfg.Webhook.Register = d.config.Webhook.Register
+	cfg.Triage.Enabled = d.config.Triage.Enabled
+	cfg.Queue.Role = d.config.Queue.Role
+	cfg.Logging.Level = d.config.Logging.Level
+	d.config = cfg
+}
+
+// Config returns the configuration the daemon runs with, which includes the
+// overrides of the project SetProject selected
+func (d *Daemon) Config() *config.Config {
+	return d.config
+}
+
 // isValidUUID checks if a string is a valid UUID format
 func isValidUUID(sessionID string) bool {
 	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
@@ -144,6 +293,22 @@ func (d *Daemon) selectProject(projects []gitlab.Project) (*gitlab.Project, erro
 		return &projects[0], nil
 	}
 
+	// Without a terminal nobody can answer the prompt, so run the configured
+	// default project or stop with an error instead of waiting forever
+	if !interactive.IsTerminal() {
+		defaultPath := d.config.Projects.DefaultPath
+		if defaultPath == "" {
+			return nil, fmt.Errorf("%d projects available and %v; set DEFAULT_PROJECT_PATH to choose the project to monitor", len(projects), interactive.ErrNotTerminal)
+		}
+		for i := range projects {
+			if projects[i].PathWithNamespace == defaultPath {
+				fmt.Printf("Using DEFAULT_PROJECT_PATH: %s\n", defaultPath)
+				return &projects[i], nil
+			}
+		}
+		return nil, fmt.Errorf("DEFAULT_PROJECT_PATH %s is not among the accessible projects", defaultPath)
+	}
+
 	fmt.Printf("\nSelect a project:\n")
 	for i, project := range projects {
 		fmt.Printf("%d. %s\n", i+1, project.PathWithNamespace)
@@ -156,20 +321,9 @@ func (d *Daemon) selectProject(projects []gitlab.Project) (*gitlab.Project, erro
 
 	fmt.Printf("Enter project number (1-%d): ", len(projects))
 
-	var choice int
-	for {
-		_, err := fmt.Scanf("%d", &choice)
-		if err != nil {
-			fmt.Printf("Invalid input. Please enter a number: ")
-			continue
-		}
-
-		if choice < 1 || choice > len(projects) {
-			fmt.Printf("Invalid choice. Please enter a number between 1 and %d: ", len(projects))
-			continue
-		}
-
-		break
+	choice, err := interactive.Choose(len(projects))
+	if err != nil {
+		return nil, err
 	}
 
 	selected := &projects[choice-1]
@@ -180,11 +334,48 @@ func (d *Daemon) selectProject(projects []gitlab.Project) (*gitlab.Project, erro
 func (d *Daemon) processIssueWithLabelUpdate(issue *gitlab.Issue) error {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 
+	// Re-check excluded labels client-side in case the API filter was ignored
+	if issue.HasAnyLabel(d.config.Daemon.ExcludeLabels) {
+		logging.Issue(issue.IID).Infof("Skipping issue #%d: carries an excluded label (%s)", issue.IID, strings.Join(d.config.Daemon.ExcludeLabels, ", "))
+		return nil
+	}
+
+	// Outside working hours the issue waits, and its reporter is told until when
+	if !d.hours.open(time.Now()) {
+		d.noteQuietHours(issue)
+		return errOutsideWorkingHours
+	}
+	d.hours.forget(issue.IID)
+
+	if d.checkDuplicates(issue, timestamp) {
+		return nil
+	}
+	d.suggestLabels(issue, timestamp)
+
+	// Scaffold issues create a project from the template instead of running a session
+	if d.config.Scaffold.Template != "" && issue.HasAnyLabel([]string{d.config.Scaffold.Label}) {
+		return d.scaffoldProject(issue)
+	}
+	if d.config.CI.SetupLabel != "" && issue.HasAnyLabel([]string{d.config.CI.SetupLabel}) {
+		return d.setupIssueCI(issue)
+	}
+
+	// Workers run the issue; the coordinator only hands it over
+	if d.queueRole() == queue.RoleCoordinator {
+		return d.enqueueIssue(issue, timestamp)
+	}
+
+	tier := issueTier(issue)
+	if !d.scheduler.tryAcquire(issue.IID, tier) {
+		return errTierAtCapacity
+	}
+
 	if d.dryRun {
-		fmt.Printf("[%s] [DRY RUN] Would process issue #%d\n", timestamp, issue.IID)
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would process issue #%d (tier %s)", issue.IID, tier)
+		d.scheduler.release(issue.IID)
 		return nil
 	} else if d.semiDryRun {
-		fmt.Printf("[%s] [SEMI-DRY RUN] Processing issue #%d\n", timestamp, issue.IID)
+		logging.Issue(issue.IID).Infof("[SEMI-DRY RUN] Processing issue #%d", issue.IID)
 	}
 
 	// Update labels to mark as being processed
@@ -200,17 +391,22 @@ func (d *Daemon) processIssueWithLabelUpdate(issue *gitlab.Issue) error {
 
 	if d.semiDryRun {
 		// Don't process in semi-dry-run mode
+		d.scheduler.release(issue.IID)
 		return nil
 	} else if !d.dryRun {
-		if err := d.gitlabClient.UpdateIssueLabels(d.selectedProject, issue.IID, newLabels); err != nil {
+		if err := d.setIssueLabels(issue.IID, newLabels); err != nil {
+			d.scheduler.release(issue.IID)
 			return fmt.Errorf("failed to update issue labels: %v", err)
 		}
 	}
 
 	// Process the issue asynchronously with completion callback
 	if err := d.processIssueAsync(issue.IID); err != nil {
+		d.scheduler.release(issue.IID)
 		return fmt.Errorf("failed to start process: %v", err)
 	}
+	d.recordEvent(issue.IID, session.EventPickedUp, "", fmt.Sprintf("tier %s", tier))
+	d.recordDescription(issue.IID, issue.Description)
 
 	return nil
 }
@@ -218,7 +414,7 @@ func (d *Daemon) processIssueWithLabelUpdate(issue *gitlab.Issue) error {
 func (d *Daemon) processIssue(issueNumber int) error {
 	processManager := claude.NewProcessManager()
 
-	fmt.Printf("Processing issue #%d...\n", issueNumber)
+	logging.Issue(issueNumber).Infof("Processing issue #%d...", issueNumber)
 
 	processID := fmt.Sprintf("issue-%d-%d", issueNumber, time.Now().Unix())
 
@@ -244,40 +440,196 @@ func (d *Daemon) processIssue(issueNumber int) error {
 }
 
 func (d *Daemon) processIssueAsync(issueNumber int) error {
+	return d.processIssueAsyncWithFlags(issueNumber, d.config.Claude.Flags)
+}
+
+// processIssueAsyncWithFlags starts an issue session with the given Claude flags
+func (d *Daemon) processIssueAsyncWithFlags(issueNumber int, claudeFlags string) error {
 	if d.dryRun {
-		fmt.Printf("[DRY RUN] Would start async process for issue #%d...\n", issueNumber)
+		logging.Issue(issueNumber).Infof("[DRY RUN] Would start async process for issue #%d...", issueNumber)
 	} else if d.semiDryRun {
-		fmt.Printf("[SEMI-DRY RUN] Starting repository check for issue #%d...\n", issueNumber)
+		logging.Issue(issueNumber).Infof("[SEMI-DRY RUN] Starting repository check for issue #%d...", issueNumber)
 	} else {
-		fmt.Printf("Starting async process for issue #%d...\n", issueNumber)
+		logging.Issue(issueNumber).Infof("Starting async process for issue #%d...", issueNumber)
 	}
 
 	processID := fmt.Sprintf("issue-%d-%d", issueNumber, time.Now().Unix())
 
+	// Without push access the branch goes to a fork, with a cross-project MR
+	forkPath := ""
+	if !d.dryRun && !d.semiDryRun {
+		var err error
+		if forkPath, err = d.issueFork(); err != nil {
+			return fmt.Errorf("failed to set up fork: %v", err)
+		}
+	}
+	if err := claude.PrepareForkRemote(d.selectedProject, d.config.GitLab.URL, forkPath, d.dryRun); err != nil {
+		return err
+	}
+	if forkPath != "" {
+		logging.Issue(issueNumber).Infof("No push access to %s, issue #%d will be pushed to fork %s", d.selectedProject, issueNumber, forkPath)
+	}
+
+	// Branches left by earlier attempts are kept, the new attempt gets its own
+	branch, previousBranch := issueBranch(issueNumber), ""
+	previousSessionID := ""
+	if !d.dryRun && !d.semiDryRun {
+		var err error
+		if branch, previousBranch, err = d.newIssueBranch(issueNumber, forkPath); err != nil {
+			// Released by the caller, the issue is picked up again next poll
+			return fmt.Errorf("failed to pick a branch: %v", err)
+		}
+		if previous, exists := d.sessionStore.GetCompletedSession(issueNumber); exists {
+			previousSessionID = previous.SessionID
+		}
+	}
+	if err := claude.PrepareIssueBranch(d.selectedProject, d.config.GitLab.URL, issueNumber, branch, previousBranch, d.dryRun); err != nil {
+		return err
+	}
+	if previousBranch != "" {
+		logging.Issue(issueNumber).Infof("Branch %s already exists, issue #%d will be pushed to %s", previousBranch, issueNumber, branch)
+	}
+
+	// Fail before the session rather than after it when the branch cannot be pushed
+	var environment *claude.Environment
+	if !d.dryRun && !d.semiDryRun {
+		if reason := d.pushBlocker(issueNumber, branch, forkPath); reason != "" {
+			d.blockIssue(issueNumber, branch, reason)
+			return errPushBlocked
+		}
+		// Sessions in a development environment get their tools from it
+		var err error
+		if environment, err = d.sessionEnvironment(d.selectedProject, ""); err != nil {
+			d.blockIssueForEnvironment(issueNumber, []string{err.Error()})
+			return errEnvironmentMissing
+		}
+		if environment == nil && d.config.Environment.ToolchainCheck {
+			if missing := d.missingToolchains(); len(missing) > 0 {
+				d.blockIssueForEnvironment(issueNumber, missing)
+				return errEnvironmentMissing
+			}
+		}
+	}
+
 	// Define completion labels - remove process label and add review label
 	completionLabels := []string{d.config.Daemon.ReviewLabel}
 
+	// Set once the session starts, so the progress comment ends with it
+	stopProgress := func() {}
+
 	// Create completion callback (runs asynchronously to avoid blocking)
 	onCompletion := func(process *claude.Process, success bool) error {
+		stopProgress()
+		// Free the tier slot so queued issues of the same complexity can start
+		d.scheduler.release(process.IssueNum)
+		d.finishLease(process.IssueNum, queue.Result{
+			Success:   success,
+			SessionID: process.ClaudeSessionID,
+			Detail:    strings.TrimSpace(fmt.Sprintf("%s %s", process.Failure, process.LastError)),
+		})
+
+		usage := process.Usage()
+		run := session.SessionRun{
+			IssueIID:  process.IssueNum,
+			SessionID: process.ClaudeSessionID,
+			Kind:      session.RunIssue,
+			Outcome:   runOutcome(success),
+			Model:     usage.Model,
+			StartedAt: process.StartTime,
+			Duration:  time.Since(process.StartTime),
+			CostUSD:   usage.CostUSD,
+			Turns:     usage.Turns,
+
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+		}
+		if !success {
+			run.Failure = string(process.Failure)
+		}
+		if process.Attempts > 1 {
+			run.Retries = process.Attempts - 1
+		}
+		d.recordRun(run)
+
+		// A paused session is stored before returning, so a shutdown waiting for it keeps it
+		if process.Failure == claude.FailurePaused {
+			d.pauseSession(process, forkPath, branch, previousSessionID)
+			return nil
+		}
+
 		// Run completion tasks asynchronously to avoid blocking the main process
 		go func() {
+			defer d.recoverPanic("completion of issue", process.IssueNum)
 			timestamp := time.Now().Format("2006-01-02 15:04:05")
 
 			if success {
-				fmt.Printf("[%s] Successfully completed issue #%d\n", timestamp, process.IssueNum)
+				logging.Issue(process.IssueNum).Infof("Successfully completed issue #%d", process.IssueNum)
+				d.retries.reset(process.IssueNum)
 
 				// First: Post a completion comment to the issue
-				completionComment := "✅ **Task completed successfully**\n\nClaude has finished processing this issue. The implementation has been completed and is ready for human review."
+				completionComment := d.message(locale.MsgCompleted, nil)
+
+				// An invalid CI configuration goes back to Claude before the pipeline runs on it
+				if lintErrors, attempts := d.lintIssueCI(process, forkPath, branch, previousSessionID, timestamp); len(lintErrors) > 0 {
+					completionComment += "\n\n" + d.message(locale.MsgCILintFailed, map[string]interface{}{
+						"Errors":   formatLintErrors(lintErrors),
+						"Attempts": attempts,
+					})
+				}
+
+				if migrationCheck := d.checkIssueMigrations(process, branch); migrationCheck != "" {
+					completionComment += "\n\n" + migrationCheck
+				}
+				if coverageDelta := d.reportIssueCoverage(process, branch); coverageDelta != "" {
+					completionComment += "\n\n" + coverageDelta
+				}
+				if benchmarks := d.benchmarkIssue(process, branch); benchmarks != "" {
+					completionComment += "\n\n" + benchmarks
+				}
+
+				// A Terraform plan that errors keeps the issue out of review
+				reviewLabel := d.config.Daemon.ReviewLabel
+				if plan, failed := d.planIssueTerraform(process, branch); plan != "" {
+					completionComment += "\n\n" + plan
+					if failed {
+						reviewLabel = "error"
+					}
+				}
+
+				// Hold the review transition until CI has reported, so reviewers see the result
+				if d.config.Daemon.WaitForPipeline {
+					timeout := time.Duration(d.config.Daemon.PipelineWaitTimeout) * time.Minute
+					logging.Issue(process.IssueNum).Infof("Waiting up to %s for the pipeline of issue #%d", timeout, process.IssueNum)
+					pipelineStatus, pipeline := d.waitForPipeline(process.IssueNum, branch, timeout, nil)
+					// A failed pipeline goes back to Claude with its job logs before reviewers see it
+					for attempt := 1; pipeline != nil && pipeline.Status == "failed" && attempt <= d.config.Daemon.PipelineFixAttempts; attempt++ {
+						d.recordEvent(process.IssueNum, session.EventPipeline, process.ClaudeSessionID, pipelineStatus)
+						logging.Issue(process.IssueNum).Infof("Pipeline of issue #%d failed, resuming to fix it (attempt %d/%d)", process.IssueNum, attempt, d.config.Daemon.PipelineFixAttempts)
+						if !d.resumeToFix(process, forkPath, branch, previousSessionID, timestamp) {
+							break
+						}
+						pipelineStatus, pipeline = d.waitForPipeline(process.IssueNum, branch, timeout, pipeline)
+					}
+					completionComment += "\n\n" + pipelineStatus
+					d.recordEvent(process.IssueNum, session.EventPipeline, process.ClaudeSessionID, pipelineStatus)
+				}
+				if mentions := d.mentionLine(process.IssueNum); mentions != "" {
+					completionComment += "\n\n" + mentions
+				}
+				completionNoteID := 0
 				note, err := d.gitlabClient.CreateIssueNote(d.selectedProject, process.IssueNum, completionComment)
 				if err != nil {
-					fmt.Printf("[%s] Warning: failed to post completion comment for issue #%d: %v\n", timestamp, process.IssueNum, err)
+					logging.Issue(process.IssueNum).Warnf("Failed to post completion comment for issue #%d: %v", process.IssueNum, err)
 					// Still continue with label updates even if comment fails
 				} else {
-					fmt.Printf("[%s] Posted completion comment for issue #%d\n", timestamp, process.IssueNum)
+					logging.Issue(process.IssueNum).Infof("Posted completion comment for issue #%d", process.IssueNum)
 					// Update the last comment time to the actual comment timestamp
 					// This prevents the daemon from immediately triggering again
-					d.lastCommentTime[process.IssueNum] = note.CreatedAt
-					fmt.Printf("[%s] Updated last comment time for issue #%d to comment timestamp: %s\n", timestamp, process.IssueNum, note.CreatedAt)
+					d.lastCommentTime.set(process.IssueNum, note.ID)
+					completionNoteID = note.ID
+					logging.Issue(process.IssueNum).Infof("Updated last processed note for issue #%d to completion comment %d", process.IssueNum, note.ID)
 				}
 
 				// Add a small delay to ensure the comment is processed
@@ -289,7 +641,7 @@ func (d *Daemon) processIssueAsync(issueNumber int) error {
 				// Get current issue to get current labels
 				issue, err := d.gitlabClient.GetIssue(d.selectedProject, process.IssueNum)
 				if err != nil {
-					fmt.Printf("[%s] Warning: failed to get issue #%d for label update: %v\n", timestamp, process.IssueNum, err)
+					logging.Issue(process.IssueNum).Warnf("Failed to get issue #%d for label update: %v", process.IssueNum, err)
 					return
 				}
 
@@ -299,54 +651,50 @@ func (d *Daemon) processIssueAsync(issueNumber int) error {
 						newLabels = append(newLabels, label)
 					}
 				}
-				newLabels = append(newLabels, d.config.Daemon.ReviewLabel)
+				newLabels = append(newLabels, reviewLabel)
 
 				// Update labels
-				if err := d.gitlabClient.UpdateIssueLabels(d.selectedProject, process.IssueNum, newLabels); err != nil {
-					fmt.Printf("[%s] Warning: failed to update completion labels for issue #%d: %v\n", timestamp, process.IssueNum, err)
+				if err := d.setIssueLabels(process.IssueNum, newLabels); err != nil {
+					logging.Issue(process.IssueNum).Warnf("Failed to update completion labels for issue #%d: %v", process.IssueNum, err)
 				} else {
-					fmt.Printf("[%s] Updated labels for issue #%d to '%s'\n", timestamp, process.IssueNum, d.config.Daemon.ReviewLabel)
+					logging.Issue(process.IssueNum).Infof("Updated labels for issue #%d to '%s'", process.IssueNum, reviewLabel)
 				}
 
 				// Store session information for comment monitoring
-				sessionID := process.ClaudeSessionID
-				if sessionID == "" {
-					fmt.Printf("[%s] Warning: Claude session ID not captured for issue #%d, using fallback ID %s\n", timestamp, process.IssueNum, process.ID)
-					sessionID = process.ID // Fallback to internal ID
+				sessionID := d.storeSession(process, forkPath, branch, previousSessionID, completionNoteID, timestamp)
+				d.recordEvent(process.IssueNum, session.EventCompleted, sessionID, "model "+process.ServedBy())
+				d.recordSummary(process, issue, branch)
+				d.recordLink(process.IssueNum, d.selectedProject, forkPath, branch)
+			} else {
+				logging.Issue(process.IssueNum).Errorf("Failed to complete issue #%d", process.IssueNum)
+				// A session held for approval is resumed once a human approves
+				if process.Failure == claude.FailureApproval {
+					d.storeSession(process, forkPath, branch, previousSessionID, 0, timestamp)
+					d.requestApproval(process.IssueNum, process.ClaudeSessionID, process.Checkpoint)
+					return
 				}
-
-				// Prepare environment context for storage
-				envVars := make(map[string]string)
-				if process.Cmd != nil && process.Cmd.Env != nil {
-					for _, env := range process.Cmd.Env {
-						if strings.Contains(env, "=") {
-							parts := strings.SplitN(env, "=", 2)
-							envVars[parts[0]] = parts[1]
-						}
-					}
+				// A cancelled session was stopped on purpose, so it is not reported as a failure
+				if process.Failure == claude.FailureCancelled {
+					d.recordEvent(process.IssueNum, session.EventFailed, process.ClaudeSessionID, string(process.Failure))
+					d.holdIssue(process.IssueNum, string(claude.FailureCancelled))
+					return
 				}
-
-				if err := d.sessionStore.AddCompletedSession(
-					process.IssueNum,
-					sessionID,
-					d.selectedProject,
-					time.Now(),
-					process.WorkingDir,
-					d.config.Claude.Command,
-					d.config.Claude.Flags,
-					envVars,
-				); err != nil {
-					fmt.Printf("[%s] Warning: failed to store session info for issue #%d: %v\n", timestamp, process.IssueNum, err)
-				} else {
-					fmt.Printf("[%s] Stored session %s for issue #%d (monitoring for new comments)\n", timestamp, sessionID, process.IssueNum)
+				d.recordEvent(process.IssueNum, session.EventFailed, process.ClaudeSessionID,
+					strings.TrimSpace(fmt.Sprintf("%s %s", process.Failure, process.LastError)))
+				d.reportSessionFailure(fmt.Sprintf("#%d", process.IssueNum), process.ClaudeSessionID, session.RunIssue,
+					process.Failure, process.LastError, process.Output())
+				d.saveFailureBundle(process.IssueNum, process.ClaudeSessionID, process.WorkingDir,
+					process.Failure, process.LastError, process.Output())
+
+				// Known failure modes get their own recovery path
+				if d.handleSessionFailure(process) {
+					return
 				}
-			} else {
-				fmt.Printf("[%s] Failed to complete issue #%d\n", timestamp, process.IssueNum)
 
 				// Get current issue to get current labels
 				issue, err := d.gitlabClient.GetIssue(d.selectedProject, process.IssueNum)
 				if err != nil {
-					fmt.Printf("[%s] Warning: failed to get issue #%d for label update: %v\n", timestamp, process.IssueNum, err)
+					logging.Issue(process.IssueNum).Warnf("Failed to get issue #%d for label update: %v", process.IssueNum, err)
 					return
 				}
 
@@ -360,10 +708,10 @@ func (d *Daemon) processIssueAsync(issueNumber int) error {
 				newLabels = append(newLabels, "error")
 
 				// Update labels
-				if err := d.gitlabClient.UpdateIssueLabels(d.selectedProject, process.IssueNum, newLabels); err != nil {
-					fmt.Printf("[%s] Warning: failed to update error labels for issue #%d: %v\n", timestamp, process.IssueNum, err)
+				if err := d.setIssueLabels(process.IssueNum, newLabels); err != nil {
+					logging.Issue(process.IssueNum).Warnf("Failed to update error labels for issue #%d: %v", process.IssueNum, err)
 				} else {
-					fmt.Printf("[%s] Updated labels for issue #%d to 'error'\n", timestamp, process.IssueNum)
+					logging.Issue(process.IssueNum).Infof("Updated labels for issue #%d to 'error'", process.IssueNum)
 				}
 			}
 		}() // End of async goroutine
@@ -371,21 +719,42 @@ func (d *Daemon) processIssueAsync(issueNumber int) error {
 		return nil // Return immediately from callback
 	}
 
+	// Related past sessions and relevant docs point the session at the right code
 	process, err := claude.CreateProcessWithCallbackAndGitlabDryRun(
 		issueNumber,
 		processID,
 		d.config.Claude.Command,
-		d.config.Claude.Flags,
+		claudeFlags,
 		d.selectedProject,
 		d.config.GitLab.Username,
 		d.config.GitLab.URL,
 		d.dryRun,
 		completionLabels,
 		onCompletion,
+		d.issuePrompt(issueNumber),
 	)
 	if err != nil {
 		return fmt.Errorf("error creating claude process: %v", err)
 	}
+	process.FallbackModel = d.config.Claude.FallbackModel
+	process.StallTimeout = time.Duration(d.config.Daemon.StallTimeout) * time.Minute
+	process.MaxTurns = d.config.Daemon.MaxTurns
+	process.Timeout = time.Duration(d.config.Daemon.SessionTimeout) * time.Minute
+	process.OnTimeout = d.noteSessionTimeout
+	process.Environment = environment
+	process.Transcript = d.startTranscript(issueNumber, "", session.RunIssue)
+	if d.config.Environment.Warmup {
+		process.WarmUp = time.Duration(d.config.Environment.WarmupTimeout) * time.Minute
+	}
+	// A dev container has its own filesystem, so only host sessions share caches
+	if d.config.Environment.Warmup && (environment == nil || environment.Kind != claude.EnvironmentDevcontainer) {
+		process.Cmd.Env = append(process.Cmd.Env, claude.SharedCacheEnv(filepath.Join(d.config.Data.Dir, "cache"))...)
+	}
+	if d.config.Approval.Enabled && !d.dryRun && !d.semiDryRun {
+		if err := d.requireApproval(process); err != nil {
+			return err
+		}
+	}
 
 	if d.dryRun || d.semiDryRun {
 		if d.dryRun {
@@ -443,24 +812,90 @@ func (d *Daemon) processIssueAsync(issueNumber int) error {
 			fmt.Printf("- Pull latest changes\n")
 			fmt.Printf("Repository will be ready for the next parallel session\n")
 		} else {
-			fmt.Println("=== END DRY RUN ===\n")
-			fmt.Printf("[DRY RUN] Would update labels: remove '%s', add '%s' on completion\n", d.config.Daemon.ProcessLabel, d.config.Daemon.ReviewLabel)
+			fmt.Print("=== END DRY RUN ===\n\n")
+			logging.Infof("[DRY RUN] Would update labels: remove '%s', add '%s' on completion", d.config.Daemon.ProcessLabel, d.config.Daemon.ReviewLabel)
 		}
 	} else {
 		d.processManager.AddProcess(process)
 
-		// Run the process asynchronously
+		// Run the process asynchronously; a panic fails the issue instead of the daemon
+		process.OnPanic = func(process *claude.Process, recovered interface{}) {
+			d.handlePanic("session of issue", process.IssueNum, recovered)
+		}
+		stopProgress = d.startProgress(process)
 		claude.RunProcessAsync(process, d.processManager)
+		d.sessionStarted(issueNumber, 0, session.RunIssue, "")
 	}
 
 	return nil
 }
 
-func (d *Daemon) resumeSessionWithComments(session *session.CompletedSession, newComments []gitlab.Note) error {
-	return d.resumeSessionWithCommentsWithContext(context.Background(), session, newComments)
+// storeSession records a finished issue session so comments on the issue can
+// resume it, and returns its session ID. completionNoteID is the comment
+// feedback is read after, 0 for none.
+func (d *Daemon) storeSession(process *claude.Process, forkPath, branch, previousSessionID string, completionNoteID int, timestamp string) string {
+	sessionID := process.ClaudeSessionID
+	if sessionID == "" {
+		logging.Issue(process.IssueNum).Warnf("Claude session ID not captured for issue #%d, using fallback ID %s", process.IssueNum, process.ID)
+		sessionID = process.ID // Fallback to internal ID
+	}
+
+	// Prepare environment context for storage
+	envVars := make(map[string]string)
+	if process.Cmd != nil && process.Cmd.Env != nil {
+		for _, env := range process.Cmd.Env {
+			if strings.Contains(env, "=") {
+				parts := strings.SplitN(env, "=", 2)
+				envVars[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	if err := d.sessionStore.AddCompletedSession(
+		process.IssueNum,
+		sessionID,
+		d.selectedProject,
+		time.Now(),
+		process.WorkingDir,
+		d.config.Claude.Command,
+		d.config.Claude.Flags,
+		envVars,
+	); err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to store session info for issue #%d: %v", process.IssueNum, err)
+	} else {
+		logging.Issue(process.IssueNum).Infof("Stored session %s for issue #%d (monitoring for new comments)", sessionID, process.IssueNum)
+		if forkPath != "" {
+			if err := d.sessionStore.UpdateForkPath(process.IssueNum, forkPath); err != nil {
+				logging.Issue(process.IssueNum).Warnf("Failed to record fork for issue #%d: %v", process.IssueNum, err)
+			}
+		}
+		if branch != issueBranch(process.IssueNum) || previousSessionID != "" {
+			if err := d.sessionStore.UpdateBranch(process.IssueNum, branch, previousSessionID); err != nil {
+				logging.Issue(process.IssueNum).Warnf("Failed to record branch for issue #%d: %v", process.IssueNum, err)
+			}
+		}
+		// Feedback is whatever comes after the completion comment
+		if completionNoteID > 0 {
+			if err := d.sessionStore.UpdateLastNote(process.IssueNum, completionNoteID, time.Time{}); err != nil {
+				logging.Issue(process.IssueNum).Warnf("Failed to record completion comment for issue #%d: %v", process.IssueNum, err)
+			}
+		}
+	}
+	return sessionID
+}
+
+func (d *Daemon) resumeSessionWithComments(session *session.CompletedSession, newComments []gitlab.Note, threads []reviewThread) error {
+	return d.resumeSessionWithCommentsWithContext(context.Background(), session, newComments, threads)
 }
 
-func (d *Daemon) resumeSessionWithCommentsWithContext(ctx context.Context, session *session.CompletedSession, newComments []gitlab.Note) error {
+func (d *Daemon) resumeSessionWithCommentsWithContext(ctx context.Context, session *session.CompletedSession, newComments []gitlab.Note, threads []reviewThread) error {
+	return d.resumeSession(ctx, session, newComments, threads, false)
+}
+
+// resumeSession resumes a stored session with new feedback. A trimmed resume
+// follows a context overflow: comments are summarized, CI logs are left out,
+// the conversation is compacted first and the overflow flags are applied.
+func (d *Daemon) resumeSession(ctx context.Context, session *session.CompletedSession, newComments []gitlab.Note, threads []reviewThread, trimmed bool) error {
 	// Check if context is already cancelled
 	select {
 	case <-ctx.Done():
@@ -468,41 +903,42 @@ func (d *Daemon) resumeSessionWithCommentsWithContext(ctx context.Context, sessi
 	default:
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-
-	// Build comment context
-	commentContext := fmt.Sprintf("# New Comments on Issue #%d\n\n", session.IssueIID)
-	commentContext += "The following comments were added after you completed this issue:\n\n"
+	data := d.resumePromptData(session, newComments, threads, trimmed)
+	description, descriptionChange := d.descriptionChange(session)
+	data.Description = descriptionChange
+	if !data.Trimmed && data.Comments == "" && data.ReviewThreads == "" && data.PipelineFailure == "" && data.CILint == "" && data.Paused == "" && data.Description == "" {
+		// Nothing to act on, e.g. a pipeline failure that has since been fixed
+		return nil
+	}
 
-	for i, comment := range newComments {
-		// Check for cancellation during comment processing
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	// A session resumed on a stale branch would build on old code
+	if d.config.AutoRebase.Enabled && !d.dryRun && !d.semiDryRun {
+		data.Upstream = d.refreshBranch(session)
+	}
 
-		commentContext += fmt.Sprintf("## Comment %d by @%s\n", i+1, comment.Author.Username)
-		commentContext += fmt.Sprintf("**Posted:** %s\n\n", comment.CreatedAt)
-		commentContext += fmt.Sprintf("%s\n\n", comment.Body)
-		commentContext += "---\n\n"
+	// Check for cancellation before rendering
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
 	}
 
-	commentContext += "Please review these comments and take any necessary follow-up actions. "
-	commentContext += "You can update your previous work, answer questions, or make additional changes as needed."
+	commentContext, err := prompts.Render(prompts.WorkflowResume, data)
+	if err != nil {
+		return err
+	}
 
 	// Validate session ID format
 	if !isValidUUID(session.SessionID) {
-		fmt.Printf("[%s] Skipping resume for issue #%d: session ID '%s' is not a valid UUID (likely from old format)\n",
-			timestamp, session.IssueIID, session.SessionID)
+		logging.Issue(session.IssueIID).Infof("Skipping resume for issue #%d: session ID '%s' is not a valid UUID (likely from old format)", session.IssueIID, session.SessionID)
 		return nil
 	}
 
 	if d.dryRun {
-		fmt.Printf("[%s] [DRY RUN] Would resume session %s with comment context:\n%s\n", timestamp, session.SessionID, commentContext)
+		logging.Issue(session.IssueIID).Infof("[DRY RUN] Would resume session %s with comment context:\n%s", session.SessionID, commentContext)
 		return nil
 	} else if d.semiDryRun {
-		fmt.Printf("[%s] [SEMI-DRY RUN] Would resume session %s with comment context:\n%s\n", timestamp, session.SessionID, commentContext)
+		logging.Issue(session.IssueIID).Infof("[SEMI-DRY RUN] Would resume session %s with comment context:\n%s", session.SessionID, commentContext)
 		return nil
 	}
 
@@ -535,14 +971,35 @@ func (d *Daemon) resumeSessionWithCommentsWithContext(ctx context.Context, sessi
 		workingDir = detectedDir
 	}
 
+	if trimmed {
+		claudeFlags = d.overflowFlags(claudeFlags)
+	}
+
+	// A recreated checkout has lost the remote the session pushes to
+	if session.ForkPath != "" {
+		if err := claude.SetForkRemote(workingDir, d.config.GitLab.URL, session.ForkPath); err != nil {
+			logging.Issue(session.IssueIID).Warnf("Failed to restore fork remote for issue #%d: %v", session.IssueIID, err)
+		}
+	}
+
 	// Build command arguments using the stored flags
 	if claudeFlags != "" {
 		args = strings.Fields(claudeFlags)
 	}
+	// Approved actions are no longer held, so the policy is rebuilt on every resume
+	var approvalEnv []string
+	if d.config.Approval.Enabled {
+		approvalArgs, env, err := d.approvalCommand(session.IssueIID, workingDir)
+		if err != nil {
+			return err
+		}
+		args = append(args, approvalArgs...)
+		approvalEnv = env
+	}
 	args = append(args, "-r", session.SessionID, "-p", commentContext)
 
-	fmt.Printf("[%s] Resuming Claude session %s for issue #%d with new comments\n", timestamp, session.SessionID, session.IssueIID)
-	fmt.Printf("[%s] Using stored environment: command=%s, working_dir=%s\n", timestamp, claudeCommand, workingDir)
+	logging.Issue(session.IssueIID).Infof("Resuming Claude session %s for issue #%d with new comments", session.SessionID, session.IssueIID)
+	logging.Infof("Using stored environment: command=%s, working_dir=%s", claudeCommand, workingDir)
 
 	// Create a context-aware command execution using the stored command and environment
 	cmd := exec.CommandContext(ctx, claudeCommand, args...)
@@ -556,88 +1013,190 @@ func (d *Daemon) resumeSessionWithCommentsWithContext(ctx context.Context, sessi
 			envSlice = append(envSlice, fmt.Sprintf("%s=%s", key, value))
 		}
 		cmd.Env = envSlice
-		fmt.Printf("[%s] Using %d stored environment variables\n", timestamp, len(session.EnvVars))
+		logging.Infof("Using %d stored environment variables", len(session.EnvVars))
 	} else {
 		// Fallback for backward compatibility
 		cmd.Env = os.Environ()
-		fmt.Printf("[%s] Using current environment (no stored env vars)\n", timestamp)
+		logging.Infof("Using current environment (no stored env vars)")
+	}
+	cmd.Env = append(cmd.Env, approvalEnv...)
+
+	if trimmed {
+		logging.Infof("Compacting session %s before the trimmed resume", session.SessionID)
+		if err := d.compactSession(ctx, claudeCommand, claudeFlags, workingDir, cmd.Env, session.SessionID); err != nil {
+			logging.Warnf("%v", err)
+		}
 	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if environment, err := d.sessionEnvironment(session.ProjectPath, workingDir); err != nil {
+		logging.Issue(session.IssueIID).Warnf("Resuming issue #%d on the host: %v", session.IssueIID, err)
+	} else if environment != nil {
+		cmd = environment.Wrap(ctx, cmd)
+	}
+
+	// Keep the end of the output to classify failures and for the failure bundle
+	outputTail := claude.NewOutputTail(4096)
+	transcript := claude.NewLineTail(claude.BundleLines)
+	transcriptFile := d.openTranscript(session.IssueIID, session.SessionID, runResume)
+	cmd.Stdout = io.MultiWriter(os.Stdout, outputTail, transcript, transcriptFile)
+	cmd.Stderr = io.MultiWriter(os.Stderr, outputTail, transcript)
 
 	// Start the resume command asynchronously
+	startedAt := time.Now()
 	if err := cmd.Start(); err != nil {
+		transcriptFile.Close()
 		return fmt.Errorf("failed to start resume session: %v", err)
 	}
 
 	// Track this process for graceful shutdown
 	d.resumeProcesses[session.IssueIID] = cmd
 
-	fmt.Printf("[%s] Started resume session for issue #%d (PID: %d)\n", timestamp, session.IssueIID, cmd.Process.Pid)
+	logging.Issue(session.IssueIID).Infof("Started resume session for issue #%d (PID: %d)", session.IssueIID, cmd.Process.Pid)
+	d.sessionStarted(session.IssueIID, 0, runResume, session.SessionID)
+	d.recordEvent(session.IssueIID, eventResumed, session.SessionID,
+		fmt.Sprintf("%d comments, %d review threads", len(newComments), len(threads)))
+	if descriptionChange != "" {
+		d.recordDescription(session.IssueIID, description)
+		d.recordEvent(session.IssueIID, eventDescriptionSync, session.SessionID, "")
+	}
 
 	// Don't wait for completion - let it run in background
 	// The process will complete on its own and respect context cancellation
 	go func() {
+		defer d.recoverPanic("resume of issue", session.IssueIID)
 		err := cmd.Wait()
+		transcriptFile.Close()
 
 		// Remove from tracking when completed
 		delete(d.resumeProcesses, session.IssueIID)
 
+		var failure claude.FailureKind
+		if err != nil {
+			failure = d.cancelledFailure(session.IssueIID, claude.ClassifyFailure(claude.ExitCode(err), outputTail.String()))
+		}
+
+		if ctx.Err() == nil {
+			usage := claude.TranscriptUsage(transcript.Lines())
+			run := sessionRun{
+				IssueIID:    session.IssueIID,
+				ProjectPath: session.ProjectPath,
+				SessionID:   session.SessionID,
+				Kind:        runResume,
+				Outcome:     runOutcome(err == nil),
+				Model:       usage.Model,
+				StartedAt:   startedAt,
+				Duration:    time.Since(startedAt),
+				CostUSD:     usage.CostUSD,
+				Turns:       usage.Turns,
+
+				InputTokens:         usage.InputTokens,
+				OutputTokens:        usage.OutputTokens,
+				CacheReadTokens:     usage.CacheReadTokens,
+				CacheCreationTokens: usage.CacheCreationTokens,
+			}
+			run.Failure = string(failure)
+			d.recordRun(run)
+		}
+
+		// The session stopped at an action that needs approval
+		if action, held := claude.TakeCheckpoint(workingDir); held && ctx.Err() == nil {
+			d.requestApproval(session.IssueIID, session.SessionID, action)
+			return
+		}
+
 		if err != nil {
 			// Check if it was cancelled due to context
 			if ctx.Err() != nil {
-				fmt.Printf("[%s] Resume session for issue #%d cancelled\n",
-					time.Now().Format("2006-01-02 15:04:05"), session.IssueIID)
+				logging.Issue(session.IssueIID).Infof("Resume session for issue #%d cancelled", session.IssueIID)
+			} else if failure == claude.FailureCancelled {
+				logging.Issue(session.IssueIID).Infof("Resume session for issue #%d cancelled on request", session.IssueIID)
+				d.recordEvent(session.IssueIID, eventResumeFailed, session.SessionID, string(failure))
 			} else {
 				errorMsg := err.Error()
-				fmt.Printf("[%s] Resume session for issue #%d completed with error: %v\n",
-					time.Now().Format("2006-01-02 15:04:05"), session.IssueIID, err)
+				logging.Issue(session.IssueIID).Infof("Resume session for issue #%d completed with error: %v (%s)", session.IssueIID, err, failure)
+				d.recordEvent(session.IssueIID, eventResumeFailed, session.SessionID, fmt.Sprintf("%s: %s", failure, errorMsg))
+				d.reportSessionFailure(fmt.Sprintf("#%d", session.IssueIID), session.SessionID, runResume,
+					failure, errorMsg, transcript.Lines())
+				d.saveFailureBundle(session.IssueIID, session.SessionID, session.WorkingDir, failure, errorMsg, transcript.Lines())
+				d.handleResumeFailure(ctx, session, newComments, threads, failure)
 
 				// Check if the error indicates the session is no longer valid
 				if strings.Contains(errorMsg, "No conversation found") ||
 					strings.Contains(errorMsg, "session ID") ||
 					strings.Contains(errorMsg, "not found") {
-					fmt.Printf("[%s] Session %s appears to be invalid/expired, removing from database\n",
-						time.Now().Format("2006-01-02 15:04:05"), session.SessionID)
+					logging.Infof("Session %s appears to be invalid/expired, removing from database", session.SessionID)
 
 					// Remove the invalid session from the store
 					if removeErr := d.sessionStore.RemoveSession(session.IssueIID); removeErr != nil {
-						fmt.Printf("[%s] Warning: Failed to remove invalid session for issue #%d: %v\n",
-							time.Now().Format("2006-01-02 15:04:05"), session.IssueIID, removeErr)
+						logging.Issue(session.IssueIID).Warnf("Failed to remove invalid session for issue #%d: %v", session.IssueIID, removeErr)
 					} else {
-						fmt.Printf("[%s] Removed invalid session for issue #%d from database\n",
-							time.Now().Format("2006-01-02 15:04:05"), session.IssueIID)
+						logging.Issue(session.IssueIID).Infof("Removed invalid session for issue #%d from database", session.IssueIID)
 					}
 				}
 			}
 		} else {
-			fmt.Printf("[%s] Resume session for issue #%d completed successfully\n",
-				time.Now().Format("2006-01-02 15:04:05"), session.IssueIID)
+			logging.Issue(session.IssueIID).Infof("Resume session for issue #%d completed successfully", session.IssueIID)
+			d.recordEvent(session.IssueIID, eventResumeCompleted, session.SessionID, "")
+			d.retries.reset(session.IssueIID)
+			d.recordLink(session.IssueIID, session.ProjectPath, session.ForkPath, sessionBranch(session))
+
+			// The feedback has been addressed, so close out the review threads
+			d.resolveReviewThreads(session.ProjectPath, threads)
 		}
 	}()
 
 	return nil
 }
 
-func (d *Daemon) checkForNewClaudeIssues(processedIssues map[int]bool, timestamp string) (int, error) {
+// intakeIssueOptions builds the issue filter used to find new work
+func (d *Daemon) intakeIssueOptions() gitlab.IssueListOptions {
+	return gitlab.IssueListOptions{
+		Labels:    []string{d.config.Daemon.ClaudeLabel},
+		NotLabels: d.config.Daemon.ExcludeLabels,
+		State:     "opened",
+	}
+}
+
+func (d *Daemon) checkForNewClaudeIssues(processedIssues *processedSet, timestamp string) (int, error) {
+	if d.queueRole() == queue.RoleWorker {
+		return d.claimQueuedIssues(context.Background(), timestamp)
+	}
+
+	held := d.releaseBackfill(timestamp)
+
 	// Fetch issues with the claude label (new work)
-	issues, err := d.gitlabClient.GetProjectIssues(d.selectedProject, []string{d.config.Daemon.ClaudeLabel}, "opened")
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, d.intakeIssueOptions())
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch claude issues: %v", err)
 	}
+	processedIssues.retain(issues)
+	issues = withoutBackfillHeld(issues, held)
+	sortByPriority(issues, d.config.Daemon.PriorityAging, time.Now())
 
 	newIssues := 0
 	for _, issue := range issues {
-		if !processedIssues[issue.IID] {
-			processedIssues[issue.IID] = true
+		if !processedIssues.has(issue.IID) {
+			processedIssues.add(issue.IID)
 			newIssues++
 
-			fmt.Printf("[%s] Processing issue #%d: %s\n", timestamp, issue.IID, issue.Title)
+			logging.Issue(issue.IID).Infof("Processing issue #%d: %s", issue.IID, issue.Title)
 
 			// Process issue asynchronously with automagic label updates
 			if err := d.processIssueWithLabelUpdate(&issue); err != nil {
-				fmt.Printf("[%s] Failed to start issue #%d: %v\n", timestamp, issue.IID, err)
+				if errors.Is(err, errOutsideWorkingHours) {
+					// Retried once the working hours open
+					processedIssues.remove(issue.IID)
+					newIssues--
+					continue
+				}
+				if errors.Is(err, errTierAtCapacity) {
+					// Leave unprocessed so the next cycle retries it
+					processedIssues.remove(issue.IID)
+					newIssues--
+					logging.Issue(issue.IID).Infof("Deferring issue #%d: tier %s at capacity", issue.IID, issueTier(&issue))
+					continue
+				}
+				logging.Issue(issue.IID).Errorf("Failed to start issue #%d: %v", issue.IID, err)
 			}
 		}
 	}
@@ -645,7 +1204,7 @@ func (d *Daemon) checkForNewClaudeIssues(processedIssues map[int]bool, timestamp
 	return newIssues, nil
 }
 
-func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, processedIssues map[int]bool, timestamp string) (int, error) {
+func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, processedIssues *processedSet, timestamp string) (int, error) {
 	// Check if context is already cancelled
 	select {
 	case <-ctx.Done():
@@ -653,8 +1212,18 @@ func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, process
 	default:
 	}
 
+	// Workers take new issues from the coordinator's queue, not from GitLab
+	switch d.queueRole() {
+	case queue.RoleWorker:
+		return d.claimQueuedIssues(ctx, timestamp)
+	case queue.RoleCoordinator:
+		d.collectResults(ctx, timestamp)
+	}
+
+	held := d.releaseBackfill(timestamp)
+
 	// Fetch issues with the claude label (new work) with timeout
-	fmt.Printf("[%s] DEBUG: Fetching issues with label '%s' from project '%s'...\n", timestamp, d.config.Daemon.ClaudeLabel, d.selectedProject)
+	logging.Debugf("Fetching issues with label '%s' from project '%s'...", d.config.Daemon.ClaudeLabel, d.selectedProject)
 
 	// Create a timeout context for the API call
 	apiCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -668,7 +1237,7 @@ func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, process
 
 	resultCh := make(chan result, 1)
 	go func() {
-		issues, err := d.gitlabClient.GetProjectIssues(d.selectedProject, []string{d.config.Daemon.ClaudeLabel}, "opened")
+		issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, d.intakeIssueOptions())
 		resultCh <- result{issues: issues, err: err}
 	}()
 
@@ -677,7 +1246,7 @@ func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, process
 	var err error
 	select {
 	case <-apiCtx.Done():
-		fmt.Printf("[%s] DEBUG: API call timed out or was cancelled\n", timestamp)
+		logging.Debugf("API call timed out or was cancelled")
 		return 0, apiCtx.Err()
 	case res := <-resultCh:
 		issues = res.issues
@@ -685,10 +1254,13 @@ func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, process
 	}
 
 	if err != nil {
-		fmt.Printf("[%s] DEBUG: Failed to fetch claude issues: %v\n", timestamp, err)
+		logging.Debugf("Failed to fetch claude issues: %v", err)
 		return 0, fmt.Errorf("failed to fetch claude issues: %v", err)
 	}
-	fmt.Printf("[%s] DEBUG: Successfully fetched %d issues with claude label\n", timestamp, len(issues))
+	logging.Debugf("Successfully fetched %d issues with claude label", len(issues))
+	processedIssues.retain(issues)
+	issues = withoutBackfillHeld(issues, held)
+	sortByPriority(issues, d.config.Daemon.PriorityAging, time.Now())
 
 	newIssues := 0
 	for _, issue := range issues {
@@ -699,20 +1271,34 @@ func (d *Daemon) checkForNewClaudeIssuesWithContext(ctx context.Context, process
 		default:
 		}
 
-		if !processedIssues[issue.IID] {
-			processedIssues[issue.IID] = true
+		if !processedIssues.has(issue.IID) {
+			processedIssues.add(issue.IID)
 			newIssues++
 
-			fmt.Printf("[%s] Found new issue #%d: %s\n", timestamp, issue.IID, issue.Title)
+			logging.Issue(issue.IID).Infof("Found new issue #%d: %s", issue.IID, issue.Title)
 
 			// Process issue asynchronously with automagic label updates
 			if err := d.processIssueWithLabelUpdate(&issue); err != nil {
-				fmt.Printf("[%s] Failed to start processing issue #%d: %v\n", timestamp, issue.IID, err)
+				if errors.Is(err, errOutsideWorkingHours) {
+					// Retried once the working hours open
+					processedIssues.remove(issue.IID)
+					newIssues--
+					continue
+				}
+				if errors.Is(err, errTierAtCapacity) {
+					// Leave unprocessed so the next cycle retries it
+					processedIssues.remove(issue.IID)
+					newIssues--
+					logging.Issue(issue.IID).Infof("Deferring issue #%d: tier %s at capacity", issue.IID, issueTier(&issue))
+					continue
+				}
+				logging.Issue(issue.IID).Errorf("Failed to start processing issue #%d: %v", issue.IID, err)
 			} else {
-				fmt.Printf("[%s] Started new Claude session for issue #%d\n", timestamp, issue.IID)
+				logging.Issue(issue.IID).Infof("Started new Claude session for issue #%d", issue.IID)
 			}
 		}
 	}
+	newIssues += d.startEnqueuedIssues(ctx, processedIssues)
 
 	return newIssues, nil
 }
@@ -726,7 +1312,7 @@ func (d *Daemon) checkForMergeRequestsWithContext(ctx context.Context, processed
 	}
 
 	// Get current user to use their ID for fetching MRs
-	currentUser, userErr := d.gitlabClient.GetCurrentUser()
+	currentUser, userErr := d.gitlabClient.Users().Current()
 	if userErr != nil {
 		return 0, fmt.Errorf("failed to get current user: %v", userErr)
 	}
@@ -772,68 +1358,139 @@ func (d *Daemon) checkForMergeRequestsWithContext(ctx context.Context, processed
 		return 0, fmt.Errorf("failed to fetch merge requests: %v", err)
 	}
 
-	newMRs := 0
 	for _, mr := range mergeRequests {
 		// Check for cancellation between MRs
 		select {
 		case <-ctx.Done():
-			return newMRs, ctx.Err()
+			return 0, ctx.Err()
 		default:
 		}
 
+		// Only announce each MR once; re-reviews are driven by head SHA changes
 		if !processedMRs[mr.IID] {
 			processedMRs[mr.IID] = true
+			logging.Infof("Found merge request: !%d - %s", mr.IID, mr.Title)
+		}
 
-			fmt.Printf("[%s] Found merge request: !%d - %s\n", timestamp, mr.IID, mr.Title)
+		// Skip if MR is already merged or closed
+		if mr.State == "merged" || mr.State == "closed" {
+			continue
+		}
 
-			// Skip if MR is already merged or closed
-			if mr.State == "merged" || mr.State == "closed" {
-				fmt.Printf("[%s] MR !%d is %s, skipping\n", timestamp, mr.IID, mr.State)
-				continue
+		// Check current labels
+		hasProcessLabel := false
+		hasReviewLabel := false
+		for _, label := range mr.Labels {
+			if label == d.config.Daemon.ProcessLabel {
+				hasProcessLabel = true
 			}
-
-			// Check current labels
-			hasProcessLabel := false
-			hasReviewLabel := false
-			for _, label := range mr.Labels {
-				if label == d.config.Daemon.ProcessLabel {
-					hasProcessLabel = true
-				}
-				if label == d.config.Daemon.ReviewLabel {
-					hasReviewLabel = true
-				}
+			if label == d.config.Daemon.ReviewLabel {
+				hasReviewLabel = true
 			}
+		}
+
+		// A process label we don't own belongs to another run; leave it alone
+		if hasProcessLabel && !d.reviews.isRunning(&mr) {
+			continue
+		}
 
-			if hasProcessLabel {
-				fmt.Printf("[%s] MR !%d already being processed, skipping\n", timestamp, mr.IID)
+		if _, known := d.reviews.lastReviewed(&mr); !known && hasReviewLabel {
+			// No SHA recorded (e.g. after a restart): fall back to comment timestamps
+			shouldReprocess, err := d.checkForNewCommitsInMR(ctx, &mr, timestamp)
+			if err != nil {
+				logging.MergeRequest(mr.IID).Errorf("Error checking commits for MR !%d: %v", mr.IID, err)
 				continue
 			}
-
-			if hasReviewLabel {
-				// Check if there are new commits since last review
-				shouldReprocess, err := d.checkForNewCommitsInMR(ctx, &mr, timestamp)
-				if err != nil {
-					fmt.Printf("[%s] Error checking commits for MR !%d: %v\n", timestamp, mr.IID, err)
-					continue
-				}
-				if !shouldReprocess {
-					// No new commits, skip
-					continue
-				}
-				fmt.Printf("[%s] MR !%d has new commits since last review, re-processing\n", timestamp, mr.IID)
+			if !shouldReprocess {
+				// Current head was already reviewed, remember it to skip this check next time
+				d.reviews.markReviewed(&mr, mr.SHA)
+				continue
 			}
+			logging.MergeRequest(mr.IID).Infof("MR !%d has new commits since last review, re-processing", mr.IID)
+		}
 
-			newMRs++
-			// Process merge request with Claude
-			if err := d.processMergeRequestWithClaude(ctx, &mr); err != nil {
-				fmt.Printf("[%s] Failed to start review for MR !%d: %v\n", timestamp, mr.IID, err)
-			}
+		if d.reviews.enqueue(mr) {
+			logging.MergeRequest(mr.IID).Infof("Queued review of MR !%d at %s", mr.IID, shortSHA(mr.SHA))
+		}
+	}
+
+	// Start queued reviews; superseded SHAs were already dropped by the queue
+	newMRs := 0
+	ready := d.reviews.next()
+	for i := range ready {
+		mr := &ready[i]
+		if err := d.processMergeRequestWithClaude(ctx, mr); err != nil {
+			d.reviews.finish(mr, false)
+			logging.MergeRequest(mr.IID).Errorf("Failed to start review for MR !%d: %v", mr.IID, err)
+			continue
 		}
+		newMRs++
 	}
 
 	return newMRs, nil
 }
 
+// maxIncrementalDiffChars caps how much diff text is embedded in an incremental review prompt
+const maxIncrementalDiffChars = 20000
+
+// incrementalReviewContext builds a prompt section covering only the commits
+// pushed since the last reviewed SHA. Returns "" when a full review is needed.
+func (d *Daemon) incrementalReviewContext(mr *gitlab.MergeRequest) string {
+	previousSHA, known := d.reviews.lastReviewed(mr)
+	if !known || mr.SHA == "" || previousSHA == mr.SHA {
+		return ""
+	}
+
+	compare, err := d.gitlabClient.CompareCommits(mr.ProjectID, previousSHA, mr.SHA)
+	if err != nil {
+		// The old SHA may be gone after a force-push; fall back to a full review
+		logging.MergeRequest(mr.IID).Warnf("Failed to compare MR !%d since %s, doing a full review: %v", mr.IID, shortSHA(previousSHA), err)
+		return ""
+	}
+	if len(compare.Commits) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## Incremental Review\n\n")
+	fmt.Fprintf(&b, "You already reviewed this merge request at commit `%s`. ", shortSHA(previousSHA))
+	b.WriteString("**Review only the new commits listed below.** Do not repeat feedback on code you already reviewed, ")
+	b.WriteString("and say whether earlier feedback was addressed where the new commits touch it.\n\n")
+
+	b.WriteString("### New Commits\n")
+	for _, commit := range compare.Commits {
+		fmt.Fprintf(&b, "- `%s` %s (%s)\n", commit.ShortID, commit.Title, commit.AuthorName)
+	}
+
+	b.WriteString("\n### Changes Since Last Review\n")
+	used := 0
+	var omitted []string
+	for _, diff := range compare.Diffs {
+		if used+len(diff.Diff) > maxIncrementalDiffChars {
+			omitted = append(omitted, diff.NewPath)
+			continue
+		}
+		used += len(diff.Diff)
+		fmt.Fprintf(&b, "\n#### %s\n```diff\n%s```\n", diff.NewPath, diff.Diff)
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\nDiffs omitted for size (fetch them with GitLab MCP tools if needed): %s\n", strings.Join(omitted, ", "))
+	}
+
+	return b.String()
+}
+
+// shortSHA abbreviates a commit SHA for log output
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	if sha == "" {
+		return "unknown"
+	}
+	return sha
+}
+
 // checkForNewCommitsInMR checks if there are new commits since the last Claude review
 func (d *Daemon) checkForNewCommitsInMR(ctx context.Context, mr *gitlab.MergeRequest, timestamp string) (bool, error) {
 	// Get project info to get project path
@@ -853,7 +1510,7 @@ func (d *Daemon) checkForNewCommitsInMR(ctx context.Context, mr *gitlab.MergeReq
 	botUsername := "" // We'll get this dynamically
 	
 	// Get current user to identify bot comments
-	currentUser, err := d.gitlabClient.GetCurrentUser()
+	currentUser, err := d.gitlabClient.Users().Current()
 	if err == nil {
 		botUsername = currentUser.Username
 	}
@@ -885,13 +1542,19 @@ func (d *Daemon) checkForNewCommitsInMR(ctx context.Context, mr *gitlab.MergeReq
 }
 
 func (d *Daemon) processMergeRequestWithClaude(ctx context.Context, mr *gitlab.MergeRequest) error {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	return d.reviewMergeRequest(ctx, mr, false)
+}
 
+// reviewMergeRequest runs a Claude review of the MR. A trimmed review follows
+// a context overflow: no diffs are embedded and the overflow flags are applied.
+func (d *Daemon) reviewMergeRequest(ctx context.Context, mr *gitlab.MergeRequest, trimmed bool) error {
 	if d.dryRun {
-		fmt.Printf("[%s] [DRY RUN] Would review MR !%d\n", timestamp, mr.IID)
+		logging.MergeRequest(mr.IID).Infof("[DRY RUN] Would review MR !%d at %s", mr.IID, shortSHA(mr.SHA))
+		d.reviews.finish(mr, true)
 		return nil
 	} else if d.semiDryRun {
-		fmt.Printf("[%s] [SEMI-DRY RUN] Would review MR !%d\n", timestamp, mr.IID)
+		logging.MergeRequest(mr.IID).Infof("[SEMI-DRY RUN] Would review MR !%d at %s", mr.IID, shortSHA(mr.SHA))
+		d.reviews.finish(mr, true)
 		return nil
 	}
 
@@ -907,7 +1570,7 @@ func (d *Daemon) processMergeRequestWithClaude(ctx context.Context, mr *gitlab.M
 	newLabels = append(newLabels, d.config.Daemon.ProcessLabel)
 
 	// Update MR labels using project ID directly
-	if err := d.gitlabClient.UpdateMergeRequestLabels(mr.ProjectID, mr.IID, newLabels); err != nil {
+	if err := d.setMergeRequestLabels(mr, newLabels); err != nil {
 		return fmt.Errorf("failed to update MR labels: %v", err)
 	}
 
@@ -917,76 +1580,38 @@ func (d *Daemon) processMergeRequestWithClaude(ctx context.Context, mr *gitlab.M
 		return fmt.Errorf("failed to get project info for MR !%d: %v", mr.IID, err)
 	}
 	projectPath := project.PathWithNamespace
-	fmt.Printf("[%s] Starting review of MR !%d\n", timestamp, mr.IID)
+	logging.MergeRequest(mr.IID).Infof("Starting review of MR !%d", mr.IID)
 
-	// Custom prompt for merge request review
-	prompt := fmt.Sprintf(`# Code Review for Merge Request !%d
-
-## Merge Request Information
-- **Project**: %s
-- **Title**: %s
-- **Source Branch**: %s → **Target Branch**: %s
-- **Author**: @%s
-- **URL**: %s
-
-## Review Instructions
-
-**DO NOT CLONE THE REPOSITORY.** Instead, use GitLab MCP tools to:
-
-1. **Get merge request details and diffs** using GitLab MCP tools
-   - Use mcp__MCP_GitLab__get_merge_request_diffs to see all changes
-   - Focus on the actual code changes in the diff output
-
-2. **Analyze the code changes** by examining:
-   - What files were modified/added/deleted
-   - The specific lines that changed
-   - Code patterns and logic flow
-   - Potential impacts on other parts of the system
-
-3. **Review for quality and security**:
-   - Code quality and best practices
-   - Security vulnerabilities or potential issues
-   - Performance implications
-   - Error handling and edge cases
-   - Code maintainability and readability
-
-4. **Check for completeness**:
-   - Are tests included for new functionality?
-   - Is documentation updated if needed?
-   - Are there any obvious missing pieces?
-
-5. **Review existing discussions** using GitLab MCP tools
-   - Check if there are existing comments or concerns
-   - See if previous feedback has been addressed
-
-6. **Provide comprehensive feedback**
-   - Post a detailed review comment using GitLab MCP tools
-   - Be constructive and specific
-   - Highlight both positive aspects and areas for improvement
-   - Suggest specific changes if needed
-
-## Review Focus Areas
-- **Security**: Look for SQL injection, XSS, authentication issues, etc.
-- **Performance**: Check for inefficient queries, loops, or operations
-- **Maintainability**: Code structure, naming, and clarity
-- **Correctness**: Logic errors, edge cases, error handling
-- **Standards**: Following project conventions and best practices
-
-**Remember**: You have access to GitLab MCP tools to fetch diffs, discussions, and post comments. Use these tools instead of trying to access the repository directly.
-`, mr.IID, projectPath, mr.Title, mr.SourceBranch, mr.TargetBranch, mr.Author.Username, mr.WebURL)
+	// Narrow the review to new commits when an earlier head was already reviewed
+	incremental := ""
+	if !trimmed {
+		if incremental = d.incrementalReviewContext(mr); incremental != "" {
+			logging.MergeRequest(mr.IID).Infof("MR !%d was reviewed before, requesting incremental review", mr.IID)
+		}
+	}
 
+	prompt, err := prompts.Render(prompts.WorkflowReview, reviewPromptData(mr, projectPath, incremental, trimmed))
+	if err != nil {
+		return err
+	}
 
 	// For MR reviews, we don't need to clone repos, just run Claude with the prompt
 	// Create a simple command that runs Claude directly with the review prompt
+	claudeFlags := d.config.Claude.Flags
+	if trimmed {
+		claudeFlags = d.overflowFlags(claudeFlags)
+	}
+
 	args := []string{}
-	if d.config.Claude.Flags != "" {
-		args = strings.Fields(d.config.Claude.Flags)
+	if claudeFlags != "" {
+		args = strings.Fields(claudeFlags)
 	}
 	args = append(args, "-p", prompt)
 
 	cmd := exec.CommandContext(ctx, d.config.Claude.Command, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	outputTail := claude.NewOutputTail(4096)
+	cmd.Stdout = io.MultiWriter(os.Stdout, outputTail)
+	cmd.Stderr = io.MultiWriter(os.Stderr, outputTail)
 
 	// Set environment variables for GitLab MCP integration
 	cmd.Env = append(os.Environ(),
@@ -999,11 +1624,23 @@ func (d *Daemon) processMergeRequestWithClaude(ctx context.Context, mr *gitlab.M
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start Claude MR review: %v", err)
 	}
+	d.sessionStarted(0, mr.IID, events.SessionReview, "")
 
 	// Don't wait for completion - let it run in background
 	go func() {
+		defer d.recoverPanic("review of merge request", 0)
 		err := cmd.Wait()
-		completionTime := time.Now().Format("2006-01-02 15:04:05")
+
+		// Retry once without embedded diffs when the review ran out of context
+		if err != nil && !trimmed && ctx.Err() == nil &&
+			claude.ClassifyFailure(claude.ExitCode(err), outputTail.String()) == claude.FailureContextOverflow {
+			logging.MergeRequest(mr.IID).Infof("MR !%d review overflowed the context window, retrying with a trimmed prompt", mr.IID)
+			retryErr := d.reviewMergeRequest(ctx, mr, true)
+			if retryErr == nil {
+				return
+			}
+			logging.MergeRequest(mr.IID).Errorf("Failed to retry MR !%d review: %v", mr.IID, retryErr)
+		}
 		
 		// Remove the process label when completed
 		finalLabels := make([]string, 0)
@@ -1014,23 +1651,29 @@ func (d *Daemon) processMergeRequestWithClaude(ctx context.Context, mr *gitlab.M
 		}
 		
 		if err != nil {
-			fmt.Printf("[%s] MR !%d review failed\n", completionTime, mr.IID)
+			logging.MergeRequest(mr.IID).Infof("MR !%d review failed", mr.IID)
+			if ctx.Err() == nil {
+				output := outputTail.String()
+				d.reportSessionFailure(fmt.Sprintf("!%d", mr.IID), "", "review",
+					claude.ClassifyFailure(claude.ExitCode(err), output), err.Error(), strings.Split(output, "\n"))
+			}
 			finalLabels = append(finalLabels, "error")
 		} else {
-			fmt.Printf("[%s] MR !%d review completed\n", completionTime, mr.IID)
+			logging.MergeRequest(mr.IID).Infof("MR !%d review completed at %s", mr.IID, shortSHA(mr.SHA))
 			finalLabels = append(finalLabels, d.config.Daemon.ReviewLabel)
 		}
+		d.reviews.finish(mr, err == nil)
 		
 		// Update labels to reflect completion
-		if labelErr := d.gitlabClient.UpdateMergeRequestLabels(mr.ProjectID, mr.IID, finalLabels); labelErr != nil {
-			fmt.Printf("[%s] Warning: failed to update labels for MR !%d: %v\n", completionTime, mr.IID, labelErr)
+		if labelErr := d.setMergeRequestLabels(mr, finalLabels); labelErr != nil {
+			logging.MergeRequest(mr.IID).Warnf("Failed to update labels for MR !%d: %v", mr.IID, labelErr)
 		}
 	}()
 
 	return nil
 }
 
-func (d *Daemon) checkForHumanReviewIssuesWithContext(ctx context.Context, processedIssues map[int]bool, timestamp string) (int, error) {
+func (d *Daemon) checkForHumanReviewIssuesWithContext(ctx context.Context, processedIssues *processedSet, timestamp string) (int, error) {
 	// Check if context is already cancelled
 	select {
 	case <-ctx.Done():
@@ -1039,49 +1682,23 @@ func (d *Daemon) checkForHumanReviewIssuesWithContext(ctx context.Context, proce
 	}
 
 	// Fetch issues with the waiting_human_review label
-	fmt.Printf("[%s] DEBUG: Fetching issues with label '%s' from project '%s'...\n", timestamp, d.config.Daemon.ReviewLabel, d.selectedProject)
-
-	// Create a timeout context for the API call
-	apiCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	// Use a channel to make the API call cancellable
-	type result struct {
-		issues []gitlab.Issue
-		err    error
-	}
-
-	resultCh := make(chan result, 1)
-	go func() {
-		issues, err := d.gitlabClient.GetProjectIssues(d.selectedProject, []string{d.config.Daemon.ReviewLabel}, "opened")
-		resultCh <- result{issues: issues, err: err}
-	}()
-
-	// Wait for either the result or context cancellation
-	var issues []gitlab.Issue
-	var err error
-	select {
-	case <-apiCtx.Done():
-		fmt.Printf("[%s] DEBUG: API call timed out or was cancelled\n", timestamp)
-		return 0, apiCtx.Err()
-	case res := <-resultCh:
-		issues = res.issues
-		err = res.err
-	}
+	logging.Debugf("Fetching issues with label '%s' from project '%s'...", d.config.Daemon.ReviewLabel, d.selectedProject)
 
+	summaries, err := d.fetchReviewIssues(ctx, timestamp)
 	if err != nil {
-		fmt.Printf("[%s] DEBUG: Failed to fetch review issues: %v\n", timestamp, err)
-		return 0, fmt.Errorf("failed to fetch review issues: %v", err)
+		return 0, err
 	}
-	fmt.Printf("[%s] DEBUG: Successfully fetched %d issues with review label\n", timestamp, len(issues))
+	logging.Debugf("Successfully fetched %d issues with review label", len(summaries))
 
 	// List all review issues for debugging
-	for i, issue := range issues {
-		fmt.Printf("[%s] DEBUG: Review issue %d: #%d - %s (labels: %v)\n", timestamp, i+1, issue.IID, issue.Title, issue.Labels)
+	for i, summary := range summaries {
+		logging.Debugf("Review issue %d: #%d - %s (labels: %v)", i+1, summary.IID, summary.Title, summary.Labels)
 	}
 
 	newSessions := 0
-	for _, issue := range issues {
+	for _, summary := range summaries {
+		issue := summary.Issue
+
 		// Check for cancellation between issues
 		select {
 		case <-ctx.Done():
@@ -1090,129 +1707,13 @@ func (d *Daemon) checkForHumanReviewIssuesWithContext(ctx context.Context, proce
 		}
 
 		// Skip if already processed in this cycle
-		if processedIssues[issue.IID] {
-			fmt.Printf("[%s] DEBUG: Issue #%d already processed in this cycle, skipping\n", timestamp, issue.IID)
-			continue
-		}
-
-		// Get the latest comments to check if last comment is from human
-		fmt.Printf("[%s] DEBUG: Checking latest comments for issue #%d\n", timestamp, issue.IID)
-
-		// Add a longer delay to handle potential API caching/replication delays
-		time.Sleep(3 * time.Second)
-
-		// Create a timeout context for comment checking
-		commentCtx, commentCancel := context.WithTimeout(ctx, 8*time.Second)
-		defer commentCancel()
-
-		type commentResult struct {
-			comments []gitlab.Note
-			err      error
-		}
-
-		commentCh := make(chan commentResult, 1)
-		go func() {
-			fmt.Printf("[%s] DEBUG: Fetching discussions for issue #%d\n", timestamp, issue.IID)
-
-			// Get all discussions/comments for this issue
-			discussions, err := d.gitlabClient.GetIssueDiscussionsWithContext(commentCtx, d.selectedProject, issue.IID)
-			if err != nil {
-				fmt.Printf("[%s] DEBUG: Error fetching discussions for issue #%d: %v\n", timestamp, issue.IID, err)
-				commentCh <- commentResult{comments: nil, err: err}
-				return
-			}
-
-			fmt.Printf("[%s] DEBUG: Issue #%d has %d discussions (fetched at %s)\n", timestamp, issue.IID, len(discussions), time.Now().Format("15:04:05"))
-
-			// Flatten all notes from all discussions and filter out system notes
-			var allNotes []gitlab.Note
-			for i, discussion := range discussions {
-				fmt.Printf("[%s] DEBUG: Discussion %d has %d notes\n", timestamp, i+1, len(discussion.Notes))
-				for j, note := range discussion.Notes {
-					fmt.Printf("[%s] DEBUG:   Note %d: @%s (system: %v) at %s: %.50s...\n",
-						timestamp, j+1, note.Author.Username, note.System, note.CreatedAt, note.Body)
-
-					// Skip system-generated notes (like label changes, etc.)
-					if !note.System {
-						allNotes = append(allNotes, note)
-					}
-				}
-			}
-
-			fmt.Printf("[%s] DEBUG: Issue #%d has %d non-system notes total\n", timestamp, issue.IID, len(allNotes))
-
-			// Sort notes by creation time to ensure we get the actual latest comment
-			sort.Slice(allNotes, func(i, j int) bool {
-				timeI, errI := time.Parse(time.RFC3339, allNotes[i].CreatedAt)
-				timeJ, errJ := time.Parse(time.RFC3339, allNotes[j].CreatedAt)
-				if errI != nil || errJ != nil {
-					// Fallback to string comparison if parsing fails
-					return allNotes[i].CreatedAt < allNotes[j].CreatedAt
-				}
-				return timeI.Before(timeJ)
-			})
-
-			commentCh <- commentResult{comments: allNotes, err: nil}
-		}()
-
-		var comments []gitlab.Note
-		select {
-		case <-commentCtx.Done():
-			fmt.Printf("[%s] DEBUG: Comment checking timed out for issue #%d\n", timestamp, issue.IID)
-			commentCancel()
-			continue
-		case res := <-commentCh:
-			comments = res.comments
-			err = res.err
-		}
-		commentCancel()
-
-		if err != nil {
-			fmt.Printf("[%s] Error getting comments for issue #%d: %v\n", timestamp, issue.IID, err)
+		if processedIssues.has(issue.IID) {
+			logging.Issue(issue.IID).Debugf("Issue #%d already processed in this cycle, skipping", issue.IID)
 			continue
 		}
 
-		fmt.Printf("[%s] DEBUG: Issue #%d has %d total comments (non-system)\n", timestamp, issue.IID, len(comments))
-		
-		// If we expected more comments, try a direct API call to double-check
-		if len(comments) < 14 { // You mentioned you added a comment, so should be > 13
-			fmt.Printf("[%s] DEBUG: Expected more comments, trying direct API call...\n", timestamp)
-			directDiscussions, directErr := d.gitlabClient.GetIssueDiscussions(d.selectedProject, issue.IID)
-			if directErr == nil {
-				var directNotes []gitlab.Note
-				for _, discussion := range directDiscussions {
-					for _, note := range discussion.Notes {
-						if !note.System {
-							directNotes = append(directNotes, note)
-						}
-					}
-				}
-				fmt.Printf("[%s] DEBUG: Direct API call found %d comments (was %d)\n", timestamp, len(directNotes), len(comments))
-				if len(directNotes) > len(comments) {
-					comments = directNotes
-					fmt.Printf("[%s] DEBUG: Using direct API results\n", timestamp)
-				}
-			}
-		}
-
-		// Show the last few comments for debugging
-		if len(comments) > 0 {
-			numToShow := 3
-			if len(comments) < numToShow {
-				numToShow = len(comments)
-			}
-
-			fmt.Printf("[%s] DEBUG: Last %d comments for issue #%d:\n", timestamp, numToShow, issue.IID)
-			for i := len(comments) - numToShow; i < len(comments); i++ {
-				comment := comments[i]
-				fmt.Printf("[%s] DEBUG:   %d. @%s at %s: %.50s...\n",
-					timestamp, i+1, comment.Author.Username, comment.CreatedAt, comment.Body)
-			}
-		}
-
 		// Check if the last comment is from a human (not a bot)
-		if len(comments) > 0 {
-			lastComment := comments[len(comments)-1]
+		if lastComment := summary.LastNote; lastComment != nil {
 			
 			// Check multiple criteria to identify bot comments
 			isBotComment := false
@@ -1238,78 +1739,150 @@ func (d *Daemon) checkForHumanReviewIssuesWithContext(ctx context.Context, proce
 			
 			isHumanComment := !isBotComment
 			
-			fmt.Printf("[%s] DEBUG: Issue #%d last comment by @%s (%s) at %s\n", 
-				timestamp, issue.IID, lastComment.Author.Username, lastComment.Author.Name, lastComment.CreatedAt)
-			fmt.Printf("[%s] DEBUG: Bot detection - Name: '%s', Username: '%s', Config: '%s'\n", 
-				timestamp, lastComment.Author.Name, lastComment.Author.Username, botUsername)
-			fmt.Printf("[%s] DEBUG: Is bot comment: %v, Is human comment: %v\n", 
-				timestamp, isBotComment, isHumanComment)
+			logging.Issue(issue.IID).Debugf("Issue #%d last comment by @%s (%s) at %s", issue.IID, lastComment.Author.Username, lastComment.Author.Name, lastComment.CreatedAt)
+			logging.Debugf("Bot detection - Name: '%s', Username: '%s', Config: '%s'", lastComment.Author.Name, lastComment.Author.Username, botUsername)
+			logging.Debugf("Is bot comment: %v, Is human comment: %v", isBotComment, isHumanComment)
 
 			// Check if this comment is newer than the last one we processed
-			lastProcessedTime, hasProcessedBefore := d.lastCommentTime[issue.IID]
-			
-			// Parse timestamps for proper comparison
-			var isNewerComment bool
+			lastProcessedNote, hasProcessedBefore := d.lastCommentTime.get(issue.IID)
+			isNewerComment := !hasProcessedBefore || lastComment.ID > lastProcessedNote
 			if !hasProcessedBefore {
-				isNewerComment = true
-				fmt.Printf("[%s] DEBUG: Issue #%d - never processed before, treating as new\n", timestamp, issue.IID)
-			} else {
-				// Parse both timestamps for proper comparison
-				lastTime, err1 := time.Parse(time.RFC3339, lastProcessedTime)
-				currentTime, err2 := time.Parse(time.RFC3339, lastComment.CreatedAt)
-				
-				if err1 != nil || err2 != nil {
-					// Fallback to string comparison if parsing fails
-					isNewerComment = lastComment.CreatedAt > lastProcessedTime
-					fmt.Printf("[%s] DEBUG: Issue #%d - timestamp parse failed, using string comparison\n", timestamp, issue.IID)
-				} else {
-					isNewerComment = currentTime.After(lastTime)
-					fmt.Printf("[%s] DEBUG: Issue #%d - parsed timestamp comparison\n", timestamp, issue.IID)
-				}
+				logging.Issue(issue.IID).Debugf("Issue #%d - never processed before, treating as new", issue.IID)
 			}
 
-			fmt.Printf("[%s] DEBUG: Issue #%d - last processed: '%s', current: '%s', newer: %v\n",
-				timestamp, issue.IID, lastProcessedTime, lastComment.CreatedAt, isNewerComment)
+			logging.Issue(issue.IID).Debugf("Issue #%d - last processed note: %d, current: %d, newer: %v", issue.IID, lastProcessedNote, lastComment.ID, isNewerComment)
 
 			if isHumanComment && isNewerComment {
 				// Mark as processed in this cycle and update last comment time
-				processedIssues[issue.IID] = true
-				d.lastCommentTime[issue.IID] = lastComment.CreatedAt
+				processedIssues.add(issue.IID)
+				d.lastCommentTime.set(issue.IID, lastComment.ID)
 				newSessions++
 
-				fmt.Printf("[%s] Found issue #%d with NEW human comment from @%s: %s\n",
-					timestamp, issue.IID, lastComment.Author.Username, issue.Title)
+				logging.Issue(issue.IID).Infof("Found issue #%d with NEW human comment from @%s: %s", issue.IID, lastComment.Author.Username, issue.Title)
 
 				// Process issue asynchronously with automagic label updates
 				if err := d.processIssueWithLabelUpdate(&issue); err != nil {
-					fmt.Printf("[%s] Failed to start processing issue #%d: %v\n", timestamp, issue.IID, err)
+					if errors.Is(err, errTierAtCapacity) {
+						// Roll back so the comment is picked up again next cycle
+						processedIssues.remove(issue.IID)
+						if hasProcessedBefore {
+							d.lastCommentTime.set(issue.IID, lastProcessedNote)
+						} else {
+							d.lastCommentTime.forget(issue.IID)
+						}
+						newSessions--
+						logging.Issue(issue.IID).Infof("Deferring issue #%d: tier %s at capacity", issue.IID, issueTier(&issue))
+						continue
+					}
+					logging.Issue(issue.IID).Errorf("Failed to start processing issue #%d: %v", issue.IID, err)
 				} else {
-					fmt.Printf("[%s] Started new Claude session for issue #%d (human review response)\n", timestamp, issue.IID)
+					logging.Issue(issue.IID).Infof("Started new Claude session for issue #%d (human review response)", issue.IID)
 				}
 			} else {
 				if !isHumanComment {
-					fmt.Printf("[%s] DEBUG: Skipping issue #%d - last comment is from bot\n", timestamp, issue.IID)
+					logging.Issue(issue.IID).Debugf("Skipping issue #%d - last comment is from bot", issue.IID)
 				} else {
-					fmt.Printf("[%s] DEBUG: Skipping issue #%d - no new human comments since last check\n", timestamp, issue.IID)
+					logging.Issue(issue.IID).Debugf("Skipping issue #%d - no new human comments since last check", issue.IID)
 				}
 			}
 		} else {
-			fmt.Printf("[%s] DEBUG: Issue #%d has no comments, skipping\n", timestamp, issue.IID)
+			logging.Issue(issue.IID).Debugf("Issue #%d has no comments, skipping", issue.IID)
 		}
 	}
 
 	return newSessions, nil
 }
 
+// Notes of review issues are fetched a few issues at a time, with requests
+// spaced out so a large review queue does not trip GitLab's rate limit
+const (
+	noteFetchConcurrency = 4
+	noteFetchInterval    = 250 * time.Millisecond
+	noteFetchTimeout     = 2 * time.Minute
+)
+
+// fetchReviewIssues lists the open issues waiting for human review, each with
+// its newest comment. With GraphQL enabled that is a single query; otherwise
+// the issues are listed over REST and their notes fetched in one batch.
+func (d *Daemon) fetchReviewIssues(ctx context.Context, timestamp string) ([]gitlab.IssueSummary, error) {
+	// Create a timeout context for the API call
+	apiCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if d.config.GitLab.GraphQL {
+		summaries, err := d.gitlabClient.ListIssueSummaries(apiCtx, d.selectedProject, []string{d.config.Daemon.ReviewLabel}, "opened")
+		if err == nil {
+			return summaries, nil
+		}
+		if apiCtx.Err() != nil {
+			logging.Debugf("API call timed out or was cancelled")
+			return nil, apiCtx.Err()
+		}
+		logging.Warnf("GraphQL query failed, falling back to REST: %v", err)
+	}
+
+	// Use a channel to make the API call cancellable
+	type result struct {
+		issues []gitlab.Issue
+		err    error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		issues, err := d.gitlabClient.GetProjectIssues(d.selectedProject, []string{d.config.Daemon.ReviewLabel}, "opened")
+		resultCh <- result{issues: issues, err: err}
+	}()
+
+	// Wait for either the result or context cancellation
+	var issues []gitlab.Issue
+	var err error
+	select {
+	case <-apiCtx.Done():
+		logging.Debugf("API call timed out or was cancelled")
+		return nil, apiCtx.Err()
+	case res := <-resultCh:
+		issues = res.issues
+		err = res.err
+	}
+
+	if err != nil {
+		logging.Debugf("Failed to fetch review issues: %v", err)
+		return nil, fmt.Errorf("failed to fetch review issues: %v", err)
+	}
+
+	iids := make([]int, len(issues))
+	for i, issue := range issues {
+		iids[i] = issue.IID
+	}
+
+	notesCtx, notesCancel := context.WithTimeout(ctx, noteFetchTimeout)
+	defer notesCancel()
+	notes, err := d.gitlabClient.GetIssuesNotes(notesCtx, d.selectedProject, iids, noteFetchConcurrency, noteFetchInterval)
+	if err != nil {
+		// Issues whose notes are missing are skipped until the next cycle
+		logging.Errorf("Error getting comments: %v", err)
+	}
+
+	summaries := make([]gitlab.IssueSummary, len(issues))
+	for i, issue := range issues {
+		summaries[i].Issue = issue
+		if comments := notes[issue.IID]; len(comments) > 0 {
+			summaries[i].LastNote = &comments[len(comments)-1]
+		}
+		logging.Issue(issue.IID).Debugf("Issue #%d has %d total comments (non-system)", issue.IID, len(notes[issue.IID]))
+	}
+	return summaries, nil
+}
+
 func (d *Daemon) checkForReviewIssuesWithComments(timestamp string) (int, error) {
 	// Fetch issues with the review label (waiting for human review)
-	fmt.Printf("[%s] DEBUG: Fetching issues with label '%s' from project '%s'...\n", timestamp, d.config.Daemon.ReviewLabel, d.selectedProject)
+	logging.Debugf("Fetching issues with label '%s' from project '%s'...", d.config.Daemon.ReviewLabel, d.selectedProject)
 	reviewIssues, err := d.gitlabClient.GetProjectIssues(d.selectedProject, []string{d.config.Daemon.ReviewLabel}, "opened")
 	if err != nil {
-		fmt.Printf("[%s] DEBUG: Failed to fetch review issues: %v\n", timestamp, err)
+		logging.Debugf("Failed to fetch review issues: %v", err)
 		return 0, fmt.Errorf("failed to fetch review issues: %v", err)
 	}
-	fmt.Printf("[%s] DEBUG: Successfully fetched %d issues with review label\n", timestamp, len(reviewIssues))
+	logging.Debugf("Successfully fetched %d issues with review label", len(reviewIssues))
 
 	resumedSessions := 0
 	for _, issue := range reviewIssues {
@@ -1320,35 +1893,38 @@ func (d *Daemon) checkForReviewIssuesWithComments(timestamp string) (int, error)
 			continue
 		}
 
-		// Determine the cutoff time for new comments
-		cutoffTime := session.CompletionTime
-		if session.LastCommentTime != nil {
-			cutoffTime = *session.LastCommentTime
-		}
+		// Determine the cutoff for new comments
+		cutoff := sessionCutoff(session)
 
-		// Check for new comments since the cutoff time
-		newComments, err := d.gitlabClient.GetIssueCommentsAfter(session.ProjectPath, session.IssueIID, cutoffTime)
+		// Check for new comments since the cutoff
+		newComments, err := d.gitlabClient.GetIssueCommentsAfter(session.ProjectPath, session.IssueIID, cutoff.since())
 		if err != nil {
-			fmt.Printf("[%s] Error checking comments for issue #%d: %v\n", timestamp, session.IssueIID, err)
+			logging.Issue(session.IssueIID).Errorf("Error checking comments for issue #%d: %v", session.IssueIID, err)
 			continue
 		}
+		newComments = cutoff.filter(newComments)
+
+		// Check for new review feedback on the issue's merge request
+		threads, err := d.collectReviewThreads(context.Background(), session, cutoff)
+		if err != nil {
+			logging.Issue(session.IssueIID).Errorf("Error checking review threads for issue #%d: %v", session.IssueIID, err)
+		}
 
-		if len(newComments) > 0 {
-			fmt.Printf("[%s] Found %d new comments on issue #%d\n", timestamp, len(newComments), session.IssueIID)
+		if len(newComments) > 0 || len(threads) > 0 {
+			logging.Issue(session.IssueIID).Infof("Found %d new comments and %d review threads on issue #%d", len(newComments), len(threads), session.IssueIID)
 
 			// Resume Claude session with new comments
-			if err := d.resumeSessionWithComments(session, newComments); err != nil {
-				fmt.Printf("[%s] Error resuming session for issue #%d: %v\n", timestamp, session.IssueIID, err)
+			if err := d.resumeSessionWithComments(session, newComments, threads); err != nil {
+				logging.Issue(session.IssueIID).Errorf("Error resuming session for issue #%d: %v", session.IssueIID, err)
 				continue
 			}
 
 			resumedSessions++
-			fmt.Printf("[%s] Resumed Claude session for issue #%d\n", timestamp, session.IssueIID)
+			logging.Issue(session.IssueIID).Infof("Resumed Claude session for issue #%d", session.IssueIID)
 
-			// Update last comment time to latest comment
-			latestCommentTime := newComments[len(newComments)-1].CreatedAt
-			if parsedTime, err := time.Parse(time.RFC3339, latestCommentTime); err == nil {
-				d.sessionStore.UpdateLastCommentTime(session.IssueIID, parsedTime)
+			// Remember the latest note so it is not fed to the session again
+			if noteID, createdAt, ok := latestFeedback(newComments, threads); ok {
+				d.sessionStore.UpdateLastNote(session.IssueIID, noteID, createdAt)
 			}
 		}
 	}
@@ -1365,7 +1941,7 @@ func (d *Daemon) checkForReviewIssuesWithCommentsWithContext(ctx context.Context
 	}
 
 	// Fetch issues with the review label (waiting for human review) with timeout
-	fmt.Printf("[%s] DEBUG: Fetching issues with label '%s' from project '%s'...\n", timestamp, d.config.Daemon.ReviewLabel, d.selectedProject)
+	logging.Debugf("Fetching issues with label '%s' from project '%s'...", d.config.Daemon.ReviewLabel, d.selectedProject)
 
 	// Create a timeout context for the API call
 	apiCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -1388,7 +1964,7 @@ func (d *Daemon) checkForReviewIssuesWithCommentsWithContext(ctx context.Context
 	var err error
 	select {
 	case <-apiCtx.Done():
-		fmt.Printf("[%s] DEBUG: Review issues API call timed out or was cancelled\n", timestamp)
+		logging.Debugf("Review issues API call timed out or was cancelled")
 		return 0, apiCtx.Err()
 	case res := <-resultCh:
 		reviewIssues = res.issues
@@ -1396,40 +1972,37 @@ func (d *Daemon) checkForReviewIssuesWithCommentsWithContext(ctx context.Context
 	}
 
 	if err != nil {
-		fmt.Printf("[%s] DEBUG: Failed to fetch review issues: %v\n", timestamp, err)
+		logging.Debugf("Failed to fetch review issues: %v", err)
 		return 0, fmt.Errorf("failed to fetch review issues: %v", err)
 	}
-	fmt.Printf("[%s] DEBUG: Successfully fetched %d issues with review label\n", timestamp, len(reviewIssues))
+	logging.Debugf("Successfully fetched %d issues with review label", len(reviewIssues))
 
 	resumedSessions := 0
 	for i, issue := range reviewIssues {
-		fmt.Printf("[%s] DEBUG: Processing review issue %d/%d (#%d)\n", timestamp, i+1, len(reviewIssues), issue.IID)
+		logging.Debugf("Processing review issue %d/%d (#%d)", i+1, len(reviewIssues), issue.IID)
 
 		// Check for cancellation between issues
 		select {
 		case <-ctx.Done():
-			fmt.Printf("[%s] DEBUG: Context cancelled while processing issue %d\n", timestamp, issue.IID)
+			logging.Debugf("Context cancelled while processing issue %d", issue.IID)
 			return resumedSessions, ctx.Err()
 		default:
 		}
 
 		// Check if we have a completed session for this issue
-		fmt.Printf("[%s] DEBUG: Looking up session for issue #%d\n", timestamp, issue.IID)
+		logging.Issue(issue.IID).Debugf("Looking up session for issue #%d", issue.IID)
 		session, exists := d.sessionStore.GetCompletedSession(issue.IID)
 		if !exists {
-			fmt.Printf("[%s] DEBUG: No session record for issue #%d, skipping\n", timestamp, issue.IID)
+			logging.Issue(issue.IID).Debugf("No session record for issue #%d, skipping", issue.IID)
 			continue
 		}
-		fmt.Printf("[%s] DEBUG: Found session for issue #%d\n", timestamp, issue.IID)
+		logging.Issue(issue.IID).Debugf("Found session for issue #%d", issue.IID)
 
-		// Determine the cutoff time for new comments
-		cutoffTime := session.CompletionTime
-		if session.LastCommentTime != nil {
-			cutoffTime = *session.LastCommentTime
-		}
+		// Determine the cutoff for new comments
+		cutoff := sessionCutoff(session)
 
-		// Check for new comments since the cutoff time (with context timeout)
-		fmt.Printf("[%s] DEBUG: Checking comments for issue #%d since %v\n", timestamp, session.IssueIID, cutoffTime)
+		// Check for new comments since the cutoff (with context timeout)
+		logging.Issue(session.IssueIID).Debugf("Checking comments for issue #%d after note %d / %v", session.IssueIID, cutoff.noteID, cutoff.time)
 
 		// Make comment checking cancellable with shorter timeout
 		commentCtx, commentCancel := context.WithTimeout(ctx, 8*time.Second)
@@ -1444,65 +2017,72 @@ func (d *Daemon) checkForReviewIssuesWithCommentsWithContext(ctx context.Context
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Printf("[%s] DEBUG: Panic in comment checking for issue #%d: %v\n", timestamp, session.IssueIID, r)
+					logging.Issue(session.IssueIID).Debugf("Panic in comment checking for issue #%d: %v", session.IssueIID, r)
 					commentCh <- commentResult{comments: nil, err: fmt.Errorf("panic in comment checking: %v", r)}
 				}
 			}()
-			fmt.Printf("[%s] DEBUG: Starting API call for comments on issue #%d\n", timestamp, session.IssueIID)
-			comments, err := d.gitlabClient.GetIssueCommentsAfterWithContext(commentCtx, session.ProjectPath, session.IssueIID, cutoffTime)
-			fmt.Printf("[%s] DEBUG: Finished API call for comments on issue #%d, found %d comments, err: %v\n", timestamp, session.IssueIID, len(comments), err)
+			logging.Issue(session.IssueIID).Debugf("Starting API call for comments on issue #%d", session.IssueIID)
+			comments, err := d.gitlabClient.GetIssueCommentsAfterWithContext(commentCtx, session.ProjectPath, session.IssueIID, cutoff.since())
+			logging.Issue(session.IssueIID).Debugf("Finished API call for comments on issue #%d, found %d comments, err: %v", session.IssueIID, len(comments), err)
 			commentCh <- commentResult{comments: comments, err: err}
 		}()
 
 		var newComments []gitlab.Note
 		select {
 		case <-commentCtx.Done():
-			fmt.Printf("[%s] DEBUG: Comment checking timed out or was cancelled for issue #%d (context error: %v)\n", timestamp, session.IssueIID, commentCtx.Err())
+			logging.Issue(session.IssueIID).Debugf("Comment checking timed out or was cancelled for issue #%d (context error: %v)", session.IssueIID, commentCtx.Err())
 			commentCancel()
 			continue
 		case res := <-commentCh:
-			newComments = res.comments
+			newComments = cutoff.filter(res.comments)
 			err = res.err
 		}
 		commentCancel()
 
 		if err != nil {
-			fmt.Printf("[%s] Error checking comments for issue #%d: %v\n", timestamp, session.IssueIID, err)
+			logging.Issue(session.IssueIID).Errorf("Error checking comments for issue #%d: %v", session.IssueIID, err)
 			continue
 		}
 
-		fmt.Printf("[%s] DEBUG: Found %d new comments for issue #%d\n", timestamp, len(newComments), session.IssueIID)
+		logging.Issue(session.IssueIID).Debugf("Found %d new comments for issue #%d", len(newComments), session.IssueIID)
+
+		// Check for new review feedback on the issue's merge request
+		threadCtx, threadCancel := context.WithTimeout(ctx, 8*time.Second)
+		threads, err := d.collectReviewThreads(threadCtx, session, cutoff)
+		threadCancel()
+		if err != nil {
+			logging.Issue(session.IssueIID).Errorf("Error checking review threads for issue #%d: %v", session.IssueIID, err)
+		}
 
-		if len(newComments) > 0 {
-			fmt.Printf("[%s] Found %d new comments on issue #%d\n", timestamp, len(newComments), session.IssueIID)
+		if len(newComments) > 0 || len(threads) > 0 {
+			logging.Issue(session.IssueIID).Infof("Found %d new comments and %d review threads on issue #%d", len(newComments), len(threads), session.IssueIID)
 
 			// Check for cancellation before resuming session
 			select {
 			case <-ctx.Done():
-				fmt.Printf("[%s] DEBUG: Cancelled before resuming session for issue #%d\n", timestamp, session.IssueIID)
+				logging.Issue(session.IssueIID).Debugf("Cancelled before resuming session for issue #%d", session.IssueIID)
 				return resumedSessions, ctx.Err()
 			default:
 			}
 
 			// Resume Claude session with new comments (this is now async and won't block)
-			fmt.Printf("[%s] DEBUG: Starting session resume for issue #%d\n", timestamp, session.IssueIID)
-			if err := d.resumeSessionWithCommentsWithContext(ctx, session, newComments); err != nil {
+			logging.Issue(session.IssueIID).Debugf("Starting session resume for issue #%d", session.IssueIID)
+			if err := d.resumeSessionWithCommentsWithContext(ctx, session, newComments, threads); err != nil {
 				if ctx.Err() != nil {
-					fmt.Printf("[%s] Session resume cancelled for issue #%d\n", timestamp, session.IssueIID)
+					logging.Issue(session.IssueIID).Infof("Session resume cancelled for issue #%d", session.IssueIID)
 					return resumedSessions, ctx.Err()
 				}
-				fmt.Printf("[%s] Error resuming session for issue #%d: %v\n", timestamp, session.IssueIID, err)
+				logging.Issue(session.IssueIID).Errorf("Error resuming session for issue #%d: %v", session.IssueIID, err)
 				continue
 			}
-			fmt.Printf("[%s] DEBUG: Finished session resume for issue #%d\n", timestamp, session.IssueIID)
+			logging.Issue(session.IssueIID).Debugf("Finished session resume for issue #%d", session.IssueIID)
 
 			resumedSessions++
-			fmt.Printf("[%s] Resumed Claude session for issue #%d\n", timestamp, session.IssueIID)
+			logging.Issue(session.IssueIID).Infof("Resumed Claude session for issue #%d", session.IssueIID)
 
-			// Update last comment time to latest comment
-			latestCommentTime := newComments[len(newComments)-1].CreatedAt
-			if parsedTime, err := time.Parse(time.RFC3339, latestCommentTime); err == nil {
-				d.sessionStore.UpdateLastCommentTime(session.IssueIID, parsedTime)
+			// Remember the latest note so it is not fed to the session again
+			if noteID, createdAt, ok := latestFeedback(newComments, threads); ok {
+				d.sessionStore.UpdateLastNote(session.IssueIID, noteID, createdAt)
 			}
 		}
 	}
@@ -1511,143 +2091,175 @@ func (d *Daemon) checkForReviewIssuesWithCommentsWithContext(ctx context.Context
 }
 
 func (d *Daemon) Run() error {
+	defer d.errorReports.Recover("daemon", nil)
+
 	// Step 1: Get current user info
-	fmt.Printf("=== GitLab Authentication ===\n")
-	currentUser, err := d.gitlabClient.GetCurrentUser()
+	logging.Infof("=== GitLab Authentication ===")
+	currentUser, err := d.gitlabClient.Users().Current()
 	if err != nil {
 		return fmt.Errorf("error fetching current user: %v", err)
 	}
-	fmt.Printf("Authenticated as: %s (@%s)\n", currentUser.Name, currentUser.Username)
-	fmt.Printf("User email: %s\n\n", currentUser.Email)
-
-	// Step 2: Select project interactively
-	fmt.Printf("=== Project Selection for Daemon Mode ===\n")
-	projects, err := d.gitlabClient.GetAccessibleProjects()
-	if err != nil {
-		return fmt.Errorf("error fetching projects: %v", err)
-	}
+	logging.Infof("Authenticated as: %s (@%s)", currentUser.Name, currentUser.Username)
+	logging.Infof("User email: %s", currentUser.Email)
 
-	selectedProject, err := d.selectProject(projects)
-	if err != nil {
-		return fmt.Errorf("error selecting project: %v", err)
+	// Step 2: Select project interactively, unless SetProject chose it
+	if err := d.chooseProject(); err != nil {
+		return err
 	}
 
-	d.selectedProject = selectedProject.PathWithNamespace
-	fmt.Printf("Project selected: %s\n", d.selectedProject)
-
 	// Step 2: Start daemon monitoring
-	fmt.Printf("\n=== Starting Daemon Mode ===\n")
+	logging.Infof("=== Starting Daemon Mode ===")
 	if d.dryRun {
-		fmt.Printf("*** DRY RUN MODE - No actual processing will occur ***\n")
+		logging.Infof("*** DRY RUN MODE - No actual processing will occur ***")
 	} else if d.semiDryRun {
-		fmt.Printf("*** SEMI-DRY RUN MODE - Will clone repositories but not execute Claude ***\n")
+		logging.Infof("*** SEMI-DRY RUN MODE - Will clone repositories but not execute Claude ***")
 	}
-	fmt.Printf("Monitoring project: %s\n", d.selectedProject)
-	fmt.Printf("Monitoring for issues with label: %s\n", d.config.Daemon.ClaudeLabel)
-	fmt.Printf("Processing interval: %d seconds\n", d.config.Daemon.Interval)
-	fmt.Printf("Press Ctrl+C to stop...\n\n")
+	logging.Infof("Monitoring project: %s", d.selectedProject)
+	logging.Infof("Monitoring for issues with label: %s", d.config.Daemon.ClaudeLabel)
+	if len(d.config.Daemon.ExcludeLabels) > 0 {
+		logging.Infof("Skipping issues with labels: %s", strings.Join(d.config.Daemon.ExcludeLabels, ", "))
+	}
+	logging.Infof("Processing interval: %d seconds", d.config.Daemon.Interval)
+	logging.Infof("Press Ctrl+C to stop...")
 
-	// Set up signal handling for graceful shutdown with context
-	ctx, cancel := context.WithCancel(context.Background())
+	// Stop on SIGINT, SIGTERM or Stop, cancelling running operations
+	ctx, cancel := d.runContext()
 	defer cancel()
+	d.startControlServer(ctx)
+	d.startChatOps(ctx)
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Cancel context when signal received
-	go func() {
-		<-sigCh
-		fmt.Printf("\nReceived shutdown signal. Cancelling operations...\n")
-		cancel()
-	}()
-
-	// Keep track of processed items to avoid duplicates
-	processedIssues := make(map[int]bool)
+	// Keep track of processed items to avoid duplicates. Processed issues are
+	// restored from the store, so a restart does not start them again.
+	processedIssues := newProcessedSet(d.sessionStore, !d.dryRun && !d.semiDryRun)
 	processedMRs := make(map[int]bool)
 
-	ticker := time.NewTicker(time.Duration(d.config.Daemon.Interval) * time.Second)
+	// In webhook mode polling only catches deliveries that went missing
+	ticker := time.NewTicker(d.startWebhook(ctx))
 	defer ticker.Stop()
 
+	go d.supervise(ctx, "telemetry", d.telemetry.Run)
+	go d.supervise(ctx, "metrics", d.metrics.Run)
+	go d.supervise(ctx, "maintenance", d.maintenanceLoop)
+	go d.supervise(ctx, "mirroring", d.mirrorLoop)
+	go d.supervise(ctx, "merge sync", d.mergeSyncLoop)
+	d.reconcileMissedEvents()
+	wake := d.wakeups(ctx, ticker.C)
+	d.heartbeat.Ready()
+
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Printf("\nReceived shutdown signal. Stopping daemon...\n")
+			logging.Infof("Received shutdown signal. Stopping daemon...")
+			d.heartbeat.Stopping()
 
-			// Gracefully stop any running processes
-			runningProcesses := d.processManager.GetRunningProcesses()
-			totalProcesses := len(runningProcesses) + len(d.resumeProcesses)
+			d.stopProcesses()
 
-			if totalProcesses > 0 {
-				fmt.Printf("Terminating %d running Claude processes...\n", totalProcesses)
+			logging.Infof("Daemon stopped.")
+			return nil
 
-				// Terminate regular Claude processes
-				for _, process := range runningProcesses {
-					if process.Cmd != nil && process.Cmd.Process != nil {
-						fmt.Printf("  Terminating process for issue #%d (PID: %d)\n", process.IssueNum, process.Cmd.Process.Pid)
-						process.Cmd.Process.Signal(syscall.SIGTERM)
+		case run := <-wake:
+			// Check if context was cancelled before starting work
+			select {
+			case <-ctx.Done():
+				logging.Infof("Operation cancelled before processing")
+				return nil
+			default:
+			}
+
+			d.survive("polling cycle", 0, func() {
+				cycleStart := time.Now()
+				timestamp := cycleStart.Format("2006-01-02 15:04:05")
+				var newIssues, newMRs, resumedIssues int
+
+				// Check for new work
+				if run.has(workflowIssues) {
+					newIssues, err = d.checkForNewClaudeIssuesWithContext(ctx, processedIssues, timestamp)
+					if err != nil && ctx.Err() == nil {
+						logging.Errorf("Error checking issues: %v", err)
 					}
+					d.apiResult("Checking issues", err)
+					d.triageNewIssues(ctx)
 				}
 
-				// Terminate resume processes
-				for issueID, cmd := range d.resumeProcesses {
-					if cmd != nil && cmd.Process != nil {
-						fmt.Printf("  Terminating resume process for issue #%d (PID: %d)\n", issueID, cmd.Process.Pid)
-						cmd.Process.Signal(syscall.SIGTERM)
+				if run.has(workflowReviews) {
+					newMRs, err = d.checkForMergeRequestsWithContext(ctx, processedMRs, timestamp)
+					if err != nil && ctx.Err() == nil {
+						logging.Errorf("Error checking merge requests: %v", err)
 					}
+					d.apiResult("Checking merge requests", err)
 				}
 
-				// Give processes a moment to terminate gracefully
-				fmt.Printf("Waiting 3 seconds for processes to terminate...\n")
-				time.Sleep(3 * time.Second)
-
-				// Force kill any remaining processes
-				for _, process := range runningProcesses {
-					if process.Cmd != nil && process.Cmd.Process != nil {
-						process.Cmd.Process.Kill()
+				if run.has(workflowResume) {
+					resumedIssues, err = d.checkForReviewIssuesWithCommentsWithContext(ctx, timestamp)
+					if err != nil && ctx.Err() == nil {
+						logging.Errorf("Error checking review issues: %v", err)
 					}
+					d.apiResult("Checking review issues", err)
+					resumedIssues += d.checkApprovals(ctx, timestamp)
+					resumedIssues += d.checkPauses(ctx, timestamp)
+					resumedIssues += d.checkDescriptionChanges(ctx, timestamp)
 				}
 
-				for _, cmd := range d.resumeProcesses {
-					if cmd != nil && cmd.Process != nil {
-						cmd.Process.Kill()
-					}
+				if run.has(workflowCIFix) {
+					resumedIssues += d.fixFailedPipelines(ctx, timestamp)
 				}
-			}
+				d.markSynced(cycleStart)
 
-			fmt.Printf("Daemon stopped.\n")
-			return nil
+				// Summary only if there's activity
+				totalActivity := newIssues + newMRs + resumedIssues
+				if totalActivity > 0 {
+					logging.Infof("Started: %d issues, %d MR reviews, %d resumed sessions", newIssues, newMRs, resumedIssues)
+				}
+				d.endCycle()
+			})
+		}
+	}
+}
 
-		case <-ticker.C:
-			// Check if context was cancelled before starting work
-			select {
-			case <-ctx.Done():
-				fmt.Printf("\nOperation cancelled before processing\n")
-				return nil
-			default:
-			}
+// stopProcesses terminates the running Claude sessions and resumes, killing
+// any still alive after a grace period
+func (d *Daemon) stopProcesses() {
+	// Paused sessions pick up where they stopped when the daemon is back
+	if d.config.Pause.OnShutdown && !d.dryRun {
+		d.pauseForShutdown()
+	}
 
-			timestamp := time.Now().Format("2006-01-02 15:04:05")
+	runningProcesses := d.processManager.GetRunningProcesses()
+	totalProcesses := len(runningProcesses) + len(d.resumeProcesses)
+
+	if totalProcesses > 0 {
+		logging.Infof("Terminating %d running Claude processes...", totalProcesses)
 
-			// Check for new work
-			newIssues, err := d.checkForNewClaudeIssuesWithContext(ctx, processedIssues, timestamp)
-			if err != nil && ctx.Err() == nil {
-				fmt.Printf("[%s] Error checking issues: %v\n", timestamp, err)
+		// Terminate regular Claude processes
+		for _, process := range runningProcesses {
+			if process.Cmd != nil && process.Cmd.Process != nil {
+				logging.Issue(process.IssueNum).Infof("  Terminating process for issue #%d (PID: %d)", process.IssueNum, process.Cmd.Process.Pid)
+				process.Cmd.Process.Signal(syscall.SIGTERM)
 			}
+		}
 
-			newMRs, err := d.checkForMergeRequestsWithContext(ctx, processedMRs, timestamp)
-			if err != nil && ctx.Err() == nil {
-				fmt.Printf("[%s] Error checking merge requests: %v\n", timestamp, err)
+		// Terminate resume processes
+		for issueID, cmd := range d.resumeProcesses {
+			if cmd != nil && cmd.Process != nil {
+				logging.Issue(issueID).Infof("  Terminating resume process for issue #%d (PID: %d)", issueID, cmd.Process.Pid)
+				cmd.Process.Signal(syscall.SIGTERM)
 			}
+		}
+
+		// Give processes a moment to terminate gracefully
+		logging.Infof("Waiting 3 seconds for processes to terminate...")
+		time.Sleep(3 * time.Second)
 
-			resumedIssues, err := d.checkForReviewIssuesWithCommentsWithContext(ctx, timestamp)
-			if err != nil && ctx.Err() == nil {
-				fmt.Printf("[%s] Error checking review issues: %v\n", timestamp, err)
+		// Force kill any remaining processes
+		for _, process := range runningProcesses {
+			if process.Cmd != nil && process.Cmd.Process != nil {
+				process.Cmd.Process.Kill()
 			}
+		}
 
-			// Summary only if there's activity
-			totalActivity := newIssues + newMRs + resumedIssues
-			if totalActivity > 0 {
-				fmt.Printf("[%s] Started: %d issues, %d MR reviews, %d resumed sessions\n", timestamp, newIssues, newMRs, resumedIssues)
+		for _, cmd := range d.resumeProcesses {
+			if cmd != nil && cmd.Process != nil {
+				cmd.Process.Kill()
 			}
 		}
 	}
@@ -1664,82 +2276,79 @@ func (d *Daemon) RunWithMemoryMode(memoryMode bool) error {
 }
 
 func (d *Daemon) RunWithoutMemory() error {
+	defer d.errorReports.Recover("daemon", nil)
+
 	// Step 1: Get current user info
-	fmt.Printf("=== GitLab Authentication ===\n")
-	currentUser, err := d.gitlabClient.GetCurrentUser()
+	logging.Infof("=== GitLab Authentication ===")
+	currentUser, err := d.gitlabClient.Users().Current()
 	if err != nil {
 		return fmt.Errorf("error fetching current user: %v", err)
 	}
-	fmt.Printf("Authenticated as: %s (@%s)\n", currentUser.Name, currentUser.Username)
-	fmt.Printf("User email: %s\n\n", currentUser.Email)
-
-	// Step 2: Select project interactively
-	fmt.Printf("=== Project Selection for Daemon Mode ===\n")
-	projects, err := d.gitlabClient.GetAccessibleProjects()
-	if err != nil {
-		return fmt.Errorf("error fetching projects: %v", err)
-	}
+	logging.Infof("Authenticated as: %s (@%s)", currentUser.Name, currentUser.Username)
+	logging.Infof("User email: %s", currentUser.Email)
 
-	selectedProject, err := d.selectProject(projects)
-	if err != nil {
-		return fmt.Errorf("error selecting project: %v", err)
+	// Step 2: Select project interactively, unless SetProject chose it
+	if err := d.chooseProject(); err != nil {
+		return err
 	}
 
-	d.selectedProject = selectedProject.PathWithNamespace
-	fmt.Printf("Project selected: %s\n", d.selectedProject)
-
 	// Step 2: Start daemon monitoring
-	fmt.Printf("\n=== Starting Daemon Mode (No Memory) ===\n")
+	logging.Infof("=== Starting Daemon Mode (No Memory) ===")
 	if d.dryRun {
-		fmt.Printf("*** DRY RUN MODE - No actual processing will occur ***\n")
+		logging.Infof("*** DRY RUN MODE - No actual processing will occur ***")
 	} else if d.semiDryRun {
-		fmt.Printf("*** SEMI-DRY RUN MODE - Will clone repositories but not execute Claude ***\n")
+		logging.Infof("*** SEMI-DRY RUN MODE - Will clone repositories but not execute Claude ***")
+	}
+	logging.Infof("Monitoring project: %s", d.selectedProject)
+	logging.Infof("Monitoring for issues with label: %s", d.config.Daemon.ClaudeLabel)
+	if len(d.config.Daemon.ExcludeLabels) > 0 {
+		logging.Infof("Skipping issues with labels: %s", strings.Join(d.config.Daemon.ExcludeLabels, ", "))
 	}
-	fmt.Printf("Monitoring project: %s\n", d.selectedProject)
-	fmt.Printf("Monitoring for issues with label: %s\n", d.config.Daemon.ClaudeLabel)
-	fmt.Printf("Processing interval: %d seconds\n", d.config.Daemon.Interval)
-	fmt.Printf("Memory mode: DISABLED (no session resumption)\n")
-	fmt.Printf("Press Ctrl+C to stop...\n\n")
+	logging.Infof("Processing interval: %d seconds", d.config.Daemon.Interval)
+	logging.Infof("Memory mode: DISABLED (no session resumption)")
+	logging.Infof("Press Ctrl+C to stop...")
 
-	// Set up signal handling for graceful shutdown with context
-	ctx, cancel := context.WithCancel(context.Background())
+	// Stop on SIGINT, SIGTERM or Stop, cancelling running operations
+	ctx, cancel := d.runContext()
 	defer cancel()
+	d.startControlServer(ctx)
+	d.startChatOps(ctx)
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Cancel context when signal received
-	go func() {
-		<-sigCh
-		fmt.Printf("\nReceived shutdown signal. Cancelling operations...\n")
-		cancel()
-	}()
-
-	ticker := time.NewTicker(time.Duration(d.config.Daemon.Interval) * time.Second)
+	// In webhook mode polling only catches deliveries that went missing
+	ticker := time.NewTicker(d.startWebhook(ctx))
 	defer ticker.Stop()
 
+	go d.supervise(ctx, "telemetry", d.telemetry.Run)
+	go d.supervise(ctx, "metrics", d.metrics.Run)
+	go d.supervise(ctx, "maintenance", d.maintenanceLoop)
+	go d.supervise(ctx, "mirroring", d.mirrorLoop)
+	d.reconcileMissedEvents()
+	wake := d.wakeups(ctx, ticker.C)
+	d.heartbeat.Ready()
+
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Printf("\nReceived shutdown signal. Stopping daemon...\n")
+			logging.Infof("Received shutdown signal. Stopping daemon...")
+			d.heartbeat.Stopping()
 
 			// Gracefully stop any running processes
 			runningProcesses := d.processManager.GetRunningProcesses()
 			totalProcesses := len(runningProcesses)
 
 			if totalProcesses > 0 {
-				fmt.Printf("Terminating %d running Claude processes...\n", totalProcesses)
+				logging.Infof("Terminating %d running Claude processes...", totalProcesses)
 
 				// Terminate regular Claude processes
 				for _, process := range runningProcesses {
 					if process.Cmd != nil && process.Cmd.Process != nil {
-						fmt.Printf("  Terminating process for issue #%d (PID: %d)\n", process.IssueNum, process.Cmd.Process.Pid)
+						logging.Issue(process.IssueNum).Infof("  Terminating process for issue #%d (PID: %d)", process.IssueNum, process.Cmd.Process.Pid)
 						process.Cmd.Process.Signal(syscall.SIGTERM)
 					}
 				}
 
 				// Give processes a moment to terminate gracefully
-				fmt.Printf("Waiting 3 seconds for processes to terminate...\n")
+				logging.Infof("Waiting 3 seconds for processes to terminate...")
 				time.Sleep(3 * time.Second)
 
 				// Force kill any remaining processes
@@ -1750,69 +2359,91 @@ func (d *Daemon) RunWithoutMemory() error {
 				}
 			}
 
-			fmt.Printf("Daemon stopped.\n")
+			logging.Infof("Daemon stopped.")
 			return nil
 
-		case <-ticker.C:
+		case run := <-wake:
 			// Check if context was cancelled before starting work
 			select {
 			case <-ctx.Done():
-				fmt.Printf("\nOperation cancelled before processing\n")
+				logging.Infof("Operation cancelled before processing")
 				return nil
 			default:
 			}
 
-			// Create fresh processed items maps for this polling cycle
-			processedIssues := make(map[int]bool)
-			processedMRs := make(map[int]bool)
+			d.survive("polling cycle", 0, func() {
+				// Without memory there are no sessions to resume for CI fixes
+				if run.has(workflowCIFix) {
+					d.takeFailedPipelines()
+				}
 
-			timestamp := time.Now().Format("2006-01-02 15:04:05")
-			fmt.Printf("[%s] Checking for issues to process...\n", timestamp)
+				// Create fresh processed items maps for this polling cycle
+				processedIssues := newProcessedSet(d.sessionStore, false)
+				processedMRs := make(map[int]bool)
 
-			// Check for new issues with 'claude' label
-			fmt.Printf("[%s] DEBUG: Starting checkForNewClaudeIssues...\n", timestamp)
-			newIssues, err := d.checkForNewClaudeIssuesWithContext(ctx, processedIssues, timestamp)
-			if err != nil {
-				if ctx.Err() != nil {
-					fmt.Printf("[%s] Operation cancelled by user\n", timestamp)
-					continue
+				cycleStart := time.Now()
+				timestamp := cycleStart.Format("2006-01-02 15:04:05")
+				logging.Infof("Checking for issues to process...")
+
+				var newIssues, newMRs, reviewIssues int
+
+				// Check for new issues with 'claude' label
+				if run.has(workflowIssues) {
+					logging.Debugf("Starting checkForNewClaudeIssues...")
+					newIssues, err = d.checkForNewClaudeIssuesWithContext(ctx, processedIssues, timestamp)
+					if err != nil {
+						if ctx.Err() != nil {
+							logging.Infof("Operation cancelled by user")
+							return
+						}
+						logging.Errorf("Error checking for new claude issues: %v", err)
+					}
+					d.apiResult("Checking issues", err)
+					logging.Debugf("Finished checkForNewClaudeIssues, found %d new issues", newIssues)
+					d.triageNewIssues(ctx)
 				}
-				fmt.Printf("[%s] Error checking for new claude issues: %v\n", timestamp, err)
-			}
-			fmt.Printf("[%s] DEBUG: Finished checkForNewClaudeIssues, found %d new issues\n", timestamp, newIssues)
 
-			// Check for assigned merge requests (new functionality)
-			fmt.Printf("[%s] DEBUG: Starting checkForMergeRequests...\n", timestamp)
-			newMRs, err := d.checkForMergeRequestsWithContext(ctx, processedMRs, timestamp)
-			if err != nil {
-				if ctx.Err() != nil {
-					fmt.Printf("[%s] Operation cancelled by user\n", timestamp)
-					continue
+				// Check for assigned merge requests (new functionality)
+				if run.has(workflowReviews) {
+					logging.Debugf("Starting checkForMergeRequests...")
+					newMRs, err = d.checkForMergeRequestsWithContext(ctx, processedMRs, timestamp)
+					if err != nil {
+						if ctx.Err() != nil {
+							logging.Infof("Operation cancelled by user")
+							return
+						}
+						logging.Errorf("Error checking for merge requests: %v", err)
+					}
+					d.apiResult("Checking merge requests", err)
+					logging.Debugf("Finished checkForMergeRequests, found %d new MRs", newMRs)
 				}
-				fmt.Printf("[%s] Error checking for merge requests: %v\n", timestamp, err)
-			}
-			fmt.Printf("[%s] DEBUG: Finished checkForMergeRequests, found %d new MRs\n", timestamp, newMRs)
 
-			// Check for issues with 'waiting_human_review' label that have human comments
-			fmt.Printf("[%s] DEBUG: Starting checkForHumanReviewIssues...\n", timestamp)
-			reviewIssues, err := d.checkForHumanReviewIssuesWithContext(ctx, processedIssues, timestamp)
-			if err != nil {
-				if ctx.Err() != nil {
-					fmt.Printf("[%s] Operation cancelled by user\n", timestamp)
-					continue
+				// Check for issues with 'waiting_human_review' label that have human comments
+				if run.has(workflowResume) {
+					logging.Debugf("Starting checkForHumanReviewIssues...")
+					reviewIssues, err = d.checkForHumanReviewIssuesWithContext(ctx, processedIssues, timestamp)
+					if err != nil {
+						if ctx.Err() != nil {
+							logging.Infof("Operation cancelled by user")
+							return
+						}
+						logging.Errorf("Error checking for human review issues: %v", err)
+					}
+					d.apiResult("Checking review issues", err)
+					logging.Debugf("Finished checkForHumanReviewIssues, found %d issues with human comments", reviewIssues)
 				}
-				fmt.Printf("[%s] Error checking for human review issues: %v\n", timestamp, err)
-			}
-			fmt.Printf("[%s] DEBUG: Finished checkForHumanReviewIssues, found %d issues with human comments\n", timestamp, reviewIssues)
 
-			// Summary
-			totalNewSessions := newIssues + newMRs + reviewIssues
-			if totalNewSessions > 0 {
-				fmt.Printf("[%s] Activity: %d new sessions started (%d claude label, %d MR reviews, %d human review)\n", timestamp, totalNewSessions, newIssues, newMRs, reviewIssues)
-			} else {
-				fmt.Printf("[%s] No new activity found\n", timestamp)
-			}
-			fmt.Printf("[%s] DEBUG: Finished polling cycle, waiting for next tick...\n", timestamp)
+				// Summary
+				totalNewSessions := newIssues + newMRs + reviewIssues
+				if totalNewSessions > 0 {
+					logging.Infof("Activity: %d new sessions started (%d claude label, %d MR reviews, %d human review)", totalNewSessions, newIssues, newMRs, reviewIssues)
+				} else {
+					logging.Infof("No new activity found")
+				}
+				d.markSynced(cycleStart)
+				d.endCycle()
+				logging.Debugf("Finished polling cycle, waiting for next tick...")
+			})
 		}
 	}
 }
@@ -1830,6 +2461,13 @@ func (d *Daemon) GetProcessStatus() {
 			process.IssueNum, process.ID, time.Since(process.StartTime))
 	}
 
+	usage := d.scheduler.usage()
+	for _, tier := range []string{"T1", "T2", "T3", "T4", untieredTier} {
+		if usage[tier] > 0 {
+			fmt.Printf("  Tier %s: %d running\n", tier, usage[tier])
+		}
+	}
+
 	fmt.Printf("Completed processes: %d\n", len(completed))
 	for _, process := range completed {
 		fmt.Printf("  - Issue #%d (ID: %s) - Completed in %v\n",
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Limits of the description diff given to a session
+const (
+	maxDiffInputLines = 400 // Longer descriptions are compared up to here
+	maxDiffLines      = 80  // Changed lines listed in the prompt
+)
+
+// recordDescription remembers an issue's description as its session saw it
+func (d *Daemon) recordDescription(issueIID int, description string) {
+	tracker, ok := d.sessionStore.(session.DescriptionTracker)
+	if !ok || !d.config.DescriptionSync.Enabled {
+		return
+	}
+	if err := tracker.SetIssueDescription(issueIID, description); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to record description of issue #%d: %v", issueIID, err)
+	}
+}
+
+// descriptionChanged reports whether an issue's description differs from
+// the one its session last saw. Issues without a recorded description are
+// recorded now, so only later edits count.
+func (d *Daemon) descriptionChanged(issueIID int, current string) (string, bool) {
+	tracker, ok := d.sessionStore.(session.DescriptionTracker)
+	if !ok || !d.config.DescriptionSync.Enabled {
+		return "", false
+	}
+	seen, exists := tracker.GetIssueDescription(issueIID)
+	if !exists {
+		d.recordDescription(issueIID, current)
+		return "", false
+	}
+	if normalizeDescription(seen) == normalizeDescription(current) {
+		return "", false
+	}
+	return seen, true
+}
+
+// descriptionChange returns the current description of a session's issue and
+// a prompt section with its edits since the session last saw it, "" when it
+// has not changed
+func (d *Daemon) descriptionChange(s *session.CompletedSession) (string, string) {
+	if !d.config.DescriptionSync.Enabled {
+		return "", ""
+	}
+	issue, err := d.gitlabClient.GetIssue(s.ProjectPath, s.IssueIID)
+	if err != nil {
+		logging.Issue(s.IssueIID).Warnf("Failed to check description of issue #%d: %v", s.IssueIID, err)
+		return "", ""
+	}
+	seen, changed := d.descriptionChanged(s.IssueIID, issue.Description)
+	if !changed {
+		return "", ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Issue Description Changed\n\nThe description of issue #%d was edited since you last worked on it. ", s.IssueIID)
+	b.WriteString("Removed lines start with `-`, added lines with `+`:\n\n```diff\n")
+	b.WriteString(lineDiff(seen, issue.Description))
+	b.WriteString("```\n\nUpdate your implementation so it matches the new description")
+	if mr, err := d.issueMergeRequest(s.ProjectPath, sessionBranch(s)); err == nil && mr != nil {
+		fmt.Fprintf(&b, ", then update the description of merge request !%d with GitLab MCP so it describes the changes as they now are", mr.IID)
+	}
+	b.WriteString(". If the edit needs no code change, say so in a comment on the issue.\n\n")
+	return issue.Description, b.String()
+}
+
+// checkDescriptionChanges resumes the sessions of issues in review whose
+// description was edited after their merge request was opened. Comments on
+// the issue resume a session with the edits too; this catches edits that
+// come without one. It returns how many sessions it resumed.
+func (d *Daemon) checkDescriptionChanges(ctx context.Context, timestamp string) int {
+	if !d.config.DescriptionSync.Enabled {
+		return 0
+	}
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{
+		Labels: []string{d.config.Daemon.ReviewLabel},
+		State:  "opened",
+	})
+	if err != nil {
+		logging.Errorf("Error checking issue descriptions: %v", err)
+		return 0
+	}
+
+	resumed := 0
+	for _, issue := range issues {
+		if ctx.Err() != nil {
+			return resumed
+		}
+		if _, running := d.resumeProcesses[issue.IID]; running {
+			continue
+		}
+		stored, exists := d.sessionStore.GetCompletedSession(issue.IID)
+		if !exists {
+			continue
+		}
+		if _, changed := d.descriptionChanged(issue.IID, issue.Description); !changed {
+			continue
+		}
+		// Until there is a merge request the session is still writing it
+		if mr, err := d.issueMergeRequest(stored.ProjectPath, sessionBranch(stored)); err != nil || mr == nil {
+			continue
+		}
+
+		logging.Issue(issue.IID).Infof("Description of issue #%d changed, resuming its session", issue.IID)
+		if err := d.resumeSession(ctx, stored, nil, nil, false); err != nil {
+			logging.Issue(issue.IID).Errorf("Error resuming session for issue #%d: %v", issue.IID, err)
+			continue
+		}
+		resumed++
+	}
+	return resumed
+}
+
+// normalizeDescription ignores line ending and trailing whitespace edits
+func normalizeDescription(description string) string {
+	lines := strings.Split(strings.ReplaceAll(description, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// descriptionLines splits a normalized description into lines, none when it is empty
+func descriptionLines(description string) []string {
+	if description = normalizeDescription(description); description == "" {
+		return nil
+	}
+	return strings.Split(description, "\n")
+}
+
+// lineDiff lists the lines removed from and added to a text, in order, each
+// prefixed with "- " or "+ ", using the longest common subsequence of lines
+func lineDiff(before, after string) string {
+	a, b := descriptionLines(before), descriptionLines(after)
+	if len(a) > maxDiffInputLines {
+		a = a[:maxDiffInputLines]
+	}
+	if len(b) > maxDiffInputLines {
+		b = b[:maxDiffInputLines]
+	}
+
+	// common[i][j] is the length of the LCS of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+
+	if len(diff) > maxDiffLines {
+		more := len(diff) - maxDiffLines
+		diff = append(diff[:maxDiffLines], fmt.Sprintf("... %d more changed lines", more))
+	}
+	return strings.Join(diff, "\n") + "\n"
+}
@@ -0,0 +1,387 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/queue"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// SetQueue connects the daemon to a job queue shared with other daemons. As
+// coordinator it enqueues eligible issues instead of running them; as worker
+// it takes new issues from the queue instead of from GitLab.
+func (d *Daemon) SetQueue(q queue.Queue) {
+	d.queue = q
+	d.leases = newLeaseSet()
+	d.languages = &languageCache{languages: make(map[string]string)}
+}
+
+// queueRole returns the daemon's role in a distributed deployment, or "" when
+// it runs standalone
+func (d *Daemon) queueRole() string {
+	if d.queue == nil {
+		return ""
+	}
+	return d.config.Queue.Role
+}
+
+// leaseTTL is how long a claimed job stays with this worker between renewals
+func (d *Daemon) leaseTTL() time.Duration {
+	return time.Duration(d.config.Queue.Lease) * time.Minute
+}
+
+// worker describes this daemon to the queue
+func (d *Daemon) worker() queue.Worker {
+	return queue.Worker{ID: d.config.Queue.WorkerID, Capabilities: d.config.Queue.Capabilities}
+}
+
+// jobRequirements returns the capabilities a worker needs to take an issue:
+// those of each QUEUE_ROUTES label the issue carries and, with
+// QUEUE_ROUTE_LANGUAGE, the project's main language
+func (d *Daemon) jobRequirements(issue *gitlab.Issue) map[string]string {
+	requires := make(map[string]string)
+	for _, label := range issue.Labels {
+		if spec, ok := d.config.Queue.Routes[label]; ok {
+			for key, value := range queue.ParseRequirements(spec) {
+				requires[key] = value
+			}
+		}
+	}
+	if d.config.Queue.RouteLanguage {
+		if language := d.projectLanguage(d.selectedProject); language != "" {
+			requires["language"] = language
+		}
+	}
+	return requires
+}
+
+// projectLanguage returns a project's main language in lower case, or "" if
+// it cannot be detected. Lookups are cached for the daemon's lifetime.
+func (d *Daemon) projectLanguage(projectPath string) string {
+	d.languages.mu.Lock()
+	defer d.languages.mu.Unlock()
+	if language, ok := d.languages.languages[projectPath]; ok {
+		return language
+	}
+
+	languages, err := d.gitlabClient.GetProjectLanguages(projectPath)
+	if err != nil {
+		logging.Warnf("Failed to detect the language of %s: %v", projectPath, err)
+		return ""
+	}
+	language := strings.ToLower(gitlab.MainLanguage(languages))
+	d.languages.languages[projectPath] = language
+	return language
+}
+
+// languageCache holds the main language detected for each project
+type languageCache struct {
+	mu        sync.Mutex
+	languages map[string]string
+}
+
+// enqueueIssue marks an eligible issue as picked up and hands it to the
+// workers. The tier travels with the job, so each worker applies its own
+// tier limits, and so do the capabilities its labels route it to.
+func (d *Daemon) enqueueIssue(issue *gitlab.Issue, timestamp string) error {
+	tier := issueTier(issue)
+	requires := d.jobRequirements(issue)
+	route := describeRequirements(requires)
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would enqueue issue #%d (tier %s) for %s", issue.IID, tier, route)
+		return nil
+	}
+
+	newLabels := []string{d.config.Daemon.ProcessLabel}
+	for _, label := range issue.Labels {
+		if label != d.config.Daemon.ClaudeLabel && label != d.config.Daemon.ReviewLabel && label != d.config.Daemon.ProcessLabel {
+			newLabels = append(newLabels, label)
+		}
+	}
+	if err := d.setIssueLabels(issue.IID, newLabels); err != nil {
+		return fmt.Errorf("failed to update issue labels: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	added, err := d.queue.Enqueue(ctx, queue.Job{
+		ProjectPath: d.selectedProject,
+		IssueIID:    issue.IID,
+		Title:       issue.Title,
+		Tier:        tier,
+		Labels:      issue.Labels,
+		Requires:    requires,
+	})
+	if err != nil {
+		return err
+	}
+	if !added {
+		logging.Issue(issue.IID).Infof("Issue #%d is already queued", issue.IID)
+		return nil
+	}
+	logging.Issue(issue.IID).Infof("Queued issue #%d (tier %s) for %s", issue.IID, tier, route)
+	d.recordEvent(issue.IID, session.EventPickedUp, "", fmt.Sprintf("queued, tier %s, %s", tier, route))
+	return nil
+}
+
+// describeRequirements names the workers that can take a job, for logs
+func describeRequirements(requires map[string]string) string {
+	if len(requires) == 0 {
+		return "any worker"
+	}
+	pairs := make([]string, 0, len(requires))
+	for key, value := range requires {
+		pairs = append(pairs, key+":"+value)
+	}
+	sort.Strings(pairs)
+	return "workers with " + strings.Join(pairs, "+")
+}
+
+// claimQueuedIssues starts sessions for queued issues while this worker has
+// room in their tiers, returning how many were started. A job whose tier is
+// full goes back to the head of the queue for another worker.
+func (d *Daemon) claimQueuedIssues(ctx context.Context, timestamp string) (int, error) {
+	started := 0
+	for ctx.Err() == nil {
+		lease, err := d.queue.Claim(ctx, d.selectedProject, d.worker(), d.leaseTTL())
+		if err != nil {
+			return started, err
+		}
+		if lease == nil {
+			return started, nil
+		}
+		job := lease.Job
+
+		if !d.scheduler.tryAcquire(job.IssueIID, job.Tier) {
+			logging.Issue(job.IssueIID).Infof("Returning issue #%d to the queue: tier %s at capacity", job.IssueIID, job.Tier)
+			if err := d.queue.Release(ctx, lease); err != nil {
+				logging.Issue(job.IssueIID).Warnf("Failed to return issue #%d to the queue: %v", job.IssueIID, err)
+			}
+			return started, nil
+		}
+
+		logging.Issue(job.IssueIID).Infof("Claimed issue #%d: %s", job.IssueIID, job.Title)
+		d.leases.hold(lease, d.renewLease)
+		if err := d.processIssueAsync(job.IssueIID); err != nil {
+			d.scheduler.release(job.IssueIID)
+			d.finishLease(job.IssueIID, queue.Result{Detail: err.Error()})
+			logging.Issue(job.IssueIID).Errorf("Failed to start issue #%d: %v", job.IssueIID, err)
+			continue
+		}
+		d.recordEvent(job.IssueIID, session.EventPickedUp, "", fmt.Sprintf("tier %s, worker %s", job.Tier, lease.Worker))
+		started++
+	}
+	return started, ctx.Err()
+}
+
+// RunWorker runs sessions for the issues of projectPath that the coordinator
+// queues, without polling GitLab. It checks the queue every DAEMON_INTERVAL
+// seconds and, when stopped, hands unfinished jobs back to the queue.
+func (d *Daemon) RunWorker(projectPath string) error {
+	if d.queue == nil {
+		return fmt.Errorf("worker mode needs QUEUE_URL")
+	}
+	defer d.errorReports.Recover("worker", nil)
+	d.useProject(projectPath)
+
+	logging.Infof("=== Starting Worker Mode ===")
+	logging.Infof("Worker: %s", d.config.Queue.WorkerID)
+	if len(d.config.Queue.Capabilities) > 0 {
+		logging.Infof("Capabilities: %s", describeRequirements(d.config.Queue.Capabilities))
+	}
+	logging.Infof("Claiming issues of project: %s", d.selectedProject)
+	logging.Infof("Queue check interval: %d seconds", d.config.Daemon.Interval)
+	logging.Infof("Press Ctrl+C to stop...")
+
+	ctx, cancel := d.runContext()
+	defer cancel()
+	d.startControlServer(ctx)
+	d.startChatOps(ctx)
+
+	ticker := time.NewTicker(time.Duration(d.config.Daemon.Interval) * time.Second)
+	defer ticker.Stop()
+	go d.supervise(ctx, "telemetry", d.telemetry.Run)
+	go d.supervise(ctx, "metrics", d.metrics.Run)
+	go d.supervise(ctx, "mirroring", d.mirrorLoop)
+	d.heartbeat.Ready()
+
+	for {
+		d.survive("polling cycle", 0, func() {
+			timestamp := time.Now().Format("2006-01-02 15:04:05")
+			started, err := d.claimQueuedIssues(ctx, timestamp)
+			if err != nil && ctx.Err() == nil {
+				logging.Errorf("Error claiming queued issues: %v", err)
+			}
+			d.apiResult("Claiming queued issues", err)
+			if started > 0 {
+				logging.Infof("Started: %d issues", started)
+			}
+			d.endCycle()
+		})
+
+		select {
+		case <-ctx.Done():
+			d.heartbeat.Stopping()
+			// Release first, so the sessions ended below are not reported as failures
+			d.releaseLeases()
+			d.stopProcesses()
+			logging.Infof("Worker stopped.")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewLease keeps a claimed job with this worker while its session runs
+func (d *Daemon) renewLease(lease *queue.Lease) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := d.queue.Renew(ctx, lease, d.leaseTTL())
+	if errors.Is(err, queue.ErrLeaseLost) {
+		logging.Issue(lease.Job.IssueIID).Warnf("Lease on issue #%d expired, another worker may pick it up", lease.Job.IssueIID)
+	}
+	return err
+}
+
+// finishLease removes the issue's job from the queue once its session has
+// ended, successfully or not, and reports result to the coordinator. It does
+// nothing for issues not claimed from the queue.
+func (d *Daemon) finishLease(issueIID int, result queue.Result) {
+	if d.leases == nil {
+		return
+	}
+	lease := d.leases.drop(issueIID)
+	if lease == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := d.queue.Complete(ctx, lease); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to complete queued issue #%d: %v", issueIID, err)
+	}
+
+	result.ProjectPath = lease.Job.ProjectPath
+	result.IssueIID = issueIID
+	result.Worker = lease.Worker
+	result.FinishedAt = time.Now()
+	if err := d.queue.Report(ctx, result); err != nil {
+		logging.Warnf("%v", err)
+	}
+}
+
+// releaseLeases returns every job this worker holds to the queue, for when
+// it shuts down with sessions still running
+func (d *Daemon) releaseLeases() {
+	if d.leases == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, lease := range d.leases.dropAll() {
+		if err := d.queue.Release(ctx, lease); err != nil {
+			logging.Issue(lease.Job.IssueIID).Warnf("Failed to return issue #%d to the queue: %v", lease.Job.IssueIID, err)
+		} else {
+			logging.Issue(lease.Job.IssueIID).Infof("Returned issue #%d to the queue", lease.Job.IssueIID)
+		}
+	}
+}
+
+// resultBatch is how many worker results the coordinator takes per poll
+const resultBatch = 50
+
+// collectResults records the results workers reported since the last poll
+// in the coordinator's audit log, so -state shows where each issue ran
+func (d *Daemon) collectResults(ctx context.Context, timestamp string) {
+	results, err := d.queue.Results(ctx, d.selectedProject, resultBatch)
+	if err != nil {
+		logging.Warnf("%v", err)
+	}
+	for _, result := range results {
+		if result.Success {
+			logging.Issue(result.IssueIID).Infof("Worker %s completed issue #%d", result.Worker, result.IssueIID)
+			d.recordEvent(result.IssueIID, session.EventCompleted, result.SessionID, "worker "+result.Worker)
+		} else {
+			logging.Issue(result.IssueIID).Infof("Worker %s failed issue #%d: %s", result.Worker, result.IssueIID, result.Detail)
+			d.recordEvent(result.IssueIID, session.EventFailed, result.SessionID,
+				strings.TrimSpace(fmt.Sprintf("worker %s: %s", result.Worker, result.Detail)))
+		}
+	}
+}
+
+// leaseSet holds this worker's leases and renews each at a third of its TTL
+// until it is dropped or lost
+type leaseSet struct {
+	mu     sync.Mutex
+	leases map[int]*heldLease
+}
+
+type heldLease struct {
+	lease *queue.Lease
+	stop  chan struct{}
+}
+
+func newLeaseSet() *leaseSet {
+	return &leaseSet{leases: make(map[int]*heldLease)}
+}
+
+// hold starts renewing lease with renew
+func (s *leaseSet) hold(lease *queue.Lease, renew func(*queue.Lease) error) {
+	held := &heldLease{lease: lease, stop: make(chan struct{})}
+	s.mu.Lock()
+	s.leases[lease.Job.IssueIID] = held
+	s.mu.Unlock()
+
+	interval := time.Until(lease.Expires) / 3
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-held.stop:
+				return
+			case <-ticker.C:
+				if err := renew(lease); errors.Is(err, queue.ErrLeaseLost) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// dropAll stops renewing every lease and returns them
+func (s *leaseSet) dropAll() []*queue.Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := make([]*queue.Lease, 0, len(s.leases))
+	for issueIID, held := range s.leases {
+		delete(s.leases, issueIID)
+		close(held.stop)
+		leases = append(leases, held.lease)
+	}
+	return leases
+}
+
+// drop stops renewing the issue's lease and returns it, or nil if none is held
+func (s *leaseSet) drop(issueIID int) *queue.Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held, ok := s.leases[issueIID]
+	if !ok {
+		return nil
+	}
+	delete(s.leases, issueIID)
+	close(held.stop)
+	return held.lease
+}
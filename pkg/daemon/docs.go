@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/docindex"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// docIndexTimeout bounds updating a documentation index and searching it
+const docIndexTimeout = 5 * time.Minute
+
+// docTopK returns how many doc passages the prompts of a project include,
+// 0 when the doc index is off for it
+func (d *Daemon) docTopK(projectPath string) int {
+	if !d.config.DocIndex.Enabled {
+		return 0
+	}
+	if k, ok := d.config.DocIndex.Projects[projectPath]; ok {
+		return k
+	}
+	return d.config.DocIndex.TopK
+}
+
+// relevantDocs returns the passages of the checkout in repoDir closest to an
+// issue, formatted for its prompt. The project's index is brought up to date
+// with the checkout first; it is cached in DATA_DIR/docindex, so only text
+// changed since the last session is embedded again. Failures are logged and
+// leave the passages out.
+func (d *Daemon) relevantDocs(issue *gitlab.Issue, repoDir string) string {
+	k := d.docTopK(d.selectedProject)
+	if k <= 0 {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), docIndexTimeout)
+	defer cancel()
+
+	embedder := docindex.NewEmbedder(d.config.DocIndex.EmbeddingURL, d.config.DocIndex.EmbeddingModel, d.config.DocIndex.EmbeddingAPIKey)
+	cachePath := filepath.Join(d.config.Data.Dir, "docindex", strings.ReplaceAll(d.selectedProject, "/", "_")+".json")
+
+	d.docIndexMu.Lock()
+	index, err := docindex.Build(ctx, embedder, repoDir, d.config.DocIndex.Files, cachePath)
+	d.docIndexMu.Unlock()
+	if err != nil {
+		logging.Warnf("Failed to index the docs of %s: %v", d.selectedProject, err)
+		return ""
+	}
+
+	chunks, err := index.Search(ctx, embedder, issue.Title+"\n\n"+issue.Description, k)
+	if err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to search the docs of %s for issue #%d: %v", d.selectedProject, issue.IID, err)
+		return ""
+	}
+
+	var docs strings.Builder
+	for _, chunk := range chunks {
+		fmt.Fprintf(&docs, "### `%s` (%s)\n\n%s\n\n", chunk.File, chunk.Heading, chunk.Text)
+	}
+	return strings.TrimSpace(docs.String())
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Limits of the duplicate search
+const (
+	duplicateSearchTerms = 3 // Title keywords searched for candidates
+	maxDuplicates        = 5 // Candidates listed in the comment
+)
+
+// stopWords are left out when comparing issues
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "be": true, "by": true,
+	"can": true, "do": true, "does": true, "for": true, "from": true, "has": true,
+	"in": true, "is": true, "it": true, "not": true, "of": true, "on": true, "or": true,
+	"should": true, "that": true, "the": true, "this": true, "to": true, "when": true,
+	"with": true, "add": true, "fix": true, "issue": true, "support": true, "use": true,
+}
+
+// duplicateCandidate is an open issue that looks like the one being picked up
+type duplicateCandidate struct {
+	issue      gitlab.Issue
+	similarity float64
+}
+
+// findDuplicates searches the project's open issues for ones similar to
+// issue, most similar first. Candidates come from a search for the title's
+// longest keywords and are kept when the overlap of their words with the
+// issue's, in the titles or overall, reaches DEDUP_THRESHOLD percent.
+func (d *Daemon) findDuplicates(issue *gitlab.Issue) ([]duplicateCandidate, error) {
+	titleWords, words := wordSet(issue.Title), wordSet(issue.Title+" "+issue.Description)
+	seen := map[int]bool{issue.IID: true}
+	var candidates []duplicateCandidate
+
+	for _, term := range searchTerms(issue.Title) {
+		issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{
+			State:  "opened",
+			Search: term,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			other := &issues[i]
+			if seen[other.IID] {
+				continue
+			}
+			seen[other.IID] = true
+			similarity := math.Max(jaccard(titleWords, wordSet(other.Title)), jaccard(words, wordSet(other.Title+" "+other.Description)))
+			if similarity*100 >= float64(d.config.Dedup.Threshold) {
+				candidates = append(candidates, duplicateCandidate{issue: *other, similarity: similarity})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].similarity > candidates[j].similarity })
+	if len(candidates) > maxDuplicates {
+		candidates = candidates[:maxDuplicates]
+	}
+	return candidates, nil
+}
+
+// checkDuplicates holds an issue that looks like a duplicate of an open one,
+// reporting whether it did. Issues already labelled DEDUP_LABEL have been
+// looked at by someone who re-added the trigger label, so they go ahead.
+// Search errors are logged and let the issue through.
+func (d *Daemon) checkDuplicates(issue *gitlab.Issue, timestamp string) bool {
+	if !d.config.Dedup.Enabled || issue.HasAnyLabel([]string{d.config.Dedup.Label}) {
+		return false
+	}
+
+	candidates, err := d.findDuplicates(issue)
+	if err != nil {
+		logging.Issue(issue.IID).Warnf("Duplicate check for issue #%d failed: %v", issue.IID, err)
+		return false
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+
+	var list strings.Builder
+	refs := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		fmt.Fprintf(&list, "- #%d %s (%.0f%% similar)\n", candidate.issue.IID, candidate.issue.Title, candidate.similarity*100)
+		refs = append(refs, fmt.Sprintf("#%d", candidate.issue.IID))
+	}
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would hold issue #%d as a possible duplicate of %s", issue.IID, strings.Join(refs, ", "))
+		return true
+	}
+
+	logging.Issue(issue.IID).Infof("Holding issue #%d as a possible duplicate of %s", issue.IID, strings.Join(refs, ", "))
+	d.recordEvent(issue.IID, session.EventFailed, "", "possible duplicate of "+strings.Join(refs, ", "))
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, d.message(locale.MsgPossibleDuplicate, map[string]interface{}{
+		"Candidates":     strings.TrimSuffix(list.String(), "\n"),
+		"DuplicateLabel": d.config.Dedup.Label,
+		"Label":          d.config.Daemon.ClaudeLabel,
+	})); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to post duplicate comment on issue #%d: %v", issue.IID, err)
+	}
+	d.holdIssue(issue.IID, d.config.Dedup.Label)
+	return true
+}
+
+// wordSet returns the distinct significant words of text
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range tokenize(text) {
+		words[word] = true
+	}
+	return words
+}
+
+// searchTerms returns the longest significant words of a title
+func searchTerms(title string) []string {
+	terms := tokenize(title)
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if !seen[term] && len(unique) < duplicateSearchTerms {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// tokenize splits text into lower-case words of three or more letters or
+// digits, without stop words
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) >= 3 && !stopWords[field] {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// jaccard returns the share of the words in either set that are in both
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/errreport"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// reportedOutputLines is how much of a crashed session's output goes with its report
+const reportedOutputLines = 50
+
+// reportSessionFailure sends a failed session to error reporting. The Claude
+// session ID is the correlation ID, so the report matches the daemon log,
+// the audit log and the failure bundle of the session.
+// ref is the issue (#12) or merge request (!34) the session worked on.
+func (d *Daemon) reportSessionFailure(ref, sessionID, kind string, failure claude.FailureKind, errorText string, output []string) {
+	if d.errorReports == nil || d.dryRun || d.semiDryRun {
+		return
+	}
+	if len(output) > reportedOutputLines {
+		output = output[len(output)-reportedOutputLines:]
+	}
+
+	id := d.errorReports.Report(errreport.Event{
+		Kind:          errreport.KindSession,
+		Message:       fmt.Sprintf("%s session failed: %s", kind, failure),
+		CorrelationID: sessionID,
+		Tags: map[string]string{
+			"ref":     d.selectedProject + ref,
+			"project": d.selectedProject,
+			"session": kind,
+			"failure": string(failure),
+			"worker":  d.config.Queue.WorkerID,
+		},
+		Extra: map[string]string{
+			"error":  errorText,
+			"output": strings.Join(output, "\n"),
+		},
+		Fingerprint: []string{errreport.KindSession, kind, string(failure)},
+	})
+	logging.Infof("Reported failed %s session of %s as %s", kind, ref, id)
+}
@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/events"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Resume event kinds, for code where a local variable shadows the session package
+const (
+	eventResumed         = session.EventResumed
+	eventResumeCompleted = session.EventResumeCompleted
+	eventResumeFailed    = session.EventResumeFailed
+	eventDescriptionSync = session.EventDescriptionSync
+	runResume            = session.RunResume
+)
+
+// sessionRun is session.SessionRun, for the same code
+type sessionRun = session.SessionRun
+
+// Events returns the bus the daemon publishes its events on, for programs
+// embedding the daemon to subscribe to
+func (d *Daemon) Events() *events.Bus {
+	return d.events
+}
+
+// subscribe connects the daemon's own consumers to its events: the audit
+// log, telemetry, metrics, tracker sync and error reports. Each reads its component when
+// the event arrives, so components set after New are picked up.
+func (d *Daemon) subscribe() {
+	events.Subscribe(d.events, func(event events.IssueEvent) {
+		d.telemetry.Workflow(event.Kind)
+		d.metrics.Event(event.Kind)
+		d.syncTrackers(event.Event)
+		if eventLog, ok := d.sessionStore.(session.EventLog); ok {
+			if err := eventLog.RecordEvent(event.Event); err != nil {
+				logging.Issue(event.IssueIID).Warnf("Failed to record %s event for issue #%d: %v", event.Kind, event.IssueIID, err)
+			}
+		}
+	})
+
+	events.Subscribe(d.events, func(event events.SessionCompleted) {
+		d.metrics.Session(event.Kind, event.Outcome, event.Duration, event.CostUSD)
+		// Sessions waiting for approval, paused or cancelled have not failed
+		if failure := claude.FailureKind(event.Failure); event.Outcome == session.RunFailed &&
+			failure != claude.FailureApproval && failure != claude.FailurePaused && failure != claude.FailureCancelled {
+			d.telemetry.Error(event.Failure)
+			d.metrics.Error(event.Failure)
+		}
+		if history, ok := d.sessionStore.(session.RunHistory); ok {
+			if err := history.RecordRun(event.SessionRun); err != nil {
+				logging.Issue(event.IssueIID).Warnf("Failed to record session run of issue #%d: %v", event.IssueIID, err)
+			}
+		}
+	})
+
+	events.Subscribe(d.events, func(event events.APIError) {
+		d.errorReports.APIResult(event.Operation, event.Err)
+	})
+}
+
+// recordEvent appends to the issue's audit log when the session store keeps one.
+// Dry runs are not recorded since nothing actually happened.
+func (d *Daemon) recordEvent(issueIID int, kind, sessionID, detail string) {
+	if d.dryRun || d.semiDryRun {
+		return
+	}
+
+	now := time.Now()
+	d.events.Publish(events.IssueEvent{Event: session.Event{
+		IssueIID:    issueIID,
+		ProjectPath: d.selectedProject,
+		Kind:        kind,
+		SessionID:   sessionID,
+		Detail:      detail,
+		Time:        now,
+	}})
+	if kind == session.EventPickedUp {
+		d.events.Publish(events.IssuePickedUp{ProjectPath: d.selectedProject, IssueIID: issueIID, Time: now})
+	}
+}
+
+// sessionStarted announces a Claude session starting on an issue, or on a
+// merge request when mrIID is set
+func (d *Daemon) sessionStarted(issueIID, mrIID int, kind, sessionID string) {
+	if d.dryRun || d.semiDryRun {
+		return
+	}
+	d.events.Publish(events.SessionStarted{
+		ProjectPath:     d.selectedProject,
+		IssueIID:        issueIID,
+		MergeRequestIID: mrIID,
+		Kind:            kind,
+		SessionID:       sessionID,
+		Time:            time.Now(),
+	})
+}
+
+// setIssueLabels replaces the labels of an issue of the selected project
+func (d *Daemon) setIssueLabels(issueIID int, labels []string) error {
+	if err := d.gitlabClient.UpdateIssueLabels(d.selectedProject, issueIID, labels); err != nil {
+		return err
+	}
+	d.events.Publish(events.LabelChanged{ProjectPath: d.selectedProject, IssueIID: issueIID, Labels: labels, Time: time.Now()})
+	return nil
+}
+
+// setMergeRequestLabels replaces the labels of a merge request
+func (d *Daemon) setMergeRequestLabels(mr *gitlab.MergeRequest, labels []string) error {
+	if err := d.gitlabClient.UpdateMergeRequestLabels(mr.ProjectID, mr.IID, labels); err != nil {
+		return err
+	}
+	d.events.Publish(events.LabelChanged{ProjectPath: d.selectedProject, MergeRequestIID: mr.IID, Labels: labels, Time: time.Now()})
+	return nil
+}
+
+// apiFailures remembers which GitLab operations failed last time they ran
+type apiFailures struct {
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+// apiResult publishes an APIError when a GitLab operation fails, and when it
+// succeeds for the first time after failing
+func (d *Daemon) apiResult(operation string, err error) {
+	d.apiFailures.mu.Lock()
+	if d.apiFailures.failing == nil {
+		d.apiFailures.failing = make(map[string]bool)
+	}
+	wasFailing := d.apiFailures.failing[operation]
+	d.apiFailures.failing[operation] = err != nil
+	d.apiFailures.mu.Unlock()
+
+	if err == nil && !wasFailing {
+		return
+	}
+	d.events.Publish(events.APIError{Operation: operation, Err: err, Time: time.Now()})
+}
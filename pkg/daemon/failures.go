@@ -0,0 +1,252 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+const (
+	// rateLimitRetryDelay is the wait before the first retry of a rate-limited
+	// session; later retries back off linearly
+	rateLimitRetryDelay = 15 * time.Minute
+	maxRateLimitRetries = 3
+	// maxContextRetries bounds fresh-session retries after a context overflow
+	maxContextRetries = 1
+)
+
+// failureRetries counts recovery attempts per issue and failure kind
+type failureRetries struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// next records another attempt and returns its number, starting at 1
+func (r *failureRetries) next(issueIID int, kind claude.FailureKind) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+	key := fmt.Sprintf("%d/%s", issueIID, kind)
+	r.counts[key]++
+	return r.counts[key]
+}
+
+// reset forgets the attempts of an issue once it succeeds
+func (r *failureRetries) reset(issueIID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prefix := fmt.Sprintf("%d/", issueIID)
+	for key := range r.counts {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.counts, key)
+		}
+	}
+}
+
+// handleSessionFailure runs the recovery path for a failed issue session. It
+// returns true when a retry was scheduled or the issue was labelled for its
+// failure, in which case it doesn't get the generic error label.
+func (d *Daemon) handleSessionFailure(process *claude.Process) bool {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	issueIID := process.IssueNum
+
+	switch process.Failure {
+	case claude.FailureAuth:
+		d.promptReauth(timestamp)
+		d.postFailureNote(issueIID, d.message(locale.MsgAuthExpired, map[string]interface{}{"Label": d.config.Daemon.ClaudeLabel}))
+
+	case claude.FailureRateLimit:
+		attempt := d.retries.next(issueIID, process.Failure)
+		if attempt > maxRateLimitRetries {
+			break
+		}
+		delay := time.Duration(attempt) * rateLimitRetryDelay
+		logging.Issue(issueIID).Infof("Issue #%d hit a rate limit, retrying in %s (attempt %d/%d)", issueIID, delay, attempt, maxRateLimitRetries)
+		d.postFailureNote(issueIID, d.message(locale.MsgRateLimited, map[string]interface{}{"Delay": delay}))
+		time.AfterFunc(delay, func() {
+			d.survive("retry of issue", issueIID, func() { d.retryIssue(issueIID, process.Failure) })
+		})
+		return true
+
+	case claude.FailureContextOverflow:
+		attempt := d.retries.next(issueIID, process.Failure)
+		if attempt > maxContextRetries {
+			break
+		}
+		// A fresh session starts from the issue prompt alone, without the
+		// transcript that overflowed, and runs with the overflow flags
+		logging.Issue(issueIID).Infof("Issue #%d overflowed the context window, retrying in a fresh session", issueIID)
+		go d.survive("retry of issue", issueIID, func() { d.retryIssue(issueIID, process.Failure) })
+		return true
+
+	case claude.FailureStalled, claude.FailureMaxTurns:
+		reason := fmt.Sprintf("it produced no output for %d minutes", d.config.Daemon.StallTimeout)
+		if process.Failure == claude.FailureMaxTurns {
+			reason = fmt.Sprintf("it ran past the limit of %d turns", d.config.Daemon.MaxTurns)
+		}
+		if process.Nudged {
+			reason += ", even after a continuation prompt"
+		}
+		logging.Issue(issueIID).Infof("Cancelled session for issue #%d: %s", issueIID, reason)
+		d.postFailureNote(issueIID, d.message(locale.MsgSessionCancelled, map[string]interface{}{
+			"Stalled": process.Failure == claude.FailureStalled,
+			"Minutes": d.config.Daemon.StallTimeout,
+			"Turns":   d.config.Daemon.MaxTurns,
+			"Nudged":  process.Nudged,
+			"Stats":   process.Stats().Describe(),
+		}))
+
+	case claude.FailureTimeout:
+		// The diagnostic comment was posted before the session was stopped
+		logging.Issue(issueIID).Infof("Issue #%d ran past the session timeout, labelling it %s", issueIID, d.config.Daemon.TimeoutLabel)
+		d.holdIssue(issueIID, d.config.Daemon.TimeoutLabel)
+		return true
+
+	case claude.FailureMCPConfig:
+		suggestion := mcpRepairSuggestion(process.WorkingDir)
+		logging.Issue(issueIID).Errorf("Issue #%d failed on the MCP configuration:\n%s", issueIID, suggestion)
+		d.postFailureNote(issueIID, d.message(locale.MsgMCPConfig, map[string]interface{}{"Suggestion": suggestion}))
+	}
+
+	return false
+}
+
+// noteSessionTimeout posts what a session was doing when it ran past the
+// session timeout, before it is stopped
+func (d *Daemon) noteSessionTimeout(process *claude.Process) {
+	d.postFailureNote(process.IssueNum, d.message(locale.MsgSessionTimedOut, map[string]interface{}{
+		"Minutes":  d.config.Daemon.SessionTimeout,
+		"Label":    d.config.Daemon.TimeoutLabel,
+		"Attempts": process.Attempts,
+		"Stats":    process.Stats().Describe(),
+	}))
+}
+
+// retryIssue starts a new session for an issue whose previous one failed
+func (d *Daemon) retryIssue(issueIID int, reason claude.FailureKind) {
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to fetch issue #%d for retry: %v", issueIID, err)
+		return
+	}
+	if issue.State != "opened" {
+		logging.Issue(issueIID).Infof("Not retrying issue #%d, it is %s", issueIID, issue.State)
+		return
+	}
+
+	if !d.scheduler.tryAcquire(issueIID, issueTier(issue)) {
+		logging.Issue(issueIID).Infof("Tier %s at capacity, delaying retry of issue #%d", issueTier(issue), issueIID)
+		time.AfterFunc(time.Minute, func() {
+			d.survive("retry of issue", issueIID, func() { d.retryIssue(issueIID, reason) })
+		})
+		return
+	}
+
+	flags := d.config.Claude.Flags
+	if reason == claude.FailureContextOverflow {
+		flags = d.overflowFlags(flags)
+	}
+
+	logging.Issue(issueIID).Infof("Retrying issue #%d after %s", issueIID, reason)
+	if err := d.processIssueAsyncWithFlags(issueIID, flags); err != nil {
+		d.scheduler.release(issueIID)
+		logging.Issue(issueIID).Errorf("Failed to retry issue #%d: %v", issueIID, err)
+		return
+	}
+	d.recordEvent(issueIID, session.EventPickedUp, "", fmt.Sprintf("retry after %s", reason))
+}
+
+// handleResumeFailure runs the recovery path for a failed resume session
+func (d *Daemon) handleResumeFailure(ctx context.Context, s *session.CompletedSession, newComments []gitlab.Note, threads []reviewThread, kind claude.FailureKind) {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+
+	switch kind {
+	case claude.FailureAuth:
+		d.promptReauth(timestamp)
+
+	case claude.FailureRateLimit:
+		attempt := d.retries.next(s.IssueIID, kind)
+		if attempt > maxRateLimitRetries {
+			return
+		}
+		delay := time.Duration(attempt) * rateLimitRetryDelay
+		logging.Issue(s.IssueIID).Infof("Resume for issue #%d hit a rate limit, retrying in %s (attempt %d/%d)", s.IssueIID, delay, attempt, maxRateLimitRetries)
+		time.AfterFunc(delay, func() {
+			defer d.recoverPanic("resume retry of issue", s.IssueIID)
+			if ctx.Err() != nil {
+				return
+			}
+			if err := d.resumeSessionWithCommentsWithContext(ctx, s, newComments, threads); err != nil {
+				logging.Issue(s.IssueIID).Errorf("Failed to retry resume for issue #%d: %v", s.IssueIID, err)
+			}
+		})
+
+	case claude.FailureContextOverflow:
+		attempt := d.retries.next(s.IssueIID, kind)
+		if attempt > maxContextRetries {
+			return
+		}
+		logging.Issue(s.IssueIID).Infof("Resume for issue #%d overflowed the context window, retrying with a trimmed prompt", s.IssueIID)
+		go func() {
+			defer d.recoverPanic("resume retry of issue", s.IssueIID)
+			if err := d.resumeSession(ctx, s, newComments, threads, true); err != nil {
+				logging.Issue(s.IssueIID).Errorf("Failed to start trimmed resume for issue #%d: %v", s.IssueIID, err)
+			}
+		}()
+
+	case claude.FailureMCPConfig:
+		logging.Issue(s.IssueIID).Errorf("Resume for issue #%d failed on the MCP configuration:\n%s",
+			s.IssueIID, mcpRepairSuggestion(s.WorkingDir))
+	}
+}
+
+// promptReauth tells the operator to log the Claude CLI in again
+func (d *Daemon) promptReauth(timestamp string) {
+	logging.Infof("============================================================")
+	logging.Infof("Claude CLI authentication has expired or is invalid.")
+	logging.Infof("Run '%s' and log in (/login) on this host, then re-label failed issues.", d.config.Claude.Command)
+	logging.Infof("============================================================")
+}
+
+// postFailureNote explains a failure on the issue
+func (d *Daemon) postFailureNote(issueIID int, body string) {
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issueIID, body); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to post failure comment on issue #%d: %v", issueIID, err)
+	}
+}
+
+// mcpRepairSuggestion describes how to fix the MCP setup, pointing at a
+// malformed .mcp.json in the working directory when there is one
+func mcpRepairSuggestion(workingDir string) string {
+	var b strings.Builder
+
+	if workingDir != "" {
+		path := filepath.Join(workingDir, ".mcp.json")
+		if content, err := os.ReadFile(path); err == nil {
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(content, &parsed); err != nil {
+				fmt.Fprintf(&b, "- `%s` is not valid JSON: %v\n", path, err)
+			} else if _, ok := parsed["mcpServers"]; !ok {
+				fmt.Fprintf(&b, "- `%s` has no `mcpServers` object\n", path)
+			}
+		}
+	}
+
+	b.WriteString("- Run `claude mcp list` on the daemon host to find the server that fails to start\n")
+	b.WriteString("- Fix its entry or remove it with `claude mcp remove <name>`\n")
+	b.WriteString("- Check the GitLab MCP integration with `automagic -debug-mcp`\n")
+	return b.String()
+}
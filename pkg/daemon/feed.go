@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/feed"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// maxFeedEntries caps the entries of a feed document, whatever its since
+const maxFeedEntries = 200
+
+// eventTitles describes each event kind in the activity feed
+var eventTitles = map[string]string{
+	session.EventPickedUp:         "picked up",
+	session.EventCompleted:        "completed",
+	session.EventFailed:           "failed",
+	session.EventResumed:          "resumed",
+	session.EventResumeCompleted:  "resume completed",
+	session.EventResumeFailed:     "resume failed",
+	session.EventPipeline:         "pipeline finished",
+	session.EventTriaged:          "triaged",
+	session.EventAwaitingApproval: "awaiting approval",
+	session.EventApproved:         "approved",
+	session.EventPaused:           "paused",
+	session.EventUnpaused:         "unpaused",
+	session.EventRebased:          "rebased",
+	session.EventDescriptionSync:  "description updated",
+	session.EventMerged:           "merged",
+	session.EventCILint:           "CI configuration invalid",
+	session.EventTerraformPlan:    "Terraform planned",
+	session.EventMigrationCheck:   "Migrations checked",
+	session.EventClassified:       "classified",
+	session.EventCoverage:         "Coverage measured",
+	session.EventBenchmark:        "Benchmarks compared",
+}
+
+// activityFeed builds the feed of the audit log events recorded at or after
+// since, served next to the control API
+func (d *Daemon) activityFeed(since time.Time) (*feed.Feed, error) {
+	store, ok := d.sessionStore.(session.EventFeed)
+	if !ok {
+		return nil, errors.New("the session store keeps no event log")
+	}
+	recent, err := store.RecentEvents(since, maxFeedEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &feed.Feed{
+		Title: "automagic activity on " + d.selectedProject,
+		Link:  fmt.Sprintf("%s/%s", strings.TrimRight(d.config.GitLab.URL, "/"), d.selectedProject),
+	}
+	for _, event := range recent {
+		title, ok := eventTitles[event.Kind]
+		if !ok {
+			title = strings.ReplaceAll(event.Kind, "_", " ")
+		}
+		content := event.Detail
+		if event.SessionID != "" {
+			content = strings.TrimSpace(content + "\nSession " + event.SessionID)
+		}
+		activity.Entries = append(activity.Entries, feed.Entry{
+			ID:       fmt.Sprintf("%s#%d/%s/%d", activity.Link, event.IssueIID, event.Kind, event.Time.UnixNano()),
+			Title:    fmt.Sprintf("#%d %s", event.IssueIID, title),
+			Link:     d.issueURL(event.ProjectPath, event.IssueIID),
+			Content:  content,
+			Category: event.Kind,
+			Updated:  event.Time,
+		})
+	}
+	return activity, nil
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+)
+
+// Values of FORK_MODE
+const (
+	forkModeAuto   = "auto"   // Fork only when the bot cannot push to the project
+	forkModeAlways = "always" // Always push issue branches to a fork
+	forkModeNever  = "never"  // Always push to the project itself
+)
+
+// issueFork returns the fork issue branches of the selected project should be
+// pushed to, forking the project if needed, or "" when they go to the project
+// itself
+func (d *Daemon) issueFork() (string, error) {
+	switch d.config.GitLab.ForkMode {
+	case forkModeNever:
+		return "", nil
+	case forkModeAlways:
+	default:
+		project, err := d.gitlabClient.GetProject(strings.ReplaceAll(d.selectedProject, "/", "%2F"))
+		if err != nil {
+			return "", fmt.Errorf("failed to check push access: %v", err)
+		}
+		if project.AccessLevel() >= gitlab.DeveloperAccess {
+			return "", nil
+		}
+	}
+
+	fork, err := d.gitlabClient.EnsureFork(d.selectedProject, d.config.GitLab.ForkNamespace)
+	if err != nil {
+		return "", err
+	}
+	return fork.PathWithNamespace, nil
+}
@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// Kinds of issues automagic files on its own; ISSUE_TEMPLATES can give each
+// kind its own description template
+const (
+	issueKindFollowUp  = "follow_up"
+	issueKindTriage    = "triage"
+	issueKindFlakyTest = "flaky_test"
+	issueKindScaffold  = "scaffold"
+)
+
+// templatePlaceholder marks where generated content goes in a description
+// template; without it the content is placed above the template
+const templatePlaceholder = "<!-- automagic -->"
+
+// createIssue files an issue generated by automagic in the selected project.
+// The description is laid out with the project's description template for
+// the kind of issue, and the issue carries ISSUE_LABELS plus labels. Dry runs
+// only print the issue and return nil.
+func (d *Daemon) createIssue(kind, title, body string, labels ...string) (*gitlab.Issue, error) {
+	return d.createProjectIssue(d.selectedProject, kind, title, body, labels...)
+}
+
+// createProjectIssue is createIssue for another project, using that
+// project's description templates
+func (d *Daemon) createProjectIssue(projectPath, kind, title, body string, labels ...string) (*gitlab.Issue, error) {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+
+	issue := gitlab.NewIssue{
+		Title:       title,
+		Description: d.applyIssueTemplate(projectPath, kind, body, timestamp),
+		Labels:      mergeLabels(d.config.Issues.Labels, labels),
+	}
+
+	if d.dryRun || d.semiDryRun {
+		logging.Infof("DRY RUN: Would create %s issue %q with labels %v", kind, issue.Title, issue.Labels)
+		return nil, nil
+	}
+
+	created, err := d.gitlabClient.CreateIssue(projectPath, issue)
+	if err != nil {
+		return nil, err
+	}
+	logging.Issue(created.IID).Infof("Created %s issue #%d: %s", kind, created.IID, created.Title)
+	return created, nil
+}
+
+// applyIssueTemplate fills the description template of a project configured
+// for kind with body. Quick actions in the template, like /label, are left for GitLab to
+// apply. A missing template leaves body unchanged.
+func (d *Daemon) applyIssueTemplate(projectPath, kind, body, timestamp string) string {
+	name, ok := d.config.Issues.Templates[kind]
+	if !ok {
+		name = d.config.Issues.Template
+	}
+	if name == "" {
+		return body
+	}
+
+	template, err := d.gitlabClient.GetIssueTemplate(projectPath, name)
+	if err != nil {
+		logging.Warnf("Failed to fetch issue template %q: %v", name, err)
+		return body
+	}
+	if template == nil {
+		return body
+	}
+
+	if strings.Contains(template.Content, templatePlaceholder) {
+		return strings.Replace(template.Content, templatePlaceholder, body, 1)
+	}
+	return body + "\n\n" + template.Content
+}
+
+// mergeLabels joins label lists, dropping duplicates but keeping order
+func mergeLabels(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, label := range list {
+			if !seen[label] {
+				seen[label] = true
+				merged = append(merged, label)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Limits of the related work listed in a prompt
+const (
+	relatedMinSimilarity = 0.2 // Title word overlap a past issue needs to be listed
+	relatedMaxFiles      = 5   // Files listed per past session
+)
+
+// recordSummary indexes what a completed issue session changed: the files on
+// its branch, its merge request and the issue's title and labels
+func (d *Daemon) recordSummary(process *claude.Process, issue *gitlab.Issue, branch string) {
+	kb, ok := d.sessionStore.(session.KnowledgeBase)
+	if !ok || !d.config.KnowledgeBase.Enabled {
+		return
+	}
+
+	summary := session.SessionSummary{
+		IssueIID:    issue.IID,
+		ProjectPath: d.selectedProject,
+		Title:       issue.Title,
+		CompletedAt: time.Now(),
+	}
+	for _, label := range issue.Labels {
+		if label != d.config.Daemon.ClaudeLabel && label != d.config.Daemon.ProcessLabel && label != d.config.Daemon.ReviewLabel {
+			summary.Labels = append(summary.Labels, label)
+		}
+	}
+
+	files, err := changedFiles(process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to list the files changed for issue #%d: %v", issue.IID, err)
+	}
+	summary.Files = files
+	if mr, err := d.issueMergeRequest(d.selectedProject, branch); err == nil && mr != nil {
+		summary.MergeRequestIID = mr.IID
+	}
+
+	if err := kb.RecordSummary(summary); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to index session of issue #%d: %v", issue.IID, err)
+	}
+}
+
+// changedFiles lists the files a branch changed since it left the default branch
+func changedFiles(repoDir, branch string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "origin/HEAD..."+branch)
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// relatedWork lists the completed sessions of the selected project whose
+// issues look most like issue, at most KNOWLEDGE_BASE_LIMIT, one markdown list
+// item each. Issues are compared by their title words, and shared labels
+// break ties. It returns "" when nothing is similar enough.
+func (d *Daemon) relatedWork(issue *gitlab.Issue) string {
+	kb, ok := d.sessionStore.(session.KnowledgeBase)
+	if !ok {
+		return ""
+	}
+	summaries, err := kb.GetSummaries(d.selectedProject)
+	if err != nil {
+		logging.Warnf("Failed to read the knowledge base: %v", err)
+		return ""
+	}
+
+	titleWords, labels := wordSet(issue.Title), labelSet(issue.Labels)
+	type related struct {
+		summary session.SessionSummary
+		score   float64
+	}
+	var matches []related
+	for _, summary := range summaries {
+		if summary.IssueIID == issue.IID {
+			continue
+		}
+		similarity := jaccard(titleWords, wordSet(summary.Title))
+		if similarity < relatedMinSimilarity {
+			continue
+		}
+		matches = append(matches, related{summary, similarity + jaccard(labels, labelSet(summary.Labels))/2})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > d.config.KnowledgeBase.Limit {
+		matches = matches[:d.config.KnowledgeBase.Limit]
+	}
+
+	var list strings.Builder
+	for _, match := range matches {
+		s := match.summary
+		fmt.Fprintf(&list, "- #%d %s", s.IssueIID, s.Title)
+		if s.MergeRequestIID > 0 {
+			fmt.Fprintf(&list, " (merge request !%d)", s.MergeRequestIID)
+		}
+		if len(s.Files) > 0 {
+			files := s.Files
+			if len(files) > relatedMaxFiles {
+				files = files[:relatedMaxFiles]
+			}
+			fmt.Fprintf(&list, " touched `%s`", strings.Join(files, "`, `"))
+			if more := len(s.Files) - len(files); more > 0 {
+				fmt.Fprintf(&list, " and %d more files", more)
+			}
+		}
+		list.WriteString("\n")
+	}
+	return strings.TrimSuffix(list.String(), "\n")
+}
+
+// labelSet returns the distinct labels of an issue in lower case
+func labelSet(labels []string) map[string]bool {
+	set := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		set[strings.ToLower(label)] = true
+	}
+	return set
+}
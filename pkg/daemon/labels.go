@@ -0,0 +1,297 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Limits of label learning
+const (
+	labelModelTTL     = time.Hour // How long a project's learned associations are reused
+	labelHistoryPages = 5         // Pages of 100 closed issues learned from
+	labelMinSupport   = 3         // Closed issues a word or label needs to count as evidence
+	labelEvidence     = 3         // Strongest associations averaged into a confidence
+)
+
+// labelModel holds how often the words and labels of a project's closed
+// issues occur together with each suggestible label
+type labelModel struct {
+	builtAt time.Time
+	counts  map[string]int            // Closed issues per feature
+	labels  map[string]map[string]int // Closed issues per feature and suggestible label
+}
+
+// labelModelCache keeps the learned model of each project
+type labelModelCache struct {
+	mu     sync.Mutex
+	models map[string]*labelModel
+}
+
+// labelSuggestion is a label with the confidence that it applies to an issue
+type labelSuggestion struct {
+	label      string
+	confidence float64
+}
+
+// issueFeatures returns the words and, as "label:<name>", the labels of an
+// issue, leaving out the daemon's own workflow labels
+func (d *Daemon) issueFeatures(issue *gitlab.Issue) map[string]bool {
+	features := wordSet(issue.Title + " " + issue.Description)
+	for _, label := range issue.Labels {
+		if label != d.config.Daemon.ClaudeLabel && label != d.config.Daemon.ProcessLabel && label != d.config.Daemon.ReviewLabel {
+			features["label:"+label] = true
+		}
+	}
+	return features
+}
+
+// suggestible reports whether a label starts with one of LABEL_SUGGEST_PREFIXES
+func (d *Daemon) suggestible(label string) bool {
+	for _, prefix := range d.config.LabelSuggest.Prefixes {
+		if strings.HasPrefix(label, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelModel returns the selected project's model, learning it again from
+// the most recent closed issues once it is older than labelModelTTL
+func (d *Daemon) labelModel() (*labelModel, error) {
+	d.labelModels.mu.Lock()
+	defer d.labelModels.mu.Unlock()
+	if model, ok := d.labelModels.models[d.selectedProject]; ok && time.Since(model.builtAt) < labelModelTTL {
+		return model, nil
+	}
+
+	model := &labelModel{builtAt: time.Now(), counts: make(map[string]int), labels: make(map[string]map[string]int)}
+	for page := 1; page <= labelHistoryPages; page++ {
+		issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{State: "closed", Page: page})
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			features := d.issueFeatures(&issues[i])
+			for feature := range features {
+				model.counts[feature]++
+				for _, label := range issues[i].Labels {
+					if !d.suggestible(label) {
+						continue
+					}
+					if model.labels[feature] == nil {
+						model.labels[feature] = make(map[string]int)
+					}
+					model.labels[feature][label]++
+				}
+			}
+		}
+		if len(issues) < 100 {
+			break
+		}
+	}
+
+	if d.labelModels.models == nil {
+		d.labelModels.models = make(map[string]*labelModel)
+	}
+	d.labelModels.models[d.selectedProject] = model
+	return model, nil
+}
+
+// suggest scores the suggestible labels an issue does not have yet, most
+// confident first. A label's confidence is the mean of its labelEvidence
+// strongest associations with the issue's words and labels, each the share of
+// closed issues with that word or label that also carried it. Labels in a
+// scope (scope::name) the issue already has a label of are left out, since
+// GitLab allows one label per scope.
+func (m *labelModel) suggest(features map[string]bool, has []string) []labelSuggestion {
+	taken := make(map[string]bool)
+	for _, label := range has {
+		taken[label] = true
+		if scope := labelScope(label); scope != "" {
+			taken[scope] = true
+		}
+	}
+
+	evidence := make(map[string][]float64)
+	for feature := range features {
+		count := m.counts[feature]
+		if count < labelMinSupport {
+			continue
+		}
+		for label, together := range m.labels[feature] {
+			evidence[label] = append(evidence[label], float64(together)/float64(count))
+		}
+	}
+
+	var suggestions []labelSuggestion
+	for label, shares := range evidence {
+		if scope := labelScope(label); taken[label] || (scope != "" && taken[scope]) {
+			continue
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(shares)))
+		if len(shares) > labelEvidence {
+			shares = shares[:labelEvidence]
+		}
+		total := 0.0
+		for _, share := range shares {
+			total += share
+		}
+		// Thin evidence counts as missing associations rather than strong ones
+		suggestions = append(suggestions, labelSuggestion{label: label, confidence: total / labelEvidence})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].confidence != suggestions[j].confidence {
+			return suggestions[i].confidence > suggestions[j].confidence
+		}
+		return suggestions[i].label < suggestions[j].label
+	})
+	return suggestions
+}
+
+// suggestLabels labels a picked up issue from the labels of similar closed
+// issues. Suggestions at LABEL_SUGGEST_APPLY percent confidence are added to
+// the issue, and to issue.Labels so later label updates keep them; those from
+// LABEL_SUGGEST_MIN are proposed in a comment. Each issue is triaged once.
+func (d *Daemon) suggestLabels(issue *gitlab.Issue, timestamp string) {
+	if !d.config.LabelSuggest.Enabled || d.hasEvent(issue.IID, session.EventTriaged) {
+		return
+	}
+	model, err := d.labelModel()
+	if err != nil {
+		logging.Warnf("Failed to learn labels of %s: %v", d.selectedProject, err)
+		return
+	}
+
+	var apply, propose []labelSuggestion
+	for _, suggestion := range model.suggest(d.issueFeatures(issue), issue.Labels) {
+		switch percent := suggestion.confidence * 100; {
+		case percent >= float64(d.config.LabelSuggest.Apply):
+			apply = append(apply, suggestion)
+		case percent >= float64(d.config.LabelSuggest.Min):
+			propose = append(propose, suggestion)
+		}
+	}
+	// Applied labels take their scope; later ones in it would replace them
+	apply = oneLabelPerScope(apply)
+	propose = outsideScopes(propose, apply)
+	if len(apply) == 0 && len(propose) == 0 {
+		return
+	}
+
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would add labels %s and propose %s on issue #%d",
+			suggestionNames(apply), suggestionNames(propose), issue.IID)
+		return
+	}
+
+	if len(apply) > 0 {
+		labels := append([]string{}, issue.Labels...)
+		for _, suggestion := range apply {
+			labels = append(labels, suggestion.label)
+		}
+		if err := d.setIssueLabels(issue.IID, labels); err != nil {
+			logging.Issue(issue.IID).Warnf("Failed to add suggested labels to issue #%d: %v", issue.IID, err)
+			propose = append(apply, propose...)
+			apply = nil
+		} else {
+			issue.Labels = labels
+			logging.Issue(issue.IID).Infof("Added labels %s to issue #%d", suggestionNames(apply), issue.IID)
+		}
+	}
+
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, d.message(locale.MsgLabelSuggestions, map[string]interface{}{
+		"Applied":  formatSuggestions(apply),
+		"Proposed": formatSuggestions(propose),
+	})); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to post label suggestions on issue #%d: %v", issue.IID, err)
+	}
+	d.recordEvent(issue.IID, session.EventTriaged, "", "added "+suggestionNames(apply)+"; proposed "+suggestionNames(propose))
+}
+
+// hasEvent reports whether an event of kind was recorded for an issue
+func (d *Daemon) hasEvent(issueIID int, kind string) bool {
+	eventLog, ok := d.sessionStore.(session.EventLog)
+	if !ok {
+		return false
+	}
+	events, err := eventLog.GetEvents(issueIID)
+	if err != nil {
+		return false
+	}
+	for _, event := range events {
+		if event.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// oneLabelPerScope keeps the most confident label of each scope
+func oneLabelPerScope(suggestions []labelSuggestion) []labelSuggestion {
+	seen := make(map[string]bool)
+	kept := suggestions[:0]
+	for _, suggestion := range suggestions {
+		if scope := labelScope(suggestion.label); scope != "" {
+			if seen[scope] {
+				continue
+			}
+			seen[scope] = true
+		}
+		kept = append(kept, suggestion)
+	}
+	return kept
+}
+
+// labelScope returns the scope of a scoped label, "area::" for area::auth,
+// or "" for an unscoped one
+func labelScope(label string) string {
+	if idx := strings.LastIndex(label, "::"); idx >= 0 {
+		return label[:idx+2]
+	}
+	return ""
+}
+
+// outsideScopes drops the suggestions in a scope one of taken is in
+func outsideScopes(suggestions, taken []labelSuggestion) []labelSuggestion {
+	scopes := make(map[string]bool)
+	for _, suggestion := range taken {
+		scopes[labelScope(suggestion.label)] = true
+	}
+	var kept []labelSuggestion
+	for _, suggestion := range suggestions {
+		if scope := labelScope(suggestion.label); scope == "" || !scopes[scope] {
+			kept = append(kept, suggestion)
+		}
+	}
+	return kept
+}
+
+// formatSuggestions lists suggestions with their confidence as markdown list
+// items
+func formatSuggestions(suggestions []labelSuggestion) string {
+	var list strings.Builder
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(&list, "- `%s` (%.0f%%)\n", suggestion.label, suggestion.confidence*100)
+	}
+	return strings.TrimSuffix(list.String(), "\n")
+}
+
+// suggestionNames joins the labels of suggestions for logs, or returns "none"
+func suggestionNames(suggestions []labelSuggestion) string {
+	if len(suggestions) == 0 {
+		return "none"
+	}
+	names := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		names[i] = suggestion.label
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// SetProject chooses the project to monitor, so Run and RunWithoutMemory
+// skip selecting one at startup
+func (d *Daemon) SetProject(projectPath string) {
+	d.useProject(projectPath)
+}
+
+// HandleSignals sets whether the daemon stops on SIGINT and SIGTERM, which it
+// does by default. Programs embedding the daemon turn it off and call Stop.
+func (d *Daemon) HandleSignals(enabled bool) {
+	d.ignoreSignals = !enabled
+}
+
+// Stop makes Run, RunWithoutMemory or RunWorker stop the running sessions and
+// return, as on SIGTERM. It returns right away and may be called more than once.
+func (d *Daemon) Stop() {
+	d.stopOnce.Do(func() { close(d.stopCh) })
+}
+
+// EnqueueIssue asks the daemon to work on an issue of its project whether or
+// not it carries the trigger label. A polling cycle starts right away to pick
+// it up; an issue already being worked on is left alone. Workers ignore it,
+// since they only run what the coordinator queues.
+func (d *Daemon) EnqueueIssue(issueIID int) {
+	d.keepEnqueued(issueIID)
+	d.wake(workflowIssues)
+}
+
+// keepEnqueued adds an issue to those the next cycle starts
+func (d *Daemon) keepEnqueued(issueIID int) {
+	d.wakeMu.Lock()
+	defer d.wakeMu.Unlock()
+	if d.enqueuedIssues == nil {
+		d.enqueuedIssues = make(map[int]bool)
+	}
+	d.enqueuedIssues[issueIID] = true
+}
+
+// runContext returns the context the daemon's loops run under, cancelled by
+// Stop and, unless signal handling is off, SIGINT or SIGTERM
+func (d *Daemon) runContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.runCtx = ctx
+
+	sigCh := make(chan os.Signal, 1)
+	if !d.ignoreSignals {
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	}
+
+	go func() {
+		select {
+		case <-sigCh:
+			logging.Infof("Received shutdown signal. Cancelling operations...")
+		case <-d.stopCh:
+			logging.Infof("Shutdown requested. Cancelling operations...")
+		case <-ctx.Done():
+			return
+		}
+		cancel()
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
+}
+
+// shutdownContext returns the context of the running loop, cancelled on
+// shutdown, for work the loop does not wait on such as completion callbacks
+func (d *Daemon) shutdownContext() context.Context {
+	if d.runCtx == nil {
+		return context.Background()
+	}
+	return d.runCtx
+}
+
+// chooseProject selects the project to monitor among the accessible ones,
+// unless SetProject already chose it
+func (d *Daemon) chooseProject() error {
+	if d.selectedProject != "" {
+		logging.Infof("Project: %s", d.selectedProject)
+		return nil
+	}
+
+	logging.Infof("=== Project Selection for Daemon Mode ===")
+	projects, err := d.gitlabClient.GetAccessibleProjects()
+	if err != nil {
+		return fmt.Errorf("error fetching projects: %v", err)
+	}
+
+	selectedProject, err := d.selectProject(projects)
+	if err != nil {
+		return fmt.Errorf("error selecting project: %v", err)
+	}
+
+	d.useProject(selectedProject.PathWithNamespace)
+	logging.Infof("Project selected: %s", d.selectedProject)
+	return nil
+}
+
+// startEnqueuedIssues starts the issues passed to EnqueueIssue, returning how
+// many started. Issues deferred for capacity stay queued for the next cycle.
+func (d *Daemon) startEnqueuedIssues(ctx context.Context, processedIssues *processedSet) int {
+	d.wakeMu.Lock()
+	enqueued := d.enqueuedIssues
+	d.enqueuedIssues = nil
+	d.wakeMu.Unlock()
+
+	started := 0
+	for issueIID := range enqueued {
+		if ctx.Err() != nil {
+			d.keepEnqueued(issueIID)
+			continue
+		}
+		if d.scheduler.holds(issueIID) {
+			logging.Issue(issueIID).Infof("Issue #%d is already being worked on", issueIID)
+			continue
+		}
+
+		issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+		if err != nil {
+			logging.Issue(issueIID).Errorf("Failed to fetch enqueued issue #%d: %v", issueIID, err)
+			continue
+		}
+
+		processedIssues.add(issueIID)
+		logging.Issue(issueIID).Infof("Starting enqueued issue #%d: %s", issueIID, issue.Title)
+		if err := d.processIssueWithLabelUpdate(issue); err != nil {
+			if errors.Is(err, errOutsideWorkingHours) {
+				d.keepEnqueued(issueIID)
+				continue
+			}
+			if errors.Is(err, errTierAtCapacity) {
+				logging.Issue(issueIID).Infof("Deferring issue #%d: tier %s at capacity", issueIID, issueTier(issue))
+				d.keepEnqueued(issueIID)
+				continue
+			}
+			logging.Issue(issueIID).Errorf("Failed to start processing issue #%d: %v", issueIID, err)
+			continue
+		}
+		started++
+	}
+	return started
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// recordLink records the branch an issue's session pushed and the merge
+// request opened from it, for "automagic open". A link whose merge request
+// cannot be found yet keeps the one recorded before.
+func (d *Daemon) recordLink(issueIID int, projectPath, forkPath, branch string) {
+	tracker, ok := d.sessionStore.(session.LinkTracker)
+	if !ok {
+		return
+	}
+
+	link := session.IssueLink{
+		IssueIID:    issueIID,
+		ProjectPath: projectPath,
+		Branch:      branch,
+		ForkPath:    forkPath,
+		UpdatedAt:   time.Now(),
+	}
+	mergeRequests, err := d.gitlabClient.GetMergeRequestsForBranch(projectPath, branch, "")
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to look up MR for issue #%d: %v", issueIID, err)
+	}
+	if len(mergeRequests) > 0 {
+		link.MergeRequestIID = mergeRequests[0].IID
+		link.MergeRequestURL = mergeRequests[0].WebURL
+		link.MergeRequestState = mergeRequests[0].State
+	} else if previous, exists := tracker.GetIssueLink(issueIID); exists && previous.Branch == branch {
+		link.MergeRequestIID = previous.MergeRequestIID
+		link.MergeRequestURL = previous.MergeRequestURL
+		link.MergeRequestState = previous.MergeRequestState
+		link.PipelineStatus = previous.PipelineStatus
+	}
+
+	if err := tracker.SetIssueLink(link); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to record links of issue #%d: %v", issueIID, err)
+	}
+}
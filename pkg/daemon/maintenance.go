@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// maintenanceInterval is how often the daemon checks whether today's backup
+// of the session store has been taken and prunes expired sessions
+const maintenanceInterval = time.Hour
+
+// maintenanceLoop keeps rolling daily backups of the session store and prunes
+// sessions and transcripts past their retention until ctx is done
+func (d *Daemon) maintenanceLoop(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		d.rotateBackups()
+		d.pruneSessions()
+		d.pruneTranscripts()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// mirrorCheckInterval is how often project mirrors are checked for a due refresh
+const mirrorCheckInterval = time.Minute
+
+// mirrorLoop fetches into project mirrors as their refresh intervals pass
+// until ctx is done
+func (d *Daemon) mirrorLoop(ctx context.Context) {
+	mirrors := claude.ActiveMirrors()
+	if mirrors == nil {
+		return
+	}
+	ticker := time.NewTicker(mirrorCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		mirrors.RefreshStale()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rotateBackups takes today's backup of the session store if it is missing
+func (d *Daemon) rotateBackups() {
+	rotator, ok := d.sessionStore.(session.BackupRotator)
+	if !ok || d.config.Database.BackupRetain <= 0 {
+		return
+	}
+
+	if path, err := rotator.RotateBackups(d.config.Database.BackupRetain); err != nil {
+		logging.Warnf("Failed to back up the session store: %v", err)
+	} else if path != "" {
+		logging.Infof("Backed up the session store to %s", path)
+	}
+}
+
+// retentionPolicy returns the configured session retention
+func (d *Daemon) retentionPolicy() session.RetentionPolicy {
+	return session.RetentionPolicy{
+		Succeeded: time.Duration(d.config.Database.RetainSucceededDays) * 24 * time.Hour,
+		Failed:    time.Duration(d.config.Database.RetainFailedDays) * 24 * time.Hour,
+	}
+}
+
+// pruneSessions removes sessions past their retention. Sessions whose issue is
+// still in review are kept whatever their age, since a review comment may
+// still resume them.
+func (d *Daemon) pruneSessions() {
+	pruner, ok := d.sessionStore.(session.SessionPruner)
+	if !ok || d.dryRun || d.semiDryRun {
+		return
+	}
+
+	removed, err := pruner.CleanupOldSessions(d.retentionPolicy(), d.keepExpiredSession)
+	if err != nil {
+		logging.Warnf("Failed to prune old sessions: %v", err)
+	}
+	if removed > 0 {
+		logging.Infof("Pruned %d sessions past their retention", removed)
+	}
+}
+
+// keepExpiredSession reports whether an expired session must be kept because
+// its issue still carries the review label or has an open merge request.
+// Deleting those would silently break the comment-triggered resume of a long
+// review. Lookup errors keep the session.
+func (d *Daemon) keepExpiredSession(s *session.CompletedSession) bool {
+	issue, err := d.gitlabClient.GetIssue(s.ProjectPath, s.IssueIID)
+	if err != nil {
+		logging.Issue(s.IssueIID).Warnf("Keeping session for issue #%d, failed to fetch the issue: %v", s.IssueIID, err)
+		return true
+	}
+	if issue.HasAnyLabel([]string{d.config.Daemon.ReviewLabel}) {
+		logging.Issue(s.IssueIID).Infof("Keeping expired session for issue #%d, it is still labeled %s", s.IssueIID, d.config.Daemon.ReviewLabel)
+		return true
+	}
+
+	mergeRequests, err := d.gitlabClient.GetMergeRequestsForBranch(s.ProjectPath, sessionBranch(s), "opened")
+	if err != nil {
+		logging.Issue(s.IssueIID).Warnf("Keeping session for issue #%d, failed to check its merge requests: %v", s.IssueIID, err)
+		return true
+	}
+	if len(mergeRequests) > 0 {
+		logging.Issue(s.IssueIID).Infof("Keeping expired session for issue #%d, merge request !%d is still open", s.IssueIID, mergeRequests[0].IID)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// mentionLine returns a line @-mentioning the participants of an issue who
+// have not opted out of bot pings, "" when mentions are off or nobody is left
+func (d *Daemon) mentionLine(issueIID int) string {
+	if !d.config.Mentions.Participants {
+		return ""
+	}
+	participants, err := d.gitlabClient.GetIssueParticipants(d.selectedProject, issueIID)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to list participants of issue #%d: %v", issueIID, err)
+		return ""
+	}
+
+	var mentions []string
+	for _, user := range participants {
+		if d.mentionable(user) {
+			mentions = append(mentions, "@"+user.Username)
+		}
+	}
+	if len(mentions) == 0 {
+		return ""
+	}
+	return "cc " + strings.Join(mentions, " ")
+}
+
+// mentionable reports whether a participant may be @-mentioned: an active
+// user other than the bot, not in MENTION_OPT_OUT and without the opt-out
+// marker in their profile bio
+func (d *Daemon) mentionable(user gitlab.User) bool {
+	if user.State != "" && user.State != "active" {
+		return false
+	}
+	if d.gitlabClient.Users().IsCurrentUser(user.Username) {
+		return false
+	}
+	for _, username := range d.config.Mentions.OptOut {
+		if strings.EqualFold(strings.TrimPrefix(username, "@"), user.Username) {
+			return false
+		}
+	}
+
+	marker := d.config.Mentions.OptOutMarker
+	if marker == "" {
+		return true
+	}
+	bio, err := d.gitlabClient.Users().Bio(user.ID)
+	if err != nil {
+		// Staying quiet is the safer mistake
+		logging.Debugf("Not mentioning @%s, failed to read their profile: %v", user.Username, err)
+		return false
+	}
+	return !strings.Contains(strings.ToLower(bio), strings.ToLower(marker))
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// mergeSyncLoop follows the merge requests linked to issues every
+// MERGE_SYNC_INTERVAL seconds until ctx is done
+func (d *Daemon) mergeSyncLoop(ctx context.Context) {
+	tracker, ok := d.sessionStore.(session.LinkTracker)
+	if !ok || d.config.MergeSync.Interval <= 0 || d.dryRun || d.semiDryRun {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(d.config.MergeSync.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.apiResult("Syncing merge requests", d.syncMergeRequests(ctx, tracker))
+	}
+}
+
+// syncMergeRequests refreshes the merge request state and pipeline status of
+// the open issue links, and marks the issues whose merge request was merged
+// as solved. It returns the last GitLab error, after trying every link.
+func (d *Daemon) syncMergeRequests(ctx context.Context, tracker session.LinkTracker) error {
+	links, err := tracker.OpenIssueLinks()
+	if err != nil {
+		logging.Warnf("Failed to load issue links: %v", err)
+		return nil
+	}
+
+	var lastErr error
+	for _, link := range links {
+		if ctx.Err() != nil {
+			break
+		}
+
+		mr, err := d.linkedMergeRequest(&link)
+		if err != nil {
+			logging.Issue(link.IssueIID).Warnf("Failed to fetch MR of issue #%d: %v", link.IssueIID, err)
+			lastErr = err
+			continue
+		}
+		if mr == nil {
+			continue
+		}
+
+		pipelineStatus := ""
+		if mr.HeadPipeline != nil {
+			pipelineStatus = mr.HeadPipeline.Status
+		}
+		if mr.IID == link.MergeRequestIID && mr.State == link.MergeRequestState && pipelineStatus == link.PipelineStatus {
+			continue
+		}
+		link.MergeRequestIID = mr.IID
+		link.MergeRequestURL = mr.WebURL
+		link.MergeRequestState = mr.State
+		link.PipelineStatus = pipelineStatus
+		link.UpdatedAt = time.Now()
+		if err := tracker.SetIssueLink(link); err != nil {
+			logging.Issue(link.IssueIID).Warnf("Failed to record MR state of issue #%d: %v", link.IssueIID, err)
+		}
+
+		if mr.State == "merged" {
+			d.solveIssue(link.IssueIID, mr)
+		}
+	}
+	return lastErr
+}
+
+// linkedMergeRequest returns the merge request of a link, looking it up by
+// branch when none was found when the session ended. It returns nil when the
+// branch has no merge request yet.
+func (d *Daemon) linkedMergeRequest(link *session.IssueLink) (*gitlab.MergeRequest, error) {
+	mergeRequestIID := link.MergeRequestIID
+	if mergeRequestIID == 0 {
+		mergeRequests, err := d.gitlabClient.GetMergeRequestsForBranch(link.ProjectPath, link.Branch, "")
+		if err != nil || len(mergeRequests) == 0 {
+			return nil, err
+		}
+		mergeRequestIID = mergeRequests[0].IID
+	}
+	// Only a single merge request carries its head pipeline
+	return d.gitlabClient.GetMergeRequest(link.ProjectPath, mergeRequestIID)
+}
+
+// solveIssue replaces the workflow labels of an issue whose merge request was
+// merged with SOLVED_LABEL, closing it when CLOSE_ON_MERGE is set
+func (d *Daemon) solveIssue(issueIID int, mr *gitlab.MergeRequest) {
+	logging.Issue(issueIID).Infof("MR !%d of issue #%d was merged, marking it %s", mr.IID, issueIID, d.config.MergeSync.SolvedLabel)
+	d.swapLabels(issueIID, []string{d.config.Daemon.ClaudeLabel, d.config.Daemon.ProcessLabel, d.config.Daemon.ReviewLabel}, d.config.MergeSync.SolvedLabel)
+	d.recordEvent(issueIID, session.EventMerged, "", fmt.Sprintf("!%d", mr.IID))
+
+	if !d.config.MergeSync.CloseIssue {
+		return
+	}
+	if err := d.gitlabClient.EditIssue(d.selectedProject, issueIID, gitlab.IssueEdit{StateEvent: "close"}); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to close issue #%d: %v", issueIID, err)
+		return
+	}
+	logging.Issue(issueIID).Infof("Closed issue #%d", issueIID)
+}
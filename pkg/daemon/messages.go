@@ -0,0 +1,16 @@
+package daemon
+
+import "github.com/bilbo290/automagic/pkg/locale"
+
+// language returns the comment language configured for the selected project
+func (d *Daemon) language() string {
+	if language, ok := d.config.Locale.Projects[d.selectedProject]; ok {
+		return language
+	}
+	return d.config.Locale.Default
+}
+
+// message renders a bot comment in the project's language
+func (d *Daemon) message(id string, data map[string]interface{}) string {
+	return locale.Message(d.language(), id, data)
+}
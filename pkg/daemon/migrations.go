@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/migrations"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// checkIssueMigrations runs the migrations a completed issue session added
+// against a disposable database, in a worktree of its branch. It returns the
+// results for the completion comment, "" when the session added none.
+func (d *Daemon) checkIssueMigrations(process *claude.Process, branch string) string {
+	if !d.config.MigrationCheck.Enabled || d.dryRun || d.semiDryRun {
+		return ""
+	}
+
+	added, err := addedFiles(process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to list the files added for issue #%d: %v", process.IssueNum, err)
+		return ""
+	}
+	// Every tool checked writes its migrations in SQL
+	hasSQL := false
+	for _, file := range added {
+		hasSQL = hasSQL || strings.HasSuffix(file, ".sql")
+	}
+	if !hasSQL {
+		return ""
+	}
+	checkout, remove, err := d.branchWorktree(process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to check out %s for the migration check of issue #%d: %v", branch, process.IssueNum, err)
+		return ""
+	}
+	defer remove()
+	sets := migrations.Detect(checkout, added)
+	if len(sets) == 0 {
+		return ""
+	}
+
+	checker := &migrations.Checker{
+		Image:   d.config.MigrationCheck.Image,
+		Timeout: time.Duration(d.config.MigrationCheck.Timeout) * time.Minute,
+	}
+	var lines []string
+	failed := 0
+	for _, set := range sets {
+		logging.Issue(process.IssueNum).Infof("Checking %d %s migrations in %s for issue #%d", len(set.Added), set.Tool, set.Dir, process.IssueNum)
+		result := checker.Check(checkout, set)
+		if result.Failed != "" {
+			failed++
+		}
+		lines = append(lines, formatMigrationResult(result))
+	}
+	d.recordEvent(process.IssueNum, session.EventMigrationCheck, process.ClaudeSessionID, fmt.Sprintf("%d migration directories checked, %d failed", len(sets), failed))
+
+	return d.message(locale.MsgMigrationCheck, map[string]interface{}{
+		"Image":   d.config.MigrationCheck.Image,
+		"Results": strings.Join(lines, "\n"),
+	})
+}
+
+// formatMigrationResult renders a migration check as a markdown list item
+func formatMigrationResult(result migrations.Result) string {
+	set := fmt.Sprintf("`%s` (%s, %d new)", result.Set.Dir, result.Set.Tool, len(result.Set.Added))
+	switch {
+	case result.Skipped != "":
+		return fmt.Sprintf("- ⏭️ %s: not checked, %s", set, result.Skipped)
+	case result.Failed != "":
+		done := ""
+		if len(result.Steps) > 0 {
+			done = strings.Join(result.Steps, ", ") + ", then "
+		}
+		return fmt.Sprintf("- ❌ %s: %sfailed to %s\n\n  ```\n%s\n  ```", set, done, result.Failed, indent(result.Error, "  "))
+	case result.Set.Tool == migrations.ToolPrisma:
+		return fmt.Sprintf("- ✅ %s: applied (Prisma migrations have no rollback)", set)
+	default:
+		return fmt.Sprintf("- ✅ %s: %s", set, strings.Join(result.Steps, ", "))
+	}
+}
+
+// addedFiles lists the files a branch added since it left the default branch
+func addedFiles(repoDir, branch string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=A", "origin/HEAD..."+branch)
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
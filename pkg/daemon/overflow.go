@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// trimmedCommentChars bounds each comment quoted in a trimmed prompt
+	trimmedCommentChars = 500
+	// compactTimeout bounds the compaction pass run before a trimmed resume
+	compactTimeout = 5 * time.Minute
+)
+
+// summarizeComment shortens a comment for a trimmed prompt: its first
+// paragraph, capped at trimmedCommentChars, with a pointer to the full text
+func summarizeComment(body string) string {
+	body = strings.TrimSpace(body)
+	summary := body
+	if idx := strings.Index(summary, "\n\n"); idx >= 0 {
+		summary = summary[:idx]
+	}
+	if len(summary) > trimmedCommentChars {
+		summary = strings.TrimSpace(summary[:trimmedCommentChars])
+	}
+	if summary != body {
+		summary += " … _(shortened, read the full comment with GitLab MCP tools)_"
+	}
+	return summary
+}
+
+// overflowFlags returns the Claude flags for a retry after a context overflow:
+// the usual flags plus CLAUDE_OVERFLOW_FLAGS, which take precedence
+func (d *Daemon) overflowFlags(flags string) string {
+	extra := strings.Fields(d.config.Claude.OverflowFlags)
+
+	// Drop flags (and their values) the overflow flags override, e.g. --max-turns
+	overridden := make(map[string]bool)
+	for _, arg := range extra {
+		if strings.HasPrefix(arg, "--") {
+			overridden[arg] = true
+		}
+	}
+
+	base := strings.Fields(flags)
+	kept := make([]string, 0, len(base))
+	for i := 0; i < len(base); i++ {
+		if overridden[base[i]] {
+			if i+1 < len(base) && !strings.HasPrefix(base[i+1], "-") {
+				i++
+			}
+			continue
+		}
+		kept = append(kept, base[i])
+	}
+
+	return strings.Join(append(kept, extra...), " ")
+}
+
+// compactSession asks Claude to compact a stored conversation before it is
+// resumed, so the resumed turn starts well below the context limit
+func (d *Daemon) compactSession(ctx context.Context, claudeCommand, flags, workingDir string, env []string, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, compactTimeout)
+	defer cancel()
+
+	args := append(strings.Fields(flags), "-r", sessionID, "-p", "/compact")
+	cmd := exec.CommandContext(ctx, claudeCommand, args...)
+	cmd.Dir = workingDir
+	cmd.Env = env
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("compaction failed: %v (%s)", err, strings.TrimSpace(lastLine(string(output))))
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// pauseCheckInterval is how often a shutdown checks whether paused sessions have stopped
+const pauseCheckInterval = time.Second
+
+// pauseReason returns why an issue's session is paused, "" if it is not
+func (d *Daemon) pauseReason(issueIID int) string {
+	eventLog, ok := d.sessionStore.(session.EventLog)
+	if !ok {
+		return ""
+	}
+	events, err := eventLog.GetEvents(issueIID)
+	if err != nil {
+		return ""
+	}
+	reason := ""
+	for _, event := range events {
+		switch event.Kind {
+		case session.EventPaused:
+			reason = event.Detail
+		case session.EventUnpaused:
+			reason = ""
+		}
+	}
+	return reason
+}
+
+// pauseSession stores a session that stopped at a tool boundary so
+// checkPauses can resume it, and tells the issue it is paused
+func (d *Daemon) pauseSession(process *claude.Process, forkPath, branch, previousSessionID string) {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	logging.Issue(process.IssueNum).Infof("Paused session for issue #%d: %s", process.IssueNum, process.Paused)
+
+	sessionID := d.storeSession(process, forkPath, branch, previousSessionID, 0, timestamp)
+	d.recordEvent(process.IssueNum, session.EventPaused, sessionID, process.Paused)
+
+	comment := d.message(locale.MsgSessionPaused, map[string]interface{}{
+		"Reason": process.Paused,
+		"Label":  d.config.Pause.Label,
+	})
+	note, err := d.gitlabClient.CreateIssueNote(d.selectedProject, process.IssueNum, comment)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to post pause comment for issue #%d: %v", process.IssueNum, err)
+		return
+	}
+	// Comments from before the pause are in the session already
+	if err := d.sessionStore.UpdateLastNote(process.IssueNum, note.ID, time.Time{}); err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to record pause comment for issue #%d: %v", process.IssueNum, err)
+	}
+}
+
+// checkPauses pauses the running sessions of issues labeled PAUSE_LABEL and
+// resumes the paused sessions of issues no longer labeled. It returns how
+// many sessions it resumed.
+func (d *Daemon) checkPauses(ctx context.Context, timestamp string) int {
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{
+		Labels: []string{d.config.Pause.Label},
+		State:  "opened",
+	})
+	if err != nil {
+		logging.Errorf("Error checking paused issues: %v", err)
+		return 0
+	}
+	labeled := make(map[int]bool, len(issues))
+	for _, issue := range issues {
+		labeled[issue.IID] = true
+	}
+
+	running := make(map[int]bool)
+	for _, process := range d.processManager.ListProcesses() {
+		running[process.IssueNum] = true
+		if labeled[process.IssueNum] && process.Status == "running" {
+			process.RequestPause(fmt.Sprintf("the issue was labeled `%s`", d.config.Pause.Label))
+		}
+	}
+
+	tracker, ok := d.sessionStore.(session.PauseTracker)
+	if !ok {
+		return 0
+	}
+	paused, err := tracker.PausedIssues()
+	if err != nil {
+		logging.Errorf("Error listing paused sessions: %v", err)
+		return 0
+	}
+
+	resumed := 0
+	for _, issueIID := range paused {
+		if ctx.Err() != nil {
+			return resumed
+		}
+		if labeled[issueIID] || running[issueIID] {
+			continue
+		}
+		stored, exists := d.sessionStore.GetCompletedSession(issueIID)
+		if !exists {
+			continue
+		}
+		issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+		if err != nil {
+			logging.Issue(issueIID).Errorf("Error checking paused issue #%d: %v", issueIID, err)
+			continue
+		}
+		if issue.State != "opened" {
+			d.recordEvent(issueIID, session.EventUnpaused, stored.SessionID, "issue "+issue.State)
+			continue
+		}
+
+		// Like any resumed session, it finishes under the review label
+		logging.Issue(issueIID).Infof("Resuming paused session for issue #%d", issueIID)
+		d.swapLabels(issueIID, []string{d.config.Daemon.ProcessLabel}, d.config.Daemon.ReviewLabel)
+		if err := d.resumeSession(ctx, stored, nil, nil, false); err != nil {
+			logging.Issue(issueIID).Errorf("Error resuming paused session for issue #%d: %v", issueIID, err)
+			continue
+		}
+		d.recordEvent(issueIID, session.EventUnpaused, stored.SessionID, "")
+		resumed++
+	}
+	return resumed
+}
+
+// pauseForShutdown asks the running issue sessions to pause and waits up to
+// PAUSE_SHUTDOWN_TIMEOUT for them to stop and be stored. Sessions still
+// running after that are left to be terminated.
+func (d *Daemon) pauseForShutdown() {
+	processes := d.processManager.GetRunningProcesses()
+	if len(processes) == 0 {
+		return
+	}
+	timeout := time.Duration(d.config.Pause.ShutdownTimeout) * time.Minute
+	logging.Infof("Pausing %d running Claude sessions, waiting up to %s...", len(processes), timeout)
+	for _, process := range processes {
+		process.RequestPause("the daemon was stopped")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		remaining := 0
+		for _, process := range processes {
+			if _, exists := d.processManager.GetProcess(process.ID); exists {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return
+		}
+		time.Sleep(pauseCheckInterval)
+	}
+	logging.Infof("Some sessions did not reach a tool boundary in %s", timeout)
+}
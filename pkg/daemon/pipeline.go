@@ -0,0 +1,239 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/cilog"
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/events"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// issueMergeRequest returns the open merge request for an issue branch, with
+// its head pipeline populated, or nil if there is none
+func (d *Daemon) issueMergeRequest(projectPath, branch string) (*gitlab.MergeRequest, error) {
+	mergeRequests, err := d.gitlabClient.GetMergeRequestsForBranch(projectPath, branch, "opened")
+	if err != nil {
+		return nil, err
+	}
+	if len(mergeRequests) == 0 {
+		return nil, nil
+	}
+
+	// The list endpoint omits head_pipeline, so fetch the MR itself
+	return d.gitlabClient.GetMergeRequest(projectPath, mergeRequests[0].IID)
+}
+
+// pipelineFailureContext describes the failed jobs of the issue MR's head
+// pipeline for a resume prompt. Each job log is distilled to keep the prompt
+// small; the full log is uploaded as a snippet and linked.
+func (d *Daemon) pipelineFailureContext(s *session.CompletedSession) string {
+	mr, err := d.issueMergeRequest(s.ProjectPath, sessionBranch(s))
+	if err != nil {
+		logging.Issue(s.IssueIID).Warnf("Failed to look up MR for issue #%d: %v", s.IssueIID, err)
+		return ""
+	}
+	if mr == nil {
+		return ""
+	}
+	pipeline := d.mergeRequestPipeline(s.ProjectPath, mr)
+	if pipeline == nil || pipeline.Status != "failed" {
+		return ""
+	}
+
+	return d.formatPipelineFailure(mr.ProjectID, mr.IID, pipeline)
+}
+
+// mergeRequestPipeline returns the latest pipeline of a merge request: its head
+// pipeline, or the newest of its pipelines while GitLab has not attached one
+func (d *Daemon) mergeRequestPipeline(projectPath string, mr *gitlab.MergeRequest) *gitlab.Pipeline {
+	if mr.HeadPipeline != nil {
+		return mr.HeadPipeline
+	}
+
+	pipelines, err := d.gitlabClient.GetMergeRequestPipelines(projectPath, mr.IID)
+	if err != nil {
+		logging.Warnf("Failed to list pipelines of MR !%d: %v", mr.IID, err)
+		return nil
+	}
+	if len(pipelines) == 0 {
+		return nil
+	}
+	return &pipelines[0]
+}
+
+// formatPipelineFailure renders the failed jobs of a pipeline as a prompt section
+func (d *Daemon) formatPipelineFailure(projectID, mrIID int, pipeline *gitlab.Pipeline) string {
+	jobs, err := d.gitlabClient.GetPipelineJobs(projectID, pipeline.ID)
+	if err != nil {
+		logging.Warnf("Failed to fetch jobs for pipeline #%d: %v", pipeline.ID, err)
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Failing Pipeline on MR !%d\n\n", mrIID)
+	fmt.Fprintf(&b, "Pipeline #%d for `%s` failed: %s\n\n", pipeline.ID, shortSHA(pipeline.SHA), pipeline.WebURL)
+
+	failed := 0
+	for _, job := range jobs {
+		if job.Status != "failed" || job.AllowFailure {
+			continue
+		}
+		failed++
+
+		fmt.Fprintf(&b, "## Job `%s` (stage: %s)\n\n", job.Name, job.Stage)
+		if job.FailureReason != "" {
+			fmt.Fprintf(&b, "**Failure reason:** %s\n", job.FailureReason)
+		}
+
+		log, err := d.gitlabClient.GetJobLog(projectID, job.ID)
+		if err != nil {
+			fmt.Fprintf(&b, "Log unavailable (%v), see %s\n\n", err, job.WebURL)
+			continue
+		}
+
+		if link := d.uploadJobLog(projectID, pipeline, job, log); link != "" {
+			fmt.Fprintf(&b, "**Full log:** %s\n", link)
+		}
+		fmt.Fprintf(&b, "\n```\n%s\n```\n\n", cilog.Distill(log, cilog.DefaultOptions()))
+	}
+
+	if failed == 0 {
+		return ""
+	}
+
+	b.WriteString("Please fix the failing jobs and push the changes to the same branch.\n\n")
+	return b.String()
+}
+
+// uploadJobLog attaches the full job log as a private snippet and returns its URL.
+// Nothing is uploaded in dry-run modes.
+func (d *Daemon) uploadJobLog(projectID int, pipeline *gitlab.Pipeline, job gitlab.Job, log string) string {
+	if d.dryRun || d.semiDryRun {
+		return job.WebURL
+	}
+
+	title := fmt.Sprintf("Pipeline #%d job %s log", pipeline.ID, job.Name)
+	snippet, err := d.gitlabClient.CreateProjectSnippet(projectID, title, fmt.Sprintf("job-%d.log", job.ID), cilog.StripString(log))
+	if err != nil {
+		logging.Warnf("Failed to upload log for job %d: %v", job.ID, err)
+		return job.WebURL
+	}
+
+	return snippet.WebURL
+}
+
+// pipelinePollInterval is how often an unfinished pipeline is re-checked
+const pipelinePollInterval = 30 * time.Second
+
+// waitForPipeline blocks until the latest pipeline of the MR for the issue's
+// branch finishes or the timeout elapses, returning a status line for the
+// completion comment and the finished pipeline, nil if none finished. A
+// previous pipeline is waited past unless the branch has not moved since.
+func (d *Daemon) waitForPipeline(issueIID int, branch string, timeout time.Duration, previous *gitlab.Pipeline) (string, *gitlab.Pipeline) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		mr, err := d.issueMergeRequest(d.selectedProject, branch)
+		if err != nil {
+			logging.Issue(issueIID).Warnf("Failed to check pipeline for issue #%d: %v", issueIID, err)
+		}
+
+		var pipeline *gitlab.Pipeline
+		if mr != nil {
+			pipeline = d.mergeRequestPipeline(d.selectedProject, mr)
+		}
+		if pipeline != nil && previous != nil && pipeline.ID <= previous.ID {
+			if mr.SHA == previous.SHA {
+				return d.pipelineStatusLine(previous), previous
+			}
+			pipeline = nil
+		}
+		if pipeline != nil && pipeline.IsFinished() {
+			return d.pipelineStatusLine(pipeline), pipeline
+		}
+
+		if time.Now().After(deadline) {
+			switch {
+			case mr == nil:
+				return d.message(locale.MsgPipelineNoMR, nil), nil
+			case pipeline == nil:
+				return d.message(locale.MsgPipelineNotStarted, map[string]interface{}{"Timeout": timeout}), nil
+			default:
+				return d.message(locale.MsgPipelineStillRunning, map[string]interface{}{
+					"Link":    pipelineLink(pipeline),
+					"Status":  pipeline.Status,
+					"Timeout": timeout,
+				}), nil
+			}
+		}
+
+		time.Sleep(pipelinePollInterval)
+	}
+}
+
+// resumeToFix resumes the session of an issue whose pipeline failed or whose
+// CI configuration is invalid, so it works on the failing job logs or lint
+// errors, and waits for the resume to end. It reports whether the resume
+// completed. The session is stored first for the resume to find it.
+func (d *Daemon) resumeToFix(process *claude.Process, forkPath, branch, previousSessionID, timestamp string) bool {
+	d.storeSession(process, forkPath, branch, previousSessionID, 0, timestamp)
+	stored, ok := d.sessionStore.GetCompletedSession(process.IssueNum)
+	if !ok {
+		return false
+	}
+
+	finished := make(chan string, 1)
+	unsubscribe := events.Subscribe(d.events, func(event events.SessionCompleted) {
+		if event.IssueIID == process.IssueNum && event.Kind == runResume {
+			select {
+			case finished <- event.Outcome:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	ctx := d.shutdownContext()
+	if err := d.resumeSession(ctx, stored, nil, nil, false); err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to resume issue #%d to fix it: %v", process.IssueNum, err)
+		return false
+	}
+	// Nothing was started, e.g. in dry runs or when no job failed for good
+	if _, running := d.resumeProcesses[process.IssueNum]; !running && len(finished) == 0 {
+		return false
+	}
+
+	select {
+	case outcome := <-finished:
+		return outcome == session.RunCompleted
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pipelineStatusLine summarizes a finished pipeline for a GitLab comment
+func (d *Daemon) pipelineStatusLine(pipeline *gitlab.Pipeline) string {
+	data := map[string]interface{}{"Link": pipelineLink(pipeline), "Status": pipeline.Status}
+
+	switch pipeline.Status {
+	case "success":
+		return d.message(locale.MsgPipelinePassed, data)
+	case "failed":
+		return d.message(locale.MsgPipelineFailed, data)
+	case "manual":
+		return d.message(locale.MsgPipelineManual, data)
+	default:
+		return d.message(locale.MsgPipelineOther, data)
+	}
+}
+
+// pipelineLink renders a markdown link to a pipeline
+func pipelineLink(pipeline *gitlab.Pipeline) string {
+	return fmt.Sprintf("[#%d](%s)", pipeline.ID, pipeline.WebURL)
+}
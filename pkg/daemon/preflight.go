@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// errPushBlocked is returned when an issue session is not started because
+// the bot could not push its branch
+var errPushBlocked = errors.New("bot cannot push the issue branch")
+
+// pushBlocker explains why the bot cannot push an issue's branch to the
+// project it pushes to (the fork, when there is one), or returns "" when it
+// can. Lookup errors are logged and let the session start, so a flaky API
+// does not hold issues back.
+func (d *Daemon) pushBlocker(issueIID int, branch, forkPath string) string {
+	target := d.selectedProject
+	if forkPath != "" {
+		target = forkPath
+	}
+
+	project, err := d.gitlabClient.GetProject(strings.ReplaceAll(target, "/", "%2F"))
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Push check for issue #%d could not read %s: %v", issueIID, target, err)
+		return ""
+	}
+	level := project.AccessLevel()
+	if level < gitlab.DeveloperAccess {
+		return fmt.Sprintf("the bot has no Developer access to `%s` and FORK_MODE is `%s`", target, d.config.GitLab.ForkMode)
+	}
+
+	user, err := d.gitlabClient.Users().Current()
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Push check for issue #%d could not look up the bot user: %v", issueIID, err)
+		return ""
+	}
+	rules, err := d.gitlabClient.GetProtectedBranches(target)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Push check for issue #%d could not list protected branches: %v", issueIID, err)
+		return ""
+	}
+	for _, rule := range rules {
+		if rule.Matches(branch) && !rule.AllowsPush(level, user.ID) {
+			return fmt.Sprintf("`%s` matches the protected branch rule `%s` in `%s`, which does not allow the bot to push", branch, rule.Name, target)
+		}
+	}
+	return ""
+}
+
+// blockIssue reports a failed push check on the issue and moves it from the
+// in-progress label to the error label, so it is not picked up again until
+// someone re-adds the trigger label
+func (d *Daemon) blockIssue(issueIID int, branch, reason string) {
+	logging.Issue(issueIID).Infof("Not starting issue #%d: %s", issueIID, reason)
+
+	d.recordEvent(issueIID, session.EventFailed, "", "push blocked: "+reason)
+	d.postFailureNote(issueIID, d.message(locale.MsgPushBlocked, map[string]interface{}{
+		"Branch": branch,
+		"Reason": reason,
+		"Label":  d.config.Daemon.ClaudeLabel,
+	}))
+
+	d.holdIssue(issueIID, "error")
+}
+
+// holdIssue moves an issue from the trigger and in-progress labels to label,
+// so it is not picked up again until someone re-adds the trigger label
+func (d *Daemon) holdIssue(issueIID int, label string) {
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to get issue #%d for label update: %v", issueIID, err)
+		return
+	}
+	labels := []string{label}
+	for _, existing := range issue.Labels {
+		if existing != d.config.Daemon.ProcessLabel && existing != d.config.Daemon.ClaudeLabel && existing != label {
+			labels = append(labels, existing)
+		}
+	}
+	if err := d.setIssueLabels(issueIID, labels); err != nil {
+		logging.Issue(issueIID).Warnf("Failed to update %s labels for issue #%d: %v", label, issueIID, err)
+	}
+}
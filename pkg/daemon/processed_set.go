@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// processedSet tracks the issues started on while they carry the trigger
+// label. With a tracker the set survives restarts, so a daemon that crashed
+// mid-cycle does not start the same issues again; otherwise, as in dry runs
+// and memoryless mode, it only lives in memory.
+type processedSet struct {
+	mu      sync.Mutex
+	issues  map[int]bool
+	tracker session.IntakeTracker
+}
+
+// newProcessedSet loads the issues recorded in store when it supports it
+// and persist is set. The store must already be scoped to the project.
+func newProcessedSet(store session.Store, persist bool) *processedSet {
+	processed := &processedSet{issues: make(map[int]bool)}
+	tracker, ok := store.(session.IntakeTracker)
+	if !ok || !persist {
+		return processed
+	}
+	processed.tracker = tracker
+
+	issues, err := tracker.GetProcessedIssues()
+	if err != nil {
+		logging.Warnf("Failed to load processed issues, starting with none: %v", err)
+		return processed
+	}
+	if len(issues) > 0 {
+		logging.Infof("Restored %d processed issues from the last run", len(issues))
+		processed.issues = issues
+	}
+	return processed
+}
+
+// has reports whether an issue was processed
+func (p *processedSet) has(issueIID int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.issues[issueIID]
+}
+
+// add records an issue as processed
+func (p *processedSet) add(issueIID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.issues[issueIID] = true
+	if p.tracker != nil {
+		if err := p.tracker.SetProcessedIssue(issueIID); err != nil {
+			logging.Issue(issueIID).Warnf("Failed to persist processed issue #%d: %v", issueIID, err)
+		}
+	}
+}
+
+// remove forgets an issue, so the next cycle may start it again
+func (p *processedSet) remove(issueIID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.issues, issueIID)
+	if p.tracker != nil {
+		if err := p.tracker.ClearProcessedIssue(issueIID); err != nil {
+			logging.Issue(issueIID).Warnf("Failed to clear processed issue #%d: %v", issueIID, err)
+		}
+	}
+}
+
+// retain forgets the processed issues that no longer carry the trigger label,
+// given the complete list of issues that do, so re-adding the label starts
+// an issue again
+func (p *processedSet) retain(labeled []gitlab.Issue) {
+	current := make(map[int]bool, len(labeled))
+	for _, issue := range labeled {
+		current[issue.IID] = true
+	}
+
+	p.mu.Lock()
+	var stale []int
+	for issueIID := range p.issues {
+		if !current[issueIID] {
+			stale = append(stale, issueIID)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, issueIID := range stale {
+		p.remove(issueIID)
+	}
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// maxProgressStep caps the latest step quoted in a progress comment
+const maxProgressStep = 1500
+
+// startProgress keeps one comment on the issue up to date with the plan and
+// latest step of its running session, checking every PROGRESS_INTERVAL
+// seconds. The comment is posted once the session has something to show and
+// only edited when that changes. The returned function marks the comment
+// ended and stops updating it; it may be called more than once.
+func (d *Daemon) startProgress(process *claude.Process) func() {
+	if !d.config.Progress.Enabled || d.dryRun || d.semiDryRun {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer d.recoverPanic("progress comment of issue", process.IssueNum)
+		ticker := time.NewTicker(time.Duration(d.config.Progress.Interval) * time.Second)
+		defer ticker.Stop()
+
+		noteID, shown := 0, ""
+		for {
+			select {
+			case <-done:
+				d.updateProgress(process, &noteID, &shown, true)
+				return
+			case <-ticker.C:
+				// A session that panicked never completes, so stop with it
+				if _, running := d.processManager.GetProcess(process.ID); !running {
+					d.updateProgress(process, &noteID, &shown, true)
+					return
+				}
+				d.updateProgress(process, &noteID, &shown, false)
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// updateProgress posts or edits the progress comment of a session when its
+// plan or latest step changed since shown, or it ended. noteID is 0 until the
+// comment is posted; a session that ends before showing anything gets none.
+func (d *Daemon) updateProgress(process *claude.Process, noteID *int, shown *string, ended bool) {
+	progress := process.Progress()
+	plan := formatPlan(progress.Plan)
+	step := progress.Step
+	if utf8.RuneCountInString(step) > maxProgressStep {
+		step = string([]rune(step)[:maxProgressStep]) + "…"
+	}
+	current := plan + "\n" + step
+	if current == "\n" || (current == *shown && !ended) || (*noteID == 0 && ended) {
+		return
+	}
+
+	if step != "" {
+		step = indent(step, "> ")
+	}
+	body := d.message(locale.MsgProgress, map[string]interface{}{
+		"Ended":   ended,
+		"Elapsed": fmt.Sprintf("%d min", int(time.Since(process.StartTime).Minutes())),
+		"Turns":   progress.Stats.Turns,
+		"Plan":    plan,
+		"Step":    step,
+	})
+
+	if *noteID == 0 {
+		note, err := d.gitlabClient.CreateIssueNote(d.selectedProject, process.IssueNum, body)
+		if err != nil {
+			logging.Issue(process.IssueNum).Warnf("Failed to post progress comment on issue #%d: %v", process.IssueNum, err)
+			return
+		}
+		*noteID = note.ID
+	} else if err := d.gitlabClient.UpdateIssueNote(d.selectedProject, process.IssueNum, *noteID, body); err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to update progress comment on issue #%d: %v", process.IssueNum, err)
+		return
+	}
+	*shown = current
+}
+
+// formatPlan renders a session's todo list as a markdown task list, the step
+// in progress in bold
+func formatPlan(plan []claude.PlanItem) string {
+	lines := make([]string, 0, len(plan))
+	for _, item := range plan {
+		switch item.Status {
+		case "completed":
+			lines = append(lines, "- [x] "+item.Content)
+		case "in_progress":
+			text := item.ActiveForm
+			if text == "" {
+				text = item.Content
+			}
+			lines = append(lines, "- [ ] **"+text+"**")
+		default:
+			lines = append(lines, "- [ ] "+item.Content)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
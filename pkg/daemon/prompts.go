@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/prompts"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// reviewPromptData collects the review template's data for a merge request
+func reviewPromptData(mr *gitlab.MergeRequest, projectPath, incremental string, trimmed bool) prompts.ReviewData {
+	return prompts.ReviewData{
+		MergeRequestIID: mr.IID,
+		ProjectPath:     projectPath,
+		Title:           mr.Title,
+		SourceBranch:    mr.SourceBranch,
+		TargetBranch:    mr.TargetBranch,
+		Author:          mr.Author.Username,
+		WebURL:          mr.WebURL,
+		Incremental:     incremental,
+		Trimmed:         trimmed,
+	}
+}
+
+// resumePromptData renders the sections of a resume prompt: new issue
+// comments, review threads, CI lint errors and, unless trimmed, the failing
+// pipeline
+func (d *Daemon) resumePromptData(s *session.CompletedSession, newComments []gitlab.Note, threads []reviewThread, trimmed bool) prompts.ResumeData {
+	var comments strings.Builder
+	for i, comment := range newComments {
+		fmt.Fprintf(&comments, "## Comment %d by @%s\n", i+1, comment.Author.Username)
+		fmt.Fprintf(&comments, "**Posted:** %s\n", comment.CreatedAt)
+		if comment.DiscussionID != "" {
+			fmt.Fprintf(&comments, "**Thread:** `%s`\n", comment.DiscussionID)
+		}
+		body := comment.Body
+		if trimmed {
+			body = summarizeComment(body)
+		}
+		fmt.Fprintf(&comments, "\n%s\n\n---\n\n", body)
+	}
+
+	data := prompts.ResumeData{
+		IssueIID:      s.IssueIID,
+		Comments:      comments.String(),
+		ReviewThreads: formatReviewThreads(threads, trimmed),
+		Trimmed:       trimmed,
+	}
+	if !trimmed {
+		data.PipelineFailure = d.pipelineFailureContext(s)
+	}
+	data.CILint = d.ciLintContext(s)
+	data.Paused = d.pauseReason(s.IssueIID)
+	return data
+}
+
+// PromptPreview is a workflow's prompt rendered against live data
+type PromptPreview struct {
+	Workflow string
+	Source   string // Template file, or "built-in"
+	Prompt   string
+	Err      error
+}
+
+// RenderPrompts renders the prompt of each workflow for an issue, as the
+// daemon would build it right now, without starting any session. An empty
+// workflows list renders every configured template. Workflows are rendered
+// concurrently since each one fetches its own data from GitLab.
+func (d *Daemon) RenderPrompts(issueIID int, workflows []string) []PromptPreview {
+	if d.selectedProject == "" {
+		d.useProject(d.config.Projects.DefaultPath)
+	}
+	set := prompts.Active()
+	if len(workflows) == 0 {
+		workflows = set.Workflows()
+	}
+
+	previews := make([]PromptPreview, len(workflows))
+	var wg sync.WaitGroup
+	for i, workflow := range workflows {
+		wg.Add(1)
+		go func(i int, workflow string) {
+			defer wg.Done()
+			prompt, err := d.renderPrompt(set, workflow, issueIID)
+			previews[i] = PromptPreview{Workflow: workflow, Source: set.Source(workflow), Prompt: prompt, Err: err}
+		}(i, workflow)
+	}
+	wg.Wait()
+
+	return previews
+}
+
+// renderPrompt gathers the live data of one workflow and renders its template
+func (d *Daemon) renderPrompt(set *prompts.Set, workflow string, issueIID int) (string, error) {
+	if !set.Has(workflow) {
+		return "", fmt.Errorf("no template configured, add %s.tmpl to the prompts directory", workflow)
+	}
+
+	switch workflow {
+	case prompts.WorkflowReview:
+		branch := d.currentIssueBranch(issueIID)
+		mr, err := d.issueMergeRequest(d.selectedProject, branch)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up the merge request: %v", err)
+		}
+		if mr == nil {
+			return "", fmt.Errorf("issue #%d has no open merge request on branch %s", issueIID, branch)
+		}
+		return set.Render(workflow, reviewPromptData(mr, d.selectedProject, d.incrementalReviewContext(mr), false))
+
+	case prompts.WorkflowResume:
+		s, exists := d.sessionStore.GetCompletedSession(issueIID)
+		if !exists {
+			return "", fmt.Errorf("issue #%d has no stored session to resume", issueIID)
+		}
+		cutoff := sessionCutoff(s)
+		comments, err := d.gitlabClient.GetIssueCommentsAfter(s.ProjectPath, issueIID, cutoff.since())
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch comments: %v", err)
+		}
+		comments = cutoff.filter(comments)
+		threads, err := d.collectReviewThreads(context.Background(), s, cutoff)
+		if err != nil {
+			return "", err
+		}
+		return set.Render(workflow, d.resumePromptData(s, comments, threads, false))
+
+	default:
+		// The issue workflow and custom workflows render against the issue
+		workingDir, _, err := claude.DetectProjectDirectory(d.selectedProject)
+		if err != nil {
+			return "", fmt.Errorf("failed to detect working directory: %v", err)
+		}
+		issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get issue: %v", err)
+		}
+		data := claude.IssuePromptData(issueIID, d.selectedProject, d.config.GitLab.Username, workingDir)
+		d.enrichIssuePrompt(&data, issue)
+		return set.Render(workflow, data)
+	}
+}
+
+// enrichIssuePrompt adds the issue's title and labels, the knowledge base's
+// related work and the doc index's passages to an issue prompt's data
+func (d *Daemon) enrichIssuePrompt(data *prompts.IssueData, issue *gitlab.Issue) {
+	data.Title = issue.Title
+	data.Labels = issue.Labels
+	if d.config.KnowledgeBase.Enabled {
+		data.RelatedWork = d.relatedWork(issue)
+	}
+	data.Docs = d.relevantDocs(issue, data.WorkingDir)
+}
+
+// issuePrompt renders the prompt of an issue with the template PROMPT_LABELS
+// selects for its labels, or returns "" for the default issue prompt when the
+// issue cannot be fetched or the prompt fails to render
+func (d *Daemon) issuePrompt(issueIID int) string {
+	issue, err := d.gitlabClient.GetIssue(d.selectedProject, issueIID)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to get issue #%d for its prompt: %v", issueIID, err)
+		return ""
+	}
+	workingDir, err := claude.RepositoryDir(d.selectedProject, d.config.GitLab.URL, d.dryRun)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("No checkout to render the prompt of issue #%d in: %v", issueIID, err)
+		return ""
+	}
+
+	set := prompts.Active()
+	workflow := set.ForLabels(issue.Labels, d.config.Prompts.Labels)
+	if workflow != prompts.WorkflowIssue {
+		logging.Issue(issueIID).Infof("Using the %s prompt for issue #%d", workflow, issueIID)
+	}
+	data := claude.IssuePromptData(issueIID, d.selectedProject, d.config.GitLab.Username, workingDir)
+	d.enrichIssuePrompt(&data, issue)
+	prompt, err := set.Render(workflow, data)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Failed to render the prompt of issue #%d: %v", issueIID, err)
+		return ""
+	}
+	return prompt
+}
@@ -0,0 +1,196 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+const (
+	// reviewRetryDelay is how long a failed review waits before it is retried
+	// on the same SHA; the wait doubles with every further failure
+	reviewRetryDelay = 5 * time.Minute
+	// maxReviewAttempts is how many times a SHA is reviewed before the queue
+	// gives up on it until the MR gets a new head
+	maxReviewAttempts = 3
+)
+
+// reviewFailure tracks the failed reviews of an MR's head SHA
+type reviewFailure struct {
+	sha      string
+	attempts int
+	retryAt  time.Time
+}
+
+// reviewQueue coalesces MR review requests so that only the latest head SHA
+// of each merge request is reviewed, and identical SHAs are never re-reviewed
+type reviewQueue struct {
+	mu            sync.Mutex
+	pending       map[string]gitlab.MergeRequest // Queued-but-unstarted reviews by MR key
+	order         []string                       // FIFO order of pending MR keys
+	running       map[string]string              // SHA under review by MR key
+	reviewedSHA   map[string]string              // Last successfully reviewed SHA by MR key
+	failed        map[string]reviewFailure       // Failed reviews of the head SHA by MR key
+	maxConcurrent int                            // Max reviews running at once, 0 means unlimited
+	tracker       session.ReviewTracker          // Persists reviewed SHAs across restarts, may be nil
+}
+
+// newReviewQueue creates a review queue, persisting reviewed SHAs if the store supports it
+func newReviewQueue(maxConcurrent int, store session.Store) *reviewQueue {
+	tracker, _ := store.(session.ReviewTracker)
+	return &reviewQueue{
+		pending:       make(map[string]gitlab.MergeRequest),
+		running:       make(map[string]string),
+		reviewedSHA:   make(map[string]string),
+		failed:        make(map[string]reviewFailure),
+		maxConcurrent: maxConcurrent,
+		tracker:       tracker,
+	}
+}
+
+// reviewedLocked returns the last reviewed SHA, consulting the persistent
+// tracker on a cache miss. Callers must hold q.mu.
+func (q *reviewQueue) reviewedLocked(mr *gitlab.MergeRequest) (string, bool) {
+	key := mrKey(mr)
+	if sha, exists := q.reviewedSHA[key]; exists {
+		return sha, true
+	}
+	if q.tracker == nil {
+		return "", false
+	}
+
+	sha, exists := q.tracker.GetReviewedSHA(mr.ProjectID, mr.IID)
+	if exists {
+		q.reviewedSHA[key] = sha
+	}
+	return sha, exists
+}
+
+// recordLocked stores a reviewed SHA in memory and in the tracker. Callers must hold q.mu.
+func (q *reviewQueue) recordLocked(mr *gitlab.MergeRequest, sha string) {
+	q.reviewedSHA[mrKey(mr)] = sha
+	if q.tracker != nil {
+		if err := q.tracker.SetReviewedSHA(mr.ProjectID, mr.IID, sha); err != nil {
+			logging.MergeRequest(mr.IID).Warnf("Failed to persist reviewed SHA for MR !%d: %v", mr.IID, err)
+		}
+	}
+}
+
+// mrKey identifies a merge request across projects
+func mrKey(mr *gitlab.MergeRequest) string {
+	return fmt.Sprintf("%d!%d", mr.ProjectID, mr.IID)
+}
+
+// enqueue queues a review of the MR's current head. A queued review for an
+// older SHA of the same MR is replaced. Returns false if the SHA needs no
+// review, is backing off after a failed review, or is unknown: without a SHA
+// the queue cannot tell a reviewed head from a new one.
+func (q *reviewQueue) enqueue(mr gitlab.MergeRequest) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := mrKey(&mr)
+	if mr.SHA == "" {
+		return false
+	}
+	if reviewed, _ := q.reviewedLocked(&mr); reviewed == mr.SHA || q.running[key] == mr.SHA {
+		return false
+	}
+	if failure, ok := q.failed[key]; ok && failure.sha == mr.SHA {
+		if failure.attempts >= maxReviewAttempts || time.Now().Before(failure.retryAt) {
+			return false
+		}
+	}
+
+	if _, queued := q.pending[key]; !queued {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = mr
+	return true
+}
+
+// next pops the queued reviews that may start now. MRs with a review already
+// running stay queued until it finishes.
+func (q *reviewQueue) next() []gitlab.MergeRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var ready []gitlab.MergeRequest
+	remaining := q.order[:0]
+	for _, key := range q.order {
+		_, busy := q.running[key]
+		full := q.maxConcurrent > 0 && len(q.running) >= q.maxConcurrent
+		if busy || full {
+			remaining = append(remaining, key)
+			continue
+		}
+
+		mr := q.pending[key]
+		delete(q.pending, key)
+		q.running[key] = mr.SHA
+		ready = append(ready, mr)
+	}
+	q.order = remaining
+
+	return ready
+}
+
+// isRunning reports whether a review is in progress for the MR
+func (q *reviewQueue) isRunning(mr *gitlab.MergeRequest) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, running := q.running[mrKey(mr)]
+	return running
+}
+
+// lastReviewed returns the last successfully reviewed SHA for the MR
+func (q *reviewQueue) lastReviewed(mr *gitlab.MergeRequest) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.reviewedLocked(mr)
+}
+
+// markReviewed records a SHA as reviewed without running a review
+func (q *reviewQueue) markReviewed(mr *gitlab.MergeRequest, sha string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if sha != "" {
+		q.recordLocked(mr, sha)
+	}
+}
+
+// finish marks a running review as done, recording the SHA on success. A
+// failed review is retried on the same SHA after a growing delay, up to
+// maxReviewAttempts times.
+func (q *reviewQueue) finish(mr *gitlab.MergeRequest, success bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := mrKey(mr)
+	sha := q.running[key]
+	delete(q.running, key)
+	if sha == "" {
+		return
+	}
+	if success {
+		delete(q.failed, key)
+		q.recordLocked(mr, sha)
+		return
+	}
+
+	failure := q.failed[key]
+	if failure.sha != sha {
+		failure = reviewFailure{sha: sha}
+	}
+	failure.attempts++
+	failure.retryAt = time.Now().Add(reviewRetryDelay << (failure.attempts - 1))
+	q.failed[key] = failure
+	if failure.attempts >= maxReviewAttempts {
+		logging.MergeRequest(mr.IID).Warnf("Review of MR !%d at %s failed %d times, not retrying until it gets new commits",
+			mr.IID, shortSHA(sha), failure.attempts)
+	}
+}
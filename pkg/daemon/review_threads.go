@@ -0,0 +1,210 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// reviewThread is a merge request discussion with human feedback Claude has not seen yet
+type reviewThread struct {
+	mergeRequestIID int
+	headSHA         string // MR head when the feedback was collected
+	discussionID    string
+	resolvable      bool
+	notes           []gitlab.Note
+}
+
+// issueBranch returns the branch Claude is instructed to work on for an issue
+func issueBranch(issueIID int) string {
+	return fmt.Sprintf("issue-%d", issueIID)
+}
+
+// collectReviewThreads gathers new human notes on the open merge requests for
+// the session's issue branch, grouped by discussion thread
+func (d *Daemon) collectReviewThreads(ctx context.Context, s *session.CompletedSession, cutoff noteCutoff) ([]reviewThread, error) {
+	mergeRequests, err := d.gitlabClient.GetMergeRequestsForBranch(s.ProjectPath, sessionBranch(s), "opened")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch merge requests for issue #%d: %v", s.IssueIID, err)
+	}
+
+	var threads []reviewThread
+	for _, mr := range mergeRequests {
+		notes, err := d.gitlabClient.GetMergeRequestCommentsAfterWithContext(ctx, s.ProjectPath, mr.IID, cutoff.since())
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch comments for MR !%d: %v", mr.IID, err)
+		}
+		notes = cutoff.filter(notes)
+
+		byDiscussion := make(map[string]int)
+		for _, note := range notes {
+			// Claude's own replies are not feedback
+			if note.Author.Username == d.config.GitLab.Username {
+				continue
+			}
+
+			idx, exists := byDiscussion[note.DiscussionID]
+			if !exists {
+				idx = len(threads)
+				byDiscussion[note.DiscussionID] = idx
+				threads = append(threads, reviewThread{
+					mergeRequestIID: mr.IID,
+					headSHA:         mr.SHA,
+					discussionID:    note.DiscussionID,
+				})
+
+				// A human replying to a resolved thread means it isn't done,
+				// so reopen it to keep the unresolved-thread counter honest
+				if note.Resolved && !d.dryRun && !d.semiDryRun {
+					if err := d.gitlabClient.UnresolveDiscussion(s.ProjectPath, mr.IID, note.DiscussionID); err != nil {
+						logging.MergeRequest(mr.IID).Warnf("Failed to unresolve thread %s on MR !%d: %v", note.DiscussionID, mr.IID, err)
+					}
+				}
+			}
+			threads[idx].resolvable = threads[idx].resolvable || note.Resolvable
+			threads[idx].notes = append(threads[idx].notes, note)
+		}
+	}
+
+	return threads, nil
+}
+
+// formatReviewThreads renders MR feedback for the resume prompt, asking Claude
+// to answer inside each thread instead of posting top-level comments
+func formatReviewThreads(threads []reviewThread, trimmed bool) string {
+	if len(threads) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("# New Merge Request Review Feedback\n\n")
+	b.WriteString("The following review threads on your merge request have new comments:\n\n")
+
+	for _, thread := range threads {
+		fmt.Fprintf(&b, "## Thread `%s` on MR !%d\n\n", thread.discussionID, thread.mergeRequestIID)
+		for _, note := range thread.notes {
+			body := note.Body
+			if trimmed {
+				body = summarizeComment(body)
+			}
+			fmt.Fprintf(&b, "**@%s** (%s):\n\n%s\n\n", note.Author.Username, note.CreatedAt, body)
+		}
+		b.WriteString("---\n\n")
+	}
+
+	b.WriteString("Reply to each review comment **inside its thread** by creating a note on the discussion ID shown ")
+	b.WriteString("(POST /projects/:id/merge_requests/:merge_request_iid/discussions/:discussion_id/notes) ")
+	b.WriteString("rather than posting new top-level comments. Threads are resolved for you once fixes are pushed.\n\n")
+
+	return b.String()
+}
+
+// resolveReviewThreads resolves the MR threads whose feedback was addressed.
+// Threads are only resolved when fixes were pushed, i.e. the MR head moved;
+// otherwise they stay open for a human to close.
+func (d *Daemon) resolveReviewThreads(projectPath string, threads []reviewThread) {
+	headChanged := make(map[int]bool)
+	for _, thread := range threads {
+		if !thread.resolvable {
+			continue
+		}
+
+		changed, checked := headChanged[thread.mergeRequestIID]
+		if !checked {
+			mr, err := d.gitlabClient.GetMergeRequest(projectPath, thread.mergeRequestIID)
+			if err != nil {
+				logging.MergeRequest(thread.mergeRequestIID).Warnf("Failed to fetch MR !%d to check for pushed fixes: %v", thread.mergeRequestIID, err)
+			}
+			changed = err == nil && mr.SHA != thread.headSHA
+			headChanged[thread.mergeRequestIID] = changed
+			if !changed {
+				logging.MergeRequest(thread.mergeRequestIID).Infof("No new commits on MR !%d, leaving review threads open", thread.mergeRequestIID)
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if err := d.gitlabClient.ResolveMergeRequestDiscussion(projectPath, thread.mergeRequestIID, thread.discussionID); err != nil {
+			logging.MergeRequest(thread.mergeRequestIID).Warnf("Failed to resolve thread %s on MR !%d: %v", thread.discussionID, thread.mergeRequestIID, err)
+			continue
+		}
+		logging.MergeRequest(thread.mergeRequestIID).Infof("Resolved thread %s on MR !%d", thread.discussionID, thread.mergeRequestIID)
+	}
+}
+
+// noteCutoff selects the notes a session has not seen yet: by note ID once one
+// has been recorded, or by creation time for sessions stored before note IDs
+// were tracked. Note IDs increase across the GitLab instance, so they order
+// issue and MR notes alike without clock skew or timestamp precision issues.
+type noteCutoff struct {
+	noteID int
+	time   time.Time
+}
+
+// sessionCutoff returns the cutoff for feedback a session has not seen
+func sessionCutoff(s *session.CompletedSession) noteCutoff {
+	cutoff := noteCutoff{noteID: s.LastNoteID, time: s.CompletionTime}
+	if s.LastCommentTime != nil {
+		cutoff.time = *s.LastCommentTime
+	}
+	return cutoff
+}
+
+// since returns the creation time to fetch notes after; with a note ID every
+// note is fetched and filter does the cut
+func (c noteCutoff) since() time.Time {
+	if c.noteID > 0 {
+		return time.Time{}
+	}
+	return c.time
+}
+
+// filter drops the notes at or before the cutoff note
+func (c noteCutoff) filter(notes []gitlab.Note) []gitlab.Note {
+	if c.noteID == 0 {
+		return notes
+	}
+	var newer []gitlab.Note
+	for _, note := range notes {
+		if note.ID > c.noteID {
+			newer = append(newer, note)
+		}
+	}
+	return newer
+}
+
+// latestFeedback returns the ID and creation time of the newest issue comment
+// or review note
+func latestFeedback(comments []gitlab.Note, threads []reviewThread) (int, time.Time, bool) {
+	var latest gitlab.Note
+	found := false
+
+	consider := func(note gitlab.Note) {
+		if !found || note.ID > latest.ID {
+			latest = note
+			found = true
+		}
+	}
+
+	for _, comment := range comments {
+		consider(comment)
+	}
+	for _, thread := range threads {
+		for _, note := range thread.notes {
+			consider(note)
+		}
+	}
+
+	if !found {
+		return 0, time.Time{}, false
+	}
+	// The time is informational (audit timeline); a bad timestamp leaves it unchanged
+	createdAt, _ := time.Parse(time.RFC3339, latest.CreatedAt)
+	return latest.ID, createdAt, true
+}
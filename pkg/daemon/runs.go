@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"github.com/bilbo290/automagic/pkg/events"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// recordRun publishes a finished session run, which adds it to the run history
+// behind "automagic report export". Dry runs are not recorded.
+func (d *Daemon) recordRun(run session.SessionRun) {
+	if d.dryRun || d.semiDryRun {
+		return
+	}
+	if run.ProjectPath == "" {
+		run.ProjectPath = d.selectedProject
+	}
+	d.events.Publish(events.SessionCompleted{SessionRun: run})
+}
+
+// runOutcome returns the outcome of a run that succeeded or not
+func runOutcome(success bool) string {
+	if success {
+		return session.RunCompleted
+	}
+	return session.RunFailed
+}
@@ -0,0 +1,214 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+var (
+	// scaffoldProjectLine names the project to create, e.g. "Project: group/app"
+	scaffoldProjectLine = regexp.MustCompile(`(?i)^\s*project\s*:\s*` + "`?" + `([\w.\-/]+)` + "`?" + `\s*$`)
+	// checklistItem is a Markdown task list item, checked or not
+	checklistItem = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+?)\s*$`)
+)
+
+// scaffoldPlan is what a scaffold issue asks for
+type scaffoldPlan struct {
+	projectPath string   // Namespace and path of the project to create
+	overview    string   // The description without the project line and checklist
+	tasks       []string // Unchecked checklist items, each becoming an issue
+}
+
+// parseScaffoldIssue reads the project to create and the remaining checklist
+// from an issue description. A project named without a namespace goes in
+// namespace. The project path is empty when the description names none.
+func parseScaffoldIssue(description, namespace string) scaffoldPlan {
+	var plan scaffoldPlan
+	var overview []string
+	for _, line := range strings.Split(description, "\n") {
+		if match := scaffoldProjectLine.FindStringSubmatch(line); match != nil && plan.projectPath == "" {
+			plan.projectPath = strings.Trim(match[1], "/")
+			continue
+		}
+		if match := checklistItem.FindStringSubmatch(line); match != nil {
+			if match[1] == " " {
+				plan.tasks = append(plan.tasks, match[2])
+			}
+			continue
+		}
+		overview = append(overview, line)
+	}
+	plan.overview = strings.TrimSpace(strings.Join(overview, "\n"))
+
+	if plan.projectPath != "" && !strings.Contains(plan.projectPath, "/") && namespace != "" {
+		plan.projectPath = namespace + "/" + plan.projectPath
+	}
+	return plan
+}
+
+// scaffoldProject works on an issue carrying SCAFFOLD_LABEL: it creates the
+// project the issue names from SCAFFOLD_TEMPLATE, commits a README describing
+// it and files the unchecked checklist items as issues of the new project.
+// The issue is then labelled for review. It runs in the background since
+// copying the template can take minutes.
+func (d *Daemon) scaffoldProject(issue *gitlab.Issue) error {
+	namespace := d.config.Scaffold.Namespace
+	if namespace == "" {
+		namespace = path.Dir(d.selectedProject)
+	}
+	plan := parseScaffoldIssue(issue.Description, namespace)
+
+	if plan.projectPath == "" {
+		logging.Issue(issue.IID).Infof("Holding scaffold issue #%d: its description names no project", issue.IID)
+		if d.dryRun || d.semiDryRun {
+			return nil
+		}
+		d.postFailureNote(issue.IID, d.message(locale.MsgScaffoldNeedsProject, map[string]interface{}{"Label": d.config.Daemon.ClaudeLabel}))
+		d.holdIssue(issue.IID, d.config.Scaffold.Label)
+		return nil
+	}
+	if d.dryRun || d.semiDryRun {
+		logging.Issue(issue.IID).Infof("[DRY RUN] Would create %s from %s with %d issues", plan.projectPath, d.config.Scaffold.Template, len(plan.tasks))
+		return nil
+	}
+
+	return d.runIssueTask(issue, "scaffold", "scaffold "+plan.projectPath, locale.MsgScaffoldFailed, func() error {
+		return d.runScaffold(issue, plan)
+	})
+}
+
+// runIssueTask works on an issue without a Claude session: it marks the issue
+// as being processed, then runs task in the background. The task comments on
+// success and the issue goes to review; a failure is commented with
+// failureMsg, which takes Error and Label, and the issue gets the error label.
+func (d *Daemon) runIssueTask(issue *gitlab.Issue, name, detail, failureMsg string, task func() error) error {
+	labels := []string{d.config.Daemon.ProcessLabel}
+	for _, label := range issue.Labels {
+		if label != d.config.Daemon.ClaudeLabel && label != d.config.Daemon.ReviewLabel {
+			labels = append(labels, label)
+		}
+	}
+	if err := d.setIssueLabels(issue.IID, labels); err != nil {
+		return fmt.Errorf("failed to update issue labels: %v", err)
+	}
+	d.recordEvent(issue.IID, session.EventPickedUp, "", detail)
+
+	go d.survive(name+" of issue", issue.IID, func() {
+		if err := task(); err != nil {
+			logging.Issue(issue.IID).Errorf("Failed to %s for issue #%d: %v", detail, issue.IID, err)
+			d.recordEvent(issue.IID, session.EventFailed, "", name+": "+err.Error())
+			d.postFailureNote(issue.IID, d.message(failureMsg, map[string]interface{}{
+				"Error": err.Error(),
+				"Label": d.config.Daemon.ClaudeLabel,
+			}))
+			d.holdIssue(issue.IID, "error")
+			return
+		}
+
+		current, err := d.gitlabClient.GetIssue(d.selectedProject, issue.IID)
+		if err != nil {
+			logging.Issue(issue.IID).Warnf("Failed to get issue #%d for label update: %v", issue.IID, err)
+		} else {
+			labels := []string{d.config.Daemon.ReviewLabel}
+			for _, label := range current.Labels {
+				if label != d.config.Daemon.ProcessLabel && label != d.config.Daemon.ReviewLabel {
+					labels = append(labels, label)
+				}
+			}
+			if err := d.setIssueLabels(issue.IID, labels); err != nil {
+				logging.Issue(issue.IID).Warnf("Failed to update completion labels for issue #%d: %v", issue.IID, err)
+			}
+		}
+		d.recordEvent(issue.IID, session.EventCompleted, "", detail)
+	})
+	return nil
+}
+
+// runScaffold creates the project of a plan, or picks up the one an earlier
+// attempt created, and fills it in
+func (d *Daemon) runScaffold(issue *gitlab.Issue, plan scaffoldPlan) error {
+	project, err := d.gitlabClient.FindProject(plan.projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %v", plan.projectPath, err)
+	}
+	if project == nil {
+		logging.Issue(issue.IID).Infof("Creating %s from %s for issue #%d", plan.projectPath, d.config.Scaffold.Template, issue.IID)
+		project, err = d.gitlabClient.CreateProjectFromTemplate(d.config.Scaffold.Template, gitlab.NewProject{
+			Name:        path.Base(plan.projectPath),
+			Path:        path.Base(plan.projectPath),
+			Namespace:   path.Dir(plan.projectPath),
+			Visibility:  d.config.Scaffold.Visibility,
+			Description: issue.Title,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		logging.Issue(issue.IID).Infof("Project %s already exists, filling it in for issue #%d", project.PathWithNamespace, issue.IID)
+	}
+
+	branch := project.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+	readme := fmt.Sprintf("# %s\n\n%s\n\nSet up from `%s` for %s#%d.\n",
+		path.Base(project.PathWithNamespace), plan.overview, d.config.Scaffold.Template, d.selectedProject, issue.IID)
+	if err := d.gitlabClient.CommitFiles(project.ID, branch, fmt.Sprintf("Set up project for %s#%d", d.selectedProject, issue.IID),
+		map[string]string{"README.md": readme}); err != nil {
+		return err
+	}
+
+	// Checklist items already filed by an earlier attempt are not filed again
+	existing, err := d.gitlabClient.ListProjectIssues(project.PathWithNamespace, gitlab.IssueListOptions{State: "all"})
+	if err != nil {
+		return fmt.Errorf("failed to list issues of %s: %v", project.PathWithNamespace, err)
+	}
+	filed := make(map[string]bool, len(existing))
+	for _, other := range existing {
+		filed[other.Title] = true
+	}
+
+	var issues strings.Builder
+	for _, task := range plan.tasks {
+		if filed[task] {
+			continue
+		}
+		body := fmt.Sprintf("Part of the setup of this project, from %s#%d.", d.selectedProject, issue.IID)
+		created, err := d.createProjectIssue(project.PathWithNamespace, issueKindScaffold, task, body, d.config.Scaffold.IssueLabels...)
+		if err != nil {
+			return fmt.Errorf("failed to file %q: %v", task, err)
+		}
+		fmt.Fprintf(&issues, "- %s#%d %s\n", project.PathWithNamespace, created.IID, created.Title)
+	}
+
+	// A template that brings its own CI configuration keeps it
+	ci := ""
+	if d.config.Scaffold.CI {
+		proposal, err := d.setupCI(project, fmt.Sprintf("%s#%d", d.selectedProject, issue.IID), false)
+		if err != nil {
+			logging.Issue(issue.IID).Warnf("Failed to propose CI for %s: %v", project.PathWithNamespace, err)
+		} else if proposal != nil {
+			ci = fmt.Sprintf("[%s!%d](%s)", project.PathWithNamespace, proposal.mergeRequest.IID, proposal.mergeRequest.WebURL)
+		}
+	}
+
+	comment := d.message(locale.MsgScaffolded, map[string]interface{}{
+		"Project":  project.PathWithNamespace,
+		"Link":     project.WebURL,
+		"Template": d.config.Scaffold.Template,
+		"Issues":   strings.TrimSuffix(issues.String(), "\n"),
+		"CI":       ci,
+	})
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, comment); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to post scaffold comment on issue #%d: %v", issue.IID, err)
+	}
+	logging.Issue(issue.IID).Infof("Scaffolded %s for issue #%d", project.PathWithNamespace, issue.IID)
+	return nil
+}
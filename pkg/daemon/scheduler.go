@@ -0,0 +1,190 @@
+package daemon
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+)
+
+// errTierAtCapacity is returned when an issue cannot start because its
+// complexity tier already has the maximum number of running sessions
+var errTierAtCapacity = errors.New("complexity tier at capacity")
+
+// untieredTier is used for issues without a complexity label or weight
+const untieredTier = "untiered"
+
+// tierScheduler limits how many sessions may run concurrently per complexity tier
+type tierScheduler struct {
+	mu           sync.Mutex
+	limits       map[string]int // Max concurrent sessions per tier, 0 means unlimited
+	defaultLimit int            // Limit applied to untiered issues
+	running      map[string]int // Running sessions per tier
+	issueTiers   map[int]string // Tier held by each running issue
+}
+
+func newTierScheduler(limits map[string]int, defaultLimit int) *tierScheduler {
+	return &tierScheduler{
+		limits:       limits,
+		defaultLimit: defaultLimit,
+		running:      make(map[string]int),
+		issueTiers:   make(map[int]string),
+	}
+}
+
+// tryAcquire reserves a slot for the issue in its tier, returning false if the tier is full
+func (s *tierScheduler) tryAcquire(issueIID int, tier string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An issue that already holds a slot keeps it
+	if _, holding := s.issueTiers[issueIID]; holding {
+		return true
+	}
+
+	limit, exists := s.limits[tier]
+	if !exists {
+		limit = s.defaultLimit
+	}
+
+	if limit > 0 && s.running[tier] >= limit {
+		return false
+	}
+
+	s.running[tier]++
+	s.issueTiers[issueIID] = tier
+	return true
+}
+
+// release frees the slot held by the issue, if any
+func (s *tierScheduler) release(issueIID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tier, holding := s.issueTiers[issueIID]
+	if !holding {
+		return
+	}
+
+	delete(s.issueTiers, issueIID)
+	if s.running[tier] > 0 {
+		s.running[tier]--
+	}
+}
+
+// holds reports whether the issue holds a slot, i.e. is being worked on
+func (s *tierScheduler) holds(issueIID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, holding := s.issueTiers[issueIID]
+	return holding
+}
+
+// usage returns the number of running sessions per tier
+func (s *tierScheduler) usage() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := make(map[string]int, len(s.running))
+	for tier, count := range s.running {
+		usage[tier] = count
+	}
+	return usage
+}
+
+// issueTier determines the complexity tier of an issue. Triage labels
+// (T1-T4, optionally scoped as complexity::T1) win over issue weight.
+func issueTier(issue *gitlab.Issue) string {
+	for _, label := range issue.Labels {
+		name := strings.ToUpper(label)
+		if idx := strings.LastIndex(name, "::"); idx >= 0 {
+			name = name[idx+2:]
+		}
+		switch name {
+		case "T1", "T2", "T3", "T4":
+			return name
+		}
+	}
+
+	// Heavier issues take longer, so map weight onto the slower tiers
+	switch {
+	case issue.Weight >= 8:
+		return "T1"
+	case issue.Weight >= 5:
+		return "T2"
+	case issue.Weight >= 3:
+		return "T3"
+	case issue.Weight > 0:
+		return "T4"
+	}
+
+	return untieredTier
+}
+
+// Issue priorities, from priority:: labels or weight
+const (
+	priorityLow = iota + 1
+	priorityMedium
+	priorityHigh
+)
+
+// issuePriority determines the priority of an issue. A priority::high,
+// priority::medium or priority::low label wins over weight (5+ high, 3+
+// medium, 1+ low); issues with neither are medium.
+func issuePriority(issue *gitlab.Issue) int {
+	for _, label := range issue.Labels {
+		switch strings.ToLower(label) {
+		case "priority::high":
+			return priorityHigh
+		case "priority::medium":
+			return priorityMedium
+		case "priority::low":
+			return priorityLow
+		}
+	}
+
+	switch {
+	case issue.Weight >= 5:
+		return priorityHigh
+	case issue.Weight >= 3:
+		return priorityMedium
+	case issue.Weight > 0:
+		return priorityLow
+	}
+	return priorityMedium
+}
+
+// sortByPriority orders issues in the order they should start: by priority,
+// then oldest first. Every agingHours an issue has been open raises it one
+// priority level, so old low-priority issues are not starved by a steady
+// stream of high-priority ones. agingHours 0 turns aging off.
+func sortByPriority(issues []gitlab.Issue, agingHours int, now time.Time) {
+	created := make(map[int]time.Time, len(issues))
+	for _, issue := range issues {
+		createdAt, err := time.Parse(time.RFC3339, issue.CreatedAt)
+		if err != nil {
+			createdAt = now
+		}
+		created[issue.IID] = createdAt
+	}
+
+	rank := func(issue *gitlab.Issue) int {
+		priority := issuePriority(issue)
+		if agingHours > 0 {
+			priority += int(now.Sub(created[issue.IID]) / (time.Duration(agingHours) * time.Hour))
+		}
+		return priority
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if ri, rj := rank(&issues[i]), rank(&issues[j]); ri != rj {
+			return ri > rj
+		}
+		if !created[issues[i].IID].Equal(created[issues[j].IID]) {
+			return created[issues[i].IID].Before(created[issues[j].IID])
+		}
+		return issues[i].IID < issues[j].IID
+	})
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/errreport"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Delays before a background task that panicked is restarted
+const (
+	restartDelay    = 10 * time.Second
+	maxRestartDelay = 5 * time.Minute
+)
+
+// survive runs fn, keeping a panic in it from stopping the daemon. issueIID
+// is the issue fn works on, 0 for none. Start goroutines with it:
+//
+//	go d.survive("retry of issue", issueIID, func() { ... })
+func (d *Daemon) survive(where string, issueIID int, fn func()) {
+	defer d.recoverPanic(where, issueIID)
+	fn()
+}
+
+// recoverPanic, deferred, keeps a panic from stopping the daemon
+func (d *Daemon) recoverPanic(where string, issueIID int) {
+	if recovered := recover(); recovered != nil {
+		d.handlePanic(where, issueIID, recovered)
+	}
+}
+
+// supervise runs loop until ctx is done, restarting it with a growing delay
+// when it panics, so a bug in a background task does not stop the daemon
+func (d *Daemon) supervise(ctx context.Context, name string, loop func(ctx context.Context)) {
+	delay := restartDelay
+	for {
+		panicked := func() (panicked bool) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					d.handlePanic(name, 0, recovered)
+					panicked = true
+				}
+			}()
+			loop(ctx)
+			return false
+		}()
+		if !panicked {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		logging.Infof("Restarting %s after a panic", name)
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+// handlePanic logs and reports a recovered panic with the stack of the
+// panicking goroutine. A panic while working on an issue also fails the issue
+// as a failed session would: a failed event and the error label, so it is
+// not picked up again until someone re-adds the trigger label.
+func (d *Daemon) handlePanic(where string, issueIID int, recovered interface{}) {
+	// Leave out Stack, handlePanic and the deferred function that recovered
+	frames := errreport.Stack(3)
+
+	var stack strings.Builder
+	for i := len(frames) - 1; i >= 0; i-- {
+		fmt.Fprintf(&stack, "\n\t%s\n\t\t%s:%d", frames[i].Function, frames[i].File, frames[i].Line)
+	}
+	tags := map[string]string{"where": where}
+	if issueIID > 0 {
+		tags["issue"] = fmt.Sprintf("%s#%d", d.selectedProject, issueIID)
+		logging.Issue(issueIID).Errorf("Panic in %s #%d: %v%s", where, issueIID, recovered, stack.String())
+	} else {
+		logging.Errorf("Panic in %s: %v%s", where, recovered, stack.String())
+	}
+	if id := d.errorReports.Panic(where, recovered, tags, frames); id != "" {
+		logging.Infof("Panic in %s reported as %s", where, id)
+	}
+	d.telemetry.Error(string(claude.FailurePanic))
+	d.metrics.Error(string(claude.FailurePanic))
+
+	if issueIID == 0 || d.dryRun || d.semiDryRun {
+		return
+	}
+	d.scheduler.release(issueIID)
+	d.recordEvent(issueIID, session.EventFailed, "", fmt.Sprintf("%s %s: %v", claude.FailurePanic, where, recovered))
+	d.holdIssue(issueIID, "error")
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+	"github.com/bilbo290/automagic/pkg/terraform"
+)
+
+// planIssueTerraform runs terraform plan on the root modules a completed
+// issue session changed, in a worktree of its branch, and posts the plans on
+// the issue's merge request. It returns what to add to the completion
+// comment and whether a plan failed, which keeps the issue out of review.
+func (d *Daemon) planIssueTerraform(process *claude.Process, branch string) (string, bool) {
+	if !d.config.Terraform.Plan || d.dryRun || d.semiDryRun {
+		return "", false
+	}
+
+	files, err := changedFiles(process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to list the files changed for issue #%d: %v", process.IssueNum, err)
+		return "", false
+	}
+	modules := terraform.Modules(files)
+	if len(modules) == 0 {
+		return "", false
+	}
+
+	checkout, remove, err := d.branchWorktree(process.WorkingDir, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to check out %s for the Terraform plan of issue #%d: %v", branch, process.IssueNum, err)
+		return "", false
+	}
+	defer remove()
+
+	planner := &terraform.Planner{
+		Command: d.config.Terraform.Command,
+		Timeout: time.Duration(d.config.Terraform.PlanTimeout) * time.Minute,
+		DataDir: filepath.Join(d.config.Data.Dir, "terraform"),
+	}
+	var plans []string
+	failed := 0
+	for _, module := range modules {
+		// A module whose files were all deleted has nothing left to plan
+		if _, err := os.Stat(filepath.Join(checkout, filepath.FromSlash(module))); err != nil {
+			continue
+		}
+		logging.Issue(process.IssueNum).Infof("Planning Terraform module %s of issue #%d", module, process.IssueNum)
+		result := planner.Plan(checkout, module)
+		if result.Failed() {
+			failed++
+			plans = append(plans, fmt.Sprintf("- `%s`: failed\n\n  ```\n%s\n  ```", module, indent(result.Error, "  ")))
+		} else {
+			plans = append(plans, fmt.Sprintf("- `%s`: %s", module, result.Summary))
+		}
+	}
+	if len(plans) == 0 {
+		return "", false
+	}
+	summary := strings.Join(plans, "\n")
+	d.recordEvent(process.IssueNum, session.EventTerraformPlan, process.ClaudeSessionID, fmt.Sprintf("%d modules planned, %d failed", len(plans), failed))
+
+	posted := false
+	mr, err := d.issueMergeRequest(d.selectedProject, branch)
+	if err != nil {
+		logging.Issue(process.IssueNum).Warnf("Failed to look up MR for issue #%d: %v", process.IssueNum, err)
+	} else if mr != nil {
+		plan := d.message(locale.MsgTerraformPlan, map[string]interface{}{"Plans": summary})
+		if _, err := d.gitlabClient.CreateMergeRequestNote(d.selectedProject, mr.IID, plan); err != nil {
+			logging.Issue(process.IssueNum).Warnf("Failed to post the Terraform plan on !%d: %v", mr.IID, err)
+		} else {
+			posted = true
+		}
+	}
+
+	if failed > 0 {
+		return d.message(locale.MsgTerraformPlanFailed, map[string]interface{}{
+			"Plans": summary,
+			"Label": d.config.Daemon.ReviewLabel,
+		}), true
+	}
+	if !posted {
+		return d.message(locale.MsgTerraformPlan, map[string]interface{}{"Plans": summary}), false
+	}
+	return "", false
+}
+
+// branchWorktree checks a branch or commit out in a temporary worktree of
+// repoDir under DATA_DIR/worktrees, leaving the repository's own checkout
+// alone. remove deletes the worktree.
+func (d *Daemon) branchWorktree(repoDir, branch string) (string, func(), error) {
+	parent := filepath.Join(d.config.Data.Dir, "worktrees")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp(parent, "worktree-")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := gitIn(repoDir, "worktree", "add", "--detach", dir, branch); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return dir, func() {
+		gitIn(repoDir, "worktree", "remove", "--force", dir)
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// gitIn runs a git command in dir
+func gitIn(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
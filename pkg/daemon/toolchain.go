@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// errEnvironmentMissing is returned when an issue session is not started
+// because the host lacks a toolchain the project needs
+var errEnvironmentMissing = errors.New("host is missing a required toolchain")
+
+// defaultToolchains lists the commands a session needs to build and test
+// each language, keyed by GitLab's language name in lower case. Languages
+// not listed need nothing.
+var defaultToolchains = map[string][]string{
+	"go":         {"go"},
+	"javascript": {"node", "npm"},
+	"typescript": {"node", "npm"},
+	"vue":        {"node", "npm"},
+	"python":     {"python3"},
+	"rust":       {"cargo"},
+	"ruby":       {"ruby", "bundle"},
+	"java":       {"java"},
+	"kotlin":     {"java"},
+	"php":        {"php"},
+}
+
+// projectLanguages returns the main languages of the selected project: those
+// making up at least TOOLCHAIN_MIN_SHARE percent of its code by GitLab's
+// detection, or those implied by the build files of the checkout when the
+// detection fails or has not run yet
+func (d *Daemon) projectLanguages() []string {
+	shares, err := d.gitlabClient.GetProjectLanguages(d.selectedProject)
+	if err != nil {
+		logging.Warnf("Language detection failed for %s, checking build files: %v", d.selectedProject, err)
+	}
+	var languages []string
+	for language, share := range shares {
+		if share >= float64(d.config.Environment.MinLanguageShare) {
+			languages = append(languages, language)
+		}
+	}
+	if len(languages) > 0 {
+		sort.Strings(languages)
+		return languages
+	}
+
+	repoDir, err := claude.RepositoryDir(d.selectedProject, d.config.GitLab.URL, false)
+	if err != nil {
+		logging.Warnf("No checkout of %s to detect languages in: %v", d.selectedProject, err)
+		return nil
+	}
+	return claude.DetectLanguages(repoDir)
+}
+
+// toolchainCommands returns the commands a language needs, as overridden by
+// TOOLCHAINS
+func (d *Daemon) toolchainCommands(language string) []string {
+	for name, commands := range d.config.Environment.Toolchains {
+		if strings.EqualFold(name, language) {
+			return strings.Split(commands, "+")
+		}
+	}
+	return defaultToolchains[strings.ToLower(language)]
+}
+
+// missingToolchains lists the commands the project's main languages need
+// that are not on this host's PATH, one "`cmd` (Language)" entry each
+func (d *Daemon) missingToolchains() []string {
+	var missing []string
+	for _, language := range d.projectLanguages() {
+		for _, command := range d.toolchainCommands(language) {
+			command = strings.TrimSpace(command)
+			if command == "" {
+				continue
+			}
+			if _, err := exec.LookPath(command); err != nil {
+				missing = append(missing, fmt.Sprintf("`%s` (%s)", command, language))
+			}
+		}
+	}
+	return missing
+}
+
+// blockIssueForEnvironment reports the missing toolchains on the issue and
+// moves it to ENVIRONMENT_LABEL
+func (d *Daemon) blockIssueForEnvironment(issueIID int, missing []string) {
+	logging.Issue(issueIID).Infof("Not starting issue #%d: missing %s", issueIID, strings.Join(missing, ", "))
+
+	d.recordEvent(issueIID, session.EventFailed, "", "needs environment: "+strings.Join(missing, ", "))
+	d.postFailureNote(issueIID, d.message(locale.MsgNeedsEnvironment, map[string]interface{}{
+		"Missing": "- " + strings.Join(missing, "\n- "),
+		"Label":   d.config.Daemon.ClaudeLabel,
+	}))
+
+	d.holdIssue(issueIID, d.config.Environment.Label)
+}
+
+// sessionEnvironment prepares the development environment a project declares
+// when SESSION_ENVIRONMENT allows it, or returns nil to run on the host.
+// repoDir is the checkout, found or cloned when empty.
+func (d *Daemon) sessionEnvironment(projectPath, repoDir string) (*claude.Environment, error) {
+	mode := d.config.Environment.Session
+	if mode == "" || mode == "off" {
+		return nil, nil
+	}
+	if repoDir == "" {
+		var err error
+		if repoDir, err = claude.RepositoryDir(projectPath, d.config.GitLab.URL, false); err != nil {
+			return nil, err
+		}
+	}
+	kind := claude.DetectEnvironment(repoDir, mode)
+	if kind == "" {
+		return nil, nil
+	}
+
+	d.environmentMu.Lock()
+	defer d.environmentMu.Unlock()
+	logging.Infof("Preparing %s environment for %s", kind, projectPath)
+	return claude.PrepareEnvironment(kind, repoDir, projectPath, filepath.Join(d.config.Data.Dir, "environments"))
+}
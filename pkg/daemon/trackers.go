@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+	"github.com/bilbo290/automagic/pkg/tracker"
+)
+
+// trackerStatuses maps audit log events to the tracker status they move an
+// issue to
+var trackerStatuses = map[string]string{
+	session.EventPickedUp:        tracker.StatusPickedUp,
+	session.EventResumed:         tracker.StatusPickedUp,
+	session.EventCompleted:       tracker.StatusInReview,
+	session.EventResumeCompleted: tracker.StatusInReview,
+	session.EventMerged:          tracker.StatusDone,
+}
+
+// syncTrackers mirrors the status an event moves its issue to to the
+// external trackers, in the background
+func (d *Daemon) syncTrackers(event session.Event) {
+	trackers := d.trackers
+	status, ok := trackerStatuses[event.Kind]
+	if trackers == nil || !ok {
+		return
+	}
+
+	go func() {
+		issue, err := d.gitlabClient.GetIssue(event.ProjectPath, event.IssueIID)
+		if err != nil {
+			logging.Issue(event.IssueIID).Warnf("Failed to get issue #%d for tracker sync: %v", event.IssueIID, err)
+			return
+		}
+		synced, err := trackers.Sync(tracker.Transition{
+			ProjectPath: event.ProjectPath,
+			IssueIID:    event.IssueIID,
+			Title:       issue.Title,
+			Description: issue.Description,
+			URL:         issue.WebURL,
+			Status:      status,
+			Time:        event.Time,
+		})
+		if err != nil {
+			logging.Issue(event.IssueIID).Warnf("Failed to sync issue #%d to trackers: %v", event.IssueIID, err)
+		}
+		if len(synced) > 0 {
+			logging.Issue(event.IssueIID).Debugf("Synced issue #%d as %s to %v", event.IssueIID, status, synced)
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// transcriptDir is where transcripts are kept under DATA_DIR
+const transcriptDir = "transcripts"
+
+// startTranscript picks the file a session run of an issue records its output
+// in and indexes it, so "automagic -transcript" finds it while the run is
+// still going. It returns "" when transcripts are off.
+func (d *Daemon) startTranscript(issueIID int, sessionID, kind string) string {
+	index, ok := d.sessionStore.(session.TranscriptIndex)
+	if !ok || !d.config.Transcript.Enabled || d.dryRun || d.semiDryRun {
+		return ""
+	}
+
+	startedAt := time.Now()
+	path := filepath.Join(d.config.Data.Dir, transcriptDir, strings.ReplaceAll(d.selectedProject, "/", "_"),
+		fmt.Sprintf("%d-%s-%s.jsonl", issueIID, kind, startedAt.Format("20060102-150405")))
+	err := index.AddTranscript(session.Transcript{
+		IssueIID:    issueIID,
+		ProjectPath: d.selectedProject,
+		SessionID:   sessionID,
+		Kind:        kind,
+		Path:        path,
+		StartedAt:   startedAt,
+	})
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Not recording the transcript of issue #%d: %v", issueIID, err)
+		return ""
+	}
+	return path
+}
+
+// openTranscript starts the transcript of a run whose output the daemon
+// copies itself, as for resumes. When transcripts are off or the file cannot
+// be opened, writes to it are discarded.
+func (d *Daemon) openTranscript(issueIID int, sessionID, kind string) io.WriteCloser {
+	path := d.startTranscript(issueIID, sessionID, kind)
+	if path == "" {
+		return nopWriteCloser{io.Discard}
+	}
+	file, err := claude.OpenTranscript(path)
+	if err != nil {
+		logging.Issue(issueIID).Warnf("Not recording the transcript of issue #%d: %v", issueIID, err)
+		return nopWriteCloser{io.Discard}
+	}
+	return file
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// pruneTranscripts removes the transcripts past TRANSCRIPT_RETAIN_DAYS
+func (d *Daemon) pruneTranscripts() {
+	index, ok := d.sessionStore.(session.TranscriptIndex)
+	if !ok || d.config.Transcript.RetainDays <= 0 || d.dryRun || d.semiDryRun {
+		return
+	}
+
+	expired, err := index.PruneTranscripts(time.Now().AddDate(0, 0, -d.config.Transcript.RetainDays))
+	if err != nil {
+		logging.Warnf("Failed to prune transcripts: %v", err)
+		return
+	}
+	for _, transcript := range expired {
+		if err := os.Remove(transcript.Path); err != nil && !os.IsNotExist(err) {
+			logging.Warnf("Failed to remove transcript %s: %v", transcript.Path, err)
+		}
+	}
+	if len(expired) > 0 {
+		logging.Infof("Pruned %d transcripts past their retention", len(expired))
+	}
+}
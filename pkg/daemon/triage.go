@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+	"github.com/bilbo290/automagic/pkg/triage"
+)
+
+// triageTimeout limits one classification
+const triageTimeout = 2 * time.Minute
+
+// triageNewIssues classifies open issues without any label, up to
+// TRIAGE_LIMIT per cycle. Each gets a type and a complexity label and a
+// comment with Claude's estimate, but no session. It returns how many issues
+// were triaged.
+func (d *Daemon) triageNewIssues(ctx context.Context) int {
+	if !d.config.Triage.Enabled {
+		return 0
+	}
+
+	// GitLab's label filter "None" matches issues without labels
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{Labels: []string{"None"}, State: "opened"})
+	if err != nil {
+		logging.Warnf("Failed to list unlabeled issues for triage: %v", err)
+		return 0
+	}
+
+	classifier := &triage.Classifier{Command: d.config.Claude.Command, Model: d.config.Triage.Model}
+	triaged := 0
+	for i := range issues {
+		if triaged == d.config.Triage.Limit || ctx.Err() != nil {
+			break
+		}
+		issue := &issues[i]
+		if d.hasEvent(issue.IID, session.EventClassified) {
+			continue
+		}
+		triaged++
+		if d.dryRun || d.semiDryRun {
+			logging.Issue(issue.IID).Infof("[DRY RUN] Would triage issue #%d", issue.IID)
+			continue
+		}
+		d.triageIssue(ctx, classifier, issue)
+	}
+	return triaged
+}
+
+// triageIssue classifies an issue and labels it. A classification that fails
+// is recorded too, so the issue is left for a human instead of retried every
+// cycle.
+func (d *Daemon) triageIssue(ctx context.Context, classifier *triage.Classifier, issue *gitlab.Issue) {
+	ctx, cancel := context.WithTimeout(ctx, triageTimeout)
+	defer cancel()
+	classification, err := classifier.Classify(ctx, issue.Title, issue.Description)
+	if err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to triage issue #%d: %v", issue.IID, err)
+		d.recordEvent(issue.IID, session.EventClassified, "", "classification failed: "+err.Error())
+		return
+	}
+
+	labels := append([]string{}, issue.Labels...)
+	labels = append(labels, d.triageLabel(classification.Type), d.triageLabel(classification.Complexity))
+	if err := d.setIssueLabels(issue.IID, labels); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to label triaged issue #%d: %v", issue.IID, err)
+		return
+	}
+	logging.Issue(issue.IID).Infof("Triaged issue #%d as %s, %s", issue.IID, classification.Type, classification.Complexity)
+
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, d.message(locale.MsgTriaged, map[string]interface{}{
+		"Type":       d.triageLabel(classification.Type),
+		"Complexity": d.triageLabel(classification.Complexity),
+		"Estimate":   classification.Estimate,
+		"Reason":     classification.Reason,
+		"Label":      d.config.Daemon.ClaudeLabel,
+	})); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to post triage comment on issue #%d: %v", issue.IID, err)
+	}
+	d.recordEvent(issue.IID, session.EventClassified, "", "classified "+classification.Type+", "+classification.Complexity+", "+classification.Estimate)
+}
+
+// triageLabel returns the label TRIAGE_LABELS maps a type or tier to, the
+// name itself when it maps none
+func (d *Daemon) triageLabel(name string) string {
+	if label, ok := d.config.Triage.Labels[name]; ok {
+		return label
+	}
+	return name
+}
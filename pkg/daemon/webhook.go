@@ -0,0 +1,463 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/session"
+	"github.com/bilbo290/automagic/pkg/webhook"
+)
+
+// workflow is a set of daemon workflows to run on the next wake-up
+type workflow uint8
+
+const (
+	workflowIssues  workflow = 1 << iota // Pick up newly labelled issues
+	workflowResume                       // Resume sessions with new comments
+	workflowReviews                      // Review merge requests
+	workflowCIFix                        // Resume sessions whose pipeline failed
+
+	// pollWorkflows run on every polling tick; CI fixes are webhook-driven only
+	pollWorkflows = workflowIssues | workflowResume | workflowReviews
+)
+
+func (w workflow) has(other workflow) bool {
+	return w&other != 0
+}
+
+// failedPipeline is a failed pipeline reported by a webhook, awaiting a CI fix
+type failedPipeline struct {
+	projectPath string
+	ref         string
+	pipelineID  int
+}
+
+// issueBranchPattern extracts the issue number from an issue-N branch, or an
+// issue-N-rK branch of a later attempt
+var issueBranchPattern = regexp.MustCompile(`^issue-(\d+)(?:-r\d+)?$`)
+
+// startWebhook starts the webhook endpoint and returns the polling interval.
+// In webhook mode (-webhook) it also registers the endpoint on the monitored
+// project and polls only every WEBHOOK_POLL_INTERVAL seconds; if the endpoint
+// cannot listen or be registered, it falls back to polling every
+// DAEMON_INTERVAL seconds.
+func (d *Daemon) startWebhook(ctx context.Context) time.Duration {
+	interval := time.Duration(d.config.Daemon.Interval) * time.Second
+	cfg := &d.config.Webhook
+	if !cfg.Register {
+		d.startWebhookServer(ctx)
+		return interval
+	}
+
+	if cfg.Addr == "" || cfg.URL == "" {
+		logging.Warnf("Webhook mode needs WEBHOOK_ADDR and WEBHOOK_URL, falling back to polling")
+		return interval
+	}
+	if cfg.Secret == "" {
+		// The hook is registered with this secret, so it need not outlive the process
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			logging.Warnf("Failed to generate a webhook secret, falling back to polling: %v", err)
+			return interval
+		}
+		cfg.Secret = hex.EncodeToString(secret)
+	}
+	if !d.startWebhookServer(ctx) {
+		logging.Warnf("Webhook endpoint is not running, falling back to polling")
+		return interval
+	}
+	if err := d.registerWebhook(); err != nil {
+		logging.Warnf("Failed to register webhook on %s, falling back to polling: %v", d.selectedProject, err)
+		return interval
+	}
+
+	if cfg.PollInterval > 0 {
+		interval = time.Duration(cfg.PollInterval) * time.Second
+	}
+	logging.Infof("Webhook mode: GitLab delivers events to %s, polling every %s to catch missed deliveries", cfg.URL, interval)
+	return interval
+}
+
+// registerWebhook points a webhook of the monitored project at WEBHOOK_URL
+// with the current secret and events, updating an existing hook for that URL
+// rather than adding a second one
+func (d *Daemon) registerWebhook() error {
+	cfg := d.config.Webhook
+	hook := gitlab.ProjectHook{
+		URL:                   cfg.URL,
+		Token:                 cfg.Secret,
+		IssuesEvents:          cfg.IssueEvents,
+		NoteEvents:            cfg.NoteEvents,
+		MergeRequestsEvents:   cfg.MergeRequestEvents,
+		PipelineEvents:        cfg.PipelineEvents,
+		EnableSSLVerification: true,
+	}
+
+	hooks, err := d.gitlabClient.GetProjectHooks(d.selectedProject)
+	if err != nil {
+		return err
+	}
+	for _, existing := range hooks {
+		if existing.URL == cfg.URL {
+			return d.gitlabClient.EditProjectHook(d.selectedProject, existing.ID, hook)
+		}
+	}
+	_, err = d.gitlabClient.AddProjectHook(d.selectedProject, hook)
+	return err
+}
+
+// startWebhookServer serves the webhook endpoint until ctx is cancelled and
+// reports whether it is listening. Every accepted delivery wakes the polling
+// loop so it runs immediately.
+func (d *Daemon) startWebhookServer(ctx context.Context) bool {
+	cfg := d.config.Webhook
+	if cfg.Addr == "" {
+		return false
+	}
+	if cfg.Secret == "" {
+		logging.Warnf("WEBHOOK_ADDR is set but WEBHOOK_SECRET is empty, webhook endpoint disabled")
+		return false
+	}
+
+	// Persist delivery IDs when the store supports it so retries are dropped across restarts
+	deliveries, _ := d.sessionStore.(webhook.DeliveryStore)
+	handler := webhook.NewHandler(cfg.Secret, time.Duration(cfg.MaxAge)*time.Second, deliveries, d.handleWebhookEvent)
+
+	mux := http.NewServeMux()
+	mux.Handle("/webhook", handler)
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Listen before returning so a taken port is reported to the caller
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		logging.Warnf("Webhook server failed to listen on %s: %v", cfg.Addr, err)
+		return false
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		logging.Infof("Webhook endpoint listening on %s/webhook", cfg.Addr)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logging.Warnf("Webhook server stopped: %v", err)
+		}
+	}()
+	return true
+}
+
+// handleWebhookEvent persists each verified, fresh delivery and routes it to
+// the workflow it concerns
+func (d *Daemon) handleWebhookEvent(event webhook.Event) {
+	// Keep the delivery until a cycle has handled it, so a crash doesn't lose it
+	if queue, ok := d.sessionStore.(session.WebhookQueue); ok {
+		queued := session.QueuedWebhookEvent{
+			DeliveryID: event.DeliveryID,
+			Kind:       event.Kind,
+			Payload:    event.Payload,
+			ReceivedAt: event.ReceivedAt,
+		}
+		if queued.DeliveryID == "" {
+			queued.DeliveryID = fmt.Sprintf("%s-%d", event.Kind, event.ReceivedAt.UnixNano())
+		}
+		if err := queue.EnqueueWebhookEvent(queued); err != nil {
+			logging.Warnf("Failed to persist webhook delivery %s: %v", event.DeliveryID, err)
+		}
+	}
+
+	run := d.routeWebhookEvent(event.Kind, event.Payload)
+	if run == 0 {
+		logging.Infof("Webhook: ignoring %s (delivery %s)", event.Kind, event.DeliveryID)
+		return
+	}
+
+	logging.Infof("Webhook: received %s live (delivery %s)", event.Kind, event.DeliveryID)
+	d.wake(run)
+}
+
+// routeWebhookEvent maps a delivery to the workflows it should wake, honouring
+// the per-event toggles
+func (d *Daemon) routeWebhookEvent(kind string, payload []byte) workflow {
+	cfg := d.config.Webhook
+
+	var run workflow
+	switch kind {
+	case "Issue Hook":
+		if cfg.IssueEvents {
+			run = workflowIssues
+		}
+	case "Note Hook":
+		if cfg.NoteEvents {
+			run = workflowResume
+		}
+	case "Merge Request Hook":
+		if cfg.MergeRequestEvents {
+			run = workflowReviews
+		}
+	case "Pipeline Hook":
+		if cfg.PipelineEvents && d.queueFailedPipeline(payload) {
+			run = workflowCIFix
+		}
+	}
+
+	return run
+}
+
+// queueFailedPipeline queues a CI fix for failed pipelines on issue branches,
+// reporting whether the payload was queued
+func (d *Daemon) queueFailedPipeline(payload []byte) bool {
+	var body struct {
+		ObjectAttributes struct {
+			ID     int    `json:"id"`
+			Ref    string `json:"ref"`
+			Status string `json:"status"`
+		} `json:"object_attributes"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return false
+	}
+
+	attrs := body.ObjectAttributes
+	if attrs.Status != "failed" || !issueBranchPattern.MatchString(attrs.Ref) {
+		return false
+	}
+
+	d.addFailedPipeline(failedPipeline{
+		projectPath: body.Project.PathWithNamespace,
+		ref:         attrs.Ref,
+		pipelineID:  attrs.ID,
+	})
+	return true
+}
+
+func (d *Daemon) addFailedPipeline(pipeline failedPipeline) {
+	d.wakeMu.Lock()
+	defer d.wakeMu.Unlock()
+	d.failedPipelines = append(d.failedPipelines, pipeline)
+}
+
+// takeFailedPipelines returns and clears the queued pipeline failures
+func (d *Daemon) takeFailedPipelines() []failedPipeline {
+	d.wakeMu.Lock()
+	defer d.wakeMu.Unlock()
+	pipelines := d.failedPipelines
+	d.failedPipelines = nil
+	return pipelines
+}
+
+// fixFailedPipelines resumes the sessions whose issue branch pipeline failed,
+// returning how many were resumed
+func (d *Daemon) fixFailedPipelines(ctx context.Context, timestamp string) int {
+	resumed := 0
+	for _, pipeline := range d.takeFailedPipelines() {
+		if pipeline.projectPath != d.selectedProject {
+			continue
+		}
+
+		issueIID, _ := strconv.Atoi(issueBranchPattern.FindStringSubmatch(pipeline.ref)[1])
+		s, exists := d.sessionStore.GetCompletedSession(issueIID)
+		// Branches of superseded attempts are no longer worked on
+		if !exists || sessionBranch(s) != pipeline.ref {
+			continue
+		}
+		if _, running := d.resumeProcesses[issueIID]; running {
+			logging.Issue(issueIID).Infof("Pipeline #%d failed for issue #%d, but a resume is already running", pipeline.pipelineID, issueIID)
+			continue
+		}
+
+		logging.Issue(issueIID).Infof("Pipeline #%d failed for issue #%d, resuming session to fix it", pipeline.pipelineID, issueIID)
+		if err := d.resumeSessionWithCommentsWithContext(ctx, s, nil, nil); err != nil {
+			logging.Issue(issueIID).Errorf("Error resuming session for issue #%d: %v", issueIID, err)
+			continue
+		}
+		resumed++
+	}
+	return resumed
+}
+
+// wake requests an immediate run of the given workflows; requests made while
+// one is already pending are folded into it
+func (d *Daemon) wake(run workflow) {
+	d.wakeMu.Lock()
+	d.pendingWorkflows |= run
+	d.wakeMu.Unlock()
+
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// takePendingWorkflows returns and clears the workflows requested by webhooks
+func (d *Daemon) takePendingWorkflows() workflow {
+	d.wakeMu.Lock()
+	defer d.wakeMu.Unlock()
+	run := d.pendingWorkflows
+	d.pendingWorkflows = 0
+	return run
+}
+
+// wakeups merges the polling ticker with webhook triggers into one channel,
+// yielding the workflows to run on each wake-up. Ticks are dropped while the
+// project activity feed is quiet.
+func (d *Daemon) wakeups(ctx context.Context, tick <-chan time.Time) <-chan workflow {
+	wakeCh := make(chan workflow)
+	go d.supervise(ctx, "wake-ups", func(ctx context.Context) {
+		for {
+			var run workflow
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+				if d.projectActive() {
+					run = pollWorkflows
+				} else {
+					// A quiet tick is a cycle too; a hung loop blocks below instead
+					d.endCycle()
+				}
+			case <-d.trigger:
+			}
+			run |= d.takePendingWorkflows()
+			if run == 0 {
+				continue
+			}
+
+			select {
+			case wakeCh <- run:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return wakeCh
+}
+
+// webhookEnabled reports whether the webhook endpoint is configured to run
+func (d *Daemon) webhookEnabled() bool {
+	return d.config.Webhook.Addr != "" && d.config.Webhook.Secret != ""
+}
+
+// markSynced records that a cycle starting at cycleStart has handled
+// everything received before it
+func (d *Daemon) markSynced(cycleStart time.Time) {
+	queue, ok := d.sessionStore.(session.WebhookQueue)
+	if !ok || !d.webhookEnabled() || d.dryRun || d.semiDryRun {
+		return
+	}
+
+	if err := queue.MarkWebhookEventsHandled(cycleStart); err != nil {
+		logging.Warnf("Failed to mark webhook deliveries handled: %v", err)
+	}
+	if err := queue.SetLastSyncTime(cycleStart); err != nil {
+		logging.Warnf("Failed to record sync time: %v", err)
+	}
+}
+
+// reconcileMissedEvents recovers triggers lost while the daemon was down: it
+// replays deliveries that were queued but never handled, then compares GitLab
+// state changed since the last sync against what webhooks would have reported
+func (d *Daemon) reconcileMissedEvents() {
+	queue, ok := d.sessionStore.(session.WebhookQueue)
+	if !ok || !d.webhookEnabled() {
+		return
+	}
+
+	pending, err := queue.PendingWebhookEvents()
+	if err != nil {
+		logging.Warnf("Failed to load queued webhook deliveries: %v", err)
+	}
+	for _, event := range pending {
+		if run := d.routeWebhookEvent(event.Kind, event.Payload); run != 0 {
+			logging.Infof("Webhook: replaying queued %s (delivery %s, received %s)", event.Kind, event.DeliveryID, event.ReceivedAt.Format("2006-01-02 15:04:05"))
+			d.wake(run)
+		}
+	}
+
+	since, ok := queue.GetLastSyncTime()
+	if !ok {
+		logging.Infof("Reconciliation: no previous sync recorded, relying on the first poll")
+		return
+	}
+
+	recovered := 0
+	recover := func(run workflow, what string) {
+		logging.Infof("Reconciliation: recovered missed event for %s", what)
+		d.wake(run)
+		recovered++
+	}
+
+	issues, err := d.gitlabClient.ListProjectIssues(d.selectedProject, gitlab.IssueListOptions{
+		State:        "opened",
+		UpdatedAfter: since,
+	})
+	if err != nil {
+		logging.Warnf("Reconciliation failed to list issues: %v", err)
+	}
+	for _, issue := range issues {
+		switch {
+		case issue.HasAnyLabel([]string{d.config.Daemon.ClaudeLabel}) && d.config.Webhook.IssueEvents:
+			recover(workflowIssues, fmt.Sprintf("issue #%d (labelled %s)", issue.IID, d.config.Daemon.ClaudeLabel))
+		case issue.HasAnyLabel([]string{d.config.Daemon.ReviewLabel}) && d.config.Webhook.NoteEvents:
+			recover(workflowResume, fmt.Sprintf("issue #%d (activity while waiting for review)", issue.IID))
+		}
+	}
+
+	mergeRequests, err := d.gitlabClient.GetProjectMergeRequests(d.selectedProject, "opened")
+	if err != nil {
+		logging.Warnf("Reconciliation failed to list merge requests: %v", err)
+	}
+	for _, mr := range mergeRequests {
+		updatedAt, err := time.Parse(time.RFC3339, mr.UpdatedAt)
+		if err != nil || !updatedAt.After(since) {
+			continue
+		}
+
+		if d.config.Webhook.MergeRequestEvents {
+			recover(workflowReviews, fmt.Sprintf("MR !%d (updated)", mr.IID))
+		}
+
+		// Pipeline results are only delivered by webhook, so check issue branches explicitly
+		match := issueBranchPattern.FindStringSubmatch(mr.SourceBranch)
+		if match == nil || !d.config.Webhook.PipelineEvents {
+			continue
+		}
+		issueIID, _ := strconv.Atoi(match[1])
+		if s, exists := d.sessionStore.GetCompletedSession(issueIID); !exists || sessionBranch(s) != mr.SourceBranch {
+			continue
+		}
+
+		full, err := d.gitlabClient.GetMergeRequest(d.selectedProject, mr.IID)
+		if err != nil || full.HeadPipeline == nil || full.HeadPipeline.Status != "failed" {
+			continue
+		}
+		d.addFailedPipeline(failedPipeline{
+			projectPath: d.selectedProject,
+			ref:         mr.SourceBranch,
+			pipelineID:  full.HeadPipeline.ID,
+		})
+		recover(workflowCIFix, fmt.Sprintf("pipeline #%d on MR !%d (failed)", full.HeadPipeline.ID, mr.IID))
+	}
+
+	logging.Infof("Reconciliation: replayed %d queued deliveries, recovered %d missed events since %s", len(pending), recovered, since.Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// errOutsideWorkingHours is returned when an issue cannot start because the
+// daemon is outside its working hours
+var errOutsideWorkingHours = errors.New("outside working hours")
+
+// weekdays maps the three-letter day names accepted in WORKING_DAYS
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// workingHours is the part of the week new issues are started in. A nil
+// *workingHours is always open, which is how unrestricted hours are represented.
+type workingHours struct {
+	start, end time.Duration // Offsets into the day; end before start spans midnight
+	days       map[time.Weekday]bool
+	location   *time.Location
+
+	mu       sync.Mutex
+	notified map[int]bool // Issues told when processing resumes
+}
+
+// newWorkingHours parses WORKING_HOURS ("08:00-18:00"), WORKING_DAYS ("Mon-Fri"
+// or "Mon,Wed,Fri") and WORKING_TIMEZONE. It returns nil, and logs why, when
+// hours is empty or invalid, so issues are started at any time.
+func newWorkingHours(hours string, days []string, timezone string) *workingHours {
+	if hours == "" {
+		return nil
+	}
+	w, err := parseWorkingHours(hours, days, timezone)
+	if err != nil {
+		logging.Warnf("Ignoring working hours: %v", err)
+		return nil
+	}
+	return w
+}
+
+func parseWorkingHours(hours string, days []string, timezone string) (*workingHours, error) {
+	w := &workingHours{days: make(map[time.Weekday]bool), location: time.Local, notified: make(map[int]bool)}
+
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid WORKING_HOURS %q, expected e.g. 08:00-18:00", hours)
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid WORKING_HOURS %q: empty range", hours)
+	}
+
+	if len(days) == 0 {
+		days = []string{"mon-fri"}
+	}
+	for _, day := range days {
+		first, last, isRange := strings.Cut(strings.ToLower(day), "-")
+		from, ok := weekdays[first]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q in WORKING_DAYS", day)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[last]; !ok {
+				return nil, fmt.Errorf("invalid day %q in WORKING_DAYS", day)
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+
+	if timezone != "" {
+		if w.location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid WORKING_TIMEZONE %q: %v", timezone, err)
+		}
+	}
+	return w, nil
+}
+
+// parseClock parses a time of day such as 08:00
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q in WORKING_HOURS, expected HH:MM", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// open reports whether now falls within the working hours. A range spanning
+// midnight belongs to the day it starts on.
+func (w *workingHours) open(now time.Time) bool {
+	if w == nil {
+		return true
+	}
+	now = now.In(w.location)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, w.location)
+	offset := now.Sub(midnight)
+
+	if w.start < w.end {
+		return w.days[now.Weekday()] && offset >= w.start && offset < w.end
+	}
+	if offset >= w.start {
+		return w.days[now.Weekday()]
+	}
+	return offset < w.end && w.days[midnight.AddDate(0, 0, -1).Weekday()]
+}
+
+// next returns when the working hours open next after now
+func (w *workingHours) next(now time.Time) time.Time {
+	now = now.In(w.location)
+	for day := 0; day <= 7; day++ {
+		date := now.AddDate(0, 0, day)
+		start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, w.location).Add(w.start)
+		if start.After(now) && w.days[start.Weekday()] {
+			return start
+		}
+	}
+	return now
+}
+
+// resumes describes when the working hours open next, with the weekday when
+// that is not today, e.g. "08:00 +07" or "Mon 08:00 +07"
+func (w *workingHours) resumes(now time.Time) string {
+	next := w.next(now)
+	if next.YearDay() == now.In(w.location).YearDay() {
+		return next.Format("15:04 MST")
+	}
+	return next.Format("Mon 15:04 MST")
+}
+
+// notify reports whether the issue has not been told when processing resumes
+// yet, marking it told
+func (w *workingHours) notify(issueIID int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.notified[issueIID] {
+		return false
+	}
+	w.notified[issueIID] = true
+	return true
+}
+
+// forget lets the issue be told again the next time it waits for working hours
+func (w *workingHours) forget(issueIID int) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.notified, issueIID)
+}
+
+// noteQuietHours tells the reporter of an issue queued outside working hours
+// when processing resumes, once per issue
+func (d *Daemon) noteQuietHours(issue *gitlab.Issue) {
+	if !d.hours.notify(issue.IID) {
+		return
+	}
+	resumes := d.hours.resumes(time.Now())
+	logging.Issue(issue.IID).Infof("Queued issue #%d outside working hours, processing resumes at %s", issue.IID, resumes)
+	if d.dryRun || d.semiDryRun {
+		return
+	}
+
+	comment := d.message(locale.MsgQueuedQuietHours, map[string]interface{}{"Resumes": resumes})
+	if _, err := d.gitlabClient.CreateIssueNote(d.selectedProject, issue.IID, comment); err != nil {
+		logging.Issue(issue.IID).Warnf("Failed to post queued comment for issue #%d: %v", issue.IID, err)
+	}
+}
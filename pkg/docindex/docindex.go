@@ -0,0 +1,274 @@
+// Package docindex keeps an embedding index of a repository's documentation
+// and key source files, for finding the passages relevant to an issue.
+package docindex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of what is indexed
+const (
+	maxFileSize   = 256 * 1024 // Larger files are skipped
+	maxChunkChars = 2000       // Longer sections are split
+	codeChunkLine = 60         // Lines per chunk of a source file
+)
+
+// skippedDirs are never walked into
+var skippedDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+}
+
+// Chunk is an indexed passage of a file
+type Chunk struct {
+	File    string    `json:"file"`    // Path relative to the repository
+	Heading string    `json:"heading"` // Markdown section, or the line range of code
+	Text    string    `json:"text"`
+	Hash    string    `json:"hash"`
+	Vector  []float32 `json:"vector"`
+}
+
+// Index is the embedded chunks of one repository
+type Index struct {
+	Model  string  `json:"model"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Build indexes the markdown files of repoDir and the files matching
+// patterns, globs on the path relative to the repository or on the file name.
+// The index is cached at cachePath; chunks whose text has not changed since
+// the last build keep their vectors, so only new text is embedded.
+func Build(ctx context.Context, embedder *Embedder, repoDir string, patterns []string, cachePath string) (*Index, error) {
+	cached := make(map[string][]float32)
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var previous Index
+		if json.Unmarshal(data, &previous) == nil && previous.Model == embedder.Model {
+			for _, chunk := range previous.Chunks {
+				cached[chunk.Hash] = chunk.Vector
+			}
+		}
+	}
+
+	chunks, err := collectChunks(repoDir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []int
+	var texts []string
+	for i := range chunks {
+		if vector, ok := cached[chunks[i].Hash]; ok {
+			chunks[i].Vector = vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, chunks[i].File+"\n"+chunks[i].Heading+"\n"+chunks[i].Text)
+	}
+	if len(texts) > 0 {
+		fmt.Printf("Embedding %d of %d documentation chunks in %s\n", len(texts), len(chunks), repoDir)
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range missing {
+			chunks[i].Vector = vectors[j]
+		}
+	}
+
+	index := &Index{Model: embedder.Model, Chunks: chunks}
+	if err := index.save(cachePath); err != nil {
+		fmt.Printf("Warning: failed to cache documentation index: %v\n", err)
+	}
+	return index, nil
+}
+
+// Search returns the k chunks closest to query, closest first
+func (ix *Index) Search(ctx context.Context, embedder *Embedder, query string, k int) ([]Chunk, error) {
+	if len(ix.Chunks) == 0 || k <= 0 {
+		return nil, nil
+	}
+	vectors, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(ix.Chunks))
+	order := make([]int, len(ix.Chunks))
+	for i, chunk := range ix.Chunks {
+		scores[i] = cosine(vectors[0], chunk.Vector)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) > k {
+		order = order[:k]
+	}
+
+	results := make([]Chunk, len(order))
+	for i, n := range order {
+		results[i] = ix.Chunks[n]
+	}
+	return results, nil
+}
+
+// save writes the index to path
+func (ix *Index) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// collectChunks splits the indexed files of repoDir into chunks
+func collectChunks(repoDir string, patterns []string) ([]Chunk, error) {
+	var chunks []Chunk
+	err := filepath.WalkDir(repoDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != repoDir && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		markdown := strings.EqualFold(filepath.Ext(rel), ".md")
+		if !markdown && !matchesAny(rel, patterns) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		if markdown {
+			chunks = append(chunks, markdownChunks(rel, string(content))...)
+		} else {
+			chunks = append(chunks, codeChunks(rel, string(content))...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository files: %v", err)
+	}
+	return chunks, nil
+}
+
+// matchesAny reports whether a relative path or its file name matches one
+// of the globs
+func matchesAny(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownChunks splits a markdown file at its headings
+func markdownChunks(file, content string) []Chunk {
+	var chunks []Chunk
+	heading := ""
+	var section strings.Builder
+	flush := func() {
+		chunks = append(chunks, newChunks(file, heading, section.String())...)
+		section.Reset()
+	}
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		// Comments in code blocks also start with #
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+		section.WriteString(line)
+		section.WriteString("\n")
+	}
+	flush()
+	return chunks
+}
+
+// codeChunks splits a source file into windows of codeChunkLine lines
+func codeChunks(file, content string) []Chunk {
+	lines := strings.Split(content, "\n")
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += codeChunkLine {
+		end := start + codeChunkLine
+		if end > len(lines) {
+			end = len(lines)
+		}
+		heading := fmt.Sprintf("lines %d-%d", start+1, end)
+		chunks = append(chunks, newChunks(file, heading, strings.Join(lines[start:end], "\n"))...)
+	}
+	return chunks
+}
+
+// newChunks turns a section into chunks of at most maxChunkChars, dropping
+// blank ones
+func newChunks(file, heading, text string) []Chunk {
+	var chunks []Chunk
+	for text = strings.TrimSpace(text); text != ""; {
+		part := text
+		if len(part) > maxChunkChars {
+			cut := maxChunkChars
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			part = part[:cut]
+			if line := strings.LastIndex(part, "\n"); line > 0 {
+				part = part[:line]
+			}
+		}
+		text = strings.TrimSpace(text[len(part):])
+
+		sum := sha256.Sum256([]byte(file + "\x00" + heading + "\x00" + part))
+		chunks = append(chunks, Chunk{File: file, Heading: heading, Text: part, Hash: hex.EncodeToString(sum[:])})
+	}
+	return chunks
+}
+
+// cosine returns the cosine similarity of two vectors, 0 when they differ
+// in length or either is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,98 @@
+package docindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// embedBatch is how many texts are sent in one embeddings request
+const embedBatch = 32
+
+// Embedder turns text into vectors through an OpenAI-compatible embeddings
+// endpoint, such as OpenAI's own or a local Ollama server
+type Embedder struct {
+	URL    string // Base URL, the request goes to URL + "/embeddings"
+	Model  string
+	APIKey string // Sent as a bearer token when set
+
+	client *http.Client
+}
+
+// NewEmbedder creates an embeddings client
+func NewEmbedder(url, model, apiKey string) *Embedder {
+	return &Embedder{
+		URL:    strings.TrimSuffix(url, "/"),
+		Model:  model,
+		APIKey: apiKey,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Embed returns the vector of each text, in order
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatch {
+		end := start + embedBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embed sends one embeddings request
+func (e *Embedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.URL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request embeddings: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to request embeddings: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings: %v", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
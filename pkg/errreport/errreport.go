@@ -0,0 +1,255 @@
+// Package errreport sends daemon panics, repeated API failures and session
+// crashes to Sentry or a generic error webhook, so failure loops in an
+// unattended daemon get noticed.
+package errreport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of reported errors
+const (
+	KindPanic   = "panic"
+	KindAPI     = "api"
+	KindSession = "session"
+)
+
+// Event is an error sent to the configured destinations
+type Event struct {
+	ID            string            // Sentry event ID, generated when empty
+	Kind          string            // KindPanic, KindAPI or KindSession
+	Message       string            // One line describing the error
+	CorrelationID string            // Ties the report to log lines, e.g. a Claude session ID
+	Tags          map[string]string // Indexed values, e.g. issue and failure category
+	Extra         map[string]string // Context too long or unique to index, e.g. output tails
+	Fingerprint   []string          // Groups related events, defaults to kind and message
+	Frames        []Frame           // Stack of the error, innermost frame last
+	Fatal         bool              // The error stops the daemon
+	Time          time.Time
+}
+
+// Frame is a stack frame of an event
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"filename"`
+	Line     int    `json:"lineno"`
+}
+
+// Options configures where errors are reported
+type Options struct {
+	SentryDSN   string // Sentry project DSN, "" for none
+	WebhookURL  string // Receives each event as a JSON POST, "" for none
+	Environment string // Sentry environment, e.g. production
+	Release     string // automagic version
+	ServerName  string // Names this daemon, the hostname by default
+	// APIFailures is how many consecutive failures of a GitLab operation are
+	// reported; longer runs are reported again each time they double
+	APIFailures int
+}
+
+// Reporter reports errors. A nil *Reporter is valid and reports nothing,
+// which is how disabled error reporting is represented.
+type Reporter struct {
+	options Options
+	sentry  *sentryClient
+	webhook *webhookClient
+
+	mu          sync.Mutex
+	apiFailures map[string]int // Consecutive failures by operation
+	pending     sync.WaitGroup // Events being sent
+}
+
+// New returns a reporter for the options, or nil when they name no destination
+func New(options Options) (*Reporter, error) {
+	if options.SentryDSN == "" && options.WebhookURL == "" {
+		return nil, nil
+	}
+	if options.ServerName == "" {
+		options.ServerName, _ = os.Hostname()
+	}
+	if options.APIFailures <= 0 {
+		options.APIFailures = 3
+	}
+
+	r := &Reporter{options: options, apiFailures: make(map[string]int)}
+	if options.SentryDSN != "" {
+		sentry, err := newSentryClient(options.SentryDSN)
+		if err != nil {
+			return nil, err
+		}
+		r.sentry = sentry
+	}
+	if options.WebhookURL != "" {
+		r.webhook = newWebhookClient(options.WebhookURL)
+	}
+	return r, nil
+}
+
+// Report sends the event in the background and returns its ID, which the
+// caller logs so the report can be found from the log
+func (r *Reporter) Report(event Event) string {
+	if r == nil {
+		return ""
+	}
+	if event.ID == "" {
+		event.ID = NewID()
+	}
+	if event.CorrelationID == "" {
+		event.CorrelationID = event.ID
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if len(event.Fingerprint) == 0 {
+		event.Fingerprint = []string{event.Kind, event.Message}
+	}
+
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.send(event)
+	}()
+	return event.ID
+}
+
+func (r *Reporter) send(event Event) {
+	if r.sentry != nil {
+		if err := r.sentry.send(event, r.options); err != nil {
+			fmt.Printf("Warning: failed to report error to Sentry: %v\n", err)
+		}
+	}
+	if r.webhook != nil {
+		if err := r.webhook.send(event, r.options); err != nil {
+			fmt.Printf("Warning: failed to report error to the error webhook: %v\n", err)
+		}
+	}
+}
+
+// Flush waits up to timeout for events being sent
+func (r *Reporter) Flush(timeout time.Duration) {
+	if r == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// Recover reports a panic of the calling goroutine and panics again, so the
+// daemon still stops as it would without reporting. Use it deferred:
+//
+//	defer reporter.Recover("poll loop", tags)
+func (r *Reporter) Recover(where string, tags map[string]string) {
+	if r == nil {
+		return
+	}
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	event := panicEvent(where, recovered, tags, Stack(2))
+	event.Fatal = true
+	id := r.Report(event)
+	fmt.Printf("[%s] Panic in %s reported as %s\n", time.Now().Format("2006-01-02 15:04:05"), where, id)
+	r.Flush(5 * time.Second)
+	panic(recovered)
+}
+
+// Panic reports a panic that was recovered from, with the stack of the
+// panicking goroutine, and returns the event ID
+func (r *Reporter) Panic(where string, recovered interface{}, tags map[string]string, frames []Frame) string {
+	if r == nil {
+		return ""
+	}
+	return r.Report(panicEvent(where, recovered, tags, frames))
+}
+
+func panicEvent(where string, recovered interface{}, tags map[string]string, frames []Frame) Event {
+	return Event{
+		Kind:        KindPanic,
+		Message:     fmt.Sprintf("panic in %s: %v", where, recovered),
+		Tags:        tags,
+		Fingerprint: []string{KindPanic, where, fmt.Sprint(recovered)},
+		Frames:      frames,
+	}
+}
+
+// APIResult tracks the outcome of a GitLab operation, reporting when it has
+// failed APIFailures times in a row and again each time that run doubles.
+// A nil err ends the run.
+func (r *Reporter) APIResult(operation string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if err == nil {
+		delete(r.apiFailures, operation)
+		r.mu.Unlock()
+		return
+	}
+	r.apiFailures[operation]++
+	failures := r.apiFailures[operation]
+	r.mu.Unlock()
+
+	threshold := r.options.APIFailures
+	if failures < threshold || failures%threshold != 0 || !isPowerOfTwo(failures/threshold) {
+		return
+	}
+	id := r.Report(Event{
+		Kind:        KindAPI,
+		Message:     fmt.Sprintf("%s failed %d times in a row", operation, failures),
+		Tags:        map[string]string{"operation": operation},
+		Extra:       map[string]string{"last_error": err.Error(), "failures": fmt.Sprint(failures)},
+		Fingerprint: []string{KindAPI, operation},
+		Frames:      Stack(2),
+	})
+	fmt.Printf("[%s] %s failed %d times in a row, reported as %s\n",
+		time.Now().Format("2006-01-02 15:04:05"), operation, failures, id)
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Stack returns the stack of the calling goroutine, innermost frame last,
+// leaving out skip frames starting with Stack itself
+func Stack(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		// The runtime's own frames, e.g. of gopanic, say nothing about the error
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append([]Frame{{Function: frame.Function, File: frame.File, Line: frame.Line}}, stack...)
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// NewID returns a random 32 digit hex ID, the form Sentry expects event IDs in
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
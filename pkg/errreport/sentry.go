@@ -0,0 +1,126 @@
+package errreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sentryClient posts events to the store endpoint of a Sentry project
+type sentryClient struct {
+	storeURL  string
+	publicKey string
+	secretKey string
+	client    *http.Client
+}
+
+// newSentryClient parses a DSN of the form https://key@host/[path/]project
+func newSentryClient(dsn string) (*sentryClient, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid SENTRY_DSN, expected https://key@host/project")
+	}
+	path := strings.TrimRight(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid SENTRY_DSN, it names no project")
+	}
+	secret, _ := u.User.Password()
+
+	return &sentryClient{
+		storeURL:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:slash], project),
+		publicKey: u.User.Username(),
+		secretKey: secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// sentryEvent is the part of Sentry's event payload automagic fills in
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     string            `json:"message"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Fingerprint []string          `json:"fingerprint"`
+	Exception   *sentryExceptions `json:"exception,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []Frame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+func (s *sentryClient) send(event Event, options Options) error {
+	payload := sentryEvent{
+		EventID:     event.ID,
+		Timestamp:   event.Time.UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "automagic",
+		Environment: options.Environment,
+		ServerName:  options.ServerName,
+		Message:     event.Message,
+		Tags:        map[string]string{"kind": event.Kind, "correlation_id": event.CorrelationID},
+		Extra:       event.Extra,
+		Fingerprint: event.Fingerprint,
+	}
+	if event.Fatal {
+		payload.Level = "fatal"
+	}
+	if options.Release != "" {
+		payload.Release = "automagic@" + options.Release
+	}
+	for key, value := range event.Tags {
+		payload.Tags[key] = value
+	}
+	if len(event.Frames) > 0 {
+		exception := sentryException{Type: event.Kind, Value: event.Message}
+		exception.Stacktrace.Frames = event.Frames
+		payload.Exception = &sentryExceptions{Values: []sentryException{exception}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %v", err)
+	}
+	req, err := http.NewRequest("POST", s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=automagic/%s, sentry_key=%s", options.Release, s.publicKey)
+	if s.secretKey != "" {
+		auth += ", sentry_secret=" + s.secretKey
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Sentry returned %s", resp.Status)
+	}
+	return nil
+}
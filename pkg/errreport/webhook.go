@@ -0,0 +1,75 @@
+package errreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookClient posts events as JSON to any endpoint, e.g. a chat incoming
+// webhook behind a small adapter or an alerting system
+type webhookClient struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookClient(url string) *webhookClient {
+	return &webhookClient{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// webhookEvent is the payload posted to ERROR_WEBHOOK_URL
+type webhookEvent struct {
+	ID            string            `json:"id"`
+	Kind          string            `json:"kind"`
+	Message       string            `json:"message"`
+	CorrelationID string            `json:"correlation_id"`
+	Fatal         bool              `json:"fatal"`
+	Time          time.Time         `json:"time"`
+	Environment   string            `json:"environment,omitempty"`
+	Release       string            `json:"release,omitempty"`
+	Server        string            `json:"server,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	Extra         map[string]string `json:"extra,omitempty"`
+	Stack         string            `json:"stack,omitempty"`
+}
+
+func (w *webhookClient) send(event Event, options Options) error {
+	var stack strings.Builder
+	// Innermost frame first, as Go prints stacks
+	for i := len(event.Frames) - 1; i >= 0; i-- {
+		frame := event.Frames[i]
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+
+	body, err := json.Marshal(webhookEvent{
+		ID:            event.ID,
+		Kind:          event.Kind,
+		Message:       event.Message,
+		CorrelationID: event.CorrelationID,
+		Fatal:         event.Fatal,
+		Time:          event.Time,
+		Environment:   options.Environment,
+		Release:       options.Release,
+		Server:        options.ServerName,
+		Tags:          event.Tags,
+		Extra:         event.Extra,
+		Stack:         stack.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error webhook returned %s", resp.Status)
+	}
+	return nil
+}
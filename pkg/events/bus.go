@@ -0,0 +1,84 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// Bus delivers published events to its subscribers. Handlers run on the
+// publishing goroutine in the order they subscribed, so they see events in
+// the order they happened; a handler with slow work should hand it off to a
+// goroutine of its own. A nil *Bus drops everything published on it.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	id      int
+	handler func(Event)
+}
+
+// NewBus returns a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls handler with every event of type T published on the bus,
+// until the returned function is called:
+//
+//	events.Subscribe(bus, func(event events.SessionCompleted) { ... })
+func Subscribe[T Event](bus *Bus, handler func(T)) (unsubscribe func()) {
+	return bus.SubscribeAll(func(event Event) {
+		if typed, ok := event.(T); ok {
+			handler(typed)
+		}
+	})
+}
+
+// SubscribeAll calls handler with every event published on the bus, until
+// the returned function is called
+func (b *Bus) SubscribeAll(handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subscribers {
+			if s.id == id {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to the subscribers. A panicking handler is
+// logged and skipped so it cannot take the publisher down with it.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		deliver(s.handler, event)
+	}
+}
+
+func deliver(handler func(Event), event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.Errorf("Panic in %s event handler: %v", event.EventName(), recovered)
+		}
+	}()
+	handler(event)
+}
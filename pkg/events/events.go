@@ -0,0 +1,76 @@
+// Package events is the daemon's in-process publish/subscribe bus. The daemon
+// publishes what happens to issues, sessions and its GitLab calls; the audit
+// log, metrics, telemetry, error reporting and programs embedding the daemon
+// subscribe to the events they need instead of being called from its control
+// flow.
+package events
+
+import (
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Event is implemented by every event published on a Bus
+type Event interface {
+	// EventName identifies the type of the event, e.g. in JSON or logs
+	EventName() string
+}
+
+// IssueEvent is an entry of an issue's audit log, such as picked_up,
+// completed or resumed (the session.Event* kinds)
+type IssueEvent struct {
+	session.Event
+}
+
+// IssuePickedUp is published when the daemon takes an issue to work on
+type IssuePickedUp struct {
+	ProjectPath string
+	IssueIID    int
+	Time        time.Time
+}
+
+// SessionStarted is published when a Claude session starts on an issue or
+// merge request
+type SessionStarted struct {
+	ProjectPath     string
+	IssueIID        int    // 0 for merge request reviews
+	MergeRequestIID int    // 0 for issue sessions
+	Kind            string // session.RunIssue, session.RunResume or SessionReview
+	SessionID       string // Session being resumed, "" for new sessions
+	Time            time.Time
+}
+
+// SessionReview is the Kind of sessions reviewing a merge request
+const SessionReview = "review"
+
+// SessionCompleted is published when an issue or resume session ends,
+// successfully or not
+type SessionCompleted struct {
+	session.SessionRun
+}
+
+// LabelChanged is published when the daemon sets the labels of an issue or
+// merge request
+type LabelChanged struct {
+	ProjectPath     string
+	IssueIID        int // 0 for merge requests
+	MergeRequestIID int // 0 for issues
+	Labels          []string
+	Time            time.Time
+}
+
+// APIError is published when a GitLab operation of the polling loop fails,
+// and once more with a nil Err when it first succeeds again
+type APIError struct {
+	Operation string // e.g. "Checking issues"
+	Err       error
+	Time      time.Time
+}
+
+func (IssueEvent) EventName() string       { return "issue_event" }
+func (IssuePickedUp) EventName() string    { return "issue_picked_up" }
+func (SessionStarted) EventName() string   { return "session_started" }
+func (SessionCompleted) EventName() string { return "session_completed" }
+func (LabelChanged) EventName() string     { return "label_changed" }
+func (APIError) EventName() string         { return "api_error" }
@@ -0,0 +1,286 @@
+// Package feed renders a list of entries as an Atom, RSS 2.0 or JSON Feed
+// document and serves the three of them over HTTP, so activity can be
+// followed from any feed reader.
+package feed
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Feed is a titled list of entries, newest first
+type Feed struct {
+	Title   string
+	Link    string // Web page the feed is about
+	Updated time.Time
+	Entries []Entry
+}
+
+// Entry is one item of a feed
+type Entry struct {
+	ID       string // Stable and unique within the feed
+	Title    string
+	Link     string
+	Content  string // Plain text
+	Category string
+	Updated  time.Time
+}
+
+// Paths are the paths a Handler serves, for mounting it on a shared mux
+var Paths = []string{"/feed.atom", "/feed.rss", "/feed.json"}
+
+// Source builds the feed for a request, limited to the entries after since
+type Source func(since time.Time) (*Feed, error)
+
+// Handler serves a Source as:
+//
+//	GET /feed.atom
+//	GET /feed.rss
+//	GET /feed.json
+//
+// Each accepts ?since=RFC3339 (default the last 24 hours). Feed readers cannot
+// always set headers, so the token may be sent as ?token= as well as a bearer
+// token. Any of its tokens is accepted.
+type Handler struct {
+	source Source
+	tokens []string
+	mux    *http.ServeMux
+}
+
+// NewHandler creates a handler serving the feeds of source to requests that
+// carry one of tokens
+func NewHandler(source Source, tokens ...string) *Handler {
+	h := &Handler{source: source, mux: http.NewServeMux()}
+	for _, token := range tokens {
+		if token != "" {
+			h.tokens = append(h.tokens, token)
+		}
+	}
+	h.mux.HandleFunc("GET /feed.atom", h.serve(WriteAtom, "application/atom+xml; charset=utf-8"))
+	h.mux.HandleFunc("GET /feed.rss", h.serve(WriteRSS, "application/rss+xml; charset=utf-8"))
+	h.mux.HandleFunc("GET /feed.json", h.serve(WriteJSON, "application/feed+json; charset=utf-8"))
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	for _, accepted := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(accepted)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) serve(write func(io.Writer, *Feed) error, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since := time.Now().Add(-24 * time.Hour)
+		if value := r.URL.Query().Get("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			since = parsed
+		}
+
+		feed, err := h.source(since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		write(w, feed)
+	}
+}
+
+// updated is when the feed last changed: its newest entry, or Updated
+func (f *Feed) updated() time.Time {
+	updated := f.Updated
+	for _, entry := range f.Entries {
+		if entry.Updated.After(updated) {
+			updated = entry.Updated
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated.UTC()
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID       string        `xml:"id"`
+	Title    string        `xml:"title"`
+	Link     *atomLink     `xml:"link,omitempty"`
+	Updated  string        `xml:"updated"`
+	Category *atomCategory `xml:"category,omitempty"`
+	Content  string        `xml:"content,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// WriteAtom writes the feed as an Atom 1.0 document
+func WriteAtom(w io.Writer, f *Feed) error {
+	doc := atomFeed{
+		ID:      f.Link,
+		Title:   f.Title,
+		Link:    atomLink{Href: f.Link},
+		Updated: f.updated().Format(time.RFC3339),
+		Author:  f.Title,
+	}
+	for _, entry := range f.Entries {
+		item := atomEntry{
+			ID:      entry.ID,
+			Title:   entry.Title,
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+			Content: entry.Content,
+		}
+		if entry.Link != "" {
+			item.Link = &atomLink{Href: entry.Link, Rel: "alternate"}
+		}
+		if entry.Category != "" {
+			item.Category = &atomCategory{Term: entry.Category}
+		}
+		doc.Entries = append(doc.Entries, item)
+	}
+	return writeXML(w, doc)
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	GUID        rssGUID `xml:"guid"`
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	Category    string  `xml:"category,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssDocument struct {
+	XMLName       xml.Name  `xml:"rss"`
+	Version       string    `xml:"version,attr"`
+	Title         string    `xml:"channel>title"`
+	Link          string    `xml:"channel>link"`
+	Description   string    `xml:"channel>description"`
+	LastBuildDate string    `xml:"channel>lastBuildDate"`
+	Items         []rssItem `xml:"channel>item"`
+}
+
+// WriteRSS writes the feed as an RSS 2.0 document
+func WriteRSS(w io.Writer, f *Feed) error {
+	doc := rssDocument{
+		Version:       "2.0",
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Title,
+		LastBuildDate: f.updated().Format(time.RFC1123Z),
+	}
+	for _, entry := range f.Entries {
+		doc.Items = append(doc.Items, rssItem{
+			GUID:        rssGUID{Value: entry.ID},
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Content,
+			Category:    entry.Category,
+			PubDate:     entry.Updated.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode feed: %v", err)
+	}
+	return nil
+}
+
+type jsonItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url,omitempty"`
+	Title         string   `json:"title"`
+	ContentText   string   `json:"content_text"`
+	DateModified  string   `json:"date_modified"`
+	DatePublished string   `json:"date_published"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+// WriteJSON writes the feed as a JSON Feed 1.1 document
+func WriteJSON(w io.Writer, f *Feed) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		Items:       []jsonItem{},
+	}
+	for _, entry := range f.Entries {
+		item := jsonItem{
+			ID:            entry.ID,
+			URL:           entry.Link,
+			Title:         entry.Title,
+			ContentText:   entry.Content,
+			DateModified:  entry.Updated.UTC().Format(time.RFC3339),
+			DatePublished: entry.Updated.UTC().Format(time.RFC3339),
+		}
+		if entry.Category != "" {
+			item.Tags = []string{entry.Category}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode feed: %v", err)
+	}
+	return nil
+}
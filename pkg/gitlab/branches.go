@@ -0,0 +1,32 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BranchExists reports whether a project has a branch with the given name
+func (c *Client) BranchExists(projectPath, branch string) (bool, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/repository/branches/%s", encodedPath, url.PathEscape(branch))
+
+	if _, err := c.makeRequest(endpoint); err != nil {
+		if strings.Contains(err.Error(), "status 404") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateBranch creates a branch from ref, a branch name or commit SHA
+func (c *Client) CreateBranch(projectID int, branch, ref string) error {
+	endpoint := fmt.Sprintf("/projects/%d/repository/branches", projectID)
+	payload := map[string]string{"branch": branch, "ref": ref}
+	if _, err := c.doJSONRequest("POST", endpoint, payload, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create branch %s: %v", branch, err)
+	}
+	return nil
+}
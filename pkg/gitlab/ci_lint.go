@@ -0,0 +1,31 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// CILint is GitLab's verdict on a CI configuration
+type CILint struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// LintCIConfig validates CI configuration content in the context of a
+// project, so includes and the project's CI/CD variables resolve as they
+// would in a pipeline
+func (c *Client) LintCIConfig(projectID int, content string) (*CILint, error) {
+	endpoint := fmt.Sprintf("/projects/%d/ci/lint", projectID)
+	respBody, err := c.doJSONRequest("POST", endpoint, map[string]string{"content": content}, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lint CI configuration: %v", err)
+	}
+
+	var lint CILint
+	if err := json.Unmarshal(respBody, &lint); err != nil {
+		return nil, fmt.Errorf("failed to parse CI lint response: %v", err)
+	}
+	return &lint, nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,6 +23,7 @@ type Issue struct {
 	UpdatedAt   string   `json:"updated_at"`
 	Labels      []string `json:"labels"`
 	WebURL      string   `json:"web_url"`
+	Weight      int      `json:"weight"`
 	Author      struct {
 		ID       int    `json:"id"`
 		Name     string `json:"name"`
@@ -31,6 +34,41 @@ type Issue struct {
 		Name     string `json:"name"`
 		Username string `json:"username"`
 	} `json:"assignee"`
+	Assignees    []User     `json:"assignees"`
+	Milestone    *Milestone `json:"milestone"`
+	DueDate      string     `json:"due_date"` // YYYY-MM-DD, empty if unset
+	Confidential bool       `json:"confidential"`
+	TimeStats    TimeStats  `json:"time_stats"`
+}
+
+// Milestone represents a GitLab milestone
+type Milestone struct {
+	ID      int    `json:"id"`
+	IID     int    `json:"iid"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	DueDate string `json:"due_date"`
+	WebURL  string `json:"web_url"`
+}
+
+// TimeStats holds the time tracking totals of an issue, in seconds
+type TimeStats struct {
+	TimeEstimate        int    `json:"time_estimate"`
+	TotalTimeSpent      int    `json:"total_time_spent"`
+	HumanTimeEstimate   string `json:"human_time_estimate"`
+	HumanTotalTimeSpent string `json:"human_total_time_spent"`
+}
+
+// AssigneeUsernames returns the usernames of all assignees
+func (i *Issue) AssigneeUsernames() []string {
+	usernames := make([]string, 0, len(i.Assignees))
+	for _, assignee := range i.Assignees {
+		usernames = append(usernames, assignee.Username)
+	}
+	if len(usernames) == 0 && i.Assignee.Username != "" {
+		usernames = append(usernames, i.Assignee.Username)
+	}
+	return usernames
 }
 
 type Project struct {
@@ -43,6 +81,16 @@ type Project struct {
 	DefaultBranch     string `json:"default_branch"`
 	Visibility        string `json:"visibility"`
 	LastActivityAt    string `json:"last_activity_at"`
+	ImportStatus      string `json:"import_status"` // Progress of a new fork, "finished" once it can be pushed to
+	Permissions       struct {
+		ProjectAccess *ProjectAccess `json:"project_access"`
+		GroupAccess   *ProjectAccess `json:"group_access"`
+	} `json:"permissions"`
+}
+
+// ProjectAccess is the current user's access level to a project
+type ProjectAccess struct {
+	AccessLevel int `json:"access_level"`
 }
 
 type MergeRequest struct {
@@ -54,9 +102,13 @@ type MergeRequest struct {
 	State        string `json:"state"`
 	CreatedAt    string `json:"created_at"`
 	UpdatedAt    string `json:"updated_at"`
+	MergedAt     string `json:"merged_at"`
 	SourceBranch string `json:"source_branch"`
 	TargetBranch string `json:"target_branch"`
 	WebURL       string `json:"web_url"`
+	SHA          string `json:"sha"` // Head commit of the source branch
+	// HeadPipeline is only populated when fetching a single merge request
+	HeadPipeline *Pipeline `json:"head_pipeline"`
 	Author       struct {
 		ID       int    `json:"id"`
 		Name     string `json:"name"`
@@ -86,12 +138,16 @@ type Discussion struct {
 }
 
 type Note struct {
-	ID        int    `json:"id"`
-	Body      string `json:"body"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	System    bool   `json:"system"`
-	Author    struct {
+	ID         int    `json:"id"`
+	Body       string `json:"body"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	System     bool   `json:"system"`
+	Resolvable bool   `json:"resolvable"`
+	Resolved   bool   `json:"resolved"`
+	// DiscussionID is filled in by the client when notes are flattened out of discussions
+	DiscussionID string `json:"-"`
+	Author       struct {
 		ID       int    `json:"id"`
 		Name     string `json:"name"`
 		Username string `json:"username"`
@@ -102,14 +158,19 @@ type Client struct {
 	BaseURL string
 	Token   string
 	client  *http.Client
+	retry   RetryPolicy
+	users   *UserCache
 }
 
 func NewClient(baseURL, token string) *Client {
-	return &Client{
+	c := &Client{
 		BaseURL: baseURL,
 		Token:   token,
 		client:  &http.Client{Timeout: 10 * time.Second}, // Reduced from 30s to 10s
+		retry:   DefaultRetryPolicy,
 	}
+	c.users = newUserCache(c)
+	return c
 }
 
 func (c *Client) makeRequest(endpoint string) ([]byte, error) {
@@ -127,7 +188,7 @@ func (c *Client) makeRequestWithContext(ctx context.Context, endpoint string) ([
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %v", err)
 	}
@@ -145,6 +206,46 @@ func (c *Client) makeRequestWithContext(ctx context.Context, endpoint string) ([
 	return body, nil
 }
 
+// doJSONRequest sends a request with an optional JSON payload and returns the
+// response body, failing unless the response has the expected status code
+func (c *Client) doJSONRequest(method, endpoint string, payload interface{}, expectedStatus int) ([]byte, error) {
+	requestURL := fmt.Sprintf("%s/api/v4%s", c.BaseURL, endpoint)
+
+	var reqBody io.Reader
+	if payload != nil {
+		jsonPayload, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		reqBody = strings.NewReader(string(jsonPayload))
+	}
+
+	req, err := http.NewRequest(method, requestURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
+
 func (c *Client) TestConnection() error {
 	_, err := c.makeRequest("/user")
 	return err
@@ -194,21 +295,54 @@ func (c *Client) SearchProjects(query string) ([]Project, error) {
 	return projects, nil
 }
 
+// IssueListOptions controls filtering when listing project issues
+type IssueListOptions struct {
+	Labels       []string // Issues must carry all of these labels
+	NotLabels    []string // Issues must carry none of these labels
+	State        string
+	UpdatedAfter time.Time // Only issues updated after this time, zero means any
+	Search       string    // Issues must contain these words in their title or description
+	Page         int       // Page of 100 issues to fetch, 0 for the first
+}
+
 func (c *Client) GetProjectIssues(projectPath string, labels []string, state string) ([]Issue, error) {
+	return c.ListProjectIssues(projectPath, IssueListOptions{Labels: labels, State: state})
+}
+
+// ListProjectIssues fetches project issues using the given filter options
+func (c *Client) ListProjectIssues(projectPath string, opts IssueListOptions) ([]Issue, error) {
 	// URL encode the project path
 	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
 
-	endpoint := fmt.Sprintf("/projects/%s/issues?per_page=100", encodedPath)
+	query := url.Values{}
+	query.Set("per_page", "100")
 
-	if len(labels) > 0 {
-		labelStr := strings.Join(labels, ",")
-		endpoint += "&labels=" + labelStr
+	if len(opts.Labels) > 0 {
+		query.Set("labels", strings.Join(opts.Labels, ","))
 	}
 
-	if state != "" {
-		endpoint += "&state=" + state
+	if len(opts.NotLabels) > 0 {
+		query.Set("not[labels]", strings.Join(opts.NotLabels, ","))
+	}
+
+	if opts.State != "" {
+		query.Set("state", opts.State)
+	}
+
+	if !opts.UpdatedAfter.IsZero() {
+		query.Set("updated_after", opts.UpdatedAfter.UTC().Format(time.RFC3339))
+	}
+
+	if opts.Search != "" {
+		query.Set("search", opts.Search)
+	}
+
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
 	}
 
+	endpoint := fmt.Sprintf("/projects/%s/issues?%s", encodedPath, query.Encode())
+
 	body, err := c.makeRequest(endpoint)
 	if err != nil {
 		return nil, err
@@ -222,6 +356,18 @@ func (c *Client) GetProjectIssues(projectPath string, labels []string, state str
 	return issues, nil
 }
 
+// HasAnyLabel reports whether the issue carries at least one of the given labels
+func (i *Issue) HasAnyLabel(labels []string) bool {
+	for _, label := range i.Labels {
+		for _, candidate := range labels {
+			if label == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *Client) GetIssue(projectPath string, issueIID int) (*Issue, error) {
 	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
 	endpoint := fmt.Sprintf("/projects/%s/issues/%d", encodedPath, issueIID)
@@ -239,6 +385,25 @@ func (c *Client) GetIssue(projectPath string, issueIID int) (*Issue, error) {
 	return &issue, nil
 }
 
+// GetIssueParticipants returns the users who took part in an issue: its
+// author, assignees and commenters
+func (c *Client) GetIssueParticipants(projectPath string, issueIID int) ([]User, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/issues/%d/participants", encodedPath, issueIID)
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []User
+	if err := json.Unmarshal(body, &participants); err != nil {
+		return nil, fmt.Errorf("failed to parse issue participants: %v", err)
+	}
+
+	return participants, nil
+}
+
 func (c *Client) UpdateIssueLabels(projectPath string, issueIID int, labels []string) error {
 	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
 	endpoint := fmt.Sprintf("/projects/%s/issues/%d", encodedPath, issueIID)
@@ -254,7 +419,7 @@ func (c *Client) UpdateIssueLabels(projectPath string, issueIID int, labels []st
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to make request: %v", err)
 	}
@@ -343,6 +508,7 @@ func (c *Client) GetIssueCommentsAfterWithContext(ctx context.Context, projectPa
 
 			// Only include comments after the specified time
 			if createdAt.After(afterTime) {
+				note.DiscussionID = discussion.ID
 				newComments = append(newComments, note)
 			}
 		}
@@ -401,7 +567,7 @@ func (c *Client) CreateIssueNote(projectPath string, issueIID int, body string)
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %v", err)
 	}
@@ -424,6 +590,17 @@ func (c *Client) CreateIssueNote(projectPath string, issueIID int, body string)
 	return &note, nil
 }
 
+// UpdateIssueNote replaces the body of an existing issue comment
+func (c *Client) UpdateIssueNote(projectPath string, issueIID, noteID int, body string) error {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/issues/%d/notes/%d", encodedPath, issueIID, noteID)
+
+	if _, err := c.doJSONRequest("PUT", endpoint, map[string]string{"body": body}, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to update note: %v", err)
+	}
+	return nil
+}
+
 func (c *Client) GetAssignedMergeRequests(username string, state string) ([]MergeRequest, error) {
 	// Try with scope=all to get MRs from all accessible projects
 	endpoint := fmt.Sprintf("/merge_requests?assignee_username=%s&scope=all&per_page=100", username)
@@ -544,6 +721,36 @@ func (c *Client) GetMergeRequestDiscussionsWithContext(ctx context.Context, proj
 	return allDiscussions, nil
 }
 
+// GetMergeRequestCommentsAfterWithContext returns non-system MR notes created after the given time,
+// tagged with the discussion they belong to
+func (c *Client) GetMergeRequestCommentsAfterWithContext(ctx context.Context, projectPath string, mergeRequestIID int, afterTime time.Time) ([]Note, error) {
+	discussions, err := c.GetMergeRequestDiscussionsWithContext(ctx, projectPath, mergeRequestIID)
+	if err != nil {
+		return nil, err
+	}
+
+	var newComments []Note
+	for _, discussion := range discussions {
+		for _, note := range discussion.Notes {
+			if note.System {
+				continue
+			}
+
+			createdAt, err := time.Parse(time.RFC3339, note.CreatedAt)
+			if err != nil {
+				continue
+			}
+
+			if createdAt.After(afterTime) {
+				note.DiscussionID = discussion.ID
+				newComments = append(newComments, note)
+			}
+		}
+	}
+
+	return newComments, nil
+}
+
 func (c *Client) CreateMergeRequestNote(projectPath string, mergeRequestIID int, body string) (*Note, error) {
 	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
 	url := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/notes", c.BaseURL, encodedPath, mergeRequestIID)
@@ -565,7 +772,7 @@ func (c *Client) CreateMergeRequestNote(projectPath string, mergeRequestIID int,
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %v", err)
 	}
@@ -588,6 +795,357 @@ func (c *Client) CreateMergeRequestNote(projectPath string, mergeRequestIID int,
 	return &note, nil
 }
 
+// Commit represents a repository commit
+type Commit struct {
+	ID         string `json:"id"`
+	ShortID    string `json:"short_id"`
+	Title      string `json:"title"`
+	AuthorName string `json:"author_name"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Diff represents the changes to a single file
+type Diff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// Compare is the result of comparing two refs
+type Compare struct {
+	Commits        []Commit `json:"commits"`
+	Diffs          []Diff   `json:"diffs"`
+	CompareSameRef bool     `json:"compare_same_ref"`
+}
+
+// CompareCommits returns the commits and diffs between two refs (from...to)
+func (c *Client) CompareCommits(projectID int, from, to string) (*Compare, error) {
+	endpoint := fmt.Sprintf("/projects/%d/repository/compare?from=%s&to=%s", projectID, url.QueryEscape(from), url.QueryEscape(to))
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var compare Compare
+	if err := json.Unmarshal(body, &compare); err != nil {
+		return nil, fmt.Errorf("failed to parse compare result: %v", err)
+	}
+
+	return &compare, nil
+}
+
+// CommitStatus is a single CI status reported against a commit
+type CommitStatus struct {
+	ID           int    `json:"id"`
+	SHA          string `json:"sha"`
+	Ref          string `json:"ref"`
+	Status       string `json:"status"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	TargetURL    string `json:"target_url"`
+	AllowFailure bool   `json:"allow_failure"`
+	CreatedAt    string `json:"created_at"`
+	FinishedAt   string `json:"finished_at"`
+}
+
+// Pipeline represents a GitLab CI pipeline
+type Pipeline struct {
+	ID         int    `json:"id"`
+	IID        int    `json:"iid"`
+	ProjectID  int    `json:"project_id"`
+	SHA        string `json:"sha"`
+	Ref        string `json:"ref"`
+	Status     string `json:"status"`
+	Source     string `json:"source"`
+	WebURL     string `json:"web_url"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// Job represents a single job within a pipeline
+type Job struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Stage         string  `json:"stage"`
+	Status        string  `json:"status"`
+	AllowFailure  bool    `json:"allow_failure"`
+	FailureReason string  `json:"failure_reason"`
+	Duration      float64 `json:"duration"`
+	WebURL        string  `json:"web_url"`
+}
+
+// IsFinished reports whether the pipeline has reached a terminal status
+func (p *Pipeline) IsFinished() bool {
+	switch p.Status {
+	case "success", "failed", "canceled", "skipped", "manual":
+		return true
+	}
+	return false
+}
+
+// GetCommitStatuses returns the CI statuses reported for a commit
+func (c *Client) GetCommitStatuses(projectID int, sha string) ([]CommitStatus, error) {
+	endpoint := fmt.Sprintf("/projects/%d/repository/commits/%s/statuses?per_page=100", projectID, url.PathEscape(sha))
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []CommitStatus
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to parse commit statuses: %v", err)
+	}
+
+	return statuses, nil
+}
+
+// GetPipeline returns a single pipeline
+func (c *Client) GetPipeline(projectID, pipelineID int) (*Pipeline, error) {
+	endpoint := fmt.Sprintf("/projects/%d/pipelines/%d", projectID, pipelineID)
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var pipeline Pipeline
+	if err := json.Unmarshal(body, &pipeline); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline: %v", err)
+	}
+
+	return &pipeline, nil
+}
+
+// GetMergeRequestPipelines returns the pipelines of a merge request, newest first
+func (c *Client) GetMergeRequestPipelines(projectPath string, mrIID int) ([]Pipeline, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/merge_requests/%d/pipelines?per_page=20", encodedPath, mrIID)
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var pipelines []Pipeline
+	if err := json.Unmarshal(body, &pipelines); err != nil {
+		return nil, fmt.Errorf("failed to parse merge request pipelines: %v", err)
+	}
+
+	return pipelines, nil
+}
+
+// GetPipelineJobs returns the jobs of a pipeline
+func (c *Client) GetPipelineJobs(projectID, pipelineID int) ([]Job, error) {
+	endpoint := fmt.Sprintf("/projects/%d/pipelines/%d/jobs?per_page=100", projectID, pipelineID)
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline jobs: %v", err)
+	}
+
+	return jobs, nil
+}
+
+// GetJobLog returns the raw log (trace) of a job
+func (c *Client) GetJobLog(projectID, jobID int) (string, error) {
+	endpoint := fmt.Sprintf("/projects/%d/jobs/%d/trace", projectID, jobID)
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// Snippet represents a GitLab project snippet
+type Snippet struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	WebURL string `json:"web_url"`
+}
+
+// CreateProjectSnippet uploads content as a private project snippet
+func (c *Client) CreateProjectSnippet(projectID int, title, fileName, content string) (*Snippet, error) {
+	endpoint := fmt.Sprintf("/projects/%d/snippets", projectID)
+	payload := map[string]interface{}{
+		"title":      title,
+		"visibility": "private",
+		"files": []map[string]string{
+			{"file_path": fileName, "content": content},
+		},
+	}
+
+	respBody, err := c.doJSONRequest("POST", endpoint, payload, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snippet: %v", err)
+	}
+
+	var snippet Snippet
+	if err := json.Unmarshal(respBody, &snippet); err != nil {
+		return nil, fmt.Errorf("failed to parse snippet response: %v", err)
+	}
+
+	return &snippet, nil
+}
+
+// NewIssue is the content of an issue to create
+type NewIssue struct {
+	Title       string
+	Description string
+	Labels      []string
+}
+
+// CreateIssue opens a new issue in a project. Quick actions in the
+// description, such as /label, are applied by GitLab.
+func (c *Client) CreateIssue(projectPath string, issue NewIssue) (*Issue, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/issues", encodedPath)
+	payload := map[string]string{
+		"title":       issue.Title,
+		"description": issue.Description,
+	}
+	if len(issue.Labels) > 0 {
+		payload["labels"] = strings.Join(issue.Labels, ",")
+	}
+
+	respBody, err := c.doJSONRequest("POST", endpoint, payload, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create issue: %v", err)
+	}
+
+	var created Issue
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse issue response: %v", err)
+	}
+
+	return &created, nil
+}
+
+// IssueTemplate is a description template from a project's .gitlab/issue_templates
+type IssueTemplate struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// GetIssueTemplate fetches a project's issue description template by name,
+// returning nil if the project has no such template
+func (c *Client) GetIssueTemplate(projectPath, name string) (*IssueTemplate, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/templates/issues/%s", encodedPath, url.PathEscape(name))
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		if strings.Contains(err.Error(), "status 404") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var template IssueTemplate
+	if err := json.Unmarshal(body, &template); err != nil {
+		return nil, fmt.Errorf("failed to parse issue template: %v", err)
+	}
+
+	return &template, nil
+}
+
+// CreateIssueDiscussionNote replies inside an existing issue discussion thread
+func (c *Client) CreateIssueDiscussionNote(projectPath string, issueIID int, discussionID, body string) (*Note, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/issues/%d/discussions/%s/notes", encodedPath, issueIID, discussionID)
+
+	respBody, err := c.doJSONRequest("POST", endpoint, map[string]string{"body": body}, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discussion note: %v", err)
+	}
+
+	var note Note
+	if err := json.Unmarshal(respBody, &note); err != nil {
+		return nil, fmt.Errorf("failed to parse note response: %v", err)
+	}
+	note.DiscussionID = discussionID
+
+	return &note, nil
+}
+
+// CreateMergeRequestDiscussionNote replies inside an existing merge request discussion thread
+func (c *Client) CreateMergeRequestDiscussionNote(projectPath string, mergeRequestIID int, discussionID, body string) (*Note, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/merge_requests/%d/discussions/%s/notes", encodedPath, mergeRequestIID, discussionID)
+
+	respBody, err := c.doJSONRequest("POST", endpoint, map[string]string{"body": body}, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discussion note: %v", err)
+	}
+
+	var note Note
+	if err := json.Unmarshal(respBody, &note); err != nil {
+		return nil, fmt.Errorf("failed to parse note response: %v", err)
+	}
+	note.DiscussionID = discussionID
+
+	return &note, nil
+}
+
+// ResolveMergeRequestDiscussion marks a merge request discussion thread as resolved
+func (c *Client) ResolveMergeRequestDiscussion(projectPath string, mergeRequestIID int, discussionID string) error {
+	if err := c.setDiscussionResolved(projectPath, mergeRequestIID, discussionID, true); err != nil {
+		return fmt.Errorf("failed to resolve discussion: %v", err)
+	}
+	return nil
+}
+
+// UnresolveDiscussion reopens a resolved merge request discussion thread
+func (c *Client) UnresolveDiscussion(projectPath string, mergeRequestIID int, discussionID string) error {
+	if err := c.setDiscussionResolved(projectPath, mergeRequestIID, discussionID, false); err != nil {
+		return fmt.Errorf("failed to unresolve discussion: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) setDiscussionResolved(projectPath string, mergeRequestIID int, discussionID string, resolved bool) error {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/merge_requests/%d/discussions/%s?resolved=%t", encodedPath, mergeRequestIID, discussionID, resolved)
+
+	_, err := c.doJSONRequest("PUT", endpoint, nil, http.StatusOK)
+	return err
+}
+
+// GetMergeRequestsForBranch returns the project's merge requests opened from the given source branch
+func (c *Client) GetMergeRequestsForBranch(projectPath, sourceBranch, state string) ([]MergeRequest, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	endpoint := fmt.Sprintf("/projects/%s/merge_requests?source_branch=%s&per_page=100", encodedPath, url.QueryEscape(sourceBranch))
+
+	if state != "" {
+		endpoint += "&state=" + state
+	}
+
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var mergeRequests []MergeRequest
+	if err := json.Unmarshal(body, &mergeRequests); err != nil {
+		return nil, fmt.Errorf("failed to parse merge requests: %v", err)
+	}
+
+	return mergeRequests, nil
+}
+
 // User represents a GitLab user
 type User struct {
 	ID       int    `json:"id"`
@@ -596,6 +1154,7 @@ type User struct {
 	Email    string `json:"email"`
 	State    string `json:"state"`
 	WebURL   string `json:"web_url"`
+	Bio      string `json:"bio"` // Only in single user responses
 }
 
 // GetCurrentUser returns information about the authenticated user
@@ -658,7 +1217,7 @@ func (c *Client) UpdateMergeRequestLabels(projectID int, mergeRequestIID int, la
 	req.Header.Set("Private-Token", c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to make request: %v", err)
 	}
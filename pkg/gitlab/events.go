@@ -0,0 +1,50 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProjectEvent is an entry in a project's activity feed
+type ProjectEvent struct {
+	ID          int    `json:"id"`
+	ActionName  string `json:"action_name"`
+	TargetType  string `json:"target_type"`
+	TargetIID   int    `json:"target_iid"`
+	TargetTitle string `json:"target_title"`
+	CreatedAt   string `json:"created_at"`
+	Author      struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// GetProjectEvents returns the newest events of a project, newest first, up
+// to 100. GitLab filters after by day only and excludes that day, so events
+// from the day before after are included and callers compare event IDs or
+// times themselves.
+func (c *Client) GetProjectEvents(projectPath string, after time.Time) ([]ProjectEvent, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+
+	query := url.Values{}
+	query.Set("per_page", "100")
+	query.Set("sort", "desc")
+	if !after.IsZero() {
+		query.Set("after", after.UTC().AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+
+	body, err := c.makeRequest(fmt.Sprintf("/projects/%s/events?%s", encodedPath, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ProjectEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %v", err)
+	}
+	return events, nil
+}
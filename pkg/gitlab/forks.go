@@ -0,0 +1,87 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeveloperAccess is the lowest access level that can push to unprotected branches
+const DeveloperAccess = 30
+
+// AccessLevel returns the current user's effective access to the project, the
+// higher of its direct and inherited group membership
+func (p *Project) AccessLevel() int {
+	level := 0
+	for _, access := range []*ProjectAccess{p.Permissions.ProjectAccess, p.Permissions.GroupAccess} {
+		if access != nil && access.AccessLevel > level {
+			level = access.AccessLevel
+		}
+	}
+	return level
+}
+
+// forkReadyTimeout bounds how long EnsureFork waits for GitLab to copy the repository
+const forkReadyTimeout = 2 * time.Minute
+
+// EnsureFork returns the current user's fork of a project, forking it first if
+// the user has none. A namespace puts a new fork in that group instead of the
+// user's personal namespace. New forks are returned once their repository has
+// been copied and can be pushed to.
+func (c *Client) EnsureFork(projectPath, namespace string) (*Project, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+
+	body, err := c.makeRequest(fmt.Sprintf("/projects/%s/forks?owned=true&per_page=100", encodedPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forks: %v", err)
+	}
+	var forks []Project
+	if err := json.Unmarshal(body, &forks); err != nil {
+		return nil, fmt.Errorf("failed to parse forks: %v", err)
+	}
+	for _, fork := range forks {
+		if namespace == "" || strings.HasPrefix(fork.PathWithNamespace, namespace+"/") {
+			return &fork, nil
+		}
+	}
+
+	payload := map[string]string{}
+	if namespace != "" {
+		payload["namespace_path"] = namespace
+	}
+	respBody, err := c.doJSONRequest("POST", fmt.Sprintf("/projects/%s/fork", encodedPath), payload, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fork project: %v", err)
+	}
+	var fork Project
+	if err := json.Unmarshal(respBody, &fork); err != nil {
+		return nil, fmt.Errorf("failed to parse fork response: %v", err)
+	}
+
+	return c.waitForFork(&fork)
+}
+
+// waitForFork polls a new fork until GitLab has finished copying it
+func (c *Client) waitForFork(fork *Project) (*Project, error) {
+	deadline := time.Now().Add(forkReadyTimeout)
+	for {
+		switch fork.ImportStatus {
+		case "", "none", "finished":
+			return fork, nil
+		case "failed":
+			return nil, fmt.Errorf("forking into %s failed", fork.PathWithNamespace)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("fork %s was not ready after %s", fork.PathWithNamespace, forkReadyTimeout)
+		}
+
+		time.Sleep(2 * time.Second)
+		current, err := c.GetProject(fmt.Sprintf("%d", fork.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check fork status: %v", err)
+		}
+		fork = current
+	}
+}
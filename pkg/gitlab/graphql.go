@@ -0,0 +1,253 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// graphQLPageSize is the number of issues fetched per GraphQL page
+const graphQLPageSize = 100
+
+// IssueSummary is an issue together with its newest comment, as fetched in
+// bulk over GraphQL
+type IssueSummary struct {
+	Issue
+	// LastNote is the newest non-system note, nil when the issue has none
+	LastNote *Note
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// graphQL runs a query against the GraphQL endpoint and decodes its data into result
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/graphql", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GraphQL request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to parse GraphQL response: %v", err)
+	}
+	if len(envelope.Errors) > 0 {
+		messages := make([]string, len(envelope.Errors))
+		for i, e := range envelope.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("GraphQL query failed: %s", strings.Join(messages, "; "))
+	}
+
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return fmt.Errorf("failed to parse GraphQL data: %v", err)
+	}
+	return nil
+}
+
+const issueSummariesQuery = `
+query($fullPath: ID!, $labels: [String!], $state: IssuableState, $after: String, $first: Int) {
+  project(fullPath: $fullPath) {
+    issues(labelName: $labels, state: $state, first: $first, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id iid title description state createdAt updatedAt webUrl weight confidential dueDate
+        author { id name username }
+        labels { nodes { title } }
+        assignees { nodes { id name username } }
+        notes(last: 1, filter: ONLY_COMMENTS) {
+          nodes {
+            id body createdAt updatedAt system resolvable resolved
+            author { id name username }
+            discussion { id }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type graphQLUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+type graphQLIssue struct {
+	ID           string      `json:"id"`
+	IID          string      `json:"iid"`
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	State        string      `json:"state"`
+	CreatedAt    string      `json:"createdAt"`
+	UpdatedAt    string      `json:"updatedAt"`
+	WebURL       string      `json:"webUrl"`
+	Weight       *int        `json:"weight"`
+	Confidential bool        `json:"confidential"`
+	DueDate      string      `json:"dueDate"`
+	Author       graphQLUser `json:"author"`
+	Labels       struct {
+		Nodes []struct {
+			Title string `json:"title"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []graphQLUser `json:"nodes"`
+	} `json:"assignees"`
+	Notes struct {
+		Nodes []struct {
+			ID         string      `json:"id"`
+			Body       string      `json:"body"`
+			CreatedAt  string      `json:"createdAt"`
+			UpdatedAt  string      `json:"updatedAt"`
+			System     bool        `json:"system"`
+			Resolvable bool        `json:"resolvable"`
+			Resolved   bool        `json:"resolved"`
+			Author     graphQLUser `json:"author"`
+			Discussion struct {
+				ID string `json:"id"`
+			} `json:"discussion"`
+		} `json:"nodes"`
+	} `json:"notes"`
+}
+
+// ListIssueSummaries fetches the issues of a project carrying all of labels,
+// with their labels, assignees and newest comment, in one GraphQL query per
+// 100 issues instead of a discussions request per issue
+func (c *Client) ListIssueSummaries(ctx context.Context, projectPath string, labels []string, state string) ([]IssueSummary, error) {
+	var summaries []IssueSummary
+	variables := map[string]interface{}{
+		"fullPath": projectPath,
+		"labels":   labels,
+		"state":    state,
+		"first":    graphQLPageSize,
+	}
+
+	for {
+		var data struct {
+			Project *struct {
+				Issues struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []graphQLIssue `json:"nodes"`
+				} `json:"issues"`
+			} `json:"project"`
+		}
+		if err := c.graphQL(ctx, issueSummariesQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		if data.Project == nil {
+			return nil, fmt.Errorf("project %s not found", projectPath)
+		}
+
+		for _, node := range data.Project.Issues.Nodes {
+			summaries = append(summaries, node.summary())
+		}
+
+		if !data.Project.Issues.PageInfo.HasNextPage {
+			return summaries, nil
+		}
+		variables["after"] = data.Project.Issues.PageInfo.EndCursor
+	}
+}
+
+// summary converts a GraphQL issue to the REST shapes the rest of the code uses
+func (g *graphQLIssue) summary() IssueSummary {
+	var summary IssueSummary
+	issue := &summary.Issue
+
+	issue.ID = globalIDNumber(g.ID)
+	issue.IID, _ = strconv.Atoi(g.IID)
+	issue.Title = g.Title
+	issue.Description = g.Description
+	issue.State = g.State
+	issue.CreatedAt = g.CreatedAt
+	issue.UpdatedAt = g.UpdatedAt
+	issue.WebURL = g.WebURL
+	if g.Weight != nil {
+		issue.Weight = *g.Weight
+	}
+	issue.Confidential = g.Confidential
+	issue.DueDate = g.DueDate
+	issue.Author.ID = globalIDNumber(g.Author.ID)
+	issue.Author.Name = g.Author.Name
+	issue.Author.Username = g.Author.Username
+
+	issue.Labels = make([]string, 0, len(g.Labels.Nodes))
+	for _, label := range g.Labels.Nodes {
+		issue.Labels = append(issue.Labels, label.Title)
+	}
+	for _, assignee := range g.Assignees.Nodes {
+		issue.Assignees = append(issue.Assignees, User{
+			ID:       globalIDNumber(assignee.ID),
+			Name:     assignee.Name,
+			Username: assignee.Username,
+		})
+	}
+	if len(issue.Assignees) > 0 {
+		issue.Assignee.ID = issue.Assignees[0].ID
+		issue.Assignee.Name = issue.Assignees[0].Name
+		issue.Assignee.Username = issue.Assignees[0].Username
+	}
+
+	if len(g.Notes.Nodes) > 0 {
+		n := g.Notes.Nodes[len(g.Notes.Nodes)-1]
+		note := &Note{
+			ID:           globalIDNumber(n.ID),
+			Body:         n.Body,
+			CreatedAt:    n.CreatedAt,
+			UpdatedAt:    n.UpdatedAt,
+			System:       n.System,
+			Resolvable:   n.Resolvable,
+			Resolved:     n.Resolved,
+			DiscussionID: n.Discussion.ID[strings.LastIndex(n.Discussion.ID, "/")+1:],
+		}
+		note.Author.ID = globalIDNumber(n.Author.ID)
+		note.Author.Name = n.Author.Name
+		note.Author.Username = n.Author.Username
+		if !note.System {
+			summary.LastNote = note
+		}
+	}
+
+	return summary
+}
+
+// globalIDNumber extracts the numeric ID from a GraphQL global ID such as
+// gid://gitlab/Note/123
+func globalIDNumber(id string) int {
+	n, _ := strconv.Atoi(id[strings.LastIndex(id, "/")+1:])
+	return n
+}
@@ -0,0 +1,58 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProjectHook is a project webhook
+type ProjectHook struct {
+	ID                    int    `json:"id,omitempty"`
+	URL                   string `json:"url"`
+	Token                 string `json:"token,omitempty"` // Only sent, GitLab never returns it
+	IssuesEvents          bool   `json:"issues_events"`
+	NoteEvents            bool   `json:"note_events"`
+	MergeRequestsEvents   bool   `json:"merge_requests_events"`
+	PipelineEvents        bool   `json:"pipeline_events"`
+	PushEvents            bool   `json:"push_events"`
+	EnableSSLVerification bool   `json:"enable_ssl_verification"`
+}
+
+// GetProjectHooks lists the webhooks of a project; it needs Maintainer access
+func (c *Client) GetProjectHooks(projectPath string) ([]ProjectHook, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	body, err := c.makeRequest(fmt.Sprintf("/projects/%s/hooks?per_page=100", encodedPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []ProjectHook
+	if err := json.Unmarshal(body, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to parse project hooks: %v", err)
+	}
+	return hooks, nil
+}
+
+// AddProjectHook creates a webhook on a project
+func (c *Client) AddProjectHook(projectPath string, hook ProjectHook) (*ProjectHook, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	body, err := c.doJSONRequest("POST", fmt.Sprintf("/projects/%s/hooks", encodedPath), hook, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	var created ProjectHook
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse project hook: %v", err)
+	}
+	return &created, nil
+}
+
+// EditProjectHook replaces the settings of a project webhook
+func (c *Client) EditProjectHook(projectPath string, hookID int, hook ProjectHook) error {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	_, err := c.doJSONRequest("PUT", fmt.Sprintf("/projects/%s/hooks/%d", encodedPath, hookID), hook, http.StatusOK)
+	return err
+}
@@ -0,0 +1,22 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// IssueEdit changes an issue relative to its current state, so concurrent
+// edits of other labels are kept
+type IssueEdit struct {
+	AddLabels    string `json:"add_labels,omitempty"`    // Comma-separated labels to add
+	RemoveLabels string `json:"remove_labels,omitempty"` // Comma-separated labels to remove
+	StateEvent   string `json:"state_event,omitempty"`   // "close" or "reopen"
+}
+
+// EditIssue applies an IssueEdit to an issue
+func (c *Client) EditIssue(projectPath string, issueIID int, edit IssueEdit) error {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	_, err := c.doJSONRequest("PUT", fmt.Sprintf("/projects/%s/issues/%d", encodedPath, issueIID), edit, http.StatusOK)
+	return err
+}
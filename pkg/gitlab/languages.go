@@ -0,0 +1,35 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GetProjectLanguages returns the share of a project's code in each language,
+// as detected by GitLab, in percent
+func (c *Client) GetProjectLanguages(projectPath string) (map[string]float64, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	body, err := c.makeRequest(fmt.Sprintf("/projects/%s/languages", encodedPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var languages map[string]float64
+	if err := json.Unmarshal(body, &languages); err != nil {
+		return nil, fmt.Errorf("failed to parse project languages: %v", err)
+	}
+	return languages, nil
+}
+
+// MainLanguage returns the language with the largest share, or "" for an
+// empty map
+func MainLanguage(languages map[string]float64) string {
+	main, share := "", 0.0
+	for language, s := range languages {
+		if s > share || (s == share && language < main) {
+			main, share = language, s
+		}
+	}
+	return main
+}
@@ -0,0 +1,118 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GetIssuesNotes fetches the non-system notes of several issues concurrently
+// and returns them keyed by issue IID, sorted oldest first. At most concurrency
+// issues are fetched at once and requests start at least interval apart, so
+// the whole batch shares one rate limit. Issues that could not be fetched are
+// missing from the map and listed in the returned error.
+func (c *Client) GetIssuesNotes(ctx context.Context, projectPath string, issueIIDs []int, concurrency int, interval time.Duration) (map[int][]Note, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		notes  = make(map[int][]Note, len(issueIIDs))
+		failed []string
+	)
+
+	limiter := newRequestLimiter(interval)
+	defer limiter.stop()
+
+	sem := make(chan struct{}, concurrency)
+	for _, iid := range issueIIDs {
+		wg.Add(1)
+		go func(iid int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var issueNotes []Note
+			err := limiter.wait(ctx)
+			if err == nil {
+				issueNotes, err = c.getIssueNotes(ctx, projectPath, iid)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("#%d: %v", iid, err))
+				return
+			}
+			notes[iid] = issueNotes
+		}(iid)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return notes, fmt.Errorf("failed to fetch notes for %d issues: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return notes, nil
+}
+
+// getIssueNotes flattens the discussions of an issue into its non-system notes
+func (c *Client) getIssueNotes(ctx context.Context, projectPath string, issueIID int) ([]Note, error) {
+	discussions, err := c.GetIssueDiscussionsWithContext(ctx, projectPath, issueIID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	for _, discussion := range discussions {
+		for _, note := range discussion.Notes {
+			if !note.System {
+				note.DiscussionID = discussion.ID
+				notes = append(notes, note)
+			}
+		}
+	}
+
+	// Sort notes by ID, which GitLab assigns in creation order
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].ID < notes[j].ID
+	})
+	return notes, nil
+}
+
+// requestLimiter spaces out requests made from several goroutines
+type requestLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRequestLimiter(interval time.Duration) *requestLimiter {
+	if interval <= 0 {
+		return &requestLimiter{}
+	}
+	return &requestLimiter{ticker: time.NewTicker(interval)}
+}
+
+// wait blocks until the next request may start
+func (l *requestLimiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.ticker.C:
+		return nil
+	}
+}
+
+func (l *requestLimiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
@@ -0,0 +1,151 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NewProject describes a project to create from a template
+type NewProject struct {
+	Name        string
+	Path        string
+	Namespace   string // Group or user namespace, "" for the current user's
+	Visibility  string // private, internal or public; "" keeps the template's
+	Description string
+}
+
+// CreateProjectFromTemplate creates a project holding a copy of a template
+// project's repository. The template is forked under the new name and the
+// fork relationship removed, which works on every GitLab tier, unlike custom
+// project templates. The project is returned once it can be pushed to.
+func (c *Client) CreateProjectFromTemplate(templatePath string, project NewProject) (*Project, error) {
+	encodedPath := strings.ReplaceAll(templatePath, "/", "%2F")
+
+	payload := map[string]string{"name": project.Name, "path": project.Path}
+	if project.Namespace != "" {
+		payload["namespace_path"] = project.Namespace
+	}
+	if project.Visibility != "" {
+		payload["visibility"] = project.Visibility
+	}
+	if project.Description != "" {
+		payload["description"] = project.Description
+	}
+	respBody, err := c.doJSONRequest("POST", fmt.Sprintf("/projects/%s/fork", encodedPath), payload, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project from template %s: %v", templatePath, err)
+	}
+	var created Project
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse project response: %v", err)
+	}
+
+	ready, err := c.waitForFork(&created)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.doJSONRequest("DELETE", fmt.Sprintf("/projects/%d/fork", ready.ID), nil, http.StatusNoContent); err != nil {
+		return nil, fmt.Errorf("failed to detach %s from its template: %v", ready.PathWithNamespace, err)
+	}
+	return ready, nil
+}
+
+// FindProject returns the project at a path, or nil if there is none
+func (c *Client) FindProject(projectPath string) (*Project, error) {
+	project, err := c.GetProject(strings.ReplaceAll(projectPath, "/", "%2F"))
+	if err != nil {
+		if strings.Contains(err.Error(), "status 404") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return project, nil
+}
+
+// CommitFiles commits files, keyed by path, to a branch in one commit.
+// Existing files are overwritten and missing ones created.
+func (c *Client) CommitFiles(projectID int, branch, message string, files map[string]string) error {
+	actions := make([]map[string]string, 0, len(files))
+	for path, content := range files {
+		exists, err := c.fileExists(projectID, branch, path)
+		if err != nil {
+			return err
+		}
+		action := "create"
+		if exists {
+			action = "update"
+		}
+		actions = append(actions, map[string]string{"action": action, "file_path": path, "content": content})
+	}
+
+	payload := map[string]interface{}{
+		"branch":         branch,
+		"commit_message": message,
+		"actions":        actions,
+	}
+	if _, err := c.doJSONRequest("POST", fmt.Sprintf("/projects/%d/repository/commits", projectID), payload, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to commit files: %v", err)
+	}
+	return nil
+}
+
+// fileExists reports whether a file exists on a branch
+func (c *Client) fileExists(projectID int, branch, path string) (bool, error) {
+	endpoint := fmt.Sprintf("/projects/%d/repository/files/%s?ref=%s", projectID, url.PathEscape(path), url.QueryEscape(branch))
+	if _, err := c.makeRequest(endpoint); err != nil {
+		if strings.Contains(err.Error(), "status 404") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// GetRawFile returns the content of a file on a ref, "" if there is no such file
+func (c *Client) GetRawFile(projectID int, ref, path string) (string, error) {
+	endpoint := fmt.Sprintf("/projects/%d/repository/files/%s/raw?ref=%s", projectID, url.PathEscape(path), url.QueryEscape(ref))
+	body, err := c.makeRequest(endpoint)
+	if err != nil {
+		if strings.Contains(err.Error(), "status 404") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return string(body), nil
+}
+
+// NewMergeRequest is the content of a merge request to open
+type NewMergeRequest struct {
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	Description  string
+	Labels       []string
+}
+
+// CreateMergeRequest opens a merge request within a project
+func (c *Client) CreateMergeRequest(projectID int, mr NewMergeRequest) (*MergeRequest, error) {
+	payload := map[string]interface{}{
+		"source_branch":        mr.SourceBranch,
+		"target_branch":        mr.TargetBranch,
+		"title":                mr.Title,
+		"description":          mr.Description,
+		"remove_source_branch": true,
+	}
+	if len(mr.Labels) > 0 {
+		payload["labels"] = strings.Join(mr.Labels, ",")
+	}
+	respBody, err := c.doJSONRequest("POST", fmt.Sprintf("/projects/%d/merge_requests", projectID), payload, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge request: %v", err)
+	}
+
+	var created MergeRequest
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse merge request response: %v", err)
+	}
+	return &created, nil
+}
@@ -0,0 +1,71 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProtectedBranch is a protected branch rule; Name may contain * wildcards
+type ProtectedBranch struct {
+	Name             string `json:"name"`
+	PushAccessLevels []struct {
+		AccessLevel int `json:"access_level"` // 0 means no one
+		UserID      int `json:"user_id"`
+		GroupID     int `json:"group_id"`
+	} `json:"push_access_levels"`
+}
+
+// GetProtectedBranches lists the protected branch rules of a project
+func (c *Client) GetProtectedBranches(projectPath string) ([]ProtectedBranch, error) {
+	encodedPath := strings.ReplaceAll(projectPath, "/", "%2F")
+	body, err := c.makeRequest(fmt.Sprintf("/projects/%s/protected_branches?per_page=100", encodedPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []ProtectedBranch
+	if err := json.Unmarshal(body, &branches); err != nil {
+		return nil, fmt.Errorf("failed to parse protected branches: %v", err)
+	}
+	return branches, nil
+}
+
+// Matches reports whether the rule protects branch. A * in the rule matches
+// any run of characters, slashes included.
+func (b *ProtectedBranch) Matches(branch string) bool {
+	parts := strings.Split(b.Name, "*")
+	if len(parts) == 1 {
+		return branch == b.Name
+	}
+	if !strings.HasPrefix(branch, parts[0]) {
+		return false
+	}
+	rest := branch[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
+
+// AllowsPush reports whether a user with the given ID and project access level
+// may push to branches the rule protects. Group grants are not resolved and
+// count as not allowing the push.
+func (b *ProtectedBranch) AllowsPush(accessLevel, userID int) bool {
+	for _, push := range b.PushAccessLevels {
+		if push.UserID != 0 {
+			if push.UserID == userID {
+				return true
+			}
+			continue
+		}
+		if push.GroupID == 0 && push.AccessLevel > 0 && accessLevel >= push.AccessLevel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package gitlab
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// RetryPolicy decides how requests failing with a transient error, such as a
+// timeout, 429 Too Many Requests or 503 Service Unavailable, are retried
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt, 0 to fail right away
+	BaseDelay  time.Duration // Delay before the first retry, doubling with each one after
+	MaxDelay   time.Duration // Longest wait between attempts; a longer Retry-After fails the request
+}
+
+// DefaultRetryPolicy is the policy of new clients
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// SetRetryPolicy changes how the client retries failed requests
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// do sends a request, retrying it under the client's retry policy while it
+// fails with a transient error. Waits honor the Retry-After header and are
+// otherwise backed off exponentially with jitter, so daemons sharing a GitLab
+// instance do not retry in lockstep.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt >= c.retry.MaxRetries || !transient(req.Method, resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		// A body that cannot be replayed cannot be sent again
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := c.retry.backoff(attempt)
+		if resp != nil {
+			if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				if wait > c.retry.MaxDelay {
+					return resp, err
+				}
+				delay = wait
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			logging.Debugf("GitLab %s %s returned %d, retrying in %s", req.Method, req.URL.Path, resp.StatusCode, delay.Round(time.Millisecond))
+		} else {
+			logging.Debugf("GitLab %s %s failed, retrying in %s: %v", req.Method, req.URL.Path, delay.Round(time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			retry.Body = body
+		}
+		req = retry
+	}
+}
+
+// transient reports whether a request failed in a way that may succeed when
+// it is sent again. Reads are retried on any network error and on an
+// overloaded or restarting instance. Writes are retried only when GitLab
+// cannot have applied them: the connection was never made, or it answered 429
+// or 503. A timeout or a 502/504 from a proxy may come after the write went
+// through, and sending it again would post a second comment or MR.
+func transient(method string, resp *http.Response, err error) bool {
+	if method != http.MethodGet && method != http.MethodHead {
+		if err != nil {
+			return notConnected(err)
+		}
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+	}
+
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// notConnected reports whether a request failed before reaching GitLab
+func notConnected(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// backoff returns the delay before retry number attempt+1: the base delay
+// doubled for each earlier retry, capped at the maximum, of which a random
+// half is taken off
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
@@ -0,0 +1,187 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// UserCache maps user IDs, usernames and display names. Entries are filled
+// lazily from the Users API and never expire; user records rarely change
+// within the lifetime of a daemon.
+type UserCache struct {
+	client     *Client
+	mu         sync.Mutex
+	byID       map[int]*User
+	byUsername map[string]*User // Keyed by lowercased username
+	bios       map[int]string   // Profile bios, which list responses leave out
+	current    *User
+}
+
+func newUserCache(client *Client) *UserCache {
+	return &UserCache{
+		client:     client,
+		byID:       make(map[int]*User),
+		byUsername: make(map[string]*User),
+		bios:       make(map[int]string),
+	}
+}
+
+// Users returns the client's user cache
+func (c *Client) Users() *UserCache {
+	return c.users
+}
+
+// GetUser returns a user by ID
+func (c *Client) GetUser(userID int) (*User, error) {
+	respBody, err := c.makeRequest(fmt.Sprintf("/users/%d", userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %d: %v", userID, err)
+	}
+
+	var user User
+	if err := json.Unmarshal(respBody, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user response: %v", err)
+	}
+
+	return &user, nil
+}
+
+// GetUserByUsername looks up a user by username
+func (c *Client) GetUserByUsername(username string) (*User, error) {
+	respBody, err := c.makeRequest("/users?username=" + url.QueryEscape(username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user @%s: %v", username, err)
+	}
+
+	var users []User
+	if err := json.Unmarshal(respBody, &users); err != nil {
+		return nil, fmt.Errorf("failed to parse users response: %v", err)
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("user @%s not found", username)
+	}
+
+	return &users[0], nil
+}
+
+// Remember adds users seen in other API responses (note authors, assignees)
+// so later lookups don't hit the API
+func (u *UserCache) Remember(users ...User) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := range users {
+		u.storeLocked(&users[i])
+	}
+}
+
+func (u *UserCache) storeLocked(user *User) {
+	if user.ID == 0 || user.Username == "" {
+		return
+	}
+	stored := *user
+	u.byID[stored.ID] = &stored
+	u.byUsername[strings.ToLower(stored.Username)] = &stored
+}
+
+// Current returns the authenticated user, fetched once
+func (u *UserCache) Current() (*User, error) {
+	u.mu.Lock()
+	if u.current != nil {
+		current := u.current
+		u.mu.Unlock()
+		return current, nil
+	}
+	u.mu.Unlock()
+
+	user, err := u.client.GetCurrentUser()
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.current = user
+	u.storeLocked(user)
+	return user, nil
+}
+
+// ByID returns the user with the given ID
+func (u *UserCache) ByID(userID int) (*User, error) {
+	u.mu.Lock()
+	if user, exists := u.byID[userID]; exists {
+		u.mu.Unlock()
+		return user, nil
+	}
+	u.mu.Unlock()
+
+	user, err := u.client.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Remember(*user)
+	return user, nil
+}
+
+// ByUsername returns the user with the given username (case-insensitive)
+func (u *UserCache) ByUsername(username string) (*User, error) {
+	username = strings.TrimPrefix(username, "@")
+
+	u.mu.Lock()
+	if user, exists := u.byUsername[strings.ToLower(username)]; exists {
+		u.mu.Unlock()
+		return user, nil
+	}
+	u.mu.Unlock()
+
+	user, err := u.client.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Remember(*user)
+	return user, nil
+}
+
+// Bio returns the profile bio of a user, fetching the full user record once
+func (u *UserCache) Bio(userID int) (string, error) {
+	u.mu.Lock()
+	if bio, exists := u.bios[userID]; exists {
+		u.mu.Unlock()
+		return bio, nil
+	}
+	u.mu.Unlock()
+
+	user, err := u.client.GetUser(userID)
+	if err != nil {
+		return "", err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.bios[userID] = user.Bio
+	u.storeLocked(user)
+	return user.Bio, nil
+}
+
+// DisplayName returns the user's display name, falling back to the username
+// when the lookup fails
+func (u *UserCache) DisplayName(username string) string {
+	user, err := u.ByUsername(username)
+	if err != nil || user.Name == "" {
+		return username
+	}
+	return user.Name
+}
+
+// IsCurrentUser reports whether the username belongs to the authenticated user
+func (u *UserCache) IsCurrentUser(username string) bool {
+	current, err := u.Current()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(current.Username, strings.TrimPrefix(username, "@"))
+}
@@ -0,0 +1,93 @@
+// Package heartbeat lets external supervisors tell a working daemon from a
+// hung one: it touches a heartbeat file and, under systemd, sends sd_notify
+// watchdog keep-alives.
+package heartbeat
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Heartbeat signals that the polling loop is alive. A nil *Heartbeat is valid
+// and signals nothing, which is how a disabled heartbeat is represented.
+type Heartbeat struct {
+	file   string // Path rewritten with the time of each beat, "" for none
+	socket string // systemd notification socket, "" when not run by systemd
+}
+
+// New returns a heartbeat writing to file and, when systemd started the
+// process with NOTIFY_SOCKET, notifying systemd. It returns nil when there is
+// neither.
+func New(file string) *Heartbeat {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if file == "" && socket == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	return &Heartbeat{file: file, socket: socket}
+}
+
+// Ready tells systemd the daemon has started, for Type=notify units
+func (h *Heartbeat) Ready() {
+	if h == nil {
+		return
+	}
+	h.notify("READY=1")
+	h.Beat()
+}
+
+// Beat records that the loop completed another cycle
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	if h.file != "" {
+		if err := writeAtomic(h.file, time.Now().Format(time.RFC3339)+"\n"); err != nil {
+			fmt.Printf("Warning: failed to write heartbeat file: %v\n", err)
+		}
+	}
+	h.notify("WATCHDOG=1")
+}
+
+// Stopping tells systemd the daemon is shutting down on purpose
+func (h *Heartbeat) Stopping() {
+	if h == nil {
+		return
+	}
+	h.notify("STOPPING=1")
+}
+
+// notify sends a state to the systemd notification socket
+func (h *Heartbeat) notify(state string) {
+	if h.socket == "" {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: h.socket, Net: "unixgram"})
+	if err != nil {
+		fmt.Printf("Warning: failed to reach systemd notify socket: %v\n", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		fmt.Printf("Warning: failed to notify systemd: %v\n", err)
+	}
+}
+
+// writeAtomic replaces path with content, so readers never see a partial file
+func writeAtomic(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,60 @@
+// Package interactive reads menu choices from the terminal. Prompts fail
+// instead of waiting when stdin is not a terminal, so a daemon run under
+// systemd, Docker or cron never blocks on input nobody can give.
+package interactive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNotTerminal is returned by Choose when stdin is not a terminal
+var ErrNotTerminal = errors.New("stdin is not a terminal, cannot prompt for input")
+
+// stdin buffers whole lines, so an invalid answer is rejected once rather
+// than once per character
+var stdin = bufio.NewReader(os.Stdin)
+
+// IsTerminal reports whether stdin is a terminal someone can type into
+func IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Choose reads a number between 1 and max from stdin, asking again until it
+// gets one. It fails when stdin is not a terminal or is closed.
+func Choose(max int) (int, error) {
+	if !IsTerminal() {
+		return 0, ErrNotTerminal
+	}
+	for {
+		line, err := stdin.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return 0, fmt.Errorf("stdin closed before a choice was made")
+			}
+			return 0, fmt.Errorf("failed to read choice: %v", err)
+		}
+
+		choice, convErr := strconv.Atoi(strings.TrimSpace(line))
+		switch {
+		case convErr != nil:
+			fmt.Printf("Invalid input. Please enter a number: ")
+		case choice < 1 || choice > max:
+			fmt.Printf("Invalid choice. Please enter a number between 1 and %d: ", max)
+		default:
+			return choice, nil
+		}
+		if err == io.EOF {
+			return 0, fmt.Errorf("stdin closed before a choice was made")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package keyring stores secrets in the OS keyring: the login keychain on
+// macOS (via security) and the Secret Service on Linux (via secret-tool).
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when the keyring has no such secret
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// Get returns the secret stored for service and account
+func Get(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", unsupported()
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && missing(exitErr.ExitCode(), string(out), stderr.String()) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read keyring: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	secret := strings.TrimRight(string(out), "\n")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores secret for service and account, replacing any existing one
+func Set(service, account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security -i reads the command from stdin without prompting, so the
+		// secret never shows up in the process list; -X takes it as hex,
+		// which needs no quoting
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+			strconv.Quote(service), strconv.Quote(account), hex.EncodeToString([]byte(secret))))
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return unsupported()
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write keyring: %v %s", err, strings.TrimSpace(string(out)))
+	}
+
+	// Read it back: a secret that did not make it must not be relied on
+	stored, err := Get(service, account)
+	if err != nil {
+		return fmt.Errorf("failed to verify keyring write: %v", err)
+	}
+	if stored != secret {
+		return fmt.Errorf("failed to verify keyring write: the stored secret does not match")
+	}
+	return nil
+}
+
+// missing tells a lookup of an item that does not exist from one that failed:
+// secret-tool exits 1 without output for a missing item, but also fails for a
+// locked keyring or an unreachable Secret Service, which must not be mistaken
+// for an empty keyring
+func missing(exitCode int, stdout, stderr string) bool {
+	if runtime.GOOS == "darwin" {
+		return strings.Contains(stderr, "could not be found")
+	}
+	return exitCode == 1 && stdout == "" && strings.TrimSpace(stderr) == ""
+}
+
+func unsupported() error {
+	return fmt.Errorf("no keyring support on %s, set the secret through the environment instead", runtime.GOOS)
+}
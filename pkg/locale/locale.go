@@ -0,0 +1,184 @@
+package locale
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultLanguage is used for languages and messages without a translation
+const DefaultLanguage = "en"
+
+// Messages posted by the bot on issues
+const (
+	MsgCompleted            = "completed"              // Session finished, ready for review
+	MsgPipelinePassed       = "pipeline_passed"        // Link
+	MsgPipelineFailed       = "pipeline_failed"        // Link
+	MsgPipelineManual       = "pipeline_manual"        // Link
+	MsgPipelineOther        = "pipeline_other"         // Link, Status
+	MsgPipelineNoMR         = "pipeline_no_mr"         // No merge request to wait on
+	MsgPipelineNotStarted   = "pipeline_not_started"   // Timeout
+	MsgPipelineStillRunning = "pipeline_still_running" // Link, Status, Timeout
+	MsgAuthExpired          = "auth_expired"           // Label
+	MsgRateLimited          = "rate_limited"           // Delay
+	MsgSessionCancelled     = "session_cancelled"      // Stalled, Minutes, Turns, Nudged, Stats
+	MsgSessionTimedOut      = "session_timed_out"      // Minutes, Label, Attempts, Stats
+	MsgMCPConfig            = "mcp_config"             // Suggestion
+	MsgPushBlocked          = "push_blocked"           // Branch, Reason, Label
+	MsgNeedsEnvironment     = "needs_environment"      // Missing, Label
+	MsgPossibleDuplicate    = "possible_duplicate"     // Candidates, DuplicateLabel, Label
+	MsgLabelSuggestions     = "label_suggestions"      // Applied, Proposed
+	MsgApprovalRequired     = "approval_required"      // Action, Command, Label
+	MsgSessionPaused        = "session_paused"         // Reason, Label
+	MsgFailureBundle        = "failure_bundle"         // Failure, Link
+	MsgQueuedQuietHours     = "queued_quiet_hours"     // Resumes
+	MsgScaffolded           = "scaffolded"             // Project, Link, Template, Issues, CI
+	MsgScaffoldNeedsProject = "scaffold_needs_project" // Label
+	MsgScaffoldFailed       = "scaffold_failed"        // Error, Label
+	MsgCIProposed           = "ci_proposed"            // Link, MergeRequest, Replaced, Jobs
+	MsgCIUpToDate           = "ci_up_to_date"          // No fields
+	MsgCISetupFailed        = "ci_setup_failed"        // Error, Label
+	MsgCILintFailed         = "ci_lint_failed"         // Errors, Attempts
+	MsgTerraformPlan        = "terraform_plan"         // Plans
+	MsgTerraformPlanFailed  = "terraform_plan_failed"  // Plans, Label
+	MsgMigrationCheck       = "migration_check"        // Image, Results
+	MsgTriaged              = "triaged"                // Type, Complexity, Estimate, Reason, Label
+	MsgCoverageDelta        = "coverage_delta"         // Before, After, Delta, Regression, Threshold
+	MsgBenchmark            = "benchmark"              // Base, Command, Table
+	MsgBenchmarkFailed      = "benchmark_failed"       // Command, Error
+	MsgProgress             = "progress"               // Ended, Elapsed, Turns, Plan, Step
+)
+
+// templateExt is the file extension of message templates
+const templateExt = ".tmpl"
+
+// Catalog holds the message templates of each language
+type Catalog struct {
+	messages map[string]map[string]*template.Template
+}
+
+// Defaults returns the built-in translations
+func Defaults() *Catalog {
+	catalog := &Catalog{messages: make(map[string]map[string]*template.Template)}
+	for language, messages := range defaultMessages {
+		for id, text := range messages {
+			catalog.add(language, id, template.Must(template.New(language+"/"+id).Option("missingkey=error").Parse(text)))
+		}
+	}
+	return catalog
+}
+
+func (c *Catalog) add(language, id string, tmpl *template.Template) {
+	if c.messages[language] == nil {
+		c.messages[language] = make(map[string]*template.Template)
+	}
+	c.messages[language][id] = tmpl
+}
+
+// Load returns the built-in translations overridden by the files in dir,
+// laid out as <language>/<message>.tmpl. An empty dir, or one that does not
+// exist, yields the defaults.
+func Load(dir string) (*Catalog, error) {
+	catalog := Defaults()
+	if dir == "" {
+		return catalog, nil
+	}
+
+	languages, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return catalog, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locales directory: %v", err)
+	}
+
+	for _, language := range languages {
+		if !language.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, language.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale %s: %v", language.Name(), err)
+		}
+
+		for _, file := range files {
+			id, ok := strings.CutSuffix(file.Name(), templateExt)
+			if file.IsDir() || !ok {
+				continue
+			}
+			path := filepath.Join(dir, language.Name(), file.Name())
+			if _, known := defaultMessages[DefaultLanguage][id]; !known {
+				return nil, fmt.Errorf("%s: unknown message %q (known: %s)", path, id, strings.Join(messageIDs(), ", "))
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", path, err)
+			}
+			tmpl, err := template.New(path).Option("missingkey=error").Parse(strings.TrimRight(string(content), "\n"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid message template: %v", err)
+			}
+			catalog.add(language.Name(), id, tmpl)
+		}
+	}
+
+	return catalog, nil
+}
+
+// Message renders a message in the given language, falling back to English
+// when the language has no translation of it or the translation fails
+func (c *Catalog) Message(language, id string, data map[string]interface{}) string {
+	if tmpl, ok := c.messages[language][id]; ok && language != DefaultLanguage {
+		var b bytes.Buffer
+		err := tmpl.Execute(&b, data)
+		if err == nil {
+			return b.String()
+		}
+		fmt.Printf("Warning: failed to render %s message in %s, using %s: %v\n", id, language, DefaultLanguage, err)
+	}
+
+	var b bytes.Buffer
+	if err := c.messages[DefaultLanguage][id].Execute(&b, data); err != nil {
+		fmt.Printf("Warning: failed to render %s message: %v\n", id, err)
+	}
+	return b.String()
+}
+
+// Languages lists the languages with at least one translated message
+func (c *Catalog) Languages() []string {
+	languages := make([]string, 0, len(c.messages))
+	for language := range c.messages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// messageIDs lists the known message IDs for diagnostics
+func messageIDs() []string {
+	ids := make([]string, 0, len(defaultMessages[DefaultLanguage]))
+	for id := range defaultMessages[DefaultLanguage] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// active is the catalog used by Message. Use is meant to be called once at
+// startup, before any session is started.
+var active = Defaults()
+
+// Use makes catalog the active translations
+func Use(catalog *Catalog) {
+	active = catalog
+}
+
+// Message renders a message of the active catalog
+func Message(language, id string, data map[string]interface{}) string {
+	return active.Message(language, id, data)
+}
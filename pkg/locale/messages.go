@@ -0,0 +1,158 @@
+package locale
+
+// defaultMessages are the built-in translations. English must define every
+// message; other languages fall back to it for anything they leave out.
+var defaultMessages = map[string]map[string]string{
+	"en": {
+		MsgCompleted: "✅ **Task completed successfully**\n\n" +
+			"Claude has finished processing this issue. The implementation has been completed and is ready for human review.",
+		MsgPipelinePassed:       "✅ **Pipeline:** {{.Link}} passed",
+		MsgPipelineFailed:       "❌ **Pipeline:** {{.Link}} failed",
+		MsgPipelineManual:       "⏸️ **Pipeline:** {{.Link}} is waiting on a manual job",
+		MsgPipelineOther:        "⚠️ **Pipeline:** {{.Link}} {{.Status}}",
+		MsgPipelineNoMR:         "⚠️ **Pipeline:** no merge request found for this issue",
+		MsgPipelineNotStarted:   "⏱️ **Pipeline:** no pipeline started within {{.Timeout}}",
+		MsgPipelineStillRunning: "⏱️ **Pipeline:** {{.Link}} still {{.Status}} after waiting {{.Timeout}}",
+		MsgAuthExpired: "🔑 **Claude CLI authentication expired**\n\n" +
+			"The session could not start because the daemon's Claude login is no longer valid. " +
+			"Once a maintainer has logged in again, re-add the `{{.Label}}` label to retry.",
+		MsgRateLimited: "⏳ **Rate limited**\n\nThe Claude API rate limit was reached. " +
+			"I'll retry this issue automatically in {{.Delay}}.",
+		MsgSessionCancelled: "🛑 **Session cancelled**\n\nThe session was stopped because " +
+			"{{if .Stalled}}it produced no output for {{.Minutes}} minutes{{else}}it ran past the limit of {{.Turns}} turns{{end}}" +
+			"{{if .Nudged}}, even after a continuation prompt{{end}}.\n\n{{.Stats}}",
+		MsgSessionTimedOut: "⏱️ **Session timed out**\n\nThe session ran for more than {{.Minutes}} minutes" +
+			"{{if gt .Attempts 1}} over {{.Attempts}} attempts{{end}} and is being stopped. " +
+			"The issue is labelled `{{.Label}}`; re-add the trigger label to try again.\n\n{{.Stats}}",
+		MsgMCPConfig: "🔌 **MCP configuration problem**\n\n{{.Suggestion}}",
+		MsgPushBlocked: "🔒 **Cannot push `{{.Branch}}`**\n\n" +
+			"No session was started because {{.Reason}}. " +
+			"Once the bot can push the branch, re-add the `{{.Label}}` label to retry.",
+		MsgNeedsEnvironment: "🧰 **Missing toolchain**\n\n" +
+			"No session was started because this host lacks tools the project needs:\n\n{{.Missing}}\n\n" +
+			"Once they are installed, re-add the `{{.Label}}` label to retry.",
+		MsgPossibleDuplicate: "🔁 **Possible duplicate**\n\n" +
+			"This issue looks like these open issues, so no session was started:\n\n{{.Candidates}}\n\n" +
+			"If it is a duplicate, close it. If it is not, re-add the `{{.Label}}` label and keep " +
+			"`{{.DuplicateLabel}}`, and the work will start without another check.",
+		MsgLabelSuggestions: "🏷️ **Label suggestions**\n\n" +
+			"{{if .Applied}}Added from the labels of similar closed issues:\n\n{{.Applied}}\n\n{{end}}" +
+			"{{if .Proposed}}These may also apply. Add the ones that do:\n\n{{.Proposed}}{{end}}",
+		MsgApprovalRequired: "✋ **Approval needed**\n\n" +
+			"The session stopped before it would {{.Action}}. " +
+			"Comment `{{.Command}}` to let it go ahead; the issue stays labeled `{{.Label}}` until then.",
+		MsgSessionPaused: "⏸️ **Session paused**\n\n" +
+			"The session stopped after its last tool call: {{.Reason}}. Its work so far is kept, " +
+			"and it picks up where it left off once the issue no longer has the `{{.Label}}` label.",
+		MsgFailureBundle: "🧰 **Failure details**\n\n" +
+			"The session failed ({{.Failure}}). Its last output and the state of its worktree are in [this snippet]({{.Link}}).",
+		MsgQueuedQuietHours: "🌙 **Queued**\n\n" +
+			"This issue arrived outside working hours, so it is queued; processing resumes at {{.Resumes}}.",
+		MsgScaffolded: "🏗️ **Project created**\n\n" +
+			"[{{.Project}}]({{.Link}}) was created from `{{.Template}}`, with a README describing it." +
+			"{{if .Issues}}\n\nThe rest of the checklist is now tracked in these issues:\n\n{{.Issues}}{{end}}" +
+			"{{if .CI}}\n\nA CI configuration is proposed in {{.CI}}.{{end}}",
+		MsgScaffoldNeedsProject: "🏗️ **Project path needed**\n\n" +
+			"Add a line such as `Project: my-group/my-app` to the description to name the project to create, " +
+			"then re-add the `{{.Label}}` label.",
+		MsgScaffoldFailed: "🏗️ **Project setup failed**\n\n{{.Error}}\n\n" +
+			"Steps that already succeeded are not repeated. Re-add the `{{.Label}}` label to try again.",
+		MsgCIProposed: "⚙️ **CI configuration proposed**\n\n" +
+			"[!{{.MergeRequest}}]({{.Link}}) {{if .Replaced}}regenerates{{else}}adds{{end}} `.gitlab-ci.yml` with {{.Jobs}} jobs. " +
+			"GitLab's CI lint accepted it.",
+		MsgCIUpToDate: "⚙️ **CI configuration up to date**\n\nRegenerating `.gitlab-ci.yml` changes nothing, so no merge request was opened.",
+		MsgCISetupFailed: "⚙️ **CI setup failed**\n\n{{.Error}}\n\n" +
+			"Re-add the `{{.Label}}` label to try again.",
+		MsgCILintFailed: "⚠️ **Invalid CI configuration:** GitLab's CI lint rejects the `.gitlab-ci.yml` of this change" +
+			"{{if .Attempts}}, also after {{.Attempts}} attempts to fix it{{end}}:\n\n{{.Errors}}",
+		MsgTerraformPlan: "🏗️ **Terraform plan** of the modules this change touches:\n\n{{.Plans}}",
+		MsgTerraformPlanFailed: "⚠️ **Terraform plan failed**, so this issue is labeled `error` instead of `{{.Label}}`:\n\n{{.Plans}}\n\n" +
+			"Add `{{.Label}}` once the plan is sorted out; comments are picked up again from then on.",
+		MsgMigrationCheck: "🗃️ **Migration check** against a disposable `{{.Image}}` database:\n\n{{.Results}}",
+		MsgTriaged: "🏷️ **Triaged** as `{{.Type}}` with complexity `{{.Complexity}}`\n\n" +
+			"**Estimate:** {{.Estimate}}\n\n{{.Reason}}\n\nAdd `{{.Label}}` to have Claude work on it.",
+		MsgCoverageDelta: "{{if .Regression}}⚠️ **Coverage regression:**{{else}}📊 **Coverage:**{{end}} {{.Before}} → {{.After}} ({{.Delta}} points)" +
+			"{{if .Regression}}, a drop of more than {{.Threshold}} points{{end}}",
+		MsgBenchmark: "⏱️ **Benchmarks** against `{{.Base}}`, medians ± spread of `{{.Command}}`; ~ means the runs overlap, so the change may be noise:\n\n{{.Table}}",
+		MsgBenchmarkFailed: "⚠️ **Benchmarks failed** on this branch, though they ran on the default branch:\n\n" +
+			"```\n{{.Command}}\n{{.Error}}\n```",
+		MsgProgress: "{{if .Ended}}⏹️ **Session ended** after {{.Elapsed}}, {{.Turns}} turns{{else}}⏳ **Claude is working on this issue**, {{.Elapsed}} and {{.Turns}} turns so far{{end}}" +
+			"{{if .Plan}}\n\n**Plan:**\n\n{{.Plan}}{{end}}{{if .Step}}\n\n**{{if .Ended}}Last{{else}}Latest{{end}} step:**\n\n{{.Step}}{{end}}",
+	},
+	"th": {
+		MsgCompleted: "✅ **ดำเนินการเสร็จเรียบร้อย**\n\n" +
+			"Claude ดำเนินการกับ issue นี้เสร็จแล้ว งานพร้อมให้ทีมตรวจสอบ",
+		MsgPipelinePassed:       "✅ **Pipeline:** {{.Link}} ผ่าน",
+		MsgPipelineFailed:       "❌ **Pipeline:** {{.Link}} ไม่ผ่าน",
+		MsgPipelineManual:       "⏸️ **Pipeline:** {{.Link}} รอให้สั่งรัน job แบบ manual",
+		MsgPipelineOther:        "⚠️ **Pipeline:** {{.Link}} {{.Status}}",
+		MsgPipelineNoMR:         "⚠️ **Pipeline:** ไม่พบ merge request ของ issue นี้",
+		MsgPipelineNotStarted:   "⏱️ **Pipeline:** ไม่มี pipeline เริ่มทำงานภายใน {{.Timeout}}",
+		MsgPipelineStillRunning: "⏱️ **Pipeline:** {{.Link}} ยังอยู่ในสถานะ {{.Status}} หลังจากรอ {{.Timeout}}",
+		MsgAuthExpired: "🔑 **การล็อกอิน Claude CLI หมดอายุ**\n\n" +
+			"ไม่สามารถเริ่มทำงานได้ เพราะการล็อกอิน Claude ของระบบใช้งานไม่ได้แล้ว " +
+			"เมื่อผู้ดูแลล็อกอินใหม่แล้ว ให้ใส่ label `{{.Label}}` อีกครั้งเพื่อลองใหม่",
+		MsgRateLimited: "⏳ **ใช้งานเกินขีดจำกัด**\n\nการใช้งาน Claude API ถึงขีดจำกัดแล้ว " +
+			"ระบบจะลองทำ issue นี้ใหม่โดยอัตโนมัติในอีก {{.Delay}}",
+		MsgSessionCancelled: "🛑 **ยกเลิกการทำงาน**\n\nหยุดการทำงานเนื่องจาก" +
+			"{{if .Stalled}}ไม่มีความคืบหน้าเป็นเวลา {{.Minutes}} นาที{{else}}ทำงานเกินขีดจำกัด {{.Turns}} รอบ{{end}}" +
+			"{{if .Nudged}} แม้จะสั่งให้ทำต่อแล้ว{{end}}\n\n{{.Stats}}",
+		MsgSessionTimedOut: "⏱️ **หมดเวลาการทำงาน**\n\nการทำงานใช้เวลาเกิน {{.Minutes}} นาที" +
+			"{{if gt .Attempts 1}} ใน {{.Attempts}} รอบ{{end}} จึงถูกหยุด " +
+			"issue นี้ถูกติดป้าย `{{.Label}}` ติดป้ายเริ่มงานอีกครั้งเพื่อลองใหม่\n\n{{.Stats}}",
+		MsgMCPConfig: "🔌 **การตั้งค่า MCP มีปัญหา**\n\n{{.Suggestion}}",
+		MsgPushBlocked: "🔒 **ไม่สามารถ push `{{.Branch}}` ได้**\n\n" +
+			"ยังไม่ได้เริ่มทำงาน เนื่องจาก {{.Reason}} " +
+			"เมื่อระบบสามารถ push branch นี้ได้แล้ว ให้ใส่ label `{{.Label}}` อีกครั้งเพื่อลองใหม่",
+		MsgNeedsEnvironment: "🧰 **ไม่พบเครื่องมือที่จำเป็น**\n\n" +
+			"ยังไม่ได้เริ่มทำงาน เนื่องจากเครื่องนี้ไม่มีเครื่องมือที่โปรเจกต์ต้องใช้:\n\n{{.Missing}}\n\n" +
+			"เมื่อติดตั้งเรียบร้อยแล้ว ให้ใส่ label `{{.Label}}` อีกครั้งเพื่อลองใหม่",
+		MsgPossibleDuplicate: "🔁 **อาจเป็น issue ซ้ำ**\n\n" +
+			"issue นี้คล้ายกับ issue ที่เปิดอยู่ต่อไปนี้ จึงยังไม่ได้เริ่มทำงาน:\n\n{{.Candidates}}\n\n" +
+			"หากซ้ำจริง ให้ปิด issue นี้ หากไม่ซ้ำ ให้ใส่ label `{{.Label}}` อีกครั้งโดยคง " +
+			"`{{.DuplicateLabel}}` ไว้ แล้วระบบจะเริ่มทำงานโดยไม่ตรวจซ้ำอีก",
+		MsgLabelSuggestions: "🏷️ **label ที่แนะนำ**\n\n" +
+			"{{if .Applied}}เพิ่มตาม label ของ issue ที่ปิดแล้วซึ่งคล้ายกัน:\n\n{{.Applied}}\n\n{{end}}" +
+			"{{if .Proposed}}label ต่อไปนี้อาจเกี่ยวข้อง โปรดเพิ่มเฉพาะที่ตรง:\n\n{{.Proposed}}{{end}}",
+		MsgApprovalRequired: "✋ **ต้องได้รับการอนุมัติ**\n\n" +
+			"session หยุดก่อนที่จะ {{.Action}} " +
+			"แสดงความคิดเห็น `{{.Command}}` เพื่ออนุญาตให้ดำเนินการต่อ ระหว่างนี้ issue จะมี label `{{.Label}}`",
+		MsgSessionPaused: "⏸️ **session หยุดชั่วคราว**\n\n" +
+			"session หยุดหลังจากเรียกใช้เครื่องมือครั้งล่าสุด: {{.Reason}} งานที่ทำไว้ยังอยู่ครบ " +
+			"และจะทำต่อจากจุดเดิมเมื่อ issue ไม่มี label `{{.Label}}` แล้ว",
+		MsgFailureBundle: "🧰 **รายละเอียดความล้มเหลว**\n\n" +
+			"session ล้มเหลว ({{.Failure}}) ผลลัพธ์ล่าสุดและสถานะของ worktree อยู่ใน [snippet นี้]({{.Link}})",
+		MsgQueuedQuietHours: "🌙 **อยู่ในคิว**\n\n" +
+			"issue นี้เข้ามานอกเวลาทำงาน จึงถูกจัดเข้าคิวไว้ และจะเริ่มดำเนินการเวลา {{.Resumes}}",
+		MsgScaffolded: "🏗️ **สร้างโปรเจกต์แล้ว**\n\n" +
+			"สร้าง [{{.Project}}]({{.Link}}) จาก `{{.Template}}` พร้อม README ที่อธิบายโปรเจกต์แล้ว" +
+			"{{if .Issues}}\n\nงานที่เหลือในรายการถูกแยกเป็น issue ดังนี้:\n\n{{.Issues}}{{end}}" +
+			"{{if .CI}}\n\nเสนอการตั้งค่า CI ไว้ใน {{.CI}}{{end}}",
+		MsgScaffoldNeedsProject: "🏗️ **ต้องระบุ path ของโปรเจกต์**\n\n" +
+			"เพิ่มบรรทัด เช่น `Project: my-group/my-app` ในรายละเอียดเพื่อระบุโปรเจกต์ที่จะสร้าง " +
+			"แล้วติดป้าย `{{.Label}}` อีกครั้ง",
+		MsgScaffoldFailed: "🏗️ **ตั้งค่าโปรเจกต์ไม่สำเร็จ**\n\n{{.Error}}\n\n" +
+			"ขั้นตอนที่สำเร็จแล้วจะไม่ทำซ้ำ ติดป้าย `{{.Label}}` อีกครั้งเพื่อลองใหม่",
+		MsgCIProposed: "⚙️ **เสนอการตั้งค่า CI**\n\n" +
+			"[!{{.MergeRequest}}]({{.Link}}) {{if .Replaced}}สร้าง{{else}}เพิ่ม{{end}} `.gitlab-ci.yml` ใหม่ พร้อม job ของ {{.Jobs}} " +
+			"ผ่านการตรวจของ CI lint ของ GitLab แล้ว",
+		MsgCIUpToDate: "⚙️ **การตั้งค่า CI เป็นปัจจุบันแล้ว**\n\nการสร้าง `.gitlab-ci.yml` ใหม่ไม่มีอะไรเปลี่ยน จึงไม่ได้เปิด merge request",
+		MsgCISetupFailed: "⚙️ **ตั้งค่า CI ไม่สำเร็จ**\n\n{{.Error}}\n\n" +
+			"ติดป้าย `{{.Label}}` อีกครั้งเพื่อลองใหม่",
+		MsgCILintFailed: "⚠️ **การตั้งค่า CI ไม่ถูกต้อง:** CI lint ของ GitLab ไม่ผ่าน `.gitlab-ci.yml` ของการแก้ไขนี้" +
+			"{{if .Attempts}} แม้จะพยายามแก้ไขแล้ว {{.Attempts}} ครั้ง{{end}}:\n\n{{.Errors}}",
+		MsgTerraformPlan: "🏗️ **Terraform plan** ของ module ที่การแก้ไขนี้เกี่ยวข้อง:\n\n{{.Plans}}",
+		MsgTerraformPlanFailed: "⚠️ **Terraform plan ล้มเหลว** issue นี้จึงได้รับ label `error` แทน `{{.Label}}`:\n\n{{.Plans}}\n\n" +
+			"เพิ่ม `{{.Label}}` เมื่อแก้ไข plan แล้ว ความคิดเห็นจะถูกนำไปดำเนินการอีกครั้งตั้งแต่นั้น",
+		MsgMigrationCheck: "🗃️ **ตรวจสอบ migration** กับฐานข้อมูล `{{.Image}}` ชั่วคราว:\n\n{{.Results}}",
+		MsgTriaged: "🏷️ **จัดประเภทแล้ว** เป็น `{{.Type}}` ความซับซ้อน `{{.Complexity}}`\n\n" +
+			"**ประมาณการ:** {{.Estimate}}\n\n{{.Reason}}\n\nเพิ่ม `{{.Label}}` เพื่อให้ Claude ดำเนินการ",
+		MsgCoverageDelta: "{{if .Regression}}⚠️ **Coverage ลดลง:**{{else}}📊 **Coverage:**{{end}} {{.Before}} → {{.After}} ({{.Delta}} จุด)" +
+			"{{if .Regression}} ลดลงมากกว่า {{.Threshold}} จุด{{end}}",
+		MsgBenchmark: "⏱️ **Benchmark** เทียบกับ `{{.Base}}` ค่ามัธยฐาน ± ความแปรปรวนของ `{{.Command}}` โดย ~ หมายถึงผลสองฝั่งซ้อนทับกัน การเปลี่ยนแปลงอาจเป็นเพียงสัญญาณรบกวน:\n\n{{.Table}}",
+		MsgBenchmarkFailed: "⚠️ **Benchmark ล้มเหลว** บน branch นี้ แม้จะรันผ่านบน branch หลัก:\n\n" +
+			"```\n{{.Command}}\n{{.Error}}\n```",
+		MsgProgress: "{{if .Ended}}⏹️ **Session จบแล้ว** หลัง {{.Elapsed}}, {{.Turns}} turns{{else}}⏳ **Claude กำลังดำเนินการ issue นี้** {{.Elapsed}} และ {{.Turns}} turns แล้ว{{end}}" +
+			"{{if .Plan}}\n\n**แผน:**\n\n{{.Plan}}{{end}}{{if .Step}}\n\n**ขั้นตอน{{if .Ended}}สุดท้าย{{else}}ล่าสุด{{end}}:**\n\n{{.Step}}{{end}}",
+	},
+}
@@ -0,0 +1,145 @@
+// Package logging is the daemon's log: leveled, as text for people or JSON
+// for log pipelines, to stdout or a file. It wraps log/slog; records about an
+// issue or merge request carry it as a correlation ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures the log
+type Options struct {
+	Level  string // debug, info, warn or error
+	Format string // FormatText or FormatJSON
+	File   string // Appended to instead of writing to stdout, "" for stdout
+}
+
+var (
+	mu      sync.RWMutex
+	logger  = slog.New(newTextHandler(os.Stdout, slog.LevelInfo))
+	project string // Prefixes correlation IDs, e.g. group/app#12
+)
+
+// Setup replaces the default log, info and up as text on stdout, with the
+// one the options describe. The returned closer closes the log file.
+func Setup(options Options) (io.Closer, error) {
+	level, err := ParseLevel(options.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stdout
+	var closer io.Closer = io.NopCloser(nil)
+	if options.File != "" {
+		if err := os.MkdirAll(filepath.Dir(options.File), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %v", err)
+		}
+		file, err := os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		out, closer = file, file
+	}
+
+	var handler slog.Handler
+	switch options.Format {
+	case "", FormatText:
+		handler = newTextHandler(out, level)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})
+	default:
+		closer.Close()
+		return nil, fmt.Errorf("unknown log format %q, use %s or %s", options.Format, FormatText, FormatJSON)
+	}
+
+	mu.Lock()
+	logger = slog.New(handler)
+	mu.Unlock()
+	slog.SetDefault(logger)
+	return closer, nil
+}
+
+// ParseLevel reads a level name; "" is info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+}
+
+// SetProject sets the project whose issues later correlation IDs name
+func SetProject(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	project = path
+}
+
+// Logger logs records with attributes attached, e.g. an issue
+type Logger struct {
+	attrs []any
+}
+
+// Issue returns a logger for records about an issue, carrying its number and
+// project#number as correlation_id
+func Issue(iid int) *Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &Logger{attrs: []any{"issue", iid, "correlation_id", fmt.Sprintf("%s#%d", project, iid)}}
+}
+
+// MergeRequest returns a logger for records about a merge request, carrying
+// its number and project!number as correlation_id
+func MergeRequest(iid int) *Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &Logger{attrs: []any{"merge_request", iid, "correlation_id", fmt.Sprintf("%s!%d", project, iid)}}
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.log(slog.LevelDebug, format, args) }
+func (l *Logger) Infof(format string, args ...any)  { l.log(slog.LevelInfo, format, args) }
+func (l *Logger) Warnf(format string, args ...any)  { l.log(slog.LevelWarn, format, args) }
+func (l *Logger) Errorf(format string, args ...any) { l.log(slog.LevelError, format, args) }
+
+func (l *Logger) log(level slog.Level, format string, args []any) {
+	mu.RLock()
+	current := logger
+	mu.RUnlock()
+	if !current.Enabled(context.Background(), level) {
+		return
+	}
+	current.Log(context.Background(), level, fmt.Sprintf(format, args...), l.attrs...)
+}
+
+var std = &Logger{}
+
+// Debugf logs detail for troubleshooting, hidden unless the level is debug
+func Debugf(format string, args ...any) { std.Debugf(format, args...) }
+
+// Infof logs what the daemon does
+func Infof(format string, args ...any) { std.Infof(format, args...) }
+
+// Warnf logs a problem the daemon works around
+func Warnf(format string, args ...any) { std.Warnf(format, args...) }
+
+// Errorf logs a failed operation
+func Errorf(format string, args ...any) { std.Errorf(format, args...) }
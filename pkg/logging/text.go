@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// textHandler writes records the way automagic always printed them:
+// "[2006-01-02 15:04:05] message", with DEBUG: or Warning: before debug and
+// warning messages. Correlation attributes are left out, as messages already
+// name their issue; any other attributes follow as key=value.
+type textHandler struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newTextHandler(out io.Writer, level slog.Level) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, out: out, level: level}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] ", record.Time.Format("2006-01-02 15:04:05"))
+	switch {
+	case record.Level < slog.LevelInfo:
+		b.WriteString("DEBUG: ")
+	case record.Level >= slog.LevelWarn && record.Level < slog.LevelError:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(record.Message)
+
+	write := func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "issue", "merge_request", "correlation_id":
+		default:
+			fmt.Fprintf(&b, " %s=%v", attr.Key, attr.Value)
+		}
+		return true
+	}
+	for _, attr := range h.attrs {
+		write(attr)
+	}
+	record.Attrs(write)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{mu: h.mu, out: h.out, level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+// WithGroup is not used by automagic; groups are flattened
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
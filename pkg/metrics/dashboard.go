@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Dashboard returns a Grafana dashboard with a panel for each metric in
+// Definitions, querying a Prometheus data source picked when it is imported
+func Dashboard() ([]byte, error) {
+	type target struct {
+		Expr         string `json:"expr"`
+		LegendFormat string `json:"legendFormat,omitempty"`
+		RefID        string `json:"refId"`
+	}
+	type panel struct {
+		ID          int               `json:"id"`
+		Type        string            `json:"type"`
+		Title       string            `json:"title"`
+		Description string            `json:"description"`
+		Datasource  map[string]string `json:"datasource"`
+		GridPos     map[string]int    `json:"gridPos"`
+		Targets     []target          `json:"targets"`
+	}
+
+	datasource := map[string]string{"type": "prometheus", "uid": "${datasource}"}
+	var panels []panel
+	for i, definition := range Definitions {
+		expr := definition.Name
+		title := strings.TrimPrefix(definition.Name, "automagic_")
+		if definition.Type == Counter {
+			// Counters restart with the daemon, which increase() accounts for
+			expr = fmt.Sprintf("increase(%s[$__rate_interval])", definition.Name)
+			title += " (increase)"
+		}
+		if len(definition.Labels) > 0 {
+			expr = fmt.Sprintf("sum by (%s) (%s)", strings.Join(definition.Labels, ", "), expr)
+		} else if definition.Type == Counter {
+			expr = fmt.Sprintf("sum(%s)", expr)
+		}
+
+		var legend []string
+		for _, label := range definition.Labels {
+			legend = append(legend, "{{"+label+"}}")
+		}
+		panels = append(panels, panel{
+			ID:          i + 1,
+			Type:        "timeseries",
+			Title:       title,
+			Description: definition.Help,
+			Datasource:  datasource,
+			GridPos:     map[string]int{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			Targets:     []target{{Expr: expr, LegendFormat: strings.Join(legend, " "), RefID: "A"}},
+		})
+	}
+
+	dashboard := map[string]interface{}{
+		"title":         "automagic",
+		"uid":           "automagic",
+		"schemaVersion": 39,
+		"editable":      true,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []map[string]string{{"name": "datasource", "label": "Data source", "type": "datasource", "query": "prometheus"}},
+		},
+		"panels": panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,270 @@
+// Package metrics keeps the daemon's Prometheus metrics, serves them for
+// scraping and pushes them to a Pushgateway or InfluxDB where scraping the
+// daemon is not possible.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric types, as written in the TYPE lines of the text format
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Definition describes a metric the daemon records
+type Definition struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string
+}
+
+// Names of the metrics the daemon records
+const (
+	SessionsTotal          = "automagic_sessions_total"
+	SessionDurationSeconds = "automagic_session_duration_seconds_total"
+	SessionCostUSD         = "automagic_session_cost_usd_total"
+	EventsTotal            = "automagic_events_total"
+	ErrorsTotal            = "automagic_errors_total"
+	PollCyclesTotal        = "automagic_poll_cycles_total"
+	LastPollTimestamp      = "automagic_last_poll_timestamp_seconds"
+	RunningSessions        = "automagic_running_sessions"
+)
+
+// Definitions lists every metric, in the order they are written
+var Definitions = []Definition{
+	{SessionsTotal, "Claude session runs by kind (issue or resume) and outcome", Counter, []string{"kind", "outcome"}},
+	{SessionDurationSeconds, "Time spent in Claude session runs", Counter, []string{"kind"}},
+	{SessionCostUSD, "Cost of Claude session runs as reported by Claude, in US dollars", Counter, []string{"kind"}},
+	{EventsTotal, "Issue lifecycle events, e.g. picked_up or resumed", Counter, []string{"kind"}},
+	{ErrorsTotal, "Failed sessions by failure category, e.g. rate_limit", Counter, []string{"category"}},
+	{PollCyclesTotal, "Completed polling cycles", Counter, nil},
+	{LastPollTimestamp, "Unix time of the last completed polling cycle", Gauge, nil},
+	{RunningSessions, "Claude sessions running now", Gauge, nil},
+}
+
+// Options configures where metrics go
+type Options struct {
+	Addr         string        // Listen address serving /metrics, "" for none
+	PushURL      string        // Pushgateway base URL or InfluxDB write URL, "" for none
+	PushFormat   string        // FormatPushgateway or FormatInflux
+	PushInterval time.Duration // How often metrics are pushed
+	PushToken    string        // Sent as the Authorization header of pushes, "" for none
+	Instance     string        // Names this daemon in pushed metrics
+}
+
+// Metrics records the daemon's metrics. A nil *Metrics is valid and records
+// nothing, which is how disabled metrics are represented.
+type Metrics struct {
+	options Options
+	client  *http.Client
+
+	mu     sync.Mutex
+	values map[string]map[string]float64 // Metric name, then rendered label set
+}
+
+// New returns metrics for the options, or nil when they neither serve nor
+// push metrics
+func New(options Options) *Metrics {
+	if options.Addr == "" && options.PushURL == "" {
+		return nil
+	}
+	if options.PushInterval <= 0 {
+		options.PushInterval = time.Minute
+	}
+	return &Metrics{
+		options: options,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		values:  make(map[string]map[string]float64),
+	}
+}
+
+// Session records a finished session run
+func (m *Metrics) Session(kind, outcome string, duration time.Duration, costUSD float64) {
+	if m == nil {
+		return
+	}
+	m.add(SessionsTotal, 1, kind, outcome)
+	m.add(SessionDurationSeconds, duration.Seconds(), kind)
+	m.add(SessionCostUSD, costUSD, kind)
+}
+
+// Event counts an issue lifecycle event
+func (m *Metrics) Event(kind string) {
+	if m == nil {
+		return
+	}
+	m.add(EventsTotal, 1, kind)
+}
+
+// Error counts a failed session by category
+func (m *Metrics) Error(category string) {
+	if m == nil || category == "" {
+		return
+	}
+	m.add(ErrorsTotal, 1, category)
+}
+
+// Cycle records a completed polling cycle and the sessions still running
+func (m *Metrics) Cycle(running int) {
+	if m == nil {
+		return
+	}
+	m.add(PollCyclesTotal, 1)
+	m.set(LastPollTimestamp, float64(time.Now().Unix()))
+	m.set(RunningSessions, float64(running))
+}
+
+func (m *Metrics) add(name string, value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(name)[labelSet(name, labelValues)] += value
+}
+
+func (m *Metrics) set(name string, value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(name)[labelSet(name, labelValues)] = value
+}
+
+func (m *Metrics) series(name string) map[string]float64 {
+	series, ok := m.values[name]
+	if !ok {
+		series = make(map[string]float64)
+		m.values[name] = series
+	}
+	return series
+}
+
+// labelSet renders label values as name="value" pairs in definition order
+func labelSet(name string, values []string) string {
+	for _, definition := range Definitions {
+		if definition.Name != name {
+			continue
+		}
+		pairs := make([]string, 0, len(definition.Labels))
+		for i, label := range definition.Labels {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%s", label, strconv.Quote(value)))
+		}
+		return strings.Join(pairs, ",")
+	}
+	return ""
+}
+
+// sample is one value of a metric
+type sample struct {
+	name   string
+	labels string
+	value  float64
+}
+
+// snapshot returns the recorded values in definition order, then label order
+func (m *Metrics) snapshot() []sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var samples []sample
+	for _, definition := range Definitions {
+		series := m.values[definition.Name]
+		labels := make([]string, 0, len(series))
+		for labelSet := range series {
+			labels = append(labels, labelSet)
+		}
+		sort.Strings(labels)
+		for _, labelSet := range labels {
+			samples = append(samples, sample{definition.Name, labelSet, series[labelSet]})
+		}
+	}
+	return samples
+}
+
+// WriteText writes the metrics in the Prometheus text format
+func (m *Metrics) WriteText(w io.Writer) error {
+	samples := m.snapshot()
+	var b strings.Builder
+	for _, definition := range Definitions {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", definition.Name, definition.Help, definition.Name, definition.Type)
+		for _, s := range samples {
+			switch {
+			case s.name != definition.Name:
+			case s.labels == "":
+				fmt.Fprintf(&b, "%s %s\n", s.name, formatValue(s.value))
+			default:
+				fmt.Fprintf(&b, "%s{%s} %s\n", s.name, s.labels, formatValue(s.value))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Run serves /metrics and pushes the metrics every PushInterval, as
+// configured, until ctx is done; a last push is made on the way out
+func (m *Metrics) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	if m.options.Addr != "" {
+		m.serve(ctx)
+	}
+	if m.options.PushURL == "" {
+		return
+	}
+
+	ticker := time.NewTicker(m.options.PushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Push(); err != nil {
+				fmt.Printf("Warning: failed to push metrics: %v\n", err)
+			}
+			return
+		case <-ticker.C:
+			if err := m.Push(); err != nil {
+				fmt.Printf("Warning: failed to push metrics: %v\n", err)
+			}
+		}
+	}
+}
+
+// serve starts the /metrics endpoint, stopping it when ctx is done
+func (m *Metrics) serve(ctx context.Context) {
+	listener, err := net.Listen("tcp", m.options.Addr)
+	if err != nil {
+		fmt.Printf("Warning: failed to serve metrics on %s: %v\n", m.options.Addr, err)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WriteText(w)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go server.Serve(listener)
+	fmt.Printf("Serving metrics on http://%s/metrics\n", listener.Addr())
+}
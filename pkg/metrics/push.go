@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Push formats
+const (
+	FormatPushgateway = "pushgateway"
+	FormatInflux      = "influx"
+)
+
+// Push sends the current metrics to PushURL. A Pushgateway receives them
+// under job "automagic" and the daemon's instance, replacing what that
+// instance pushed before; InfluxDB receives line protocol, so PushURL must be
+// its full write URL, e.g. http://influx:8086/api/v2/write?org=o&bucket=b.
+func (m *Metrics) Push() error {
+	if m == nil || m.options.PushURL == "" {
+		return nil
+	}
+
+	var method, target, contentType string
+	var body bytes.Buffer
+	switch m.options.PushFormat {
+	case FormatPushgateway:
+		method = "PUT"
+		target = fmt.Sprintf("%s/metrics/job/automagic/instance/%s",
+			strings.TrimRight(m.options.PushURL, "/"), url.PathEscape(m.options.Instance))
+		contentType = "text/plain; version=0.0.4"
+		if err := m.WriteText(&body); err != nil {
+			return err
+		}
+	case FormatInflux:
+		method = "POST"
+		target = m.options.PushURL
+		contentType = "text/plain; charset=utf-8"
+		m.writeLineProtocol(&body, time.Now())
+	default:
+		return fmt.Errorf("unknown push format %q, use %s or %s", m.options.PushFormat, FormatPushgateway, FormatInflux)
+	}
+
+	req, err := http.NewRequest(method, target, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if m.options.PushToken != "" {
+		req.Header.Set("Authorization", m.options.PushToken)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return nil
+}
+
+// writeLineProtocol writes one InfluxDB point per sample: the measurement is
+// the metric name, its labels and the instance are tags, and value the field
+func (m *Metrics) writeLineProtocol(buf *bytes.Buffer, at time.Time) {
+	for _, s := range m.snapshot() {
+		buf.WriteString(s.name)
+		fmt.Fprintf(buf, ",instance=%s", escapeTag(m.options.Instance))
+		if s.labels != "" {
+			for _, pair := range strings.Split(s.labels, ",") {
+				name, quoted, _ := strings.Cut(pair, "=")
+				fmt.Fprintf(buf, ",%s=%s", name, escapeTag(strings.Trim(quoted, `"`)))
+			}
+		}
+		fmt.Fprintf(buf, " value=%s %d\n", formatValue(s.value), at.UnixNano())
+	}
+}
+
+// escapeTag escapes the characters line protocol gives meaning to in tags;
+// an empty tag value is not allowed, so it becomes "none"
+func escapeTag(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(value)
+}
@@ -0,0 +1,214 @@
+// Package migrations checks that new database migrations apply and roll back
+// cleanly against a disposable database
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration tools recognized by Detect
+const (
+	ToolMigrate = "golang-migrate"
+	ToolGoose   = "goose"
+	ToolPrisma  = "prisma"
+)
+
+// maxErrorLines caps how much of a failed command's output a result keeps
+const maxErrorLines = 20
+
+var (
+	// migrateFile is a golang-migrate up migration, e.g. 000002_add_users.up.sql
+	migrateFile = regexp.MustCompile(`^\d+_.+\.up\.sql$`)
+	// gooseFile is a goose SQL migration, e.g. 20240101120000_add_users.sql
+	gooseFile = regexp.MustCompile(`^\d+_.+\.sql$`)
+	// prismaFile is a Prisma migration, e.g. prisma/migrations/20240101120000_init/migration.sql
+	prismaFile = regexp.MustCompile(`(^|/)migrations/[^/]+/migration\.sql$`)
+)
+
+// Set is the migrations of one directory that a change adds
+type Set struct {
+	Tool  string
+	Dir   string   // Migrations directory, relative to the repository
+	Added []string // The migrations added, relative to the repository
+}
+
+// Detect groups the added files of a checkout that are migrations by
+// directory. Goose migrations are told from other numbered SQL files by
+// their "-- +goose Up" annotation.
+func Detect(checkout string, added []string) []Set {
+	sets := make(map[string]*Set)
+	add := func(tool, dir, file string) {
+		key := tool + " " + dir
+		if sets[key] == nil {
+			sets[key] = &Set{Tool: tool, Dir: dir}
+		}
+		sets[key].Added = append(sets[key].Added, file)
+	}
+
+	for _, file := range added {
+		name := path.Base(file)
+		switch {
+		case prismaFile.MatchString(file):
+			add(ToolPrisma, path.Dir(path.Dir(file)), file)
+		case migrateFile.MatchString(name):
+			add(ToolMigrate, path.Dir(file), file)
+		case gooseFile.MatchString(name) && !strings.HasSuffix(name, ".down.sql"):
+			content, err := os.ReadFile(filepath.Join(checkout, filepath.FromSlash(file)))
+			if err == nil && strings.Contains(string(content), "+goose Up") {
+				add(ToolGoose, path.Dir(file), file)
+			}
+		}
+	}
+
+	result := make([]Set, 0, len(sets))
+	for _, set := range sets {
+		sort.Strings(set.Added)
+		result = append(result, *set)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Dir < result[j].Dir })
+	return result
+}
+
+// Result is the check of one set of migrations
+type Result struct {
+	Set     Set
+	Steps   []string // Steps that succeeded, e.g. "applied", "rolled back"
+	Failed  string   // Step that failed, e.g. "roll back", "" when none did
+	Error   string   // The end of the failed step's output
+	Skipped string   // Why the set was not checked, "" when it was
+}
+
+// step is one command of a check
+type step struct {
+	name string // What the step does, e.g. "roll back"
+	done string // The same once done, e.g. "rolled back"
+	args []string
+}
+
+// Checker runs migrations against a throwaway PostgreSQL container
+type Checker struct {
+	Image   string        // PostgreSQL image the database runs on
+	Timeout time.Duration // Limit for checking one set, 0 for none
+}
+
+// Check applies all migrations of a set's directory to a fresh database,
+// rolls back the added ones and applies them again. Prisma migrations have no
+// down step, so they are only applied.
+func (c *Checker) Check(checkout string, set Set) Result {
+	result := Result{Set: set}
+	executable := map[string]string{ToolMigrate: "migrate", ToolGoose: "goose", ToolPrisma: "npx"}[set.Tool]
+	for _, command := range []string{"docker", executable} {
+		if _, err := exec.LookPath(command); err != nil {
+			result.Skipped = command + " is not installed"
+			return result
+		}
+	}
+
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	url, stop, err := c.startDatabase(ctx)
+	if err != nil {
+		result.Skipped = err.Error()
+		return result
+	}
+	defer stop()
+
+	dir := filepath.Join(checkout, filepath.FromSlash(set.Dir))
+	var steps []step
+	switch set.Tool {
+	case ToolMigrate:
+		steps = []step{
+			{"apply", "applied", []string{"migrate", "-path", dir, "-database", url, "up"}},
+			{"roll back", "rolled back", []string{"migrate", "-path", dir, "-database", url, "down", fmt.Sprint(len(set.Added))}},
+			{"re-apply", "re-applied", []string{"migrate", "-path", dir, "-database", url, "up"}},
+		}
+	case ToolGoose:
+		steps = append(steps, step{"apply", "applied", []string{"goose", "-dir", dir, "postgres", url, "up"}})
+		// goose rolls back one migration at a time
+		for range set.Added {
+			steps = append(steps, step{"roll back", "rolled back", []string{"goose", "-dir", dir, "postgres", url, "down"}})
+		}
+		steps = append(steps, step{"re-apply", "re-applied", []string{"goose", "-dir", dir, "postgres", url, "up"}})
+	case ToolPrisma:
+		schema := filepath.Join(filepath.Dir(dir), "schema.prisma")
+		steps = []step{{"apply", "applied", []string{"npx", "--no-install", "prisma", "migrate", "deploy", "--schema", schema}}}
+	}
+
+	for _, s := range steps {
+		cmd := exec.CommandContext(ctx, s.args[0], s.args[1:]...)
+		cmd.Dir = checkout
+		cmd.Env = append(os.Environ(), "DATABASE_URL="+url)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			result.Failed = s.name
+			if ctx.Err() == context.DeadlineExceeded {
+				result.Error = fmt.Sprintf("timed out after %s", c.Timeout)
+			} else {
+				result.Error = fmt.Sprintf("%v\n%s", err, lastLines(output.String()))
+			}
+			return result
+		}
+		if len(result.Steps) == 0 || result.Steps[len(result.Steps)-1] != s.done {
+			result.Steps = append(result.Steps, s.done)
+		}
+	}
+	return result
+}
+
+// startDatabase runs a PostgreSQL container on a random local port and waits
+// for it to accept connections. stop removes the container.
+func (c *Checker) startDatabase(ctx context.Context) (string, func(), error) {
+	output, err := exec.CommandContext(ctx, "docker", "run", "--detach", "--rm",
+		"--publish", "127.0.0.1::5432",
+		"--env", "POSTGRES_PASSWORD=automagic", "--env", "POSTGRES_DB=automagic",
+		c.Image).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to start %s: %v", c.Image, err)
+	}
+	container := strings.TrimSpace(string(output))
+	stop := func() {
+		exec.Command("docker", "rm", "--force", container).Run()
+	}
+
+	output, err = exec.CommandContext(ctx, "docker", "port", container, "5432/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("failed to find the port of the database: %v", err)
+	}
+	address := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+
+	for exec.CommandContext(ctx, "docker", "exec", container, "pg_isready", "-U", "postgres", "-h", "127.0.0.1").Run() != nil {
+		select {
+		case <-ctx.Done():
+			stop()
+			return "", nil, fmt.Errorf("the database did not become ready: %v", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+	return fmt.Sprintf("postgres://postgres:automagic@%s/automagic?sslmode=disable", address), stop, nil
+}
+
+// lastLines keeps the end of a command's output, up to maxErrorLines
+func lastLines(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxErrorLines {
+		lines = lines[len(lines)-maxErrorLines:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,293 @@
+// Package orchestrator embeds the automagic engine in another program. It sets
+// up the daemon the way the automagic binary does, from a configuration, and
+// runs it in the background:
+//
+//	cfg, err := config.Load()
+//	...
+//	if err := orchestrator.Prepare(cfg); err != nil { ... }
+//	o, err := orchestrator.New(cfg, orchestrator.Options{Project: "group/app", Memory: true})
+//	...
+//	o.Subscribe(func(event events.Event) { log.Println(event.EventName()) })
+//	o.Start(ctx)
+//	o.EnqueueIssue(42)
+//	...
+//	o.Shutdown(shutdownCtx)
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/config"
+	"github.com/bilbo290/automagic/pkg/daemon"
+	"github.com/bilbo290/automagic/pkg/errreport"
+	"github.com/bilbo290/automagic/pkg/events"
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/heartbeat"
+	"github.com/bilbo290/automagic/pkg/locale"
+	"github.com/bilbo290/automagic/pkg/logging"
+	"github.com/bilbo290/automagic/pkg/metrics"
+	"github.com/bilbo290/automagic/pkg/prompts"
+	"github.com/bilbo290/automagic/pkg/queue"
+	"github.com/bilbo290/automagic/pkg/telemetry"
+	"github.com/bilbo290/automagic/pkg/tracker"
+)
+
+// Options chooses how the engine runs
+type Options struct {
+	Project       string         // Project to monitor, "" to select one as the binary does (DEFAULT_PROJECT_PATH without a terminal)
+	Memory        bool           // Store sessions and resume them on new comments, like -memory
+	Worker        bool           // Only run what the coordinator queues, like -worker; needs QUEUE_URL and a Project
+	DryRun        bool           // Log what would be done without doing it
+	SemiDryRun    bool           // Clone repositories but do not run Claude
+	HandleSignals bool           // Stop on SIGINT and SIGTERM; programs embedding the engine usually leave this off
+	Version       string         // Reported in telemetry and error reports, "" for "dev"
+	Client        *gitlab.Client // GitLab client to use, nil for one from the configuration
+}
+
+// Orchestrator is an automagic daemon set up from a configuration
+type Orchestrator struct {
+	daemon  *daemon.Daemon
+	queue   queue.Queue
+	options Options
+
+	mu      sync.Mutex
+	started bool
+	done    chan struct{}
+	err     error
+}
+
+// Prepare loads what the engine reads from disk for the configuration: it
+// validates it, loads the prompt templates and comment translations and sets
+// up repository mirrors. The automagic binary calls it before anything else.
+func Prepare(cfg *config.Config) error {
+	if err := config.Validate(cfg); err != nil {
+		return fmt.Errorf("configuration error: %v", err)
+	}
+
+	promptSet, err := prompts.Load(cfg.Prompts.Dir)
+	if err == nil {
+		err = promptSet.CheckRoutes(cfg.Prompts.Labels)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading prompt templates: %v", err)
+	}
+	prompts.Use(promptSet)
+
+	catalog, err := locale.Load(cfg.Locale.Dir)
+	if err != nil {
+		return fmt.Errorf("error loading comment translations: %v", err)
+	}
+	locale.Use(catalog)
+
+	if cfg.Mirror.Enabled {
+		projects := make(map[string]time.Duration)
+		for project, minutes := range cfg.Mirror.Projects {
+			projects[project] = time.Duration(minutes) * time.Minute
+		}
+		claude.UseMirrors(&claude.Mirrors{
+			Dir:      filepath.Join(cfg.Data.Dir, "mirrors"),
+			Refresh:  time.Duration(cfg.Mirror.Refresh) * time.Minute,
+			Projects: projects,
+		})
+	}
+	return nil
+}
+
+// NewClient returns a GitLab client for the configuration, retrying failed
+// requests as it configures
+func NewClient(cfg *config.Config) *gitlab.Client {
+	client := gitlab.NewClient(cfg.GitLab.URL, cfg.GitLab.Token)
+	client.SetRetryPolicy(gitlab.RetryPolicy{
+		MaxRetries: cfg.GitLab.Retries,
+		BaseDelay:  time.Duration(cfg.GitLab.RetryDelay) * time.Second,
+		MaxDelay:   time.Duration(cfg.GitLab.RetryMaxDelay) * time.Second,
+	})
+	return client
+}
+
+// New sets up the daemon for a configuration prepared with Prepare, with the
+// telemetry, heartbeat, metrics, error reporting and distributed queue it
+// configures. Nothing runs until Start.
+func New(cfg *config.Config, options Options) (*Orchestrator, error) {
+	if options.Version == "" {
+		options.Version = "dev"
+	}
+	if options.Worker && (cfg.Queue.URL == "" || options.Project == "") {
+		return nil, fmt.Errorf("worker mode needs QUEUE_URL and a project")
+	}
+	if cfg.Queue.URL != "" && cfg.Queue.Role != queue.RoleCoordinator && cfg.Queue.Role != queue.RoleWorker {
+		return nil, fmt.Errorf("QUEUE_ROLE must be %s or %s, got %q", queue.RoleCoordinator, queue.RoleWorker, cfg.Queue.Role)
+	}
+	if cfg.Metrics.PushURL != "" && cfg.Metrics.PushFormat != metrics.FormatPushgateway && cfg.Metrics.PushFormat != metrics.FormatInflux {
+		return nil, fmt.Errorf("METRICS_PUSH_FORMAT must be %s or %s, got %q", metrics.FormatPushgateway, metrics.FormatInflux, cfg.Metrics.PushFormat)
+	}
+
+	client := options.Client
+	if client == nil {
+		client = NewClient(cfg)
+	}
+
+	var d *daemon.Daemon
+	if options.DryRun {
+		d = daemon.NewWithDryRun(client, cfg, true)
+	} else if options.SemiDryRun {
+		d = daemon.NewWithSemiDryRun(client, cfg)
+	} else {
+		d = daemon.New(client, cfg)
+	}
+	d.HandleSignals(options.HandleSignals)
+	if options.Project != "" {
+		d.SetProject(options.Project)
+		// The project's overrides apply to everything set up below
+		cfg = d.Config()
+	}
+
+	if cfg.Telemetry.Enabled && cfg.Telemetry.Endpoint != "" {
+		logging.Infof("Telemetry enabled, sending anonymous usage counts to %s", cfg.Telemetry.Endpoint)
+		d.SetTelemetry(telemetry.NewReporter(cfg.Telemetry.Endpoint, options.Version))
+	}
+	d.SetHeartbeat(heartbeat.New(cfg.Heartbeat.File))
+	d.SetMetrics(metrics.New(metrics.Options{
+		Addr:         cfg.Metrics.Addr,
+		PushURL:      cfg.Metrics.PushURL,
+		PushFormat:   cfg.Metrics.PushFormat,
+		PushInterval: time.Duration(cfg.Metrics.PushInterval) * time.Second,
+		PushToken:    cfg.Metrics.PushToken,
+		Instance:     cfg.Metrics.Instance,
+	}))
+	errorReports, err := errreport.New(errreport.Options{
+		SentryDSN:   cfg.ErrorReport.SentryDSN,
+		WebhookURL:  cfg.ErrorReport.WebhookURL,
+		Environment: cfg.ErrorReport.Environment,
+		Release:     options.Version,
+		APIFailures: cfg.ErrorReport.APIFailures,
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.SetErrorReporter(errorReports)
+
+	trackers := tracker.New(tracker.Options{
+		Statuses:             cfg.Trackers.Statuses,
+		JiraURL:              cfg.Trackers.JiraURL,
+		JiraEmail:            cfg.Trackers.JiraEmail,
+		JiraToken:            cfg.Trackers.JiraToken,
+		LinearAPIKey:         cfg.Trackers.LinearAPIKey,
+		NotionToken:          cfg.Trackers.NotionToken,
+		NotionDatabase:       cfg.Trackers.NotionDatabase,
+		NotionURLProperty:    cfg.Trackers.NotionURLProperty,
+		NotionStatusProperty: cfg.Trackers.NotionStatusProperty,
+	})
+	if trackers != nil {
+		logging.Infof("Syncing issue status to %s", strings.Join(trackers.Targets(), ", "))
+	}
+	d.SetTrackers(trackers)
+
+	o := &Orchestrator{daemon: d, options: options, done: make(chan struct{})}
+	if cfg.Queue.URL != "" {
+		q, err := queue.Open(cfg.Queue.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the queue: %v", err)
+		}
+		logging.Infof("Distributed queue enabled, running as %s %s", cfg.Queue.Role, cfg.Queue.WorkerID)
+		d.SetQueue(q)
+		o.queue = q
+	}
+	return o, nil
+}
+
+// Start runs the daemon in the background until Shutdown is called or ctx is
+// done. An orchestrator starts only once.
+func (o *Orchestrator) Start(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.started {
+		return fmt.Errorf("orchestrator already started")
+	}
+	o.started = true
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			o.daemon.Stop()
+		case <-o.done:
+		}
+	}()
+
+	go func() {
+		err := o.run()
+		if o.queue != nil {
+			o.queue.Close()
+		}
+		o.mu.Lock()
+		o.err = err
+		o.mu.Unlock()
+		close(o.done)
+	}()
+	return nil
+}
+
+func (o *Orchestrator) run() error {
+	if o.options.Worker {
+		return o.daemon.RunWorker(o.options.Project)
+	}
+	return o.daemon.RunWithMemoryMode(o.options.Memory)
+}
+
+// EnqueueIssue asks the daemon to work on an issue of its project, whether or
+// not it carries the trigger label. Issues enqueued before Start are picked up
+// on the first cycle.
+func (o *Orchestrator) EnqueueIssue(issueIID int) {
+	o.daemon.EnqueueIssue(issueIID)
+}
+
+// Subscribe calls handler with every event the daemon publishes until the
+// returned function is called. Handlers run synchronously on the daemon's
+// goroutines; use events.Subscribe on Events for events of one type.
+func (o *Orchestrator) Subscribe(handler func(events.Event)) (unsubscribe func()) {
+	return o.daemon.Events().SubscribeAll(handler)
+}
+
+// Events returns the daemon's event bus
+func (o *Orchestrator) Events() *events.Bus {
+	return o.daemon.Events()
+}
+
+// Wait blocks until the daemon stops and returns the error it stopped with
+func (o *Orchestrator) Wait() error {
+	<-o.done
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// Shutdown stops the daemon like SIGTERM does, ending or pausing its running
+// sessions, and waits for it to stop or for ctx to be done. Shutting down an
+// orchestrator that never started returns right away.
+func (o *Orchestrator) Shutdown(ctx context.Context) error {
+	o.mu.Lock()
+	if !o.started {
+		o.started = true
+		if o.queue != nil {
+			o.queue.Close()
+		}
+		close(o.done)
+		o.mu.Unlock()
+		return nil
+	}
+	o.mu.Unlock()
+
+	o.daemon.Stop()
+	select {
+	case <-o.done:
+		return o.Wait()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
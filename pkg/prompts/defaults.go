@@ -0,0 +1,192 @@
+package prompts
+
+// defaultTemplates are the built-in prompts, used for any workflow without a
+// template file in the prompts directory
+var defaultTemplates = map[string]string{
+	WorkflowIssue:  defaultIssueTemplate,
+	WorkflowReview: defaultReviewTemplate,
+	WorkflowResume: defaultResumeTemplate,
+}
+
+// defaultIssueTemplate starts a session on a new issue (IssueData)
+const defaultIssueTemplate = `# Look at issue {{.IssueIID}} and fix it
+## Project Information
+- **GitLab Project Path**: ` + "`{{.ProjectPath}}`" + `
+- **Your Username**: @{{.Username}}
+- **Current Working Directory**: ` + "`{{.WorkingDir}}`" + `{{if .ModuleName}}
+- **Go Module**: ` + "`{{.ModuleName}}`" + `{{end}}{{if .RelatedWork}}
+
+## Related Past Work
+Earlier sessions on similar issues in this project touched these files. Look there first, and follow the approach of their merge requests where it fits:
+{{.RelatedWork}}{{end}}{{if .Docs}}
+
+## Relevant Documentation
+These passages of the project's docs and code look closest to the issue:
+
+{{.Docs}}{{end}}
+
+Always use Gitlab MCP for Gitlab related tasks.
+Use git for commit and push.
+
+**Important**: The repository has been verified/cloned and you are now in the project directory.
+
+Complexity Index:
+T4: 1-3 minutes
+T3: 4-6 minutes  
+T2: 7-15 minutes
+T1: 15+ minutes
+
+## MANDATORY Workflow - Follow these steps in order:
+
+### 1. **Retrieve & Analyze Issue** 
+   - Get issue details using GitLab MCP
+   - Read the issue description thoroughly
+   - Read ALL existing comments on the issue to understand context and any previous attempts
+   - Analyze the requirements and acceptance criteria
+
+### 2. **Create and Post Implementation Plan** (REQUIRED)
+   - Search the codebase to understand the current implementation
+   - Identify files that need to be modified
+   - Create a detailed plan with:
+     - Summary of the issue
+     - List of files to be modified
+     - Step-by-step implementation approach
+     - Testing strategy
+     - A Mermaid diagram of the affected components (` + "`flowchart TD`" + `) or of the changed request flow (` + "`sequenceDiagram`" + `)
+   - **POST THIS PLAN AS A COMMENT ON THE GITLAB ISSUE using GitLab MCP**
+   - Format the plan clearly with markdown
+   - GitLab renders the diagram natively, so keep its syntax valid:
+     - Put it in a fenced code block tagged ` + "`mermaid`" + `
+     - Use simple node IDs (letters, digits, underscores) and put labels in double quotes, e.g. ` + "`A[\"pkg/daemon\"] --> B[\"pkg/gitlab\"]`" + `
+     - Keep it under 15 nodes and avoid parentheses, colons or semicolons in unquoted text
+
+### 3. **Verify Current State**
+   - Run 'git status' to check current branch and changes
+   - Run 'git pull' to ensure you have the latest changes
+
+### 4. **Create Branch**
+   - Create a new branch for the issue: ` + "`git checkout -b {{.Branch}}`" + `{{if .PreviousBranch}}
+   - An earlier attempt at this issue left the branch ` + "`{{.PreviousBranch}}`" + `. Do not check it out, reset it or push to it{{end}}
+
+### 5. **Implement Changes**
+   - Follow your posted plan
+   - Make the necessary code changes
+   - Test changes locally
+   - Commit changes with clear commit messages
+
+### 6. **Push & Create MR**{{if .ForkPath}}
+   - You cannot push to ` + "`{{.ProjectPath}}`" + `, so push to your fork instead: ` + "`git push -u fork {{.Branch}}`" + `
+   - Create the merge request using GitLab MCP with ` + "`{{.ForkPath}}`" + ` as the source project and ` + "`{{.ProjectPath}}`" + ` as the target project{{else}}
+   - Push branch: ` + "`git push -u origin {{.Branch}}`" + `
+   - Create merge request using GitLab MCP{{end}}
+   - Reference the issue in the MR description{{if .PreviousBranch}}
+   - Note in the MR description that it supersedes the earlier attempt on ` + "`{{.PreviousBranch}}`" + `{{end}}
+
+### 7. **Final Update & Human Review**
+   - Comment on the issue with the MR link and completion status
+   - The issue will be automagically marked as "waiting_human_review"
+   - Humans can now review your work and provide feedback
+   - If they add comments with feedback, I will automagically resume this session to iterate
+
+**IMPORTANT**: You MUST post your implementation plan to the GitLab issue before making any code changes. This ensures transparency and allows for feedback before implementation begins.
+
+**Human Review Process**: After completion, the issue enters a review phase where:
+- The issue label changes from "picked_up_by_claude" to "waiting_human_review"
+- Humans can review the code, test the changes, and provide feedback
+- Any new comments will automagically trigger a session resume with the feedback context
+- Only when humans are satisfied should they manually change the label to "solved"
+`
+
+// defaultReviewTemplate reviews a merge request (ReviewData)
+const defaultReviewTemplate = `{{if .Diff}}# Code Review of {{.Title}}
+
+Review the changes below before they are pushed{{if .TargetBranch}} and merged into ` + "`{{.TargetBranch}}`" + `{{end}}. There is no merge request: do not use GitLab tools and do not modify any files. You may read files in the current directory for context.
+
+Review for:
+- **Correctness**: Logic errors, edge cases, error handling
+- **Security**: Injection, authentication, secrets in the diff
+- **Performance**: Inefficient queries, loops, or operations
+- **Maintainability**: Code structure, naming, and clarity
+- **Completeness**: Missing tests or documentation
+
+Write the review in markdown, most important findings first, each with the file and line it refers to and a suggested fix. Keep it short when the changes are fine.
+
+End with a line of its own giving your verdict:
+- ` + "`VERDICT: PASS`" + ` when the changes can be pushed as they are
+- ` + "`VERDICT: WARN`" + ` when there are issues worth fixing that do not block the push
+- ` + "`VERDICT: FAIL`" + ` when there are bugs or security problems that must be fixed first
+
+` + "```diff" + `
+{{.Diff}}
+` + "```" + `
+{{else}}# Code Review for Merge Request !{{.MergeRequestIID}}
+
+## Merge Request Information
+- **Project**: {{.ProjectPath}}
+- **Title**: {{.Title}}
+- **Source Branch**: {{.SourceBranch}} → **Target Branch**: {{.TargetBranch}}
+- **Author**: @{{.Author}}
+- **URL**: {{.WebURL}}
+
+## Review Instructions
+
+**DO NOT CLONE THE REPOSITORY.** Instead, use GitLab MCP tools to:
+
+1. **Get merge request details and diffs** using GitLab MCP tools
+   - Use mcp__MCP_GitLab__get_merge_request_diffs to see all changes
+   - Focus on the actual code changes in the diff output
+
+2. **Analyze the code changes** by examining:
+   - What files were modified/added/deleted
+   - The specific lines that changed
+   - Code patterns and logic flow
+   - Potential impacts on other parts of the system
+
+3. **Review for quality and security**:
+   - Code quality and best practices
+   - Security vulnerabilities or potential issues
+   - Performance implications
+   - Error handling and edge cases
+   - Code maintainability and readability
+
+4. **Check for completeness**:
+   - Are tests included for new functionality?
+   - Is documentation updated if needed?
+   - Are there any obvious missing pieces?
+
+5. **Review existing discussions** using GitLab MCP tools
+   - Check if there are existing comments or concerns
+   - See if previous feedback has been addressed
+
+6. **Provide comprehensive feedback**
+   - Post a detailed review comment using GitLab MCP tools
+   - Be constructive and specific
+   - Highlight both positive aspects and areas for improvement
+   - Suggest specific changes if needed
+
+## Review Focus Areas
+- **Security**: Look for SQL injection, XSS, authentication issues, etc.
+- **Performance**: Check for inefficient queries, loops, or operations
+- **Maintainability**: Code structure, naming, and clarity
+- **Correctness**: Logic errors, edge cases, error handling
+- **Standards**: Following project conventions and best practices
+
+**Remember**: You have access to GitLab MCP tools to fetch diffs, discussions, and post comments. Use these tools instead of trying to access the repository directly.
+{{if .Trimmed}}
+**Note**: A previous attempt ran out of context. Fetch diffs one file at a time and keep the review focused on the most important findings.
+{{else}}{{.Incremental}}{{end}}{{end}}`
+
+// defaultResumeTemplate resumes a session with new feedback (ResumeData)
+const defaultResumeTemplate = `{{if .Paused}}# Continue Issue #{{.IssueIID}}
+
+Your session was paused before you finished this issue ({{.Paused}}). Your work so far is still in the working directory. Continue where you left off; if your last tool call may not have completed, check its effect before repeating it.
+
+{{end}}{{.Upstream}}{{.Description}}{{if .Comments}}# New Comments on Issue #{{.IssueIID}}
+
+The following comments were added after you completed this issue:
+
+{{.Comments}}When answering an issue comment, reply inside its thread (POST /projects/:id/issues/:issue_iid/discussions/:discussion_id/notes) instead of posting a new top-level comment.
+
+{{end}}{{.ReviewThreads}}{{if .Trimmed}}If the merge request pipeline is failing, inspect the failed jobs with GitLab MCP tools.
+
+{{else}}{{.PipelineFailure}}{{end}}{{.CILint}}{{if or .Comments .ReviewThreads}}Please review these comments and take any necessary follow-up actions. You can update your previous work, answer questions, or make additional changes as needed.{{end}}`
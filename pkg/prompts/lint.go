@@ -0,0 +1,163 @@
+package prompts
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxStaticText caps the literal text of a template. Instructions that long
+// crowd out the issue itself and usually mean a file was pasted in by mistake.
+const maxStaticText = 16000
+
+// requiredFields lists, per workflow, the fields a template must use for the
+// prompt to make sense; a resume prompt without the new comments is useless
+var requiredFields = map[string][]string{
+	WorkflowIssue:  {"IssueIID"},
+	WorkflowReview: {"MergeRequestIID"},
+	WorkflowResume: {"Comments", "ReviewThreads"},
+}
+
+// Diagnostic is a problem found in a template file
+type Diagnostic struct {
+	Location string // file:line:col
+	Message  string
+}
+
+// LintError collects the diagnostics of every template that failed linting
+type LintError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *LintError) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics)+1)
+	lines = append(lines, fmt.Sprintf("%d problems in prompt templates:", len(e.Diagnostics)))
+	for _, diagnostic := range e.Diagnostics {
+		lines = append(lines, fmt.Sprintf("  %s: %s", diagnostic.Location, diagnostic.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dataFor returns the data type a workflow's template is rendered against
+func dataFor(workflow string) reflect.Type {
+	switch workflow {
+	case WorkflowReview:
+		return reflect.TypeOf(ReviewData{})
+	case WorkflowResume:
+		return reflect.TypeOf(ResumeData{})
+	}
+	return reflect.TypeOf(IssueData{})
+}
+
+// lint checks a parsed template for fields its data does not have, required
+// fields it never uses and oversize static text
+func lint(workflow, path string, tmpl *template.Template) []Diagnostic {
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return []Diagnostic{{Location: path, Message: "template is empty"}}
+	}
+
+	l := &linter{tree: tmpl.Tree, data: dataFor(workflow), used: make(map[string]bool)}
+	l.walk(tmpl.Tree.Root, true)
+
+	for _, field := range requiredFields[workflow] {
+		if !l.used[field] {
+			l.diagnostics = append(l.diagnostics, Diagnostic{
+				Location: path,
+				Message:  fmt.Sprintf("missing required field .%s for the %s workflow", field, workflow),
+			})
+		}
+	}
+
+	if l.staticText > maxStaticText {
+		l.diagnostics = append(l.diagnostics, Diagnostic{
+			Location: path,
+			Message:  fmt.Sprintf("static text is %d characters, more than the limit of %d", l.staticText, maxStaticText),
+		})
+	}
+
+	return l.diagnostics
+}
+
+// linter walks a template's parse tree
+type linter struct {
+	tree        *parse.Tree
+	data        reflect.Type
+	used        map[string]bool
+	staticText  int
+	diagnostics []Diagnostic
+}
+
+// walk visits a node. topLevel is false inside range and with blocks, where
+// dot no longer refers to the workflow data.
+func (l *linter) walk(node parse.Node, topLevel bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.walk(child, topLevel)
+		}
+	case *parse.TextNode:
+		l.staticText += len(n.Text)
+	case *parse.ActionNode:
+		l.walk(n.Pipe, topLevel)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			l.walk(cmd, topLevel)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			l.walk(arg, topLevel)
+		}
+	case *parse.FieldNode:
+		if topLevel {
+			l.checkField(n, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		// $ is the workflow data everywhere in the template
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			l.checkField(n, n.Ident[1])
+		}
+	case *parse.IfNode:
+		l.walk(n.Pipe, topLevel)
+		l.walk(n.List, topLevel)
+		l.walk(n.ElseList, topLevel)
+	case *parse.RangeNode:
+		l.walk(n.Pipe, topLevel)
+		l.walk(n.List, false)
+		l.walk(n.ElseList, topLevel)
+	case *parse.WithNode:
+		l.walk(n.Pipe, topLevel)
+		l.walk(n.List, false)
+		l.walk(n.ElseList, topLevel)
+	}
+}
+
+// checkField records a field reference and reports it if the data has no such field
+func (l *linter) checkField(node parse.Node, name string) {
+	if _, ok := l.data.FieldByName(name); ok {
+		l.used[name] = true
+		return
+	}
+
+	location, _ := l.tree.ErrorContext(node)
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Location: location,
+		Message:  fmt.Sprintf("unknown field .%s (available: %s)", name, fieldNames(l.data)),
+	})
+}
+
+// fieldNames lists the fields of a data type for diagnostics
+func fieldNames(data reflect.Type) string {
+	names := make([]string, 0, data.NumField())
+	for i := 0; i < data.NumField(); i++ {
+		names = append(names, "."+data.Field(i).Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,218 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Built-in workflows; any other <name>.tmpl in the prompts directory adds a
+// custom workflow rendered against IssueData (e.g. triage.tmpl)
+const (
+	WorkflowIssue  = "issue"
+	WorkflowReview = "review"
+	WorkflowResume = "resume"
+)
+
+// templateExt is the file extension of prompt templates
+const templateExt = ".tmpl"
+
+// IssueData is available to the issue template and to custom templates
+type IssueData struct {
+	IssueIID       int
+	Title          string
+	Labels         []string
+	ProjectPath    string
+	Username       string
+	WorkingDir     string
+	ModuleName     string // Go module of the repository, empty if there is none
+	ForkPath       string // Fork to push the branch to when the bot cannot push to the project
+	Branch         string // Branch to work on, issue-N unless that was taken by an earlier attempt
+	PreviousBranch string // Branch of the earlier attempt this one replaces, if any
+	RelatedWork    string // Past sessions on similar issues, one list item each, empty if none
+	Docs           string // Passages of the project's docs closest to the issue, empty if none
+}
+
+// ReviewData is available to the review template
+type ReviewData struct {
+	MergeRequestIID int
+	ProjectPath     string
+	Title           string
+	SourceBranch    string
+	TargetBranch    string
+	Author          string
+	WebURL          string
+	Incremental     string // Section narrowing the review to new commits, if any
+	Trimmed         bool   // Retrying after a context overflow
+	Diff            string // Local diff to review instead of a merge request, see package review
+}
+
+// ResumeData is available to the resume template. The sections are rendered
+// by the daemon and are empty when there is nothing new of that kind.
+type ResumeData struct {
+	IssueIID        int
+	Comments        string // New issue comments, one block per comment
+	ReviewThreads   string // Unresolved merge request review threads
+	PipelineFailure string // Failed pipeline jobs with distilled logs
+	CILint          string // GitLab's CI lint errors for the branch's .gitlab-ci.yml
+	Trimmed         bool   // Retrying after a context overflow
+	Paused          string // Why the session was paused before it finished, when resuming it
+	Upstream        string // Commits the default branch gained since the issue branch left it
+	Description     string // Edits to the issue description since the session last saw it
+}
+
+// Set is a collection of parsed prompt templates keyed by workflow
+type Set struct {
+	templates map[string]*template.Template
+	sources   map[string]string // Template file path, or "built-in"
+}
+
+// Defaults returns the built-in prompt templates
+func Defaults() *Set {
+	set := &Set{templates: make(map[string]*template.Template), sources: make(map[string]string)}
+	for workflow, text := range defaultTemplates {
+		set.templates[workflow] = template.Must(template.New(workflow).Option("missingkey=error").Parse(text))
+		set.sources[workflow] = "built-in"
+	}
+	return set
+}
+
+// Load returns the built-in templates overridden by the <workflow>.tmpl files
+// in dir. An empty dir, or one that does not exist, yields the defaults. Every
+// file is linted; any problem fails the load with a *LintError.
+func Load(dir string) (*Set, error) {
+	set := Defaults()
+	if dir == "" {
+		return set, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return set, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts directory: %v", err)
+	}
+
+	var diagnostics []Diagnostic
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), templateExt) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template %s: %v", path, err)
+		}
+
+		workflow := strings.TrimSuffix(entry.Name(), templateExt)
+		tmpl, err := template.New(path).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			// Parse errors already read "template: file:line: ..."
+			diagnostics = append(diagnostics, Diagnostic{Location: path, Message: err.Error()})
+			continue
+		}
+		diagnostics = append(diagnostics, lint(workflow, path, tmpl)...)
+		set.templates[workflow] = tmpl
+		set.sources[workflow] = path
+	}
+
+	// Fail at startup rather than when an issue first triggers the workflow
+	if len(diagnostics) > 0 {
+		return nil, &LintError{Diagnostics: diagnostics}
+	}
+	return set, nil
+}
+
+// Render executes the template of a workflow against data
+func (s *Set) Render(workflow string, data interface{}) (string, error) {
+	tmpl, ok := s.templates[workflow]
+	if !ok {
+		return "", fmt.Errorf("no prompt template for workflow %q", workflow)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %v", workflow, err)
+	}
+	return b.String(), nil
+}
+
+// Has reports whether a template is configured for the workflow
+func (s *Set) Has(workflow string) bool {
+	_, ok := s.templates[workflow]
+	return ok
+}
+
+// Workflows lists the configured workflows, built-in ones first
+func (s *Set) Workflows() []string {
+	workflows := []string{WorkflowIssue, WorkflowReview, WorkflowResume}
+	var custom []string
+	for workflow := range s.templates {
+		if _, builtIn := defaultTemplates[workflow]; !builtIn {
+			custom = append(custom, workflow)
+		}
+	}
+	sort.Strings(custom)
+	return append(workflows, custom...)
+}
+
+// ForLabels returns the workflow whose template starts a session on an issue
+// with labels: the workflow labels maps the first of them to, or WorkflowIssue
+// when it maps none
+func (s *Set) ForLabels(labels []string, routes map[string]string) string {
+	for _, label := range labels {
+		if workflow, ok := routes[label]; ok && s.Has(workflow) {
+			return workflow
+		}
+	}
+	return WorkflowIssue
+}
+
+// CheckRoutes reports label routes to workflows without a template, which
+// would otherwise quietly fall back to the issue prompt
+func (s *Set) CheckRoutes(routes map[string]string) error {
+	var missing []string
+	for label, workflow := range routes {
+		if !s.Has(workflow) {
+			missing = append(missing, fmt.Sprintf("%s=%s", label, workflow))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no template for the workflows of %s, add <workflow>%s to the prompts directory",
+			strings.Join(missing, ", "), templateExt)
+	}
+	return nil
+}
+
+// Source returns where the template of a workflow came from
+func (s *Set) Source(workflow string) string {
+	if source, ok := s.sources[workflow]; ok {
+		return source
+	}
+	return "not configured"
+}
+
+// active is the set used by Render. Use is meant to be called once at startup,
+// before any session is started.
+var active = Defaults()
+
+// Use makes set the active prompt templates
+func Use(set *Set) {
+	active = set
+}
+
+// Active returns the active prompt templates
+func Active() *Set {
+	return active
+}
+
+// Render executes a template of the active set
+func Render(workflow string, data interface{}) (string, error) {
+	return active.Render(workflow, data)
+}
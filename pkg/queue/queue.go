@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Roles a daemon can take in a distributed deployment
+const (
+	RoleCoordinator = "coordinator" // Polls GitLab and enqueues eligible issues
+	RoleWorker      = "worker"      // Claims queued issues and runs their sessions
+)
+
+// ErrLeaseLost is returned when a lease expired and the job was handed to
+// another worker
+var ErrLeaseLost = errors.New("lease lost")
+
+// Job is an issue waiting for a worker
+type Job struct {
+	ProjectPath string            `json:"project_path"`
+	IssueIID    int               `json:"issue_iid"`
+	Title       string            `json:"title"`
+	Tier        string            `json:"tier"`
+	Labels      []string          `json:"labels,omitempty"`
+	Requires    map[string]string `json:"requires,omitempty"` // Capabilities a worker needs to take the job
+	EnqueuedAt  time.Time         `json:"enqueued_at"`
+}
+
+// Lease is a job claimed by a worker. The job goes back to the queue if the
+// lease is not renewed before Expires, so a crashed worker loses nothing.
+type Lease struct {
+	Job     Job
+	Worker  string
+	Expires time.Time
+}
+
+// Result is what a worker reports back to the coordinator about a job
+type Result struct {
+	ProjectPath string    `json:"project_path"`
+	IssueIID    int       `json:"issue_iid"`
+	Worker      string    `json:"worker"`
+	Success     bool      `json:"success"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Detail      string    `json:"detail,omitempty"` // Failure reason, empty on success
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// Queue is a job queue shared by a coordinator and its workers. Jobs are kept
+// per project and an issue is queued at most once.
+type Queue interface {
+	// Enqueue adds a job, reporting false if the issue is already queued or claimed
+	Enqueue(ctx context.Context, job Job) (bool, error)
+	// Claim leases the oldest job of a project that worker can take, or
+	// returns nil when there is none. Expired leases are requeued first.
+	Claim(ctx context.Context, projectPath string, worker Worker, ttl time.Duration) (*Lease, error)
+	// Renew extends a lease, or returns ErrLeaseLost
+	Renew(ctx context.Context, lease *Lease, ttl time.Duration) error
+	// Complete removes a finished job
+	Complete(ctx context.Context, lease *Lease) error
+	// Release puts a claimed job back at the head of the queue
+	Release(ctx context.Context, lease *Lease) error
+	// Report sends a finished job's result to the coordinator
+	Report(ctx context.Context, result Result) error
+	// Results takes up to max reported results of a project, oldest first
+	Results(ctx context.Context, projectPath string, max int) ([]Result, error)
+	// Pending returns how many jobs of a project wait for a worker, by route
+	// ("" for jobs any worker can take, else the required capabilities)
+	Pending(ctx context.Context, projectPath string) (map[string]int, error)
+	Close() error
+}
+
+// Open connects to the queue at rawURL, e.g. redis://:password@host:6379/0
+func Open(rawURL string) (Queue, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %v", err)
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedisQueue(u)
+	default:
+		return nil, fmt.Errorf("unsupported queue URL scheme %q, use redis://", u.Scheme)
+	}
+}
@@ -0,0 +1,320 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyPrefix namespaces the queue's Redis keys. Each project has a pending list
+// of issue IIDs per route, jobs (hash of IID to job JSON), leases (sorted set
+// of IIDs by lease expiry in milliseconds), owners (hash of IID to worker),
+// queues (hash of IID to its pending list) and routes (set of route names),
+// plus results (list of result JSON) for the coordinator.
+const keyPrefix = "automagic:queue:"
+
+// Scripts run atomically on the server, so two workers never claim one job.
+// Their KEYS are jobs, leases, owners, queues and routes; a job whose pending
+// list is unknown, queued before routing existed, goes back to the default one.
+const (
+	enqueueScript = `
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then return 0 end
+redis.call('RPUSH', ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[4], ARGV[1], ARGV[3])
+if ARGV[4] ~= '' then redis.call('SADD', KEYS[5], ARGV[4]) end
+return 1`
+
+	claimScript = `
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])) do
+  redis.call('ZREM', KEYS[2], id)
+  redis.call('HDEL', KEYS[3], id)
+  redis.call('LPUSH', redis.call('HGET', KEYS[4], id) or ARGV[4], id)
+end
+for i = 5, #ARGV do
+  while true do
+    local id = redis.call('LPOP', ARGV[i])
+    if not id then break end
+    local job = redis.call('HGET', KEYS[1], id)
+    if job then
+      redis.call('ZADD', KEYS[2], ARGV[2], id)
+      redis.call('HSET', KEYS[3], id, ARGV[3])
+      return job
+    end
+  end
+end
+return false`
+
+	renewScript = `
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then return 0 end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1`
+
+	completeScript = `
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then return 0 end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1`
+
+	releaseScript = `
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then return 0 end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('LPUSH', redis.call('HGET', KEYS[4], ARGV[1]) or ARGV[3], ARGV[1])
+return 1`
+
+	resultsScript = `
+local results = {}
+for i = 1, tonumber(ARGV[1]) do
+  local result = redis.call('LPOP', KEYS[1])
+  if not result then break end
+  results[i] = result
+end
+return results`
+)
+
+// RedisQueue keeps jobs and leases in Redis
+type RedisQueue struct {
+	conn *respConn
+}
+
+// Ensure RedisQueue implements the Queue interface
+var _ Queue = (*RedisQueue)(nil)
+
+// NewRedisQueue connects to the Redis server at u, whose path selects the
+// database number
+func NewRedisQueue(u *url.URL) (*RedisQueue, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr += ":6379"
+	}
+	conn := &respConn{addr: addr}
+	if u.User != nil {
+		conn.username = u.User.Username()
+		conn.password, _ = u.User.Password()
+		if conn.password == "" {
+			// redis://password@host is a common shorthand
+			conn.password, conn.username = conn.username, ""
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+		conn.db = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), respTimeout)
+	defer cancel()
+	if _, err := conn.do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to reach Redis: %v", err)
+	}
+	return &RedisQueue{conn: conn}, nil
+}
+
+// projectKeys returns the keys a project's scripts take, in KEYS order
+func projectKeys(projectPath string) []string {
+	base := keyPrefix + projectPath + ":"
+	return []string{base + "jobs", base + "leases", base + "owners", base + "queues", base + "routes"}
+}
+
+// pendingKey returns the key of a project's pending list for route. Jobs any
+// worker can take keep the list used before routing existed.
+func pendingKey(projectPath, route string) string {
+	if route == "" {
+		return keyPrefix + projectPath + ":pending"
+	}
+	return keyPrefix + projectPath + ":pending:" + route
+}
+
+// routesKey returns the key of the set of a project's route names
+func routesKey(projectPath string) string {
+	return keyPrefix + projectPath + ":routes"
+}
+
+// resultsKey returns the key of a project's reported results
+func resultsKey(projectPath string) string {
+	return keyPrefix + projectPath + ":results"
+}
+
+func (q *RedisQueue) eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	command := append([]string{"EVAL", script, strconv.Itoa(len(keys))}, keys...)
+	return q.conn.do(ctx, append(command, args...)...)
+}
+
+// Enqueue adds a job, reporting false if the issue is already queued or claimed
+func (q *RedisQueue) Enqueue(ctx context.Context, job Job) (bool, error) {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+
+	route := routeName(job.Requires)
+	reply, err := q.eval(ctx, enqueueScript, projectKeys(job.ProjectPath),
+		strconv.Itoa(job.IssueIID), string(data), pendingKey(job.ProjectPath, route), route)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue issue #%d: %v", job.IssueIID, err)
+	}
+	return reply == int64(1), nil
+}
+
+// Claim leases the oldest job of a project that worker can take, or returns
+// nil when there is none. Expired leases are requeued first. Jobs with
+// requirements the worker meets come before jobs any worker can take, so
+// capable workers are not kept busy with work others could do.
+func (q *RedisQueue) Claim(ctx context.Context, projectPath string, worker Worker, ttl time.Duration) (*Lease, error) {
+	routes, err := q.routes(ctx, projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expires := now.Add(ttl)
+	defaultPending := pendingKey(projectPath, "")
+	args := []string{strconv.FormatInt(now.UnixMilli(), 10), strconv.FormatInt(expires.UnixMilli(), 10), worker.ID, defaultPending}
+	for _, route := range routes {
+		if worker.Satisfies(parseRouteName(route)) {
+			args = append(args, pendingKey(projectPath, route))
+		}
+	}
+	args = append(args, defaultPending)
+
+	reply, err := q.eval(ctx, claimScript, projectKeys(projectPath), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim a job: %v", err)
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, nil
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to parse job: %v", err)
+	}
+	return &Lease{Job: job, Worker: worker.ID, Expires: expires}, nil
+}
+
+// routes returns the names of the routes a project's jobs have been queued on
+func (q *RedisQueue) routes(ctx context.Context, projectPath string) ([]string, error) {
+	reply, err := q.conn.do(ctx, "SMEMBERS", routesKey(projectPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %v", err)
+	}
+	items, _ := reply.([]interface{})
+
+	routes := make([]string, 0, len(items))
+	for _, item := range items {
+		if route, ok := item.(string); ok && route != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// Renew extends a lease, or returns ErrLeaseLost
+func (q *RedisQueue) Renew(ctx context.Context, lease *Lease, ttl time.Duration) error {
+	expires := time.Now().Add(ttl)
+
+	reply, err := q.eval(ctx, renewScript, projectKeys(lease.Job.ProjectPath),
+		strconv.Itoa(lease.Job.IssueIID), lease.Worker, strconv.FormatInt(expires.UnixMilli(), 10))
+	if err != nil {
+		return fmt.Errorf("failed to renew lease on issue #%d: %v", lease.Job.IssueIID, err)
+	}
+	if reply != int64(1) {
+		return ErrLeaseLost
+	}
+	lease.Expires = expires
+	return nil
+}
+
+// Complete removes a finished job
+func (q *RedisQueue) Complete(ctx context.Context, lease *Lease) error {
+	return q.finish(ctx, completeScript, lease)
+}
+
+// Release puts a claimed job back at the head of its queue
+func (q *RedisQueue) Release(ctx context.Context, lease *Lease) error {
+	return q.finish(ctx, releaseScript, lease)
+}
+
+// finish runs a script that ends a lease, if worker still holds it
+func (q *RedisQueue) finish(ctx context.Context, script string, lease *Lease) error {
+	projectPath := lease.Job.ProjectPath
+	reply, err := q.eval(ctx, script, projectKeys(projectPath),
+		strconv.Itoa(lease.Job.IssueIID), lease.Worker, pendingKey(projectPath, ""))
+	if err != nil {
+		return fmt.Errorf("failed to update job for issue #%d: %v", lease.Job.IssueIID, err)
+	}
+	if reply != int64(1) {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Report sends a finished job's result to the coordinator
+func (q *RedisQueue) Report(ctx context.Context, result Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if _, err := q.conn.do(ctx, "RPUSH", resultsKey(result.ProjectPath), string(data)); err != nil {
+		return fmt.Errorf("failed to report result for issue #%d: %v", result.IssueIID, err)
+	}
+	return nil
+}
+
+// Results takes up to max reported results of a project, oldest first
+func (q *RedisQueue) Results(ctx context.Context, projectPath string, max int) ([]Result, error) {
+	reply, err := q.eval(ctx, resultsScript, []string{resultsKey(projectPath)}, strconv.Itoa(max))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch results: %v", err)
+	}
+	items, _ := reply.([]interface{})
+
+	results := make([]Result, 0, len(items))
+	for _, item := range items {
+		data, _ := item.(string)
+		var result Result
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return results, fmt.Errorf("failed to parse result: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Pending returns how many jobs of a project wait for a worker, by route.
+// Routes with nothing pending are left out.
+func (q *RedisQueue) Pending(ctx context.Context, projectPath string) (map[string]int, error) {
+	routes, err := q.routes(ctx, projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]int)
+	for _, route := range append(routes, "") {
+		reply, err := q.conn.do(ctx, "LLEN", pendingKey(projectPath, route))
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := reply.(int64); n > 0 {
+			pending[route] = int(n)
+		}
+	}
+	return pending, nil
+}
+
+// Close closes the connection
+func (q *RedisQueue) Close() error {
+	return q.conn.close()
+}
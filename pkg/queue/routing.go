@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"sort"
+	"strings"
+)
+
+// Worker identifies a worker and the capabilities it offers, such as
+// language=go, size=large or gpu=yes
+type Worker struct {
+	ID           string
+	Capabilities map[string]string
+}
+
+// Satisfies reports whether the worker offers every capability in requires.
+// Values are compared case-insensitively.
+func (w Worker) Satisfies(requires map[string]string) bool {
+	for key, value := range requires {
+		if !strings.EqualFold(w.Capabilities[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRequirements reads a route's requirements written as key:value pairs
+// joined by +, e.g. size:large+gpu:yes
+func ParseRequirements(spec string) map[string]string {
+	requires := make(map[string]string)
+	for _, pair := range strings.Split(spec, "+") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if key != "" && value != "" {
+			requires[key] = strings.ToLower(value)
+		}
+	}
+	return requires
+}
+
+// routeName canonicalizes requirements into the name of the route whose
+// workers can take the job: sorted key=value pairs, "" for any worker
+func routeName(requires map[string]string) string {
+	pairs := make([]string, 0, len(requires))
+	for key, value := range requires {
+		pairs = append(pairs, key+"="+strings.ToLower(value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// parseRouteName is the inverse of routeName
+func parseRouteName(route string) map[string]string {
+	requires := make(map[string]string)
+	if route == "" {
+		return requires
+	}
+	for _, pair := range strings.Split(route, ",") {
+		if parts := strings.SplitN(pair, "=", 2); len(parts) == 2 {
+			requires[parts[0]] = parts[1]
+		}
+	}
+	return requires
+}
@@ -0,0 +1,89 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// hookMarker identifies the hooks written by InstallHook, so they can be
+// replaced without -force
+const hookMarker = "# Installed by automagic hooks install"
+
+// prePushHook runs "automagic review" on the commits of each pushed ref. Git
+// gives the hook the remote name and one "<local ref> <local sha> <remote ref>
+// <remote sha>" line per ref; refs that are deleted are skipped, and new
+// branches are reviewed against the remote's default branch. The review's
+// stdin is closed so Claude does not read the ref lines.
+const prePushHook = `#!/bin/sh
+%s
+# Reviews the pushed commits with Claude and stops the push when the verdict is
+# worse than %s. Skip it with git push --no-verify.
+remote="$1"
+while read -r local_ref local_sha remote_ref remote_sha; do
+	case "$local_sha" in *[!0]*) ;; *) continue ;; esac
+	case "$remote_sha" in
+	*[!0]*) range="$remote_sha..$local_sha" ;;
+	*)
+		git rev-parse --verify --quiet "$remote/HEAD" >/dev/null || continue
+		range="$remote/HEAD..$local_sha"
+		;;
+	esac
+	echo "automagic: reviewing $local_ref ($range)"
+	%s review -range "$range" -title "$local_ref" -model %s -threshold %s </dev/null || exit 1
+done
+exit 0
+`
+
+// HookOptions configures the pre-push hook
+type HookOptions struct {
+	Binary    string // automagic executable the hook runs
+	Model     string // Model the hook reviews with
+	Threshold string // Worst verdict that lets a push through, VerdictPass or VerdictWarn
+	Force     bool   // Replace a pre-push hook automagic did not write
+}
+
+// InstallHook writes a pre-push hook running the local review into the hooks
+// directory of the git repository in dir and returns its path
+func InstallHook(dir string, opts HookOptions) (string, error) {
+	if opts.Threshold != VerdictPass && opts.Threshold != VerdictWarn {
+		return "", fmt.Errorf("invalid threshold %q, use %s or %s", opts.Threshold, VerdictPass, VerdictWarn)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the hooks directory, is %s a git repository? %v", dir, err)
+	}
+	hooksDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %v", err)
+	}
+
+	path := filepath.Join(hooksDir, "pre-push")
+	if existing, err := os.ReadFile(path); err == nil && !opts.Force && !strings.Contains(string(existing), hookMarker) {
+		return "", fmt.Errorf("%s already exists, use -force to replace it", path)
+	}
+
+	script := fmt.Sprintf(prePushHook, hookMarker, opts.Threshold,
+		shellQuote(opts.Binary), shellQuote(opts.Model), opts.Threshold)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %v", err)
+	}
+	// WriteFile keeps the mode of a hook it replaces
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to make hook executable: %v", err)
+	}
+	return path, nil
+}
+
+// shellQuote quotes a word for sh
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
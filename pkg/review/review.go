@@ -0,0 +1,134 @@
+// Package review runs the merge request review workflow against a local diff,
+// for reviewing changes before they are pushed.
+package review
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/bilbo290/automagic/pkg/prompts"
+)
+
+// Verdicts a review ends with, from best to worst
+const (
+	VerdictPass = "pass"
+	VerdictWarn = "warn"
+	VerdictFail = "fail"
+)
+
+// verdictPattern matches the verdict line the review template asks for
+var verdictPattern = regexp.MustCompile(`(?im)^\W*VERDICT:\W*(PASS|WARN|FAIL)\b`)
+
+// readOnlyTools are the tools a local review may use for context
+const readOnlyTools = "Read Grep Glob"
+
+// Request is a local diff to review
+type Request struct {
+	Diff         string
+	Title        string // What is reviewed, e.g. the range or the patch file
+	SourceBranch string // Branch of the changes, if known
+	TargetBranch string // Branch the changes go to, if known
+}
+
+// Reviewer reviews diffs with the Claude CLI
+type Reviewer struct {
+	Command string // Claude CLI executable
+	Model   string // Model to review with, empty for the CLI's default
+	Dir     string // Directory Claude may read for context, empty for the current one
+}
+
+// Review renders the review prompt for the diff and returns Claude's review
+func (r *Reviewer) Review(ctx context.Context, req Request) (string, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return "", fmt.Errorf("the diff is empty, nothing to review")
+	}
+	prompt, err := prompts.Render(prompts.WorkflowReview, prompts.ReviewData{
+		Title:        req.Title,
+		SourceBranch: req.SourceBranch,
+		TargetBranch: req.TargetBranch,
+		Diff:         req.Diff,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"--output-format", "text", "--allowedTools", readOnlyTools}
+	if r.Model != "" {
+		args = append(args, "--model", r.Model)
+	}
+	args = append(args, "-p", prompt)
+
+	cmd := exec.CommandContext(ctx, r.Command, args...)
+	cmd.Dir = r.Dir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run the review: %v", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Verdict returns the verdict a review ends with, "" when it has none
+func Verdict(review string) string {
+	matches := verdictPattern.FindAllStringSubmatch(review, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return strings.ToLower(matches[len(matches)-1][1])
+}
+
+// Passes reports whether a verdict is no worse than threshold, VerdictPass or
+// VerdictWarn. A review without a verdict counts as VerdictWarn.
+func Passes(verdict, threshold string) bool {
+	rank := map[string]int{VerdictPass: 0, VerdictWarn: 1, VerdictFail: 2}
+	if verdict == "" {
+		verdict = VerdictWarn
+	}
+	return rank[verdict] <= rank[threshold]
+}
+
+// DiffFromFile reads a patch file, or stdin when path is "-"
+func DiffFromFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read diff: %v", err)
+	}
+	return string(data), nil
+}
+
+// DiffFromRange returns the diff of a git revision range such as
+// main..feature in the repository in dir
+func DiffFromRange(dir, revisionRange string) (string, error) {
+	cmd := exec.Command("git", "diff", revisionRange)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s: %v: %s", revisionRange, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// RangeBranches returns the two ends of a range such as main..feature or
+// main...feature, "" for an end that is left out
+func RangeBranches(revisionRange string) (target, source string) {
+	parts := strings.SplitN(strings.Replace(revisionRange, "...", "..", 1), "..", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
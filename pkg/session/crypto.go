@@ -0,0 +1,242 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sealedPrefix marks a value encrypted by seal, so plaintext written before
+// encryption was enabled can still be read
+const sealedPrefix = "enc:v1:"
+
+// EncryptionKeySize is the length of the AES-256 key protecting the store
+const EncryptionKeySize = 32
+
+// storeCipher encrypts sensitive columns when set; nil stores plaintext
+var storeCipher cipher.AEAD
+
+// SetEncryptionKey enables encryption of sensitive session data (working
+// directories, commands, flags, environment snapshots, event details,
+// webhook payloads and, through SealLine, transcripts). It must be called
+// before any store is opened.
+func SetEncryptionKey(key []byte) error {
+	if len(key) != EncryptionKeySize {
+		return fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	storeCipher = aead
+	return nil
+}
+
+// GenerateEncryptionKey returns a new random key
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+// seal encrypts a value when encryption is enabled
+func seal(plain string) string {
+	if storeCipher == nil || plain == "" || isSealed(plain) {
+		return plain
+	}
+
+	nonce := make([]byte, storeCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		// Never fall back to writing plaintext
+		panic(fmt.Sprintf("failed to generate nonce: %v", err))
+	}
+	sealed := storeCipher.Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// unseal decrypts a value written by seal; plaintext is returned unchanged
+func unseal(value string) (string, error) {
+	if !isSealed(value) {
+		return value, nil
+	}
+	if storeCipher == nil {
+		return "", fmt.Errorf("value is encrypted but SESSION_ENCRYPTION is off")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil || len(sealed) < storeCipher.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:storeCipher.NonceSize()], sealed[storeCipher.NonceSize():]
+	plain, err := storeCipher.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, is the key right? %v", err)
+	}
+	return string(plain), nil
+}
+
+// mustUnseal is unseal for read paths that cannot return an error; it logs
+// and yields an empty value rather than handing ciphertext to callers
+func mustUnseal(value string) string {
+	plain, err := unseal(value)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return ""
+	}
+	return plain
+}
+
+// SealLine encrypts one line of a file kept next to the store, such as a
+// transcript, when encryption is enabled. A sealed line holds no newline.
+func SealLine(line string) string {
+	return seal(line)
+}
+
+// UnsealLine decrypts a line written by SealLine; plaintext lines, written
+// before encryption was enabled, are returned unchanged
+func UnsealLine(line string) (string, error) {
+	return unseal(line)
+}
+
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// sealedColumns lists the sensitive columns of the SQLite store by table.
+// Project paths and session IDs stay plaintext because queries filter on them.
+var sealedColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"completed_sessions", []string{"working_dir", "claude_command", "claude_flags", "env_vars"}},
+	{"issue_events", []string{"detail"}},
+	{"webhook_events", []string{"payload"}},
+	{"issue_descriptions", []string{"description"}},
+	{"failure_bundles", []string{"content"}},
+}
+
+// HasSealedData reports whether the stores in dataDir hold values encrypted
+// with some key, so a new key must not be generated in its place
+func HasSealedData(dataDir string) (bool, error) {
+	if data, err := os.ReadFile(filepath.Join(dataDir, "sessions.json")); err == nil && isSealed(string(data)) {
+		return true, nil
+	}
+
+	dbPath := filepath.Join(dataDir, dbFileName)
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return false, nil
+	}
+	db, err := openSQLite(dbPath, true)
+	if err != nil {
+		return false, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for _, t := range sealedColumns {
+		var tables int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, t.table).Scan(&tables); err != nil {
+			return false, fmt.Errorf("failed to read %s: %v", dbPath, err)
+		}
+		if tables == 0 {
+			continue
+		}
+		for _, column := range t.columns {
+			var sealed int
+			query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE CAST(%s AS TEXT) LIKE ?`, t.table, column)
+			if err := db.QueryRow(query, sealedPrefix+"%").Scan(&sealed); err != nil {
+				// Databases older than the column were never encrypted
+				if strings.Contains(err.Error(), "no such column") {
+					continue
+				}
+				return false, fmt.Errorf("failed to check %s.%s: %v", t.table, column, err)
+			}
+			if sealed > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// sealExisting encrypts rows written before encryption was enabled. Without a
+// key it refuses to open a store that already holds encrypted rows, rather than
+// failing on every read later.
+func (s *SQLiteSessionStore) sealExisting() error {
+	for _, t := range sealedColumns {
+		for _, column := range t.columns {
+			if storeCipher == nil {
+				var sealed int
+				query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE CAST(%s AS TEXT) LIKE ?`, t.table, column)
+				if err := s.db.QueryRow(query, sealedPrefix+"%").Scan(&sealed); err != nil {
+					return fmt.Errorf("failed to check %s.%s: %v", t.table, column, err)
+				}
+				if sealed > 0 {
+					return fmt.Errorf("session database is encrypted, set SESSION_ENCRYPTION=true and provide its key")
+				}
+				continue
+			}
+
+			if err := s.sealColumn(t.table, column); err != nil {
+				return fmt.Errorf("failed to encrypt %s.%s: %v", t.table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sealColumn encrypts every plaintext value of one column in a transaction
+func (s *SQLiteSessionStore) sealColumn(table, column string) error {
+	query := fmt.Sprintf(`SELECT rowid, CAST(%s AS TEXT) FROM %s WHERE %s IS NOT NULL AND %s != '' AND CAST(%s AS TEXT) NOT LIKE ?`,
+		column, table, column, column, column)
+	rows, err := s.db.Query(query, sealedPrefix+"%")
+	if err != nil {
+		return err
+	}
+
+	plain := make(map[int64]string)
+	for rows.Next() {
+		var rowID int64
+		var value string
+		if err := rows.Scan(&rowID, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		plain[rowID] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, table, column)
+	for rowID, value := range plain {
+		if _, err := tx.Exec(update, seal(value), rowID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Encrypted %d existing value(s) in %s.%s\n", len(plain), table, column)
+	return nil
+}
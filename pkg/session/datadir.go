@@ -0,0 +1,98 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/bilbo290/automagic/pkg/logging"
+)
+
+// DefaultDataDir is where automagic keeps its data unless DATA_DIR says otherwise
+func DefaultDataDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".automagic")
+}
+
+// legacyDataDir is where versions before DATA_DIR kept both stores
+func legacyDataDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".automagic")
+}
+
+// legacyStoreFiles are the store files migrated out of the legacy directory,
+// in groups moved together: the SQLite database with its WAL and shared
+// memory files, and the JSON store
+var legacyStoreFiles = [][]string{
+	{dbFileName, dbFileName + "-wal", dbFileName + "-shm"},
+	{"sessions.json"},
+}
+
+// MigrateLegacyData moves the session stores left in the legacy directory
+// into dataDir. Nothing else there is touched. A store dataDir already has is
+// left where it is, so running it again is harmless. It returns the paths
+// that were moved.
+func MigrateLegacyData(dataDir string) ([]string, error) {
+	target, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data directory: %v", err)
+	}
+	legacy := legacyDataDir()
+	if legacy == target {
+		return nil, nil
+	}
+
+	var moved []string
+	for _, group := range legacyStoreFiles {
+		first := filepath.Join(legacy, group[0])
+		if _, err := os.Lstat(first); os.IsNotExist(err) {
+			continue
+		}
+		if existing := filepath.Join(target, group[0]); fileExists(existing) {
+			logging.Warnf("Not migrating %s, %s already exists", first, existing)
+			continue
+		}
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return moved, fmt.Errorf("failed to create data directory: %v", err)
+		}
+
+		for _, name := range group {
+			from := filepath.Join(legacy, name)
+			if !fileExists(from) {
+				continue
+			}
+			if err := moveFile(from, filepath.Join(target, name)); err != nil {
+				return moved, err
+			}
+			moved = append(moved, from)
+		}
+	}
+
+	return moved, nil
+}
+
+// moveFile renames a file, copying it and removing the original when the
+// two paths are on different filesystems
+func moveFile(from, to string) error {
+	err := os.Rename(from, to)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("failed to move %s to %s: %v", from, to, err)
+	}
+
+	if err := copyFile(from, to); err != nil {
+		os.Remove(to)
+		return fmt.Errorf("failed to move %s to %s: %v", from, to, err)
+	}
+	if err := os.Remove(from); err != nil {
+		return fmt.Errorf("copied %s to %s but failed to remove it: %v", from, to, err)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -6,6 +6,14 @@ import "time"
 type Store interface {
 	AddCompletedSession(issueIID int, sessionID, projectPath string, completionTime time.Time, workingDir, claudeCommand, claudeFlags string, envVars map[string]string) error
 	UpdateLastCommentTime(issueIID int, commentTime time.Time) error
+	// UpdateLastNote records the newest note seen for an issue; a zero
+	// commentTime leaves the last comment time unchanged
+	UpdateLastNote(issueIID, noteID int, commentTime time.Time) error
+	// UpdateForkPath records the fork an issue's branch was pushed to
+	UpdateForkPath(issueIID int, forkPath string) error
+	// UpdateBranch records the branch an issue session pushed to and the
+	// session of the attempt before it
+	UpdateBranch(issueIID int, branch, previousSessionID string) error
 	GetCompletedSession(issueIID int) (*CompletedSession, bool)
 	GetCompletedSessions() []*CompletedSession
 	GetRecentlyCompletedSessions(since time.Duration) []*CompletedSession
@@ -20,4 +28,274 @@ type Loadable interface {
 // Save method for backward compatibility with JSON store  
 type Saveable interface {
 	Save() error
-}
\ No newline at end of file
+}
+
+// ReviewTracker records the last reviewed head commit of each merge request
+type ReviewTracker interface {
+	GetReviewedSHA(projectID, mrIID int) (string, bool)
+	SetReviewedSHA(projectID, mrIID int, sha string) error
+}
+
+// Event kinds recorded in an issue's audit log
+const (
+	EventPickedUp         = "picked_up"
+	EventCompleted        = "completed"
+	EventFailed           = "failed"
+	EventResumed          = "resumed"
+	EventResumeCompleted  = "resume_completed"
+	EventResumeFailed     = "resume_failed"
+	EventPipeline         = "pipeline"
+	EventTriaged          = "triaged"
+	EventAwaitingApproval = "awaiting_approval"
+	EventApproved         = "approved"
+	EventPaused           = "paused"
+	EventUnpaused         = "unpaused"
+	EventRebased          = "rebased"
+	EventDescriptionSync  = "description_sync"
+	EventMerged           = "merged"
+	EventCILint           = "ci_lint"
+	EventTerraformPlan    = "terraform_plan"
+	EventMigrationCheck   = "migration_check"
+	EventClassified       = "classified"
+	EventCoverage         = "coverage"
+	EventBenchmark        = "benchmark"
+)
+
+// Event is a single entry in an issue's audit log
+type Event struct {
+	IssueIID    int
+	ProjectPath string
+	Kind        string
+	SessionID   string
+	Detail      string
+	Time        time.Time
+}
+
+// EventLog records the lifecycle of each issue as seen by the daemon
+type EventLog interface {
+	RecordEvent(event Event) error
+	GetEvents(issueIID int) ([]Event, error)
+}
+
+// EventFeed lists the events of every issue, for the activity feed
+type EventFeed interface {
+	// RecentEvents returns up to limit events recorded at or after since,
+	// newest first
+	RecentEvents(since time.Time, limit int) ([]Event, error)
+}
+
+// BackfillEntry is a historical issue held back until its release time
+type BackfillEntry struct {
+	IssueIID    int
+	ProjectPath string
+	ReleaseAt   time.Time
+}
+
+// BackfillQueue trickles historical issues to the daemon at a controlled rate
+// instead of releasing them all on first deployment
+type BackfillQueue interface {
+	// EnqueueBackfill queues entries, skipping issues already queued, and
+	// returns how many were added
+	EnqueueBackfill(entries []BackfillEntry) (int, error)
+	// LastBackfillRelease returns the latest scheduled release in a project
+	LastBackfillRelease(projectPath string) (time.Time, bool)
+	GetBackfill(projectPath string) ([]BackfillEntry, error)
+	RemoveBackfill(projectPath string, issueIID int) error
+}
+
+// QueuedWebhookEvent is a webhook delivery that has not been handled yet
+type QueuedWebhookEvent struct {
+	DeliveryID string
+	Kind       string
+	Payload    []byte
+	ReceivedAt time.Time
+}
+
+// WebhookQueue persists webhook deliveries until the daemon has handled them,
+// and remembers when the daemon last synced with GitLab
+type WebhookQueue interface {
+	EnqueueWebhookEvent(event QueuedWebhookEvent) error
+	PendingWebhookEvents() ([]QueuedWebhookEvent, error)
+	MarkWebhookEventsHandled(before time.Time) error
+	GetLastSyncTime() (time.Time, bool)
+	SetLastSyncTime(syncTime time.Time) error
+}
+
+// SessionSummary describes the work of a completed issue session, for
+// pointing later sessions at related changes
+type SessionSummary struct {
+	IssueIID        int
+	ProjectPath     string
+	Title           string
+	Labels          []string
+	Files           []string // Files changed on the issue branch
+	MergeRequestIID int      // 0 when no merge request was found
+	CompletedAt     time.Time
+}
+
+// KnowledgeBase indexes the summaries of completed sessions
+type KnowledgeBase interface {
+	// RecordSummary stores a summary, replacing an earlier one of the issue
+	RecordSummary(summary SessionSummary) error
+	// GetSummaries returns the summaries of a project, newest first
+	GetSummaries(projectPath string) ([]SessionSummary, error)
+}
+
+// BackupRotator keeps rolling backups of a store that lives in a file
+type BackupRotator interface {
+	// RotateBackups takes today's backup if it is missing and keeps the
+	// newest retain backups, returning the path of a new backup or ""
+	RotateBackups(retain int) (string, error)
+}
+
+// RetentionPolicy is how long a session record is kept after its last
+// activity, by the outcome of the latest run on it. Zero keeps records forever.
+type RetentionPolicy struct {
+	Succeeded time.Duration
+	Failed    time.Duration
+}
+
+// SessionPruner deletes session records past their retention
+type SessionPruner interface {
+	// CleanupOldSessions removes expired sessions unless keep returns true
+	// for them, and returns how many were removed
+	CleanupOldSessions(policy RetentionPolicy, keep func(*CompletedSession) bool) (int, error)
+}
+
+// CommentTracker remembers the ID of the last issue note the daemon acted on,
+// so a restart does not mistake already handled comments for new ones. GitLab
+// note IDs increase across the instance, so they order notes without
+// comparing timestamps.
+type CommentTracker interface {
+	GetProcessedNote(issueIID int) (int, bool)
+	SetProcessedNote(issueIID, noteID int) error
+	ClearProcessedNote(issueIID int) error
+}
+
+// IntakeTracker remembers the issues the daemon started on while they carry
+// the trigger label, so a restart does not start them a second time
+type IntakeTracker interface {
+	GetProcessedIssues() (map[int]bool, error)
+	SetProcessedIssue(issueIID int) error
+	ClearProcessedIssue(issueIID int) error
+}
+
+// PauseTracker finds the issues whose session is paused
+type PauseTracker interface {
+	// PausedIssues returns the issues whose latest pause event is EventPaused
+	// rather than EventUnpaused
+	PausedIssues() ([]int, error)
+}
+
+// DescriptionTracker remembers each issue's description as its session last
+// saw it, so later edits can be passed on to the session
+type DescriptionTracker interface {
+	GetIssueDescription(issueIID int) (string, bool)
+	SetIssueDescription(issueIID int, description string) error
+}
+
+// IssueLink ties an issue to the branch its session pushed and the merge
+// request opened from it
+type IssueLink struct {
+	IssueIID          int
+	ProjectPath       string
+	Branch            string
+	ForkPath          string // Project the branch was pushed to, "" for ProjectPath
+	MergeRequestIID   int    // 0 until a merge request is found
+	MergeRequestURL   string
+	MergeRequestState string // opened, merged or closed, "" until known
+	PipelineStatus    string // Status of the merge request's head pipeline, "" for none
+	UpdatedAt         time.Time
+}
+
+// LinkTracker records the branch and merge request of each issue
+type LinkTracker interface {
+	// SetIssueLink stores a link, replacing an earlier one of the issue
+	SetIssueLink(link IssueLink) error
+	GetIssueLink(issueIID int) (*IssueLink, bool)
+	// OpenIssueLinks returns the links whose merge request is not known to
+	// be merged or closed
+	OpenIssueLinks() ([]IssueLink, error)
+}
+
+// FailureBundle is the rendered state a failed session left behind
+type FailureBundle struct {
+	IssueIID    int
+	ProjectPath string
+	SessionID   string
+	Content     string
+	SnippetURL  string // Snippet the bundle was uploaded to, if it was
+	CreatedAt   time.Time
+}
+
+// FailureBundles keeps the failure bundle of each issue's latest failed session
+type FailureBundles interface {
+	// SaveFailureBundle stores a bundle, replacing an earlier one of the issue
+	SaveFailureBundle(bundle FailureBundle) error
+	GetFailureBundle(issueIID int) (*FailureBundle, bool)
+}
+
+// Transcript is a file holding the raw stream-json output of one session run
+type Transcript struct {
+	IssueIID    int
+	ProjectPath string
+	SessionID   string // Session resumed by the run, "" for a new session
+	Kind        string // RunIssue or RunResume
+	Path        string
+	StartedAt   time.Time
+}
+
+// TranscriptIndex records where the transcripts of each issue's runs are
+type TranscriptIndex interface {
+	AddTranscript(transcript Transcript) error
+	// GetTranscripts returns the transcripts of an issue, oldest first
+	GetTranscripts(issueIID int) ([]Transcript, error)
+	// PruneTranscripts forgets the transcripts started before a time and
+	// returns them, so their files can be removed
+	PruneTranscripts(before time.Time) ([]Transcript, error)
+}
+
+// SessionRun is one run of a Claude session on an issue, for cost and
+// outcome reporting
+type SessionRun struct {
+	IssueIID    int
+	ProjectPath string
+	SessionID   string
+	Kind        string // RunIssue or RunResume
+	Outcome     string // RunCompleted or RunFailed
+	Failure     string // Why a failed run failed, e.g. stalled
+	Model       string
+	StartedAt   time.Time
+	Duration    time.Duration
+	CostUSD     float64 // As reported by Claude, 0 when it reported none
+	Turns       int
+	Retries     int // Attempts beyond the first, on the fallback model or after a nudge
+
+	InputTokens         int
+	OutputTokens        int
+	CacheReadTokens     int
+	CacheCreationTokens int
+}
+
+// Kinds and outcomes of session runs
+const (
+	RunIssue     = "issue"
+	RunResume    = "resume"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+// RunHistory keeps a record of every session run
+type RunHistory interface {
+	RecordRun(run SessionRun) error
+	// GetRuns returns the runs of every project started at or after since,
+	// oldest first
+	GetRuns(since time.Time) ([]SessionRun, error)
+}
+
+// ProjectScoped is implemented by stores keyed by project path and issue IID.
+// SetProject limits the issue-keyed Store and EventLog methods to one project,
+// since issue IIDs are only unique within a project.
+type ProjectScoped interface {
+	SetProject(projectPath string)
+}
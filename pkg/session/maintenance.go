@@ -0,0 +1,191 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dbFileName = "sessions.db"
+	backupDir  = "backups"
+	// backupPrefix and backupDateFormat name the rolling daily backups,
+	// e.g. sessions-2024-05-01.db
+	backupPrefix     = "sessions-"
+	backupDateFormat = "2006-01-02"
+)
+
+// integrityCheck runs PRAGMA integrity_check and returns the problems it reports
+func integrityCheck(db *sql.DB) error {
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// CheckIntegrity verifies the database file is not corrupted
+func (s *SQLiteSessionStore) CheckIntegrity() error {
+	return integrityCheck(s.db)
+}
+
+// Backup writes a consistent copy of the database to path
+func (s *SQLiteSessionStore) Backup(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s already exists", path)
+	}
+	if _, err := s.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	return nil
+}
+
+// RotateBackups takes today's backup if there is none yet and deletes all but
+// the newest retain backups. It returns the path of the new backup, or "" when
+// today's already existed.
+func (s *SQLiteSessionStore) RotateBackups(retain int) (string, error) {
+	if retain <= 0 {
+		return "", nil
+	}
+
+	dir := filepath.Join(s.dataDir, backupDir)
+	path := filepath.Join(dir, backupPrefix+time.Now().Format(backupDateFormat)+".db")
+
+	created := ""
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := s.Backup(path); err != nil {
+			return "", err
+		}
+		created = path
+	}
+
+	backups, err := ListBackups(s.dataDir)
+	if err != nil {
+		return created, err
+	}
+	for len(backups) > retain {
+		if err := os.Remove(backups[0]); err != nil {
+			return created, fmt.Errorf("failed to remove old backup: %v", err)
+		}
+		backups = backups[1:]
+	}
+
+	return created, nil
+}
+
+// Vacuum rebuilds the database file, reclaiming space left by deleted rows
+func (s *SQLiteSessionStore) Vacuum() error {
+	_, err := s.db.Exec(`VACUUM`)
+	return err
+}
+
+// ListBackups returns the rolling backups in dataDir, oldest first
+func ListBackups(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir, backupDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), backupPrefix) && strings.HasSuffix(entry.Name(), ".db") {
+			backups = append(backups, filepath.Join(dataDir, backupDir, entry.Name()))
+		}
+	}
+	// Dated names sort chronologically
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// RestoreBackup replaces the database in dataDir with a backup, after checking
+// the backup's integrity. The current database is kept next to it with a
+// .replaced-<time> suffix. No store may have the database open.
+func RestoreBackup(dataDir, backupPath string) error {
+	backup, err := openSQLite(backupPath, true)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	err = integrityCheck(backup)
+	backup.Close()
+	if err != nil {
+		return fmt.Errorf("backup %s is not usable: %v", backupPath, err)
+	}
+
+	dbPath := filepath.Join(dataDir, dbFileName)
+	if _, err := os.Stat(dbPath); err == nil {
+		aside := fmt.Sprintf("%s.replaced-%s", dbPath, time.Now().Format("20060102-150405"))
+		if err := os.Rename(dbPath, aside); err != nil {
+			return fmt.Errorf("failed to move current database aside: %v", err)
+		}
+	}
+	// Stale WAL files belong to the replaced database
+	os.Remove(dbPath + "-wal")
+	os.Remove(dbPath + "-shm")
+
+	return copyFile(backupPath, dbPath)
+}
+
+// restoreLatestBackup restores the newest backup that passes an integrity check
+func restoreLatestBackup(dataDir string) (string, error) {
+	backups, err := ListBackups(dataDir)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		if err := RestoreBackup(dataDir, backups[i]); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		return backups[i], nil
+	}
+	return "", fmt.Errorf("no usable backup in %s", filepath.Join(dataDir, backupDir))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	return out.Close()
+}
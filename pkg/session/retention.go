@@ -0,0 +1,116 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+var _ SessionPruner = (*SQLiteSessionStore)(nil)
+
+// expiredSession is a cleanup candidate with the outcome of its latest run
+type expiredSession struct {
+	rowID   int64
+	session CompletedSession
+	failed  bool
+}
+
+// CleanupOldSessions removes sessions whose last activity (completion, last
+// seen comment or latest run) is older than the policy allows for the outcome
+// of their latest run, unless keep says otherwise
+func (s *SQLiteSessionStore) CleanupOldSessions(policy RetentionPolicy, keep func(*CompletedSession) bool) (int, error) {
+	shortest := policy.Succeeded
+	if shortest <= 0 || (policy.Failed > 0 && policy.Failed < shortest) {
+		shortest = policy.Failed
+	}
+	if shortest <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	candidates, err := s.sessionsBefore(now.Add(-shortest))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, candidate := range candidates {
+		retain := policy.Succeeded
+		if candidate.failed {
+			retain = policy.Failed
+		}
+		if retain <= 0 || now.Sub(lastActivity(&candidate.session)) < retain {
+			continue
+		}
+		if keep != nil && keep(&candidate.session) {
+			continue
+		}
+
+		if _, err := s.stmt.removeSessionRow.Exec(candidate.rowID); err != nil {
+			return removed, fmt.Errorf("failed to remove session for issue %d: %v", candidate.session.IssueIID, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// sessionsBefore returns the sessions completed before cutoff along with the
+// outcome of their latest run; CompletionTime is set to the latest activity
+func (s *SQLiteSessionStore) sessionsBefore(cutoff time.Time) ([]expiredSession, error) {
+	rows, err := s.stmt.listSessionsBefore.Query(cutoff.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query old sessions: %v", err)
+	}
+
+	var candidates []expiredSession
+	for rows.Next() {
+		var candidate expiredSession
+		var completionTime int64
+		var lastCommentTime sql.NullInt64
+		if err := rows.Scan(&candidate.rowID, &candidate.session.IssueIID, &candidate.session.SessionID,
+			&candidate.session.ProjectPath, &completionTime, &lastCommentTime); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan old session: %v", err)
+		}
+		candidate.session.CompletionTime = time.Unix(completionTime, 0)
+		if lastCommentTime.Valid {
+			t := time.Unix(lastCommentTime.Int64, 0)
+			candidate.session.LastCommentTime = &t
+		}
+		candidates = append(candidates, candidate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Look outcomes up after the scan; the store may run on a single connection
+	for i := range candidates {
+		session := &candidates[i].session
+		var kind string
+		var at int64
+		err := s.stmt.lastOutcome.QueryRow(session.ProjectPath, session.IssueIID,
+			EventCompleted, EventFailed, EventResumeCompleted, EventResumeFailed).Scan(&kind, &at)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up outcome of issue %d: %v", session.IssueIID, err)
+		}
+		candidates[i].failed = kind == EventFailed || kind == EventResumeFailed
+		if outcomeTime := time.Unix(at, 0); outcomeTime.After(session.CompletionTime) {
+			session.CompletionTime = outcomeTime
+		}
+	}
+
+	return candidates, nil
+}
+
+// lastActivity is the later of a session's completion and its last seen comment
+func lastActivity(session *CompletedSession) time.Time {
+	if session.LastCommentTime != nil && session.LastCommentTime.After(session.CompletionTime) {
+		return *session.LastCommentTime
+	}
+	return session.CompletionTime
+}
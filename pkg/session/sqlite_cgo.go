@@ -0,0 +1,23 @@
+//go:build !purego
+
+package session
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver names the SQLite driver compiled in. The default build uses
+// mattn/go-sqlite3, which needs CGO; build with -tags purego for a driver
+// that does not.
+const SQLiteDriver = "mattn/go-sqlite3 (cgo)"
+
+// openSQLite opens the database at path with a busy timeout and WAL
+// journaling, or read-only without either
+func openSQLite(path string, readOnly bool) (*sql.DB, error) {
+	if readOnly {
+		return sql.Open("sqlite3", path+"?mode=ro")
+	}
+	return sql.Open("sqlite3", path+"?_timeout=5000&_journal_mode=WAL")
+}
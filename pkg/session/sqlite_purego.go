@@ -0,0 +1,22 @@
+//go:build purego
+
+package session
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDriver names the SQLite driver compiled in. The purego build uses
+// modernc.org/sqlite, so it cross-compiles with CGO_ENABLED=0.
+const SQLiteDriver = "modernc.org/sqlite (pure Go)"
+
+// openSQLite opens the database at path with a busy timeout and WAL
+// journaling, or read-only without either
+func openSQLite(path string, readOnly bool) (*sql.DB, error) {
+	if readOnly {
+		return sql.Open("sqlite", "file:"+path+"?mode=ro")
+	}
+	return sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+}
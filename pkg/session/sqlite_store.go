@@ -8,22 +8,39 @@ import (
 	"path/filepath"
 	"regexp"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // SQLiteSessionStore manages storage of completed sessions using SQLite
 type SQLiteSessionStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dataDir string
+	stmt    statements
+	project string // Scopes issue-keyed lookups, "" matches every project
 }
 
 // Ensure SQLiteSessionStore implements the Store interface
 var _ Store = (*SQLiteSessionStore)(nil)
-
-// NewSQLiteSessionStore creates a new SQLite-based session store
+var _ ReviewTracker = (*SQLiteSessionStore)(nil)
+var _ EventLog = (*SQLiteSessionStore)(nil)
+var _ EventFeed = (*SQLiteSessionStore)(nil)
+var _ WebhookQueue = (*SQLiteSessionStore)(nil)
+var _ BackfillQueue = (*SQLiteSessionStore)(nil)
+var _ BackupRotator = (*SQLiteSessionStore)(nil)
+var _ ProjectScoped = (*SQLiteSessionStore)(nil)
+var _ CommentTracker = (*SQLiteSessionStore)(nil)
+var _ KnowledgeBase = (*SQLiteSessionStore)(nil)
+var _ PauseTracker = (*SQLiteSessionStore)(nil)
+var _ DescriptionTracker = (*SQLiteSessionStore)(nil)
+var _ LinkTracker = (*SQLiteSessionStore)(nil)
+var _ FailureBundles = (*SQLiteSessionStore)(nil)
+var _ RunHistory = (*SQLiteSessionStore)(nil)
+var _ TranscriptIndex = (*SQLiteSessionStore)(nil)
+
+// NewSQLiteSessionStore creates a new SQLite-based session store. A database
+// that fails its integrity check is replaced by the newest good backup.
 func NewSQLiteSessionStore(dataDir string) (*SQLiteSessionStore, error) {
 	if dataDir == "" {
-		dataDir = filepath.Join(os.Getenv("HOME"), ".automagic")
+		dataDir = DefaultDataDir()
 	}
 
 	// Ensure directory exists
@@ -31,35 +48,56 @@ func NewSQLiteSessionStore(dataDir string) (*SQLiteSessionStore, error) {
 		return nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 
-	dbPath := filepath.Join(dataDir, "sessions.db")
-	db, err := sql.Open("sqlite3", dbPath+"?_timeout=5000&_journal_mode=WAL")
+	dbPath := filepath.Join(dataDir, dbFileName)
+	db, err := openDatabase(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %v", err)
+		return nil, err
 	}
 
-	store := &SQLiteSessionStore{db: db}
+	if err := integrityCheck(db); err != nil {
+		db.Close()
+		fmt.Printf("ERROR: %s is corrupted (%v)\n", dbPath, err)
+
+		restored, restoreErr := restoreLatestBackup(dataDir)
+		if restoreErr != nil {
+			return nil, fmt.Errorf("%s is corrupted and could not be restored (%v); "+
+				"restore a copy with 'automagic -db restore -db-file <backup>'", dbPath, restoreErr)
+		}
+		fmt.Printf("Restored %s from backup %s; sessions completed since that backup cannot be resumed\n", dbPath, restored)
+
+		if db, err = openDatabase(dbPath); err != nil {
+			return nil, err
+		}
+	}
+
+	store := &SQLiteSessionStore{db: db, dataDir: dataDir}
 	if err := store.createTables(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create tables: %v", err)
 	}
+	if err := store.sealExisting(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := store.prepare(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to prepare statements: %v", err)
+	}
 
 	return store, nil
 }
 
+func openDatabase(dbPath string) (*sql.DB, error) {
+	db, err := openSQLite(dbPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	return db, nil
+}
+
 // createTables creates the necessary tables
 func (s *SQLiteSessionStore) createTables() error {
-	// First create the table with original schema if it doesn't exist
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS completed_sessions (
-		issue_iid INTEGER PRIMARY KEY,
-		session_id TEXT NOT NULL,
-		project_path TEXT NOT NULL,
-		completion_time INTEGER NOT NULL,
-		last_comment_time INTEGER
-	);
-	`
-
-	if _, err := s.db.Exec(createTableQuery); err != nil {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS completed_sessions ` + completedSessionsSchema); err != nil {
 		return err
 	}
 
@@ -69,6 +107,10 @@ func (s *SQLiteSessionStore) createTables() error {
 		`ALTER TABLE completed_sessions ADD COLUMN claude_command TEXT`,
 		`ALTER TABLE completed_sessions ADD COLUMN claude_flags TEXT`,
 		`ALTER TABLE completed_sessions ADD COLUMN env_vars TEXT`,
+		`ALTER TABLE completed_sessions ADD COLUMN last_note_id INTEGER`,
+		`ALTER TABLE completed_sessions ADD COLUMN fork_path TEXT`,
+		`ALTER TABLE completed_sessions ADD COLUMN branch TEXT`,
+		`ALTER TABLE completed_sessions ADD COLUMN previous_session_id TEXT`,
 	}
 
 	for _, query := range migrationQueries {
@@ -76,12 +118,293 @@ func (s *SQLiteSessionStore) createTables() error {
 		s.db.Exec(query)
 	}
 
-	// Create index
-	indexQuery := `CREATE INDEX IF NOT EXISTS idx_completion_time ON completed_sessions(completion_time);`
+	if err := s.migrateSessionKey(); err != nil {
+		return fmt.Errorf("failed to re-key completed_sessions: %v", err)
+	}
+
+	// Track the last reviewed head commit per merge request
+	reviewsQuery := `
+	CREATE TABLE IF NOT EXISTS reviewed_merge_requests (
+		project_id INTEGER NOT NULL,
+		mr_iid INTEGER NOT NULL,
+		sha TEXT NOT NULL,
+		reviewed_at INTEGER NOT NULL,
+		PRIMARY KEY (project_id, mr_iid)
+	);
+	`
+	if _, err := s.db.Exec(reviewsQuery); err != nil {
+		return err
+	}
+
+	// Audit log of issue lifecycle events
+	eventsQuery := `
+	CREATE TABLE IF NOT EXISTS issue_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		issue_iid INTEGER NOT NULL,
+		project_path TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		session_id TEXT,
+		detail TEXT,
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_issue_events_issue ON issue_events(issue_iid, created_at);
+	CREATE INDEX IF NOT EXISTS idx_issue_events_project ON issue_events(project_path, issue_iid);
+	CREATE INDEX IF NOT EXISTS idx_issue_events_session ON issue_events(session_id);
+	`
+	if _, err := s.db.Exec(eventsQuery); err != nil {
+		return err
+	}
+
+	// Webhook delivery IDs, used to drop retried deliveries
+	deliveriesQuery := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		delivery_id TEXT PRIMARY KEY,
+		received_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_received ON webhook_deliveries(received_at);
+	`
+	if _, err := s.db.Exec(deliveriesQuery); err != nil {
+		return err
+	}
+
+	// Webhook deliveries awaiting handling, plus small bits of daemon state
+	queueQuery := `
+	CREATE TABLE IF NOT EXISTS webhook_events (
+		delivery_id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		payload BLOB,
+		received_at INTEGER NOT NULL,
+		handled_at INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS daemon_state (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	if _, err := s.db.Exec(queueQuery); err != nil {
+		return err
+	}
+
+	// Historical issues waiting to be released to the daemon
+	backfillQuery := `
+	CREATE TABLE IF NOT EXISTS backfill_queue (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		release_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(backfillQuery); err != nil {
+		return err
+	}
+
+	// What each completed session changed, kept after the session record
+	// itself expires; labels and files are JSON arrays
+	summariesQuery := `
+	CREATE TABLE IF NOT EXISTS session_summaries (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		labels TEXT,
+		files TEXT,
+		mr_iid INTEGER,
+		completed_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(summariesQuery); err != nil {
+		return err
+	}
+
+	// Issue descriptions as the issue's session last saw them
+	descriptionsQuery := `
+	CREATE TABLE IF NOT EXISTS issue_descriptions (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		description TEXT NOT NULL,
+		recorded_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(descriptionsQuery); err != nil {
+		return err
+	}
+
+	// Branch and merge request of each issue
+	linksQuery := `
+	CREATE TABLE IF NOT EXISTS issue_links (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		branch TEXT NOT NULL,
+		fork_path TEXT NOT NULL DEFAULT '',
+		mr_iid INTEGER NOT NULL DEFAULT 0,
+		mr_url TEXT NOT NULL DEFAULT '',
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(linksQuery); err != nil {
+		return err
+	}
+	// Fail if the columns already exist, which is expected
+	s.db.Exec(`ALTER TABLE issue_links ADD COLUMN mr_state TEXT NOT NULL DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE issue_links ADD COLUMN pipeline_status TEXT NOT NULL DEFAULT ''`)
+
+	// Output and worktree state of each issue's latest failed session
+	bundlesQuery := `
+	CREATE TABLE IF NOT EXISTS failure_bundles (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		session_id TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		snippet_url TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(bundlesQuery); err != nil {
+		return err
+	}
+
+	// Every session run with its cost, for reports; kept when sessions expire
+	runsQuery := `
+	CREATE TABLE IF NOT EXISTS session_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		session_id TEXT NOT NULL DEFAULT '',
+		kind TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		failure TEXT NOT NULL DEFAULT '',
+		model TEXT NOT NULL DEFAULT '',
+		started_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		cost_usd REAL NOT NULL DEFAULT 0,
+		turns INTEGER NOT NULL DEFAULT 0,
+		retries INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_session_runs_started ON session_runs(started_at);
+	`
+	if _, err := s.db.Exec(runsQuery); err != nil {
+		return err
+	}
+	// Fail if the columns already exist, which is expected
+	s.db.Exec(`ALTER TABLE session_runs ADD COLUMN input_tokens INTEGER NOT NULL DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE session_runs ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE session_runs ADD COLUMN cache_read_tokens INTEGER NOT NULL DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE session_runs ADD COLUMN cache_creation_tokens INTEGER NOT NULL DEFAULT 0`)
+
+	// Transcript files of session runs, for auditing what a session did
+	transcriptsQuery := `
+	CREATE TABLE IF NOT EXISTS transcripts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		session_id TEXT NOT NULL DEFAULT '',
+		kind TEXT NOT NULL,
+		path TEXT NOT NULL,
+		started_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_transcripts_issue ON transcripts(project_path, issue_iid);
+	`
+	if _, err := s.db.Exec(transcriptsQuery); err != nil {
+		return err
+	}
+
+	// ID of the last issue note the daemon acted on. Note IDs replace the
+	// creation timestamps kept in processed_comments by earlier versions.
+	commentsQuery := `
+	CREATE TABLE IF NOT EXISTS processed_notes (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		note_id INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	DROP TABLE IF EXISTS processed_comments;
+	`
+	if _, err := s.db.Exec(commentsQuery); err != nil {
+		return err
+	}
+
+	// Issues the daemon started on while they carry the trigger label
+	processedQuery := `
+	CREATE TABLE IF NOT EXISTS processed_issues (
+		project_path TEXT NOT NULL,
+		issue_iid INTEGER NOT NULL,
+		processed_at INTEGER NOT NULL,
+		PRIMARY KEY (project_path, issue_iid)
+	);
+	`
+	if _, err := s.db.Exec(processedQuery); err != nil {
+		return err
+	}
+
+	// The primary key covers lookups by project_path; unscoped lookups go by
+	// issue_iid, and failure and retention checks by session_id
+	indexQuery := `
+	CREATE INDEX IF NOT EXISTS idx_completion_time ON completed_sessions(completion_time);
+	CREATE INDEX IF NOT EXISTS idx_completed_sessions_issue ON completed_sessions(issue_iid);
+	CREATE INDEX IF NOT EXISTS idx_completed_sessions_session ON completed_sessions(session_id);
+	`
 	_, err := s.db.Exec(indexQuery)
 	return err
 }
 
+// completedSessionsSchema keys sessions by project and issue, since issue IIDs
+// are only unique within a project
+const completedSessionsSchema = `(
+	issue_iid INTEGER NOT NULL,
+	session_id TEXT NOT NULL,
+	project_path TEXT NOT NULL,
+	completion_time INTEGER NOT NULL,
+	last_comment_time INTEGER,
+	last_note_id INTEGER,
+	working_dir TEXT,
+	claude_command TEXT,
+	claude_flags TEXT,
+	env_vars TEXT,
+	fork_path TEXT,
+	branch TEXT,
+	previous_session_id TEXT,
+	PRIMARY KEY (project_path, issue_iid)
+)`
+
+// migrateSessionKey rebuilds a completed_sessions table keyed by issue_iid
+// alone, as created by earlier versions, with the (project_path, issue_iid) key
+func (s *SQLiteSessionStore) migrateSessionKey() error {
+	var keyColumns int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('completed_sessions') WHERE pk > 0`).Scan(&keyColumns); err != nil {
+		return err
+	}
+	if keyColumns != 1 {
+		return nil
+	}
+
+	const columns = `issue_iid, session_id, project_path, completion_time, last_comment_time, last_note_id, working_dir, claude_command, claude_flags, env_vars, fork_path, branch, previous_session_id`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`CREATE TABLE completed_sessions_new ` + completedSessionsSchema,
+		`INSERT INTO completed_sessions_new (` + columns + `) SELECT ` + columns + ` FROM completed_sessions`,
+		`DROP TABLE completed_sessions`,
+		`ALTER TABLE completed_sessions_new RENAME TO completed_sessions`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetProject limits issue-keyed lookups (sessions and events) to one project.
+// It must be called before the store is shared between goroutines.
+func (s *SQLiteSessionStore) SetProject(projectPath string) {
+	s.project = projectPath
+}
+
 // AddCompletedSession stores information about a completed session
 func (s *SQLiteSessionStore) AddCompletedSession(issueIID int, sessionID, projectPath string, completionTime time.Time, workingDir, claudeCommand, claudeFlags string, envVars map[string]string) error {
 	// Convert envVars map to JSON string for storage
@@ -92,21 +415,14 @@ func (s *SQLiteSessionStore) AddCompletedSession(issueIID int, sessionID, projec
 		}
 	}
 
-	query := `
-	INSERT OR REPLACE INTO completed_sessions 
-	(issue_iid, session_id, project_path, completion_time, last_comment_time, working_dir, claude_command, claude_flags, env_vars)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err := s.db.Exec(query, issueIID, sessionID, projectPath, completionTime.Unix(), nil, workingDir, claudeCommand, claudeFlags, envVarsJSON)
+	_, err := s.stmt.addSession.Exec(issueIID, sessionID, projectPath, completionTime.Unix(), nil, nil,
+		seal(workingDir), seal(claudeCommand), seal(claudeFlags), seal(envVarsJSON), nil, nil, nil)
 	return err
 }
 
 // UpdateLastCommentTime updates the last seen comment time for an issue
 func (s *SQLiteSessionStore) UpdateLastCommentTime(issueIID int, commentTime time.Time) error {
-	query := `UPDATE completed_sessions SET last_comment_time = ? WHERE issue_iid = ?`
-
-	result, err := s.db.Exec(query, commentTime.Unix(), issueIID)
+	result, err := s.stmt.updateLastComment.Exec(commentTime.Unix(), issueIID, s.project, s.project)
 	if err != nil {
 		return err
 	}
@@ -123,21 +439,75 @@ func (s *SQLiteSessionStore) UpdateLastCommentTime(issueIID int, commentTime tim
 	return nil
 }
 
+// UpdateLastNote records the newest note seen on an issue and its merge
+// requests, and when it was written if known
+func (s *SQLiteSessionStore) UpdateLastNote(issueIID, noteID int, commentTime time.Time) error {
+	var commentTimeUnix interface{}
+	if !commentTime.IsZero() {
+		commentTimeUnix = commentTime.Unix()
+	}
+
+	result, err := s.stmt.updateLastNote.Exec(noteID, commentTimeUnix, issueIID, s.project, s.project)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	return nil
+}
+
+// UpdateForkPath records the fork an issue's branch was pushed to
+func (s *SQLiteSessionStore) UpdateForkPath(issueIID int, forkPath string) error {
+	result, err := s.stmt.updateForkPath.Exec(forkPath, issueIID, s.project, s.project)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	return nil
+}
+
+// UpdateBranch records the branch an issue session pushed to and the session
+// of the attempt before it
+func (s *SQLiteSessionStore) UpdateBranch(issueIID int, branch, previousSessionID string) error {
+	result, err := s.stmt.updateBranch.Exec(branch, previousSessionID, issueIID, s.project, s.project)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	return nil
+}
+
 // GetCompletedSession retrieves session information for an issue
 func (s *SQLiteSessionStore) GetCompletedSession(issueIID int) (*CompletedSession, bool) {
-	query := `
-	SELECT issue_iid, session_id, project_path, completion_time, last_comment_time, 
-	       working_dir, claude_command, claude_flags, env_vars
-	FROM completed_sessions 
-	WHERE issue_iid = ?
-	`
+	session, err := scanSession(s.stmt.getSession.QueryRow(issueIID, s.project, s.project))
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		fmt.Printf("Error querying session for issue %d: %v\n", issueIID, err)
+		return nil, false
+	}
 
-	row := s.db.QueryRow(query, issueIID)
+	return session, true
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanSession reads a row of sessionColumns, decrypting the environment
+// columns when the store is encrypted
+func scanSession(row rowScanner) (*CompletedSession, error) {
 	var session CompletedSession
 	var completionTimeUnix int64
-	var lastCommentTimeUnix sql.NullInt64
-	var workingDir, claudeCommand, claudeFlags, envVarsJSON sql.NullString
+	var lastCommentTimeUnix, lastNoteID sql.NullInt64
+	var workingDir, claudeCommand, claudeFlags, envVarsJSON, forkPath, branch, previousSessionID sql.NullString
 
 	err := row.Scan(
 		&session.IssueIID,
@@ -145,18 +515,17 @@ func (s *SQLiteSessionStore) GetCompletedSession(issueIID int) (*CompletedSessio
 		&session.ProjectPath,
 		&completionTimeUnix,
 		&lastCommentTimeUnix,
+		&lastNoteID,
 		&workingDir,
 		&claudeCommand,
 		&claudeFlags,
 		&envVarsJSON,
+		&forkPath,
+		&branch,
+		&previousSessionID,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, false
-	}
 	if err != nil {
-		fmt.Printf("Error querying session for issue %d: %v\n", issueIID, err)
-		return nil, false
+		return nil, err
 	}
 
 	session.CompletionTime = time.Unix(completionTimeUnix, 0)
@@ -164,37 +533,27 @@ func (s *SQLiteSessionStore) GetCompletedSession(issueIID int) (*CompletedSessio
 		t := time.Unix(lastCommentTimeUnix.Int64, 0)
 		session.LastCommentTime = &t
 	}
-
-	// Set environment context fields
-	if workingDir.Valid {
-		session.WorkingDir = workingDir.String
-	}
-	if claudeCommand.Valid {
-		session.ClaudeCommand = claudeCommand.String
-	}
-	if claudeFlags.Valid {
-		session.ClaudeFlags = claudeFlags.String
-	}
-	if envVarsJSON.Valid && envVarsJSON.String != "" {
-		var envVars map[string]string
-		if err := json.Unmarshal([]byte(envVarsJSON.String), &envVars); err == nil {
-			session.EnvVars = envVars
+	session.LastNoteID = int(lastNoteID.Int64)
+	session.ForkPath = forkPath.String
+	session.Branch = branch.String
+	session.PreviousSessionID = previousSessionID.String
+
+	session.WorkingDir = mustUnseal(workingDir.String)
+	session.ClaudeCommand = mustUnseal(claudeCommand.String)
+	session.ClaudeFlags = mustUnseal(claudeFlags.String)
+	if envVars := mustUnseal(envVarsJSON.String); envVars != "" {
+		var parsed map[string]string
+		if err := json.Unmarshal([]byte(envVars), &parsed); err == nil {
+			session.EnvVars = parsed
 		}
 	}
 
-	return &session, true
+	return &session, nil
 }
 
 // GetCompletedSessions returns all completed sessions
 func (s *SQLiteSessionStore) GetCompletedSessions() []*CompletedSession {
-	query := `
-	SELECT issue_iid, session_id, project_path, completion_time, last_comment_time,
-	       working_dir, claude_command, claude_flags, env_vars
-	FROM completed_sessions 
-	ORDER BY completion_time DESC
-	`
-
-	rows, err := s.db.Query(query)
+	rows, err := s.stmt.listSessions.Query(s.project, s.project)
 	if err != nil {
 		fmt.Printf("Error querying all sessions: %v\n", err)
 		return nil
@@ -203,51 +562,12 @@ func (s *SQLiteSessionStore) GetCompletedSessions() []*CompletedSession {
 
 	var sessions []*CompletedSession
 	for rows.Next() {
-		var session CompletedSession
-		var completionTimeUnix int64
-		var lastCommentTimeUnix sql.NullInt64
-		var workingDir, claudeCommand, claudeFlags, envVarsJSON sql.NullString
-
-		err := rows.Scan(
-			&session.IssueIID,
-			&session.SessionID,
-			&session.ProjectPath,
-			&completionTimeUnix,
-			&lastCommentTimeUnix,
-			&workingDir,
-			&claudeCommand,
-			&claudeFlags,
-			&envVarsJSON,
-		)
+		session, err := scanSession(rows)
 		if err != nil {
 			fmt.Printf("Error scanning session row: %v\n", err)
 			continue
 		}
-
-		session.CompletionTime = time.Unix(completionTimeUnix, 0)
-		if lastCommentTimeUnix.Valid {
-			t := time.Unix(lastCommentTimeUnix.Int64, 0)
-			session.LastCommentTime = &t
-		}
-
-		// Set environment context fields
-		if workingDir.Valid {
-			session.WorkingDir = workingDir.String
-		}
-		if claudeCommand.Valid {
-			session.ClaudeCommand = claudeCommand.String
-		}
-		if claudeFlags.Valid {
-			session.ClaudeFlags = claudeFlags.String
-		}
-		if envVarsJSON.Valid && envVarsJSON.String != "" {
-			var envVars map[string]string
-			if err := json.Unmarshal([]byte(envVarsJSON.String), &envVars); err == nil {
-				session.EnvVars = envVars
-			}
-		}
-
-		sessions = append(sessions, &session)
+		sessions = append(sessions, session)
 	}
 
 	return sessions
@@ -257,15 +577,7 @@ func (s *SQLiteSessionStore) GetCompletedSessions() []*CompletedSession {
 func (s *SQLiteSessionStore) GetRecentlyCompletedSessions(since time.Duration) []*CompletedSession {
 	cutoff := time.Now().Add(-since).Unix()
 
-	query := `
-	SELECT issue_iid, session_id, project_path, completion_time, last_comment_time,
-	       working_dir, claude_command, claude_flags, env_vars
-	FROM completed_sessions 
-	WHERE completion_time > ?
-	ORDER BY completion_time DESC
-	`
-
-	rows, err := s.db.Query(query, cutoff)
+	rows, err := s.stmt.listRecentSessions.Query(cutoff, s.project, s.project)
 	if err != nil {
 		fmt.Printf("Error querying recent sessions: %v\n", err)
 		return nil
@@ -274,51 +586,12 @@ func (s *SQLiteSessionStore) GetRecentlyCompletedSessions(since time.Duration) [
 
 	var sessions []*CompletedSession
 	for rows.Next() {
-		var session CompletedSession
-		var completionTimeUnix int64
-		var lastCommentTimeUnix sql.NullInt64
-		var workingDir, claudeCommand, claudeFlags, envVarsJSON sql.NullString
-
-		err := rows.Scan(
-			&session.IssueIID,
-			&session.SessionID,
-			&session.ProjectPath,
-			&completionTimeUnix,
-			&lastCommentTimeUnix,
-			&workingDir,
-			&claudeCommand,
-			&claudeFlags,
-			&envVarsJSON,
-		)
+		session, err := scanSession(rows)
 		if err != nil {
 			fmt.Printf("Error scanning recent session row: %v\n", err)
 			continue
 		}
-
-		session.CompletionTime = time.Unix(completionTimeUnix, 0)
-		if lastCommentTimeUnix.Valid {
-			t := time.Unix(lastCommentTimeUnix.Int64, 0)
-			session.LastCommentTime = &t
-		}
-
-		// Set environment context fields
-		if workingDir.Valid {
-			session.WorkingDir = workingDir.String
-		}
-		if claudeCommand.Valid {
-			session.ClaudeCommand = claudeCommand.String
-		}
-		if claudeFlags.Valid {
-			session.ClaudeFlags = claudeFlags.String
-		}
-		if envVarsJSON.Valid && envVarsJSON.String != "" {
-			var envVars map[string]string
-			if err := json.Unmarshal([]byte(envVarsJSON.String), &envVars); err == nil {
-				session.EnvVars = envVars
-			}
-		}
-
-		sessions = append(sessions, &session)
+		sessions = append(sessions, session)
 	}
 
 	return sessions
@@ -326,78 +599,575 @@ func (s *SQLiteSessionStore) GetRecentlyCompletedSessions(since time.Duration) [
 
 // RemoveSession removes a session from the store
 func (s *SQLiteSessionStore) RemoveSession(issueIID int) error {
-	query := `DELETE FROM completed_sessions WHERE issue_iid = ?`
-	_, err := s.db.Exec(query, issueIID)
+	_, err := s.stmt.removeSession.Exec(issueIID, s.project, s.project)
 	return err
 }
 
 // CleanupInvalidSessions removes sessions with invalid (non-UUID) session IDs
 func (s *SQLiteSessionStore) CleanupInvalidSessions() error {
 	// Get all sessions and check them in Go code since SQLite REGEXP might not be available
-	query := `SELECT issue_iid, session_id FROM completed_sessions`
-
-	rows, err := s.db.Query(query)
+	rows, err := s.stmt.listSessionIDs.Query()
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	var invalidIssueIDs []int
+	type invalidSession struct {
+		rowID    int64
+		issueIID int
+	}
+	var invalid []invalidSession
 	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
 	for rows.Next() {
-		var issueIID int
+		var session invalidSession
 		var sessionID string
 
-		if err := rows.Scan(&issueIID, &sessionID); err != nil {
+		if err := rows.Scan(&session.rowID, &session.issueIID, &sessionID); err != nil {
 			continue
 		}
 
 		if !uuidPattern.MatchString(sessionID) {
-			invalidIssueIDs = append(invalidIssueIDs, issueIID)
+			invalid = append(invalid, session)
 		}
 	}
+	rows.Close()
 
 	// Delete invalid sessions
-	for _, issueIID := range invalidIssueIDs {
-		deleteQuery := `DELETE FROM completed_sessions WHERE issue_iid = ?`
-		if _, err := s.db.Exec(deleteQuery, issueIID); err != nil {
-			fmt.Printf("Warning: Failed to delete invalid session for issue %d: %v\n", issueIID, err)
+	for _, session := range invalid {
+		if _, err := s.stmt.removeSessionRow.Exec(session.rowID); err != nil {
+			fmt.Printf("Warning: Failed to delete invalid session for issue %d: %v\n", session.issueIID, err)
 		}
 	}
 
-	if len(invalidIssueIDs) > 0 {
-		fmt.Printf("Cleaned up %d sessions with invalid session IDs\n", len(invalidIssueIDs))
+	if len(invalid) > 0 {
+		fmt.Printf("Cleaned up %d sessions with invalid session IDs\n", len(invalid))
 	}
 
 	return nil
 }
 
-// CleanupOldSessions removes sessions older than the specified duration
-func (s *SQLiteSessionStore) CleanupOldSessions(maxAge time.Duration) error {
-	cutoff := time.Now().Add(-maxAge).Unix()
+// GetReviewedSHA returns the last reviewed head commit for a merge request
+func (s *SQLiteSessionStore) GetReviewedSHA(projectID, mrIID int) (string, bool) {
+	var sha string
+	err := s.stmt.getReviewedSHA.QueryRow(projectID, mrIID).Scan(&sha)
+	if err == sql.ErrNoRows {
+		return "", false
+	}
+	if err != nil {
+		fmt.Printf("Error querying reviewed SHA for MR !%d: %v\n", mrIID, err)
+		return "", false
+	}
+
+	return sha, true
+}
+
+// SetReviewedSHA records the head commit a merge request was last reviewed at
+func (s *SQLiteSessionStore) SetReviewedSHA(projectID, mrIID int, sha string) error {
+	_, err := s.stmt.setReviewedSHA.Exec(projectID, mrIID, sha, time.Now().Unix())
+	return err
+}
+
+// RecordEvent appends an event to the issue's audit log
+func (s *SQLiteSessionStore) RecordEvent(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
 
-	query := `DELETE FROM completed_sessions WHERE completion_time < ?`
+	_, err := s.stmt.recordEvent.Exec(event.IssueIID, event.ProjectPath, event.Kind, event.SessionID, seal(event.Detail), event.Time.Unix())
+	return err
+}
 
-	result, err := s.db.Exec(query, cutoff)
+// GetEvents returns the issue's audit log, oldest first
+func (s *SQLiteSessionStore) GetEvents(issueIID int) ([]Event, error) {
+	rows, err := s.stmt.getEvents.Query(issueIID, s.project, s.project)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to query events: %v", err)
 	}
+	return scanEvents(rows)
+}
 
-	rowsAffected, err := result.RowsAffected()
+// RecentEvents returns up to limit events of the selected project recorded at
+// or after since, newest first
+func (s *SQLiteSessionStore) RecentEvents(since time.Time, limit int) ([]Event, error) {
+	rows, err := s.stmt.recentEvents.Query(since.Unix(), s.project, s.project, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent events: %v", err)
+	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var sessionID, detail sql.NullString
+		var createdAt int64
+
+		if err := rows.Scan(&event.IssueIID, &event.ProjectPath, &event.Kind, &sessionID, &detail, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %v", err)
+		}
+
+		event.SessionID = sessionID.String
+		event.Detail = mustUnseal(detail.String)
+		event.Time = time.Unix(createdAt, 0)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// PausedIssues returns the issues of the selected project whose session is paused
+func (s *SQLiteSessionStore) PausedIssues() ([]int, error) {
+	rows, err := s.stmt.pausedIssues.Query(EventPaused, EventPaused, EventUnpaused, s.project, s.project)
 	if err != nil {
+		return nil, fmt.Errorf("failed to query paused issues: %v", err)
+	}
+	defer rows.Close()
+
+	var issues []int
+	for rows.Next() {
+		var issueIID int
+		if err := rows.Scan(&issueIID); err != nil {
+			return nil, fmt.Errorf("failed to scan paused issue: %v", err)
+		}
+		issues = append(issues, issueIID)
+	}
+	return issues, rows.Err()
+}
+
+// webhookDeliveryRetention is how long delivery IDs are kept for deduplication
+const webhookDeliveryRetention = 7 * 24 * time.Hour
+
+// MarkDelivered records a webhook delivery ID, returning false if it was already recorded
+func (s *SQLiteSessionStore) MarkDelivered(deliveryID string, receivedAt time.Time) (bool, error) {
+	result, err := s.stmt.markDelivered.Exec(deliveryID, receivedAt.Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to record delivery: %v", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// GitLab stops retrying long before the retention window, so old IDs can go
+	cutoff := receivedAt.Add(-webhookDeliveryRetention).Unix()
+	s.stmt.pruneDeliveries.Exec(cutoff)
+
+	return inserted > 0, nil
+}
+
+// EnqueueWebhookEvent stores a delivery until it is marked handled
+func (s *SQLiteSessionStore) EnqueueWebhookEvent(event QueuedWebhookEvent) error {
+	_, err := s.stmt.enqueueWebhook.Exec(event.DeliveryID, event.Kind, seal(string(event.Payload)), event.ReceivedAt.UnixNano())
+	return err
+}
+
+// PendingWebhookEvents returns unhandled deliveries, oldest first
+func (s *SQLiteSessionStore) PendingWebhookEvents() ([]QueuedWebhookEvent, error) {
+	rows, err := s.stmt.pendingWebhooks.Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []QueuedWebhookEvent
+	for rows.Next() {
+		var event QueuedWebhookEvent
+		var receivedAt int64
+		var payload sql.NullString
+		if err := rows.Scan(&event.DeliveryID, &event.Kind, &payload, &receivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %v", err)
+		}
+		plain, err := unseal(payload.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook event %s: %v", event.DeliveryID, err)
+		}
+		event.Payload = []byte(plain)
+		event.ReceivedAt = time.Unix(0, receivedAt)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkWebhookEventsHandled marks deliveries received before the given time as
+// handled and drops handled deliveries older than a day
+func (s *SQLiteSessionStore) MarkWebhookEventsHandled(before time.Time) error {
+	now := time.Now()
+	if _, err := s.stmt.markWebhooksHandled.Exec(now.Unix(), before.UnixNano()); err != nil {
 		return err
 	}
 
-	if rowsAffected > 0 {
-		fmt.Printf("Cleaned up %d old sessions (older than %v)\n", rowsAffected, maxAge)
+	_, err := s.stmt.pruneWebhooks.Exec(now.Add(-24 * time.Hour).Unix())
+	return err
+}
+
+// GetLastSyncTime returns when the daemon last completed a sync with GitLab
+func (s *SQLiteSessionStore) GetLastSyncTime() (time.Time, bool) {
+	var value string
+	err := s.stmt.getState.QueryRow(stateLastSync).Scan(&value)
+	if err != nil {
+		return time.Time{}, false
 	}
 
-	return nil
+	syncTime, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return syncTime, true
+}
+
+// SetLastSyncTime records when the daemon last completed a sync with GitLab
+func (s *SQLiteSessionStore) SetLastSyncTime(syncTime time.Time) error {
+	_, err := s.stmt.setState.Exec(stateLastSync, syncTime.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// EnqueueBackfill queues historical issues, leaving already queued ones untouched
+func (s *SQLiteSessionStore) EnqueueBackfill(entries []BackfillEntry) (int, error) {
+	added := 0
+	for _, entry := range entries {
+		result, err := s.stmt.enqueueBackfill.Exec(entry.ProjectPath, entry.IssueIID, entry.ReleaseAt.Unix())
+		if err != nil {
+			return added, fmt.Errorf("failed to queue issue #%d: %v", entry.IssueIID, err)
+		}
+		if inserted, err := result.RowsAffected(); err == nil && inserted > 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// LastBackfillRelease returns the latest scheduled release time in a project
+func (s *SQLiteSessionStore) LastBackfillRelease(projectPath string) (time.Time, bool) {
+	var releaseAt sql.NullInt64
+	err := s.stmt.lastBackfill.QueryRow(projectPath).Scan(&releaseAt)
+	if err != nil || !releaseAt.Valid {
+		return time.Time{}, false
+	}
+	return time.Unix(releaseAt.Int64, 0), true
+}
+
+// GetBackfill returns the queued issues of a project, earliest release first
+func (s *SQLiteSessionStore) GetBackfill(projectPath string) ([]BackfillEntry, error) {
+	rows, err := s.stmt.getBackfill.Query(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backfill queue: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []BackfillEntry
+	for rows.Next() {
+		entry := BackfillEntry{ProjectPath: projectPath}
+		var releaseAt int64
+		if err := rows.Scan(&entry.IssueIID, &releaseAt); err != nil {
+			return nil, fmt.Errorf("failed to scan backfill entry: %v", err)
+		}
+		entry.ReleaseAt = time.Unix(releaseAt, 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// RemoveBackfill drops an issue from the backfill queue
+func (s *SQLiteSessionStore) RemoveBackfill(projectPath string, issueIID int) error {
+	_, err := s.stmt.removeBackfill.Exec(projectPath, issueIID)
+	return err
+}
+
+// GetProcessedNote returns the ID of the last note processed on an issue
+func (s *SQLiteSessionStore) GetProcessedNote(issueIID int) (int, bool) {
+	var noteID int
+	err := s.stmt.getProcessedNote.QueryRow(issueIID, s.project, s.project).Scan(&noteID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error querying processed note for issue %d: %v\n", issueIID, err)
+		}
+		return 0, false
+	}
+	return noteID, true
+}
+
+// SetProcessedNote records the ID of the last note processed on an issue in
+// the store's project
+func (s *SQLiteSessionStore) SetProcessedNote(issueIID, noteID int) error {
+	_, err := s.stmt.setProcessedNote.Exec(s.project, issueIID, noteID)
+	return err
+}
+
+// ClearProcessedNote forgets the last processed note of an issue
+func (s *SQLiteSessionStore) ClearProcessedNote(issueIID int) error {
+	_, err := s.stmt.clearProcessedNote.Exec(issueIID, s.project, s.project)
+	return err
+}
+
+// GetProcessedIssues returns the issues processed in the store's project
+func (s *SQLiteSessionStore) GetProcessedIssues() (map[int]bool, error) {
+	rows, err := s.stmt.getProcessedIssues.Query(s.project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processed issues: %v", err)
+	}
+	defer rows.Close()
+
+	issues := make(map[int]bool)
+	for rows.Next() {
+		var issueIID int
+		if err := rows.Scan(&issueIID); err != nil {
+			return nil, fmt.Errorf("failed to scan processed issue: %v", err)
+		}
+		issues[issueIID] = true
+	}
+	return issues, rows.Err()
+}
+
+// SetProcessedIssue records that an issue of the store's project was processed
+func (s *SQLiteSessionStore) SetProcessedIssue(issueIID int) error {
+	_, err := s.stmt.setProcessedIssue.Exec(s.project, issueIID, time.Now().Unix())
+	return err
+}
+
+// ClearProcessedIssue forgets that an issue was processed
+func (s *SQLiteSessionStore) ClearProcessedIssue(issueIID int) error {
+	_, err := s.stmt.clearProcessedIssue.Exec(s.project, issueIID)
+	return err
+}
+
+// GetIssueDescription returns the description recorded for an issue in the
+// store's project
+func (s *SQLiteSessionStore) GetIssueDescription(issueIID int) (string, bool) {
+	var description string
+	err := s.stmt.getDescription.QueryRow(s.project, issueIID).Scan(&description)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error querying description of issue %d: %v\n", issueIID, err)
+		}
+		return "", false
+	}
+	return mustUnseal(description), true
+}
+
+// SetIssueDescription records the description of an issue in the store's project
+func (s *SQLiteSessionStore) SetIssueDescription(issueIID int, description string) error {
+	_, err := s.stmt.setDescription.Exec(s.project, issueIID, seal(description), time.Now().Unix())
+	return err
+}
+
+// SetIssueLink stores the branch and merge request of an issue
+func (s *SQLiteSessionStore) SetIssueLink(link IssueLink) error {
+	_, err := s.stmt.setLink.Exec(link.ProjectPath, link.IssueIID, link.Branch, link.ForkPath,
+		link.MergeRequestIID, link.MergeRequestURL, link.MergeRequestState, link.PipelineStatus, link.UpdatedAt.Unix())
+	return err
+}
+
+// GetIssueLink returns the branch and merge request of an issue in the store's project
+func (s *SQLiteSessionStore) GetIssueLink(issueIID int) (*IssueLink, bool) {
+	link := &IssueLink{IssueIID: issueIID}
+	var updatedAt int64
+	err := s.stmt.getLink.QueryRow(issueIID, s.project, s.project).Scan(&link.ProjectPath, &link.Branch,
+		&link.ForkPath, &link.MergeRequestIID, &link.MergeRequestURL, &link.MergeRequestState, &link.PipelineStatus, &updatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error querying link of issue %d: %v\n", issueIID, err)
+		}
+		return nil, false
+	}
+	link.UpdatedAt = time.Unix(updatedAt, 0)
+	return link, true
+}
+
+// OpenIssueLinks returns the links in the store's project whose merge request
+// is not known to be merged or closed, oldest first
+func (s *SQLiteSessionStore) OpenIssueLinks() ([]IssueLink, error) {
+	rows, err := s.stmt.openLinks.Query(s.project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query issue links: %v", err)
+	}
+	defer rows.Close()
+
+	var links []IssueLink
+	for rows.Next() {
+		link := IssueLink{ProjectPath: s.project}
+		var updatedAt int64
+		if err := rows.Scan(&link.IssueIID, &link.Branch, &link.ForkPath, &link.MergeRequestIID,
+			&link.MergeRequestURL, &link.MergeRequestState, &link.PipelineStatus, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan issue link: %v", err)
+		}
+		link.UpdatedAt = time.Unix(updatedAt, 0)
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// SaveFailureBundle stores the failure bundle of an issue's session
+func (s *SQLiteSessionStore) SaveFailureBundle(bundle FailureBundle) error {
+	_, err := s.stmt.saveBundle.Exec(bundle.ProjectPath, bundle.IssueIID, bundle.SessionID,
+		seal(bundle.Content), bundle.SnippetURL, bundle.CreatedAt.Unix())
+	return err
+}
+
+// GetFailureBundle returns the failure bundle of an issue in the store's project
+func (s *SQLiteSessionStore) GetFailureBundle(issueIID int) (*FailureBundle, bool) {
+	bundle := &FailureBundle{IssueIID: issueIID}
+	var content string
+	var createdAt int64
+	err := s.stmt.getBundle.QueryRow(issueIID, s.project, s.project).Scan(&bundle.ProjectPath, &bundle.SessionID,
+		&content, &bundle.SnippetURL, &createdAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error querying failure bundle of issue %d: %v\n", issueIID, err)
+		}
+		return nil, false
+	}
+	bundle.Content = mustUnseal(content)
+	bundle.CreatedAt = time.Unix(createdAt, 0)
+	return bundle, true
+}
+
+// RecordRun appends a session run to the run history
+func (s *SQLiteSessionStore) RecordRun(run SessionRun) error {
+	_, err := s.stmt.recordRun.Exec(run.ProjectPath, run.IssueIID, run.SessionID, run.Kind, run.Outcome,
+		run.Failure, run.Model, run.StartedAt.Unix(), run.Duration.Milliseconds(), run.CostUSD, run.Turns, run.Retries,
+		run.InputTokens, run.OutputTokens, run.CacheReadTokens, run.CacheCreationTokens)
+	return err
+}
+
+// GetRuns returns the session runs of every project started since a time
+func (s *SQLiteSessionStore) GetRuns(since time.Time) ([]SessionRun, error) {
+	rows, err := s.stmt.getRuns.Query(since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session runs: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []SessionRun
+	for rows.Next() {
+		var run SessionRun
+		var startedAt, durationMS int64
+		if err := rows.Scan(&run.ProjectPath, &run.IssueIID, &run.SessionID, &run.Kind, &run.Outcome, &run.Failure,
+			&run.Model, &startedAt, &durationMS, &run.CostUSD, &run.Turns, &run.Retries,
+			&run.InputTokens, &run.OutputTokens, &run.CacheReadTokens, &run.CacheCreationTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan session run: %v", err)
+		}
+		run.StartedAt = time.Unix(startedAt, 0)
+		run.Duration = time.Duration(durationMS) * time.Millisecond
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// AddTranscript records the transcript file of a session run
+func (s *SQLiteSessionStore) AddTranscript(transcript Transcript) error {
+	_, err := s.stmt.addTranscript.Exec(transcript.ProjectPath, transcript.IssueIID, transcript.SessionID,
+		transcript.Kind, transcript.Path, transcript.StartedAt.Unix())
+	return err
+}
+
+// GetTranscripts returns the transcripts of an issue in the store's project
+func (s *SQLiteSessionStore) GetTranscripts(issueIID int) ([]Transcript, error) {
+	rows, err := s.stmt.getTranscripts.Query(issueIID, s.project, s.project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transcripts: %v", err)
+	}
+	defer rows.Close()
+
+	var transcripts []Transcript
+	for rows.Next() {
+		transcript := Transcript{IssueIID: issueIID}
+		var startedAt int64
+		if err := rows.Scan(&transcript.ProjectPath, &transcript.SessionID, &transcript.Kind,
+			&transcript.Path, &startedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transcript: %v", err)
+		}
+		transcript.StartedAt = time.Unix(startedAt, 0)
+		transcripts = append(transcripts, transcript)
+	}
+	return transcripts, rows.Err()
+}
+
+// PruneTranscripts removes the transcripts of every project started before a
+// time from the index and returns them
+func (s *SQLiteSessionStore) PruneTranscripts(before time.Time) ([]Transcript, error) {
+	rows, err := s.stmt.listTranscripts.Query(before.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transcripts: %v", err)
+	}
+	var transcripts []Transcript
+	for rows.Next() {
+		var transcript Transcript
+		var startedAt int64
+		if err := rows.Scan(&transcript.ProjectPath, &transcript.IssueIID, &transcript.SessionID,
+			&transcript.Kind, &transcript.Path, &startedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan transcript: %v", err)
+		}
+		transcript.StartedAt = time.Unix(startedAt, 0)
+		transcripts = append(transcripts, transcript)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.stmt.pruneTranscripts.Exec(before.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to prune transcripts: %v", err)
+	}
+	return transcripts, nil
 }
 
-// Close closes the database connection
+// RecordSummary stores the summary of a completed session, replacing an
+// earlier one of the same issue
+func (s *SQLiteSessionStore) RecordSummary(summary SessionSummary) error {
+	labels, err := json.Marshal(summary.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %v", err)
+	}
+	files, err := json.Marshal(summary.Files)
+	if err != nil {
+		return fmt.Errorf("failed to marshal files: %v", err)
+	}
+	_, err = s.stmt.recordSummary.Exec(summary.ProjectPath, summary.IssueIID, summary.Title,
+		string(labels), string(files), summary.MergeRequestIID, summary.CompletedAt.Unix())
+	return err
+}
+
+// GetSummaries returns the session summaries of a project, newest first
+func (s *SQLiteSessionStore) GetSummaries(projectPath string) ([]SessionSummary, error) {
+	rows, err := s.stmt.getSummaries.Query(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session summaries: %v", err)
+	}
+	defer rows.Close()
+
+	var summaries []SessionSummary
+	for rows.Next() {
+		summary := SessionSummary{ProjectPath: projectPath}
+		var labels, files sql.NullString
+		var mrIID sql.NullInt64
+		var completedAt int64
+		if err := rows.Scan(&summary.IssueIID, &summary.Title, &labels, &files, &mrIID, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session summary: %v", err)
+		}
+		if labels.Valid {
+			json.Unmarshal([]byte(labels.String), &summary.Labels)
+		}
+		if files.Valid {
+			json.Unmarshal([]byte(files.String), &summary.Files)
+		}
+		summary.MergeRequestIID = int(mrIID.Int64)
+		summary.CompletedAt = time.Unix(completedAt, 0)
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// Close closes the prepared statements and the database connection
 func (s *SQLiteSessionStore) Close() error {
+	s.stmt.close()
 	return s.db.Close()
 }
 
@@ -427,6 +1197,18 @@ func (s *SQLiteSessionStore) MigrateFromJSONStore(jsonStore *SessionStore) error
 				return fmt.Errorf("failed to migrate last comment time for session %d: %v", session.IssueIID, err)
 			}
 		}
+
+		if session.ForkPath != "" {
+			if err := s.UpdateForkPath(session.IssueIID, session.ForkPath); err != nil {
+				return fmt.Errorf("failed to migrate fork for session %d: %v", session.IssueIID, err)
+			}
+		}
+
+		if session.Branch != "" || session.PreviousSessionID != "" {
+			if err := s.UpdateBranch(session.IssueIID, session.Branch, session.PreviousSessionID); err != nil {
+				return fmt.Errorf("failed to migrate branch for session %d: %v", session.IssueIID, err)
+			}
+		}
 	}
 
 	return nil
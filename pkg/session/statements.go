@@ -0,0 +1,237 @@
+package session
+
+import "database/sql"
+
+// stateLastSync is the daemon_state key of the last completed GitLab sync
+const stateLastSync = "last_sync_time"
+
+// sessionColumns are the completed_sessions columns read into a CompletedSession
+const sessionColumns = `issue_iid, session_id, project_path, completion_time, last_comment_time, last_note_id,
+	working_dir, claude_command, claude_flags, env_vars, fork_path, branch, previous_session_id`
+
+// projectScope matches every project when the store is unscoped; its two
+// placeholders both take the store's project path
+const projectScope = `(? = '' OR project_path = ?)`
+
+// statements are prepared once when the store opens. The daemon runs most of
+// them on every poll, so with tens of thousands of sessions re-parsing the SQL
+// each time adds up.
+type statements struct {
+	addSession         *sql.Stmt
+	updateLastComment  *sql.Stmt
+	updateLastNote     *sql.Stmt
+	updateForkPath     *sql.Stmt
+	updateBranch       *sql.Stmt
+	getSession         *sql.Stmt
+	listSessions       *sql.Stmt
+	listRecentSessions *sql.Stmt
+	removeSession      *sql.Stmt
+	removeSessionRow   *sql.Stmt
+	listSessionIDs     *sql.Stmt
+	listSessionsBefore *sql.Stmt
+	lastOutcome        *sql.Stmt
+
+	getReviewedSHA *sql.Stmt
+	setReviewedSHA *sql.Stmt
+
+	recordEvent  *sql.Stmt
+	getEvents    *sql.Stmt
+	recentEvents *sql.Stmt
+	pausedIssues *sql.Stmt
+
+	markDelivered       *sql.Stmt
+	pruneDeliveries     *sql.Stmt
+	enqueueWebhook      *sql.Stmt
+	pendingWebhooks     *sql.Stmt
+	markWebhooksHandled *sql.Stmt
+	pruneWebhooks       *sql.Stmt
+
+	getState *sql.Stmt
+	setState *sql.Stmt
+
+	enqueueBackfill *sql.Stmt
+	lastBackfill    *sql.Stmt
+	getBackfill     *sql.Stmt
+	removeBackfill  *sql.Stmt
+
+	getProcessedNote   *sql.Stmt
+	setProcessedNote   *sql.Stmt
+	clearProcessedNote *sql.Stmt
+
+	getProcessedIssues  *sql.Stmt
+	setProcessedIssue   *sql.Stmt
+	clearProcessedIssue *sql.Stmt
+
+	recordSummary *sql.Stmt
+	getSummaries  *sql.Stmt
+
+	getDescription *sql.Stmt
+	setDescription *sql.Stmt
+
+	getLink   *sql.Stmt
+	setLink   *sql.Stmt
+	openLinks *sql.Stmt
+
+	getBundle  *sql.Stmt
+	saveBundle *sql.Stmt
+
+	recordRun *sql.Stmt
+	getRuns   *sql.Stmt
+
+	addTranscript    *sql.Stmt
+	getTranscripts   *sql.Stmt
+	listTranscripts  *sql.Stmt
+	pruneTranscripts *sql.Stmt
+
+	all []*sql.Stmt
+}
+
+// prepare compiles every statement the store runs after opening
+func (s *SQLiteSessionStore) prepare() error {
+	var err error
+	prepare := func(query string) *sql.Stmt {
+		if err != nil {
+			return nil
+		}
+		var stmt *sql.Stmt
+		if stmt, err = s.db.Prepare(query); err == nil {
+			s.stmt.all = append(s.stmt.all, stmt)
+		}
+		return stmt
+	}
+
+	st := &s.stmt
+	st.addSession = prepare(`INSERT OR REPLACE INTO completed_sessions (` + sessionColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	st.updateLastComment = prepare(`UPDATE completed_sessions SET last_comment_time = ?
+		WHERE issue_iid = ? AND ` + projectScope)
+	st.updateLastNote = prepare(`UPDATE completed_sessions
+		SET last_note_id = ?, last_comment_time = COALESCE(?, last_comment_time)
+		WHERE issue_iid = ? AND ` + projectScope)
+	st.updateForkPath = prepare(`UPDATE completed_sessions SET fork_path = ?
+		WHERE issue_iid = ? AND ` + projectScope)
+	st.updateBranch = prepare(`UPDATE completed_sessions SET branch = ?, previous_session_id = ?
+		WHERE issue_iid = ? AND ` + projectScope)
+	// Unscoped, an IID may exist in several projects; the newest session wins
+	st.getSession = prepare(`SELECT ` + sessionColumns + ` FROM completed_sessions
+		WHERE issue_iid = ? AND ` + projectScope + `
+		ORDER BY completion_time DESC LIMIT 1`)
+	st.listSessions = prepare(`SELECT ` + sessionColumns + ` FROM completed_sessions
+		WHERE ` + projectScope + `
+		ORDER BY completion_time DESC`)
+	st.listRecentSessions = prepare(`SELECT ` + sessionColumns + ` FROM completed_sessions
+		WHERE completion_time > ? AND ` + projectScope + `
+		ORDER BY completion_time DESC`)
+	st.removeSession = prepare(`DELETE FROM completed_sessions WHERE issue_iid = ? AND ` + projectScope)
+	st.removeSessionRow = prepare(`DELETE FROM completed_sessions WHERE rowid = ?`)
+	st.listSessionIDs = prepare(`SELECT rowid, issue_iid, session_id FROM completed_sessions`)
+	st.listSessionsBefore = prepare(`SELECT rowid, issue_iid, session_id, project_path, completion_time, last_comment_time
+		FROM completed_sessions WHERE completion_time < ?`)
+	st.lastOutcome = prepare(`SELECT kind, created_at FROM issue_events
+		WHERE project_path = ? AND issue_iid = ? AND kind IN (?, ?, ?, ?)
+		ORDER BY created_at DESC, id DESC LIMIT 1`)
+
+	st.getReviewedSHA = prepare(`SELECT sha FROM reviewed_merge_requests WHERE project_id = ? AND mr_iid = ?`)
+	st.setReviewedSHA = prepare(`INSERT OR REPLACE INTO reviewed_merge_requests (project_id, mr_iid, sha, reviewed_at)
+		VALUES (?, ?, ?, ?)`)
+
+	st.recordEvent = prepare(`INSERT INTO issue_events (issue_iid, project_path, kind, session_id, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	st.getEvents = prepare(`SELECT issue_iid, project_path, kind, session_id, detail, created_at
+		FROM issue_events
+		WHERE issue_iid = ? AND ` + projectScope + `
+		ORDER BY created_at, id`)
+	st.recentEvents = prepare(`SELECT issue_iid, project_path, kind, session_id, detail, created_at
+		FROM issue_events
+		WHERE created_at >= ? AND ` + projectScope + `
+		ORDER BY created_at DESC, id DESC LIMIT ?`)
+	st.pausedIssues = prepare(`SELECT issue_iid FROM issue_events
+		WHERE kind = ? AND id IN (
+			SELECT MAX(id) FROM issue_events
+			WHERE kind IN (?, ?) AND ` + projectScope + `
+			GROUP BY project_path, issue_iid)
+		ORDER BY issue_iid`)
+
+	st.markDelivered = prepare(`INSERT OR IGNORE INTO webhook_deliveries (delivery_id, received_at) VALUES (?, ?)`)
+	st.pruneDeliveries = prepare(`DELETE FROM webhook_deliveries WHERE received_at < ?`)
+	st.enqueueWebhook = prepare(`INSERT OR IGNORE INTO webhook_events (delivery_id, kind, payload, received_at)
+		VALUES (?, ?, ?, ?)`)
+	st.pendingWebhooks = prepare(`SELECT delivery_id, kind, payload, received_at
+		FROM webhook_events
+		WHERE handled_at IS NULL
+		ORDER BY received_at`)
+	st.markWebhooksHandled = prepare(`UPDATE webhook_events SET handled_at = ? WHERE handled_at IS NULL AND received_at < ?`)
+	st.pruneWebhooks = prepare(`DELETE FROM webhook_events WHERE handled_at IS NOT NULL AND handled_at < ?`)
+
+	st.getState = prepare(`SELECT value FROM daemon_state WHERE key = ?`)
+	st.setState = prepare(`INSERT OR REPLACE INTO daemon_state (key, value) VALUES (?, ?)`)
+
+	st.enqueueBackfill = prepare(`INSERT OR IGNORE INTO backfill_queue (project_path, issue_iid, release_at) VALUES (?, ?, ?)`)
+	st.lastBackfill = prepare(`SELECT MAX(release_at) FROM backfill_queue WHERE project_path = ?`)
+	st.getBackfill = prepare(`SELECT issue_iid, release_at FROM backfill_queue WHERE project_path = ? ORDER BY release_at`)
+	st.removeBackfill = prepare(`DELETE FROM backfill_queue WHERE project_path = ? AND issue_iid = ?`)
+
+	st.getProcessedNote = prepare(`SELECT MAX(note_id) FROM processed_notes
+		WHERE issue_iid = ? AND ` + projectScope + `
+		HAVING COUNT(*) > 0`)
+	st.setProcessedNote = prepare(`INSERT OR REPLACE INTO processed_notes (project_path, issue_iid, note_id) VALUES (?, ?, ?)`)
+	st.clearProcessedNote = prepare(`DELETE FROM processed_notes WHERE issue_iid = ? AND ` + projectScope)
+
+	st.getProcessedIssues = prepare(`SELECT issue_iid FROM processed_issues WHERE project_path = ?`)
+	st.setProcessedIssue = prepare(`INSERT OR REPLACE INTO processed_issues (project_path, issue_iid, processed_at) VALUES (?, ?, ?)`)
+	st.clearProcessedIssue = prepare(`DELETE FROM processed_issues WHERE project_path = ? AND issue_iid = ?`)
+
+	st.recordSummary = prepare(`INSERT OR REPLACE INTO session_summaries
+		(project_path, issue_iid, title, labels, files, mr_iid, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	st.getSummaries = prepare(`SELECT issue_iid, title, labels, files, mr_iid, completed_at
+		FROM session_summaries WHERE project_path = ?
+		ORDER BY completed_at DESC`)
+
+	st.getDescription = prepare(`SELECT description FROM issue_descriptions WHERE project_path = ? AND issue_iid = ?`)
+	st.setDescription = prepare(`INSERT OR REPLACE INTO issue_descriptions (project_path, issue_iid, description, recorded_at)
+		VALUES (?, ?, ?, ?)`)
+
+	st.getLink = prepare(`SELECT project_path, branch, fork_path, mr_iid, mr_url, mr_state, pipeline_status, updated_at
+		FROM issue_links WHERE issue_iid = ? AND ` + projectScope + `
+		ORDER BY updated_at DESC LIMIT 1`)
+	st.setLink = prepare(`INSERT OR REPLACE INTO issue_links
+		(project_path, issue_iid, branch, fork_path, mr_iid, mr_url, mr_state, pipeline_status, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	st.openLinks = prepare(`SELECT issue_iid, branch, fork_path, mr_iid, mr_url, mr_state, pipeline_status, updated_at
+		FROM issue_links WHERE project_path = ? AND mr_state NOT IN ('merged', 'closed')
+		ORDER BY updated_at`)
+
+	st.getBundle = prepare(`SELECT project_path, session_id, content, snippet_url, created_at
+		FROM failure_bundles WHERE issue_iid = ? AND ` + projectScope + `
+		ORDER BY created_at DESC LIMIT 1`)
+	st.saveBundle = prepare(`INSERT OR REPLACE INTO failure_bundles
+		(project_path, issue_iid, session_id, content, snippet_url, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+
+	st.recordRun = prepare(`INSERT INTO session_runs
+		(project_path, issue_iid, session_id, kind, outcome, failure, model, started_at, duration_ms, cost_usd, turns, retries,
+		input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	st.getRuns = prepare(`SELECT project_path, issue_iid, session_id, kind, outcome, failure, model,
+		started_at, duration_ms, cost_usd, turns, retries, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens
+		FROM session_runs WHERE started_at >= ? ORDER BY started_at, id`)
+
+	st.addTranscript = prepare(`INSERT INTO transcripts (project_path, issue_iid, session_id, kind, path, started_at)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	st.getTranscripts = prepare(`SELECT project_path, session_id, kind, path, started_at
+		FROM transcripts WHERE issue_iid = ? AND ` + projectScope + `
+		ORDER BY started_at, id`)
+	st.listTranscripts = prepare(`SELECT project_path, issue_iid, session_id, kind, path, started_at
+		FROM transcripts WHERE started_at < ? ORDER BY started_at, id`)
+	st.pruneTranscripts = prepare(`DELETE FROM transcripts WHERE started_at < ?`)
+
+	return err
+}
+
+func (st *statements) close() {
+	for _, stmt := range st.all {
+		stmt.Close()
+	}
+	st.all = nil
+}
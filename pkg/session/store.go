@@ -16,6 +16,16 @@ type CompletedSession struct {
 	ProjectPath     string     `json:"project_path"`
 	CompletionTime  time.Time  `json:"completion_time"`
 	LastCommentTime *time.Time `json:"last_comment_time,omitempty"`
+	// LastNoteID is the newest issue or MR note already fed to the session
+	LastNoteID int `json:"last_note_id,omitempty"`
+	// ForkPath is the fork the issue branch was pushed to, empty when the
+	// branch lives in the project itself
+	ForkPath string `json:"fork_path,omitempty"`
+	// Branch is the issue branch when it is not issue-N, because that was
+	// taken by an earlier attempt
+	Branch string `json:"branch,omitempty"`
+	// PreviousSessionID is the session of the earlier attempt at the issue
+	PreviousSessionID string `json:"previous_session_id,omitempty"`
 	// Environment context for session resumption
 	WorkingDir    string            `json:"working_dir"`
 	ClaudeCommand string            `json:"claude_command"`
@@ -36,7 +46,7 @@ var _ Store = (*SessionStore)(nil)
 // NewSessionStore creates a new session store
 func NewSessionStore(dataDir string) *SessionStore {
 	if dataDir == "" {
-		dataDir = filepath.Join(os.Getenv("HOME"), ".automagic")
+		dataDir = DefaultDataDir()
 	}
 
 	// Ensure directory exists
@@ -62,8 +72,13 @@ func (s *SessionStore) Load() error {
 		return fmt.Errorf("failed to read session file: %v", err)
 	}
 
+	plain, err := unseal(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to read session file: %v", err)
+	}
+
 	var sessions []CompletedSession
-	if err := json.Unmarshal(data, &sessions); err != nil {
+	if err := json.Unmarshal([]byte(plain), &sessions); err != nil {
 		return fmt.Errorf("failed to parse session file: %v", err)
 	}
 
@@ -93,7 +108,8 @@ func (s *SessionStore) Save() error {
 		return fmt.Errorf("failed to marshal sessions: %v", err)
 	}
 
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+	// The legacy file is encrypted as a whole when encryption is enabled
+	if err := os.WriteFile(s.filePath, []byte(seal(string(data))), 0644); err != nil {
 		return fmt.Errorf("failed to write session file: %v", err)
 	}
 
@@ -132,6 +148,59 @@ func (s *SessionStore) UpdateLastCommentTime(issueIID int, commentTime time.Time
 	return fmt.Errorf("session not found for issue %d", issueIID)
 }
 
+// UpdateLastNote records the newest note seen for an issue
+func (s *SessionStore) UpdateLastNote(issueIID, noteID int, commentTime time.Time) error {
+	s.mu.Lock()
+	session, exists := s.sessions[issueIID]
+	if exists {
+		session.LastNoteID = noteID
+		if !commentTime.IsZero() {
+			session.LastCommentTime = &commentTime
+		}
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	// Save takes the read lock itself
+	return s.Save()
+}
+
+// UpdateForkPath records the fork an issue's branch was pushed to
+func (s *SessionStore) UpdateForkPath(issueIID int, forkPath string) error {
+	s.mu.Lock()
+	session, exists := s.sessions[issueIID]
+	if exists {
+		session.ForkPath = forkPath
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	// Save takes the read lock itself
+	return s.Save()
+}
+
+// UpdateBranch records the branch an issue session pushed to and the session
+// of the attempt before it
+func (s *SessionStore) UpdateBranch(issueIID int, branch, previousSessionID string) error {
+	s.mu.Lock()
+	session, exists := s.sessions[issueIID]
+	if exists {
+		session.Branch = branch
+		session.PreviousSessionID = previousSessionID
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("session not found for issue %d", issueIID)
+	}
+	// Save takes the read lock itself
+	return s.Save()
+}
+
 // GetCompletedSession retrieves session information for an issue
 func (s *SessionStore) GetCompletedSession(issueIID int) (*CompletedSession, bool) {
 	s.mu.RLock()
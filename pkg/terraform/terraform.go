@@ -0,0 +1,145 @@
+// Package terraform previews the infrastructure changes of a branch with a
+// read-only terraform plan
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxErrorLines caps how much of a failed command's output a result keeps
+const maxErrorLines = 20
+
+// Result is the plan of one root module
+type Result struct {
+	Dir     string // Module directory, relative to the repository
+	Summary string // Terraform's summary line, e.g. "Plan: 1 to add, 0 to change, 0 to destroy."
+	Error   string // The end of terraform's output when init or plan failed, "" otherwise
+}
+
+// Failed reports whether the module could not be planned
+func (r Result) Failed() bool {
+	return r.Error != ""
+}
+
+// Planner runs terraform plan against a checkout
+type Planner struct {
+	Command string        // Terraform executable
+	Timeout time.Duration // Limit for init and plan of one module, 0 for none
+	DataDir string        // Parent of the per-plan data directories, the system temp directory when empty
+}
+
+// Modules returns the directories holding the given .tf files, sorted
+func Modules(files []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range files {
+		if path.Ext(file) != ".tf" {
+			continue
+		}
+		dir := path.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Plan initializes the module in dir of a checkout with its configured backend
+// and plans it without taking the state lock. Providers and modules are
+// downloaded to a temporary directory under DataDir, so the checkout is left
+// untouched.
+func (p *Planner) Plan(checkout, dir string) Result {
+	result := Result{Dir: dir}
+	if p.DataDir != "" {
+		if err := os.MkdirAll(p.DataDir, 0755); err != nil {
+			result.Error = fmt.Sprintf("failed to create terraform data directory: %v", err)
+			return result
+		}
+	}
+	dataDir, err := os.MkdirTemp(p.DataDir, "automagic-terraform-")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create terraform data directory: %v", err)
+		return result
+	}
+	defer os.RemoveAll(dataDir)
+
+	moduleDir := filepath.Join(checkout, filepath.FromSlash(dir))
+	if _, err := p.run(moduleDir, dataDir, "init", "-input=false", "-no-color"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	output, err := p.run(moduleDir, dataDir, "plan", "-input=false", "-lock=false", "-no-color")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Summary = summaryLine(output)
+	return result
+}
+
+// run runs terraform in a module, returning its output or an error carrying
+// the end of it
+func (p *Planner) run(moduleDir, dataDir string, args ...string) (string, error) {
+	ctx := context.Background()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, p.Command, args...)
+	cmd.Dir = moduleDir
+	cmd.Env = append(os.Environ(), "TF_DATA_DIR="+dataDir, "TF_IN_AUTOMATION=1")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("terraform %s timed out after %s", args[0], p.Timeout)
+		}
+		return "", fmt.Errorf("terraform %s failed: %v\n%s", args[0], err, errorLines(output.String()))
+	}
+	return output.String(), nil
+}
+
+// summaryLine finds the line of plan output stating what would change
+func summaryLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Plan:") || strings.HasPrefix(line, "No changes.") || strings.HasPrefix(line, "Changes to Outputs:") {
+			return line
+		}
+	}
+	return "The plan finished without a summary."
+}
+
+// errorLines keeps the output from terraform's first error on, or its last
+// lines when it reported none, up to maxErrorLines
+func errorLines(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	start := len(lines) - maxErrorLines
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start+maxErrorLines < len(lines) {
+		lines = lines[:start+maxErrorLines]
+	}
+	return strings.Join(lines[start:], "\n")
+}
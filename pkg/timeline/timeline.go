@@ -0,0 +1,168 @@
+package timeline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/gitlab"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// Issue states shown in the lifecycle diagram
+const (
+	StateQueued        = "Queued"
+	StateInProgress    = "InProgress"
+	StateWaitingReview = "WaitingReview"
+	StateError         = "Error"
+	StateMerged        = "Merged"
+)
+
+// Entry is one step in an issue's lifecycle
+type Entry struct {
+	Time      time.Time
+	State     string // State the issue is in after this step
+	Summary   string
+	SessionID string
+}
+
+// Build assembles an issue's lifecycle from the audit log, the stored session
+// and the merge requests opened from its branch, ordered by time
+func Build(events []session.Event, completed *session.CompletedSession, mergeRequests []gitlab.MergeRequest) []Entry {
+	var entries []Entry
+
+	for _, event := range events {
+		entry := Entry{Time: event.Time, SessionID: event.SessionID}
+		detail := event.Detail
+		switch event.Kind {
+		case session.EventPickedUp:
+			entry.State, entry.Summary = StateInProgress, "Picked up"
+		case session.EventCompleted:
+			entry.State, entry.Summary = StateWaitingReview, "Completed"
+		case session.EventFailed:
+			entry.State, entry.Summary = StateError, "Failed"
+		case session.EventResumed:
+			entry.State, entry.Summary = StateInProgress, "Resumed"
+		case session.EventResumeCompleted:
+			entry.State, entry.Summary = StateWaitingReview, "Resume completed"
+		case session.EventResumeFailed:
+			entry.State, entry.Summary = StateError, "Resume failed"
+		case session.EventPipeline:
+			// The detail is the markdown status line posted with the completion comment
+			entry.Summary, detail = strings.ReplaceAll(event.Detail, "**", ""), ""
+		case session.EventCILint:
+			entry.Summary = "CI lint failed"
+		case session.EventTerraformPlan:
+			entry.Summary = "Terraform plan"
+		case session.EventMigrationCheck:
+			entry.Summary = "Migration check"
+		case session.EventClassified:
+			entry.Summary = "Classified"
+		case session.EventCoverage:
+			entry.Summary = "Coverage"
+		case session.EventBenchmark:
+			entry.Summary = "Benchmarks"
+		default:
+			entry.Summary = event.Kind
+		}
+		if detail != "" {
+			entry.Summary += ": " + detail
+		}
+		entries = append(entries, entry)
+	}
+
+	// Sessions completed before the audit log existed still have a session record
+	if len(events) == 0 && completed != nil {
+		entries = append(entries, Entry{
+			Time:      completed.CompletionTime,
+			State:     StateWaitingReview,
+			Summary:   "Completed",
+			SessionID: completed.SessionID,
+		})
+		if completed.LastCommentTime != nil {
+			entries = append(entries, Entry{
+				Time:    *completed.LastCommentTime,
+				Summary: "Last human comment handled",
+			})
+		}
+	}
+
+	for _, mr := range mergeRequests {
+		if createdAt, err := time.Parse(time.RFC3339, mr.CreatedAt); err == nil {
+			entries = append(entries, Entry{Time: createdAt, Summary: fmt.Sprintf("MR !%d opened", mr.IID)})
+		}
+		if mergedAt, err := time.Parse(time.RFC3339, mr.MergedAt); err == nil {
+			entries = append(entries, Entry{Time: mergedAt, State: StateMerged, Summary: fmt.Sprintf("MR !%d merged", mr.IID)})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries
+}
+
+// WriteText prints the timeline as a human-readable list
+func WriteText(w io.Writer, issueIID int, entries []Entry) {
+	fmt.Fprintf(w, "Lifecycle of issue #%d\n\n", issueIID)
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No recorded activity.")
+		return
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintf(w, "  %s  %s", entry.Time.Format("2006-01-02 15:04:05"), entry.Summary)
+		if entry.SessionID != "" {
+			fmt.Fprintf(w, " (session %s)", entry.SessionID)
+		}
+		fmt.Fprintln(w)
+	}
+
+	resumes := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Summary, "Resumed") {
+			resumes++
+		}
+	}
+	fmt.Fprintf(w, "\nCurrent state: %s (resumed %d times)\n", currentState(entries), resumes)
+}
+
+// WriteMermaid prints the timeline as a Mermaid state diagram that can be
+// pasted into a GitLab comment inside a ```mermaid block
+func WriteMermaid(w io.Writer, entries []Entry) {
+	fmt.Fprintln(w, "stateDiagram-v2")
+
+	previous := "[*]"
+	for _, entry := range entries {
+		state := entry.State
+		if state == "" {
+			// Informational steps don't move the issue, so draw them as self-transitions
+			if previous == "[*]" {
+				state = StateQueued
+			} else {
+				state = previous
+			}
+		}
+
+		label := strings.ReplaceAll(entry.Summary, ":", " -")
+		fmt.Fprintf(w, "    %s --> %s : %s %s\n", previous, state, entry.Time.Format("01-02 15:04"), label)
+		previous = state
+	}
+
+	if previous == StateMerged {
+		fmt.Fprintf(w, "    %s --> [*]\n", previous)
+	}
+}
+
+// currentState returns the state after the last state-changing entry
+func currentState(entries []Entry) string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].State != "" {
+			return entries[i].State
+		}
+	}
+	return StateQueued
+}
@@ -0,0 +1,131 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// jira moves the Jira issues whose keys the GitLab issue mentions through
+// their workflow transitions
+type jira struct {
+	baseURL string
+	email   string
+	token   string
+	client  *http.Client
+}
+
+func newJira(baseURL, email, token string) *jira {
+	return &jira{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		email:   email,
+		token:   token,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (j *jira) name() string {
+	return "jira"
+}
+
+func (j *jira) sync(transition Transition, status string) (bool, error) {
+	linked := false
+	for _, key := range issueKeys(transition) {
+		found, err := j.move(key, status)
+		if err != nil {
+			return linked, fmt.Errorf("%s: %v", key, err)
+		}
+		linked = linked || found
+	}
+	return linked, nil
+}
+
+// move transitions a Jira issue to the status, reporting whether the issue exists
+func (j *jira) move(key, status string) (bool, error) {
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	found, err := j.call("GET", "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=status", nil, &issue)
+	if err != nil || !found {
+		return found, err
+	}
+	if strings.EqualFold(issue.Fields.Status.Name, status) {
+		return true, nil
+	}
+
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if _, err := j.call("GET", "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions", nil, &transitions); err != nil {
+		return true, err
+	}
+	for _, t := range transitions.Transitions {
+		if strings.EqualFold(t.To.Name, status) || strings.EqualFold(t.Name, status) {
+			payload := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+			_, err := j.call("POST", "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions", payload, nil)
+			return true, err
+		}
+	}
+	return true, fmt.Errorf("no transition from %q to %q", issue.Fields.Status.Name, status)
+}
+
+// call sends a request to the Jira REST API, reporting false for a missing issue
+func (j *jira) call(method, path string, payload, result interface{}) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal request: %v", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, j.baseURL+path, body)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %v", err)
+	}
+	if j.email != "" {
+		req.SetBasicAuth(j.email, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach Jira: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("Jira returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return false, fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return true, nil
+}
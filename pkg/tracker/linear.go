@@ -0,0 +1,133 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// linearAPIURL is Linear's GraphQL endpoint
+const linearAPIURL = "https://api.linear.app/graphql"
+
+// linear moves the Linear issues whose identifiers the GitLab issue mentions
+// to the workflow state of their team named like the status
+type linear struct {
+	apiKey string
+	client *http.Client
+}
+
+func newLinear(apiKey string) *linear {
+	return &linear{apiKey: apiKey, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (l *linear) name() string {
+	return "linear"
+}
+
+func (l *linear) sync(transition Transition, status string) (bool, error) {
+	linked := false
+	for _, key := range issueKeys(transition) {
+		found, err := l.move(key, status)
+		if err != nil {
+			return linked, fmt.Errorf("%s: %v", key, err)
+		}
+		linked = linked || found
+	}
+	return linked, nil
+}
+
+// move sets the state of a Linear issue, reporting whether the issue exists
+func (l *linear) move(identifier, status string) (bool, error) {
+	var data struct {
+		Issue *struct {
+			ID    string `json:"id"`
+			State struct {
+				Name string `json:"name"`
+			} `json:"state"`
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { id state { name } team { states { nodes { id name } } } } }`
+	if err := l.call(query, map[string]interface{}{"id": identifier}, &data); err != nil {
+		// Identifiers of other trackers are not found
+		if strings.Contains(err.Error(), "not found") {
+			return false, nil
+		}
+		return false, err
+	}
+	if data.Issue == nil {
+		return false, nil
+	}
+	if strings.EqualFold(data.Issue.State.Name, status) {
+		return true, nil
+	}
+
+	for _, state := range data.Issue.Team.States.Nodes {
+		if strings.EqualFold(state.Name, status) {
+			mutation := `mutation($id: String!, $stateId: String!) { issueUpdate(id: $id, input: {stateId: $stateId}) { success } }`
+			return true, l.call(mutation, map[string]interface{}{"id": data.Issue.ID, "stateId": state.ID}, nil)
+		}
+	}
+	return true, fmt.Errorf("team has no state %q", status)
+}
+
+// call runs a GraphQL operation
+func (l *linear) call(query string, variables map[string]interface{}, result interface{}) error {
+	encoded, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	req, err := http.NewRequest("POST", linearAPIURL, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", l.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Linear: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return fmt.Errorf("Linear returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("Linear: %s", strings.ToLower(strings.Join(messages, "; ")))
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Linear returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	notionAPIURL  = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+)
+
+// notion sets the status property of the pages of a database whose URL
+// property holds the GitLab issue's address
+type notion struct {
+	token          string
+	database       string
+	urlProperty    string
+	statusProperty string
+	client         *http.Client
+}
+
+func newNotion(token, database, urlProperty, statusProperty string) *notion {
+	if urlProperty == "" {
+		urlProperty = "GitLab Issue"
+	}
+	if statusProperty == "" {
+		statusProperty = "Status"
+	}
+	return &notion{
+		token:          token,
+		database:       database,
+		urlProperty:    urlProperty,
+		statusProperty: statusProperty,
+		client:         &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (n *notion) name() string {
+	return "notion"
+}
+
+func (n *notion) sync(transition Transition, status string) (bool, error) {
+	if transition.URL == "" {
+		return false, nil
+	}
+
+	var pages struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	query := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"property": n.urlProperty,
+			"url":      map[string]string{"equals": transition.URL},
+		},
+	}
+	if err := n.call("POST", "/databases/"+n.database+"/query", query, &pages); err != nil {
+		return false, err
+	}
+
+	for _, page := range pages.Results {
+		update := map[string]interface{}{
+			"properties": map[string]interface{}{
+				n.statusProperty: map[string]interface{}{"status": map[string]string{"name": status}},
+			},
+		}
+		if err := n.call("PATCH", "/pages/"+page.ID, update, nil); err != nil {
+			return true, err
+		}
+	}
+	return len(pages.Results) > 0, nil
+}
+
+// call sends a request to the Notion API
+func (n *notion) call(method, path string, payload, result interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	req, err := http.NewRequest(method, notionAPIURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	req.Header.Set("Notion-Version", notionVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Notion: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Notion returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
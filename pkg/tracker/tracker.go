@@ -0,0 +1,155 @@
+// Package tracker mirrors the status transitions of GitLab issues to external
+// project trackers (Jira, Linear and Notion databases), so their items move
+// through picked up, in review and done as the daemon works on the issues.
+package tracker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Statuses an issue moves through, as named in Options.Statuses
+const (
+	StatusPickedUp = "picked_up"
+	StatusInReview = "in_review"
+	StatusDone     = "done"
+)
+
+// DefaultStatuses names the tracker status of each transition when Statuses
+// leaves it out
+var DefaultStatuses = map[string]string{
+	StatusPickedUp: "In Progress",
+	StatusInReview: "In Review",
+	StatusDone:     "Done",
+}
+
+// Transition is an issue entering a status
+type Transition struct {
+	ProjectPath string
+	IssueIID    int
+	Title       string
+	Description string
+	URL         string // Web address of the GitLab issue
+	Status      string // StatusPickedUp, StatusInReview or StatusDone
+	Time        time.Time
+}
+
+// Options configures the trackers transitions are mirrored to. A tracker
+// whose credentials are empty is left out.
+type Options struct {
+	// Statuses maps picked_up, in_review and done to the status names used by
+	// the trackers, compared without case; a status mapped to "" is not synced
+	Statuses map[string]string
+
+	JiraURL   string // e.g. https://example.atlassian.net
+	JiraEmail string // Account of an Atlassian Cloud API token, "" for a personal access token
+	JiraToken string
+
+	LinearAPIKey string
+
+	NotionToken          string
+	NotionDatabase       string // Database whose pages track the issues
+	NotionURLProperty    string // URL property holding the GitLab issue address
+	NotionStatusProperty string // Status property set on transitions
+}
+
+// target is an external tracker
+type target interface {
+	name() string
+	// sync moves the items of the issue to the status, reporting whether
+	// the issue has an item in the tracker
+	sync(transition Transition, status string) (bool, error)
+}
+
+// Syncer mirrors transitions to the configured trackers. A nil *Syncer is
+// valid and syncs nothing, which is how disabled syncing is represented.
+type Syncer struct {
+	statuses map[string]string
+	targets  []target
+}
+
+// New returns a syncer for the options, or nil when they name no tracker
+func New(options Options) *Syncer {
+	s := &Syncer{statuses: make(map[string]string)}
+	for status, name := range DefaultStatuses {
+		s.statuses[status] = name
+	}
+	for status, name := range options.Statuses {
+		s.statuses[status] = name
+	}
+
+	if options.JiraURL != "" && options.JiraToken != "" {
+		s.targets = append(s.targets, newJira(options.JiraURL, options.JiraEmail, options.JiraToken))
+	}
+	if options.LinearAPIKey != "" {
+		s.targets = append(s.targets, newLinear(options.LinearAPIKey))
+	}
+	if options.NotionToken != "" && options.NotionDatabase != "" {
+		s.targets = append(s.targets, newNotion(options.NotionToken, options.NotionDatabase,
+			options.NotionURLProperty, options.NotionStatusProperty))
+	}
+	if len(s.targets) == 0 {
+		return nil
+	}
+	return s
+}
+
+// Targets names the trackers transitions are mirrored to
+func (s *Syncer) Targets() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.targets))
+	for _, t := range s.targets {
+		names = append(names, t.name())
+	}
+	return names
+}
+
+// Sync mirrors a transition to every tracker in which the issue has an
+// item, returning the trackers it was mirrored to. Failures of single
+// trackers are combined into the error; the others are still synced.
+func (s *Syncer) Sync(transition Transition) ([]string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	status := s.statuses[transition.Status]
+	if status == "" {
+		return nil, nil
+	}
+
+	var synced, failures []string
+	for _, t := range s.targets {
+		linked, err := t.sync(transition, status)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", t.name(), err))
+			continue
+		}
+		if linked {
+			synced = append(synced, t.name())
+		}
+	}
+	if len(failures) > 0 {
+		return synced, fmt.Errorf("failed to sync status %q: %s", status, strings.Join(failures, "; "))
+	}
+	return synced, nil
+}
+
+// issueKeyPattern matches Jira and Linear issue keys such as OPS-123
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-[1-9][0-9]*\b`)
+
+// issueKeys returns the tracker keys mentioned in an issue's title and
+// description, title first, without duplicates
+func issueKeys(transition Transition) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range issueKeyPattern.FindAllString(transition.Title+"\n"+transition.Description, -1) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
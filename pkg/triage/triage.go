@@ -0,0 +1,109 @@
+// Package triage classifies new issues with a single short Claude call, for
+// labeling them without starting a session.
+package triage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// Issue types a classification picks from
+const (
+	TypeBug     = "bug"
+	TypeFeature = "feature"
+	TypeDocs    = "docs"
+)
+
+// maxDescription caps the description sent, keeping the call cheap
+const maxDescription = 6000
+
+// promptTemplate asks for a classification as one JSON object. Its arguments
+// are the title and the description.
+const promptTemplate = `Classify this GitLab issue. Do not use any tools and do not try to solve it.
+
+Title: %s
+
+Description:
+%s
+
+Answer with one JSON object and nothing else:
+{"type": "bug|feature|docs", "complexity": "T1|T2|T3|T4", "estimate": "...", "reason": "..."}
+
+- type: "bug" for something broken, "docs" for documentation only, "feature" for anything else
+- complexity: T4 for a small, local change (a typo, one function); T3 for a change across a few files; T2 for a feature touching several parts of the code; T1 for large or risky work such as migrations, redesigns or cross-service changes
+- estimate: how long an experienced developer would take, e.g. "1-2 hours" or "2-3 days"
+- reason: one sentence explaining the complexity
+`
+
+// Classification is what Claude made of an issue
+type Classification struct {
+	Type       string `json:"type"`
+	Complexity string `json:"complexity"`
+	Estimate   string `json:"estimate"`
+	Reason     string `json:"reason"`
+}
+
+// Classifier classifies issues with the Claude CLI
+type Classifier struct {
+	Command string // Claude CLI executable
+	Model   string // Model to classify with, empty for the CLI's default
+}
+
+// Classify asks Claude for the type, complexity tier and estimate of an issue
+func (c *Classifier) Classify(ctx context.Context, title, description string) (*Classification, error) {
+	if len(description) > maxDescription {
+		description = description[:maxDescription]
+		for !utf8.ValidString(description) {
+			description = description[:len(description)-1]
+		}
+		description += "\n[truncated]"
+	}
+
+	args := []string{"--output-format", "text", "--max-turns", "1"}
+	if c.Model != "" {
+		args = append(args, "--model", c.Model)
+	}
+	args = append(args, "-p", fmt.Sprintf(promptTemplate, title, description))
+
+	cmd := exec.CommandContext(ctx, c.Command, args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run the classification: %v", err)
+	}
+	return Parse(stdout.String())
+}
+
+// Parse reads the JSON object of a classification from Claude's answer,
+// which may wrap it in prose or a code fence
+func Parse(answer string) (*Classification, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the answer has no classification: %q", strings.TrimSpace(answer))
+	}
+	var classification Classification
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &classification); err != nil {
+		return nil, fmt.Errorf("failed to parse the classification: %v", err)
+	}
+
+	classification.Type = strings.ToLower(strings.TrimSpace(classification.Type))
+	switch classification.Type {
+	case TypeBug, TypeFeature, TypeDocs:
+	default:
+		return nil, fmt.Errorf("unknown issue type %q", classification.Type)
+	}
+	classification.Complexity = strings.ToUpper(strings.TrimSpace(classification.Complexity))
+	switch classification.Complexity {
+	case "T1", "T2", "T3", "T4":
+	default:
+		return nil, fmt.Errorf("unknown complexity %q", classification.Complexity)
+	}
+	return &classification, nil
+}
@@ -0,0 +1,240 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/claude"
+	"github.com/bilbo290/automagic/pkg/control"
+	"github.com/bilbo290/automagic/pkg/session"
+)
+
+// transcriptTail is how much of the end of a transcript is read for the
+// output panel
+const transcriptTail = 64 * 1024
+
+// maxIssueLines caps the issues panel so the output panel keeps its room
+const maxIssueLines = 8
+
+// draw redraws the whole screen
+func (d *dashboard) draw(term *terminal) {
+	rows, cols := term.size()
+	if rows < 8 {
+		rows = 8
+	}
+	var lines []string
+	add := func(style, text string) {
+		text = fit(text, cols)
+		if style != "" {
+			text = style + text + reset
+		}
+		lines = append(lines, text)
+	}
+
+	// Header
+	title := "automagic"
+	if d.status != nil {
+		title += " — " + d.status.Project
+		if d.status.Role != "" {
+			title += " (" + d.status.Role + ")"
+		}
+		if d.status.DryRun {
+			title += " [dry run]"
+		}
+	}
+	add(bold, fmt.Sprintf("%s  %s", title, time.Now().Format("15:04:05")))
+	if d.statusErr != nil {
+		add("", "Daemon unreachable: "+d.statusErr.Error())
+	} else {
+		add("", d.queueLine())
+	}
+
+	// Sessions: running ones first, then the recent completions
+	add("", "")
+	add(bold, "Sessions")
+	if len(d.rows) == 0 {
+		add(dim, "  No sessions in the last 24 hours")
+	}
+	running := 0
+	if d.status != nil {
+		running = len(d.status.Running)
+	}
+	for i, r := range d.rows {
+		var text string
+		if r.running {
+			text = runningLine(d.status.Running, r.issueIID)
+		} else {
+			text = completedLine(d.recent[i-running])
+		}
+		style := ""
+		if i == d.selected {
+			style = inverse
+		}
+		add(style, text)
+	}
+
+	// Issues
+	if d.options.Issues != nil {
+		add("", "")
+		add(bold, "Issues")
+		switch {
+		case d.issuesErr != nil:
+			add("", "  Failed to list issues: "+d.issuesErr.Error())
+		case len(d.issues) == 0:
+			add(dim, "  No monitored issues")
+		}
+		for i, issue := range d.issues {
+			if i == maxIssueLines-1 && len(d.issues) > maxIssueLines {
+				add(dim, fmt.Sprintf("  … %d more", len(d.issues)-i))
+				break
+			}
+			add("", fmt.Sprintf("  #%-5d %-12s %s", issue.IID, issue.Stage, issue.Title))
+		}
+	}
+
+	// Output of the selected session fills what is left above the footer
+	if r, ok := d.selectedRow(); ok && d.options.Transcript != nil {
+		add("", "")
+		add(bold, fmt.Sprintf("Output of #%d", r.issueIID))
+		room := rows - len(lines) - 2
+		for _, line := range d.output(r.issueIID, room) {
+			add("", "  "+line)
+		}
+	}
+
+	// Footer at the bottom
+	for len(lines) < rows-2 {
+		lines = append(lines, "")
+	}
+	lines = lines[:rows-2]
+	add(dim, "↑/↓ select   c cancel session   r re-run issue   q quit")
+	add("", d.message)
+
+	fmt.Print(clearScreen + strings.Join(lines, "\r\n"))
+}
+
+// queueLine describes the queued issues and the tier usage
+func (d *dashboard) queueLine() string {
+	queued := "empty"
+	if len(d.status.Enqueued) > 0 {
+		issues := make([]string, 0, len(d.status.Enqueued))
+		for _, iid := range d.status.Enqueued {
+			issues = append(issues, fmt.Sprintf("#%d", iid))
+		}
+		queued = strings.Join(issues, " ")
+	}
+
+	tiers := make([]string, 0, len(d.status.TierUsage))
+	for tier, used := range d.status.TierUsage {
+		tiers = append(tiers, fmt.Sprintf("%s %d", tier, used))
+	}
+	sort.Strings(tiers)
+	line := fmt.Sprintf("Running %d   Queue: %s", len(d.status.Running), queued)
+	if len(tiers) > 0 {
+		line += "   Tiers: " + strings.Join(tiers, ", ")
+	}
+	return line
+}
+
+// runningLine describes the running session of an issue
+func runningLine(running []control.RunningSession, issueIID int) string {
+	for _, s := range running {
+		if s.IssueIID != issueIID {
+			continue
+		}
+		elapsed := ""
+		if !s.StartedAt.IsZero() {
+			elapsed = time.Since(s.StartedAt).Round(time.Second).String()
+		}
+		return fmt.Sprintf("▶ #%-5d %-7s running    %-10s pid %d", s.IssueIID, s.Kind, elapsed, s.PID)
+	}
+	return fmt.Sprintf("▶ #%-5d", issueIID)
+}
+
+// completedLine describes a finished session run
+func completedLine(s control.Session) string {
+	ago := time.Since(s.StartedAt.Add(time.Duration(s.Duration * float64(time.Second)))).Round(time.Minute)
+	line := fmt.Sprintf("  #%-5d %-7s %-10s $%-9.2f %s ago", s.IssueIID, s.Kind, s.Outcome, s.CostUSD, ago)
+	if s.Failure != "" {
+		line += " (" + s.Failure + ")"
+	}
+	return line
+}
+
+// output returns the last lines of the latest transcript of an issue, as
+// -transcript renders them
+func (d *dashboard) output(issueIID, lines int) []string {
+	if lines <= 0 {
+		return nil
+	}
+	path, err := d.options.Transcript(issueIID)
+	if err != nil {
+		return []string{"Failed to find the transcript: " + err.Error()}
+	}
+	if path == "" {
+		return []string{"No transcript recorded"}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return []string{"Failed to open the transcript: " + err.Error()}
+	}
+	defer file.Close()
+	offset := int64(0)
+	if info, err := file.Stat(); err == nil && info.Size() > transcriptTail {
+		offset = info.Size() - transcriptTail
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return []string{"Failed to read the transcript: " + err.Error()}
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return []string{"Failed to read the transcript: " + err.Error()}
+	}
+
+	raw := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if offset > 0 && len(raw) > 0 {
+		// The first line was cut by the seek
+		raw = raw[1:]
+	}
+	var rendered []string
+	for _, line := range raw {
+		line, err := session.UnsealLine(line)
+		if err != nil {
+			return []string{"Failed to read the transcript: " + err.Error()}
+		}
+		if text := claude.RenderTranscriptLine(line); text != "" {
+			rendered = append(rendered, strings.Split(text, "\n")...)
+		}
+	}
+	if len(rendered) > lines {
+		rendered = rendered[len(rendered)-lines:]
+	}
+	return rendered
+}
+
+// fit cuts text to width columns, counting runes, and drops control
+// characters that would move the cursor
+func fit(text string, width int) string {
+	text = strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+	runes := []rune(text)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ANSI sequences the dashboard draws with
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	bold           = "\x1b[1m"
+	dim            = "\x1b[2m"
+	inverse        = "\x1b[7m"
+	reset          = "\x1b[0m"
+)
+
+// terminal switches the terminal to reading single keys without echo, and
+// back. stty is used so no terminal library is needed.
+type terminal struct {
+	saved string // stty -g output restored on exit
+}
+
+func openTerminal() (*terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings: %v", err)
+	}
+	// cbreak keeps Ctrl+C working, unlike raw
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to set up terminal: %v", err)
+	}
+	fmt.Print(enterAltScreen)
+	return &terminal{saved: strings.TrimSpace(saved)}, nil
+}
+
+func (t *terminal) close() {
+	fmt.Print(leaveAltScreen)
+	if _, err := stty(t.saved); err != nil {
+		stty("sane")
+	}
+}
+
+// size returns the rows and columns of the terminal, 24x80 when unknown
+func (t *terminal) size() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		if fields := strings.Fields(out); len(fields) == 2 {
+			rows, rowsErr := strconv.Atoi(fields[0])
+			cols, colsErr := strconv.Atoi(fields[1])
+			if rowsErr == nil && colsErr == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+// stty runs stty on the terminal of stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,216 @@
+// Package tui is a terminal dashboard of a running daemon: the issues it
+// monitors, its running sessions with their output as it is written, the
+// queue and the recent completions. It talks to the daemon through the
+// control API, and can cancel a session or run an issue again.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/bilbo290/automagic/pkg/control"
+)
+
+// Controller is the part of the control API the dashboard uses, implemented
+// by *control.Client
+type Controller interface {
+	Status() (*control.Status, error)
+	Sessions(since time.Time) ([]control.Session, error)
+	Enqueue(projectPath string, issueIID int) error
+	Cancel(issueIID int) error
+}
+
+// Issue is a monitored issue and the workflow stage its labels put it in
+type Issue struct {
+	IID   int
+	Title string
+	Stage string // e.g. queued, in progress or in review
+}
+
+// Options configures the dashboard
+type Options struct {
+	Controller Controller
+	// Issues lists the monitored issues, nil to leave the panel out
+	Issues func() ([]Issue, error)
+	// Transcript returns the transcript file of an issue's latest run, ""
+	// when it has none
+	Transcript func(issueIID int) (string, error)
+	// Refresh is how often the daemon's status is polled, 2s by default
+	Refresh time.Duration
+	// IssueRefresh is how often Issues is called, 30s by default
+	IssueRefresh time.Duration
+}
+
+// recentCompletions is how many finished runs are listed
+const recentCompletions = 8
+
+// row is a selectable line of the sessions panel
+type row struct {
+	issueIID int
+	running  bool
+}
+
+type dashboard struct {
+	options Options
+
+	status    *control.Status
+	statusErr error
+	recent    []control.Session // Newest first
+	issues    []Issue
+	issuesErr error
+	issuesAt  time.Time
+
+	rows     []row
+	selected int
+	message  string // Result of the last key command
+}
+
+// Run shows the dashboard until q is pressed or the process is interrupted.
+// It needs stdin to be a terminal.
+func Run(options Options) error {
+	if options.Refresh <= 0 {
+		options.Refresh = 2 * time.Second
+	}
+	if options.IssueRefresh <= 0 {
+		options.IssueRefresh = 30 * time.Second
+	}
+
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.close()
+
+	keys := make(chan string, 8)
+	go readKeys(keys)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(options.Refresh)
+	defer ticker.Stop()
+
+	d := &dashboard{options: options}
+	d.refresh()
+	for {
+		d.draw(term)
+		select {
+		case <-signals:
+			return nil
+		case key, ok := <-keys:
+			if !ok || !d.handle(key) {
+				return nil
+			}
+		case <-ticker.C:
+			d.refresh()
+		}
+	}
+}
+
+// readKeys sends the keys typed, an escape sequence counting as one key
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- string(buf[:n])
+	}
+}
+
+// refresh polls the daemon, and the issues when they are due
+func (d *dashboard) refresh() {
+	d.status, d.statusErr = d.options.Controller.Status()
+
+	sessions, err := d.options.Controller.Sessions(time.Now().Add(-24 * time.Hour))
+	if err == nil {
+		d.recent = d.recent[:0]
+		for i := len(sessions) - 1; i >= 0 && len(d.recent) < recentCompletions; i-- {
+			d.recent = append(d.recent, sessions[i])
+		}
+	} else if d.statusErr == nil {
+		d.statusErr = err
+	}
+
+	if d.options.Issues != nil && time.Since(d.issuesAt) >= d.options.IssueRefresh {
+		d.issues, d.issuesErr = d.options.Issues()
+		d.issuesAt = time.Now()
+	}
+
+	// Keep the selection on the same session as rows come and go
+	var current row
+	if d.selected < len(d.rows) {
+		current = d.rows[d.selected]
+	}
+	d.rows = d.rows[:0]
+	if d.status != nil {
+		running := append([]control.RunningSession(nil), d.status.Running...)
+		sort.Slice(running, func(i, j int) bool { return running[i].IssueIID < running[j].IssueIID })
+		for _, s := range running {
+			d.rows = append(d.rows, row{issueIID: s.IssueIID, running: true})
+		}
+	}
+	for _, s := range d.recent {
+		d.rows = append(d.rows, row{issueIID: s.IssueIID})
+	}
+	d.selected = 0
+	for i, r := range d.rows {
+		if r == current {
+			d.selected = i
+			break
+		}
+	}
+}
+
+// handle runs the command of a key, returning false to quit
+func (d *dashboard) handle(key string) bool {
+	switch key {
+	case "q", "Q":
+		return false
+	case "k", "\x1b[A", "\x1bOA":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "j", "\x1b[B", "\x1bOB":
+		if d.selected < len(d.rows)-1 {
+			d.selected++
+		}
+	case "c":
+		r, ok := d.selectedRow()
+		if !ok || !r.running {
+			d.message = "Select a running session to cancel"
+			break
+		}
+		if err := d.options.Controller.Cancel(r.issueIID); err != nil {
+			d.message = fmt.Sprintf("Failed to cancel #%d: %v", r.issueIID, err)
+		} else {
+			d.message = fmt.Sprintf("Cancelled the session of #%d", r.issueIID)
+		}
+		d.refresh()
+	case "r":
+		r, ok := d.selectedRow()
+		if !ok {
+			d.message = "Select a session to re-run its issue"
+			break
+		}
+		if err := d.options.Controller.Enqueue("", r.issueIID); err != nil {
+			d.message = fmt.Sprintf("Failed to re-run #%d: %v", r.issueIID, err)
+		} else {
+			d.message = fmt.Sprintf("Queued #%d to run again", r.issueIID)
+		}
+		d.refresh()
+	}
+	return true
+}
+
+func (d *dashboard) selectedRow() (row, bool) {
+	if d.selected >= len(d.rows) {
+		return row{}, false
+	}
+	return d.rows[d.selected], true
+}
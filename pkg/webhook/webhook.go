@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxPayloadBytes bounds the size of a webhook body we are willing to read
+const maxPayloadBytes = 5 << 20
+
+// Event is a verified, deduplicated webhook delivery
+type Event struct {
+	Kind       string // X-Gitlab-Event header, e.g. "Issue Hook"
+	DeliveryID string
+	ReceivedAt time.Time
+	Payload    []byte
+}
+
+// DeliveryStore persists delivery IDs so retried deliveries are handled once
+type DeliveryStore interface {
+	// MarkDelivered records a delivery, returning false if it was already seen
+	MarkDelivered(deliveryID string, receivedAt time.Time) (bool, error)
+}
+
+// Handler verifies GitLab webhook deliveries and passes fresh, unseen ones to onEvent
+type Handler struct {
+	secret     string
+	maxAge     time.Duration
+	deliveries DeliveryStore
+	onEvent    func(Event)
+}
+
+// NewHandler creates a webhook handler. Deliveries must carry the secret in
+// the X-Gitlab-Token header; those whose object is older than maxAge are
+// dropped as stale (0 disables the check).
+func NewHandler(secret string, maxAge time.Duration, deliveries DeliveryStore, onEvent func(Event)) *Handler {
+	if deliveries == nil {
+		deliveries = NewMemoryDeliveryStore()
+	}
+	return &Handler{
+		secret:     secret,
+		maxAge:     maxAge,
+		deliveries: deliveries,
+		onEvent:    onEvent,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get("X-Gitlab-Token")
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	event := Event{
+		Kind:       r.Header.Get("X-Gitlab-Event"),
+		DeliveryID: deliveryID(r),
+		ReceivedAt: now,
+		Payload:    payload,
+	}
+
+	// Stale and duplicate deliveries are acknowledged rather than failed, so
+	// GitLab doesn't keep retrying them or disable the hook
+	if h.maxAge > 0 {
+		if occurredAt, ok := payloadTime(payload); ok && now.Sub(occurredAt) > h.maxAge {
+			fmt.Printf("Webhook: ignoring stale %s delivery %s from %s\n", event.Kind, event.DeliveryID, occurredAt.Format(time.RFC3339))
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, "stale")
+			return
+		}
+	}
+
+	if event.DeliveryID != "" {
+		fresh, err := h.deliveries.MarkDelivered(event.DeliveryID, now)
+		if err != nil {
+			http.Error(w, "failed to record delivery", http.StatusInternalServerError)
+			return
+		}
+		if !fresh {
+			fmt.Printf("Webhook: ignoring duplicate %s delivery %s\n", event.Kind, event.DeliveryID)
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, "duplicate")
+			return
+		}
+	}
+
+	h.onEvent(event)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// deliveryID returns the identifier GitLab keeps stable across retries of one delivery
+func deliveryID(r *http.Request) string {
+	for _, header := range []string{"Idempotency-Key", "X-Gitlab-Event-UUID", "X-Gitlab-Webhook-UUID"} {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// payloadTime extracts when the event's object last changed
+func payloadTime(payload []byte) (time.Time, bool) {
+	var body struct {
+		ObjectAttributes struct {
+			UpdatedAt  string `json:"updated_at"`
+			FinishedAt string `json:"finished_at"`
+			CreatedAt  string `json:"created_at"`
+		} `json:"object_attributes"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return time.Time{}, false
+	}
+
+	attrs := body.ObjectAttributes
+	for _, value := range []string{attrs.UpdatedAt, attrs.FinishedAt, attrs.CreatedAt} {
+		if t, ok := parseTime(value); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTime accepts the timestamp formats GitLab uses across hook payloads
+func parseTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MemoryDeliveryStore remembers delivery IDs for the lifetime of the process
+type MemoryDeliveryStore struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{seen: make(map[string]time.Time)}
+}
+
+func (m *MemoryDeliveryStore) MarkDelivered(deliveryID string, receivedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.seen[deliveryID]; exists {
+		return false, nil
+	}
+	m.seen[deliveryID] = receivedAt
+	return true, nil
+}